@@ -6,14 +6,15 @@ import (
 )
 
 type AccessKey struct {
-	ID                uid.ID `json:"id"`
-	Created           Time   `json:"created"`
-	Name              string `json:"name"`
-	IssuedForName     string `json:"issuedForName"`
-	IssuedFor         uid.ID `json:"issuedFor"`
-	ProviderID        uid.ID `json:"providerID"`
-	Expires           Time   `json:"expires" note:"key is no longer valid after this time"`
-	ExtensionDeadline Time   `json:"extensionDeadline" note:"key must be used within this duration to remain valid"`
+	ID                uid.ID   `json:"id"`
+	Created           Time     `json:"created"`
+	Name              string   `json:"name"`
+	IssuedForName     string   `json:"issuedForName"`
+	IssuedFor         uid.ID   `json:"issuedFor"`
+	ProviderID        uid.ID   `json:"providerID"`
+	Expires           Time     `json:"expires" note:"key is no longer valid after this time"`
+	ExtensionDeadline Time     `json:"extensionDeadline" note:"key must be used within this duration to remain valid"`
+	Scopes            []string `json:"scopes,omitempty" note:"if set, the key can only be used for these scopes"`
 }
 
 type ListAccessKeysRequest struct {
@@ -34,6 +35,7 @@ type CreateAccessKeyRequest struct {
 	Name              string   `json:"name"`
 	TTL               Duration `json:"ttl" note:"maximum time valid"`
 	ExtensionDeadline Duration `json:"extensionDeadline,omitempty" note:"How long the key is active for before it needs to be renewed. The access key must be used within this amount of time to renew validity"`
+	Scopes            []string `json:"scopes,omitempty" example:"grants:read" note:"optional, limits the key to API operations (grants:read, destinations:write), resources (resource:production), or networks (cidr:10.0.0.0/8)"`
 }
 
 func (r CreateAccessKeyRequest) ValidationRules() []validate.ValidationRule {
@@ -46,14 +48,32 @@ func (r CreateAccessKeyRequest) ValidationRules() []validate.ValidationRule {
 }
 
 type CreateAccessKeyResponse struct {
-	ID                uid.ID `json:"id"`
-	Created           Time   `json:"created"`
-	Name              string `json:"name"`
-	IssuedFor         uid.ID `json:"issuedFor"`
-	ProviderID        uid.ID `json:"providerID"`
-	Expires           Time   `json:"expires" note:"after this deadline the key is no longer valid"`
-	ExtensionDeadline Time   `json:"extensionDeadline" note:"the key must be used by this time to remain valid"`
-	AccessKey         string `json:"accessKey"`
+	ID                uid.ID   `json:"id"`
+	Created           Time     `json:"created"`
+	Name              string   `json:"name"`
+	IssuedFor         uid.ID   `json:"issuedFor"`
+	ProviderID        uid.ID   `json:"providerID"`
+	Expires           Time     `json:"expires" note:"after this deadline the key is no longer valid"`
+	ExtensionDeadline Time     `json:"extensionDeadline" note:"the key must be used by this time to remain valid"`
+	Scopes            []string `json:"scopes,omitempty"`
+	AccessKey         string   `json:"accessKey"`
+}
+
+// ResourceRequest is a request for specific resources. Access keys which are
+// scoped to resources can only be used for these requests.
+type ResourceRequest interface {
+	ScopeResources() []string
+}
+
+// nonEmpty returns the values which are set, or nil if none are.
+func nonEmpty(values ...string) []string {
+	var result []string
+	for _, v := range values {
+		if v != "" {
+			result = append(result, v)
+		}
+	}
+	return result
 }
 
 // ValidateName returns a standard validation rule for all name fields. The
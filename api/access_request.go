@@ -0,0 +1,49 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type AccessRequest struct {
+	ID              uid.ID   `json:"id"`
+	Created         Time     `json:"created"`
+	RequestedBy     uid.ID   `json:"requestedBy" note:"id of the user that the grant is for"`
+	RequestedByName string   `json:"requestedByName"`
+	Privilege       string   `json:"privilege" example:"admin" note:"a role or permission"`
+	Resource        string   `json:"resource" example:"production" note:"a resource name in Infra's Universal Resource Notation"`
+	Reason          string   `json:"reason" example:"investigating incident 1234"`
+	Duration        Duration `json:"duration" note:"how long the grant is valid for once approved"`
+	Status          string   `json:"status" example:"pending" note:"one of pending, approved, or denied"`
+	DecidedBy       uid.ID   `json:"decidedBy,omitempty" note:"id of the user that approved or denied the request"`
+	Decided         Time     `json:"decided"`
+	GrantID         uid.ID   `json:"grantID,omitempty" note:"id of the grant created when the request was approved"`
+}
+
+type CreateAccessRequestRequest struct {
+	Privilege string   `json:"privilege" example:"admin" note:"a role or permission"`
+	Resource  string   `json:"resource" example:"production" note:"a resource name in Infra's Universal Resource Notation"`
+	Reason    string   `json:"reason" example:"investigating incident 1234"`
+	Duration  Duration `json:"duration" note:"how long the grant is valid for once approved"`
+}
+
+func (r CreateAccessRequestRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("privilege", r.Privilege),
+		validate.Required("resource", r.Resource),
+		validate.Required("reason", r.Reason),
+		validate.Required("duration", r.Duration),
+	}
+}
+
+type ListAccessRequestsRequest struct {
+	User   uid.ID `form:"user" note:"only include requests made by this user"`
+	Status string `form:"status" example:"pending"`
+	PaginationRequest
+}
+
+func (r ListAccessRequestsRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Enum("status", r.Status, []string{"pending", "approved", "denied"}),
+	}
+}
@@ -0,0 +1,97 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type AccessReview struct {
+	ID          uid.ID   `json:"id"`
+	Created     Time     `json:"created"`
+	CreatedBy   uid.ID   `json:"createdBy" note:"id of the admin that created the campaign"`
+	Name        string   `json:"name" example:"2022 Q4 production review"`
+	Destination string   `json:"destination,omitempty" example:"production" note:"the destination whose grants are reviewed"`
+	Group       uid.ID   `json:"group,omitempty" note:"id of the group whose grants, and the grants of its members, are reviewed"`
+	Reviewers   []uid.ID `json:"reviewers" note:"ids of the users who may review the grants, in addition to admins"`
+	Deadline    Time     `json:"deadline"`
+	AutoRevoke  bool     `json:"autoRevoke" note:"if true, grants which have not been reviewed are revoked at the deadline"`
+	Status      string   `json:"status" example:"open" note:"one of open or closed"`
+	Closed      Time     `json:"closed"`
+}
+
+type CreateAccessReviewRequest struct {
+	Name        string   `json:"name" example:"2022 Q4 production review"`
+	Destination string   `json:"destination" example:"production" note:"review the grants which apply to this destination, or any of its namespaces"`
+	Group       uid.ID   `json:"group" note:"review the grants to this group, and to the members of this group"`
+	Reviewers   []uid.ID `json:"reviewers" note:"ids of the users who may review the grants, in addition to admins"`
+	Deadline    Time     `json:"deadline"`
+	AutoRevoke  bool     `json:"autoRevoke" note:"if true, grants which have not been reviewed are revoked at the deadline"`
+}
+
+func (r CreateAccessReviewRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("name", r.Name),
+		validate.RequireOneOf(
+			validate.Field{Name: "destination", Value: r.Destination},
+			validate.Field{Name: "group", Value: r.Group},
+		),
+		validate.MutuallyExclusive(
+			validate.Field{Name: "destination", Value: r.Destination},
+			validate.Field{Name: "group", Value: r.Group},
+		),
+		validate.Required("deadline", r.Deadline),
+	}
+}
+
+type ListAccessReviewsRequest struct {
+	Status string `form:"status" example:"open"`
+	PaginationRequest
+}
+
+func (r ListAccessReviewsRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Enum("status", r.Status, []string{"open", "closed"}),
+	}
+}
+
+type AccessReviewItem struct {
+	ID          uid.ID `json:"id"`
+	Review      uid.ID `json:"review" note:"id of the access review campaign"`
+	Grant       uid.ID `json:"grant" note:"id of the grant being reviewed"`
+	User        uid.ID `json:"user,omitempty"`
+	Group       uid.ID `json:"group,omitempty"`
+	SubjectName string `json:"subjectName" note:"name of the user or group of the grant, when the campaign was created"`
+	Privilege   string `json:"privilege" example:"admin"`
+	Resource    string `json:"resource" example:"production"`
+	Decision    string `json:"decision" example:"pending" note:"one of pending, keep, or revoke"`
+	DecidedBy   uid.ID `json:"decidedBy,omitempty" note:"id of the reviewer, empty when the grant was revoked at the deadline"`
+	Decided     Time   `json:"decided"`
+}
+
+type ListAccessReviewItemsRequest struct {
+	ID       uid.ID `uri:"id" json:"-"`
+	Decision string `form:"decision" example:"pending"`
+	PaginationRequest
+}
+
+func (r ListAccessReviewItemsRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Enum("decision", r.Decision, []string{"pending", "keep", "revoke"}),
+	}
+}
+
+type DecideAccessReviewItemRequest struct {
+	ID       uid.ID `uri:"id" json:"-"`
+	ItemID   uid.ID `uri:"item" json:"-"`
+	Decision string `json:"decision" example:"keep" note:"one of keep or revoke"`
+}
+
+func (r DecideAccessReviewItemRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("item", r.ItemID),
+		validate.Required("decision", r.Decision),
+		validate.Enum("decision", r.Decision, []string{"keep", "revoke"}),
+	}
+}
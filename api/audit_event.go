@@ -0,0 +1,35 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type AuditEvent struct {
+	ID           uid.ID `json:"id"`
+	Created      Time   `json:"created"`
+	ActorID      uid.ID `json:"actorID,omitempty" note:"id of the user that made the request"`
+	ActorName    string `json:"actorName,omitempty"`
+	AccessKeyID  uid.ID `json:"accessKeyID,omitempty" note:"id of the access key used to authenticate the request"`
+	Method       string `json:"method" example:"POST"`
+	Route        string `json:"route" example:"/api/grants/:id"`
+	ResourceType string `json:"resourceType" example:"grants"`
+	ResourceID   uid.ID `json:"resourceID,omitempty"`
+	Before       string `json:"before,omitempty" note:"JSON encoded state of the resource before the request"`
+	After        string `json:"after,omitempty" note:"JSON encoded state of the resource after the request"`
+	StatusCode   int    `json:"statusCode" note:"HTTP status code of the response"`
+}
+
+type ListAuditEventsRequest struct {
+	Actor        uid.ID `form:"actor" note:"only include events performed by this user"`
+	ResourceType string `form:"resourceType" example:"grants"`
+	Since        Time   `form:"since" note:"only include events that happened after this time"`
+	Until        Time   `form:"until" note:"only include events that happened before this time"`
+	PaginationRequest
+}
+
+func (r ListAuditEventsRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
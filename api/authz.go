@@ -0,0 +1,51 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type AuthzCheckRequest struct {
+	User      uid.ID `json:"user"`
+	Group     uid.ID `json:"group"`
+	Privilege string `json:"privilege" example:"view" note:"a role or permission"`
+	Resource  string `json:"resource" example:"production.default" note:"a resource name in Infra's Universal Resource Notation"`
+	ClientIP  string `json:"clientIP" example:"10.0.0.12" note:"optional, the address the subject connects from, which grants with network conditions are checked against"`
+	Time      Time   `json:"time" note:"optional, when the subject connects, which grants with time windows are checked against. Defaults to now"`
+}
+
+func (r AuthzCheckRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.RequireOneOf(
+			validate.Field{Name: "user", Value: r.User},
+			validate.Field{Name: "group", Value: r.Group},
+		),
+		validate.Required("privilege", r.Privilege),
+		validate.Required("resource", r.Resource),
+	}
+}
+
+func (r AuthzCheckRequest) ScopeResources() []string {
+	return []string{r.Resource}
+}
+
+type AuthzCheckResponse struct {
+	Allowed bool          `json:"allowed" note:"true when a grant allows the privilege, and the clientIP and time satisfy its conditions"`
+	Reasons []AuthzReason `json:"reasons" note:"the grants which allow the privilege, including grants with conditions which the clientIP and time do not satisfy"`
+	Groups  []string      `json:"groups" note:"names of the groups of the user, which were checked for grants"`
+}
+
+func (r *AuthzCheckResponse) StatusCode() int {
+	return http.StatusOK
+}
+
+// AuthzReason is a grant which allows the privilege, and how it applies to the
+// subject of the check.
+type AuthzReason struct {
+	Grant Grant  `json:"grant"`
+	Group string   `json:"group,omitempty" note:"the group which has the grant, empty when the grant is to the subject"`
+	Path  []string `json:"path,omitempty" note:"the chain of group memberships from the subject to the group, starting with a group the subject is a direct member of"`
+	Role  string   `json:"role,omitempty" note:"the custom role of the grant which includes the privilege"`
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+)
+
+// CreateCertificateRequest requests a client certificate for the user. The
+// certificate identifies the user, so the subject of the request is ignored.
+type CreateCertificateRequest struct {
+	// CSR is a PEM encoded certificate signing request, signed with the
+	// private key of the certificate
+	CSR string `json:"csr"`
+}
+
+func (r CreateCertificateRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("csr", r.CSR),
+	}
+}
+
+// Certificate is a short-lived client certificate, which is used to login
+// with mutual TLS.
+type Certificate struct {
+	// Certificate is the PEM encoded certificate
+	Certificate string `json:"certificate"`
+	Expires     Time   `json:"expires"`
+}
+
+// CertificateAuthority is a CA which signs client certificates. There are two
+// active CAs while the CA is being rotated.
+type CertificateAuthority struct {
+	// Certificate is the PEM encoded certificate of the CA
+	Certificate string `json:"certificate"`
+	Expires     Time   `json:"expires"`
+}
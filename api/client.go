@@ -186,6 +186,23 @@ func (c Client) DeleteUser(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/users/%s", id))
 }
 
+func (c Client) CreateMFA(req *CreateMFARequest) (*CreateMFAResponse, error) {
+	return post[CreateMFARequest, CreateMFAResponse](c, fmt.Sprintf("/api/users/%s/mfa", req.UserID), req)
+}
+
+func (c Client) VerifyMFA(req *VerifyMFARequest) (*VerifyMFAResponse, error) {
+	return post[VerifyMFARequest, VerifyMFAResponse](c, fmt.Sprintf("/api/users/%s/mfa/verify", req.UserID), req)
+}
+
+func (c Client) DeleteMFA(req *DeleteMFARequest) error {
+	_, err := request[DeleteMFARequest, EmptyResponse](c, http.MethodDelete, fmt.Sprintf("/api/users/%s/mfa", req.UserID), Query{}, req)
+	return err
+}
+
+func (c Client) UnlockUser(id uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/users/%s/lockout", id))
+}
+
 // Deprecated: use ListGrants
 func (c Client) ListUserGrants(id uid.ID) (*ListResponse[Grant], error) {
 	return get[ListResponse[Grant]](c, fmt.Sprintf("/api/users/%s/grants", id), Query{})
@@ -193,8 +210,9 @@ func (c Client) ListUserGrants(id uid.ID) (*ListResponse[Grant], error) {
 
 func (c Client) ListGroups(req ListGroupsRequest) (*ListResponse[Group], error) {
 	return get[ListResponse[Group]](c, "/api/groups", Query{
-		"name":   {req.Name},
-		"userID": {req.UserID.String()},
+		"name":    {req.Name},
+		"userID":  {req.UserID.String()},
+		"groupID": {req.GroupID.String()},
 	})
 }
 
@@ -210,11 +228,20 @@ func (c Client) DeleteGroup(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/groups/%s", id))
 }
 
+func (c Client) UpdateGroup(req *UpdateGroupRequest) (*Group, error) {
+	return put[UpdateGroupRequest, Group](c, fmt.Sprintf("/api/groups/%s", req.ID), req)
+}
+
 func (c Client) UpdateUsersInGroup(req *UpdateUsersInGroupRequest) error {
 	_, err := patch[UpdateUsersInGroupRequest, EmptyResponse](c, fmt.Sprintf("/api/groups/%s/users", req.GroupID), req)
 	return err
 }
 
+func (c Client) UpdateGroupsInGroup(req *UpdateGroupsInGroupRequest) error {
+	_, err := patch[UpdateGroupsInGroupRequest, EmptyResponse](c, fmt.Sprintf("/api/groups/%s/groups", req.GroupID), req)
+	return err
+}
+
 // Deprecated: use ListGrants
 func (c Client) ListGroupGrants(id uid.ID) (*ListResponse[Grant], error) {
 	return get[ListResponse[Grant]](c, fmt.Sprintf("/api/groups/%s/grants", id), Query{})
@@ -236,17 +263,55 @@ func (c Client) UpdateProvider(req UpdateProviderRequest) (*Provider, error) {
 	return put[UpdateProviderRequest, Provider](c, fmt.Sprintf("/api/providers/%s", req.ID.String()), &req)
 }
 
+func (c Client) CreateProviderSCIMKey(req *CreateProviderSCIMKeyRequest) (*CreateAccessKeyResponse, error) {
+	return post[CreateProviderSCIMKeyRequest, CreateAccessKeyResponse](c, fmt.Sprintf("/api/providers/%s/scim-keys", req.ID), req)
+}
+
 func (c Client) DeleteProvider(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/providers/%s", id))
 }
 
+func (c Client) ListOIDCClients(req ListOIDCClientsRequest) (*ListResponse[OIDCClient], error) {
+	return get[ListResponse[OIDCClient]](c, "/api/oidc-clients", Query{"name": {req.Name}})
+}
+
+func (c Client) CreateOIDCClient(req *CreateOIDCClientRequest) (*CreateOIDCClientResponse, error) {
+	return post[CreateOIDCClientRequest, CreateOIDCClientResponse](c, "/api/oidc-clients", req)
+}
+
+func (c Client) DeleteOIDCClient(id uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/oidc-clients/%s", id))
+}
+
+func (c Client) ListRoles(req ListRolesRequest) (*ListResponse[Role], error) {
+	return get[ListResponse[Role]](c, "/api/roles", Query{"name": {req.Name}})
+}
+
+func (c Client) GetRole(id uid.ID) (*Role, error) {
+	return get[Role](c, fmt.Sprintf("/api/roles/%s", id), Query{})
+}
+
+func (c Client) CreateRole(req *CreateRoleRequest) (*Role, error) {
+	return post[CreateRoleRequest, Role](c, "/api/roles", req)
+}
+
+func (c Client) UpdateRole(req UpdateRoleRequest) (*Role, error) {
+	return put[UpdateRoleRequest, Role](c, fmt.Sprintf("/api/roles/%s", req.ID.String()), &req)
+}
+
+func (c Client) DeleteRole(id uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/roles/%s", id))
+}
+
 func (c Client) ListGrants(req ListGrantsRequest) (*ListResponse[Grant], error) {
 	return get[ListResponse[Grant]](c, "/api/grants", Query{
 		"user":          {req.User.String()},
 		"group":         {req.Group.String()},
 		"resource":      {req.Resource},
+		"destination":   {req.Destination},
 		"privilege":     {req.Privilege},
 		"showInherited": {strconv.FormatBool(req.ShowInherited)},
+		"showPending":   {strconv.FormatBool(req.ShowPending)},
 	})
 }
 
@@ -258,9 +323,14 @@ func (c Client) DeleteGrant(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/grants/%s", id))
 }
 
+func (c Client) CheckAuthorization(req *AuthzCheckRequest) (*AuthzCheckResponse, error) {
+	return post[AuthzCheckRequest, AuthzCheckResponse](c, "/api/authz/check", req)
+}
+
 func (c Client) ListDestinations(req ListDestinationsRequest) (*ListResponse[Destination], error) {
 	return get[ListResponse[Destination]](c, "/api/destinations", Query{
 		"name":      {req.Name},
+		"kind":      {req.Kind},
 		"unique_id": {req.UniqueID},
 	})
 }
@@ -277,6 +347,29 @@ func (c Client) DeleteDestination(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/destinations/%s", id))
 }
 
+func (c Client) CreateSSHCertificate(req *CreateSSHCertificateRequest) (*SSHCertificate, error) {
+	return post[CreateSSHCertificateRequest, SSHCertificate](c, fmt.Sprintf("/api/destinations/%s/ssh-certificates", req.ID), req)
+}
+
+func (c Client) GetSSHCertificateAuthority() (*SSHCertificateAuthority, error) {
+	return get[SSHCertificateAuthority](c, "/api/ssh-ca", Query{})
+}
+
+func (c Client) CreateCertificate(req *CreateCertificateRequest) (*Certificate, error) {
+	return post[CreateCertificateRequest, Certificate](c, "/api/certificates", req)
+}
+
+func (c Client) ListCertificateAuthorities() (*ListResponse[CertificateAuthority], error) {
+	return get[ListResponse[CertificateAuthority]](c, "/api/certificate-authorities", Query{})
+}
+
+// CreateDatabaseCredentials requests credentials from the connector of a
+// postgres destination. The URL of the client is the connector, and the access
+// key is a token from CreateToken.
+func (c Client) CreateDatabaseCredentials() (*DatabaseCredentials, error) {
+	return post[EmptyRequest, DatabaseCredentials](c, "/postgres/credentials", &EmptyRequest{})
+}
+
 func (c Client) ListAccessKeys(req ListAccessKeysRequest) (*ListResponse[AccessKey], error) {
 	return get[ListResponse[AccessKey]](c, "/api/access-keys", Query{
 		"user_id":      {req.UserID.String()},
@@ -293,6 +386,89 @@ func (c Client) DeleteAccessKey(id uid.ID) error {
 	return delete(c, fmt.Sprintf("/api/access-keys/%s", id))
 }
 
+func (c Client) ListSessions(userID uid.ID) (*ListResponse[Session], error) {
+	return get[ListResponse[Session]](c, fmt.Sprintf("/api/users/%s/sessions", userID), Query{})
+}
+
+func (c Client) DeleteSession(userID, sessionID uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/users/%s/sessions/%s", userID, sessionID))
+}
+
+// DeleteSessions logs the user out everywhere, by revoking all of their
+// sessions.
+func (c Client) DeleteSessions(userID uid.ID) error {
+	return delete(c, fmt.Sprintf("/api/users/%s/sessions", userID))
+}
+
+func (c Client) ListAuditEvents(req ListAuditEventsRequest) (*ListResponse[AuditEvent], error) {
+	query := Query{
+		"actor":        {req.Actor.String()},
+		"resourceType": {req.ResourceType},
+		"page":         {strconv.Itoa(req.Page)},
+		"limit":        {strconv.Itoa(req.Limit)},
+	}
+	if !req.Since.Time().IsZero() {
+		query["since"] = []string{req.Since.String()}
+	}
+	if !req.Until.Time().IsZero() {
+		query["until"] = []string{req.Until.String()}
+	}
+	return get[ListResponse[AuditEvent]](c, "/api/audit-events", query)
+}
+
+func (c Client) ListAccessRequests(req ListAccessRequestsRequest) (*ListResponse[AccessRequest], error) {
+	return get[ListResponse[AccessRequest]](c, "/api/access-requests", Query{
+		"user":   {req.User.String()},
+		"status": {req.Status},
+		"page":   {strconv.Itoa(req.Page)},
+		"limit":  {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetAccessRequest(id uid.ID) (*AccessRequest, error) {
+	return get[AccessRequest](c, fmt.Sprintf("/api/access-requests/%s", id), Query{})
+}
+
+func (c Client) CreateAccessRequest(req *CreateAccessRequestRequest) (*AccessRequest, error) {
+	return post[CreateAccessRequestRequest, AccessRequest](c, "/api/access-requests", req)
+}
+
+func (c Client) ApproveAccessRequest(id uid.ID) (*AccessRequest, error) {
+	return post[EmptyRequest, AccessRequest](c, fmt.Sprintf("/api/access-requests/%s/approve", id), &EmptyRequest{})
+}
+
+func (c Client) DenyAccessRequest(id uid.ID) (*AccessRequest, error) {
+	return post[EmptyRequest, AccessRequest](c, fmt.Sprintf("/api/access-requests/%s/deny", id), &EmptyRequest{})
+}
+
+func (c Client) ListAccessReviews(req ListAccessReviewsRequest) (*ListResponse[AccessReview], error) {
+	return get[ListResponse[AccessReview]](c, "/api/access-reviews", Query{
+		"status": {req.Status},
+		"page":   {strconv.Itoa(req.Page)},
+		"limit":  {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) GetAccessReview(id uid.ID) (*AccessReview, error) {
+	return get[AccessReview](c, fmt.Sprintf("/api/access-reviews/%s", id), Query{})
+}
+
+func (c Client) CreateAccessReview(req *CreateAccessReviewRequest) (*AccessReview, error) {
+	return post[CreateAccessReviewRequest, AccessReview](c, "/api/access-reviews", req)
+}
+
+func (c Client) ListAccessReviewItems(req ListAccessReviewItemsRequest) (*ListResponse[AccessReviewItem], error) {
+	return get[ListResponse[AccessReviewItem]](c, fmt.Sprintf("/api/access-reviews/%s/items", req.ID), Query{
+		"decision": {req.Decision},
+		"page":     {strconv.Itoa(req.Page)},
+		"limit":    {strconv.Itoa(req.Limit)},
+	})
+}
+
+func (c Client) DecideAccessReviewItem(req *DecideAccessReviewItemRequest) (*AccessReviewItem, error) {
+	return put[DecideAccessReviewItemRequest, AccessReviewItem](c, fmt.Sprintf("/api/access-reviews/%s/items/%s", req.ID, req.ItemID), req)
+}
+
 func (c Client) CreateToken() (*CreateTokenResponse, error) {
 	return post[EmptyRequest, CreateTokenResponse](c, "/api/tokens", &EmptyRequest{})
 }
@@ -306,6 +482,15 @@ func (c Client) Logout() error {
 	return err
 }
 
+func (c Client) StartDeviceFlow() (*StartDeviceFlowResponse, error) {
+	return post[EmptyRequest, StartDeviceFlowResponse](c, "/api/device", &EmptyRequest{})
+}
+
+func (c Client) ApproveDeviceFlow(req *ApproveDeviceFlowRequest) error {
+	_, err := post[ApproveDeviceFlowRequest, EmptyResponse](c, "/api/device/approve", req)
+	return err
+}
+
 func (c Client) SignupEnabled() (*SignupEnabledResponse, error) {
 	return get[SignupEnabledResponse](c, "/api/signup", Query{})
 }
@@ -9,6 +9,7 @@ type Destination struct {
 	ID         uid.ID                `json:"id"`
 	UniqueID   string                `json:"uniqueID" form:"uniqueID" example:"94c2c570a20311180ec325fd56"`
 	Name       string                `json:"name" form:"name"`
+	Kind       string                `json:"kind" example:"kubernetes"`
 	Created    Time                  `json:"created"`
 	Updated    Time                  `json:"updated"`
 	Connection DestinationConnection `json:"connection"`
@@ -22,6 +23,16 @@ type Destination struct {
 	Version string `json:"version"`
 }
 
+// destinationKinds are the kinds of destinations. A destination without a kind
+// is a kubernetes cluster.
+var destinationKinds = []string{"kubernetes", "ssh", "postgres", "http"}
+
+// DestinationConnection is how clients connect to a destination. For an ssh
+// destination the URL is the address of the SSH server, and the CA is its
+// public host key in the authorized_keys format. For a postgres destination
+// the URL is the address of the connector which issues database credentials,
+// and for an http destination it is the address that browsers use to reach
+// the connector.
 type DestinationConnection struct {
 	URL string `json:"url" example:"aa60eexample.us-west-2.elb.amazonaws.com"`
 	CA  PEM    `json:"ca" example:"-----BEGIN CERTIFICATE-----\nMIIDNTCCAh2gAwIBAgIRALRetnpcTo9O3V2fAK3ix+c\n-----END CERTIFICATE-----\n"`
@@ -35,6 +46,7 @@ func (r DestinationConnection) ValidationRules() []validate.ValidationRule {
 
 type ListDestinationsRequest struct {
 	Name     string `form:"name"`
+	Kind     string `form:"kind"`
 	UniqueID string `form:"unique_id"`
 	PaginationRequest
 }
@@ -45,9 +57,14 @@ func (r ListDestinationsRequest) ValidationRules() []validate.ValidationRule {
 	return nil
 }
 
+func (r ListDestinationsRequest) ScopeResources() []string {
+	return nonEmpty(r.Name)
+}
+
 type CreateDestinationRequest struct {
 	UniqueID   string                `json:"uniqueID"`
 	Name       string                `json:"name"`
+	Kind       string                `json:"kind"`
 	Version    string                `json:"version"`
 	Connection DestinationConnection `json:"connection"`
 
@@ -60,12 +77,18 @@ func (r CreateDestinationRequest) ValidationRules() []validate.ValidationRule {
 		validate.Required("uniqueID", r.UniqueID),
 		ValidateName(r.Name),
 		validate.Required("name", r.Name),
+		validate.Enum("kind", r.Kind, destinationKinds),
 	}
 }
 
+func (r CreateDestinationRequest) ScopeResources() []string {
+	return []string{r.Name}
+}
+
 type UpdateDestinationRequest struct {
 	ID         uid.ID                `uri:"id" json:"-"`
 	Name       string                `json:"name"`
+	Kind       string                `json:"kind"`
 	UniqueID   string                `json:"uniqueID"`
 	Version    string                `json:"version"`
 	Connection DestinationConnection `json:"connection"`
@@ -80,5 +103,50 @@ func (r UpdateDestinationRequest) ValidationRules() []validate.ValidationRule {
 		validate.Required("id", r.ID),
 		validate.Required("name", r.Name),
 		ValidateName(r.Name),
+		validate.Enum("kind", r.Kind, destinationKinds),
+	}
+}
+
+func (r UpdateDestinationRequest) ScopeResources() []string {
+	return []string{r.Name}
+}
+
+type CreateSSHCertificateRequest struct {
+	ID        uid.ID `uri:"id" json:"-"`
+	PublicKey string `json:"publicKey" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIK2CqbPkbRNY6bxLHcpBzSoHlR3wJwBP8yNDRjXbY5l0"`
+}
+
+func (r CreateSSHCertificateRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("publicKey", r.PublicKey),
 	}
 }
+
+// SSHCertificate is a short-lived SSH user certificate for a destination.
+type SSHCertificate struct {
+	// Certificate is the signed certificate in the authorized_keys format
+	Certificate string `json:"certificate"`
+	// Principals are the users on the host that the certificate can log in as
+	Principals []string `json:"principals" example:"ubuntu"`
+	Expires    Time     `json:"expires"`
+}
+
+// SSHCertificateAuthority is the public key which signs SSH user certificates.
+// Hosts trust certificates signed by this key.
+type SSHCertificateAuthority struct {
+	PublicKey string `json:"publicKey" example:"ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIK2CqbPkbRNY6bxLHcpBzSoHlR3wJwBP8yNDRjXbY5l0"`
+}
+
+// DatabaseCredentials are the credentials of a short-lived login role, issued
+// by the connector of a postgres destination.
+type DatabaseCredentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Host     string `json:"host"`
+	Port     string `json:"port"`
+	Database string `json:"database"`
+	// Roles are the database roles that the login role is a member of
+	Roles   []string `json:"roles"`
+	Expires Time     `json:"expires"`
+}
@@ -0,0 +1,29 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+)
+
+type StartDeviceFlowResponse struct {
+	// DeviceCode is a secret which is sent to /api/login once the user has
+	// approved the login.
+	DeviceCode string `json:"deviceCode"`
+	// UserCode is shown to the user, who enters it at the VerificationURI to
+	// approve the login.
+	UserCode        string `json:"userCode"`
+	VerificationURI string `json:"verificationURI"`
+	// PollIntervalSeconds is the minimum time to wait between login attempts
+	// with the device code.
+	PollIntervalSeconds int  `json:"pollIntervalSeconds"`
+	Expires             Time `json:"expires"`
+}
+
+type ApproveDeviceFlowRequest struct {
+	UserCode string `json:"userCode"`
+}
+
+func (r ApproveDeviceFlowRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("userCode", r.UserCode),
+	}
+}
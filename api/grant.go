@@ -17,7 +17,27 @@ type Grant struct {
 	User      uid.ID `json:"user,omitempty"`
 	Group     uid.ID `json:"group,omitempty"`
 	Privilege string `json:"privilege" note:"a role or permission"`
-	Resource  string `json:"resource" note:"a resource name in Infra's Universal Resource Notation"`
+	Resource  string `json:"resource" note:"a resource name in Infra's Universal Resource Notation, may use * as a wildcard within a segment"`
+	NotBefore Time   `json:"notBefore" note:"the grant has no effect before this time"`
+	ExpiresAt Time   `json:"expiresAt" note:"the grant has no effect after this time"`
+
+	Conditions *GrantConditions `json:"conditions,omitempty" note:"the grant only applies to requests which satisfy these conditions"`
+}
+
+// GrantConditions limit a grant to requests from some networks, or made during
+// some times. When both are set, a request must satisfy both.
+type GrantConditions struct {
+	CIDRs   []string     `json:"cidrs,omitempty" example:"10.0.0.0/8" note:"the grant applies to requests from any of these networks"`
+	Windows []TimeWindow `json:"windows,omitempty" note:"the grant applies to requests made during any of these windows"`
+}
+
+// TimeWindow is a time of day on some days of the week. A window with an end
+// before its start ends on the next day.
+type TimeWindow struct {
+	Days     []string `json:"days,omitempty" example:"mon" note:"the days the window starts on, as mon to sun, every day when empty"`
+	Start    string   `json:"start" example:"09:00"`
+	End      string   `json:"end" example:"17:00"`
+	Timezone string   `json:"timezone,omitempty" example:"America/New_York" note:"an IANA timezone, defaults to UTC"`
 }
 
 type CreateGrantResponse struct {
@@ -35,9 +55,11 @@ func (r *CreateGrantResponse) StatusCode() int {
 type ListGrantsRequest struct {
 	User          uid.ID `form:"user"`
 	Group         uid.ID `form:"group"`
-	Resource      string `form:"resource" example:"production"`
+	Resource      string `form:"resource" example:"production" note:"includes grants with a wildcard resource that matches this resource"`
+	Destination   string `form:"destination" example:"production" note:"grants that apply to this destination, or any of its namespaces"`
 	Privilege     string `form:"privilege" example:"view"`
 	ShowInherited bool   `form:"showInherited" note:"if true, this field includes grants that the user inherits through groups"`
+	ShowPending   bool   `form:"showPending" note:"if true, this field includes grants that are not in effect yet"`
 	PaginationRequest
 }
 
@@ -47,14 +69,26 @@ func (r ListGrantsRequest) ValidationRules() []validate.ValidationRule {
 			validate.Field{Name: "user", Value: r.User},
 			validate.Field{Name: "group", Value: r.Group},
 		),
+		validate.MutuallyExclusive(
+			validate.Field{Name: "resource", Value: r.Resource},
+			validate.Field{Name: "destination", Value: r.Destination},
+		),
 	}
 }
 
+func (r ListGrantsRequest) ScopeResources() []string {
+	return nonEmpty(r.Resource, r.Destination)
+}
+
 type CreateGrantRequest struct {
 	User      uid.ID `json:"user"`
 	Group     uid.ID `json:"group"`
 	Privilege string `json:"privilege" example:"view" note:"a role or permission"`
-	Resource  string `json:"resource" example:"production" note:"a resource name in Infra's Universal Resource Notation"`
+	Resource  string `json:"resource" example:"production" note:"a resource name in Infra's Universal Resource Notation, may use * as a wildcard within a segment"`
+	NotBefore Time   `json:"notBefore" note:"optional, the grant has no effect before this time"`
+	ExpiresAt Time   `json:"expiresAt" note:"optional, the grant has no effect after this time"`
+
+	Conditions *GrantConditions `json:"conditions" note:"optional, the grant only applies to requests which satisfy these conditions"`
 }
 
 func (r CreateGrantRequest) ValidationRules() []validate.ValidationRule {
@@ -67,3 +101,7 @@ func (r CreateGrantRequest) ValidationRules() []validate.ValidationRule {
 		validate.Required("resource", r.Resource),
 	}
 }
+
+func (r CreateGrantRequest) ScopeResources() []string {
+	return []string{r.Resource}
+}
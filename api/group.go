@@ -10,6 +10,9 @@ type Group struct {
 	Name    string `json:"name"`
 	Created Time   `json:"created"`
 	Updated Time   `json:"updated"`
+	// RequireMFA requires members of the group, who login with a password, to
+	// enroll in multi-factor authentication.
+	RequireMFA bool `json:"requireMFA"`
 }
 
 type ListGroupsRequest struct {
@@ -17,6 +20,8 @@ type ListGroupsRequest struct {
 	Name string `form:"name"`
 	// UserID filters the results to only groups where this user is a member.
 	UserID uid.ID `form:"userID"`
+	// GroupID filters the results to only groups which are members of this group.
+	GroupID uid.ID `form:"groupID"`
 	PaginationRequest
 }
 
@@ -27,7 +32,8 @@ func (r ListGroupsRequest) ValidationRules() []validate.ValidationRule {
 }
 
 type CreateGroupRequest struct {
-	Name string `json:"name"`
+	Name       string `json:"name"`
+	RequireMFA bool   `json:"requireMFA"`
 }
 
 func (r CreateGroupRequest) ValidationRules() []validate.ValidationRule {
@@ -37,6 +43,17 @@ func (r CreateGroupRequest) ValidationRules() []validate.ValidationRule {
 	}
 }
 
+type UpdateGroupRequest struct {
+	ID         uid.ID `uri:"id" json:"-"`
+	RequireMFA bool   `json:"requireMFA"`
+}
+
+func (r UpdateGroupRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+	}
+}
+
 type UpdateUsersInGroupRequest struct {
 	GroupID         uid.ID   `uri:"id" json:"-"`
 	UserIDsToAdd    []uid.ID `json:"usersToAdd"`
@@ -48,3 +65,15 @@ func (r UpdateUsersInGroupRequest) ValidationRules() []validate.ValidationRule {
 		validate.Required("id", r.GroupID),
 	}
 }
+
+type UpdateGroupsInGroupRequest struct {
+	GroupID          uid.ID   `uri:"id" json:"-"`
+	GroupIDsToAdd    []uid.ID `json:"groupsToAdd"`
+	GroupIDsToRemove []uid.ID `json:"groupsToRemove"`
+}
+
+func (r UpdateGroupsInGroupRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.GroupID),
+	}
+}
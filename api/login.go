@@ -35,10 +35,68 @@ func (r LoginRequestPasswordCredentials) ValidationRules() []validate.Validation
 	}
 }
 
+// LoginRequestLDAP is a login with the username and password of a user in the
+// directory of an LDAP provider.
+type LoginRequestLDAP struct {
+	ProviderID uid.ID `json:"providerID"`
+	Name       string `json:"name"`
+	Password   string `json:"password"`
+}
+
+func (r LoginRequestLDAP) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("providerID", r.ProviderID),
+		validate.Required("name", r.Name),
+		validate.Required("password", r.Password),
+	}
+}
+
+// LoginRequestSAML is a login with the code from a SAML login, which the
+// client receives at its redirect URL once the identity provider responds.
+type LoginRequestSAML struct {
+	ProviderID uid.ID `json:"providerID"`
+	Code       string `json:"code"`
+}
+
+func (r LoginRequestSAML) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("providerID", r.ProviderID),
+		validate.Required("code", r.Code),
+	}
+}
+
+// LoginRequestMFA completes a password login of a user who is enrolled in
+// multi-factor authentication.
+type LoginRequestMFA struct {
+	// Token is the MFA token from the response to the password login.
+	Token string `json:"token"`
+	// Code is a TOTP code, or one of the recovery codes of the user.
+	Code string `json:"code"`
+}
+
+func (r LoginRequestMFA) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("token", r.Token),
+		validate.Required("code", r.Code),
+	}
+}
+
 type LoginRequest struct {
 	AccessKey           string                           `json:"accessKey"`
 	PasswordCredentials *LoginRequestPasswordCredentials `json:"passwordCredentials"`
 	OIDC                *LoginRequestOIDC                `json:"oidc"`
+	LDAP                *LoginRequestLDAP                `json:"ldap"`
+	SAML                *LoginRequestSAML                `json:"saml"`
+	MFA                 *LoginRequestMFA                 `json:"mfa"`
+	// DeviceCode is the device code from /api/device, which completes the
+	// login once the user has approved it.
+	DeviceCode string `json:"deviceCode"`
+	// WorkloadToken is an OIDC token of a workload, such as a CI job, signed
+	// by an issuer which is trusted by the server.
+	WorkloadToken string `json:"workloadToken"`
+	// ClientCertificate logs in with the client certificate of the TLS
+	// connection, which must be signed by the server.
+	ClientCertificate bool `json:"clientCertificate"`
 }
 
 func (r LoginRequest) ValidationRules() []validate.ValidationRule {
@@ -47,6 +105,12 @@ func (r LoginRequest) ValidationRules() []validate.ValidationRule {
 			validate.Field{Name: "accessKey", Value: r.AccessKey},
 			validate.Field{Name: "passwordCredentials", Value: r.PasswordCredentials},
 			validate.Field{Name: "oidc", Value: r.OIDC},
+			validate.Field{Name: "ldap", Value: r.LDAP},
+			validate.Field{Name: "saml", Value: r.SAML},
+			validate.Field{Name: "mfa", Value: r.MFA},
+			validate.Field{Name: "deviceCode", Value: r.DeviceCode},
+			validate.Field{Name: "workloadToken", Value: r.WorkloadToken},
+			validate.Field{Name: "clientCertificate", Value: r.ClientCertificate},
 		),
 	}
 }
@@ -57,4 +121,13 @@ type LoginResponse struct {
 	AccessKey              string `json:"accessKey"`
 	PasswordUpdateRequired bool   `json:"passwordUpdateRequired,omitempty"`
 	Expires                Time   `json:"expires"`
+
+	// MFAToken is set instead of AccessKey when the user is enrolled in
+	// multi-factor authentication. The login is completed by a second login
+	// request with the token and a code.
+	MFAToken string `json:"mfaToken,omitempty"`
+	// MFAEnrollmentRequired is set when the user is a member of a group which
+	// requires MFA, and has not enrolled yet. The access key can only be used
+	// to enroll.
+	MFAEnrollmentRequired bool `json:"mfaEnrollmentRequired,omitempty"`
 }
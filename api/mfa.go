@@ -0,0 +1,56 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type CreateMFARequest struct {
+	UserID uid.ID `uri:"id" json:"-"`
+}
+
+func (r CreateMFARequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.UserID),
+	}
+}
+
+type CreateMFAResponse struct {
+	// Secret is the TOTP secret, for authenticator apps which can not scan
+	// the provisioning URI.
+	Secret string `json:"secret"`
+	// ProvisioningURI is an otpauth:// URI, usually shown as a QR code, which
+	// adds the secret to an authenticator app.
+	ProvisioningURI string `json:"provisioningURI"`
+}
+
+type VerifyMFARequest struct {
+	UserID uid.ID `uri:"id" json:"-"`
+	Code   string `json:"code"`
+}
+
+func (r VerifyMFARequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.UserID),
+		validate.Required("code", r.Code),
+	}
+}
+
+type DeleteMFARequest struct {
+	UserID uid.ID `uri:"id" json:"-"`
+	// Code is a TOTP code, or a recovery code. It is required to disable mfa
+	// for yourself.
+	Code string `json:"code"`
+}
+
+func (r DeleteMFARequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.UserID),
+	}
+}
+
+type VerifyMFAResponse struct {
+	// RecoveryCodes can each be used once to login instead of a TOTP code.
+	// They are only shown once.
+	RecoveryCodes []string `json:"recoveryCodes"`
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// OIDCClient is an application which trusts Infra as its OpenID Connect
+// identity provider.
+type OIDCClient struct {
+	ID               uid.ID   `json:"id"`
+	Created          Time     `json:"created"`
+	Name             string   `json:"name" example:"kubernetes"`
+	ClientID         string   `json:"clientID" example:"q9ZbzgJVNAQUp2mXEk7ueR4a"`
+	RedirectURIs     []string `json:"redirectURIs" example:"['http://localhost:8000']"`
+	SigningAlgorithm string   `json:"signingAlgorithm" example:"RS256"`
+	Public           bool     `json:"public" note:"public clients have no secret, and must use PKCE"`
+}
+
+type ListOIDCClientsRequest struct {
+	Name string `form:"name" example:"kubernetes"`
+	PaginationRequest
+}
+
+func (r ListOIDCClientsRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
+
+var signingAlgorithms = []string{"RS256", "ES256", "EdDSA"}
+
+type CreateOIDCClientRequest struct {
+	Name             string   `json:"name" example:"kubernetes"`
+	RedirectURIs     []string `json:"redirectURIs" example:"['http://localhost:8000']"`
+	SigningAlgorithm string   `json:"signingAlgorithm" example:"RS256" note:"algorithm of the tokens issued to the client, defaults to RS256"`
+	Public           bool     `json:"public" note:"create a client without a secret, such as a CLI, which must use PKCE"`
+}
+
+func (r CreateOIDCClientRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		ValidateName(r.Name),
+		validate.Required("name", r.Name),
+		validate.Required("redirectURIs", r.RedirectURIs),
+		validate.Enum("signingAlgorithm", r.SigningAlgorithm, signingAlgorithms),
+	}
+}
+
+type CreateOIDCClientResponse struct {
+	ID               uid.ID   `json:"id"`
+	Created          Time     `json:"created"`
+	Name             string   `json:"name" example:"kubernetes"`
+	ClientID         string   `json:"clientID" example:"q9ZbzgJVNAQUp2mXEk7ueR4a"`
+	ClientSecret     string   `json:"clientSecret,omitempty" note:"only returned when the client is created, empty for public clients"`
+	RedirectURIs     []string `json:"redirectURIs" example:"['http://localhost:8000']"`
+	SigningAlgorithm string   `json:"signingAlgorithm" example:"RS256"`
+	Public           bool     `json:"public"`
+}
@@ -17,44 +17,91 @@ type Provider struct {
 	Scopes   []string `json:"scopes" example:"['openid', 'email']"`
 }
 
+// ProviderLDAP is the directory configuration of an LDAP provider, such as
+// Active Directory.
+type ProviderLDAP struct {
+	BindDN       string `json:"bindDN" example:"cn=infra,ou=services,dc=example,dc=com"`
+	BindPassword string `json:"bindPassword" example:"password"`
+	SearchBase   string `json:"searchBase" example:"dc=example,dc=com"`
+	UserFilter   string `json:"userFilter" example:"(uid=%s)" note:"%s is replaced by the username, defaults to (uid=%s)"`
+	GroupFilter  string `json:"groupFilter" example:"(member=%s)" note:"%s is replaced by the DN of the user, defaults to (member=%s)"`
+}
+
+func (r ProviderLDAP) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("bindDN", r.BindDN),
+		validate.Required("bindPassword", r.BindPassword),
+		validate.Required("searchBase", r.SearchBase),
+	}
+}
+
+// ProviderSAML is the configuration of a SAML provider. The metadata of the
+// identity provider is downloaded from the url of the provider, unless the
+// metadata is included.
+type ProviderSAML struct {
+	Metadata        string `json:"metadata" note:"XML metadata of the identity provider, downloaded from the url when not set"`
+	GroupsAttribute string `json:"groupsAttribute" example:"groups" note:"name of the assertion attribute which lists the groups of the user, defaults to groups"`
+}
+
 type CreateProviderRequest struct {
-	Name         string `json:"name" example:"okta"`
-	URL          string `json:"url" example:"infrahq.okta.com"`
-	ClientID     string `json:"clientID" example:"0oapn0qwiQPiMIyR35d6"`
-	ClientSecret string `json:"clientSecret" example:"jmda5eG93ax3jMDxTGrbHd_TBGT6kgNZtrCugLbU"`
-	Kind         string `json:"kind" example:"oidc"`
+	Name         string        `json:"name" example:"okta"`
+	URL          string        `json:"url" example:"infrahq.okta.com"`
+	ClientID     string        `json:"clientID" example:"0oapn0qwiQPiMIyR35d6"`
+	ClientSecret string        `json:"clientSecret" example:"jmda5eG93ax3jMDxTGrbHd_TBGT6kgNZtrCugLbU"`
+	Kind         string        `json:"kind" example:"oidc"`
+	LDAP         *ProviderLDAP `json:"ldap"`
+	SAML         *ProviderSAML `json:"saml"`
 }
 
-var kinds = []string{"oidc", "okta", "azure", "google"}
+var kinds = []string{"oidc", "okta", "azure", "google", "ldap", "saml"}
 
 func (r CreateProviderRequest) ValidationRules() []validate.ValidationRule {
-	return []validate.ValidationRule{
+	rules := []validate.ValidationRule{
 		validate.Required("name", r.Name),
 		validate.Required("url", r.URL),
-		validate.Required("clientID", r.ClientID),
-		validate.Required("clientSecret", r.ClientSecret),
-		validate.Enum("kind", r.Kind, kinds),
+	}
+	rules = append(rules, providerKindRules(r.Kind, r.ClientID, r.ClientSecret, r.LDAP)...)
+	return append(rules, validate.Enum("kind", r.Kind, kinds))
+}
+
+// providerKindRules returns the rules for the fields which depend on the kind
+// of the provider. An LDAP provider requires the ldap configuration instead of
+// an OIDC client, and a SAML provider does not use an OIDC client.
+func providerKindRules(kind, clientID, clientSecret string, ldap *ProviderLDAP) []validate.ValidationRule {
+	switch kind {
+	case "ldap":
+		return []validate.ValidationRule{
+			validate.Required("ldap", ldap),
+		}
+	case "saml":
+		return nil
+	}
+
+	return []validate.ValidationRule{
+		validate.Required("clientID", clientID),
+		validate.Required("clientSecret", clientSecret),
 	}
 }
 
 type UpdateProviderRequest struct {
-	ID           uid.ID `uri:"id" json:"-"`
-	Name         string `json:"name" example:"okta"`
-	URL          string `json:"url" example:"infrahq.okta.com"`
-	ClientID     string `json:"clientID" example:"0oapn0qwiQPiMIyR35d6"`
-	ClientSecret string `json:"clientSecret" example:"jmda5eG93ax3jMDxTGrbHd_TBGT6kgNZtrCugLbU"`
-	Kind         string `json:"kind" example:"oidc"`
+	ID           uid.ID        `uri:"id" json:"-"`
+	Name         string        `json:"name" example:"okta"`
+	URL          string        `json:"url" example:"infrahq.okta.com"`
+	ClientID     string        `json:"clientID" example:"0oapn0qwiQPiMIyR35d6"`
+	ClientSecret string        `json:"clientSecret" example:"jmda5eG93ax3jMDxTGrbHd_TBGT6kgNZtrCugLbU"`
+	Kind         string        `json:"kind" example:"oidc"`
+	LDAP         *ProviderLDAP `json:"ldap"`
+	SAML         *ProviderSAML `json:"saml"`
 }
 
 func (r UpdateProviderRequest) ValidationRules() []validate.ValidationRule {
-	return []validate.ValidationRule{
+	rules := []validate.ValidationRule{
 		validate.Required("id", r.ID),
 		validate.Required("name", r.Name),
 		validate.Required("url", r.URL),
-		validate.Required("clientID", r.ClientID),
-		validate.Required("clientSecret", r.ClientSecret),
-		validate.Enum("kind", r.Kind, kinds),
 	}
+	rules = append(rules, providerKindRules(r.Kind, r.ClientID, r.ClientSecret, r.LDAP)...)
+	return append(rules, validate.Enum("kind", r.Kind, kinds))
 }
 
 type ListProvidersRequest struct {
@@ -67,3 +114,15 @@ func (r ListProvidersRequest) ValidationRules() []validate.ValidationRule {
 	// embedded PaginationRequest struct are not applied twice.
 	return nil
 }
+
+type CreateProviderSCIMKeyRequest struct {
+	ID  uid.ID   `uri:"id" json:"-"`
+	TTL Duration `json:"ttl" note:"maximum time valid"`
+}
+
+func (r CreateProviderSCIMKeyRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("ttl", r.TTL),
+	}
+}
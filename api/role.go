@@ -0,0 +1,98 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+type Role struct {
+	ID          uid.ID   `json:"id"`
+	Created     Time     `json:"created"`
+	Updated     Time     `json:"updated"`
+	Name        string   `json:"name" example:"grant-manager"`
+	Description string   `json:"description" example:"Manages grants to destinations"`
+	Permissions []string `json:"permissions" example:"grants:read,grants:create" note:"Infra API permissions, builtin roles, or destination privileges"`
+}
+
+type ListRolesRequest struct {
+	Name string `form:"name" example:"grant-manager"`
+	PaginationRequest
+}
+
+func (r ListRolesRequest) ValidationRules() []validate.ValidationRule {
+	// no-op ValidationRules implementation so that the rules from the
+	// embedded PaginationRequest struct are not applied twice.
+	return nil
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" example:"grant-manager"`
+	Description string   `json:"description" example:"Manages grants to destinations"`
+	Permissions []string `json:"permissions" example:"grants:read,grants:create" note:"Infra API permissions, builtin roles, or destination privileges"`
+}
+
+func (r CreateRoleRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		ValidateName(r.Name),
+		validate.Required("name", r.Name),
+		validate.Required("permissions", r.Permissions),
+		permissionsRule(r.Permissions),
+	}
+}
+
+type UpdateRoleRequest struct {
+	ID          uid.ID   `uri:"id" json:"-"`
+	Description string   `json:"description" example:"Manages grants to destinations"`
+	Permissions []string `json:"permissions" example:"grants:read,grants:create" note:"Infra API permissions, builtin roles, or destination privileges"`
+}
+
+func (r UpdateRoleRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.ID),
+		validate.Required("permissions", r.Permissions),
+		permissionsRule(r.Permissions),
+	}
+}
+
+// permissionsRule checks that each permission is a name which can be stored
+// in the list of permissions of a role.
+type permissionsRule []string
+
+func (r permissionsRule) Validate() *validate.Failure {
+	var problems []string
+	for i, permission := range r {
+		rule := validate.StringRule{
+			Value:     permission,
+			Name:      "permissions",
+			MaxLength: 256,
+			CharacterRanges: []validate.CharRange{
+				validate.AlphabetLower,
+				validate.AlphabetUpper,
+				validate.Numbers,
+				validate.Dash, validate.Underscore, validate.Dot, validate.Colon,
+			},
+		}
+
+		if permission == "" {
+			problems = append(problems, fmt.Sprintf("permission at position %d is empty", i))
+			continue
+		}
+
+		if failure := rule.Validate(); failure != nil {
+			for _, problem := range failure.Problems {
+				problems = append(problems, fmt.Sprintf("permission %q: %s", permission, problem))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &validate.Failure{Name: "permissions", Problems: problems}
+	}
+	return nil
+}
+
+func (r permissionsRule) DescribeSchema(_ *openapi3.Schema) {}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"github.com/infrahq/infra/internal/validate"
+	"github.com/infrahq/infra/uid"
+)
+
+// Session is an access key which was issued to a user at login.
+type Session struct {
+	ID            uid.ID `json:"id"`
+	Created       Time   `json:"created"`
+	LoginMethod   string `json:"loginMethod" example:"oidc"`
+	ClientIP      string `json:"clientIP" example:"192.0.2.10"`
+	UserAgent     string `json:"userAgent"`
+	ClientVersion string `json:"clientVersion" example:"0.16.0" note:"the version of the Infra CLI, empty for other clients"`
+	LastUsed      Time   `json:"lastUsed"`
+	Expires       Time   `json:"expires"`
+	// Current is true for the session which made the request.
+	Current bool `json:"current"`
+}
+
+type DeleteSessionRequest struct {
+	UserID    uid.ID `uri:"id" json:"-"`
+	SessionID uid.ID `uri:"session" json:"-"`
+}
+
+func (r DeleteSessionRequest) ValidationRules() []validate.ValidationRule {
+	return []validate.ValidationRule{
+		validate.Required("id", r.UserID),
+		validate.Required("session", r.SessionID),
+	}
+}
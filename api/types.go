@@ -74,6 +74,19 @@ func (t *Time) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// UnmarshalText allows Time to be used as a query parameter.
+func (t *Time) UnmarshalText(data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	tmp, err := time.Parse(time.RFC3339, string(data))
+	if err != nil {
+		return err
+	}
+	*t = Time(tmp.UTC())
+	return nil
+}
+
 func (t Time) String() string {
 	return time.Time(t).Format(time.RFC3339)
 }
@@ -3,7 +3,9 @@ package access
 import (
 	"errors"
 	"fmt"
+	"net"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -38,46 +40,26 @@ func hasAuthorization(c *gin.Context, requestedResource uid.ID, isResourceOwner
 
 const ResourceInfraAPI = "infra"
 
-// RequireInfraRole checks that the identity in the context can perform an action on a resource based on their granted roles
+// RequireInfraRole checks that the identity in the context can perform an action on a resource based on their granted roles.
+// A custom role which includes one of the roles, or permissions, also satisfies the check.
+// Grants to the groups of the identity are resolved in the same query, and the
+// decision is cached for a short time.
 func RequireInfraRole(c *gin.Context, oneOfRoles ...string) (*gorm.DB, error) {
-	db := getDB(c)
-
 	identity := AuthenticatedIdentity(c)
 	if identity == nil {
 		return nil, fmt.Errorf("no active identity")
 	}
 
-	for _, role := range oneOfRoles {
-		ok, err := Can(db, identity.PolyID(), role, ResourceInfraAPI)
-		if err != nil {
-			return nil, err
-		}
-
-		if ok {
-			return db, nil
-		}
-	}
-
-	// check if they belong to a group that is authorized
-	groups, err := data.ListGroups(db, &models.Pagination{}, data.ByGroupMember(identity.ID))
+	ok, err := getAuthzCache(c).hasGrant(c, identity.PolyID(), oneOfRoles, ResourceInfraAPI)
 	if err != nil {
-		return nil, fmt.Errorf("auth user groups: %w", err)
+		return nil, err
 	}
 
-	for _, group := range groups {
-		for _, role := range oneOfRoles {
-			ok, err := Can(db, group.PolyID(), role, ResourceInfraAPI)
-			if err != nil {
-				return nil, err
-			}
-
-			if ok {
-				return db, nil
-			}
-		}
+	if !ok {
+		return nil, ErrNotAuthorized
 	}
 
-	return nil, ErrNotAuthorized
+	return getDB(c), nil
 }
 
 var ErrNotAuthorized = errors.New("not authorized")
@@ -93,6 +75,9 @@ type AuthorizationError struct {
 func (e AuthorizationError) Error() string {
 	var roles strings.Builder
 	switch len(e.RequiredRoles) {
+	case 0:
+		// the operation is not allowed by any role
+		return fmt.Sprintf("you do not have permission to %v %v", e.Operation, e.Resource)
 	case 1:
 		roles.WriteString(e.RequiredRoles[0])
 	default:
@@ -126,12 +111,83 @@ func HandleAuthErr(err error, resource, operation string, roles ...string) error
 	}
 }
 
-// Can checks if an identity has a privilege that means it can perform an action on a resource
-func Can(db *gorm.DB, identity uid.PolymorphicID, privilege, resource string) (bool, error) {
-	grants, err := data.ListGrants(db, &models.Pagination{}, data.BySubject(identity), data.ByPrivilege(privilege), data.ByResource(resource))
+// Can checks if an identity has a privilege that means it can perform an action on a resource.
+// The privilege may be granted directly, by a custom role which includes it, or
+// to a group of the identity.
+// Grants with conditions only apply when origin satisfies the conditions.
+func Can(db *gorm.DB, origin Origin, identity uid.PolymorphicID, privilege, resource string) (bool, error) {
+	return hasGrant(db, origin, identity, []string{privilege}, resource)
+}
+
+// privilegesIncluding returns the privileges, and the names of all the custom
+// roles which include any of the privileges.
+func privilegesIncluding(db *gorm.DB, privileges ...string) ([]string, error) {
+	roles, err := data.RolesIncluding(db, privileges...)
 	if err != nil {
-		return false, fmt.Errorf("has grants: %w", err)
+		return nil, fmt.Errorf("roles including privileges: %w", err)
 	}
 
-	return len(grants) > 0, nil
+	return append(roles, privileges...), nil
+}
+
+// grantConditions returns the conditions of the grants to the subject, or one
+// of its groups, of one of the privileges or a custom role which includes one
+// of them on the resource. The result is empty when there are no grants.
+func grantConditions(db *gorm.DB, subject uid.PolymorphicID, privileges []string, resource string) ([]models.GrantConditions, error) {
+	privileges, err := privilegesIncluding(db, privileges...)
+	if err != nil {
+		return nil, err
+	}
+
+	conditions, err := data.ListGrantConditions(db, subject, privileges, resource)
+	if err != nil {
+		return nil, fmt.Errorf("has grants: %w", err)
+	}
+
+	return conditions, nil
+}
+
+// hasGrant returns true if the subject, or one of its groups, has one of the
+// privileges or a custom role which includes one of them on the resource, and
+// the request from origin satisfies the conditions of the grant.
+func hasGrant(db *gorm.DB, origin Origin, subject uid.PolymorphicID, privileges []string, resource string) (bool, error) {
+	conditions, err := grantConditions(db, subject, privileges, resource)
+	if err != nil {
+		return false, err
+	}
+
+	return origin.satisfiesAny(conditions), nil
+}
+
+// Origin is the client IP and time of a request. Grants with conditions only
+// apply to requests from an origin which satisfies the conditions.
+type Origin struct {
+	IP   net.IP
+	Time time.Time
+}
+
+// RequestOrigin returns the origin of the request. The IP is the address of
+// the peer, because the X-Forwarded-For header is set by the client. The IP is
+// nil when the context has no request.
+func RequestOrigin(c *gin.Context) Origin {
+	origin := Origin{Time: time.Now()}
+	if c.Request != nil {
+		origin.IP = net.ParseIP(c.RemoteIP())
+	}
+	return origin
+}
+
+// satisfies returns true if the origin satisfies the conditions of a grant. A
+// grant without conditions is always satisfied.
+func (o Origin) satisfies(conditions models.GrantConditions) bool {
+	return conditions.IsZero() || conditions.Allows(o.IP, o.Time)
+}
+
+func (o Origin) satisfiesAny(conditions []models.GrantConditions) bool {
+	for _, c := range conditions {
+		if o.satisfies(c) {
+			return true
+		}
+	}
+	return false
 }
@@ -10,8 +10,11 @@ import (
 	"github.com/infrahq/infra/uid"
 )
 
+// currentAccessKey returns the access key of the request, or nil when the
+// request was authenticated with a client certificate.
 func currentAccessKey(c *gin.Context) *models.AccessKey {
-	accessKey, ok := c.MustGet("key").(*models.AccessKey)
+	val, _ := c.Get("key")
+	accessKey, ok := val.(*models.AccessKey)
 	if !ok {
 		return nil
 	}
@@ -19,6 +22,27 @@ func currentAccessKey(c *gin.Context) *models.AccessKey {
 	return accessKey
 }
 
+// requireUnscopedAccessKey checks the access key of the request has no scopes
+// which limit it, before it is used to create a credential which can not keep
+// those scopes, such as a client certificate.
+func requireUnscopedAccessKey(c *gin.Context, resource string) error {
+	if key := currentAccessKey(c); key != nil && len(key.LimitingScopes()) > 0 {
+		return AuthorizationError{Resource: resource + " with an access key which has scopes", Operation: "create"}
+	}
+	return nil
+}
+
+// currentProviderID returns the provider which the user of the request logged
+// in with. Requests authenticated with a client certificate use the infra
+// provider.
+func currentProviderID(c *gin.Context) uid.ID {
+	if key := currentAccessKey(c); key != nil {
+		return key.ProviderID
+	}
+
+	return data.InfraProvider(getDB(c)).ID
+}
+
 func ListAccessKeys(c *gin.Context, identityID uid.ID, name string, showExpired bool, p *models.Pagination) ([]models.AccessKey, error) {
 	roles := []string{models.InfraAdminRole, models.InfraViewRole}
 	db, err := RequireInfraRole(c, roles...)
@@ -60,6 +84,10 @@ func DeleteAccessKey(c *gin.Context, id uid.ID) error {
 func DeleteRequestAccessKey(c *gin.Context) error {
 	// does not need authorization check, this action is limited to the calling key
 	key := currentAccessKey(c)
+	if key == nil {
+		// client certificates expire on their own
+		return nil
+	}
 
 	db := getDB(c)
 
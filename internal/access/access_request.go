@@ -0,0 +1,163 @@
+package access
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/resource"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+var accessRequestApproverRoles = []string{models.InfraAdminRole, models.InfraApproverRole}
+
+// isAccessRequestSelf is used by authorization checks to see if the calling user is requesting their own access request
+func isAccessRequestSelf(c *gin.Context, requestID uid.ID) (bool, error) {
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return false, nil
+	}
+
+	request, err := data.GetAccessRequest(getDB(c), data.ByID(requestID))
+	if err != nil {
+		return false, err
+	}
+
+	return request.RequestedBy == identity.ID, nil
+}
+
+func CreateAccessRequest(c *gin.Context, request *models.AccessRequest) error {
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return fmt.Errorf("no active identity")
+	}
+
+	request.RequestedBy = identity.ID
+	request.Status = models.AccessRequestStatusPending
+
+	return data.CreateAccessRequest(getDB(c), request)
+}
+
+func GetAccessRequest(c *gin.Context, id uid.ID) (*models.AccessRequest, error) {
+	db, err := hasAuthorization(c, id, isAccessRequestSelf, accessRequestApproverRoles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access request", "get", accessRequestApproverRoles...)
+	}
+
+	return data.GetAccessRequest(db.Preload("RequestedByIdentity"), data.ByID(id))
+}
+
+// ListAccessRequests returns all access requests to admins and approvers. Any
+// other user may only list their own access requests.
+func ListAccessRequests(c *gin.Context, requestedBy uid.ID, status string, p *models.Pagination) ([]models.AccessRequest, error) {
+	db, err := RequireInfraRole(c, accessRequestApproverRoles...)
+	if err != nil {
+		err = HandleAuthErr(err, "access requests", "list", accessRequestApproverRoles...)
+
+		identity := AuthenticatedIdentity(c)
+		if identity == nil || requestedBy != identity.ID {
+			return nil, err
+		}
+
+		db = getDB(c)
+	}
+
+	return data.ListAccessRequests(db.Preload("RequestedByIdentity"), p,
+		data.ByOptionalRequestedBy(requestedBy),
+		data.ByOptionalStatus(status))
+}
+
+// ApproveAccessRequest creates a grant for the access request. The grant
+// expires after the duration of the request.
+func ApproveAccessRequest(c *gin.Context, id uid.ID) (*models.AccessRequest, error) {
+	db, request, err := decideAccessRequest(c, id, "approve")
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	grant := &models.Grant{
+		Subject:   uid.NewIdentityPolymorphicID(request.RequestedBy),
+		Privilege: request.Privilege,
+		Resource:  request.Resource,
+		CreatedBy: request.DecidedBy,
+		ExpiresAt: now.Add(request.Duration),
+	}
+
+	err = data.DeleteGrants(db,
+		data.BySubject(grant.Subject),
+		data.ByPrivilege(grant.Privilege),
+		data.ByExactResource(grant.Resource),
+		data.ByExpiredGrants())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := data.CreateGrant(db, grant); err != nil {
+		return nil, err
+	}
+
+	request.Status = models.AccessRequestStatusApproved
+	request.DecidedAt = now
+	request.GrantID = grant.ID
+
+	if err := data.SaveAccessRequest(db, request); err != nil {
+		return nil, err
+	}
+
+	return data.GetAccessRequest(db.Preload("RequestedByIdentity"), data.ByID(id))
+}
+
+func DenyAccessRequest(c *gin.Context, id uid.ID) (*models.AccessRequest, error) {
+	db, request, err := decideAccessRequest(c, id, "deny")
+	if err != nil {
+		return nil, err
+	}
+
+	request.Status = models.AccessRequestStatusDenied
+	request.DecidedAt = time.Now().UTC()
+
+	if err := data.SaveAccessRequest(db, request); err != nil {
+		return nil, err
+	}
+
+	return data.GetAccessRequest(db.Preload("RequestedByIdentity"), data.ByID(id))
+}
+
+// decideAccessRequest checks that the caller is allowed to approve or deny the
+// pending access request, and records the caller as the decider.
+func decideAccessRequest(c *gin.Context, id uid.ID, operation string) (*gorm.DB, *models.AccessRequest, error) {
+	db, err := RequireInfraRole(c, accessRequestApproverRoles...)
+	if err != nil {
+		return nil, nil, HandleAuthErr(err, "access request", operation, accessRequestApproverRoles...)
+	}
+
+	request, err := data.GetAccessRequest(db, data.ByID(id))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// approvers can not use access requests to give out access to infra itself
+	if resource.IsInfra(request.Resource) {
+		if _, err := RequireInfraRole(c, models.InfraAdminRole); err != nil {
+			return nil, nil, HandleAuthErr(err, "access request", operation, models.InfraAdminRole)
+		}
+	}
+
+	if request.Status != models.AccessRequestStatusPending {
+		return nil, nil, fmt.Errorf("%w: access request has already been %s", internal.ErrBadRequest, request.Status)
+	}
+
+	identity := AuthenticatedIdentity(c)
+	if request.RequestedBy == identity.ID {
+		return nil, nil, fmt.Errorf("%w: cannot %s your own access request", internal.ErrBadRequest, operation)
+	}
+
+	request.DecidedBy = identity.ID
+	return db, request, nil
+}
@@ -0,0 +1,231 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// isAccessReviewReviewer is used by authorization checks to see if the calling
+// user is one of the reviewers of the access review
+func isAccessReviewReviewer(c *gin.Context, reviewID uid.ID) (bool, error) {
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return false, nil
+	}
+
+	_, err := data.GetAccessReview(getDB(c), data.ByID(reviewID), data.ByReviewer(identity.ID))
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, internal.ErrNotFound):
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// CreateAccessReview creates the campaign with an item for each of the active
+// grants which apply to the destination, or to the group and its members.
+func CreateAccessReview(c *gin.Context, review *models.AccessReview, reviewerIDs []uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "access review", "create", models.InfraAdminRole)
+	}
+
+	if !review.Deadline.After(time.Now()) {
+		return fmt.Errorf("%w: deadline must be in the future", internal.ErrBadRequest)
+	}
+
+	if len(reviewerIDs) > 0 {
+		review.Reviewers, err = data.ListIdentities(db, &models.Pagination{}, data.ByIDs(reviewerIDs))
+		if err != nil {
+			return err
+		}
+
+		if len(review.Reviewers) != len(uniqueIDs(reviewerIDs)) {
+			return fmt.Errorf("%w: reviewer does not exist", internal.ErrBadRequest)
+		}
+	}
+
+	var grants []models.Grant
+	if review.GroupID != 0 {
+		if _, err := data.GetGroup(db, data.ByID(review.GroupID)); err != nil {
+			return fmt.Errorf("get group: %w", err)
+		}
+
+		grants, err = data.ListGrants(db, &models.Pagination{}, data.GrantsToGroupAndMembers(review.GroupID))
+	} else {
+		grants, err = data.ListGrants(db, &models.Pagination{}, data.ByOptionalDestination(review.Destination))
+	}
+	if err != nil {
+		return err
+	}
+
+	items, err := accessReviewItems(db, grants)
+	if err != nil {
+		return err
+	}
+
+	review.CreatedBy = AuthenticatedIdentity(c).ID
+	review.Status = models.AccessReviewStatusOpen
+
+	return data.CreateAccessReview(db, review, items)
+}
+
+// accessReviewItems returns a pending item for each grant, with the name of
+// the user or group of the grant.
+func accessReviewItems(db *gorm.DB, grants []models.Grant) ([]models.AccessReviewItem, error) {
+	var userIDs, groupIDs []uid.ID
+	for _, grant := range grants {
+		switch {
+		case grant.Subject.IsIdentity():
+			userIDs = append(userIDs, grant.SubjectID)
+		case grant.Subject.IsGroup():
+			groupIDs = append(groupIDs, grant.SubjectID)
+		}
+	}
+
+	names := map[uid.PolymorphicID]string{}
+	if len(userIDs) > 0 {
+		users, err := data.ListIdentities(db, &models.Pagination{}, data.ByIDs(userIDs))
+		if err != nil {
+			return nil, err
+		}
+		for _, user := range users {
+			names[user.PolyID()] = user.Name
+		}
+	}
+
+	if len(groupIDs) > 0 {
+		groups, err := data.ListGroups(db, &models.Pagination{}, data.ByIDs(groupIDs))
+		if err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			names[group.PolyID()] = group.Name
+		}
+	}
+
+	items := make([]models.AccessReviewItem, 0, len(grants))
+	for _, grant := range grants {
+		items = append(items, models.AccessReviewItem{
+			GrantID:     grant.ID,
+			Subject:     grant.Subject,
+			SubjectName: names[grant.Subject],
+			Privilege:   grant.Privilege,
+			Resource:    grant.Resource,
+			Decision:    models.AccessReviewDecisionPending,
+		})
+	}
+
+	return items, nil
+}
+
+func GetAccessReview(c *gin.Context, id uid.ID) (*models.AccessReview, error) {
+	db, err := hasAuthorization(c, id, isAccessReviewReviewer, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access review", "get", models.InfraAdminRole)
+	}
+
+	return data.GetAccessReview(db, data.ByID(id))
+}
+
+// ListAccessReviews returns all access reviews to admins. Any other user may
+// only list the access reviews they are a reviewer of.
+func ListAccessReviews(c *gin.Context, status string, p *models.Pagination) ([]models.AccessReview, error) {
+	selectors := []data.SelectorFunc{data.ByOptionalStatus(status)}
+
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		err = HandleAuthErr(err, "access reviews", "list", models.InfraAdminRole)
+
+		identity := AuthenticatedIdentity(c)
+		if identity == nil {
+			return nil, err
+		}
+
+		db = getDB(c)
+		selectors = append(selectors, data.ByReviewer(identity.ID))
+	}
+
+	return data.ListAccessReviews(db, p, selectors...)
+}
+
+func ListAccessReviewItems(c *gin.Context, id uid.ID, decision string, p *models.Pagination) ([]models.AccessReviewItem, error) {
+	db, err := hasAuthorization(c, id, isAccessReviewReviewer, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access review", "get", models.InfraAdminRole)
+	}
+
+	if _, err := data.GetAccessReview(db, data.ByID(id)); err != nil {
+		return nil, err
+	}
+
+	return data.ListAccessReviewItems(db, p, data.ByAccessReview(id), data.ByOptionalDecision(decision))
+}
+
+// DecideAccessReviewItem records the decision of the reviewer to keep or revoke
+// the grant of the item. A grant is revoked immediately, and can not be kept
+// afterwards.
+func DecideAccessReviewItem(c *gin.Context, id, itemID uid.ID, decision string) (*models.AccessReviewItem, error) {
+	db, err := hasAuthorization(c, id, isAccessReviewReviewer, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "access review", "decide", models.InfraAdminRole)
+	}
+
+	review, err := data.GetAccessReview(db, data.ByID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	if review.Status != models.AccessReviewStatusOpen {
+		return nil, fmt.Errorf("%w: access review is %s", internal.ErrBadRequest, review.Status)
+	}
+
+	item, err := data.GetAccessReviewItem(db, data.ByID(itemID), data.ByAccessReview(id))
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Decision == models.AccessReviewDecisionRevoke {
+		return nil, fmt.Errorf("%w: grant has already been revoked", internal.ErrBadRequest)
+	}
+
+	identity := AuthenticatedIdentity(c)
+	if item.Subject == identity.PolyID() {
+		return nil, fmt.Errorf("%w: cannot review your own grant", internal.ErrBadRequest)
+	}
+
+	if decision == models.AccessReviewDecisionRevoke {
+		if err := data.DeleteGrants(db, data.ByID(item.GrantID)); err != nil {
+			return nil, err
+		}
+	}
+
+	item.Decision = decision
+	item.DecidedBy = identity.ID
+	item.DecidedAt = time.Now().UTC()
+
+	if err := data.SaveAccessReviewItem(db, item); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func uniqueIDs(ids []uid.ID) map[uid.ID]struct{} {
+	unique := make(map[uid.ID]struct{}, len(ids))
+	for _, id := range ids {
+		unique[id] = struct{}{}
+	}
+	return unique
+}
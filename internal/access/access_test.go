@@ -3,6 +3,7 @@ package access
 import (
 	"errors"
 	"fmt"
+	"net"
 	"net/http/httptest"
 	"testing"
 	"time"
@@ -11,13 +12,14 @@ import (
 	"gorm.io/gorm"
 	"gotest.tools/v3/assert"
 
+	"github.com/infrahq/infra/internal/claims"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/internal/testing/patch"
 	"github.com/infrahq/infra/uid"
 )
 
-func setupDB(t *testing.T) *gorm.DB {
+func setupDB(t testing.TB) *gorm.DB {
 	driver, err := data.NewSQLiteDriver("file::memory:")
 	assert.NilError(t, err)
 
@@ -91,6 +93,152 @@ func TestBasicGrant(t *testing.T) {
 	cant(t, db, "i:alice", "write", "infra.machines")
 }
 
+func TestTimeBoundGrant(t *testing.T) {
+	db := setupDB(t)
+
+	create := func(t *testing.T, subject uid.PolymorphicID, notBefore, expiresAt time.Time) {
+		t.Helper()
+		err := data.CreateGrant(db, &models.Grant{
+			Subject:   subject,
+			Privilege: "read",
+			Resource:  "infra.groups",
+			NotBefore: notBefore,
+			ExpiresAt: expiresAt,
+		})
+		assert.NilError(t, err)
+	}
+
+	create(t, "i:active", time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+	can(t, db, "i:active", "read", "infra.groups")
+
+	create(t, "i:expired", time.Time{}, time.Now().Add(-time.Minute))
+	cant(t, db, "i:expired", "read", "infra.groups")
+
+	create(t, "i:future", time.Now().Add(time.Hour), time.Time{})
+	cant(t, db, "i:future", "read", "infra.groups")
+}
+
+func TestConditionalGrant(t *testing.T) {
+	db := setupDB(t)
+
+	user := &models.Identity{Name: "conditional@example.com"}
+	err := data.CreateIdentity(db, user)
+	assert.NilError(t, err)
+
+	err = data.CreateGrant(db, &models.Grant{
+		Subject:   user.PolyID(),
+		Privilege: models.InfraAdminRole,
+		Resource:  ResourceInfraAPI,
+		Conditions: models.GrantConditions{Conditions: claims.Conditions{
+			CIDRs: []string{"10.0.0.0/8"},
+			Windows: []claims.TimeWindow{
+				{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00", Timezone: "America/New_York"},
+			},
+		}},
+	})
+	assert.NilError(t, err)
+
+	// Monday, 10:00 in New York
+	workday := time.Date(2022, 10, 3, 14, 0, 0, 0, time.UTC)
+	weekend := workday.AddDate(0, 0, 5)
+	vpn, home := net.ParseIP("10.1.2.3"), net.ParseIP("192.168.1.2")
+
+	type testCase struct {
+		origin   Origin
+		expected bool
+	}
+
+	testCases := map[string]testCase{
+		"satisfies conditions": {origin: Origin{IP: vpn, Time: workday}, expected: true},
+		"outside network":      {origin: Origin{IP: home, Time: workday}},
+		"outside window":       {origin: Origin{IP: vpn, Time: weekend}},
+		"no client ip":         {origin: Origin{Time: workday}},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			actual, err := Can(db, tc.origin, user.PolyID(), models.InfraAdminRole, ResourceInfraAPI)
+			assert.NilError(t, err)
+			assert.Equal(t, actual, tc.expected)
+		})
+	}
+
+	t.Run("require infra role", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/users", nil)
+		c.Request.RemoteAddr = "192.168.1.2:4321"
+		c.Set("db", db)
+		c.Set("identity", user)
+
+		_, err := RequireInfraRole(c, models.InfraAdminRole)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+
+		// an unconditional grant applies from anywhere
+		grant(t, db, user, user.PolyID(), models.InfraViewRole, ResourceInfraAPI)
+		_, err = RequireInfraRole(c, models.InfraAdminRole, models.InfraViewRole)
+		assert.NilError(t, err)
+	})
+
+	t.Run("forwarded for header is ignored", func(t *testing.T) {
+		err = data.CreateGrant(db, &models.Grant{
+			Subject:    user.PolyID(),
+			Privilege:  "view",
+			Resource:   "production",
+			Conditions: models.GrantConditions{Conditions: claims.Conditions{CIDRs: []string{"10.0.0.0/8"}}},
+		})
+		assert.NilError(t, err)
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/api/users", nil)
+		c.Request.RemoteAddr = "192.168.1.2:4321"
+		c.Request.Header.Set("X-Forwarded-For", "10.1.2.3")
+
+		actual, err := Can(db, RequestOrigin(c), user.PolyID(), "view", "production")
+		assert.NilError(t, err)
+		assert.Equal(t, actual, false)
+
+		c.Request.RemoteAddr = "10.1.2.3:4321"
+		actual, err = Can(db, RequestOrigin(c), user.PolyID(), "view", "production")
+		assert.NilError(t, err)
+		assert.Equal(t, actual, true)
+	})
+}
+
+func TestWildcardGrant(t *testing.T) {
+	db := setupDB(t)
+
+	create := func(t *testing.T, subject uid.PolymorphicID, resource string) {
+		t.Helper()
+		err := data.CreateGrant(db, &models.Grant{Subject: subject, Privilege: "view", Resource: resource})
+		assert.NilError(t, err)
+	}
+
+	create(t, "i:fleet", "prod-*")
+	can(t, db, "i:fleet", "view", "prod-1")
+	can(t, db, "i:fleet", "view", "prod-east")
+	cant(t, db, "i:fleet", "view", "Prod-1")
+	cant(t, db, "i:fleet", "view", "staging-1")
+	cant(t, db, "i:fleet", "view", "prod-1.default")
+
+	create(t, "i:system", "*.kube-system")
+	can(t, db, "i:system", "view", "prod-1.kube-system")
+	cant(t, db, "i:system", "view", "prod-1.default")
+	cant(t, db, "i:system", "view", "prod-1")
+
+	create(t, "i:namespaces", "cluster.*")
+	can(t, db, "i:namespaces", "view", "cluster.default")
+	cant(t, db, "i:namespaces", "view", "cluster")
+	cant(t, db, "i:namespaces", "view", "other.default")
+
+	create(t, "i:literal", "prod_1")
+	can(t, db, "i:literal", "view", "prod_1")
+	cant(t, db, "i:literal", "view", "prodx1")
+
+	create(t, "i:everything", "*")
+	can(t, db, "i:everything", "view", "anything")
+	cant(t, db, "i:everything", "view", "infra")
+}
+
 func TestUsersGroupGrant(t *testing.T) {
 	db := setupDB(t)
 
@@ -125,6 +273,49 @@ func TestUsersGroupGrant(t *testing.T) {
 	assert.Assert(t, authDB != nil)
 }
 
+func TestRequireInfraRole_Cache(t *testing.T) {
+	db := setupDB(t)
+
+	user := &models.Identity{Name: "cached@example.com"}
+	err := data.CreateIdentity(db, user)
+	assert.NilError(t, err)
+
+	group := &models.Group{Name: "cached"}
+	err = data.CreateGroup(db, group)
+	assert.NilError(t, err)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("db", db)
+	c.Set("identity", user)
+	c.Set("authzCache", NewAuthzCache(time.Minute))
+
+	requireAdmin := func(t *testing.T) error {
+		t.Helper()
+		_, err := RequireInfraRole(c, models.InfraAdminRole)
+		return err
+	}
+
+	assert.ErrorIs(t, requireAdmin(t), ErrNotAuthorized)
+
+	// a new grant invalidates the cached decision
+	grant(t, db, user, group.PolyID(), models.InfraAdminRole, ResourceInfraAPI)
+	assert.ErrorIs(t, requireAdmin(t), ErrNotAuthorized)
+
+	// so does a change to group membership
+	err = data.AddUsersToGroup(db, group.ID, []uid.ID{user.ID})
+	assert.NilError(t, err)
+	assert.NilError(t, requireAdmin(t))
+
+	// the decision is cached until the data changes
+	err = db.Exec("DELETE FROM identities_groups").Error
+	assert.NilError(t, err)
+	assert.NilError(t, requireAdmin(t))
+
+	err = data.DeleteGrants(db, data.BySubject(group.PolyID()))
+	assert.NilError(t, err)
+	assert.ErrorIs(t, requireAdmin(t), ErrNotAuthorized)
+}
+
 func TestInfraRequireInfraRole(t *testing.T) {
 	db := setupDB(t)
 
@@ -175,9 +366,47 @@ func TestInfraRequireInfraRole(t *testing.T) {
 		assert.ErrorIs(t, err, ErrNotAuthorized)
 		assert.Assert(t, authDB == nil)
 	})
+
+	err := data.CreateRole(db, &models.Role{
+		Name:        "grant-manager",
+		Permissions: models.CommaSeparatedStrings{models.PermissionGrantsRead, models.PermissionGrantsCreate},
+	})
+	assert.NilError(t, err)
+
+	t.Run("has custom role which includes permission", func(t *testing.T) {
+		c := setup(t, "grant-manager")
+
+		authDB, err := RequireInfraRole(c, models.InfraAdminRole, models.PermissionGrantsCreate)
+		assert.NilError(t, err)
+		assert.Assert(t, authDB != nil)
+	})
+
+	t.Run("has custom role which does not include permission", func(t *testing.T) {
+		c := setup(t, "grant-manager")
+
+		authDB, err := RequireInfraRole(c, models.InfraAdminRole)
+		assert.ErrorIs(t, err, ErrNotAuthorized)
+		assert.Assert(t, authDB == nil)
+	})
 }
 
-func grant(t *testing.T, db *gorm.DB, currentUser *models.Identity, subject uid.PolymorphicID, privilege, resource string) {
+func TestCustomRoleGrant(t *testing.T) {
+	db := setupDB(t)
+
+	err := data.CreateRole(db, &models.Role{Name: "operator", Permissions: models.CommaSeparatedStrings{"view", "edit"}})
+	assert.NilError(t, err)
+
+	err = data.CreateGrant(db, &models.Grant{Subject: "i:operator", Privilege: "operator", Resource: "prod-*"})
+	assert.NilError(t, err)
+
+	can(t, db, "i:operator", "operator", "prod-1")
+	can(t, db, "i:operator", "view", "prod-1")
+	can(t, db, "i:operator", "edit", "prod-1")
+	cant(t, db, "i:operator", "admin", "prod-1")
+	cant(t, db, "i:operator", "view", "staging")
+}
+
+func grant(t testing.TB, db *gorm.DB, currentUser *models.Identity, subject uid.PolymorphicID, privilege, resource string) {
 	err := data.CreateGrant(db, &models.Grant{
 		Subject:   subject,
 		Privilege: privilege,
@@ -188,13 +417,13 @@ func grant(t *testing.T, db *gorm.DB, currentUser *models.Identity, subject uid.
 }
 
 func can(t *testing.T, db *gorm.DB, subject uid.PolymorphicID, privilege, resource string) {
-	canAccess, err := Can(db, subject, privilege, resource)
+	canAccess, err := Can(db, Origin{Time: time.Now()}, subject, privilege, resource)
 	assert.NilError(t, err)
 	assert.Assert(t, canAccess)
 }
 
 func cant(t *testing.T, db *gorm.DB, subject uid.PolymorphicID, privilege, resource string) {
-	canAccess, err := Can(db, subject, privilege, resource)
+	canAccess, err := Can(db, Origin{Time: time.Now()}, subject, privilege, resource)
 	assert.NilError(t, err)
 	assert.Assert(t, !canAccess)
 }
@@ -232,3 +461,89 @@ func TestAuthorizationError(t *testing.T) {
 		assert.Assert(t, errors.Is(err, ErrNotAuthorized))
 	})
 }
+
+func BenchmarkRequireInfraRole(b *testing.B) {
+	for _, numGroups := range []int{10, 1000, 5000} {
+		b.Run(fmt.Sprintf("groups=%d", numGroups), func(b *testing.B) {
+			db := setupDB(b)
+
+			user := &models.Identity{Name: "bench@example.com"}
+			err := data.CreateIdentity(db, user)
+			assert.NilError(b, err)
+
+			// the user is an admin through the last of their groups
+			var group *models.Group
+			for i := 0; i < numGroups; i++ {
+				group = &models.Group{Name: fmt.Sprintf("group-%d", i)}
+				err := data.CreateGroup(db, group)
+				assert.NilError(b, err)
+				err = data.AddUsersToGroup(db, group.ID, []uid.ID{user.ID})
+				assert.NilError(b, err)
+			}
+			grant(b, db, user, group.PolyID(), models.InfraAdminRole, ResourceInfraAPI)
+
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Set("db", db)
+			c.Set("identity", user)
+
+			b.Run("query per group", func(b *testing.B) {
+				b.ReportAllocs()
+				for n := 0; n < b.N; n++ {
+					ok, err := requireInfraRoleByGroup(db, user, models.InfraAdminRole)
+					assert.NilError(b, err)
+					assert.Assert(b, ok)
+				}
+			})
+
+			b.Run("single query", func(b *testing.B) {
+				b.ReportAllocs()
+				for n := 0; n < b.N; n++ {
+					_, err := RequireInfraRole(c, models.InfraAdminRole)
+					assert.NilError(b, err)
+				}
+			})
+
+			b.Run("cached", func(b *testing.B) {
+				c.Set("authzCache", NewAuthzCache(time.Minute))
+				defer c.Set("authzCache", nil)
+
+				b.ReportAllocs()
+				for n := 0; n < b.N; n++ {
+					_, err := RequireInfraRole(c, models.InfraAdminRole)
+					assert.NilError(b, err)
+				}
+			})
+		})
+	}
+}
+
+// requireInfraRoleByGroup is how RequireInfraRole used to resolve grants, with
+// a query for the grants of each group of the user. It is the baseline for
+// BenchmarkRequireInfraRole.
+func requireInfraRoleByGroup(db *gorm.DB, identity *models.Identity, oneOfRoles ...string) (bool, error) {
+	privileges, err := privilegesIncluding(db, oneOfRoles...)
+	if err != nil {
+		return false, err
+	}
+
+	subjects := []uid.PolymorphicID{identity.PolyID()}
+	groups, err := data.ListGroups(db, &models.Pagination{}, data.ByGroupMember(identity.ID))
+	if err != nil {
+		return false, err
+	}
+	for _, group := range groups {
+		subjects = append(subjects, group.PolyID())
+	}
+
+	for _, subject := range subjects {
+		grants, err := data.ListGrants(db, &models.Pagination{}, data.BySubject(subject), data.ByPrivileges(privileges), data.ByResource(ResourceInfraAPI))
+		if err != nil {
+			return false, err
+		}
+		if len(grants) > 0 {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
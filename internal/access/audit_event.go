@@ -0,0 +1,24 @@
+package access
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func ListAuditEvents(c *gin.Context, actorID uid.ID, resourceType string, since, until time.Time, p *models.Pagination) ([]models.AuditEvent, error) {
+	roles := []string{models.InfraAdminRole, models.PermissionAuditEventsRead}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "audit events", "list", roles...)
+	}
+
+	return data.ListAuditEvents(db, p,
+		data.ByOptionalActorID(actorID),
+		data.ByOptionalResourceType(resourceType),
+		data.ByOptionalCreatedBetween(since, until))
+}
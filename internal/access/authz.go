@@ -0,0 +1,125 @@
+package access
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// AuthzDecision is the result of checking if a subject has a privilege on a
+// resource, with the grants and group memberships which produced it.
+type AuthzDecision struct {
+	// Allowed is true when one of the grants allows the privilege from the
+	// origin of the check, which must satisfy the conditions of the grant.
+	Allowed bool
+	// Grants are the grants to the subject, or to one of its groups, which
+	// allow the privilege on the resource, including grants with conditions
+	// which this request does not satisfy.
+	Grants []models.Grant
+	// Groups are the groups of the subject when it is a user. Grants to these
+	// groups were checked as well.
+	Groups []models.Group
+	// Paths are the chains of group memberships from the subject to each of
+	// the groups it is a member of, directly or through other groups, by the
+	// ID of the group.
+	Paths map[uid.ID][]models.Group
+}
+
+// CheckAuthorization checks if the subject has the privilege on the resource,
+// and explains why. Users can check their own access, and the access of their
+// groups. A grant with conditions only allows the privilege when the origin
+// satisfies them, the conditions are returned with the grant. The origin is
+// where the subject connects from, not the origin of this request, which is
+// usually made by someone else.
+func CheckAuthorization(c *gin.Context, subject uid.PolymorphicID, privilege, resource string, origin Origin) (*AuthzDecision, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.PermissionGrantsRead}
+	db, err := RequireInfraRole(c, roles...)
+	err = HandleAuthErr(err, "authorization", "check", roles...)
+	if errors.Is(err, ErrNotAuthorized) {
+		db = getDB(c)
+		subjectID, err2 := subject.ID()
+		if err2 != nil {
+			return nil, err
+		}
+		identity := AuthenticatedIdentity(c)
+		switch {
+		case identity == nil:
+			return nil, err
+		case subject.IsIdentity() && identity.ID == subjectID:
+		case subject.IsGroup() && userInGroup(db, identity.ID, subjectID):
+		default:
+			return nil, err
+		}
+	} else if err != nil {
+		return nil, err
+	}
+
+	subjectID, err := subject.ID()
+	if err != nil {
+		return nil, err
+	}
+
+	decision := &AuthzDecision{}
+	if subject.IsIdentity() {
+		if _, err := data.GetIdentity(db, data.ByID(subjectID)); err != nil {
+			return nil, err
+		}
+
+		decision.Groups, err = data.ListGroups(db, &models.Pagination{}, data.ByGroupMember(subjectID))
+		if err != nil {
+			return nil, err
+		}
+	} else if _, err := data.GetGroup(db, data.ByID(subjectID)); err != nil {
+		return nil, err
+	}
+
+	paths, err := data.GroupMembershipPaths(db, subject)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uid.ID, 0, len(paths))
+	for id := range paths {
+		ids = append(ids, id)
+	}
+
+	groups, err := data.ListGroups(db, &models.Pagination{}, data.ByIDs(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uid.ID]models.Group, len(groups))
+	for _, group := range groups {
+		byID[group.ID] = group
+	}
+
+	decision.Paths = make(map[uid.ID][]models.Group, len(paths))
+	for id, path := range paths {
+		for _, groupID := range path {
+			decision.Paths[id] = append(decision.Paths[id], byID[groupID])
+		}
+	}
+
+	privileges, err := privilegesIncluding(db, privilege)
+	if err != nil {
+		return nil, err
+	}
+
+	decision.Grants, err = data.ListGrants(db, &models.Pagination{},
+		data.GrantsInheritedBySubject(subject),
+		data.ByPrivileges(privileges),
+		data.ByResource(resource))
+	if err != nil {
+		return nil, err
+	}
+
+	decision.Allowed, err = Can(db, origin, subject, privilege, resource)
+	if err != nil {
+		return nil, err
+	}
+	return decision, nil
+}
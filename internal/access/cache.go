@@ -0,0 +1,99 @@
+package access
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// AuthzCacheTTL is how long an authorization decision is cached. Changes to
+// grants, roles, and group memberships made by this server invalidate the
+// cache immediately. The TTL bounds how long a decision outlives a grant which
+// expired, or a change made by another server.
+const AuthzCacheTTL = 10 * time.Second
+
+// authzCacheMaxEntries bounds the memory used by the cache. When it is full,
+// all entries are discarded.
+const authzCacheMaxEntries = 10000
+
+// AuthzCache caches the result of authorization checks, so that most requests
+// do not need to query grants. The zero value is not usable, use
+// NewAuthzCache. A nil *AuthzCache caches nothing.
+type AuthzCache struct {
+	ttl time.Duration
+
+	mu      sync.Mutex
+	entries map[authzCacheKey]authzCacheEntry
+}
+
+type authzCacheKey struct {
+	subject    uid.PolymorphicID
+	privileges string
+	resource   string
+}
+
+// authzCacheEntry caches the conditions of the grants, instead of the decision,
+// because a decision for a grant with conditions depends on the origin of the
+// request.
+type authzCacheEntry struct {
+	conditions []models.GrantConditions
+	generation uint64
+	expires    time.Time
+}
+
+func NewAuthzCache(ttl time.Duration) *AuthzCache {
+	return &AuthzCache{ttl: ttl, entries: map[authzCacheKey]authzCacheEntry{}}
+}
+
+// hasGrant returns true if the subject has one of the privileges, or a custom
+// role which includes one of them, on the resource, and the request satisfies
+// the conditions of the grant. The grants are cached.
+func (a *AuthzCache) hasGrant(c *gin.Context, subject uid.PolymorphicID, privileges []string, resource string) (bool, error) {
+	db := getDB(c)
+	origin := RequestOrigin(c)
+	if a == nil {
+		return hasGrant(db, origin, subject, privileges, resource)
+	}
+
+	key := authzCacheKey{subject: subject, privileges: strings.Join(privileges, ","), resource: resource}
+	// read the generation before the decision, so that a change made while
+	// the decision is made invalidates it
+	generation := data.AuthzGeneration()
+	now := time.Now()
+
+	a.mu.Lock()
+	entry, ok := a.entries[key]
+	a.mu.Unlock()
+
+	if ok && entry.generation == generation && now.Before(entry.expires) {
+		return origin.satisfiesAny(entry.conditions), nil
+	}
+
+	conditions, err := grantConditions(db, subject, privileges, resource)
+	if err != nil {
+		return false, err
+	}
+
+	a.mu.Lock()
+	if len(a.entries) >= authzCacheMaxEntries {
+		a.entries = map[authzCacheKey]authzCacheEntry{}
+	}
+	a.entries[key] = authzCacheEntry{conditions: conditions, generation: generation, expires: now.Add(a.ttl)}
+	a.mu.Unlock()
+
+	return origin.satisfiesAny(conditions), nil
+}
+
+// getAuthzCache returns the cache of the server handling the request, or nil
+// when there is none.
+func getAuthzCache(c *gin.Context) *AuthzCache {
+	val, _ := c.Get("authzCache")
+	cache, _ := val.(*AuthzCache)
+	return cache
+}
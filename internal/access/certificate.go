@@ -0,0 +1,40 @@
+package access
+
+import (
+	"crypto/x509"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/pki"
+)
+
+// CreateCertificate signs a client certificate for the authenticated identity.
+// Certificates can only be created with an access key, so that a client
+// certificate can not be renewed by itself, and the certificate is revoked
+// with the access key. A certificate has no scopes, so keys with scopes can
+// not create one.
+func CreateCertificate(c *gin.Context, certificates pki.CertificateProvider, csr *x509.CertificateRequest) (*x509.Certificate, []byte, error) {
+	// does not need authorization check, the certificate is limited to the calling user
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return nil, nil, fmt.Errorf("no active identity")
+	}
+
+	key := currentAccessKey(c)
+	if key == nil {
+		return nil, nil, fmt.Errorf("%w: client certificates can only be created with an access key", internal.ErrUnauthorized)
+	}
+
+	if err := requireUnscopedAccessKey(c, "client certificates"); err != nil {
+		return nil, nil, err
+	}
+
+	cert, raw, err := pki.SignUserCert(certificates, csr, identity, key.ID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", internal.ErrBadRequest, err)
+	}
+
+	return cert, raw, nil
+}
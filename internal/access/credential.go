@@ -17,9 +17,10 @@ import (
 )
 
 func CreateCredential(c *gin.Context, user models.Identity) (string, error) {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	roles := []string{models.InfraAdminRole, models.PermissionUsersCreate}
+	db, err := RequireInfraRole(c, roles...)
 	if err != nil {
-		return "", HandleAuthErr(err, "user", "create", models.InfraAdminRole)
+		return "", HandleAuthErr(err, "user", "create", roles...)
 	}
 
 	tmpPassword, err := generate.CryptoRandom(12, generate.CharsetPassword)
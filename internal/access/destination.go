@@ -33,16 +33,17 @@ func GetDestination(c *gin.Context, id uid.ID) (*models.Destination, error) {
 	return data.GetDestination(db, data.ByID(id))
 }
 
-func ListDestinations(c *gin.Context, uniqueID, name string, p *models.Pagination) ([]models.Destination, error) {
+func ListDestinations(c *gin.Context, uniqueID, name, kind string, p *models.Pagination) ([]models.Destination, error) {
 	db := getDB(c)
 	return data.ListDestinations(db, p, data.ByOptionalUniqueID(uniqueID),
-		data.ByOptionalName(name))
+		data.ByOptionalName(name), data.ByOptionalKind(kind))
 }
 
 func DeleteDestination(c *gin.Context, id uid.ID) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	roles := []string{models.InfraAdminRole, models.PermissionDestinationsDelete}
+	db, err := RequireInfraRole(c, roles...)
 	if err != nil {
-		return HandleAuthErr(err, "destination", "delete", models.InfraAdminRole)
+		return HandleAuthErr(err, "destination", "delete", roles...)
 	}
 
 	return data.DeleteDestinations(db, data.ByID(id))
@@ -0,0 +1,55 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// StartDeviceFlow begins a login for a device which can not open a browser.
+// It does not require authentication.
+func StartDeviceFlow(c *gin.Context) (*models.DeviceFlowAuthRequest, error) {
+	return authn.StartDeviceFlow(getDB(c))
+}
+
+// ApproveDeviceFlow approves the device flow login with the user code, which
+// logs the device in as the authenticated user
+func ApproveDeviceFlow(c *gin.Context, userCode string) error {
+	db := getDB(c)
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return fmt.Errorf("%w: no authenticated user", internal.ErrUnauthorized)
+	}
+
+	request, err := data.GetDeviceFlowAuthRequest(db, data.ByUserCode(authn.NormalizeUserCode(userCode)))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return fmt.Errorf("%w: invalid user code", internal.ErrBadRequest)
+	case err != nil:
+		return err
+	}
+
+	if time.Now().After(request.ExpiresAt) {
+		return fmt.Errorf("%w: the user code has expired", internal.ErrBadRequest)
+	}
+
+	if request.ApprovedBy != 0 {
+		return fmt.Errorf("%w: the login has already been approved", internal.ErrBadRequest)
+	}
+
+	request.ApprovedBy = identity.ID
+	request.ProviderID = currentProviderID(c)
+	// the device can not do more than the access key which approved it
+	if key := currentAccessKey(c); key != nil {
+		request.Scopes = key.LimitingScopes()
+	}
+
+	return data.SaveDeviceFlowAuthRequest(db, request)
+}
@@ -6,27 +6,39 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
+	"github.com/infrahq/infra/internal/resource"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
 )
 
 func GetGrant(c *gin.Context, id uid.ID) (*models.Grant, error) {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	roles := []string{models.InfraAdminRole, models.PermissionGrantsRead}
+	db, err := RequireInfraRole(c, roles...)
 	if err != nil {
-		return nil, HandleAuthErr(err, "grant", "get", models.InfraAdminRole)
+		return nil, HandleAuthErr(err, "grant", "get", roles...)
 	}
 
 	return data.GetGrant(db, data.ByID(id))
 }
 
-func ListGrants(c *gin.Context, subject uid.PolymorphicID, resource string, privilege string, inherited bool, p *models.Pagination) ([]models.Grant, error) {
+// ListGrants returns the grants which apply to the resource, including grants
+// with a pattern that matches the resource. When destination is set, only the
+// grants which apply to the destination or any of its namespaces are returned.
+// Grants which are not in effect yet are only returned when pending is true.
+func ListGrants(c *gin.Context, subject uid.PolymorphicID, resource, destination, privilege string, inherited, pending bool, p *models.Pagination) ([]models.Grant, error) {
+	listGrants := data.ListGrants
+	if pending {
+		listGrants = data.ListGrantsIncludingPending
+	}
+
 	selectors := []data.SelectorFunc{
 		data.ByOptionalResource(resource),
+		data.ByOptionalDestination(destination),
 		data.ByOptionalPrivilege(privilege),
 	}
 
-	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole, models.PermissionGrantsRead}
 	db, err := RequireInfraRole(c, roles...)
 	err = HandleAuthErr(err, "grants", "list", roles...)
 	if errors.Is(err, ErrNotAuthorized) {
@@ -47,14 +59,14 @@ func ListGrants(c *gin.Context, subject uid.PolymorphicID, resource string, priv
 			} else {
 				selectors = append(selectors, data.BySubject(subject))
 			}
-			return data.ListGrants(db, p, selectors...)
+			return listGrants(db, p, selectors...)
 		case subject.IsGroup() && userInGroup(db, identity.ID, subjectID):
 			if inherited {
 				selectors = append(selectors, data.GrantsInheritedBySubject(subject))
 			} else {
 				selectors = append(selectors, data.BySubject(subject))
 			}
-			return data.ListGrants(db, p, selectors...)
+			return listGrants(db, p, selectors...)
 		default:
 			return nil, err
 		}
@@ -68,7 +80,7 @@ func ListGrants(c *gin.Context, subject uid.PolymorphicID, resource string, priv
 		selectors = append(selectors, data.ByOptionalSubject(subject))
 	}
 
-	return data.ListGrants(db, p, selectors...)
+	return listGrants(db, p, selectors...)
 }
 
 func userInGroup(db *gorm.DB, authnUserID uid.ID, groupID uid.ID) bool {
@@ -86,23 +98,63 @@ func userInGroup(db *gorm.DB, authnUserID uid.ID, groupID uid.ID) bool {
 }
 
 func CreateGrant(c *gin.Context, grant *models.Grant) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	roles := []string{models.InfraAdminRole, models.PermissionGrantsCreate}
+	db, err := RequireInfraRole(c, roles...)
 	if err != nil {
-		return HandleAuthErr(err, "grant", "create", models.InfraAdminRole)
+		return HandleAuthErr(err, "grant", "create", roles...)
+	}
+
+	if err := requireAdminForInfraGrant(c, grant, "create"); err != nil {
+		return err
 	}
 
 	creator := AuthenticatedIdentity(c)
 
 	grant.CreatedBy = creator.ID
 
+	// an expired grant which has not been removed yet would otherwise conflict
+	// with the new grant
+	err = data.DeleteGrants(db,
+		data.BySubject(grant.Subject),
+		data.ByPrivilege(grant.Privilege),
+		data.ByExactResource(grant.Resource),
+		data.ByExpiredGrants())
+	if err != nil {
+		return err
+	}
+
 	return data.CreateGrant(db, grant)
 }
 
 func DeleteGrant(c *gin.Context, id uid.ID) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	roles := []string{models.InfraAdminRole, models.PermissionGrantsDelete}
+	db, err := RequireInfraRole(c, roles...)
 	if err != nil {
-		return HandleAuthErr(err, "grant", "delete", models.InfraAdminRole)
+		return HandleAuthErr(err, "grant", "delete", roles...)
+	}
+
+	grant, err := data.GetGrant(db, data.ByID(id))
+	if err != nil {
+		return err
+	}
+
+	if err := requireAdminForInfraGrant(c, grant, "delete"); err != nil {
+		return err
 	}
 
 	return data.DeleteGrants(db, data.ByID(id))
 }
+
+// requireAdminForInfraGrant checks that only an admin can change grants to the
+// infra resource, so that the grants permissions can not be used to become an
+// admin.
+func requireAdminForInfraGrant(c *gin.Context, grant *models.Grant, operation string) error {
+	if !resource.IsInfra(grant.Resource) {
+		return nil
+	}
+
+	if _, err := RequireInfraRole(c, models.InfraAdminRole); err != nil {
+		return HandleAuthErr(err, "infra grant", operation, models.InfraAdminRole)
+	}
+	return nil
+}
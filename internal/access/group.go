@@ -25,7 +25,7 @@ func isUserInGroup(c *gin.Context, requestedResourceID uid.ID) (bool, error) {
 	return false, nil
 }
 
-func ListGroups(c *gin.Context, name string, userID uid.ID, p *models.Pagination) ([]models.Group, error) {
+func ListGroups(c *gin.Context, name string, userID uid.ID, groupID uid.ID, p *models.Pagination) ([]models.Group, error) {
 	var selectors []data.SelectorFunc = []data.SelectorFunc{}
 	if name != "" {
 		selectors = append(selectors, data.ByName(name))
@@ -33,8 +33,9 @@ func ListGroups(c *gin.Context, name string, userID uid.ID, p *models.Pagination
 	if userID != 0 {
 		selectors = append(selectors, data.ByGroupMember(userID))
 	}
+	selectors = append(selectors, data.ByOptionalMemberOfGroup(groupID))
 
-	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole, models.PermissionGroupsRead}
 	db, err := RequireInfraRole(c, roles...)
 	if err == nil {
 		return data.ListGroups(db, p, selectors...)
@@ -57,16 +58,17 @@ func ListGroups(c *gin.Context, name string, userID uid.ID, p *models.Pagination
 }
 
 func CreateGroup(c *gin.Context, group *models.Group) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	roles := []string{models.InfraAdminRole, models.PermissionGroupsCreate}
+	db, err := RequireInfraRole(c, roles...)
 	if err != nil {
-		return HandleAuthErr(err, "group", "create", models.InfraAdminRole)
+		return HandleAuthErr(err, "group", "create", roles...)
 	}
 
 	return data.CreateGroup(db, group)
 }
 
 func GetGroup(c *gin.Context, id uid.ID) (*models.Group, error) {
-	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole, models.PermissionGroupsRead}
 	db, err := hasAuthorization(c, id, isUserInGroup, roles...)
 	if err != nil {
 		return nil, HandleAuthErr(err, "group", "get", roles...)
@@ -75,6 +77,15 @@ func GetGroup(c *gin.Context, id uid.ID) (*models.Group, error) {
 	return data.GetGroup(db, data.ByID(id))
 }
 
+func SaveGroup(c *gin.Context, group *models.Group) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "group", "update", models.InfraAdminRole)
+	}
+
+	return data.SaveGroup(db, group)
+}
+
 func DeleteGroup(c *gin.Context, id uid.ID) error {
 	db, err := RequireInfraRole(c, models.InfraAdminRole)
 	if err != nil {
@@ -142,3 +153,60 @@ func UpdateUsersInGroup(c *gin.Context, groupID uid.ID, uidsToAdd []uid.ID, uids
 	}
 	return data.RemoveUsersFromGroup(db, groupID, rmIDList)
 }
+
+func checkGroupsInList(db *gorm.DB, ids []uid.ID) ([]uid.ID, error) {
+	groups, err := data.ListGroups(db, &models.Pagination{}, data.ByIDs(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	// return the original list if we found all of the IDs
+	if len(groups) == len(ids) {
+		return ids, nil
+	}
+
+	uidMap := make(map[uid.ID]bool)
+	for _, group := range groups {
+		uidMap[group.ID] = true
+	}
+
+	var uidStrList []string
+	for _, id := range ids {
+		_, ok := uidMap[id]
+		if !ok {
+			uidStrList = append(uidStrList, id.String())
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %s", internal.ErrBadRequest, "Couldn't find UIDs: "+strings.Join(uidStrList, ","))
+}
+
+// UpdateGroupsInGroup adds and removes groups which are members of the group.
+// The members of a member group are also members of the group.
+func UpdateGroupsInGroup(c *gin.Context, groupID uid.ID, idsToAdd []uid.ID, idsToRemove []uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "group", "update", models.InfraAdminRole)
+	}
+
+	_, err = data.GetGroup(db, data.ByID(groupID))
+	if err != nil {
+		return err
+	}
+
+	addIDList, err := checkGroupsInList(db, idsToAdd)
+	if err != nil {
+		return err
+	}
+
+	rmIDList, err := checkGroupsInList(db, idsToRemove)
+	if err != nil {
+		return err
+	}
+
+	err = data.AddGroupsToGroup(db, groupID, addIDList)
+	if err != nil {
+		return err
+	}
+	return data.RemoveGroupsFromGroup(db, groupID, rmIDList)
+}
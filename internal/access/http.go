@@ -0,0 +1,84 @@
+package access
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// destinationLoginLifetime is how long the connector of an http destination
+// has to exchange a login code for a token.
+const destinationLoginLifetime = time.Minute
+
+// CreateDestinationLogin creates a login code for the authenticated identity,
+// which the connector of an http destination exchanges for a token. The
+// redirect URL must be on the destination, so that the code is only sent to
+// its connector.
+func CreateDestinationLogin(c *gin.Context, destinationID uid.ID, redirectURL string) (string, error) {
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return "", fmt.Errorf("no active identity")
+	}
+
+	// the destination can not limit the login to the scopes of the key
+	if err := requireUnscopedAccessKey(c, "destination logins"); err != nil {
+		return "", err
+	}
+
+	db := getDB(c)
+
+	destination, err := data.GetDestination(db, data.ByID(destinationID))
+	if err != nil {
+		return "", err
+	}
+
+	if destination.Kind != models.DestinationKindHTTP {
+		return "", fmt.Errorf("%w: destination %s does not accept browser logins", internal.ErrBadRequest, destination.Name)
+	}
+
+	if !sameHost(redirectURL, destination.ConnectionURL) {
+		return "", fmt.Errorf("%w: redirect url must be on destination %s", internal.ErrBadRequest, destination.Name)
+	}
+
+	grants, err := data.ListGrants(db, &models.Pagination{}, data.GrantsInheritedBySubject(identity.PolyID()), data.ByResource(destination.Name))
+	if err != nil {
+		return "", fmt.Errorf("list grants: %w", err)
+	}
+
+	if len(grants) == 0 {
+		return "", AuthorizationError{Resource: destination.Name, Operation: "connect to"}
+	}
+
+	return data.CreateAccessKey(db, &models.AccessKey{
+		IssuedFor:  identity.ID,
+		ProviderID: currentProviderID(c),
+		Scopes:     models.CommaSeparatedStrings{models.ScopeDestinationLogin},
+		ExpiresAt:  time.Now().Add(destinationLoginLifetime).UTC(),
+	})
+}
+
+// sameHost returns true if the https URL is on the host of the address. The
+// address may omit the default port.
+func sameHost(rawURL, addr string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "https" {
+		return false
+	}
+
+	return withDefaultPort(u.Host) == withDefaultPort(addr)
+}
+
+func withDefaultPort(host string) string {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		return net.JoinHostPort(host, "443")
+	}
+	return host
+}
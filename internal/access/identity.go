@@ -34,7 +34,7 @@ func AuthenticatedIdentity(c *gin.Context) *models.Identity {
 }
 
 func GetIdentity(c *gin.Context, id uid.ID) (*models.Identity, error) {
-	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole, models.PermissionUsersRead}
 	db, err := hasAuthorization(c, id, isIdentitySelf, roles...)
 	if err != nil {
 		return nil, HandleAuthErr(err, "user", "get", roles...)
@@ -44,9 +44,10 @@ func GetIdentity(c *gin.Context, id uid.ID) (*models.Identity, error) {
 }
 
 func CreateIdentity(c *gin.Context, identity *models.Identity) error {
-	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	roles := []string{models.InfraAdminRole, models.PermissionUsersCreate}
+	db, err := RequireInfraRole(c, roles...)
 	if err != nil {
-		return HandleAuthErr(err, "user", "create", models.InfraAdminRole)
+		return HandleAuthErr(err, "user", "create", roles...)
 	}
 
 	return data.CreateIdentity(db, identity)
@@ -102,7 +103,7 @@ func DeleteIdentity(c *gin.Context, id uid.ID) error {
 }
 
 func ListIdentities(c *gin.Context, name string, groupID uid.ID, ids []uid.ID, p *models.Pagination) ([]models.Identity, error) {
-	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole}
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole, models.PermissionUsersRead}
 	db, err := RequireInfraRole(c, roles...)
 	if err != nil {
 		return nil, HandleAuthErr(err, "users", "list", roles...)
@@ -127,9 +128,7 @@ func GetContextProviderIdentity(c *gin.Context) (*models.Provider, string, error
 	// does not need authorization check, this action is limited to the calling user
 	db := getDB(c)
 
-	accessKey := currentAccessKey(c)
-
-	providerUser, err := data.GetProviderUser(db, accessKey.ProviderID, identity.ID)
+	providerUser, err := data.GetProviderUser(db, currentProviderID(c), identity.ID)
 	if err != nil {
 		return nil, "", err
 	}
@@ -155,9 +154,7 @@ func UpdateIdentityInfoFromProvider(c *gin.Context, oidc providers.OIDCClient) e
 	// does not need authorization check, this action is limited to the calling user
 	db := getDB(c)
 
-	accessKey := currentAccessKey(c)
-
-	provider, err := data.GetProvider(db, data.ByID(accessKey.ProviderID))
+	provider, err := data.GetProvider(db, data.ByID(currentProviderID(c)))
 	if err != nil {
 		return fmt.Errorf("user info provider: %w", err)
 	}
@@ -0,0 +1,25 @@
+package access
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// UnlockUser clears the failed logins of a user, so that they can login
+// again before their lockout expires
+func UnlockUser(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "user", "unlock", models.InfraAdminRole)
+	}
+
+	identity, err := data.GetIdentity(db, data.ByID(id))
+	if err != nil {
+		return err
+	}
+
+	return data.DeleteLoginFailures(db, data.ByLoginFailureKey(models.LoginFailureKindUser, identity.Name))
+}
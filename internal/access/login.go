@@ -1,6 +1,7 @@
 package access
 
 import (
+	"regexp"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -12,7 +13,7 @@ import (
 // Login uses a login method to authenticate a user
 func Login(c *gin.Context, loginMethod authn.LoginMethod, keyExpiresAt time.Time, keyExtension time.Duration) (*models.AccessKey, string, bool, error) {
 	db := getDB(c)
-	key, bearer, err := authn.Login(c.Request.Context(), db, loginMethod, keyExpiresAt, keyExtension)
+	key, bearer, err := authn.Login(c.Request.Context(), db, loginMethod, RequestClientMetadata(c), keyExpiresAt, keyExtension)
 	if err != nil {
 		return nil, "", false, err
 	}
@@ -27,3 +28,35 @@ func Login(c *gin.Context, loginMethod authn.LoginMethod, keyExpiresAt time.Time
 
 	return key, bearer, requiresUpdate, nil
 }
+
+// CheckLoginLockout returns an error if logins for the key are locked
+func CheckLoginLockout(c *gin.Context, lockout authn.LoginLockout, key string) error {
+	return lockout.Check(getDB(c), key, time.Now())
+}
+
+// LoginFailed records a failed login for the key
+func LoginFailed(c *gin.Context, lockout authn.LoginLockout, key string) error {
+	return lockout.Fail(getDB(c), key, time.Now())
+}
+
+// LoginSucceeded forgets the failed logins for the key
+func LoginSucceeded(c *gin.Context, lockout authn.LoginLockout, key string) error {
+	return lockout.Reset(getDB(c), key)
+}
+
+// userAgentVersion matches the user agent of api.Client, such as
+// "Infra/0.13.0 (infra 0.16.0; linux/amd64)"
+var userAgentVersion = regexp.MustCompile(`^Infra/\S+ \(\S+ (\S+);`)
+
+// RequestClientMetadata returns the client of the request, which is recorded
+// on the access key of the session.
+func RequestClientMetadata(c *gin.Context) models.ClientMetadata {
+	userAgent := c.Request.UserAgent()
+
+	client := models.ClientMetadata{IP: c.ClientIP(), UserAgent: userAgent}
+	if match := userAgentVersion.FindStringSubmatch(userAgent); match != nil && match[1] != "unknown" {
+		client.Version = match[1]
+	}
+
+	return client
+}
@@ -0,0 +1,140 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// mfaIssuer is the name authenticator apps show for the TOTP secret
+const mfaIssuer = "Infra"
+
+// CreateMFA starts the enrollment of the user in multi-factor authentication,
+// and returns the TOTP secret and its provisioning URI. MFA is not required
+// to login until a code has been verified with VerifyMFA.
+func CreateMFA(c *gin.Context, userID uid.ID) (secret string, provisioningURI string, err error) {
+	db, credential, err := selfCredential(c, userID, "enroll in")
+	if err != nil {
+		return "", "", err
+	}
+
+	if credential.MFAEnabled {
+		return "", "", fmt.Errorf("%w: mfa is already enabled, disable it to enroll again", internal.ErrBadRequest)
+	}
+
+	secret, err = authn.NewTOTPSecret()
+	if err != nil {
+		return "", "", err
+	}
+
+	credential.MFASecret = models.EncryptedAtRest(secret)
+	if err := data.SaveCredential(db, credential); err != nil {
+		return "", "", fmt.Errorf("save credential: %w", err)
+	}
+
+	identity := AuthenticatedIdentity(c)
+	return secret, authn.TOTPProvisioningURI(mfaIssuer, identity.Name, secret), nil
+}
+
+// VerifyMFA completes the enrollment of the user with a code generated from
+// the secret, and returns the recovery codes of the user.
+func VerifyMFA(c *gin.Context, userID uid.ID, code string) ([]string, error) {
+	db, credential, err := selfCredential(c, userID, "enroll in")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case credential.MFAEnabled:
+		return nil, fmt.Errorf("%w: mfa is already enabled", internal.ErrBadRequest)
+	case credential.MFASecret == "":
+		return nil, fmt.Errorf("%w: mfa enrollment has not been started", internal.ErrBadRequest)
+	}
+
+	counter, ok := authn.ValidateTOTP(string(credential.MFASecret), code, time.Now(), credential.MFALastCounter)
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid mfa code", internal.ErrBadRequest)
+	}
+
+	codes, hashes, err := authn.NewRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	credential.MFAEnabled = true
+	credential.MFARecoveryCodes = hashes
+	credential.MFALastCounter = counter
+	if err := data.SaveCredential(db, credential); err != nil {
+		return nil, fmt.Errorf("save credential: %w", err)
+	}
+
+	// the user has enrolled, remove the mfa-enrollment scope from the access key
+	if key, ok := c.Value("key").(*models.AccessKey); ok && key.Scopes.Includes(models.ScopeMFAEnrollment) {
+		key.Scopes = models.CommaSeparatedStrings{}
+		if err := data.SaveAccessKey(db, key); err != nil {
+			return nil, fmt.Errorf("updating access key: %w", err)
+		}
+	}
+
+	return codes, nil
+}
+
+// DeleteMFA disables multi-factor authentication for the user. Users who
+// disable it for themselves must provide a code from their authenticator app,
+// or a recovery code, so that a stolen session can't be used to remove the
+// second factor. Admins can disable it for users who lost their device and
+// recovery codes.
+func DeleteMFA(c *gin.Context, userID uid.ID, code string) error {
+	self, _ := isIdentitySelf(c, userID)
+
+	db, err := hasAuthorization(c, userID, isIdentitySelf, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "mfa", "disable", models.InfraAdminRole)
+	}
+
+	credential, err := data.GetCredential(db, data.ByIdentityID(userID))
+	if err != nil {
+		return err
+	}
+
+	// an enrollment which was not verified can be removed without a code
+	if self && credential.MFAEnabled && !authn.UseMFACode(credential, code, time.Now()) {
+		return fmt.Errorf("%w: a valid mfa code is required to disable mfa", internal.ErrBadRequest)
+	}
+
+	credential.MFASecret = ""
+	credential.MFAEnabled = false
+	credential.MFARecoveryCodes = nil
+	credential.MFALastCounter = 0
+
+	return data.SaveCredential(db, credential)
+}
+
+// selfCredential returns the credential of the user, who must be the
+// authenticated identity. MFA is only available to users of the infra
+// provider, which is where their credential comes from.
+func selfCredential(c *gin.Context, userID uid.ID, operation string) (*gorm.DB, *models.Credential, error) {
+	if self, _ := isIdentitySelf(c, userID); !self {
+		return nil, nil, AuthorizationError{Resource: "mfa for another user", Operation: operation}
+	}
+
+	db := getDB(c)
+	credential, err := data.GetCredential(db, data.ByIdentityID(userID))
+	if err != nil {
+		if errors.Is(err, internal.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%w: mfa is only available to users who login with a password", internal.ErrBadRequest)
+		}
+		return nil, nil, fmt.Errorf("get credential: %w", err)
+	}
+
+	return db, credential, nil
+}
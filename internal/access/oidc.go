@@ -0,0 +1,182 @@
+package access
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/claims"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// oidcAuthorizationCodeLifetime is how long a client has to exchange an
+// authorization code for tokens
+const oidcAuthorizationCodeLifetime = 5 * time.Minute
+
+func CreateOIDCClient(c *gin.Context, client *models.OIDCClient, public bool) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "oidc client", "create", models.InfraAdminRole)
+	}
+
+	return data.CreateOIDCClient(db, client, public)
+}
+
+func ListOIDCClients(c *gin.Context, name string, p *models.Pagination) ([]models.OIDCClient, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "oidc clients", "list", models.InfraAdminRole)
+	}
+
+	return data.ListOIDCClients(db, p, data.ByOptionalName(name))
+}
+
+func DeleteOIDCClient(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "oidc client", "delete", models.InfraAdminRole)
+	}
+
+	return data.DeleteOIDCClient(db, id)
+}
+
+// GetOIDCClientForRedirect returns the client, if the redirect URI is one of
+// its registered URIs. It does not require authentication.
+func GetOIDCClientForRedirect(c *gin.Context, clientID, redirectURI string) (*models.OIDCClient, error) {
+	client, err := data.GetOIDCClient(getDB(c), data.ByClientID(clientID))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return nil, fmt.Errorf("%w: unknown client_id", internal.ErrBadRequest)
+	case err != nil:
+		return nil, err
+	}
+
+	for _, uri := range client.RedirectURIs {
+		if uri == redirectURI {
+			return client, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%w: redirect_uri is not registered for client %s", internal.ErrBadRequest, client.Name)
+}
+
+// CreateOIDCAuthorizationCode authorizes the client for the authenticated
+// user, and returns the code which the client exchanges for tokens.
+func CreateOIDCAuthorizationCode(c *gin.Context, client *models.OIDCClient, redirectURI string, scopes []string, nonce, codeChallenge string) (string, error) {
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return "", fmt.Errorf("%w: no authenticated user", internal.ErrUnauthorized)
+	}
+
+	// the tokens of the client can not be limited to the scopes of the key
+	if err := requireUnscopedAccessKey(c, "OIDC authorization codes"); err != nil {
+		return "", err
+	}
+
+	code := &models.OIDCAuthorizationCode{
+		ClientID:      client.ID,
+		IdentityID:    identity.ID,
+		RedirectURI:   redirectURI,
+		Scopes:        scopes,
+		Nonce:         nonce,
+		CodeChallenge: codeChallenge,
+		ExpiresAt:     time.Now().Add(oidcAuthorizationCodeLifetime).UTC(),
+	}
+
+	if err := data.CreateOIDCAuthorizationCode(getDB(c), code); err != nil {
+		return "", err
+	}
+
+	return code.Code, nil
+}
+
+// OIDCTokenRequest is an authorization code grant from an OIDC client.
+type OIDCTokenRequest struct {
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+}
+
+// ExchangeOIDCAuthorizationCode exchanges an authorization code for an ID
+// token and an access token, which expire at expires. It does not require
+// authentication, the client authenticates with its secret or the PKCE
+// verifier. Errors for the client are ErrUnauthorized, and errors for the
+// code are ErrBadRequest.
+func ExchangeOIDCAuthorizationCode(c *gin.Context, issuer string, r OIDCTokenRequest, expires time.Time) (idToken string, accessToken string, err error) {
+	db := getDB(c)
+
+	client, err := data.GetOIDCClient(db, data.ByClientID(r.ClientID))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return "", "", fmt.Errorf("%w: unknown client", internal.ErrUnauthorized)
+	case err != nil:
+		return "", "", err
+	}
+
+	if !client.Public() && !data.ValidateOIDCClientSecret(client, r.ClientSecret) {
+		return "", "", fmt.Errorf("%w: invalid client secret", internal.ErrUnauthorized)
+	}
+
+	code, err := data.GetOIDCAuthorizationCode(db, data.ByOIDCAuthorizationCode(r.Code))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return "", "", fmt.Errorf("%w: invalid code", internal.ErrBadRequest)
+	case err != nil:
+		return "", "", err
+	}
+
+	// codes are used once, whether or not the exchange succeeds
+	if err := data.DeleteOIDCAuthorizationCode(db, code.ID); err != nil {
+		return "", "", err
+	}
+
+	if code.ClientID != client.ID || time.Now().After(code.ExpiresAt) {
+		return "", "", fmt.Errorf("%w: invalid code", internal.ErrBadRequest)
+	}
+
+	if code.RedirectURI != r.RedirectURI {
+		return "", "", fmt.Errorf("%w: redirect_uri does not match the authorization request", internal.ErrBadRequest)
+	}
+
+	if code.CodeChallenge != "" && !verifyCodeChallenge(code.CodeChallenge, r.CodeVerifier) {
+		return "", "", fmt.Errorf("%w: invalid code_verifier", internal.ErrBadRequest)
+	}
+
+	return data.CreateOIDCTokens(db, issuer, client, code, expires)
+}
+
+// verifyCodeChallenge returns true if the verifier answers the S256 PKCE
+// challenge (RFC 7636).
+func verifyCodeChallenge(challenge, verifier string) bool {
+	sum := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(sum[:])
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(expected)) == 1
+}
+
+// OIDCUserInfo returns the claims about the user of an access token issued to
+// an OIDC client. The token is the authentication.
+func OIDCUserInfo(c *gin.Context, issuer, accessToken string) (*claims.OIDCUser, error) {
+	db := getDB(c)
+
+	identityID, scopes, err := data.ValidateOIDCAccessToken(db, issuer, accessToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", internal.ErrUnauthorized, err)
+	}
+
+	user, err := data.OIDCUserClaims(db, identityID, scopes)
+	if errors.Is(err, internal.ErrNotFound) {
+		return nil, fmt.Errorf("%w: the user no longer exists", internal.ErrUnauthorized)
+	}
+
+	return user, err
+}
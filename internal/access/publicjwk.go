@@ -11,15 +11,10 @@ import (
 
 func GetPublicJWK(c *gin.Context) ([]jose.JSONWebKey, error) {
 	db := getDB(c)
-	settings, err := data.GetSettings(db)
+	keys, err := data.PublicJWKs(db)
 	if err != nil {
 		return nil, fmt.Errorf("could not get JWKs: %w", err)
 	}
 
-	var pubKey jose.JSONWebKey
-	if err := pubKey.UnmarshalJSON(settings.PublicJWK); err != nil {
-		return nil, fmt.Errorf("could not get JWKs: %w", err)
-	}
-
-	return []jose.JSONWebKey{pubKey}, nil
+	return keys, nil
 }
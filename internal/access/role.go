@@ -0,0 +1,70 @@
+package access
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func GetRole(c *gin.Context, id uid.ID) (*models.Role, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole, models.PermissionRolesRead}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "role", "get", roles...)
+	}
+
+	return data.GetRole(db, data.ByID(id))
+}
+
+func ListRoles(c *gin.Context, name string, p *models.Pagination) ([]models.Role, error) {
+	roles := []string{models.InfraAdminRole, models.InfraViewRole, models.InfraConnectorRole, models.PermissionRolesRead}
+	db, err := RequireInfraRole(c, roles...)
+	if err != nil {
+		return nil, HandleAuthErr(err, "roles", "list", roles...)
+	}
+
+	return data.ListRoles(db, p, data.ByOptionalName(name))
+}
+
+func CreateRole(c *gin.Context, role *models.Role) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "role", "create", models.InfraAdminRole)
+	}
+
+	for _, builtin := range models.BuiltinRoles {
+		if role.Name == builtin {
+			return fmt.Errorf("%w: %q is a builtin role", internal.ErrBadRequest, role.Name)
+		}
+	}
+
+	if identity := AuthenticatedIdentity(c); identity != nil {
+		role.CreatedBy = identity.ID
+	}
+
+	return data.CreateRole(db, role)
+}
+
+func SaveRole(c *gin.Context, role *models.Role) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "role", "update", models.InfraAdminRole)
+	}
+
+	return data.SaveRole(db, role)
+}
+
+// DeleteRole removes the role, and all the grants of the role.
+func DeleteRole(c *gin.Context, id uid.ID) error {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "role", "delete", models.InfraAdminRole)
+	}
+
+	return data.DeleteRoles(db, data.ByID(id))
+}
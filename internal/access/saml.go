@@ -0,0 +1,61 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// samlCLIRedirectURL is the local server of the CLI, which receives the login
+// code of a SAML login
+const samlCLIRedirectURL = "http://localhost:8301"
+
+// StartSAMLLogin begins a login with a SAML provider. It does not require
+// authentication. The redirect URL must be the local server of the CLI, or on
+// the Infra server itself, so that login codes are only sent to Infra clients.
+func StartSAMLLogin(c *gin.Context, provider *models.Provider, sp *saml.ServiceProvider, redirectURL, state string) (*url.URL, error) {
+	if provider.Kind != models.ProviderKindSAML {
+		return nil, fmt.Errorf("%w: provider %s is not a saml provider", internal.ErrBadRequest, provider.Name)
+	}
+
+	if redirectURL != samlCLIRedirectURL && !sameOrigin(redirectURL, sp.AcsURL) {
+		return nil, fmt.Errorf("%w: redirect url must be on the infra server", internal.ErrBadRequest)
+	}
+
+	return authn.StartSAMLLogin(getDB(c), sp, provider.ID, redirectURL, state)
+}
+
+// CompleteSAMLLogin validates the response of the identity provider of a SAML
+// login. It does not require authentication, the response is signed by the
+// identity provider.
+func CompleteSAMLLogin(c *gin.Context, provider *models.Provider, sp *saml.ServiceProvider, relayState, samlResponse string) (*url.URL, error) {
+	if provider.Kind != models.ProviderKindSAML {
+		return nil, fmt.Errorf("%w: provider %s is not a saml provider", internal.ErrBadRequest, provider.Name)
+	}
+
+	redirectURL, err := authn.CompleteSAMLLogin(getDB(c), sp, provider, relayState, samlResponse)
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return nil, fmt.Errorf("%w: unknown saml login", internal.ErrBadRequest)
+	case err != nil:
+		return nil, err
+	}
+
+	return redirectURL, nil
+}
+
+func sameOrigin(rawURL string, origin url.URL) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+
+	return u.Scheme == origin.Scheme && u.Host == origin.Host
+}
@@ -0,0 +1,343 @@
+package access
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ssoroka/slice"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// CreateSCIMAccessKey issues an access key which an identity provider can use
+// to provision users and groups through the SCIM endpoints.
+func CreateSCIMAccessKey(c *gin.Context, providerID uid.ID, ttl time.Duration) (*models.AccessKey, string, error) {
+	db, err := RequireInfraRole(c, models.InfraAdminRole)
+	if err != nil {
+		return nil, "", HandleAuthErr(err, "access key", "create", models.InfraAdminRole)
+	}
+
+	provider, err := data.GetProvider(db, data.ByID(providerID))
+	if err != nil {
+		return nil, "", err
+	}
+
+	if provider.Kind == models.ProviderKindInfra {
+		return nil, "", fmt.Errorf("%w: users of the infra provider can not be provisioned with SCIM", internal.ErrBadRequest)
+	}
+
+	identity := AuthenticatedIdentity(c)
+
+	key := &models.AccessKey{
+		Name:       fmt.Sprintf("%s-scim-%s", provider.Name, uid.New()),
+		IssuedFor:  identity.ID,
+		ProviderID: provider.ID,
+		Scopes:     models.CommaSeparatedStrings{models.ScopeSCIM},
+		ExpiresAt:  time.Now().Add(ttl).UTC(),
+	}
+
+	body, err := data.CreateAccessKey(db, key)
+	if err != nil {
+		return nil, "", fmt.Errorf("create token: %w", err)
+	}
+
+	return key, body, nil
+}
+
+// requireSCIMProvider checks that the request was authenticated with an access
+// key scoped to SCIM provisioning, and returns the provider the key was issued for.
+func requireSCIMProvider(c *gin.Context) (*gorm.DB, *models.Provider, error) {
+	key := currentAccessKey(c)
+	if key == nil || !key.Scopes.Includes(models.ScopeSCIM) {
+		return nil, nil, AuthorizationError{Resource: "users", Operation: "provision", RequiredRoles: []string{models.ScopeSCIM}}
+	}
+
+	db := getDB(c)
+
+	provider, err := data.GetProvider(db, data.ByID(key.ProviderID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("scim provider: %w", err)
+	}
+
+	return db, provider, nil
+}
+
+func ListSCIMUsers(c *gin.Context, name string) ([]models.Identity, error) {
+	db, provider, err := requireSCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.ListIdentities(db, &models.Pagination{}, data.ByOptionalName(name), data.ByProviderUser(provider.ID))
+}
+
+func GetSCIMUser(c *gin.Context, id uid.ID) (*models.Identity, error) {
+	db, provider, err := requireSCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.GetIdentity(db, data.ByID(id), data.ByProviderUser(provider.ID))
+}
+
+// ProvisionSCIMUser adds the identity to the provider, creating the identity if
+// it does not exist yet.
+func ProvisionSCIMUser(c *gin.Context, name string) (*models.Identity, error) {
+	db, provider, err := requireSCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	identity, err := data.GetIdentity(db, data.ByName(name))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		identity = &models.Identity{Name: name}
+		if err := data.CreateIdentity(db, identity); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		_, err := data.GetProviderUser(db, provider.ID, identity.ID)
+		switch {
+		case err == nil:
+			return nil, data.UniqueConstraintError{Table: "user", Column: "userName"}
+		case !errors.Is(err, internal.ErrNotFound):
+			return nil, err
+		}
+	}
+
+	if _, err := data.CreateProviderUser(db, provider, identity); err != nil {
+		return nil, fmt.Errorf("create provider user: %w", err)
+	}
+
+	return identity, nil
+}
+
+// DeprovisionSCIMUser removes the identity from the provider. The identity is
+// removed from the groups it received from the provider, and any sessions
+// started with the provider are revoked. If the identity does not belong to any
+// other provider the identity and its grants are deleted.
+func DeprovisionSCIMUser(c *gin.Context, id uid.ID) error {
+	db, provider, err := requireSCIMProvider(c)
+	if err != nil {
+		return err
+	}
+
+	identity, err := data.GetIdentity(db.Preload("Groups"), data.ByID(id), data.ByProviderUser(provider.ID))
+	if err != nil {
+		return err
+	}
+
+	if err := data.AssignIdentityToGroups(db, identity, provider, nil); err != nil {
+		return fmt.Errorf("remove provider groups: %w", err)
+	}
+
+	if err := data.DeleteAccessKeys(db, data.ByIssuedFor(identity.ID), data.ByProviderID(provider.ID)); err != nil {
+		return fmt.Errorf("delete provider access keys: %w", err)
+	}
+
+	if err := data.DeleteProviderUsers(db, data.ByIdentityID(identity.ID), data.ByProviderID(provider.ID)); err != nil {
+		return fmt.Errorf("delete provider user: %w", err)
+	}
+
+	remaining, err := data.ListProviderUsers(db, &models.Pagination{}, data.ByIdentityID(identity.ID))
+	if err != nil {
+		return err
+	}
+
+	if len(remaining) == 0 {
+		return data.DeleteIdentities(db, data.ByID(identity.ID))
+	}
+
+	return nil
+}
+
+func ListSCIMGroups(c *gin.Context, name string) ([]models.Group, error) {
+	db, _, err := requireSCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.ListGroups(db, &models.Pagination{}, data.ByOptionalName(name))
+}
+
+func GetSCIMGroup(c *gin.Context, id uid.ID) (*models.Group, error) {
+	db, _, err := requireSCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.GetGroup(db, data.ByID(id))
+}
+
+func CreateSCIMGroup(c *gin.Context, name string) (*models.Group, error) {
+	db, provider, err := requireSCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &models.Group{Name: name, CreatedByProvider: provider.ID}
+	if err := data.CreateGroup(db, group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// RenameSCIMGroup changes the name of a group created by the provider.
+func RenameSCIMGroup(c *gin.Context, id uid.ID, name string) (*models.Group, error) {
+	db, provider, err := requireSCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := data.GetGroup(db, data.ByID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	if group.Name == name {
+		return group, nil
+	}
+
+	if group.CreatedByProvider != provider.ID {
+		return nil, fmt.Errorf("%w: groups can only be renamed by the provider which created them", internal.ErrBadRequest)
+	}
+
+	members, err := ListSCIMGroupMembers(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, member := range members {
+		err := updateProviderUserGroups(db, provider, member.ID, func(groups []string) []string {
+			return append(slice.Subtract(groups, []string{group.Name}), name)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	group.Name = name
+	if err := data.SaveGroup(db, group); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// DeleteSCIMGroup deletes a group created by the provider. Groups created
+// elsewhere are kept, but all of the provider users are removed from the group.
+func DeleteSCIMGroup(c *gin.Context, id uid.ID) error {
+	db, provider, err := requireSCIMProvider(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := data.GetGroup(db, data.ByID(id))
+	if err != nil {
+		return err
+	}
+
+	members, err := ListSCIMGroupMembers(c, id)
+	if err != nil {
+		return err
+	}
+
+	ids := slice.Map[models.Identity, uid.ID](members, func(i models.Identity) uid.ID {
+		return i.ID
+	})
+	if err := UpdateSCIMGroupMembers(c, id, nil, ids); err != nil {
+		return err
+	}
+
+	if group.CreatedByProvider != provider.ID {
+		return nil
+	}
+
+	return data.DeleteGroups(db, data.ByID(id))
+}
+
+// ListSCIMGroupMembers returns the members of the group which are users of the
+// provider. Members from other providers are not visible to the provider.
+func ListSCIMGroupMembers(c *gin.Context, id uid.ID) ([]models.Identity, error) {
+	db, provider, err := requireSCIMProvider(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.ListIdentities(db, &models.Pagination{}, data.ByOptionalIdentityGroupID(id), data.ByProviderUser(provider.ID))
+}
+
+// UpdateSCIMGroupMembers adds and removes users of the provider from the group.
+// The groups of the provider user are updated as well, so that the next
+// sync of the user with the provider starts from the same state.
+func UpdateSCIMGroupMembers(c *gin.Context, id uid.ID, idsToAdd, idsToRemove []uid.ID) error {
+	db, provider, err := requireSCIMProvider(c)
+	if err != nil {
+		return err
+	}
+
+	group, err := data.GetGroup(db, data.ByID(id))
+	if err != nil {
+		return err
+	}
+
+	// check all of the users before making any changes
+	for _, identityID := range append(append([]uid.ID{}, idsToAdd...), idsToRemove...) {
+		if _, err := getSCIMProviderUser(db, provider, identityID); err != nil {
+			return err
+		}
+	}
+
+	for _, identityID := range idsToAdd {
+		err := updateProviderUserGroups(db, provider, identityID, func(groups []string) []string {
+			return append(slice.Subtract(groups, []string{group.Name}), group.Name)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, identityID := range idsToRemove {
+		err := updateProviderUserGroups(db, provider, identityID, func(groups []string) []string {
+			return slice.Subtract(groups, []string{group.Name})
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := data.AddUsersToGroup(db, group.ID, idsToAdd); err != nil {
+		return err
+	}
+
+	return data.RemoveUsersFromGroup(db, group.ID, idsToRemove)
+}
+
+func getSCIMProviderUser(db *gorm.DB, provider *models.Provider, identityID uid.ID) (*models.ProviderUser, error) {
+	pu, err := data.GetProviderUser(db, provider.ID, identityID)
+	if errors.Is(err, internal.ErrNotFound) {
+		return nil, fmt.Errorf("%w: user %s is not a member of the provider", internal.ErrBadRequest, identityID)
+	}
+	return pu, err
+}
+
+func updateProviderUserGroups(db *gorm.DB, provider *models.Provider, identityID uid.ID, update func([]string) []string) error {
+	pu, err := getSCIMProviderUser(db, provider, identityID)
+	if err != nil {
+		return err
+	}
+
+	pu.Groups = update(pu.Groups)
+	pu.LastUpdate = time.Now().UTC()
+
+	return data.UpdateProviderUser(db, pu)
+}
@@ -0,0 +1,59 @@
+package access
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// ListSessions returns the sessions of a user. Users can list their own
+// sessions.
+func ListSessions(c *gin.Context, userID uid.ID) ([]models.AccessKey, error) {
+	db, err := hasAuthorization(c, userID, isIdentitySelf, models.InfraAdminRole)
+	if err != nil {
+		return nil, HandleAuthErr(err, "sessions", "list", models.InfraAdminRole)
+	}
+
+	return data.ListSessions(db, userID)
+}
+
+// DeleteSession revokes a session of a user. Users can revoke their own
+// sessions.
+func DeleteSession(c *gin.Context, userID, sessionID uid.ID) error {
+	db, err := hasAuthorization(c, userID, isIdentitySelf, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "sessions", "delete", models.InfraAdminRole)
+	}
+
+	session, err := data.GetAccessKey(db, data.ByID(sessionID), data.ByIssuedFor(userID), data.BySession())
+	if err != nil {
+		return fmt.Errorf("%w: session %s", internal.ErrNotFound, sessionID)
+	}
+
+	return data.DeleteAccessKey(db, session.ID)
+}
+
+// DeleteSessions revokes all the sessions of a user, which logs them out
+// everywhere. Users can revoke their own sessions.
+func DeleteSessions(c *gin.Context, userID uid.ID) error {
+	db, err := hasAuthorization(c, userID, isIdentitySelf, models.InfraAdminRole)
+	if err != nil {
+		return HandleAuthErr(err, "sessions", "delete", models.InfraAdminRole)
+	}
+
+	return data.DeleteAccessKeys(db, data.ByIssuedFor(userID), data.BySession())
+}
+
+// CurrentSessionID returns the ID of the access key of the request, or 0 if
+// the request was not authenticated with an access key.
+func CurrentSessionID(c *gin.Context) uid.ID {
+	if key := currentAccessKey(c); key != nil {
+		return key.ID
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package access
+
+import (
+	"crypto/ed25519"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/pki"
+	"github.com/infrahq/infra/uid"
+)
+
+// sshCertificateLifetime is how long an SSH user certificate is valid. The
+// certificate is only used to log in, so it does not need to outlive the
+// start of the session.
+const sshCertificateLifetime = 10 * time.Minute
+
+// GetSSHCertificateAuthority returns the public key which signs SSH user
+// certificates, in the authorized_keys format.
+func GetSSHCertificateAuthority(c *gin.Context) ([]byte, error) {
+	settings, err := data.GetSettings(getDB(c))
+	if err != nil {
+		return nil, fmt.Errorf("settings: %w", err)
+	}
+
+	return pki.MarshalSSHPublicKey(ed25519.PublicKey(settings.SSHCAPublicKey))
+}
+
+// CreateSSHCertificate signs an SSH user certificate for the public key of the
+// authenticated identity. The principals of the certificate are the users on
+// the host that the identity, or one of its groups, has a grant for. Grants
+// with conditions only apply when the request satisfies them.
+func CreateSSHCertificate(c *gin.Context, destinationID uid.ID, publicKey ssh.PublicKey) (*ssh.Certificate, error) {
+	identity := AuthenticatedIdentity(c)
+	if identity == nil {
+		return nil, fmt.Errorf("no active identity")
+	}
+
+	db := getDB(c)
+
+	destination, err := data.GetDestination(db, data.ByID(destinationID))
+	if err != nil {
+		return nil, err
+	}
+
+	if destination.Kind != models.DestinationKindSSH {
+		return nil, fmt.Errorf("%w: destination %s does not accept ssh connections", internal.ErrBadRequest, destination.Name)
+	}
+
+	principals, err := sshPrincipals(db, RequestOrigin(c), identity, destination)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(principals) == 0 {
+		return nil, AuthorizationError{Resource: destination.Name, Operation: "connect to", RequiredRoles: destination.Roles}
+	}
+
+	settings, err := data.GetSettings(db)
+	if err != nil {
+		return nil, fmt.Errorf("settings: %w", err)
+	}
+
+	return pki.SignSSHUserCertificate(ed25519.PrivateKey(settings.SSHCAPrivateKey), publicKey, pki.SSHUserCertificateOptions{
+		KeyID:      identity.Name,
+		Principals: principals,
+		Lifetime:   sshCertificateLifetime,
+	})
+}
+
+// sshPrincipals returns the users on the host that the identity can log in as.
+// The privilege of a grant on the host is the name of the user, and a custom
+// role grants each of its permissions. Users which do not exist on the host
+// are ignored, and so are grants with conditions which origin does not satisfy.
+func sshPrincipals(db *gorm.DB, origin Origin, identity *models.Identity, destination *models.Destination) ([]string, error) {
+	grants, err := data.ListGrants(db, &models.Pagination{}, data.GrantsInheritedBySubject(identity.PolyID()), data.ByResource(destination.Name))
+	if err != nil {
+		return nil, fmt.Errorf("list grants: %w", err)
+	}
+
+	roles, err := data.ListRoles(db, &models.Pagination{})
+	if err != nil {
+		return nil, fmt.Errorf("list roles: %w", err)
+	}
+
+	permissions := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		permissions[role.Name] = role.Permissions
+	}
+
+	users := make(map[string]bool, len(destination.Roles))
+	for _, user := range destination.Roles {
+		users[user] = true
+	}
+
+	allowed := make(map[string]bool)
+	for _, grant := range grants {
+		if !origin.satisfies(grant.Conditions) {
+			continue
+		}
+
+		privileges := []string{grant.Privilege}
+		if p, ok := permissions[grant.Privilege]; ok {
+			privileges = p
+		}
+
+		for _, privilege := range privileges {
+			if users[privilege] {
+				allowed[privilege] = true
+			}
+		}
+	}
+
+	principals := make([]string, 0, len(allowed))
+	for principal := range allowed {
+		principals = append(principals, principal)
+	}
+
+	sort.Strings(principals)
+	return principals, nil
+}
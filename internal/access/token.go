@@ -18,5 +18,18 @@ func CreateToken(c *gin.Context) (token *models.Token, err error) {
 	// does not need authorization check, limited to calling identity
 	db := getDB(c)
 
-	return data.CreateIdentityToken(db, identity.ID)
+	token, err = data.CreateIdentityToken(db, identity.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// a destination login code can only be exchanged once. There is no access
+	// key when a token is created for a new session, such as at signup.
+	if key, ok := c.Value("key").(*models.AccessKey); ok && key.Scopes.Includes(models.ScopeDestinationLogin) {
+		if err := data.DeleteAccessKey(db, key.ID); err != nil {
+			return nil, fmt.Errorf("delete login code: %w", err)
+		}
+	}
+
+	return token, nil
 }
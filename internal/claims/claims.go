@@ -1,7 +1,25 @@
 package claims
 
+// DestinationAudience is the audience of the tokens which destinations accept.
+// Other tokens signed by the server, such as the ID tokens of OIDC clients,
+// have another audience, so that a destination can not be accessed with them.
+const DestinationAudience = "infra:destinations"
+
 type Custom struct {
 	Name   string   `json:"name"`
 	Groups []string `json:"groups"`
 	Nonce  string   `json:"nonce"`
+	// Grants are the grants with conditions to the user, or to their groups.
+	Grants []ConditionalGrant `json:"grants,omitempty"`
+}
+
+// OIDCUser are the claims about a user in the ID tokens issued to OIDC
+// clients, and in the response of the userinfo endpoint. The claims other
+// than the subject depend on the scopes requested by the client.
+type OIDCUser struct {
+	Subject       string   `json:"sub"`
+	Email         string   `json:"email,omitempty"`
+	EmailVerified bool     `json:"email_verified,omitempty"`
+	Name          string   `json:"name,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
 }
@@ -0,0 +1,175 @@
+package claims
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	// the timezones of windows are loaded from the embedded database, as the
+	// host may not have one
+	_ "time/tzdata"
+)
+
+// Conditions restrict a grant to requests from some networks, or made during
+// some times. A grant applies to a request when the client IP is in one of the
+// CIDRs, and the time of the request is in one of the windows. The zero value
+// has no conditions.
+type Conditions struct {
+	CIDRs   []string     `json:"cidrs,omitempty"`
+	Windows []TimeWindow `json:"windows,omitempty"`
+}
+
+// TimeWindow is a time of day on some days of the week, such as 09:00 to
+// 17:00 from Monday to Friday. A window with an end before its start ends on
+// the next day.
+type TimeWindow struct {
+	Days     []string `json:"days,omitempty"` // the days the window starts on, as mon to sun, every day when empty
+	Start    string   `json:"start"`          // as 15:04
+	End      string   `json:"end"`            // as 15:04
+	Timezone string   `json:"timezone,omitempty"`
+}
+
+// GrantGroupPrefix is the prefix of the name of the group that a destination
+// binds a conditional grant to. The group is only included in a request which
+// satisfies the conditions of the grant.
+const GrantGroupPrefix = "infra:grant:"
+
+// ConditionalGrant is a grant with conditions, which a connector enforces for
+// each request.
+type ConditionalGrant struct {
+	ID         string     `json:"id"`
+	Conditions Conditions `json:"conditions"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func (c Conditions) IsZero() bool {
+	return len(c.CIDRs) == 0 && len(c.Windows) == 0
+}
+
+// Validate returns an error if a CIDR, day, time, or timezone can not be
+// parsed.
+func (c Conditions) Validate() error {
+	for _, cidr := range c.CIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("invalid cidr %q", cidr)
+		}
+	}
+
+	for _, w := range c.Windows {
+		if _, _, _, err := w.parse(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Allows returns true if a request from ip at time t satisfies the conditions.
+// Conditions which can not be parsed are never satisfied.
+func (c Conditions) Allows(ip net.IP, t time.Time) bool {
+	return c.allowsIP(ip) && c.allowsTime(t)
+}
+
+func (c Conditions) allowsIP(ip net.IP) bool {
+	if len(c.CIDRs) == 0 {
+		return true
+	}
+
+	for _, cidr := range c.CIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err == nil && ip != nil && network.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c Conditions) allowsTime(t time.Time) bool {
+	if len(c.Windows) == 0 {
+		return true
+	}
+
+	for _, w := range c.Windows {
+		if w.contains(t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w TimeWindow) contains(t time.Time) bool {
+	start, end, location, err := w.parse()
+	if err != nil {
+		return false
+	}
+
+	t = t.In(location)
+	minute := t.Hour()*60 + t.Minute()
+
+	if start < end {
+		return w.startsOn(t.Weekday()) && minute >= start && minute < end
+	}
+
+	// the window ends on the day after it starts
+	yesterday := (t.Weekday() + 6) % 7
+	return (w.startsOn(t.Weekday()) && minute >= start) || (w.startsOn(yesterday) && minute < end)
+}
+
+func (w TimeWindow) startsOn(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+
+	return false
+}
+
+// parse returns the start and end of the window, as minutes after midnight,
+// and the location of its timezone.
+func (w TimeWindow) parse() (start, end int, location *time.Location, err error) {
+	for _, d := range w.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			return 0, 0, nil, fmt.Errorf("invalid day %q, expected one of mon, tue, wed, thu, fri, sat, or sun", d)
+		}
+	}
+
+	startTime, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid start time %q, expected a time like 09:00", w.Start)
+	}
+
+	endTime, err := time.Parse("15:04", w.End)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid end time %q, expected a time like 17:00", w.End)
+	}
+
+	location, err = time.LoadLocation(w.Timezone)
+	if err != nil {
+		return 0, 0, nil, fmt.Errorf("invalid timezone %q", w.Timezone)
+	}
+
+	start = startTime.Hour()*60 + startTime.Minute()
+	end = endTime.Hour()*60 + endTime.Minute()
+	if start == end {
+		return 0, 0, nil, fmt.Errorf("start and end time of the window are the same")
+	}
+
+	return start, end, location, nil
+}
@@ -0,0 +1,113 @@
+package claims
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestConditions_Allows(t *testing.T) {
+	// Friday at 23:00 in UTC, and 19:00 in New York
+	friday := time.Date(2022, 10, 7, 23, 0, 0, 0, time.UTC)
+	vpn := net.ParseIP("10.1.2.3")
+
+	type testCase struct {
+		name       string
+		conditions Conditions
+		ip         net.IP
+		time       time.Time
+		expected   bool
+	}
+
+	testCases := []testCase{
+		{name: "no conditions", time: friday, expected: true},
+		{
+			name:       "ip in cidr",
+			conditions: Conditions{CIDRs: []string{"192.168.0.0/16", "10.0.0.0/8"}},
+			ip:         vpn,
+			time:       friday,
+			expected:   true,
+		},
+		{
+			name:       "ip not in cidr",
+			conditions: Conditions{CIDRs: []string{"192.168.0.0/16"}},
+			ip:         vpn,
+			time:       friday,
+		},
+		{
+			name:       "no ip",
+			conditions: Conditions{CIDRs: []string{"10.0.0.0/8"}},
+			time:       friday,
+		},
+		{
+			name:       "outside window",
+			conditions: Conditions{Windows: []TimeWindow{{Start: "09:00", End: "17:00"}}},
+			time:       friday,
+		},
+		{
+			name:       "window in timezone",
+			conditions: Conditions{Windows: []TimeWindow{{Start: "09:00", End: "20:00", Timezone: "America/New_York"}}},
+			time:       friday,
+			expected:   true,
+		},
+		{
+			name:       "overnight window on the day it starts",
+			conditions: Conditions{Windows: []TimeWindow{{Days: []string{"fri"}, Start: "22:00", End: "06:00"}}},
+			time:       friday,
+			expected:   true,
+		},
+		{
+			name:       "overnight window on the next day",
+			conditions: Conditions{Windows: []TimeWindow{{Days: []string{"fri"}, Start: "22:00", End: "06:00"}}},
+			time:       friday.Add(6 * time.Hour),
+			expected:   true,
+		},
+		{
+			name:       "overnight window after it ends",
+			conditions: Conditions{Windows: []TimeWindow{{Days: []string{"fri"}, Start: "22:00", End: "06:00"}}},
+			time:       friday.Add(7 * time.Hour),
+		},
+		{
+			name:       "other day",
+			conditions: Conditions{Windows: []TimeWindow{{Days: []string{"Mon", "Tue"}, Start: "00:00", End: "23:59"}}},
+			time:       friday,
+		},
+		{
+			name: "cidr and window",
+			conditions: Conditions{
+				CIDRs:   []string{"10.0.0.0/8"},
+				Windows: []TimeWindow{{Days: []string{"fri"}, Start: "18:00", End: "23:30"}},
+			},
+			ip:       vpn,
+			time:     friday,
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.conditions.Allows(tc.ip, tc.time), tc.expected)
+		})
+	}
+}
+
+func TestConditions_Validate(t *testing.T) {
+	valid := Conditions{
+		CIDRs:   []string{"10.0.0.0/8", "2001:db8::/32"},
+		Windows: []TimeWindow{{Days: []string{"mon"}, Start: "22:00", End: "06:00", Timezone: "Europe/Berlin"}},
+	}
+	assert.NilError(t, valid.Validate())
+
+	window := func(w TimeWindow) Conditions {
+		return Conditions{Windows: []TimeWindow{w}}
+	}
+
+	assert.ErrorContains(t, Conditions{CIDRs: []string{"10.0.0.1"}}.Validate(), `invalid cidr "10.0.0.1"`)
+	assert.ErrorContains(t, window(TimeWindow{Days: []string{"monday"}, Start: "09:00", End: "17:00"}).Validate(), `invalid day "monday"`)
+	assert.ErrorContains(t, window(TimeWindow{Start: "9am", End: "17:00"}).Validate(), `invalid start time "9am"`)
+	assert.ErrorContains(t, window(TimeWindow{Start: "09:00", End: "24:00"}).Validate(), `invalid end time "24:00"`)
+	assert.ErrorContains(t, window(TimeWindow{Start: "09:00", End: "17:00", Timezone: "Mars/Olympus"}).Validate(), `invalid timezone "Mars/Olympus"`)
+	assert.ErrorContains(t, window(TimeWindow{Start: "09:00", End: "09:00"}).Validate(), "are the same")
+}
@@ -31,6 +31,23 @@ func pluralize(word string, number int) string {
 	return word + "s"
 }
 
+// MinArgs validates that a cobra command is executed with at least min command
+// line arguments, otherwise it returns an error that includes the usage string.
+func MinArgs(min int) cobra.PositionalArgs {
+	return func(cmd *cobra.Command, args []string) error {
+		if len(args) >= min {
+			return nil
+		}
+		return fmt.Errorf(
+			"%q requires at least %d %s.\nSee \"%s --help\".\n\nUsage:  %s\n",
+			cmd.CommandPath(),
+			min,
+			pluralize("argument", min),
+			cmd.CommandPath(),
+			cmd.UseLine())
+	}
+}
+
 // MaxArgs validates that a cobra command is executed with at most max command
 // line arguments, otherwise it returns an error that includes the usage string.
 func MaxArgs(max int) cobra.PositionalArgs {
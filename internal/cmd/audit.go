@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newAuditCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "audit",
+		Short: "View the audit log",
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newAuditListCmd(cli))
+
+	return cmd
+}
+
+type auditListOptions struct {
+	UserName     string
+	ResourceType string
+	Since        time.Duration
+	Format       string
+}
+
+func newAuditListCmd(cli *CLI) *cobra.Command {
+	var options auditListOptions
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List audit events",
+		Long:    `List the changes made to Infra, and who made them.`,
+		Example: `
+# List all audit events
+$ infra audit list
+
+# List changes to grants made by a user in the last day
+$ infra audit list --user janedoe@example.com --resource-type grants --since 24h
+`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			req := api.ListAuditEventsRequest{ResourceType: options.ResourceType}
+
+			if options.UserName != "" {
+				user, err := getUserByName(client, options.UserName)
+				if err != nil {
+					if api.ErrorStatusCode(err) == 403 {
+						logging.Debugf("%s", err.Error())
+						return Error{
+							Message: "Cannot list audit events: missing privileges for GetUser",
+						}
+					}
+					return err
+				}
+				req.Actor = user.ID
+			}
+
+			if options.Since > 0 {
+				req.Since = api.Time(time.Now().Add(-options.Since))
+			}
+
+			logging.Debugf("call server: list audit events")
+			events, err := client.ListAuditEvents(req)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list audit events: missing privileges for ListAuditEvents",
+					}
+				}
+				return err
+			}
+
+			switch options.Format {
+			case "json":
+				jsonOutput, err := json.Marshal(events.Items)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(jsonOutput))
+			case "yaml":
+				yamlOutput, err := yaml.Marshal(events.Items)
+				if err != nil {
+					return err
+				}
+				cli.Output(string(yamlOutput))
+			default:
+				type row struct {
+					Time     string `header:"TIME"`
+					Actor    string `header:"ACTOR"`
+					Method   string `header:"METHOD"`
+					Route    string `header:"ROUTE"`
+					Resource string `header:"RESOURCE"`
+					Status   int    `header:"STATUS"`
+				}
+
+				var rows []row
+				for _, event := range events.Items {
+					actor := event.ActorName
+					if actor == "" && event.ActorID != 0 {
+						actor = event.ActorID.String()
+					}
+
+					resource := event.ResourceType
+					if event.ResourceID != 0 {
+						resource += " " + event.ResourceID.String()
+					}
+
+					rows = append(rows, row{
+						Time:     HumanTime(event.Created.Time(), "unknown"),
+						Actor:    actor,
+						Method:   event.Method,
+						Route:    event.Route,
+						Resource: resource,
+						Status:   event.StatusCode,
+					})
+				}
+
+				if len(rows) > 0 {
+					printTable(rows, cli.Stdout)
+				} else {
+					cli.Output("No audit events found")
+				}
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.UserName, "user", "", "Only show events performed by this user")
+	cmd.Flags().StringVar(&options.ResourceType, "resource-type", "", "Only show events for this type of resource, ex: grants")
+	cmd.Flags().DurationVar(&options.Since, "since", 0, "Only show events that happened within this duration, ex: 24h")
+	addFormatFlag(cmd.Flags(), &options.Format)
+	return cmd
+}
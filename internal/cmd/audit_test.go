@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAuditListCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	setup := func(t *testing.T) chan *http.Request {
+		requestCh := make(chan *http.Request, 1)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			if requestMatches(req, http.MethodGet, "/api/users") {
+				resp.WriteHeader(http.StatusOK)
+				err := json.NewEncoder(resp).Encode(api.ListResponse[api.User]{
+					Count: 1,
+					Items: []api.User{{ID: uid.ID(12345678), Name: "admin@example.com"}},
+				})
+				assert.Check(t, err)
+				return
+			}
+
+			if !requestMatches(req, http.MethodGet, "/api/audit-events") {
+				resp.WriteHeader(http.StatusBadRequest)
+				return
+			}
+
+			requestCh <- req
+			resp.WriteHeader(http.StatusOK)
+			err := json.NewEncoder(resp).Encode(api.ListResponse[api.AuditEvent]{
+				Count: 1,
+				Items: []api.AuditEvent{
+					{
+						ID:           uid.ID(1),
+						Created:      api.Time(time.Now().Add(-time.Minute)),
+						ActorID:      uid.ID(12345678),
+						ActorName:    "admin@example.com",
+						Method:       http.MethodPost,
+						Route:        "/api/grants",
+						ResourceType: "grants",
+						ResourceID:   uid.ID(4),
+						StatusCode:   http.StatusCreated,
+					},
+				},
+			})
+			assert.Check(t, err)
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+
+		return requestCh
+	}
+
+	t.Run("no flags", func(t *testing.T) {
+		ch := setup(t)
+
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "audit", "list")
+		assert.NilError(t, err)
+
+		req := <-ch
+		assert.Equal(t, req.URL.Query().Get("actor"), "")
+		assert.Equal(t, req.URL.Query().Get("since"), "")
+
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "admin@example.com"))
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "/api/grants"))
+	})
+
+	t.Run("with filters", func(t *testing.T) {
+		ch := setup(t)
+
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "audit", "list", "--user=admin@example.com", "--resource-type=grants", "--since=1h")
+		assert.NilError(t, err)
+
+		req := <-ch
+		assert.Equal(t, req.URL.Query().Get("actor"), uid.ID(12345678).String())
+		assert.Equal(t, req.URL.Query().Get("resourceType"), "grants")
+
+		since, err := time.Parse(time.RFC3339, req.URL.Query().Get("since"))
+		assert.NilError(t, err)
+		assert.Assert(t, time.Since(since) > 59*time.Minute)
+	})
+}
@@ -142,7 +142,10 @@ func newUseCmd(cli *CLI) *cobra.Command {
 $ infra use development
 
 # Use a Kubernetes namespace context
-$ infra use development.kube-system`,
+$ infra use development.kube-system
+
+# Get credentials for a Postgres database
+$ infra use orders-db`,
 		Args:  ExactArgs(1),
 		Group: "Core commands:",
 		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
@@ -159,6 +162,19 @@ $ infra use development.kube-system`,
 				return err
 			}
 
+			parts := strings.Split(destination, ".")
+
+			if len(parts) == 1 {
+				postgres, err := postgresDestination(client, destination)
+				if err != nil {
+					return err
+				}
+
+				if postgres != nil {
+					return usePostgres(cli, client, *postgres)
+				}
+			}
+
 			config, err := currentHostConfig()
 			if err != nil {
 				return err
@@ -169,8 +185,6 @@ $ infra use development.kube-system`,
 				return err
 			}
 
-			parts := strings.Split(destination, ".")
-
 			if len(parts) == 1 {
 				return kubernetesSetContext(destination, "")
 			}
@@ -226,6 +240,17 @@ func newConnectorCmd() *cobra.Command {
 	cmd.Flags().String("ca-cert", "", "Path to CA certificate file")
 	cmd.Flags().String("ca-key", "", "Path to CA key file")
 	cmd.Flags().Bool("server-skip-tls-verify", false, "Skip verifying server TLS certificates")
+	cmd.Flags().String("kind", "", "Kind of destination [kubernetes, ssh, postgres, http]")
+	cmd.Flags().String("ssh-addr", "", "Address of the SSH server for an ssh destination")
+	cmd.Flags().String("postgres-connection-string", "", "Connection string used to manage login roles of a postgres destination (use file:// to load from a file)")
+	cmd.Flags().String("postgres-addr", "", "Address of the database for a postgres destination")
+	cmd.Flags().String("postgres-database", "", "Name of the database for a postgres destination")
+	cmd.Flags().String("postgres-connector-addr", "", "Address that clients use to reach the connector of a postgres destination")
+	cmd.Flags().String("http-upstream", "", "URL of the web application for an http destination")
+	cmd.Flags().String("http-addr", "", "Address that browsers use to reach the connector of an http destination")
+	cmd.Flags().StringSlice("http-roles", nil, "Roles of the web application that can be granted for an http destination")
+	cmd.Flags().String("http-tls-cert-file", "", "Path to the TLS certificate shown to browsers for an http destination")
+	cmd.Flags().String("http-tls-key-file", "", "Path to the TLS key for an http destination")
 
 	return cmd
 }
@@ -233,10 +258,15 @@ func newConnectorCmd() *cobra.Command {
 // runConnector is a shim for testing
 var runConnector = connector.Run
 
-// defaultConnectorOptions is empty for now. It exists so that it can be
-// referenced by a test.
 func defaultConnectorOptions() connector.Options {
-	return connector.Options{}
+	return connector.Options{
+		Kind: "kubernetes",
+		SSH: connector.SSHOptions{
+			HostKeyFile:           "/etc/ssh/ssh_host_ed25519_key.pub",
+			TrustedUserCAKeysFile: "/etc/ssh/infra_user_ca.pub",
+			PasswdFile:            "/etc/passwd",
+		},
+	}
 }
 
 func NewRootCmd(cli *CLI) *cobra.Command {
@@ -260,14 +290,22 @@ func NewRootCmd(cli *CLI) *cobra.Command {
 	rootCmd.AddCommand(newLogoutCmd(cli))
 	rootCmd.AddCommand(newListCmd(cli))
 	rootCmd.AddCommand(newUseCmd(cli))
+	rootCmd.AddCommand(newSSHCmd(cli))
+	rootCmd.AddCommand(newRequestCmd(cli))
 
 	// Management commands:
 	rootCmd.AddCommand(newDestinationsCmd(cli))
 	rootCmd.AddCommand(newGrantsCmd(cli))
 	rootCmd.AddCommand(newUsersCmd(cli))
 	rootCmd.AddCommand(newGroupsCmd(cli))
+	rootCmd.AddCommand(newRolesCmd(cli))
 	rootCmd.AddCommand(newKeysCmd(cli))
+	rootCmd.AddCommand(newSessionsCmd(cli))
 	rootCmd.AddCommand(newProvidersCmd(cli))
+	rootCmd.AddCommand(newOIDCClientsCmd(cli))
+	rootCmd.AddCommand(newRequestsCmd(cli))
+	rootCmd.AddCommand(newReviewsCmd(cli))
+	rootCmd.AddCommand(newAuditCmd(cli))
 
 	// Other commands:
 	rootCmd.AddCommand(newInfoCmd(cli))
@@ -299,6 +299,9 @@ server:
 name: the-name
 caCert: /path/to/cert
 caKey: /path/to/key
+kind: ssh
+ssh:
+  addr: the-host:2222
 `
 
 	dir := fs.NewDir(t, t.Name(), fs.WithFile("config.yaml", content))
@@ -317,6 +320,13 @@ caKey: /path/to/key
 		},
 		CACert: "/path/to/cert",
 		CAKey:  "/path/to/key",
+		Kind:   "ssh",
+		SSH: connector.SSHOptions{
+			Addr:                  "the-host:2222",
+			HostKeyFile:           "/etc/ssh/ssh_host_ed25519_key.pub",
+			TrustedUserCAKeysFile: "/etc/ssh/infra_user_ca.pub",
+			PasswdFile:            "/etc/passwd",
+		},
 	}
 	assert.DeepEqual(t, actual, expected)
 }
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/muesli/termenv"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+// loginWithDeviceFlow logs in by showing a code, which the user enters in the
+// UI from another device. It is for when a browser can not be opened on this
+// device, such as in an SSH session or a container.
+func loginWithDeviceFlow(cli *CLI, client *api.Client) (*api.LoginRequest, *api.LoginResponse, error) {
+	logging.Debugf("call server: start device flow")
+	started, err := client.StartDeviceFlow()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	fmt.Fprintf(cli.Stderr, "  To login, open %s in a browser and enter the code %s\n",
+		termenv.String(started.VerificationURI).Bold().String(),
+		termenv.String(started.UserCode).Bold().String())
+
+	loginReq := &api.LoginRequest{DeviceCode: started.DeviceCode}
+	interval := time.Duration(started.PollIntervalSeconds) * time.Second
+
+	for time.Now().Before(time.Time(started.Expires)) {
+		time.Sleep(interval)
+
+		logging.Debugf("call server: login with device code")
+		loginRes, err := client.Login(loginReq)
+		switch {
+		case api.ErrorStatusCode(err) == http.StatusBadRequest && strings.Contains(err.Error(), "slow_down"):
+			// polling too often, wait longer between attempts (RFC 8628 §3.5)
+			logging.Debugf("login: %s", err)
+			interval += 5 * time.Second
+			continue
+		case api.ErrorStatusCode(err) == http.StatusBadRequest:
+			// the login has not been approved yet
+			logging.Debugf("login: %s", err)
+			continue
+		case api.ErrorStatusCode(err) == http.StatusTooManyRequests:
+			return nil, nil, &LoginError{Message: "there have been too many failed logins, please try again later"}
+		case api.ErrorStatusCode(err) == http.StatusUnauthorized:
+			return nil, nil, &LoginError{Message: "the code may have expired, please try again"}
+		case err != nil:
+			return nil, nil, err
+		}
+
+		return loginReq, loginRes, nil
+	}
+
+	return nil, nil, &LoginError{Message: "the login was not approved before the code expired"}
+}
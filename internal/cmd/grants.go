@@ -3,13 +3,16 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/ssoroka/slice"
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/resource"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
 )
@@ -20,6 +23,11 @@ type grantsCmdOptions struct {
 	IsGroup     bool
 	Role        string
 	Force       bool
+	Pending     bool
+	Duration    time.Duration
+	CIDRs       []string
+	Windows     []string
+	ClientIP    string
 }
 
 func newGrantsCmd(cli *CLI) *cobra.Command {
@@ -39,6 +47,7 @@ func newGrantsCmd(cli *CLI) *cobra.Command {
 	cmd.AddCommand(newGrantsListCmd(cli))
 	cmd.AddCommand(newGrantAddCmd(cli))
 	cmd.AddCommand(newGrantRemoveCmd(cli))
+	cmd.AddCommand(newGrantCheckCmd(cli))
 
 	return cmd
 }
@@ -57,7 +66,7 @@ func newGrantsListCmd(cli *CLI) *cobra.Command {
 				return err
 			}
 
-			grants, err := client.ListGrants(api.ListGrantsRequest{Resource: options.Destination})
+			grants, err := client.ListGrants(api.ListGrantsRequest{Resource: options.Destination, ShowPending: options.Pending})
 			if err != nil {
 				if api.ErrorStatusCode(err) == 403 {
 					logging.Debugf("%s", err.Error())
@@ -91,6 +100,7 @@ func newGrantsListCmd(cli *CLI) *cobra.Command {
 	}
 
 	cmd.Flags().StringVar(&options.Destination, "destination", "", "Filter by destination")
+	cmd.Flags().BoolVar(&options.Pending, "pending", false, "Include grants which are not in effect yet")
 	return cmd
 }
 
@@ -122,7 +132,7 @@ func userGrants(cli *CLI, client *api.Client, grants *api.ListResponse[api.Grant
 
 		rows = append(rows, row{
 			User:     user.Name,
-			Access:   item.Privilege,
+			Access:   grantAccess(item),
 			Resource: item.Resource,
 		})
 	}
@@ -134,6 +144,15 @@ func userGrants(cli *CLI, client *api.Client, grants *api.ListResponse[api.Grant
 	return len(rows), nil
 }
 
+// grantAccess returns the privilege of the grant, and when it starts when it is
+// not in effect yet.
+func grantAccess(grant api.Grant) string {
+	if start := grant.NotBefore.Time(); start.After(time.Now()) {
+		return fmt.Sprintf("%s (starts in %s)", grant.Privilege, ExactDuration(time.Until(start).Round(time.Minute)))
+	}
+	return grant.Privilege
+}
+
 func groupGrants(cli *CLI, client *api.Client, grants *api.ListResponse[api.Grant]) (int, error) {
 	groups, err := client.ListGroups(api.ListGroupsRequest{})
 	if err != nil {
@@ -162,7 +181,7 @@ func groupGrants(cli *CLI, client *api.Client, grants *api.ListResponse[api.Gran
 
 		rows = append(rows, row{
 			Group:    group.Name,
-			Access:   item.Privilege,
+			Access:   grantAccess(item),
 			Resource: item.Resource,
 		})
 	}
@@ -225,11 +244,13 @@ func removeGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
 		return err
 	}
 
+	// grants which are not in effect yet are removed as well
 	listGrantsReq := api.ListGrantsRequest{
-		User:      user,
-		Group:     group,
-		Privilege: cmdOptions.Role,
-		Resource:  cmdOptions.Destination,
+		User:        user,
+		Group:       group,
+		Privilege:   cmdOptions.Role,
+		Resource:    cmdOptions.Destination,
+		ShowPending: true,
 	}
 
 	logging.Debugf("call server: list grants %#v", listGrantsReq)
@@ -244,11 +265,17 @@ func removeGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
 		return err
 	}
 
-	if grants.Count == 0 && !cmdOptions.Force {
+	// the list includes grants with a wildcard resource that matches the
+	// destination, only remove the grants for exactly this destination
+	items := slice.Select(grants.Items, func(g api.Grant) bool {
+		return !resource.HasWildcard(g.Resource) || g.Resource == cmdOptions.Destination
+	})
+
+	if len(items) == 0 && !cmdOptions.Force {
 		return Error{Message: "Grant not found"}
 	}
 
-	for _, g := range grants.Items {
+	for _, g := range items {
 		logging.Debugf("call server: delete grant %s", g.ID)
 		err := client.DeleteGrant(g.ID)
 		if err != nil {
@@ -284,6 +311,15 @@ $ infra grants add johndoe@example.com staging --role viewer
 
 # Assign a user a role within Infra
 $ infra grants add johndoe@example.com infra --role admin
+
+# Grant a group access to every destination with a name that starts with prod-
+$ infra grants add group-a 'prod-*' --group
+
+# Grant a user access to a destination for 4 hours
+$ infra grants add johndoe@example.com production --role admin --duration 4h
+
+# Grant a group access to a destination from the office network during work hours
+$ infra grants add group-a production --group --cidr 10.0.0.0/8 --window 'mon-fri 09:00-17:00 America/New_York'
 `,
 		Args: ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -296,10 +332,18 @@ $ infra grants add johndoe@example.com infra --role admin
 	cmd.Flags().BoolVarP(&options.IsGroup, "group", "g", false, "When set, creates a grant for a group instead of a user")
 	cmd.Flags().StringVar(&options.Role, "role", models.BasePermissionConnect, "Type of access that the user or group will be given")
 	cmd.Flags().BoolVar(&options.Force, "force", false, "Create grant even if requested user, destination, or role are unknown")
+	cmd.Flags().DurationVar(&options.Duration, "duration", 0, "Remove the grant after this amount of time, ex: 4h")
+	cmd.Flags().StringSliceVar(&options.CIDRs, "cidr", nil, "Only allow access from this network, ex: 10.0.0.0/8")
+	cmd.Flags().StringArrayVar(&options.Windows, "window", nil, "Only allow access during this time window, ex: 'mon-fri 09:00-17:00 America/New_York'")
 	return cmd
 }
 
 func addGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
+	conditions, err := grantConditions(cmdOptions.CIDRs, cmdOptions.Windows)
+	if err != nil {
+		return err
+	}
+
 	client, err := defaultAPIClient()
 	if err != nil {
 		return err
@@ -357,10 +401,14 @@ func addGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
 	}
 
 	createGrantReq := &api.CreateGrantRequest{
-		User:      userID,
-		Group:     groupID,
-		Privilege: cmdOptions.Role,
-		Resource:  cmdOptions.Destination,
+		User:       userID,
+		Group:      groupID,
+		Privilege:  cmdOptions.Role,
+		Resource:   cmdOptions.Destination,
+		Conditions: conditions,
+	}
+	if cmdOptions.Duration > 0 {
+		createGrantReq.ExpiresAt = api.Time(time.Now().Add(cmdOptions.Duration))
 	}
 	logging.Debugf("call server: create grant %#v", createGrantReq)
 	response, err := client.CreateGrant(createGrantReq)
@@ -373,15 +421,248 @@ func addGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
 		}
 		return err
 	}
-	if response.WasCreated {
+	switch {
+	case response.WasCreated && cmdOptions.Duration > 0:
+		cli.Output("Created grant to %q for %q, expires in %s", cmdOptions.Destination, cmdOptions.Name, ExactDuration(cmdOptions.Duration))
+	case response.WasCreated:
 		cli.Output("Created grant to %q for %q", cmdOptions.Destination, cmdOptions.Name)
-	} else {
+	default:
 		cli.Output("%q grant to %q already exists for %q. Nothing changed", cmdOptions.Role, cmdOptions.Destination, cmdOptions.Name)
 	}
 
 	return nil
 }
 
+func newGrantCheckCmd(cli *CLI) *cobra.Command {
+	var options grantsCmdOptions
+
+	cmd := &cobra.Command{
+		Use:   "check USER|GROUP DESTINATION ROLE",
+		Short: "Check if a user or group has a role on a destination, and why",
+		Example: `# Check if a user can view a destination
+$ infra grants check janedoe@example.com production view
+
+# Check if a group can edit a namespace
+$ infra grants check group-a staging.web edit --group
+
+# Check if a user is an Infra admin
+$ infra grants check janedoe@example.com infra admin
+
+# Check if a user can view a destination when connecting from an address
+$ infra grants check janedoe@example.com production view --from 10.0.0.12
+`,
+		Args: ExactArgs(3),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options.Name = args[0]
+			options.Destination = args[1]
+			options.Role = args[2]
+			return checkGrant(cli, options)
+		},
+	}
+
+	cmd.Flags().BoolVarP(&options.IsGroup, "group", "g", false, "Check a group instead of a user")
+	cmd.Flags().StringVar(&options.ClientIP, "from", "", "Check the conditions of grants for a connection from this IP address")
+	return cmd
+}
+
+func checkGrant(cli *CLI, cmdOptions grantsCmdOptions) error {
+	client, err := defaultAPIClient()
+	if err != nil {
+		return err
+	}
+
+	userID, groupID, err := checkUserGroup(client, cmdOptions.Name, cmdOptions.IsGroup)
+	if err != nil {
+		var cliError Error
+		if errors.As(err, &cliError) {
+			return Error{
+				Message: fmt.Sprintf("Cannot check grants: %s", cliError.Message),
+			}
+		}
+		return err
+	}
+
+	req := &api.AuthzCheckRequest{
+		User:      userID,
+		Group:     groupID,
+		Privilege: cmdOptions.Role,
+		Resource:  cmdOptions.Destination,
+		ClientIP:  cmdOptions.ClientIP,
+	}
+
+	logging.Debugf("call server: check authorization %#v", req)
+	resp, err := client.CheckAuthorization(req)
+	if err != nil {
+		if api.ErrorStatusCode(err) == 403 {
+			logging.Debugf("%s", err.Error())
+			return Error{
+				Message: "Cannot check grants: missing privileges for CheckAuthorization",
+			}
+		}
+		return err
+	}
+
+	if !resp.Allowed {
+		cli.Output("%q does not have %q on %q", cmdOptions.Name, cmdOptions.Role, cmdOptions.Destination)
+		switch {
+		case len(resp.Reasons) > 0 && cmdOptions.ClientIP != "":
+			cli.Output("The conditions of the grants are not satisfied from %s now:", cmdOptions.ClientIP)
+		case len(resp.Reasons) > 0:
+			cli.Output("The conditions of the grants are not satisfied now, use --from to check a connection from an address:")
+		case len(resp.Groups) > 0:
+			cli.Output("No grants to %q or to its groups: %s", cmdOptions.Name, strings.Join(resp.Groups, ", "))
+		default:
+			cli.Output("No grants to %q", cmdOptions.Name)
+		}
+	} else {
+		cli.Output("%q has %q on %q", cmdOptions.Name, cmdOptions.Role, cmdOptions.Destination)
+	}
+
+	for _, reason := range resp.Reasons {
+		var line strings.Builder
+		fmt.Fprintf(&line, "  granted %q on %q", reason.Grant.Privilege, reason.Grant.Resource)
+		if reason.Group != "" {
+			fmt.Fprintf(&line, " to group %q, which %q is a member of", reason.Group, cmdOptions.Name)
+		}
+		if len(reason.Path) > 1 {
+			through := make([]string, 0, len(reason.Path)-1)
+			for _, group := range reason.Path[:len(reason.Path)-1] {
+				through = append(through, strconv.Quote(group))
+			}
+			fmt.Fprintf(&line, " through %s", strings.Join(through, ", "))
+		}
+		if reason.Role != "" {
+			fmt.Fprintf(&line, ", role %q includes %q", reason.Role, cmdOptions.Role)
+		}
+		if !reason.Grant.ExpiresAt.Time().IsZero() {
+			fmt.Fprintf(&line, ", expires in %s", ExactDuration(time.Until(reason.Grant.ExpiresAt.Time()).Round(time.Second)))
+		}
+		if c := reason.Grant.Conditions; c != nil {
+			if len(c.CIDRs) > 0 {
+				fmt.Fprintf(&line, ", only from %s", strings.Join(c.CIDRs, ", "))
+			}
+			if len(c.Windows) > 0 {
+				windows := make([]string, 0, len(c.Windows))
+				for _, w := range c.Windows {
+					windows = append(windows, formatTimeWindow(w))
+				}
+				fmt.Fprintf(&line, ", only during %s", strings.Join(windows, ", "))
+			}
+		}
+		cli.Output("%s", line.String())
+	}
+
+	return nil
+}
+
+// grantConditions returns the conditions of a grant from the --cidr and
+// --window flags, or nil when there are none.
+func grantConditions(cidrs, windows []string) (*api.GrantConditions, error) {
+	if len(cidrs) == 0 && len(windows) == 0 {
+		return nil, nil
+	}
+
+	conditions := &api.GrantConditions{CIDRs: cidrs}
+	for _, w := range windows {
+		window, err := parseTimeWindow(w)
+		if err != nil {
+			return nil, Error{Message: fmt.Sprintf("Invalid window %q: %v", w, err)}
+		}
+		conditions.Windows = append(conditions.Windows, window)
+	}
+
+	return conditions, nil
+}
+
+var weekdays = []string{"mon", "tue", "wed", "thu", "fri", "sat", "sun"}
+
+// parseTimeWindow parses a time window like "mon-fri 09:00-17:00 UTC". The
+// days and the timezone are optional, the days are a range, or a comma
+// separated list.
+func parseTimeWindow(s string) (api.TimeWindow, error) {
+	var window api.TimeWindow
+
+	fields := strings.Fields(s)
+	if len(fields) > 0 && !strings.Contains(fields[0], ":") {
+		days, err := parseWeekdays(fields[0])
+		if err != nil {
+			return window, err
+		}
+		window.Days = days
+		fields = fields[1:]
+	}
+
+	switch len(fields) {
+	case 2:
+		window.Timezone = fields[1]
+	case 1:
+	default:
+		return window, fmt.Errorf("expected [DAYS] START-END [TIMEZONE]")
+	}
+
+	start, end, ok := strings.Cut(fields[0], "-")
+	if !ok {
+		return window, fmt.Errorf("expected a time range like 09:00-17:00")
+	}
+	window.Start, window.End = start, end
+
+	return window, nil
+}
+
+// parseWeekdays parses a range of days like mon-fri, which may wrap around the
+// end of the week, or a list of days like mon,wed,fri.
+func parseWeekdays(s string) ([]string, error) {
+	index := func(day string) (int, error) {
+		for i, d := range weekdays {
+			if strings.EqualFold(d, day) {
+				return i, nil
+			}
+		}
+		return 0, fmt.Errorf("invalid day %q, expected one of %s", day, strings.Join(weekdays, ", "))
+	}
+
+	if first, last, ok := strings.Cut(s, "-"); ok {
+		from, err := index(first)
+		if err != nil {
+			return nil, err
+		}
+		to, err := index(last)
+		if err != nil {
+			return nil, err
+		}
+
+		var days []string
+		for i := from; ; i = (i + 1) % len(weekdays) {
+			days = append(days, weekdays[i])
+			if i == to {
+				return days, nil
+			}
+		}
+	}
+
+	var days []string
+	for _, day := range strings.Split(s, ",") {
+		i, err := index(day)
+		if err != nil {
+			return nil, err
+		}
+		days = append(days, weekdays[i])
+	}
+	return days, nil
+}
+
+func formatTimeWindow(w api.TimeWindow) string {
+	parts := make([]string, 0, 3)
+	if len(w.Days) > 0 {
+		parts = append(parts, strings.Join(w.Days, ","))
+	}
+	parts = append(parts, w.Start+"-"+w.End)
+	if w.Timezone != "" {
+		parts = append(parts, w.Timezone)
+	}
+	return strings.Join(parts, " ")
+}
+
 // checkUserGroup returns the ID of the requested user or group if they exist. Otherwise it
 // returns an error
 func checkUserGroup(client *api.Client, subject string, isGroup bool) (userID uid.ID, groupID uid.ID, err error) {
@@ -417,8 +698,8 @@ func checkUserGroup(client *api.Client, subject string, isGroup bool) (userID ui
 // checkResourcesPrivileges checks if the requested destination (e.g. cluster), optional
 // resource (e.g. namespace), and role exist. destination "infra" and role "connect" are
 // reserved values and will always pass checks
-func checkResourcesPrivileges(client *api.Client, resource, privilege string) error {
-	parts := strings.SplitN(resource, ".", 2)
+func checkResourcesPrivileges(client *api.Client, grantResource, privilege string) error {
+	parts := strings.SplitN(grantResource, ".", 2)
 	destination := parts[0]
 	subresource := ""
 
@@ -430,17 +711,33 @@ func checkResourcesPrivileges(client *api.Client, resource, privilege string) er
 	supportedRoles := make(map[string]struct{})
 
 	if destination != "infra" {
-		logging.Debugf("call server: list destinations named %q", destination)
-		destinations, err := client.ListDestinations(api.ListDestinationsRequest{Name: destination})
+		req := api.ListDestinationsRequest{Name: destination}
+		if resource.HasWildcard(destination) {
+			// list all destinations and find the ones which match
+			req.Name = ""
+		}
+
+		logging.Debugf("call server: list destinations named %q", req.Name)
+		destinations, err := client.ListDestinations(req)
 		if err != nil {
 			return err
 		}
 
-		if destinations.Count == 0 {
+		items := destinations.Items
+		if resource.HasWildcard(destination) {
+			items = slice.Select(items, func(d api.Destination) bool {
+				return resource.MatchSegment(destination, d.Name)
+			})
+		}
+
+		if len(items) == 0 {
+			if resource.HasWildcard(destination) {
+				return Error{Message: fmt.Sprintf("No connected destination matches %q; to ignore, run with '--force'", destination)}
+			}
 			return Error{Message: fmt.Sprintf("Destination %q not connected; to ignore, run with '--force'", destination)}
 		}
 
-		for _, d := range destinations.Items {
+		for _, d := range items {
 			for _, r := range d.Resources {
 				supportedResources[r] = struct{}{}
 			}
@@ -450,14 +747,14 @@ func checkResourcesPrivileges(client *api.Client, resource, privilege string) er
 			}
 		}
 
-		if subresource != "" {
+		if subresource != "" && !resource.HasWildcard(subresource) {
 			if _, ok := supportedResources[subresource]; !ok {
 				return Error{Message: fmt.Sprintf("Namespace %q not detected in destination %q; to ignore, run with '--force'", subresource, destination)}
 			}
 		}
 
 		if privilege != "connect" {
-			if _, ok := supportedRoles[privilege]; !ok {
+			if _, ok := supportedRoles[privilege]; !ok && !isCustomRole(client, privilege) {
 				return Error{Message: fmt.Sprintf("Role %q is not a known role for destination %q; to ignore, run with '--force'", privilege, destination)}
 			}
 		}
@@ -465,3 +762,15 @@ func checkResourcesPrivileges(client *api.Client, resource, privilege string) er
 
 	return nil
 }
+
+// isCustomRole returns true if the privilege is the name of a custom role.
+func isCustomRole(client *api.Client, privilege string) bool {
+	logging.Debugf("call server: list roles named %q", privilege)
+	roles, err := client.ListRoles(api.ListRolesRequest{Name: privilege})
+	if err != nil {
+		logging.Debugf("list roles: %v", err)
+		return false
+	}
+
+	return roles.Count > 0
+}
@@ -9,6 +9,7 @@ import (
 	"path"
 	"strings"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 
@@ -117,6 +118,52 @@ func TestGrantsAddCmd(t *testing.T) {
 		}
 		assert.DeepEqual(t, createReq, expected)
 	})
+	t.Run("add grant with duration", func(t *testing.T) {
+		ch := setup(t)
+		ctx := context.Background()
+		err := Run(ctx, "grants", "add", "existing@example.com", "the-destination", "--duration=4h")
+		assert.NilError(t, err)
+
+		createReq := <-ch
+		expiresIn := time.Until(createReq.ExpiresAt.Time())
+		assert.Assert(t, expiresIn > 3*time.Hour+59*time.Minute && expiresIn <= 4*time.Hour, expiresIn)
+
+		createReq.ExpiresAt = api.Time{}
+		expected := api.CreateGrantRequest{
+			User:      3000,
+			Privilege: "connect",
+			Resource:  "the-destination",
+		}
+		assert.DeepEqual(t, createReq, expected)
+	})
+	t.Run("add grant with conditions", func(t *testing.T) {
+		ch := setup(t)
+		ctx := context.Background()
+		err := Run(ctx, "grants", "add", "existing@example.com", "the-destination",
+			"--cidr=10.0.0.0/8,192.168.0.0/16",
+			"--window=fri-mon 22:00-06:00 Europe/London",
+			"--window=09:00-17:00")
+		assert.NilError(t, err)
+
+		expected := api.CreateGrantRequest{
+			User:      3000,
+			Privilege: "connect",
+			Resource:  "the-destination",
+			Conditions: &api.GrantConditions{
+				CIDRs: []string{"10.0.0.0/8", "192.168.0.0/16"},
+				Windows: []api.TimeWindow{
+					{Days: []string{"fri", "sat", "sun", "mon"}, Start: "22:00", End: "06:00", Timezone: "Europe/London"},
+					{Start: "09:00", End: "17:00"},
+				},
+			},
+		}
+		assert.DeepEqual(t, <-ch, expected)
+	})
+	t.Run("add grant with invalid window", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "grants", "add", "existing@example.com", "the-destination", "--window=weekdays 09:00-17:00")
+		assert.ErrorContains(t, err, `Invalid window "weekdays 09:00-17:00": invalid day "weekdays"`)
+	})
 	t.Run("add role to existing identity", func(t *testing.T) {
 		ch := setup(t)
 		ctx := context.Background()
@@ -322,7 +369,8 @@ func TestGrantRemoveCmd(t *testing.T) {
 				}
 
 				if query.Get("user") == "TJ" { // ID=3000
-					writeResponse(t, resp, api.ListResponse[api.Grant]{Count: 1, Items: []api.Grant{{ID: 5001}, {ID: 5002}, {ID: 5003}}})
+					// 5004 has a wildcard resource which matches the destination, and must not be removed
+					writeResponse(t, resp, api.ListResponse[api.Grant]{Count: 1, Items: []api.Grant{{ID: 5001}, {ID: 5002}, {ID: 5003}, {ID: 5004, Resource: "the-*"}}})
 					return
 				}
 
@@ -394,3 +442,163 @@ func readChan(ch chan string) []string {
 func requestMatchesPrefix(req *http.Request, method string, path string) bool {
 	return req.Method == method && strings.HasPrefix(req.URL.Path, path)
 }
+
+func TestGrantCheckCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	setup := func(t *testing.T) chan api.AuthzCheckRequest {
+		requestCh := make(chan api.AuthzCheckRequest, 1)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			query := req.URL.Query()
+
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/users"):
+				resp.WriteHeader(http.StatusOK)
+				if query.Get("name") == "existing@example.com" {
+					writeResponse(t, resp, api.ListResponse[api.User]{Count: 1, Items: []api.User{{ID: 3000}}})
+					return
+				}
+				writeResponse(t, resp, api.ListResponse[api.User]{})
+
+			case requestMatches(req, http.MethodGet, "/api/groups"):
+				resp.WriteHeader(http.StatusOK)
+				if query.Get("name") == "existingGroup" {
+					writeResponse(t, resp, api.ListResponse[api.Group]{Count: 1, Items: []api.Group{{ID: 4000}}})
+					return
+				}
+				writeResponse(t, resp, api.ListResponse[api.Group]{})
+
+			case requestMatches(req, http.MethodPost, "/api/authz/check"):
+				var checkReq api.AuthzCheckRequest
+				err := json.NewDecoder(req.Body).Decode(&checkReq)
+				assert.Check(t, err)
+				requestCh <- checkReq
+
+				resp.WriteHeader(http.StatusOK)
+				switch checkReq.Privilege {
+				case "view":
+				case "edit":
+					writeResponse(t, resp, api.AuthzCheckResponse{
+						Groups: []string{"developers"},
+						Reasons: []api.AuthzReason{
+							{Grant: api.Grant{User: 3000, Privilege: "edit", Resource: "production", Conditions: &api.GrantConditions{
+								CIDRs: []string{"10.0.0.0/8"},
+							}}},
+						},
+					})
+					return
+				default:
+					writeResponse(t, resp, api.AuthzCheckResponse{Groups: []string{"developers"}})
+					return
+				}
+				writeResponse(t, resp, api.AuthzCheckResponse{
+					Allowed: true,
+					Groups:  []string{"developers"},
+					Reasons: []api.AuthzReason{
+						{Grant: api.Grant{User: 3000, Privilege: "view", Resource: "production", Conditions: &api.GrantConditions{
+							CIDRs:   []string{"10.0.0.0/8"},
+							Windows: []api.TimeWindow{{Days: []string{"mon", "tue"}, Start: "09:00", End: "17:00", Timezone: "UTC"}},
+						}}},
+						{
+							Grant: api.Grant{Group: 4000, Privilege: "operator", Resource: "prod*"},
+							Group: "developers",
+							Path:  []string{"developers"},
+							Role:  "operator",
+						},
+						{
+							Grant: api.Grant{Group: 4001, Privilege: "view", Resource: "production"},
+							Group: "engineering",
+							Path:  []string{"developers", "backend", "engineering"},
+						},
+					},
+				})
+
+			default:
+				resp.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+		return requestCh
+	}
+
+	t.Run("allowed", func(t *testing.T) {
+		ch := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "grants", "check", "existing@example.com", "production", "view")
+		assert.NilError(t, err)
+
+		expectedReq := api.AuthzCheckRequest{User: 3000, Privilege: "view", Resource: "production"}
+		assert.DeepEqual(t, <-ch, expectedReq)
+
+		expected := `"existing@example.com" has "view" on "production"
+  granted "view" on "production", only from 10.0.0.0/8, only during mon,tue 09:00-17:00 UTC
+  granted "operator" on "prod*" to group "developers", which "existing@example.com" is a member of, role "operator" includes "view"
+  granted "view" on "production" to group "engineering", which "existing@example.com" is a member of through "developers", "backend"
+`
+		assert.Equal(t, bufs.Stdout.String(), expected)
+	})
+
+	t.Run("denied", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "grants", "check", "existing@example.com", "production", "admin")
+		assert.NilError(t, err)
+
+		expected := `"existing@example.com" does not have "admin" on "production"
+No grants to "existing@example.com" or to its groups: developers
+`
+		assert.Equal(t, bufs.Stdout.String(), expected)
+	})
+
+	t.Run("conditions not satisfied", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "grants", "check", "existing@example.com", "production", "edit")
+		assert.NilError(t, err)
+
+		expected := `"existing@example.com" does not have "edit" on "production"
+The conditions of the grants are not satisfied now, use --from to check a connection from an address:
+  granted "edit" on "production", only from 10.0.0.0/8
+`
+		assert.Equal(t, bufs.Stdout.String(), expected)
+	})
+
+	t.Run("conditions not satisfied from an address", func(t *testing.T) {
+		ch := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "grants", "check", "existing@example.com", "production", "edit", "--from", "192.0.2.10")
+		assert.NilError(t, err)
+
+		expectedReq := api.AuthzCheckRequest{User: 3000, Privilege: "edit", Resource: "production", ClientIP: "192.0.2.10"}
+		assert.DeepEqual(t, <-ch, expectedReq)
+
+		expected := `"existing@example.com" does not have "edit" on "production"
+The conditions of the grants are not satisfied from 192.0.2.10 now:
+  granted "edit" on "production", only from 10.0.0.0/8
+`
+		assert.Equal(t, bufs.Stdout.String(), expected)
+	})
+
+	t.Run("group", func(t *testing.T) {
+		ch := setup(t)
+		err := Run(context.Background(), "grants", "check", "existingGroup", "production", "view", "--group")
+		assert.NilError(t, err)
+
+		expectedReq := api.AuthzCheckRequest{Group: 4000, Privilege: "view", Resource: "production"}
+		assert.DeepEqual(t, <-ch, expectedReq)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "grants", "check", "unknown@example.com", "production", "view")
+		assert.ErrorContains(t, err, "unknown@example.com")
+	})
+}
@@ -54,9 +54,12 @@ func newGroupsCmd(cli *CLI) *cobra.Command {
 
 	cmd.AddCommand(newGroupsAddCmd(cli))
 	cmd.AddCommand(newGroupsAddUserCmd(cli))
+	cmd.AddCommand(newGroupsAddGroupCmd(cli))
+	cmd.AddCommand(newGroupsEditCmd(cli))
 	cmd.AddCommand(newGroupsListCmd(cli))
 	cmd.AddCommand(newGroupsRemoveCmd(cli))
 	cmd.AddCommand(newGroupsRemoveUserCmd(cli))
+	cmd.AddCommand(newGroupsRemoveGroupCmd(cli))
 
 	return cmd
 }
@@ -74,8 +77,9 @@ func newGroupsListCmd(cli *CLI) *cobra.Command {
 			}
 
 			type row struct {
-				Name  string `header:"Name"`
-				Users string `header:"Users"`
+				Name   string `header:"Name"`
+				Users  string `header:"Users"`
+				Groups string `header:"Groups"`
 			}
 
 			var rows []row
@@ -96,9 +100,20 @@ func newGroupsListCmd(cli *CLI) *cobra.Command {
 					userNames = append(userNames, user.Name)
 				}
 
+				members, err := client.ListGroups(api.ListGroupsRequest{GroupID: group.ID})
+				if err != nil {
+					return err
+				}
+
+				var groupNames []string
+				for _, member := range members.Items {
+					groupNames = append(groupNames, member.Name)
+				}
+
 				rows = append(rows, row{
-					Name:  group.Name,
-					Users: strings.Join(userNames, ", "),
+					Name:   group.Name,
+					Users:  strings.Join(userNames, ", "),
+					Groups: strings.Join(groupNames, ", "),
 				})
 			}
 
@@ -114,19 +129,24 @@ func newGroupsListCmd(cli *CLI) *cobra.Command {
 }
 
 func newGroupsAddCmd(cli *CLI) *cobra.Command {
-	return &cobra.Command{
+	var requireMFA bool
+
+	cmd := &cobra.Command{
 		Use:   "add GROUP",
 		Short: "Create a group",
 		Args:  ExactArgs(1),
 		Example: `# Create a group
-$ infra groups add Engineering`,
+$ infra groups add Engineering
+
+# Create a group whose members must use multi-factor authentication
+$ infra groups add Admins --require-mfa`,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			client, err := defaultAPIClient()
 			if err != nil {
 				return err
 			}
 
-			_, err = client.CreateGroup(&api.CreateGroupRequest{Name: args[0]})
+			_, err = client.CreateGroup(&api.CreateGroupRequest{Name: args[0], RequireMFA: requireMFA})
 			if err != nil {
 				return err
 			}
@@ -135,6 +155,55 @@ $ infra groups add Engineering`,
 			return nil
 		},
 	}
+
+	cmd.Flags().BoolVar(&requireMFA, "require-mfa", false, "Require members to use multi-factor authentication")
+
+	return cmd
+}
+
+func newGroupsEditCmd(cli *CLI) *cobra.Command {
+	var requireMFA bool
+
+	cmd := &cobra.Command{
+		Use:   "edit GROUP",
+		Short: "Update a group",
+		Args:  ExactArgs(1),
+		Example: `# Require members of a group to use multi-factor authentication
+$ infra groups edit Admins --require-mfa
+
+# Stop requiring multi-factor authentication
+$ infra groups edit Admins --require-mfa=false`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !cmd.Flags().Changed("require-mfa") {
+				return errors.New("Please specify a field to update. For options, run 'infra groups edit --help'")
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			group, err := getGroupByName(client, args[0])
+			if err != nil {
+				if errors.Is(err, ErrGroupNotFound) {
+					return Error{Message: fmt.Sprintf("Group %q not found", args[0])}
+				}
+				return err
+			}
+
+			_, err = client.UpdateGroup(&api.UpdateGroupRequest{ID: group.ID, RequireMFA: requireMFA})
+			if err != nil {
+				return err
+			}
+			cli.Output("Updated group %q", args[0])
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&requireMFA, "require-mfa", false, "Require members to use multi-factor authentication")
+
+	return cmd
 }
 
 func newGroupsRemoveCmd(cli *CLI) *cobra.Command {
@@ -289,3 +358,115 @@ $ infra groups removeuser johndoe@example.com Engineering
 
 	return cmd
 }
+
+func newGroupsAddGroupCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "addgroup MEMBER GROUP",
+		Short: "Add a group to a group",
+		Long: `Add a group to a group. The members of the MEMBER group become members of
+GROUP, and have the access granted to GROUP.`,
+		Args: ExactArgs(2),
+		Example: `# Add a group to a group
+$ infra groups addgroup sre-oncall sre
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			memberName := args[0]
+			groupName := args[1]
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			member, err := getGroupByName(client, memberName)
+			if err != nil {
+				if errors.Is(err, ErrGroupNotFound) {
+					return Error{Message: fmt.Sprintf("unknown group %q", memberName)}
+				}
+				return err
+			}
+
+			group, err := getGroupByName(client, groupName)
+			if err != nil {
+				if errors.Is(err, ErrGroupNotFound) {
+					return Error{Message: fmt.Sprintf("unknown group %q", groupName)}
+				}
+				return err
+			}
+
+			req := &api.UpdateGroupsInGroupRequest{
+				GroupID:       group.ID,
+				GroupIDsToAdd: []uid.ID{member.ID},
+			}
+			err = client.UpdateGroupsInGroup(req)
+			if err != nil {
+				return err
+			}
+
+			cli.Output("Added group %q to group %q", member.Name, group.Name)
+
+			return nil
+		},
+	}
+}
+
+func newGroupsRemoveGroupCmd(cli *CLI) *cobra.Command {
+	var force bool
+	cmd := &cobra.Command{
+		Use:     "removegroup MEMBER GROUP",
+		Short:   "Remove a group from a group",
+		Aliases: []string{"rmgroup"},
+		Args:    ExactArgs(2),
+		Example: `# Remove a group from a group
+$ infra groups removegroup sre-oncall sre
+`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			memberName := args[0]
+			groupName := args[1]
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			member, err := getGroupByName(client, memberName)
+			if err != nil {
+				if !force {
+					if errors.Is(err, ErrGroupNotFound) {
+						return Error{Message: fmt.Sprintf("unknown group %q", memberName)}
+					}
+					return err
+				}
+				return nil
+			}
+
+			group, err := getGroupByName(client, groupName)
+			if err != nil {
+				if !force {
+					if errors.Is(err, ErrGroupNotFound) {
+						return Error{Message: fmt.Sprintf("unknown group %q", groupName)}
+					}
+					return err
+				}
+				return nil
+			}
+
+			req := &api.UpdateGroupsInGroupRequest{
+				GroupID:          group.ID,
+				GroupIDsToRemove: []uid.ID{member.ID},
+			}
+			err = client.UpdateGroupsInGroup(req)
+			if err != nil {
+				return err
+			}
+
+			cli.Output("Removed group %q from group %q", memberName, groupName)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Exit successfully even if either group does not exist")
+
+	return cmd
+}
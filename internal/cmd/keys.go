@@ -38,6 +38,7 @@ type keyCreateOptions struct {
 	Name              string
 	TTL               time.Duration
 	ExtensionDeadline time.Duration
+	Scopes            []string
 }
 
 func newKeysAddCmd(cli *CLI) *cobra.Command {
@@ -53,6 +54,10 @@ $ infra keys add user@example.com --ttl=12h --name example-key
 
 # Create an access key to add a Kubernetes connection to Infra
 $ infra keys add connector
+
+# Create an access key for a CI job which can only read and write grants
+# for the production cluster, from a private network
+$ infra keys add ci@example.com --scope grants:write --scope resource:production --scope cidr:10.0.0.0/8
 `,
 		Args: ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -86,6 +91,7 @@ $ infra keys add connector
 				Name:              options.Name,
 				TTL:               api.Duration(options.TTL),
 				ExtensionDeadline: api.Duration(options.ExtensionDeadline),
+				Scopes:            options.Scopes,
 			})
 			if err != nil {
 				if api.ErrorStatusCode(err) == 403 {
@@ -107,6 +113,9 @@ $ infra keys add connector
 			}
 			cli.Output("Issued access key %q for %q", resp.Name, userName)
 			cli.Output(expMsg.String())
+			if len(resp.Scopes) > 0 {
+				cli.Output("This key is limited to the scopes %s", strings.Join(resp.Scopes, ", "))
+			}
 			cli.Output("")
 
 			cli.Output("Key: %s", resp.AccessKey)
@@ -117,6 +126,7 @@ $ infra keys add connector
 	cmd.Flags().StringVar(&options.Name, "name", "", "The name of the access key")
 	cmd.Flags().DurationVar(&options.TTL, "ttl", thirtyDays, "The total time that the access key will be valid for")
 	cmd.Flags().DurationVar(&options.ExtensionDeadline, "extension-deadline", thirtyDays, "A specified deadline that the access key must be used within to remain valid")
+	cmd.Flags().StringSliceVar(&options.Scopes, "scope", nil, "Limit the access key to an API operation (grants:read), a resource (resource:NAME), or a network (cidr:CIDR)")
 
 	return cmd
 }
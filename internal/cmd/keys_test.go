@@ -100,6 +100,17 @@ func TestKeysAddCmd(t *testing.T) {
 		assert.Equal(t, withNewline(bufs.Stdout.String()), expectedKeysAddOutput)
 	})
 
+	t.Run("scopes", func(t *testing.T) {
+		ch := setup(t)
+
+		ctx, _ := PatchCLI(context.Background())
+		err := Run(ctx, "keys", "add", "--scope=grants:read,destinations:write", "--scope", "cidr:10.0.0.0/8", "my-user")
+		assert.NilError(t, err)
+
+		req := <-ch
+		assert.DeepEqual(t, req.Scopes, []string{"grants:read", "destinations:write", "cidr:10.0.0.0/8"})
+	})
+
 	t.Run("without required arguments", func(t *testing.T) {
 		err := Run(context.Background(), "keys", "add")
 		assert.ErrorContains(t, err, `"infra keys add" requires exactly 1 argument`)
@@ -13,6 +13,7 @@ import (
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/resource"
 	"github.com/infrahq/infra/uid"
 )
 
@@ -57,7 +58,7 @@ func kubernetesSetContext(cluster, namespace string) error {
 }
 
 func updateKubeConfig(client *api.Client, id uid.ID) error {
-	destinations, err := client.ListDestinations(api.ListDestinationsRequest{})
+	destinations, err := client.ListDestinations(api.ListDestinationsRequest{Kind: "kubernetes"})
 	if err != nil {
 		return err
 	}
@@ -72,7 +73,39 @@ func updateKubeConfig(client *api.Client, id uid.ID) error {
 		return err
 	}
 
-	return writeKubeconfig(user, destinations.Items, grants.Items)
+	return writeKubeconfig(user, destinations.Items, expandWildcardGrants(grants.Items, destinations.Items))
+}
+
+// expandWildcardGrants replaces each grant with a wildcard resource by a grant
+// for each of the destinations, and destination namespaces, that it matches.
+func expandWildcardGrants(grants []api.Grant, destinations []api.Destination) []api.Grant {
+	expanded := make([]api.Grant, 0, len(grants))
+
+	for _, g := range grants {
+		if !resource.HasWildcard(g.Resource) {
+			expanded = append(expanded, g)
+			continue
+		}
+
+		for _, d := range destinations {
+			if resource.Match(g.Resource, d.Name) {
+				grant := g
+				grant.Resource = d.Name
+				expanded = append(expanded, grant)
+			}
+
+			for _, namespace := range d.Resources {
+				name := d.Name + resource.Separator + namespace
+				if resource.Match(g.Resource, name) {
+					grant := g
+					grant.Resource = name
+					expanded = append(expanded, grant)
+				}
+			}
+		}
+	}
+
+	return expanded
 }
 
 func writeKubeconfig(user *api.User, destinations []api.Destination, grants []api.Grant) error {
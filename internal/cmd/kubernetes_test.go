@@ -174,3 +174,27 @@ func TestWriteKubeconfig_UserNamespaceOverrideResetNamespacedContext(t *testing.
 	assert.NilError(t, err)
 	assert.Equal(t, actual.Contexts["infra:cluster:default"].Namespace, "default")
 }
+
+func TestExpandWildcardGrants(t *testing.T) {
+	destinations := []api.Destination{
+		{Name: "prod-1", Resources: []string{"default", "kube-system"}},
+		{Name: "prod-2", Resources: []string{"default"}},
+		{Name: "staging", Resources: []string{"kube-system"}},
+	}
+
+	grants := []api.Grant{
+		{Privilege: "view", Resource: "staging"},
+		{Privilege: "admin", Resource: "prod-*"},
+		{Privilege: "edit", Resource: "*.kube-system"},
+	}
+
+	expected := []api.Grant{
+		{Privilege: "view", Resource: "staging"},
+		{Privilege: "admin", Resource: "prod-1"},
+		{Privilege: "admin", Resource: "prod-2"},
+		{Privilege: "edit", Resource: "prod-1.kube-system"},
+		{Privilege: "edit", Resource: "staging.kube-system"},
+	}
+
+	assert.DeepEqual(t, expandWildcardGrants(grants, destinations), expected)
+}
@@ -132,5 +132,7 @@ func getUserDestinationGrants(client *api.Client) (*api.User, *api.ListResponse[
 		return nil, nil, nil, err
 	}
 
+	grants.Items = expandWildcardGrants(grants.Items, destinations.Items)
+
 	return user, destinations, grants, nil
 }
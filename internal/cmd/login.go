@@ -37,6 +37,8 @@ type loginCmdOptions struct {
 	TrustedFingerprint string
 	NonInteractive     bool
 	NoAgent            bool
+	Device             bool
+	WorkloadToken      string
 }
 
 type loginMethod int8
@@ -44,6 +46,8 @@ type loginMethod int8
 const (
 	localLogin loginMethod = iota
 	oidcLogin
+	ldapLogin
+	samlLogin
 )
 
 const cliLoginRedirectURL = "http://localhost:8301"
@@ -64,9 +68,15 @@ $ infra login infraexampleserver.com
 # Login with a specific identity provider
 $ infra login --provider okta
 
+# Login without a browser, by approving a code from another device
+$ infra login --device
+
 # Login with an access key
 $ export INFRA_ACCESS_KEY=1M4CWy9wF5.fAKeKEy5sMLH9ZZzAur0ZIjy
-$ infra login`,
+$ infra login
+
+# Login from a Kubernetes pod with its service account token
+$ infra login --workload-token /var/run/secrets/tokens/infra`,
 		Args:  MaxArgs(1),
 		Group: "Core commands:",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -84,6 +94,8 @@ $ infra login`,
 
 	cmd.Flags().StringVar(&options.AccessKey, "key", "", "Login with an access key")
 	cmd.Flags().StringVar(&options.Provider, "provider", "", "Login with an identity provider")
+	cmd.Flags().BoolVar(&options.Device, "device", false, "Login by approving a code from a browser on another device")
+	cmd.Flags().Var((*types.StringOrFile)(&options.WorkloadToken), "workload-token", "Login with an OIDC token of a workload, from a trusted issuer")
 	cmd.Flags().BoolVar(&options.SkipTLSVerify, "skip-tls-verify", false, "Skip verifying server TLS certificates")
 	cmd.Flags().Var((*types.StringOrFile)(&options.TrustedCertificate), "tls-trusted-cert", "TLS certificate or CA used by the server")
 	cmd.Flags().StringVar(&options.TrustedFingerprint, "tls-trusted-fingerprint", "", "SHA256 fingerprint of the server TLS certificate")
@@ -146,20 +158,46 @@ func login(cli *CLI, options loginCmdOptions) error {
 		options.AccessKey = os.Getenv("INFRA_ACCESS_KEY")
 	}
 
+	if options.WorkloadToken == "" {
+		options.WorkloadToken = os.Getenv("INFRA_WORKLOAD_TOKEN")
+	}
+
 	switch {
+	case options.Device:
+		loginReq, loginRes, err := loginWithDeviceFlow(cli, lc.APIClient)
+		if err != nil {
+			return err
+		}
+
+		return finishLogin(cli, lc, loginReq, loginRes, options.NoAgent)
 	case options.AccessKey != "":
 		loginReq.AccessKey = options.AccessKey
+	case options.WorkloadToken != "":
+		// tokens read from a file usually end with a newline
+		loginReq.WorkloadToken = strings.TrimSpace(options.WorkloadToken)
 	case options.Provider != "":
 		if options.NonInteractive {
-			return Error{Message: "Non-interactive login only supports access keys, set the INFRA_ACCESS_KEY environment variable and try again"}
+			return Error{Message: "Non-interactive login only supports access keys and workload tokens, set the INFRA_ACCESS_KEY or INFRA_WORKLOAD_TOKEN environment variable and try again"}
+		}
+		provider, err := GetProviderByName(lc.APIClient, options.Provider)
+		if err != nil {
+			return err
+		}
+
+		switch provider.Kind {
+		case "ldap":
+			loginReq.LDAP, err = promptLDAPLogin(cli, provider)
+		case "saml":
+			loginReq.SAML, err = loginToSAMLProvider(lc.APIClient, provider)
+		default:
+			loginReq.OIDC, err = loginToProvider(provider)
 		}
-		loginReq.OIDC, err = loginToProviderN(lc.APIClient, options.Provider)
 		if err != nil {
 			return err
 		}
 	default:
 		if options.NonInteractive {
-			return Error{Message: "Non-interactive login only supports access keys, set the INFRA_ACCESS_KEY environment variable and try again"}
+			return Error{Message: "Non-interactive login only supports access keys and workload tokens, set the INFRA_ACCESS_KEY or INFRA_WORKLOAD_TOKEN environment variable and try again"}
 		}
 		loginMethod, provider, err := promptLoginOptions(cli, lc.APIClient)
 		if err != nil {
@@ -177,6 +215,16 @@ func login(cli *CLI, options loginCmdOptions) error {
 			if err != nil {
 				return err
 			}
+		case ldapLogin:
+			loginReq.LDAP, err = promptLDAPLogin(cli, provider)
+			if err != nil {
+				return err
+			}
+		case samlLogin:
+			loginReq.SAML, err = loginToSAMLProvider(lc.APIClient, provider)
+			if err != nil {
+				return err
+			}
 		}
 	}
 
@@ -197,22 +245,50 @@ func loginToInfra(cli *CLI, lc loginClient, loginReq *api.LoginRequest, noAgent
 	loginRes, err := lc.APIClient.Login(loginReq)
 	if err != nil {
 		logging.Debugf("login: %s", err)
+		if api.ErrorStatusCode(err) == http.StatusTooManyRequests {
+			return &LoginError{Message: "there have been too many failed logins, please try again later"}
+		}
 		if api.ErrorStatusCode(err) == http.StatusUnauthorized || api.ErrorStatusCode(err) == http.StatusNotFound {
 			switch {
 			case loginReq.AccessKey != "":
 				return &LoginError{Message: "your access key may be invalid"}
-			case loginReq.PasswordCredentials != nil:
+			case loginReq.PasswordCredentials != nil, loginReq.LDAP != nil:
 				return &LoginError{Message: "your username or password may be invalid"}
 			case loginReq.OIDC != nil:
 				return &LoginError{Message: "please contact an administrator and check identity provider configurations"}
+			case loginReq.WorkloadToken != "":
+				return &LoginError{Message: "your workload token may be invalid, or not trusted by the server"}
 			}
 		}
 
 		return err
 	}
+
+	return finishLogin(cli, lc, loginReq, loginRes, noAgent)
+}
+
+// finishLogin completes any steps the server requires after the login, and
+// saves the session
+func finishLogin(cli *CLI, lc loginClient, loginReq *api.LoginRequest, loginRes *api.LoginResponse, noAgent bool) error {
+	var err error
+	if loginRes.MFAToken != "" {
+		loginRes, err = loginWithMFACode(cli, lc.APIClient, loginRes.MFAToken)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Update the API client with the new access key from login
 	lc.APIClient.AccessKey = loginRes.AccessKey
 
+	if loginRes.MFAEnrollmentRequired {
+		fmt.Fprintf(cli.Stderr, "  Multi-factor authentication is required for your account. Please enroll an authenticator app.\n")
+
+		if err := enrollMFA(cli, lc.APIClient, loginRes.UserID); err != nil {
+			return err
+		}
+	}
+
 	if loginRes.PasswordUpdateRequired {
 		fmt.Fprintf(cli.Stderr, "  Your password has expired. Please update your password (min. length 8).\n")
 
@@ -274,8 +350,13 @@ func updateInfraConfig(lc loginClient, loginReq *api.LoginRequest, loginRes *api
 		clientHostConfig.TrustedCertificate = lc.TrustedCertificate
 	}
 
-	if loginReq.OIDC != nil {
+	switch {
+	case loginReq.OIDC != nil:
 		clientHostConfig.ProviderID = loginReq.OIDC.ProviderID
+	case loginReq.LDAP != nil:
+		clientHostConfig.ProviderID = loginReq.LDAP.ProviderID
+	case loginReq.SAML != nil:
+		clientHostConfig.ProviderID = loginReq.SAML.ProviderID
 	}
 
 	u, err := urlx.Parse(lc.APIClient.URL)
@@ -291,7 +372,9 @@ func updateInfraConfig(lc loginClient, loginReq *api.LoginRequest, loginRes *api
 	return nil
 }
 
-func oidcflow(provider *api.Provider) (string, error) {
+// oidcflow opens the URL returned by authURL in the browser, and waits for the
+// login code to be sent to the local server.
+func oidcflow(authURL func(state string) (string, error)) (string, error) {
 	// the state makes sure we are getting the correct response for our request
 	state, err := generate.CryptoRandom(12, generate.CharsetAlphaNumeric)
 	if err != nil {
@@ -304,9 +387,9 @@ func oidcflow(provider *api.Provider) (string, error) {
 		return "", err
 	}
 
-	url, err := authURLForProvider(*provider, state)
+	url, err := authURL(state)
 	if err != nil {
-		return "", fmt.Errorf("failed to parse provider URL: %w", err)
+		return "", err
 	}
 
 	err = browser.OpenURL(url)
@@ -326,20 +409,17 @@ func oidcflow(provider *api.Provider) (string, error) {
 	return code, nil
 }
 
-// Given the provider name, directs user to its OIDC login page, then saves the auth code (to later login to infra)
-func loginToProviderN(client *api.Client, providerName string) (*api.LoginRequestOIDC, error) {
-	provider, err := GetProviderByName(client, providerName)
-	if err != nil {
-		return nil, err
-	}
-	return loginToProvider(provider)
-}
-
 // Given the provider, directs user to its OIDC login page, then saves the auth code (to later login to infra)
 func loginToProvider(provider *api.Provider) (*api.LoginRequestOIDC, error) {
 	fmt.Fprintf(os.Stderr, "  Logging in with %s...\n", termenv.String(provider.Name).Bold().String())
 
-	code, err := oidcflow(provider)
+	code, err := oidcflow(func(state string) (string, error) {
+		url, err := authURLForProvider(*provider, state)
+		if err != nil {
+			return "", fmt.Errorf("failed to parse provider URL: %w", err)
+		}
+		return url, nil
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -351,6 +431,42 @@ func loginToProvider(provider *api.Provider) (*api.LoginRequestOIDC, error) {
 	}, nil
 }
 
+// loginToSAMLProvider directs the user to the SAML login of the infra server,
+// which sends them to the identity provider. Once the identity provider
+// responds, the infra server sends the login code to the local server.
+func loginToSAMLProvider(client *api.Client, provider *api.Provider) (*api.LoginRequestSAML, error) {
+	fmt.Fprintf(os.Stderr, "  Logging in with %s...\n", termenv.String(provider.Name).Bold().String())
+
+	code, err := oidcflow(func(state string) (string, error) {
+		return samlLoginURL(client.URL, *provider, state)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.LoginRequestSAML{
+		ProviderID: provider.ID,
+		Code:       code,
+	}, nil
+}
+
+// samlLoginURL builds the URL which starts a login with a SAML provider on
+// the infra server
+func samlLoginURL(serverURL string, provider api.Provider, state string) (string, error) {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse server URL: %w", err)
+	}
+
+	u.Path = fmt.Sprintf("/api/providers/%s/saml/login", provider.ID)
+	u.RawQuery = url.Values{
+		"redirectURL": {cliLoginRedirectURL},
+		"state":       {state},
+	}.Encode()
+
+	return u.String(), nil
+}
+
 func runSignupForLogin(cli *CLI, client *api.Client) (*api.LoginRequestPasswordCredentials, error) {
 	fmt.Fprintln(cli.Stderr, "  Welcome to Infra. Set up your admin user:")
 
@@ -540,6 +656,23 @@ func promptLocalLogin(cli *CLI) (*api.LoginRequestPasswordCredentials, error) {
 	}, nil
 }
 
+// promptLDAPLogin prompts for the username and password of a user in the
+// directory of an LDAP provider
+func promptLDAPLogin(cli *CLI, provider *api.Provider) (*api.LoginRequestLDAP, error) {
+	fmt.Fprintf(os.Stderr, "  Logging in with %s...\n", termenv.String(provider.Name).Bold().String())
+
+	credentials, err := promptLocalLogin(cli)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.LoginRequestLDAP{
+		ProviderID: provider.ID,
+		Name:       credentials.Name,
+		Password:   credentials.Password,
+	}, nil
+}
+
 func listProviders(client *api.Client) ([]api.Provider, error) {
 	logging.Debugf("call server: list providers")
 	providers, err := client.ListProviders("")
@@ -576,6 +709,13 @@ func promptLoginOptions(cli *CLI, client *api.Client) (loginMethod loginMethod,
 	if i == len(options)-1 {
 		return localLogin, nil, nil
 	}
+
+	switch providers[i].Kind {
+	case "ldap":
+		return ldapLogin, &providers[i], nil
+	case "saml":
+		return samlLogin, &providers[i], nil
+	}
 	return oidcLogin, &providers[i], nil
 }
 
@@ -5,6 +5,8 @@ import (
 	"encoding/pem"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
@@ -25,6 +27,10 @@ import (
 	"github.com/infrahq/infra/internal/cmd/types"
 	"github.com/infrahq/infra/internal/race"
 	"github.com/infrahq/infra/internal/server"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/testing/jwkstest"
+	"github.com/infrahq/infra/internal/testing/ldaptest"
 	"github.com/infrahq/infra/uid"
 )
 
@@ -124,6 +130,263 @@ func TestLoginCmd_SetupAdminOnFirstLogin(t *testing.T) {
 	})
 }
 
+func TestLoginCmd_MFA(t *testing.T) {
+	dir := setupEnv(t)
+
+	opts := defaultServerOptions(dir)
+	setupServerTLSOptions(t, &opts)
+
+	srv, err := server.New(opts)
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		assert.Check(t, srv.Run(ctx))
+	}()
+
+	runStep(t, "setup admin", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		t.Cleanup(cancel)
+
+		console := newConsole(t)
+		ctx = PatchCLIWithPTY(ctx, console.Tty())
+
+		g, ctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			return Run(ctx, "login", srv.Addrs.HTTPS.String(), "--skip-tls-verify")
+		})
+
+		exp := expector{console: console}
+		exp.ExpectString(t, "Email:")
+		exp.Send(t, "admin@example.com\n")
+		exp.ExpectString(t, "Password")
+		exp.Send(t, "password\n")
+		exp.ExpectString(t, "Confirm")
+		exp.Send(t, "password\n")
+		exp.ExpectString(t, "Logged in as")
+	})
+
+	var secret string
+	runStep(t, "enroll in mfa", func(t *testing.T) {
+		client, err := defaultAPIClient()
+		assert.NilError(t, err)
+
+		config, err := currentHostConfig()
+		assert.NilError(t, err)
+
+		created, err := client.CreateMFA(&api.CreateMFARequest{UserID: config.UserID})
+		assert.NilError(t, err)
+		secret = created.Secret
+
+		code, err := authn.TOTPCode(secret, time.Now())
+		assert.NilError(t, err)
+
+		_, err = client.VerifyMFA(&api.VerifyMFARequest{UserID: config.UserID, Code: code})
+		assert.NilError(t, err)
+	})
+
+	runStep(t, "login prompts for a code", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		t.Cleanup(cancel)
+
+		console := newConsole(t)
+		ctx = PatchCLIWithPTY(ctx, console.Tty())
+
+		g, ctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			return Run(ctx, "login", "--non-interactive=false", srv.Addrs.HTTPS.String(), "--skip-tls-verify", "--no-agent")
+		})
+
+		exp := expector{console: console}
+		exp.ExpectString(t, "Select a login method")
+		exp.Send(t, "\n")
+		exp.ExpectString(t, "Username:")
+		exp.Send(t, "admin@example.com\n")
+		exp.ExpectString(t, "Password:")
+		exp.Send(t, "password\n")
+		exp.ExpectString(t, "authenticator app")
+
+		// the code used to enroll can't be used again, use the next one
+		code, err := authn.TOTPCode(secret, time.Now().Add(30*time.Second))
+		assert.NilError(t, err)
+		exp.Send(t, code+"\n")
+		exp.ExpectString(t, "Logged in as")
+
+		assert.NilError(t, g.Wait())
+	})
+}
+
+func TestLoginCmd_Device(t *testing.T) {
+	dir := setupEnv(t)
+	// the device flow login looks up the infra provider of the approving user,
+	// which must not be cached from the server of another test
+	data.InvalidateCache()
+	t.Cleanup(data.InvalidateCache)
+
+	opts := defaultServerOptions(dir)
+	setupServerTLSOptions(t, &opts)
+
+	srv, err := server.New(opts)
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		assert.Check(t, srv.Run(ctx))
+	}()
+
+	runStep(t, "setup admin", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		t.Cleanup(cancel)
+
+		console := newConsole(t)
+		ctx = PatchCLIWithPTY(ctx, console.Tty())
+
+		g, ctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			return Run(ctx, "login", srv.Addrs.HTTPS.String(), "--skip-tls-verify")
+		})
+
+		exp := expector{console: console}
+		exp.ExpectString(t, "Email:")
+		exp.Send(t, "admin@example.com\n")
+		exp.ExpectString(t, "Password")
+		exp.Send(t, "password\n")
+		exp.ExpectString(t, "Confirm")
+		exp.Send(t, "password\n")
+		exp.ExpectString(t, "Logged in as")
+	})
+
+	runStep(t, "login with a code approved by another session", func(t *testing.T) {
+		client, err := defaultAPIClient()
+		assert.NilError(t, err)
+
+		ctx, cancel := context.WithCancel(ctx)
+		t.Cleanup(cancel)
+
+		console := newConsole(t)
+		ctx = PatchCLIWithPTY(ctx, console.Tty())
+
+		g, ctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			return Run(ctx, "login", srv.Addrs.HTTPS.String(), "--skip-tls-verify", "--device", "--no-agent")
+		})
+
+		userCode := regexp.MustCompile(`[B-Z]{4}-[B-Z]{4}`)
+		out, err := console.Expect(expect.RegexpPattern(userCode.String()))
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(out, "/device"), out)
+
+		err = client.ApproveDeviceFlow(&api.ApproveDeviceFlowRequest{UserCode: userCode.FindString(out)})
+		assert.NilError(t, err)
+
+		exp := expector{console: console}
+		exp.ExpectString(t, "Logged in as")
+		assert.NilError(t, g.Wait())
+
+		config, err := currentHostConfig()
+		assert.NilError(t, err)
+		assert.Equal(t, config.Name, "admin@example.com")
+	})
+}
+
+func TestLoginCmd_LDAP(t *testing.T) {
+	dir := setupEnv(t)
+	data.InvalidateCache()
+	t.Cleanup(data.InvalidateCache)
+
+	opts := defaultServerOptions(dir)
+	setupServerTLSOptions(t, &opts)
+
+	srv, err := server.New(opts)
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		assert.Check(t, srv.Run(ctx))
+	}()
+
+	directory := ldaptest.NewServer(t,
+		ldaptest.Entry{DN: "cn=infra,dc=example,dc=com", Password: "service-password"},
+		ldaptest.Entry{
+			DN:         "uid=alice,dc=example,dc=com",
+			Attributes: map[string][]string{"uid": {"alice"}, "mail": {"alice@example.com"}},
+			Password:   "alice-password",
+		},
+	)
+
+	runStep(t, "setup admin", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		t.Cleanup(cancel)
+
+		console := newConsole(t)
+		ctx = PatchCLIWithPTY(ctx, console.Tty())
+
+		g, ctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			return Run(ctx, "login", srv.Addrs.HTTPS.String(), "--skip-tls-verify")
+		})
+
+		exp := expector{console: console}
+		exp.ExpectString(t, "Email:")
+		exp.Send(t, "admin@example.com\n")
+		exp.ExpectString(t, "Password")
+		exp.Send(t, "password\n")
+		exp.ExpectString(t, "Confirm")
+		exp.Send(t, "password\n")
+		exp.ExpectString(t, "Logged in as")
+	})
+
+	var provider *api.Provider
+	runStep(t, "add ldap provider", func(t *testing.T) {
+		client, err := defaultAPIClient()
+		assert.NilError(t, err)
+
+		provider, err = client.CreateProvider(&api.CreateProviderRequest{
+			Name: "ad",
+			URL:  directory.URL,
+			Kind: "ldap",
+			LDAP: &api.ProviderLDAP{
+				BindDN:       "cn=infra,dc=example,dc=com",
+				BindPassword: "service-password",
+				SearchBase:   "dc=example,dc=com",
+			},
+		})
+		assert.NilError(t, err)
+	})
+
+	runStep(t, "login with username and password", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(ctx)
+		t.Cleanup(cancel)
+
+		console := newConsole(t)
+		ctx = PatchCLIWithPTY(ctx, console.Tty())
+
+		g, ctx := errgroup.WithContext(ctx)
+		g.Go(func() error {
+			return Run(ctx, "login", "--non-interactive=false", srv.Addrs.HTTPS.String(), "--skip-tls-verify", "--provider", "ad", "--no-agent")
+		})
+
+		exp := expector{console: console}
+		exp.ExpectString(t, "Username:")
+		exp.Send(t, "alice\n")
+		exp.ExpectString(t, "Password:")
+		exp.Send(t, "alice-password\n")
+		exp.ExpectString(t, "Logged in as")
+		assert.NilError(t, g.Wait())
+
+		config, err := currentHostConfig()
+		assert.NilError(t, err)
+		assert.Equal(t, config.Name, "alice@example.com")
+		assert.Equal(t, config.ProviderID, provider.ID)
+	})
+}
+
 func TestLoginCmd_Options(t *testing.T) {
 	dir := setupEnv(t)
 
@@ -185,6 +448,55 @@ func TestLoginCmd_Options(t *testing.T) {
 	})
 }
 
+func TestLoginCmd_WorkloadToken(t *testing.T) {
+	dir := setupEnv(t)
+	issuer := jwkstest.NewIssuer(t)
+
+	opts := defaultServerOptions(dir)
+	setupServerTLSOptions(t, &opts)
+	opts.Config.Users = []server.User{{Name: "deploy@ci.example.com"}}
+	opts.WorkloadIdentity.Issuers = []server.WorkloadIssuerOptions{{
+		Issuer:   issuer.URL,
+		JWKSURL:  issuer.JWKSURL,
+		Audience: "infra",
+		Rules: []server.WorkloadIdentityRuleOptions{{
+			Claims:   map[string]string{"repository": "infrahq/*"},
+			Identity: "deploy@ci.example.com",
+		}},
+	}}
+	srv, err := server.New(opts)
+	assert.NilError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	go func() {
+		assert.Check(t, srv.Run(ctx))
+	}()
+
+	token := issuer.Token(t, map[string]interface{}{"aud": "infra", "repository": "infrahq/infra"})
+	tokenFile := filepath.Join(dir, "token")
+	assert.NilError(t, os.WriteFile(tokenFile, []byte(token+"\n"), 0o600))
+
+	err = Run(ctx, "login", srv.Addrs.HTTPS.String(), "--skip-tls-verify", "--no-agent", "--workload-token", tokenFile)
+	assert.NilError(t, err)
+
+	cfg, err := readConfig()
+	assert.NilError(t, err)
+	expected := []ClientHostConfig{
+		{
+			Name:          "deploy@ci.example.com",
+			AccessKey:     "any-access-key",
+			UserID:        anyUID,
+			Host:          srv.Addrs.HTTPS.String(),
+			SkipTLSVerify: true,
+			Expires:       api.Time(time.Now().UTC().Add(opts.WorkloadIdentity.AccessKeyDuration)),
+			Current:       true,
+		},
+	}
+	assert.DeepEqual(t, cfg.Hosts, expected, cmpClientHostConfig)
+}
+
 var cmpClientHostConfig = cmp.Options{
 	cmp.FilterPath(
 		opt.PathField(ClientHostConfig{}, "AccessKey"),
@@ -564,3 +876,13 @@ func TestAuthURLForProvider(t *testing.T) {
 	assert.NilError(t, err)
 	assert.Equal(t, url, expectedResolvedAuthURL)
 }
+
+func TestSAMLLoginURL(t *testing.T) {
+	provider := api.Provider{ID: 1234, Kind: "saml"}
+
+	url, err := samlLoginURL("https://infra.example.com", provider, "state")
+	assert.NilError(t, err)
+
+	expected := "https://infra.example.com/api/providers/" + provider.ID.String() + "/saml/login?redirectURL=http%3A%2F%2Flocalhost%3A8301&state=state"
+	assert.Equal(t, url, expected)
+}
@@ -13,9 +13,10 @@ import (
 )
 
 type logoutCmdOptions struct {
-	clear  bool
-	server string
-	all    bool
+	clear      bool
+	server     string
+	all        bool
+	everywhere bool
 }
 
 func newLogoutCmd(_ *CLI) *cobra.Command {
@@ -42,7 +43,10 @@ $ infra logout --clear
 $ infra logout infraexampleserver.com --clear 
 		
 # Logout and clear list of all servers 
-$ infra logout --all --clear`,
+$ infra logout --all --clear
+
+# Log out of current server on every device, by revoking all of your sessions
+$ infra logout --everywhere`,
 		Args:  MaxArgs(1),
 		Group: "Core commands:",
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -52,29 +56,37 @@ $ infra logout --all --clear`,
 				}
 				options.server = args[0]
 			}
-			return logout(options.clear, options.server, options.all)
+			return logout(options.clear, options.server, options.all, options.everywhere)
 		},
 	}
 
 	cmd.Flags().BoolVar(&options.clear, "clear", false, "clear from list of servers")
 	cmd.Flags().BoolVar(&options.all, "all", false, "logout of all servers")
+	cmd.Flags().BoolVar(&options.everywhere, "everywhere", false, "logout of every device, by revoking all of your sessions")
 
 	return cmd
 }
 
-func logoutOfServer(hostConfig *ClientHostConfig) (success bool) {
+func logoutOfServer(hostConfig *ClientHostConfig, everywhere bool) (success bool) {
 	if !hostConfig.isLoggedIn() {
 		logging.Debugf("requested but not logged in to server [%s]", hostConfig.Host)
 		return false
 	}
 
 	client := apiClient(hostConfig.Host, hostConfig.AccessKey, httpTransportForHostConfig(hostConfig))
+	userID := hostConfig.UserID
 
 	hostConfig.AccessKey = ""
 	hostConfig.UserID = 0
 	hostConfig.Name = ""
 
-	err := client.Logout()
+	var err error
+	if everywhere {
+		// revoking all the sessions of the user includes this one
+		err = client.DeleteSessions(userID)
+	} else {
+		err = client.Logout()
+	}
 	switch {
 	case api.ErrorStatusCode(err) == http.StatusUnauthorized:
 		logging.Debugf("err: %s", err)
@@ -88,7 +100,7 @@ func logoutOfServer(hostConfig *ClientHostConfig) (success bool) {
 	return true
 }
 
-func logout(clear bool, server string, all bool) error {
+func logout(clear bool, server string, all bool, everywhere bool) error {
 	switch {
 	case all:
 		logging.Debugf("logging out of all servers\n")
@@ -99,13 +111,13 @@ func logout(clear bool, server string, all bool) error {
 	}
 
 	if all {
-		return logoutAll(clear)
+		return logoutAll(clear, everywhere)
 	}
 
-	return logoutOne(clear, server)
+	return logoutOne(clear, server, everywhere)
 }
 
-func logoutAll(clear bool) error {
+func logoutAll(clear bool, everywhere bool) error {
 	config, err := readConfig()
 	if err != nil {
 		if errors.Is(err, ErrConfigNotFound) {
@@ -116,7 +128,7 @@ func logoutAll(clear bool) error {
 	}
 
 	for i := range config.Hosts {
-		logoutOfServer(&config.Hosts[i])
+		logoutOfServer(&config.Hosts[i], everywhere)
 	}
 
 	fmt.Fprintf(os.Stderr, "Logged out of all servers.\n")
@@ -136,7 +148,7 @@ func logoutAll(clear bool) error {
 	return nil
 }
 
-func logoutOne(clear bool, server string) error {
+func logoutOne(clear bool, server string, everywhere bool) error {
 	config, err := readConfig()
 	if err != nil {
 		if errors.Is(err, ErrConfigNotFound) {
@@ -152,7 +164,7 @@ func logoutOne(clear bool, server string) error {
 		return nil
 	}
 
-	success := logoutOfServer(host)
+	success := logoutOfServer(host, everywhere)
 	if success {
 		fmt.Fprintf(os.Stderr, "Logged out of server %s\n", host.Host)
 	}
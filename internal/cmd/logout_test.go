@@ -12,12 +12,16 @@ import (
 	"gotest.tools/v3/assert"
 	"k8s.io/client-go/tools/clientcmd"
 	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+
+	"github.com/infrahq/infra/uid"
 )
 
 type testFields struct {
 	config     ClientConfig
 	count      *int32
 	serverURLs []string
+	// sessionsCount is the number of requests to revoke all sessions
+	sessionsCount *int32
 }
 
 func TestLogout(t *testing.T) {
@@ -29,8 +33,14 @@ func TestLogout(t *testing.T) {
 	t.Setenv("KUBECONFIG", kubeConfigPath)
 
 	setup := func(t *testing.T, currentContext string) testFields {
-		var count int32
+		var count, sessionsCount int32
 		handler := func(resp http.ResponseWriter, req *http.Request) {
+			if requestMatches(req, http.MethodDelete, "/api/users/"+uid.ID(1).String()+"/sessions") {
+				atomic.AddInt32(&sessionsCount, 1)
+				resp.WriteHeader(http.StatusOK)
+				_, _ = resp.Write([]byte(`{}`))
+				return
+			}
 			if req.URL.Path != "/api/logout" {
 				resp.WriteHeader(http.StatusBadRequest)
 				return
@@ -86,9 +96,10 @@ func TestLogout(t *testing.T) {
 		err = clientcmd.WriteToFile(kubeCfg, kubeConfigPath)
 		assert.NilError(t, err)
 		return testFields{
-			config:     cfg,
-			count:      &count,
-			serverURLs: []string{srv.Listener.Addr().String(), srv2.Listener.Addr().String()},
+			config:        cfg,
+			count:         &count,
+			serverURLs:    []string{srv.Listener.Addr().String(), srv2.Listener.Addr().String()},
+			sessionsCount: &sessionsCount,
 		}
 	}
 
@@ -311,6 +322,24 @@ func TestLogout(t *testing.T) {
 		assert.Assert(t, updatedCfg.Hosts[0].AccessKey == "")
 	})
 
+	t.Run("everywhere", func(t *testing.T) {
+		testFields := setup(t, "infra:prod")
+		err := Run(context.Background(), "logout", "--everywhere")
+		assert.NilError(t, err)
+
+		assert.Equal(t, int32(0), atomic.LoadInt32(testFields.count), "calls to logout")
+		assert.Equal(t, int32(1), atomic.LoadInt32(testFields.sessionsCount), "calls to revoke sessions")
+
+		updatedCfg, err := readConfig()
+		assert.NilError(t, err)
+
+		expected := testFields.config
+		expected.Hosts[0].AccessKey = ""
+		expected.Hosts[0].Name = ""
+		expected.Hosts[0].UserID = 0
+		assert.DeepEqual(t, &expected, updatedCfg)
+	})
+
 	t.Run("with too many arguments", func(t *testing.T) {
 		err := Run(context.Background(), "logout", "too", "many")
 		assert.ErrorContains(t, err, `"infra logout" accepts at most 1 argument`)
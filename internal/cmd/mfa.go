@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	survey "github.com/AlecAivazis/survey/v2"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+// loginWithMFACode completes a password login of a user who is enrolled in
+// multi-factor authentication
+func loginWithMFACode(cli *CLI, client *api.Client, token string) (*api.LoginResponse, error) {
+	code, err := promptMFACode(cli, "Enter the code from your authenticator app, or a recovery code:")
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Debugf("call server: login with mfa code")
+	loginRes, err := client.Login(&api.LoginRequest{MFA: &api.LoginRequestMFA{Token: token, Code: code}})
+	if err != nil {
+		if api.ErrorStatusCode(err) == http.StatusUnauthorized {
+			return nil, &LoginError{Message: "your code may be invalid or expired"}
+		}
+		return nil, err
+	}
+
+	return loginRes, nil
+}
+
+// enrollMFA adds a TOTP secret for the user to an authenticator app, and
+// prints the recovery codes once a code from the app has been verified
+func enrollMFA(cli *CLI, client *api.Client, userID uid.ID) error {
+	logging.Debugf("call server: create mfa for user %s", userID)
+	created, err := client.CreateMFA(&api.CreateMFARequest{UserID: userID})
+	if err != nil {
+		return err
+	}
+
+	cli.Output("  Add this account to your authenticator app with the URI, or by entering the secret:\n")
+	cli.Output("    %s", created.ProvisioningURI)
+	cli.Output("    %s\n", created.Secret)
+
+	for {
+		code, err := promptMFACode(cli, "Enter the code from your authenticator app:")
+		if err != nil {
+			return err
+		}
+
+		logging.Debugf("call server: verify mfa for user %s", userID)
+		verified, err := client.VerifyMFA(&api.VerifyMFARequest{UserID: userID, Code: code})
+		if err != nil {
+			if api.ErrorStatusCode(err) == http.StatusBadRequest {
+				cli.Output("  Invalid code. Please try again.")
+				continue
+			}
+			return err
+		}
+
+		cli.Output("\n  Multi-factor authentication is enabled. Store these recovery codes somewhere safe,")
+		cli.Output("  each can be used once to login without your authenticator app:\n")
+		cli.Output("    %s\n", strings.Join(verified.RecoveryCodes, "\n    "))
+		return nil
+	}
+}
+
+func promptMFACode(cli *CLI, message string) (string, error) {
+	var code string
+	if err := survey.AskOne(&survey.Input{Message: message}, &code, cli.surveyIO, survey.WithValidator(survey.Required)); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(code), nil
+}
+
+func enableUserMFA(cli *CLI, name string) error {
+	isSelf, err := isUserSelf(name)
+	if err != nil {
+		return err
+	}
+
+	if !isSelf {
+		return Error{Message: "Users can only enroll themselves in multi-factor authentication"}
+	}
+
+	client, err := defaultAPIClient()
+	if err != nil {
+		return err
+	}
+
+	config, err := currentHostConfig()
+	if err != nil {
+		return err
+	}
+
+	return enrollMFA(cli, client, config.UserID)
+}
+
+func disableUserMFA(cli *CLI, name string) error {
+	client, err := defaultAPIClient()
+	if err != nil {
+		return err
+	}
+
+	user, err := getUserByName(client, name)
+	if err != nil {
+		return err
+	}
+
+	req := &api.DeleteMFARequest{UserID: user.ID}
+
+	isSelf, err := isUserSelf(name)
+	if err != nil {
+		return err
+	}
+
+	// users must prove they have the second factor to remove it
+	if isSelf {
+		req.Code, err = promptMFACode(cli, "Enter the code from your authenticator app, or a recovery code:")
+		if err != nil {
+			return err
+		}
+	}
+
+	logging.Debugf("call server: delete mfa for user %s", user.ID)
+	if err := client.DeleteMFA(req); err != nil {
+		switch api.ErrorStatusCode(err) {
+		case http.StatusForbidden:
+			return Error{Message: fmt.Sprintf("Cannot disable multi-factor authentication for user %q: missing privileges", name)}
+		case http.StatusBadRequest:
+			if isSelf {
+				return Error{Message: "Cannot disable multi-factor authentication: invalid code"}
+			}
+		}
+		return err
+	}
+
+	cli.Output("  Disabled multi-factor authentication for user %q", name)
+	return nil
+}
@@ -0,0 +1,201 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newOIDCClientsCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "oidc-clients",
+		Short:   "Manage applications which use Infra to login",
+		Aliases: []string{"oidc-client"},
+		Group:   "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newOIDCClientsListCmd(cli))
+	cmd.AddCommand(newOIDCClientsAddCmd(cli))
+	cmd.AddCommand(newOIDCClientsRemoveCmd(cli))
+
+	return cmd
+}
+
+func newOIDCClientsListCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List OIDC clients",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: list oidc clients")
+			clients, err := client.ListOIDCClients(api.ListOIDCClientsRequest{})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list OIDC clients: missing privileges for ListOIDCClients",
+					}
+				}
+				return err
+			}
+
+			type row struct {
+				Name             string `header:"NAME"`
+				ClientID         string `header:"CLIENT ID"`
+				SigningAlgorithm string `header:"ALGORITHM"`
+				RedirectURIs     string `header:"REDIRECT URIS"`
+			}
+
+			var rows []row
+			for _, c := range clients.Items {
+				rows = append(rows, row{
+					Name:             c.Name,
+					ClientID:         c.ClientID,
+					SigningAlgorithm: c.SigningAlgorithm,
+					RedirectURIs:     strings.Join(c.RedirectURIs, ", "),
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No OIDC clients found")
+			}
+
+			return nil
+		},
+	}
+}
+
+type oidcClientAddOptions struct {
+	RedirectURIs     []string
+	SigningAlgorithm string
+	Public           bool
+}
+
+func newOIDCClientsAddCmd(cli *CLI) *cobra.Command {
+	var options oidcClientAddOptions
+
+	cmd := &cobra.Command{
+		Use:   "add NAME",
+		Short: "Register an OIDC client",
+		Long: `Register an application which trusts Infra as its OpenID Connect identity provider.
+The client secret is only shown once.`,
+		Example: `# Register a web app
+$ infra oidc-clients add wiki --redirect-uri https://wiki.example.com/oauth/callback
+
+# Register kubelogin, which logs in to kube-apiserver with Infra from kubectl
+$ infra oidc-clients add kubernetes --public --redirect-uri http://localhost:8000 --redirect-uri http://localhost:18000`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.RedirectURIs) == 0 {
+				return fmt.Errorf("missing value for required flags: redirect-uri")
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: create oidc client named %q", args[0])
+			resp, err := client.CreateOIDCClient(&api.CreateOIDCClientRequest{
+				Name:             args[0],
+				RedirectURIs:     options.RedirectURIs,
+				SigningAlgorithm: options.SigningAlgorithm,
+				Public:           options.Public,
+			})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot register OIDC client: missing privileges for CreateOIDCClient",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Registered OIDC client %q", resp.Name)
+			cli.Output("")
+			cli.Output("Client ID: %s", resp.ClientID)
+			if resp.ClientSecret != "" {
+				cli.Output("Client secret: %s", resp.ClientSecret)
+			}
+			return nil
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&options.RedirectURIs, "redirect-uri", nil, "URI which users are redirected to after they login, may be repeated")
+	cmd.Flags().StringVar(&options.SigningAlgorithm, "signing-algorithm", "RS256", "Algorithm of the tokens issued to the client. One of 'RS256, ES256, or EdDSA'")
+	cmd.Flags().BoolVar(&options.Public, "public", false, "Register a client without a secret, such as a CLI, which must use PKCE")
+	return cmd
+}
+
+func newOIDCClientsRemoveCmd(cli *CLI) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:     "remove NAME",
+		Aliases: []string{"rm"},
+		Short:   "Remove an OIDC client",
+		Example: "$ infra oidc-clients remove wiki",
+		Args:    ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: list oidc clients named %q", args[0])
+			clients, err := client.ListOIDCClients(api.ListOIDCClientsRequest{Name: args[0]})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot remove OIDC client: missing privileges for ListOIDCClients",
+					}
+				}
+				return err
+			}
+
+			if clients.Count == 0 && !force {
+				return Error{Message: fmt.Sprintf("No OIDC clients named %q", args[0])}
+			}
+
+			for _, c := range clients.Items {
+				logging.Debugf("call server: delete oidc client %s", c.ID)
+				if err := client.DeleteOIDCClient(c.ID); err != nil {
+					if api.ErrorStatusCode(err) == 403 {
+						logging.Debugf("%s", err.Error())
+						return Error{
+							Message: "Cannot remove OIDC client: missing privileges for DeleteOIDCClient",
+						}
+					}
+					return err
+				}
+
+				cli.Output("Removed OIDC client %q", c.Name)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Exit successfully even if the OIDC client does not exist")
+	return cmd
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestOIDCClientsCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	wiki := api.OIDCClient{
+		ID:               uid.ID(1001),
+		Name:             "wiki",
+		ClientID:         "the-client-id",
+		RedirectURIs:     []string{"https://wiki.example.com/callback"},
+		SigningAlgorithm: "RS256",
+	}
+
+	setup := func(t *testing.T) chan api.CreateOIDCClientRequest {
+		created := make(chan api.CreateOIDCClientRequest, 1)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodGet, "/api/oidc-clients"):
+				items := []api.OIDCClient{wiki}
+				if name := req.URL.Query().Get("name"); name != "" && name != wiki.Name {
+					items = nil
+				}
+
+				resp.WriteHeader(http.StatusOK)
+				err := json.NewEncoder(resp).Encode(api.ListResponse[api.OIDCClient]{
+					Count: len(items),
+					Items: items,
+				})
+				assert.Check(t, err)
+
+			case requestMatches(req, http.MethodPost, "/api/oidc-clients"):
+				var createReq api.CreateOIDCClientRequest
+				err := json.NewDecoder(req.Body).Decode(&createReq)
+				assert.Check(t, err)
+				created <- createReq
+
+				resp.WriteHeader(http.StatusCreated)
+				err = json.NewEncoder(resp).Encode(api.CreateOIDCClientResponse{
+					ID:               wiki.ID,
+					Name:             wiki.Name,
+					ClientID:         wiki.ClientID,
+					ClientSecret:     "the-client-secret",
+					RedirectURIs:     wiki.RedirectURIs,
+					SigningAlgorithm: wiki.SigningAlgorithm,
+				})
+				assert.Check(t, err)
+
+			case requestMatches(req, http.MethodDelete, "/api/oidc-clients/"+wiki.ID.String()):
+				resp.WriteHeader(http.StatusNoContent)
+
+			default:
+				resp.WriteHeader(http.StatusBadRequest)
+			}
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+
+		return created
+	}
+
+	t.Run("list", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "oidc-clients", "list")
+		assert.NilError(t, err)
+
+		out := bufs.Stdout.String()
+		assert.Check(t, is.Contains(out, "wiki"))
+		assert.Check(t, is.Contains(out, "the-client-id"))
+		assert.Check(t, is.Contains(out, "https://wiki.example.com/callback"))
+	})
+
+	t.Run("add", func(t *testing.T) {
+		created := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "oidc-clients", "add", "wiki", "--redirect-uri", "https://wiki.example.com/callback")
+		assert.NilError(t, err)
+
+		expected := api.CreateOIDCClientRequest{
+			Name:             "wiki",
+			RedirectURIs:     []string{"https://wiki.example.com/callback"},
+			SigningAlgorithm: "RS256",
+		}
+		assert.DeepEqual(t, <-created, expected)
+		assert.Check(t, is.Contains(bufs.Stdout.String(), "Client ID: the-client-id"))
+		assert.Check(t, is.Contains(bufs.Stdout.String(), "Client secret: the-client-secret"))
+	})
+
+	t.Run("add without redirect uri", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "oidc-clients", "add", "wiki")
+		assert.ErrorContains(t, err, "missing value for required flags: redirect-uri")
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "oidc-clients", "remove", "wiki")
+		assert.NilError(t, err)
+		assert.Equal(t, bufs.Stdout.String(), "Removed OIDC client \"wiki\"\n")
+	})
+
+	t.Run("remove missing client", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "oidc-clients", "remove", "blog")
+		assert.ErrorContains(t, err, `No OIDC clients named "blog"`)
+
+		err = Run(context.Background(), "oidc-clients", "remove", "blog", "--force")
+		assert.NilError(t, err)
+	})
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+// postgresDestination returns the postgres destination with the name, or nil
+// if there is no postgres destination with the name.
+func postgresDestination(client *api.Client, name string) (*api.Destination, error) {
+	logging.Debugf("call server: list postgres destinations named %q", name)
+	destinations, err := client.ListDestinations(api.ListDestinationsRequest{Name: name, Kind: "postgres"})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, destination := range destinations.Items {
+		if destination.Name == name && destination.Kind == "postgres" {
+			return &destination, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// usePostgres requests credentials from the connector of a postgres
+// destination, and prints a connection URL for the database.
+func usePostgres(cli *CLI, client *api.Client, destination api.Destination) error {
+	logging.Debugf("call server: create token")
+	token, err := client.CreateToken()
+	if err != nil {
+		return err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM([]byte(destination.Connection.CA)) {
+		return fmt.Errorf("invalid certificate for %s", destination.Name)
+	}
+
+	connector := &api.Client{
+		Name:      "cli",
+		Version:   internal.Version,
+		URL:       "https://" + destination.Connection.URL,
+		AccessKey: token.Token,
+		HTTP: http.Client{
+			Timeout: 60 * time.Second,
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{
+					RootCAs:    pool,
+					MinVersion: tls.VersionTLS12,
+				},
+			},
+		},
+	}
+
+	logging.Debugf("call connector: create credentials for %s", destination.Name)
+	creds, err := connector.CreateDatabaseCredentials()
+	if err != nil {
+		if api.ErrorStatusCode(err) == 403 {
+			logging.Debugf("%s", err.Error())
+			return Error{
+				Message: fmt.Sprintf("Cannot use %s: missing grants for any role of the database", destination.Name),
+			}
+		}
+		return err
+	}
+
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(creds.Username, creds.Password),
+		Host:   net.JoinHostPort(creds.Host, creds.Port),
+		Path:   "/" + creds.Database,
+	}
+
+	cli.Output("%s", u.String())
+	fmt.Fprintf(cli.Stderr, "Credentials for %s expire at %s\n", destination.Name, creds.Expires.Time().Local().Format(time.RFC1123))
+	return nil
+}
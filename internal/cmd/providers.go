@@ -3,7 +3,9 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"os"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"sigs.k8s.io/yaml"
@@ -31,6 +33,7 @@ func newProvidersCmd(cli *CLI) *cobra.Command {
 	cmd.AddCommand(newProvidersAddCmd(cli))
 	cmd.AddCommand(newProvidersEditCmd(cli))
 	cmd.AddCommand(newProvidersRemoveCmd(cli))
+	cmd.AddCommand(newProvidersSCIMKeyCmd(cli))
 
 	return cmd
 }
@@ -119,6 +122,13 @@ type providerAddOptions struct {
 	ClientID     string
 	ClientSecret string
 	Kind         string
+	LDAP         api.ProviderLDAP
+	SAML         providerAddSAMLOptions
+}
+
+type providerAddSAMLOptions struct {
+	MetadataFile    string
+	GroupsAttribute string
 }
 
 func (o providerAddOptions) Validate() error {
@@ -126,11 +136,23 @@ func (o providerAddOptions) Validate() error {
 	if o.URL == "" {
 		missing = append(missing, "url")
 	}
-	if o.ClientID == "" {
-		missing = append(missing, "client-id")
-	}
-	if o.ClientSecret == "" {
-		missing = append(missing, "client-secret")
+	if o.Kind == "ldap" {
+		if o.LDAP.BindDN == "" {
+			missing = append(missing, "bind-dn")
+		}
+		if o.LDAP.BindPassword == "" {
+			missing = append(missing, "bind-password")
+		}
+		if o.LDAP.SearchBase == "" {
+			missing = append(missing, "search-base")
+		}
+	} else if o.Kind != "saml" {
+		if o.ClientID == "" {
+			missing = append(missing, "client-id")
+		}
+		if o.ClientSecret == "" {
+			missing = append(missing, "client-secret")
+		}
 	}
 	if len(missing) > 0 {
 		return fmt.Errorf("missing value for required flags: %v", strings.Join(missing, ", "))
@@ -147,7 +169,14 @@ func newProvidersAddCmd(cli *CLI) *cobra.Command {
 		Long: `Add an identity provider for users to authenticate.
 PROVIDER is a short unique name of the identity provider being added (eg. okta)`,
 		Example: `# Connect okta to infra
-$ infra providers add okta --url example.okta.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --kind okta`,
+$ infra providers add okta --url example.okta.com --client-id 0oa3sz06o6do0muoW5d7 --client-secret VT_oXtkEDaT7UFY-C3DSRWYb00qyKZ1K1VCq7YzN --kind okta
+
+# Connect an Active Directory domain to infra
+$ export INFRA_PROVIDER_BIND_PASSWORD=Ls8qhZ2HKDHV4gvSxMQz
+$ infra providers add ad --kind ldap --url ldaps://dc.example.com --bind-dn cn=infra,cn=Users,dc=example,dc=com --search-base dc=example,dc=com --user-filter '(sAMAccountName=%s)'
+
+# Connect a SAML identity provider to infra, with the URL of its metadata
+$ infra providers add adfs --kind saml --url https://adfs.example.com/FederationMetadata/2007-06/FederationMetadata.xml --groups-attribute http://schemas.xmlsoap.org/claims/Group`,
 		Args: ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if err := cliopts.DefaultsFromEnv("INFRA_PROVIDER", cmd.Flags()); err != nil {
@@ -163,14 +192,29 @@ $ infra providers add okta --url example.okta.com --client-id 0oa3sz06o6do0muoW5
 				return err
 			}
 
-			logging.Debugf("call server: create provider named %q", args[0])
-			_, err = client.CreateProvider(&api.CreateProviderRequest{
+			req := &api.CreateProviderRequest{
 				Name:         args[0],
 				URL:          opts.URL,
 				ClientID:     opts.ClientID,
 				ClientSecret: opts.ClientSecret,
 				Kind:         opts.Kind,
-			})
+			}
+			switch opts.Kind {
+			case "ldap":
+				req.LDAP = &opts.LDAP
+			case "saml":
+				req.SAML = &api.ProviderSAML{GroupsAttribute: opts.SAML.GroupsAttribute}
+				if opts.SAML.MetadataFile != "" {
+					metadata, err := os.ReadFile(opts.SAML.MetadataFile)
+					if err != nil {
+						return fmt.Errorf("read metadata: %w", err)
+					}
+					req.SAML.Metadata = string(metadata)
+				}
+			}
+
+			logging.Debugf("call server: create provider named %q", args[0])
+			_, err = client.CreateProvider(req)
 			if err != nil {
 				if api.ErrorStatusCode(err) == 403 {
 					logging.Debugf("%s", err.Error())
@@ -186,10 +230,17 @@ $ infra providers add okta --url example.okta.com --client-id 0oa3sz06o6do0muoW5
 		},
 	}
 
-	cmd.Flags().StringVar(&opts.URL, "url", "", "Base URL of the domain of the OIDC identity provider (eg. acme.okta.com)")
+	cmd.Flags().StringVar(&opts.URL, "url", "", "Base URL of the domain of the OIDC identity provider (eg. acme.okta.com), URL of the LDAP server (eg. ldaps://dc.example.com), or URL of the SAML metadata")
 	cmd.Flags().StringVar(&opts.ClientID, "client-id", "", "OIDC client ID")
 	cmd.Flags().StringVar(&opts.ClientSecret, "client-secret", "", "OIDC client secret")
-	cmd.Flags().StringVar(&opts.Kind, "kind", "oidc", "The identity provider kind. One of 'oidc, okta, azure, or google'")
+	cmd.Flags().StringVar(&opts.Kind, "kind", "oidc", "The identity provider kind. One of 'oidc, okta, azure, google, ldap, or saml'")
+	cmd.Flags().StringVar(&opts.LDAP.BindDN, "bind-dn", "", "LDAP DN to bind as to search the directory")
+	cmd.Flags().StringVar(&opts.LDAP.BindPassword, "bind-password", "", "LDAP password of the bind DN")
+	cmd.Flags().StringVar(&opts.LDAP.SearchBase, "search-base", "", "LDAP DN to search for users and groups")
+	cmd.Flags().StringVar(&opts.LDAP.UserFilter, "user-filter", "", "LDAP filter to find a user, %s is replaced by the username (default \"(uid=%s)\")")
+	cmd.Flags().StringVar(&opts.LDAP.GroupFilter, "group-filter", "", "LDAP filter to find the groups of a user, %s is replaced by the DN of the user (default \"(member=%s)\")")
+	cmd.Flags().StringVar(&opts.SAML.MetadataFile, "metadata", "", "File with the SAML metadata, instead of downloading it from the URL")
+	cmd.Flags().StringVar(&opts.SAML.GroupsAttribute, "groups-attribute", "", "SAML attribute which lists the groups of a user (default \"groups\")")
 	return cmd
 }
 
@@ -211,6 +262,12 @@ func updateProvider(cli *CLI, name string, secret string) error {
 	}
 	provider := res.Items[0]
 
+	if provider.Kind == "ldap" || provider.Kind == "saml" {
+		return Error{
+			Message: fmt.Sprintf("Provider %s does not have a client secret, remove it and add it again to change its configuration", name),
+		}
+	}
+
 	logging.Debugf("call server: update provider named %q", name)
 	_, err = client.UpdateProvider(api.UpdateProviderRequest{
 		ID:           provider.ID,
@@ -284,6 +341,57 @@ func newProvidersRemoveCmd(cli *CLI) *cobra.Command {
 	return cmd
 }
 
+func newProvidersSCIMKeyCmd(cli *CLI) *cobra.Command {
+	var ttl time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "scim-key PROVIDER",
+		Short: "Create an access key for SCIM provisioning",
+		Long: `Create an access key which the identity provider can use to provision
+users and groups with SCIM. Set the SCIM base URL of the provider to
+https://<infra server>/scim/v2 and use the key as the bearer token.`,
+		Example: `# Create a SCIM access key for okta that expires in 90 days
+$ infra providers scim-key okta --ttl=2160h`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			provider, err := GetProviderByName(client, args[0])
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: create scim key for provider %s", provider.ID)
+			resp, err := client.CreateProviderSCIMKey(&api.CreateProviderSCIMKeyRequest{
+				ID:  provider.ID,
+				TTL: api.Duration(ttl),
+			})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot create SCIM key: missing privileges for CreateProviderSCIMKey",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Issued SCIM access key %q for provider %q", resp.Name, provider.Name)
+			cli.Output("This key will expire in %s", ExactDuration(ttl))
+			cli.Output("")
+			cli.Output("Key: %s", resp.AccessKey)
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationVar(&ttl, "ttl", 365*24*time.Hour, "The total time that the access key will be valid for")
+
+	return cmd
+}
+
 func GetProviderByName(client *api.Client, name string) (*api.Provider, error) {
 	logging.Debugf("call server: list providers named %q", name)
 	providers, err := client.ListProviders(name)
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
@@ -115,6 +117,72 @@ func TestProvidersAddCmd(t *testing.T) {
 		assert.ErrorContains(t, err, "missing value for required flags: url, client-id, client-secret")
 	})
 
+	t.Run("ldap provider with flags", func(t *testing.T) {
+		ch := setup(t)
+
+		t.Setenv("INFRA_PROVIDER_BIND_PASSWORD", "bind-password")
+
+		err := Run(context.Background(),
+			"providers", "add", "ad",
+			"--kind", "ldap",
+			"--url", "ldaps://dc.example.com",
+			"--bind-dn", "cn=infra,dc=example,dc=com",
+			"--search-base", "dc=example,dc=com",
+			"--user-filter", "(sAMAccountName=%s)",
+		)
+		assert.NilError(t, err)
+
+		createProviderRequest := <-ch
+
+		expected := api.CreateProviderRequest{
+			Name: "ad",
+			URL:  "ldaps://dc.example.com",
+			Kind: "ldap",
+			LDAP: &api.ProviderLDAP{
+				BindDN:       "cn=infra,dc=example,dc=com",
+				BindPassword: "bind-password",
+				SearchBase:   "dc=example,dc=com",
+				UserFilter:   "(sAMAccountName=%s)",
+			},
+		}
+		assert.DeepEqual(t, createProviderRequest, expected)
+	})
+
+	t.Run("ldap provider missing required flags", func(t *testing.T) {
+		err := Run(context.Background(), "providers", "add", "ad", "--kind", "ldap", "--url", "ldaps://dc.example.com")
+		assert.ErrorContains(t, err, "missing value for required flags: bind-dn, bind-password, search-base")
+	})
+
+	t.Run("saml provider with metadata file", func(t *testing.T) {
+		ch := setup(t)
+
+		metadataFile := filepath.Join(t.TempDir(), "metadata.xml")
+		err := os.WriteFile(metadataFile, []byte("<EntityDescriptor/>"), 0o600)
+		assert.NilError(t, err)
+
+		err = Run(context.Background(),
+			"providers", "add", "adfs",
+			"--kind", "saml",
+			"--url", "https://adfs.example.com/metadata",
+			"--metadata", metadataFile,
+			"--groups-attribute", "memberOf",
+		)
+		assert.NilError(t, err)
+
+		createProviderRequest := <-ch
+
+		expected := api.CreateProviderRequest{
+			Name: "adfs",
+			URL:  "https://adfs.example.com/metadata",
+			Kind: "saml",
+			SAML: &api.ProviderSAML{
+				Metadata:        "<EntityDescriptor/>",
+				GroupsAttribute: "memberOf",
+			},
+		}
+		assert.DeepEqual(t, createProviderRequest, expected)
+	})
+
 	t.Run("list with json", func(t *testing.T) {
 		setup(t)
 
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+type requestOptions struct {
+	Reason   string
+	Duration time.Duration
+}
+
+func newRequestCmd(cli *CLI) *cobra.Command {
+	var options requestOptions
+
+	cmd := &cobra.Command{
+		Use:   "request ROLE DESTINATION",
+		Short: "Request temporary access to a destination",
+		Long: `Request temporary access to a destination.
+
+The request must be approved by an admin or approver before the access is granted.
+Once approved, the access is removed after the requested duration.`,
+		Example: `# Request admin access to a destination for 4 hours
+$ infra request admin production --reason "investigating incident 1234" --duration 4h`,
+		Args:  ExactArgs(2),
+		Group: "Core commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.Reason == "" {
+				return Error{Message: "A reason is required to request access; specify one with '--reason'"}
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: create access request for %q on %q", args[0], args[1])
+			request, err := client.CreateAccessRequest(&api.CreateAccessRequestRequest{
+				Privilege: args[0],
+				Resource:  args[1],
+				Reason:    options.Reason,
+				Duration:  api.Duration(options.Duration),
+			})
+			if err != nil {
+				return err
+			}
+
+			cli.Output("Requested %q access to %q for %s", request.Privilege, request.Resource, ExactDuration(options.Duration))
+			cli.Output("The request %s must be approved before access is granted", request.ID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Reason, "reason", "", "Why the access is needed")
+	cmd.Flags().DurationVar(&options.Duration, "duration", time.Hour, "How long the access is needed for once approved")
+	return cmd
+}
+
+func newRequestsCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "requests",
+		Short:   "Manage access requests",
+		Aliases: []string{"reqs"},
+		Group:   "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newRequestsListCmd(cli))
+	cmd.AddCommand(newRequestsApproveCmd(cli))
+	cmd.AddCommand(newRequestsDenyCmd(cli))
+
+	return cmd
+}
+
+type requestsListOptions struct {
+	Status string
+	All    bool
+}
+
+func newRequestsListCmd(cli *CLI) *cobra.Command {
+	var options requestsListOptions
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List access requests",
+		Long: `List access requests.
+
+Admins and approvers see the requests of all users. Other users see only their own requests.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			req := api.ListAccessRequestsRequest{Status: options.Status}
+			if options.All {
+				req.Status = ""
+			}
+
+			logging.Debugf("call server: list access requests")
+			requests, err := client.ListAccessRequests(req)
+			if api.ErrorStatusCode(err) == 403 {
+				logging.Debugf("%s", err.Error())
+
+				config, err := currentHostConfig()
+				if err != nil {
+					return err
+				}
+
+				logging.Debugf("call server: list access requests for user %s", config.UserID)
+				req.User = config.UserID
+				requests, err = client.ListAccessRequests(req)
+				if err != nil {
+					return err
+				}
+			} else if err != nil {
+				return err
+			}
+
+			type row struct {
+				ID       string `header:"ID"`
+				User     string `header:"USER"`
+				Access   string `header:"ACCESS"`
+				Resource string `header:"DESTINATION"`
+				Duration string `header:"DURATION"`
+				Status   string `header:"STATUS"`
+				Reason   string `header:"REASON"`
+				Created  string `header:"CREATED"`
+			}
+
+			var rows []row
+			for _, r := range requests.Items {
+				rows = append(rows, row{
+					ID:       r.ID.String(),
+					User:     accessRequestUser(&r),
+					Access:   r.Privilege,
+					Resource: r.Resource,
+					Duration: ExactDuration(time.Duration(r.Duration)),
+					Status:   r.Status,
+					Reason:   r.Reason,
+					Created:  HumanTime(r.Created.Time(), "unknown"),
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No access requests found")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Status, "status", "pending", "Only show requests with this status [pending, approved, denied]")
+	cmd.Flags().BoolVar(&options.All, "all", false, "Show requests with any status")
+	return cmd
+}
+
+func newRequestsApproveCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "approve ID",
+		Short: "Approve an access request",
+		Long:  `Approve an access request. A grant is created that is removed after the requested duration.`,
+		Args:  ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			request, err := decideAccessRequest(args[0], "approve", "ApproveAccessRequest", client.ApproveAccessRequest)
+			if err != nil {
+				return err
+			}
+
+			cli.Output("Approved %q access to %q for %q for %s",
+				request.Privilege, request.Resource, accessRequestUser(request), ExactDuration(time.Duration(request.Duration)))
+			return nil
+		},
+	}
+}
+
+func newRequestsDenyCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "deny ID",
+		Short: "Deny an access request",
+		Args:  ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			request, err := decideAccessRequest(args[0], "deny", "DenyAccessRequest", client.DenyAccessRequest)
+			if err != nil {
+				return err
+			}
+
+			cli.Output("Denied %q access to %q for %q", request.Privilege, request.Resource, accessRequestUser(request))
+			return nil
+		},
+	}
+}
+
+func decideAccessRequest(rawID, decision, operation string, fn func(uid.ID) (*api.AccessRequest, error)) (*api.AccessRequest, error) {
+	id, err := uid.Parse([]byte(rawID))
+	if err != nil {
+		return nil, Error{Message: fmt.Sprintf("Invalid access request ID %q", rawID)}
+	}
+
+	logging.Debugf("call server: %s access request %s", decision, id)
+	request, err := fn(id)
+	if err != nil {
+		switch api.ErrorStatusCode(err) {
+		case 403:
+			logging.Debugf("%s", err.Error())
+			return nil, Error{
+				Message: fmt.Sprintf("Cannot %s access request: missing privileges for %s", decision, operation),
+			}
+		case 404:
+			return nil, Error{Message: fmt.Sprintf("Access request %q not found", rawID)}
+		}
+		return nil, err
+	}
+
+	return request, nil
+}
+
+func accessRequestUser(request *api.AccessRequest) string {
+	if request.RequestedByName != "" {
+		return request.RequestedByName
+	}
+	return request.RequestedBy.String()
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestRequestCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	requestCh := make(chan api.CreateAccessRequestRequest, 1)
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		if !requestMatches(req, http.MethodPost, "/api/access-requests") {
+			resp.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		var createReq api.CreateAccessRequestRequest
+		err := json.NewDecoder(req.Body).Decode(&createReq)
+		assert.Check(t, err)
+		requestCh <- createReq
+
+		resp.WriteHeader(http.StatusCreated)
+		writeResponse(t, resp, api.AccessRequest{
+			ID:        uid.ID(1234),
+			Privilege: createReq.Privilege,
+			Resource:  createReq.Resource,
+			Status:    "pending",
+		})
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	err := writeConfig(&cfg)
+	assert.NilError(t, err)
+
+	t.Run("missing reason", func(t *testing.T) {
+		err := Run(context.Background(), "request", "admin", "production")
+		assert.ErrorContains(t, err, "reason is required")
+	})
+
+	t.Run("success", func(t *testing.T) {
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "request", "admin", "production", "--reason=incident", "--duration=4h")
+		assert.NilError(t, err)
+
+		expected := api.CreateAccessRequestRequest{
+			Privilege: "admin",
+			Resource:  "production",
+			Reason:    "incident",
+			Duration:  api.Duration(4 * time.Hour),
+		}
+		assert.DeepEqual(t, <-requestCh, expected)
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), `Requested "admin" access to "production" for 4 hours`))
+	})
+}
+
+func TestRequestsApproveCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	id := uid.ID(1234)
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		switch {
+		case requestMatches(req, http.MethodPost, "/api/access-requests/"+id.String()+"/approve"):
+			resp.WriteHeader(http.StatusCreated)
+			writeResponse(t, resp, api.AccessRequest{
+				ID:              id,
+				RequestedByName: "requester@example.com",
+				Privilege:       "admin",
+				Resource:        "production",
+				Duration:        api.Duration(4 * time.Hour),
+				Status:          "approved",
+			})
+		case requestMatches(req, http.MethodPost, "/api/access-requests/"+id.String()+"/deny"):
+			resp.WriteHeader(http.StatusForbidden)
+			writeResponse(t, resp, api.Error{Code: http.StatusForbidden})
+		default:
+			resp.WriteHeader(http.StatusNotFound)
+			writeResponse(t, resp, api.Error{Code: http.StatusNotFound})
+		}
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	err := writeConfig(&cfg)
+	assert.NilError(t, err)
+
+	t.Run("approve", func(t *testing.T) {
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "requests", "approve", id.String())
+		assert.NilError(t, err)
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), `Approved "admin" access to "production" for "requester@example.com"`))
+	})
+
+	t.Run("deny without privileges", func(t *testing.T) {
+		err := Run(context.Background(), "requests", "deny", id.String())
+		assert.ErrorContains(t, err, "missing privileges for DenyAccessRequest")
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		err := Run(context.Background(), "requests", "approve", uid.ID(99).String())
+		assert.ErrorContains(t, err, "not found")
+	})
+}
@@ -0,0 +1,475 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"sigs.k8s.io/yaml"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+func newReviewsCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "reviews",
+		Short: "Manage access reviews",
+		Long: `Manage access reviews.
+
+An access review is a campaign to confirm that grants are still needed. Reviewers
+decide to keep or revoke each grant to a destination, or to a group and its members.`,
+		Group: "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newReviewsListCmd(cli))
+	cmd.AddCommand(newReviewsCreateCmd(cli))
+	cmd.AddCommand(newReviewsShowCmd(cli))
+	cmd.AddCommand(newReviewsDecideCmd(cli, "keep"))
+	cmd.AddCommand(newReviewsDecideCmd(cli, "revoke"))
+	cmd.AddCommand(newReviewsExportCmd(cli))
+
+	return cmd
+}
+
+type reviewsListOptions struct {
+	Status string
+	All    bool
+}
+
+func newReviewsListCmd(cli *CLI) *cobra.Command {
+	var options reviewsListOptions
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List access reviews",
+		Long: `List access reviews.
+
+Admins see all access reviews. Other users see the access reviews they are a reviewer of.`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			req := api.ListAccessReviewsRequest{Status: options.Status}
+			if options.All {
+				req.Status = ""
+			}
+
+			logging.Debugf("call server: list access reviews")
+			reviews, err := client.ListAccessReviews(req)
+			if err != nil {
+				return err
+			}
+
+			type row struct {
+				ID       string `header:"ID"`
+				Name     string `header:"NAME"`
+				Scope    string `header:"SCOPE"`
+				Deadline string `header:"DEADLINE"`
+				Status   string `header:"STATUS"`
+			}
+
+			var rows []row
+			for _, r := range reviews.Items {
+				scope := r.Destination
+				if r.Group != 0 {
+					scope = "group " + r.Group.String()
+					if group, err := client.GetGroup(r.Group); err == nil {
+						scope = "group " + group.Name
+					}
+				}
+
+				rows = append(rows, row{
+					ID:       r.ID.String(),
+					Name:     r.Name,
+					Scope:    scope,
+					Deadline: HumanTime(r.Deadline.Time(), "unknown"),
+					Status:   r.Status,
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No access reviews found")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Status, "status", "open", "Only show access reviews with this status [open, closed]")
+	cmd.Flags().BoolVar(&options.All, "all", false, "Show access reviews with any status")
+	return cmd
+}
+
+type reviewsCreateOptions struct {
+	Destination string
+	Group       string
+	Reviewers   []string
+	Deadline    string
+	AutoRevoke  bool
+}
+
+func newReviewsCreateCmd(cli *CLI) *cobra.Command {
+	var options reviewsCreateOptions
+
+	cmd := &cobra.Command{
+		Use:   "create NAME",
+		Short: "Create an access review",
+		Long: `Create an access review of the grants to a destination, or to a group and its members.
+
+Admins can review every access review. Use '--reviewer' to allow other users to review it.`,
+		Example: `# Review the grants to the production destination by the end of the quarter
+$ infra reviews create "2022 Q4 production" --destination production --deadline 2022-12-31 --reviewer janedoe@example.com
+
+# Review the grants of the developers group, revoking any grant which is not reviewed in time
+$ infra reviews create "developers" --group developers --deadline 2022-12-31 --auto-revoke`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if (options.Destination == "") == (options.Group == "") {
+				return Error{Message: "One of '--destination' or '--group' is required"}
+			}
+
+			deadline, err := parseDeadline(options.Deadline)
+			if err != nil {
+				return err
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			req := &api.CreateAccessReviewRequest{
+				Name:        args[0],
+				Destination: options.Destination,
+				Deadline:    api.Time(deadline),
+				AutoRevoke:  options.AutoRevoke,
+			}
+
+			if options.Group != "" {
+				group, err := getGroupByName(client, options.Group)
+				if err != nil {
+					return err
+				}
+				req.Group = group.ID
+			}
+
+			for _, name := range options.Reviewers {
+				user, err := getUserByName(client, name)
+				if err != nil {
+					return err
+				}
+				req.Reviewers = append(req.Reviewers, user.ID)
+			}
+
+			logging.Debugf("call server: create access review %q", args[0])
+			review, err := client.CreateAccessReview(req)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot create access review: missing privileges for CreateAccessReview",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Created access review %s, due %s", review.ID, review.Deadline.Format(time.RFC1123))
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Destination, "destination", "", "Review the grants to this destination")
+	cmd.Flags().StringVar(&options.Group, "group", "", "Review the grants to this group and its members")
+	cmd.Flags().StringSliceVar(&options.Reviewers, "reviewer", nil, "A user who may review the grants, in addition to admins")
+	cmd.Flags().StringVar(&options.Deadline, "deadline", "", "When the review closes, as a date (2006-01-02) or time (2006-01-02T15:04:05Z)")
+	cmd.Flags().BoolVar(&options.AutoRevoke, "auto-revoke", false, "Revoke the grants which are not reviewed by the deadline")
+	return cmd
+}
+
+// parseDeadline parses a date or an RFC3339 time. A date is the end of that
+// day in UTC.
+func parseDeadline(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, Error{Message: "A deadline is required; specify one with '--deadline'"}
+	}
+
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.Add(24*time.Hour - time.Second), nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, Error{Message: fmt.Sprintf("Invalid deadline %q; use a date (2006-01-02) or time (2006-01-02T15:04:05Z)", s)}
+	}
+	return t, nil
+}
+
+type reviewsShowOptions struct {
+	Decision string
+	Format   string
+}
+
+func newReviewsShowCmd(cli *CLI) *cobra.Command {
+	var options reviewsShowOptions
+
+	cmd := &cobra.Command{
+		Use:   "show ID",
+		Short: "Show the grants of an access review",
+		Args:  ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			items, err := listAccessReviewItems(client, args[0], options.Decision)
+			if err != nil {
+				return err
+			}
+
+			switch options.Format {
+			case "json", "yaml":
+				return printAccessReviewItems(cli, options.Format, items)
+			}
+
+			type row struct {
+				ID        string `header:"ID"`
+				Name      string `header:"NAME"`
+				Privilege string `header:"ROLE"`
+				Resource  string `header:"RESOURCE"`
+				Decision  string `header:"DECISION"`
+			}
+
+			var rows []row
+			for _, item := range items {
+				rows = append(rows, row{
+					ID:        item.ID.String(),
+					Name:      accessReviewItemSubject(item),
+					Privilege: item.Privilege,
+					Resource:  item.Resource,
+					Decision:  item.Decision,
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No grants found")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Decision, "decision", "", "Only show grants with this decision [pending, keep, revoke]")
+	addFormatFlag(cmd.Flags(), &options.Format)
+	return cmd
+}
+
+func newReviewsDecideCmd(cli *CLI, decision string) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:  decision + " ID ITEM [ITEM...]",
+		Args: MinArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			reviewID, err := parseAccessReviewID(args[0])
+			if err != nil {
+				return err
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			for _, rawID := range args[1:] {
+				itemID, err := uid.Parse([]byte(rawID))
+				if err != nil {
+					return Error{Message: fmt.Sprintf("Invalid access review item ID %q", rawID)}
+				}
+
+				logging.Debugf("call server: %s access review item %s", decision, itemID)
+				item, err := client.DecideAccessReviewItem(&api.DecideAccessReviewItemRequest{
+					ID:       reviewID,
+					ItemID:   itemID,
+					Decision: decision,
+				})
+				if err != nil {
+					switch api.ErrorStatusCode(err) {
+					case 403:
+						logging.Debugf("%s", err.Error())
+						return Error{
+							Message: "Cannot review grant: missing privileges for DecideAccessReviewItem",
+						}
+					case 404:
+						return Error{Message: fmt.Sprintf("Access review item %q not found", rawID)}
+					}
+					return err
+				}
+
+				switch decision {
+				case "keep":
+					cli.Output("Kept %q access to %q for %q", item.Privilege, item.Resource, accessReviewItemSubject(*item))
+				case "revoke":
+					cli.Output("Revoked %q access to %q for %q", item.Privilege, item.Resource, accessReviewItemSubject(*item))
+				}
+			}
+
+			return nil
+		},
+	}
+
+	switch decision {
+	case "keep":
+		cmd.Short = "Keep grants under review"
+	case "revoke":
+		cmd.Short = "Revoke grants under review"
+		cmd.Long = "Revoke grants under review. The grants are removed immediately."
+	}
+
+	return cmd
+}
+
+type reviewsExportOptions struct {
+	Format string
+}
+
+func newReviewsExportCmd(cli *CLI) *cobra.Command {
+	var options reviewsExportOptions
+
+	cmd := &cobra.Command{
+		Use:   "export ID",
+		Short: "Export the results of an access review",
+		Long: `Export the decision for each grant of an access review.
+
+Grants which were revoked at the deadline have a decision of revoke, and no reviewer.`,
+		Example: `# Export the results of an access review to a file
+$ infra reviews export 4yJ3n3D8E2 > review.csv`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			items, err := listAccessReviewItems(client, args[0], "")
+			if err != nil {
+				return err
+			}
+
+			return printAccessReviewItems(cli, options.Format, items)
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Format, "format", "csv", "Output format [csv|json|yaml]")
+	return cmd
+}
+
+func listAccessReviewItems(client *api.Client, rawID string, decision string) ([]api.AccessReviewItem, error) {
+	id, err := parseAccessReviewID(rawID)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Debugf("call server: list access review items %s", id)
+	items, err := client.ListAccessReviewItems(api.ListAccessReviewItemsRequest{ID: id, Decision: decision})
+	if err != nil {
+		switch api.ErrorStatusCode(err) {
+		case 403:
+			logging.Debugf("%s", err.Error())
+			return nil, Error{
+				Message: "Cannot show access review: missing privileges for ListAccessReviewItems",
+			}
+		case 404:
+			return nil, Error{Message: fmt.Sprintf("Access review %q not found", rawID)}
+		}
+		return nil, err
+	}
+
+	return items.Items, nil
+}
+
+func printAccessReviewItems(cli *CLI, format string, items []api.AccessReviewItem) error {
+	switch format {
+	case "json":
+		jsonOutput, err := json.Marshal(items)
+		if err != nil {
+			return err
+		}
+		cli.Output(string(jsonOutput))
+	case "yaml":
+		yamlOutput, err := yaml.Marshal(items)
+		if err != nil {
+			return err
+		}
+		cli.Output(string(yamlOutput))
+	case "csv":
+		w := csv.NewWriter(cli.Stdout)
+		_ = w.Write([]string{"item", "grant", "user", "group", "name", "privilege", "resource", "decision", "decided_by", "decided"})
+		for _, item := range items {
+			decided := ""
+			if !item.Decided.Time().IsZero() {
+				decided = item.Decided.String()
+			}
+
+			_ = w.Write([]string{
+				item.ID.String(),
+				item.Grant.String(),
+				optionalID(item.User),
+				optionalID(item.Group),
+				item.SubjectName,
+				item.Privilege,
+				item.Resource,
+				item.Decision,
+				optionalID(item.DecidedBy),
+				decided,
+			})
+		}
+		w.Flush()
+		return w.Error()
+	default:
+		return Error{Message: fmt.Sprintf("Unknown format %q", format)}
+	}
+
+	return nil
+}
+
+func parseAccessReviewID(rawID string) (uid.ID, error) {
+	id, err := uid.Parse([]byte(rawID))
+	if err != nil {
+		return 0, Error{Message: fmt.Sprintf("Invalid access review ID %q", rawID)}
+	}
+	return id, nil
+}
+
+func accessReviewItemSubject(item api.AccessReviewItem) string {
+	if item.SubjectName != "" {
+		return item.SubjectName
+	}
+	if item.Group != 0 {
+		return item.Group.String()
+	}
+	return item.User.String()
+}
+
+func optionalID(id uid.ID) string {
+	if id == 0 {
+		return ""
+	}
+	return id.String()
+}
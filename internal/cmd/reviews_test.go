@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestReviewsCreateCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	groupID, reviewerID := uid.ID(20), uid.ID(30)
+	requestCh := make(chan api.CreateAccessReviewRequest, 1)
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		switch {
+		case requestMatches(req, http.MethodGet, "/api/groups"):
+			writeResponse(t, resp, api.ListResponse[api.Group]{
+				Count: 1,
+				Items: []api.Group{{ID: groupID, Name: "developers"}},
+			})
+		case requestMatches(req, http.MethodGet, "/api/users"):
+			writeResponse(t, resp, api.ListResponse[api.User]{
+				Count: 1,
+				Items: []api.User{{ID: reviewerID, Name: "reviewer@example.com"}},
+			})
+		case requestMatches(req, http.MethodPost, "/api/access-reviews"):
+			var createReq api.CreateAccessReviewRequest
+			err := json.NewDecoder(req.Body).Decode(&createReq)
+			assert.Check(t, err)
+			requestCh <- createReq
+
+			resp.WriteHeader(http.StatusCreated)
+			writeResponse(t, resp, api.AccessReview{
+				ID:       uid.ID(1234),
+				Name:     createReq.Name,
+				Deadline: createReq.Deadline,
+				Status:   "open",
+			})
+		default:
+			resp.WriteHeader(http.StatusBadRequest)
+		}
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	err := writeConfig(&cfg)
+	assert.NilError(t, err)
+
+	t.Run("missing scope", func(t *testing.T) {
+		err := Run(context.Background(), "reviews", "create", "q4", "--deadline=2022-12-31")
+		assert.ErrorContains(t, err, "One of '--destination' or '--group' is required")
+	})
+
+	t.Run("invalid deadline", func(t *testing.T) {
+		err := Run(context.Background(), "reviews", "create", "q4", "--destination=production", "--deadline=soon")
+		assert.ErrorContains(t, err, `Invalid deadline "soon"`)
+	})
+
+	t.Run("group", func(t *testing.T) {
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "reviews", "create", "q4",
+			"--group=developers",
+			"--reviewer=reviewer@example.com",
+			"--deadline=2022-12-31",
+			"--auto-revoke")
+		assert.NilError(t, err)
+
+		expected := api.CreateAccessReviewRequest{
+			Name:       "q4",
+			Group:      groupID,
+			Reviewers:  []uid.ID{reviewerID},
+			Deadline:   api.Time(time.Date(2022, 12, 31, 23, 59, 59, 0, time.UTC)),
+			AutoRevoke: true,
+		}
+		assert.DeepEqual(t, <-requestCh, expected)
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), "Created access review "+uid.ID(1234).String()+", due Sat, 31 Dec 2022 23:59:59 UTC"))
+	})
+}
+
+func TestReviewsDecideCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	reviewID, itemID := uid.ID(1234), uid.ID(5678)
+	decisions := make(chan string, 2)
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		if !requestMatches(req, http.MethodPut, "/api/access-reviews/"+reviewID.String()+"/items/"+itemID.String()) {
+			resp.WriteHeader(http.StatusNotFound)
+			writeResponse(t, resp, api.Error{Code: http.StatusNotFound})
+			return
+		}
+
+		var decideReq api.DecideAccessReviewItemRequest
+		err := json.NewDecoder(req.Body).Decode(&decideReq)
+		assert.Check(t, err)
+		decisions <- decideReq.Decision
+
+		writeResponse(t, resp, api.AccessReviewItem{
+			ID:          itemID,
+			SubjectName: "member@example.com",
+			Privilege:   "view",
+			Resource:    "production",
+			Decision:    decideReq.Decision,
+		})
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	err := writeConfig(&cfg)
+	assert.NilError(t, err)
+
+	t.Run("keep", func(t *testing.T) {
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "reviews", "keep", reviewID.String(), itemID.String())
+		assert.NilError(t, err)
+		assert.Equal(t, <-decisions, "keep")
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), `Kept "view" access to "production" for "member@example.com"`))
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "reviews", "revoke", reviewID.String(), itemID.String())
+		assert.NilError(t, err)
+		assert.Equal(t, <-decisions, "revoke")
+		assert.Assert(t, is.Contains(bufs.Stdout.String(), `Revoked "view" access to "production" for "member@example.com"`))
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		err := Run(context.Background(), "reviews", "keep", reviewID.String(), uid.ID(99).String())
+		assert.ErrorContains(t, err, "not found")
+	})
+}
+
+func TestReviewsExportCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	reviewID := uid.ID(1234)
+	decided := time.Date(2022, 10, 1, 12, 0, 0, 0, time.UTC)
+	handler := func(resp http.ResponseWriter, req *http.Request) {
+		if !requestMatches(req, http.MethodGet, "/api/access-reviews/"+reviewID.String()+"/items") {
+			resp.WriteHeader(http.StatusForbidden)
+			writeResponse(t, resp, api.Error{Code: http.StatusForbidden})
+			return
+		}
+
+		writeResponse(t, resp, api.ListResponse[api.AccessReviewItem]{
+			Count: 2,
+			Items: []api.AccessReviewItem{
+				{
+					ID: 11, Grant: 21, User: 31, SubjectName: "member@example.com",
+					Privilege: "view", Resource: "production",
+					Decision: "keep", DecidedBy: 41, Decided: api.Time(decided),
+				},
+				{
+					ID: 12, Grant: 22, Group: 32, SubjectName: "developers",
+					Privilege: "admin", Resource: "production.default",
+					Decision: "revoke", Decided: api.Time(decided),
+				},
+			},
+		})
+	}
+
+	srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+	t.Cleanup(srv.Close)
+
+	cfg := newTestClientConfig(srv, api.User{})
+	err := writeConfig(&cfg)
+	assert.NilError(t, err)
+
+	t.Run("csv", func(t *testing.T) {
+		ctx, bufs := PatchCLI(context.Background())
+		err := Run(ctx, "reviews", "export", reviewID.String())
+		assert.NilError(t, err)
+
+		id := func(i int) string { return uid.ID(i).String() }
+		expected := "item,grant,user,group,name,privilege,resource,decision,decided_by,decided\n" +
+			id(11) + "," + id(21) + "," + id(31) + ",,member@example.com,view,production,keep," + id(41) + ",2022-10-01T12:00:00Z\n" +
+			id(12) + "," + id(22) + ",," + id(32) + ",developers,admin,production.default,revoke,,2022-10-01T12:00:00Z\n"
+		assert.Equal(t, bufs.Stdout.String(), expected)
+	})
+
+	t.Run("without privileges", func(t *testing.T) {
+		err := Run(context.Background(), "reviews", "export", uid.ID(99).String())
+		assert.ErrorContains(t, err, "missing privileges for ListAccessReviewItems")
+	})
+}
@@ -0,0 +1,267 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+)
+
+func newRolesCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "roles",
+		Short:   "Manage custom roles",
+		Aliases: []string{"role"},
+		Group:   "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newRolesListCmd(cli))
+	cmd.AddCommand(newRolesAddCmd(cli))
+	cmd.AddCommand(newRolesUpdateCmd(cli))
+	cmd.AddCommand(newRolesRemoveCmd(cli))
+
+	return cmd
+}
+
+func newRolesListCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List custom roles",
+		Args:    NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: list roles")
+			roles, err := client.ListRoles(api.ListRolesRequest{})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list roles: missing privileges for ListRoles",
+					}
+				}
+				return err
+			}
+
+			type row struct {
+				Name        string `header:"NAME"`
+				Permissions string `header:"PERMISSIONS"`
+				Description string `header:"DESCRIPTION"`
+			}
+
+			var rows []row
+			for _, role := range roles.Items {
+				rows = append(rows, row{
+					Name:        role.Name,
+					Permissions: strings.Join(role.Permissions, ", "),
+					Description: role.Description,
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No roles found")
+			}
+
+			return nil
+		},
+	}
+}
+
+type rolesCmdOptions struct {
+	Description string
+	Permissions []string
+}
+
+func newRolesAddCmd(cli *CLI) *cobra.Command {
+	var options rolesCmdOptions
+
+	cmd := &cobra.Command{
+		Use:   "add ROLE",
+		Short: "Create a custom role",
+		Long: `Create a custom role.
+
+A custom role bundles a set of permissions. Grant the role like any other role,
+using its name. When the role is granted on infra, the permissions are Infra API
+permissions or builtin roles. When the role is granted on a destination, the
+permissions are roles of the destination.
+
+Infra API permissions:
+  users:read, users:create, groups:read, groups:create,
+  grants:read, grants:create, grants:delete, destinations:delete,
+  audit-events:read, roles:read`,
+		Example: `# Create a role which can manage grants to destinations
+$ infra roles add grant-manager --permission grants:read --permission grants:create --permission grants:delete
+
+# Grant the role to a user
+$ infra grants add johndoe@example.com infra --role grant-manager
+
+# Create a role which bundles cluster roles of a destination
+$ infra roles add operator --permission view --permission edit`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.Permissions) == 0 {
+				return Error{Message: "A role requires at least one permission; specify one with '--permission'"}
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: create role %q", args[0])
+			_, err = client.CreateRole(&api.CreateRoleRequest{
+				Name:        args[0],
+				Description: options.Description,
+				Permissions: options.Permissions,
+			})
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot create role: missing privileges for CreateRole",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Added role %q", args[0])
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Description, "description", "", "Description of the role")
+	cmd.Flags().StringArrayVar(&options.Permissions, "permission", nil, "Permission to include in the role, may be repeated")
+	return cmd
+}
+
+func newRolesUpdateCmd(cli *CLI) *cobra.Command {
+	var options rolesCmdOptions
+
+	cmd := &cobra.Command{
+		Use:   "update ROLE",
+		Short: "Update the permissions of a custom role",
+		Long:  `Update the permissions of a custom role. The permissions replace the existing permissions of the role.`,
+		Example: `# Allow the grant-manager role to read users
+$ infra roles update grant-manager --permission grants:read --permission grants:create --permission grants:delete --permission users:read`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(options.Permissions) == 0 {
+				return Error{Message: "A role requires at least one permission; specify one with '--permission'"}
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			role, err := getRoleByName(client, args[0])
+			if err != nil {
+				return err
+			}
+
+			req := api.UpdateRoleRequest{
+				ID:          role.ID,
+				Description: role.Description,
+				Permissions: options.Permissions,
+			}
+			if cmd.Flags().Changed("description") {
+				req.Description = options.Description
+			}
+
+			logging.Debugf("call server: update role %s", role.ID)
+			if _, err := client.UpdateRole(req); err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot update role: missing privileges for UpdateRole",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Updated role %q", role.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.Description, "description", "", "Description of the role")
+	cmd.Flags().StringArrayVar(&options.Permissions, "permission", nil, "Permission to include in the role, may be repeated")
+	return cmd
+}
+
+func newRolesRemoveCmd(cli *CLI) *cobra.Command {
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:     "remove ROLE",
+		Aliases: []string{"rm"},
+		Short:   "Delete a custom role",
+		Long:    `Delete a custom role. All grants of the role are also removed.`,
+		Args:    ExactArgs(1),
+		Example: `# Delete a role
+$ infra roles remove grant-manager`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			role, err := getRoleByName(client, args[0])
+			if err != nil {
+				if force && api.ErrorStatusCode(err) != 403 {
+					return nil
+				}
+				return err
+			}
+
+			logging.Debugf("call server: delete role %s", role.ID)
+			if err := client.DeleteRole(role.ID); err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot remove role: missing privileges for DeleteRole",
+					}
+				}
+				return err
+			}
+
+			cli.Output("Removed role %q", role.Name)
+			return nil
+		},
+	}
+
+	cmd.Flags().BoolVar(&force, "force", false, "Exit successfully even if the role does not exist")
+	return cmd
+}
+
+func getRoleByName(client *api.Client, name string) (*api.Role, error) {
+	roles, err := client.ListRoles(api.ListRolesRequest{Name: name})
+	if err != nil {
+		if api.ErrorStatusCode(err) == 403 {
+			logging.Debugf("%s", err.Error())
+			return nil, Error{Message: "Cannot get role: missing privileges for ListRoles"}
+		}
+		return nil, err
+	}
+
+	if roles.Count == 0 {
+		return nil, Error{Message: fmt.Sprintf("Unknown role %q", name)}
+	}
+
+	return &roles.Items[0], nil
+}
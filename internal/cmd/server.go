@@ -85,6 +85,10 @@ func newServerCmd() *cobra.Command {
 	cmd.Flags().Duration("session-duration", 0, "Maximum session duration per user login")
 	cmd.Flags().Duration("session-extension-deadline", 0, "A user must interact with Infra at least once within this amount of time for their session to remain valid")
 	cmd.Flags().Bool("enable-signup", false, "Enable one-time admin signup")
+	cmd.Flags().Int("login-lockout-user-max-failures", 0, "Number of failed logins for a username before it is locked")
+	cmd.Flags().Int("login-lockout-ip-max-failures", 0, "Number of failed logins from a client IP before it is locked")
+	cmd.Flags().Duration("login-lockout-duration", 0, "How long logins are locked, which doubles with every further failure")
+	cmd.Flags().Duration("login-lockout-max-duration", 0, "The longest that logins are locked")
 
 	return cmd
 }
@@ -110,6 +114,21 @@ func defaultServerOptions(infraDir string) server.Options {
 		UI: server.UIOptions{
 			Enabled: true,
 		},
+
+		LoginLockout: server.LoginLockoutOptions{
+			UserMaxFailures: 5,
+			IPMaxFailures:   50,
+			Duration:        time.Minute,
+			MaxDuration:     time.Hour,
+		},
+
+		WorkloadIdentity: server.WorkloadIdentityOptions{
+			AccessKeyDuration: time.Hour,
+		},
+
+		OIDC: server.OIDCOptions{
+			TokenDuration: time.Hour,
+		},
 	}
 }
 
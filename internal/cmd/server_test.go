@@ -166,6 +166,27 @@ ui:
   enabled: false # default is true
   proxyURL: "1.2.3.4:5151"
 
+loginLockout:
+  userMaxFailures: 3
+  ipMaxFailures: 10
+  duration: 30s
+  maxDuration: 10m
+
+workloadIdentity:
+  accessKeyDuration: 30m
+  issuers:
+    - issuer: https://token.actions.githubusercontent.com
+      audience: https://infra.example.com
+      rules:
+        - claims:
+            repository: infrahq/*
+            ref: refs/heads/main
+          identity: deploy@example.com
+
+oidc:
+  issuer: https://infra.example.com
+  tokenDuration: 10m
+
 providers:
   - name: okta
     url: https://dev-okta.com/
@@ -220,6 +241,34 @@ users:
 						}),
 					},
 
+					LoginLockout: server.LoginLockoutOptions{
+						UserMaxFailures: 3,
+						IPMaxFailures:   10,
+						Duration:        30 * time.Second,
+						MaxDuration:     10 * time.Minute,
+					},
+
+					WorkloadIdentity: server.WorkloadIdentityOptions{
+						AccessKeyDuration: 30 * time.Minute,
+						Issuers: []server.WorkloadIssuerOptions{
+							{
+								Issuer:   "https://token.actions.githubusercontent.com",
+								Audience: "https://infra.example.com",
+								Rules: []server.WorkloadIdentityRuleOptions{
+									{
+										Claims:   map[string]string{"repository": "infrahq/*", "ref": "refs/heads/main"},
+										Identity: "deploy@example.com",
+									},
+								},
+							},
+						},
+					},
+
+					OIDC: server.OIDCOptions{
+						Issuer:        "https://infra.example.com",
+						TokenDuration: 10 * time.Minute,
+					},
+
 					TLS: server.TLSOptions{
 						CA:           "-----BEGIN CERTIFICATE-----\nnot a real ca certificate\n-----END CERTIFICATE-----\n",
 						CAPrivateKey: "file:ca.key",
@@ -290,6 +339,10 @@ users:
 					"--session-duration", "3m",
 					"--session-extension-deadline", "1m",
 					"--enable-signup=false",
+					"--login-lockout-user-max-failures", "3",
+					"--login-lockout-ip-max-failures", "0",
+					"--login-lockout-duration", "30s",
+					"--login-lockout-max-duration", "10m",
 				})
 			},
 			expected: func(t *testing.T) server.Options {
@@ -302,6 +355,12 @@ users:
 				expected.SessionDuration = 3 * time.Minute
 				expected.SessionExtensionDeadline = 1 * time.Minute
 				expected.EnableSignup = false
+				expected.LoginLockout = server.LoginLockoutOptions{
+					UserMaxFailures: 3,
+					IPMaxFailures:   0,
+					Duration:        30 * time.Second,
+					MaxDuration:     10 * time.Minute,
+				}
 				return expected
 			},
 		},
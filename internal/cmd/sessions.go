@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/uid"
+)
+
+func newSessionsCmd(cli *CLI) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "sessions",
+		Short:   "Manage login sessions",
+		Aliases: []string{"session"},
+		Group:   "Management commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+	}
+
+	cmd.AddCommand(newSessionsListCmd(cli))
+	cmd.AddCommand(newSessionsRevokeCmd(cli))
+
+	return cmd
+}
+
+// sessionsUserID returns the ID of the user named userName, or the ID of the
+// logged in user if userName is empty.
+func sessionsUserID(client *api.Client, userName string) (uid.ID, error) {
+	if userName != "" {
+		user, err := getUserByName(client, userName)
+		if err != nil {
+			return 0, err
+		}
+		return user.ID, nil
+	}
+
+	config, err := currentHostConfig()
+	if err != nil {
+		return 0, err
+	}
+
+	if config.UserID == 0 {
+		return 0, fmt.Errorf("no active user")
+	}
+
+	return config.UserID, nil
+}
+
+func newSessionsListCmd(cli *CLI) *cobra.Command {
+	var userName string
+
+	cmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "List login sessions",
+		Long:    "List the login sessions of a user, and where they were last used.",
+		Example: `# List your sessions
+$ infra sessions list
+
+# List the sessions of another user
+$ infra sessions list --user user@example.com`,
+		Args: NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			userID, err := sessionsUserID(client, userName)
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: list sessions for user %s", userID)
+			sessions, err := client.ListSessions(userID)
+			if err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot list sessions: missing privileges for ListSessions",
+					}
+				}
+				return err
+			}
+
+			type row struct {
+				ID          string `header:"ID"`
+				LoginMethod string `header:"LOGIN METHOD"`
+				ClientIP    string `header:"CLIENT IP"`
+				Client      string `header:"CLIENT"`
+				LastUsed    string `header:"LAST USED"`
+				Expires     string `header:"EXPIRES"`
+			}
+
+			var rows []row
+			for _, s := range sessions.Items {
+				id := s.ID.String()
+				if s.Current {
+					id += " (current)"
+				}
+
+				agent := s.UserAgent
+				if s.ClientVersion != "" {
+					agent = "infra " + s.ClientVersion
+				}
+
+				rows = append(rows, row{
+					ID:          id,
+					LoginMethod: s.LoginMethod,
+					ClientIP:    s.ClientIP,
+					Client:      agent,
+					LastUsed:    HumanTime(s.LastUsed.Time(), "never"),
+					Expires:     HumanTime(s.Expires.Time(), "never"),
+				})
+			}
+
+			if len(rows) > 0 {
+				printTable(rows, cli.Stdout)
+			} else {
+				cli.Output("No sessions found")
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&userName, "user", "", "The name of a user to list sessions for")
+	return cmd
+}
+
+type sessionsRevokeOptions struct {
+	UserName string
+	All      bool
+}
+
+func newSessionsRevokeCmd(cli *CLI) *cobra.Command {
+	var options sessionsRevokeOptions
+
+	cmd := &cobra.Command{
+		Use:   "revoke [SESSION]",
+		Short: "Revoke login sessions",
+		Long: `Revoke a login session, which logs out the device which uses it.
+Revoke all of the sessions of a user with --all.`,
+		Example: `# Revoke one of your sessions
+$ infra sessions revoke 4yJ3n3D8E2
+
+# Log out everywhere, by revoking all of your sessions
+$ infra sessions revoke --all
+
+# Revoke all the sessions of another user
+$ infra sessions revoke --all --user user@example.com`,
+		Args: MaxArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if options.All == (len(args) == 1) {
+				return Error{Message: "Specify either a session ID or --all"}
+			}
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			userID, err := sessionsUserID(client, options.UserName)
+			if err != nil {
+				return err
+			}
+
+			if options.All {
+				logging.Debugf("call server: delete sessions for user %s", userID)
+				if err := client.DeleteSessions(userID); err != nil {
+					if api.ErrorStatusCode(err) == 403 {
+						logging.Debugf("%s", err.Error())
+						return Error{
+							Message: "Cannot revoke sessions: missing privileges for DeleteSessions",
+						}
+					}
+					return err
+				}
+
+				cli.Output("Revoked all sessions")
+				return nil
+			}
+
+			sessionID, err := uid.Parse([]byte(args[0]))
+			if err != nil {
+				return Error{Message: fmt.Sprintf("Invalid session ID %q", args[0])}
+			}
+
+			logging.Debugf("call server: delete session %s for user %s", sessionID, userID)
+			if err := client.DeleteSession(userID, sessionID); err != nil {
+				switch api.ErrorStatusCode(err) {
+				case 403:
+					logging.Debugf("%s", err.Error())
+					return Error{
+						Message: "Cannot revoke session: missing privileges for DeleteSession",
+					}
+				case 404:
+					return Error{Message: fmt.Sprintf("No session with ID %q", args[0])}
+				}
+				return err
+			}
+
+			cli.Output("Revoked session %s", sessionID)
+			return nil
+		},
+	}
+
+	cmd.Flags().StringVar(&options.UserName, "user", "", "The name of a user to revoke sessions for")
+	cmd.Flags().BoolVar(&options.All, "all", false, "Revoke all sessions, which logs out everywhere")
+	return cmd
+}
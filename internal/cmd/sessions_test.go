@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	is "gotest.tools/v3/assert/cmp"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestSessionsCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home) // for windows
+
+	userID := uid.ID(12345678)
+	sessionsPath := "/api/users/" + userID.String() + "/sessions"
+
+	setup := func(t *testing.T) chan string {
+		deleted := make(chan string, 1)
+
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case requestMatches(req, http.MethodGet, sessionsPath):
+				resp.WriteHeader(http.StatusOK)
+				err := json.NewEncoder(resp).Encode(api.ListResponse[api.Session]{
+					Count: 2,
+					Items: []api.Session{
+						{
+							ID:            uid.ID(1001),
+							LoginMethod:   "password",
+							ClientIP:      "192.0.2.10",
+							ClientVersion: "0.16.0",
+							LastUsed:      api.Time(time.Now().Add(-time.Minute)),
+							Expires:       api.Time(time.Now().Add(time.Hour)),
+							Current:       true,
+						},
+						{
+							ID:          uid.ID(1002),
+							LoginMethod: "oidc",
+							ClientIP:    "198.51.100.7",
+							UserAgent:   "Mozilla/5.0",
+							LastUsed:    api.Time(time.Now().Add(-time.Hour)),
+							Expires:     api.Time(time.Now().Add(time.Hour)),
+						},
+					},
+				})
+				assert.Check(t, err)
+
+			case requestMatches(req, http.MethodDelete, sessionsPath),
+				requestMatches(req, http.MethodDelete, sessionsPath+"/"+uid.ID(1002).String()):
+				resp.WriteHeader(http.StatusNoContent)
+				deleted <- req.URL.Path
+
+			case req.Method == http.MethodDelete:
+				resp.WriteHeader(http.StatusNotFound)
+				err := json.NewEncoder(resp).Encode(api.Error{Code: http.StatusNotFound, Message: "not found"})
+				assert.Check(t, err)
+
+			default:
+				resp.WriteHeader(http.StatusBadRequest)
+			}
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{ID: userID})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+
+		return deleted
+	}
+
+	t.Run("list", func(t *testing.T) {
+		setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "sessions", "list")
+		assert.NilError(t, err)
+
+		out := bufs.Stdout.String()
+		assert.Check(t, is.Contains(out, uid.ID(1001).String()+" (current)"))
+		assert.Check(t, is.Contains(out, "infra 0.16.0"))
+		assert.Check(t, is.Contains(out, "192.0.2.10"))
+		assert.Check(t, is.Contains(out, "Mozilla/5.0"))
+		assert.Check(t, is.Contains(out, "oidc"))
+	})
+
+	t.Run("revoke one", func(t *testing.T) {
+		deleted := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "sessions", "revoke", uid.ID(1002).String())
+		assert.NilError(t, err)
+		assert.Equal(t, <-deleted, sessionsPath+"/"+uid.ID(1002).String())
+		assert.Equal(t, bufs.Stdout.String(), "Revoked session "+uid.ID(1002).String()+"\n")
+	})
+
+	t.Run("revoke all", func(t *testing.T) {
+		deleted := setup(t)
+		ctx, bufs := PatchCLI(context.Background())
+
+		err := Run(ctx, "sessions", "revoke", "--all")
+		assert.NilError(t, err)
+		assert.Equal(t, <-deleted, sessionsPath)
+		assert.Equal(t, bufs.Stdout.String(), "Revoked all sessions\n")
+	})
+
+	t.Run("revoke missing session", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "sessions", "revoke", uid.ID(1003).String())
+		assert.ErrorContains(t, err, "No session with ID")
+	})
+
+	t.Run("revoke needs a session or --all", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "sessions", "revoke")
+		assert.ErrorContains(t, err, "Specify either a session ID or --all")
+
+		err = Run(context.Background(), "sessions", "revoke", "--all", uid.ID(1002).String())
+		assert.ErrorContains(t, err, "Specify either a session ID or --all")
+	})
+}
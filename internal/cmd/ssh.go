@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/pki"
+)
+
+func newSSHCmd(_ *CLI) *cobra.Command {
+	var user string
+
+	cmd := &cobra.Command{
+		Use:   "ssh DESTINATION [-- SSH_ARGS]",
+		Short: "Connect to a host with SSH",
+		Long: `Connect to a host with SSH.
+
+A short-lived SSH certificate is issued for the connection. The certificate
+allows logging in as the users of the host that you have been granted.`,
+		Example: `
+# Connect to a host
+$ infra ssh web-01
+
+# Connect as the ubuntu user, and run a command
+$ infra ssh web-01 --user ubuntu -- uptime`,
+		Args:  MinArgs(1),
+		Group: "Core commands:",
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := rootPreRun(cmd.Flags()); err != nil {
+				return err
+			}
+			return mustBeLoggedIn()
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return sshConnect(args[0], user, args[1:])
+		},
+	}
+
+	cmd.Flags().StringVarP(&user, "user", "l", "", "User to log in as on the host")
+
+	return cmd
+}
+
+func sshConnect(name, user string, sshArgs []string) error {
+	client, err := defaultAPIClient()
+	if err != nil {
+		return err
+	}
+
+	logging.Debugf("call server: list destinations named %q", name)
+	destinations, err := client.ListDestinations(api.ListDestinationsRequest{Name: name, Kind: "ssh"})
+	if err != nil {
+		return err
+	}
+
+	if destinations.Count == 0 {
+		return Error{Message: fmt.Sprintf("Cannot connect to %s: no ssh destination with that name", name)}
+	}
+
+	destination := destinations.Items[0]
+
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	authorizedKey, err := pki.MarshalSSHPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	logging.Debugf("call server: create ssh certificate for destination %s", destination.ID)
+	cert, err := client.CreateSSHCertificate(&api.CreateSSHCertificateRequest{
+		ID:        destination.ID,
+		PublicKey: string(authorizedKey),
+	})
+	if err != nil {
+		if api.ErrorStatusCode(err) == 403 {
+			logging.Debugf("%s", err.Error())
+			return Error{
+				Message: fmt.Sprintf("Cannot connect to %s: missing grants for any user of the host", name),
+			}
+		}
+		return err
+	}
+
+	user, err = sshUser(user, cert.Principals)
+	if err != nil {
+		return Error{Message: fmt.Sprintf("Cannot connect to %s: %v", name, err)}
+	}
+
+	files, err := writeSSHFiles(destination, privateKey, cert)
+	if err != nil {
+		return err
+	}
+
+	host, port, err := net.SplitHostPort(destination.Connection.URL)
+	if err != nil {
+		return fmt.Errorf("invalid ssh address %q: %w", destination.Connection.URL, err)
+	}
+
+	args := []string{
+		"-i", files.key,
+		"-o", "CertificateFile=" + files.certificate,
+		"-o", "IdentitiesOnly=yes",
+		"-o", "UserKnownHostsFile=" + files.knownHosts,
+		"-p", port,
+		"-l", user,
+		host,
+	}
+
+	return execSSH(append(args, sshArgs...))
+}
+
+// sshUser returns the user to log in as. When no user is requested, the
+// certificate must only allow one user.
+func sshUser(user string, principals []string) (string, error) {
+	if user == "" {
+		if len(principals) == 1 {
+			return principals[0], nil
+		}
+		return "", fmt.Errorf("use --user to choose one of %s", strings.Join(principals, ", "))
+	}
+
+	for _, principal := range principals {
+		if principal == user {
+			return user, nil
+		}
+	}
+
+	return "", fmt.Errorf("missing grant for user %s, you can log in as %s", user, strings.Join(principals, ", "))
+}
+
+type sshFiles struct {
+	key         string
+	certificate string
+	knownHosts  string
+}
+
+// writeSSHFiles writes the private key, the certificate, and the host key of
+// the destination to files that ssh can read.
+func writeSSHFiles(destination api.Destination, privateKey ed25519.PrivateKey, cert *api.SSHCertificate) (*sshFiles, error) {
+	infraDir, err := infraHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	sshDir := filepath.Join(infraDir, "ssh")
+	if err := os.MkdirAll(sshDir, 0o700); err != nil {
+		return nil, err
+	}
+
+	files := &sshFiles{
+		key:         filepath.Join(sshDir, destination.Name),
+		certificate: filepath.Join(sshDir, destination.Name+"-cert.pub"),
+		knownHosts:  filepath.Join(sshDir, destination.Name+"-known_hosts"),
+	}
+
+	keyPEM, err := pki.MarshalSSHPrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(files.key, keyPEM, 0o600); err != nil {
+		return nil, err
+	}
+
+	if err := os.WriteFile(files.certificate, []byte(cert.Certificate), 0o600); err != nil {
+		return nil, err
+	}
+
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(destination.Connection.CA))
+	if err != nil {
+		return nil, fmt.Errorf("invalid host key for %s: %w", destination.Name, err)
+	}
+
+	knownHost := knownhosts.Line([]string{destination.Connection.URL}, hostKey) + "\n"
+	if err := os.WriteFile(files.knownHosts, []byte(knownHost), 0o600); err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// execSSH runs ssh with the arguments. It is a shim for testing.
+var execSSH = func(args []string) error {
+	cmd := exec.Command("ssh", args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/pki"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestSSHCmd(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+
+	hostPub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	hostKey, err := pki.MarshalSSHPublicKey(hostPub)
+	assert.NilError(t, err)
+
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+
+	destination := api.Destination{
+		ID:   uid.ID(123),
+		Name: "web-01",
+		Kind: "ssh",
+		Connection: api.DestinationConnection{
+			URL: "web-01.example.com:2222",
+			CA:  api.PEM(hostKey),
+		},
+	}
+
+	setup := func(t *testing.T, principals []string) {
+		handler := func(resp http.ResponseWriter, req *http.Request) {
+			switch {
+			case req.URL.Path == "/api/destinations" && req.Method == http.MethodGet:
+				assert.Equal(t, req.URL.Query().Get("kind"), "ssh")
+
+				var items []api.Destination
+				if req.URL.Query().Get("name") == destination.Name {
+					items = append(items, destination)
+				}
+
+				err := json.NewEncoder(resp).Encode(api.ListResponse[api.Destination]{Items: items, Count: len(items)})
+				assert.NilError(t, err)
+
+			case req.URL.Path == "/api/destinations/"+destination.ID.String()+"/ssh-certificates" && req.Method == http.MethodPost:
+				var createReq api.CreateSSHCertificateRequest
+				err := json.NewDecoder(req.Body).Decode(&createReq)
+				assert.NilError(t, err)
+
+				if len(principals) == 0 {
+					resp.WriteHeader(http.StatusForbidden)
+					_ = json.NewEncoder(resp).Encode(api.Error{Code: http.StatusForbidden, Message: "forbidden"})
+					return
+				}
+
+				publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(createReq.PublicKey))
+				assert.NilError(t, err)
+
+				cert, err := pki.SignSSHUserCertificate(caKey, publicKey, pki.SSHUserCertificateOptions{
+					KeyID:      "alice@example.com",
+					Principals: principals,
+					Lifetime:   time.Minute,
+				})
+				assert.NilError(t, err)
+
+				resp.WriteHeader(http.StatusCreated)
+				err = json.NewEncoder(resp).Encode(api.SSHCertificate{
+					Certificate: string(ssh.MarshalAuthorizedKey(cert)),
+					Principals:  principals,
+				})
+				assert.NilError(t, err)
+
+			default:
+				resp.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+
+		srv := httptest.NewTLSServer(http.HandlerFunc(handler))
+		t.Cleanup(srv.Close)
+
+		cfg := newTestClientConfig(srv, api.User{})
+		err := writeConfig(&cfg)
+		assert.NilError(t, err)
+	}
+
+	patchExecSSH := func(t *testing.T) *[]string {
+		var actual []string
+		orig := execSSH
+		execSSH = func(args []string) error {
+			actual = args
+			return nil
+		}
+		t.Cleanup(func() {
+			execSSH = orig
+		})
+		return &actual
+	}
+
+	t.Run("connect", func(t *testing.T) {
+		setup(t, []string{"ubuntu"})
+		args := patchExecSSH(t)
+
+		err := Run(context.Background(), "ssh", "web-01", "--", "uptime")
+		assert.NilError(t, err)
+
+		sshDir := filepath.Join(home, ".infra", "ssh")
+		expected := []string{
+			"-i", filepath.Join(sshDir, "web-01"),
+			"-o", "CertificateFile=" + filepath.Join(sshDir, "web-01-cert.pub"),
+			"-o", "IdentitiesOnly=yes",
+			"-o", "UserKnownHostsFile=" + filepath.Join(sshDir, "web-01-known_hosts"),
+			"-p", "2222",
+			"-l", "ubuntu",
+			"web-01.example.com",
+			"uptime",
+		}
+		assert.DeepEqual(t, *args, expected)
+
+		keyPEM, err := os.ReadFile(filepath.Join(sshDir, "web-01"))
+		assert.NilError(t, err)
+		signer, err := ssh.ParsePrivateKey(keyPEM)
+		assert.NilError(t, err)
+
+		certFile, err := os.ReadFile(filepath.Join(sshDir, "web-01-cert.pub"))
+		assert.NilError(t, err)
+		certKey, _, _, _, err := ssh.ParseAuthorizedKey(certFile)
+		assert.NilError(t, err)
+		cert, ok := certKey.(*ssh.Certificate)
+		assert.Assert(t, ok)
+		assert.DeepEqual(t, cert.Key.Marshal(), signer.PublicKey().Marshal())
+
+		knownHosts, err := os.ReadFile(filepath.Join(sshDir, "web-01-known_hosts"))
+		assert.NilError(t, err)
+		assert.Equal(t, string(knownHosts), "[web-01.example.com]:2222 "+string(hostKey)+"\n")
+	})
+
+	t.Run("user is required with more than one principal", func(t *testing.T) {
+		setup(t, []string{"root", "ubuntu"})
+		args := patchExecSSH(t)
+
+		err := Run(context.Background(), "ssh", "web-01")
+		assert.ErrorContains(t, err, "use --user to choose one of root, ubuntu")
+
+		err = Run(context.Background(), "ssh", "web-01", "--user", "root")
+		assert.NilError(t, err)
+		assert.Assert(t, len(*args) > 0)
+	})
+
+	t.Run("missing grants", func(t *testing.T) {
+		setup(t, nil)
+		patchExecSSH(t)
+
+		err := Run(context.Background(), "ssh", "web-01")
+		assert.ErrorContains(t, err, "Cannot connect to web-01: missing grants for any user of the host")
+	})
+
+	t.Run("unknown destination", func(t *testing.T) {
+		setup(t, []string{"ubuntu"})
+		patchExecSSH(t)
+
+		err := Run(context.Background(), "ssh", "db-01")
+		assert.ErrorContains(t, err, "Cannot connect to db-01: no ssh destination with that name")
+	})
+}
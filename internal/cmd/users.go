@@ -34,6 +34,7 @@ func newUsersCmd(cli *CLI) *cobra.Command {
 	cmd.AddCommand(newUsersEditCmd(cli))
 	cmd.AddCommand(newUsersListCmd(cli))
 	cmd.AddCommand(newUsersRemoveCmd(cli))
+	cmd.AddCommand(newUsersUnlockCmd(cli))
 
 	return cmd
 }
@@ -86,24 +87,37 @@ $ infra users add johndoe@example.com`,
 }
 
 func newUsersEditCmd(cli *CLI) *cobra.Command {
-	var editPassword bool
+	var editPassword, enableMFA, disableMFA bool
 
 	cmd := &cobra.Command{
 		Use:   "edit USER",
 		Short: "Update a user",
 		Example: `# Set a new password for a user
-$ infra users edit janedoe@example.com --password`,
+$ infra users edit janedoe@example.com --password
+
+# Enroll in multi-factor authentication with an authenticator app
+$ infra users edit janedoe@example.com --mfa
+
+# Disable multi-factor authentication for a user who lost their device
+$ infra users edit janedoe@example.com --disable-mfa`,
 		Args: ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if !editPassword {
+			switch {
+			case editPassword:
+				return updateUser(cli, args[0])
+			case enableMFA:
+				return enableUserMFA(cli, args[0])
+			case disableMFA:
+				return disableUserMFA(cli, args[0])
+			default:
 				return errors.New("Please specify a field to update. For options, run 'infra users edit --help'")
 			}
-
-			return updateUser(cli, args[0])
 		},
 	}
 
 	cmd.Flags().BoolVar(&editPassword, "password", false, "Set a new password")
+	cmd.Flags().BoolVar(&enableMFA, "mfa", false, "Enroll in multi-factor authentication")
+	cmd.Flags().BoolVar(&disableMFA, "disable-mfa", false, "Disable multi-factor authentication")
 
 	return cmd
 }
@@ -238,6 +252,41 @@ $ infra users remove janedoe@example.com`,
 	return cmd
 }
 
+func newUsersUnlockCmd(cli *CLI) *cobra.Command {
+	return &cobra.Command{
+		Use:   "unlock USER",
+		Short: "Allow a user who is locked out after failed logins to login again",
+		Example: `# Unlock a user
+$ infra users unlock janedoe@example.com`,
+		Args: ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			client, err := defaultAPIClient()
+			if err != nil {
+				return err
+			}
+
+			user, err := getUserByName(client, name)
+			if err != nil {
+				return err
+			}
+
+			logging.Debugf("call server: unlock user %s", user.ID)
+			if err := client.UnlockUser(user.ID); err != nil {
+				if api.ErrorStatusCode(err) == 403 {
+					logging.Debugf("%s", err.Error())
+					return Error{Message: fmt.Sprintf("Cannot unlock user %q: missing privileges", name)}
+				}
+				return err
+			}
+
+			cli.Output("Unlocked user %q", name)
+			return nil
+		},
+	}
+}
+
 // CreateUser creates an user within Infra
 func CreateUser(req *api.CreateUserRequest) (*api.CreateUserResponse, error) {
 	client, err := defaultAPIClient()
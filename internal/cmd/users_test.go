@@ -37,11 +37,13 @@ func TestUsersCmd(t *testing.T) {
 
 	providerIDs := []uid.ID{123}
 	providerIdx := 0
-	userIDs := []uid.ID{12, 23, 34, 45, 56}
+	userIDs := []uid.ID{12, 23, 34, 45, 56, 67}
 	userIdx := 0
+	var unlockedPaths []string
 
 	setup := func(t *testing.T) *[]models.Identity {
 		modifiedUsers := []models.Identity{}
+		unlockedPaths = nil
 
 		handler := func(resp http.ResponseWriter, req *http.Request) {
 			if strings.Contains(req.URL.Path, "/api/providers") {
@@ -60,6 +62,12 @@ func TestUsersCmd(t *testing.T) {
 				return
 			}
 
+			if strings.HasSuffix(req.URL.Path, "/lockout") && req.Method == http.MethodDelete {
+				unlockedPaths = append(unlockedPaths, req.URL.Path)
+				resp.WriteHeader(http.StatusNoContent)
+				return
+			}
+
 			if strings.Contains(req.URL.Path, "/api/users") {
 				switch req.Method {
 				case http.MethodPost:
@@ -217,4 +225,23 @@ func TestUsersCmd(t *testing.T) {
 
 		golden.Assert(t, bufs.Stdout.String(), t.Name())
 	})
+
+	t.Run("unlock user", func(t *testing.T) {
+		setup(t)
+		ctx := context.Background()
+		err := Run(ctx, "users", "add", "locked-user@example.com")
+		assert.NilError(t, err)
+
+		err = Run(ctx, "users", "unlock", "locked-user@example.com")
+		assert.NilError(t, err)
+		assert.Equal(t, len(unlockedPaths), 1)
+		assert.Assert(t, strings.HasPrefix(unlockedPaths[0], "/api/users/"))
+	})
+
+	t.Run("unlock unknown user", func(t *testing.T) {
+		setup(t)
+		err := Run(context.Background(), "users", "unlock", "unknown@example.com")
+		assert.ErrorContains(t, err, "unknown user")
+		assert.Equal(t, len(unlockedPaths), 0)
+	})
 }
@@ -49,6 +49,14 @@ func (j *authenticator) Authenticate(req *http.Request) (claims.Custom, error) {
 		return c, fmt.Errorf("no bearer token found")
 	}
 
+	return j.authenticateToken(raw)
+}
+
+// authenticateToken validates a JWT issued by the infra server for
+// destinations, and returns its claims.
+func (j *authenticator) authenticateToken(raw string) (claims.Custom, error) {
+	c := claims.Custom{}
+
 	tok, err := jwt.ParseSigned(raw)
 	if err != nil {
 		return c, fmt.Errorf("invalid JWT signature: %w", err)
@@ -67,7 +75,12 @@ func (j *authenticator) Authenticate(req *http.Request) (claims.Custom, error) {
 		return c, fmt.Errorf("invalid token claims: %w", err)
 	}
 
-	err = allClaims.Claims.Validate(jwt.Expected{Time: time.Now().UTC()})
+	// the server signs other tokens with the same key, such as the ID tokens
+	// of OIDC clients, which are not for destinations
+	err = allClaims.Claims.Validate(jwt.Expected{
+		Audience: jwt.Audience{claims.DestinationAudience},
+		Time:     time.Now().UTC(),
+	})
 	switch {
 	case errors.Is(err, jwt.ErrExpired):
 		return c, err
@@ -12,6 +12,7 @@ import (
 	"net/http"
 	"net/http/httputil"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -22,11 +23,13 @@ import (
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/claims"
 	"github.com/infrahq/infra/internal/cmd/types"
 	"github.com/infrahq/infra/internal/ginutil"
 	"github.com/infrahq/infra/internal/kubernetes"
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/repeat"
+	"github.com/infrahq/infra/internal/resource"
 	"github.com/infrahq/infra/metrics"
 )
 
@@ -35,6 +38,13 @@ type Options struct {
 	Name   string
 	CACert string
 	CAKey  string
+
+	// Kind is the kind of destination the connector brokers access to, one of
+	// kubernetes, ssh, postgres, or http.
+	Kind     string
+	SSH      SSHOptions
+	Postgres PostgresOptions
+	HTTP     HTTPOptions
 }
 
 type ServerOptions struct {
@@ -45,6 +55,15 @@ type ServerOptions struct {
 }
 
 func Run(ctx context.Context, options Options) error {
+	switch options.Kind {
+	case destinationKindSSH:
+		return runSSH(ctx, options)
+	case destinationKindPostgres:
+		return runPostgres(ctx, options)
+	case destinationKindHTTP:
+		return runHTTP(ctx, options)
+	}
+
 	k8s, err := kubernetes.NewKubernetes()
 	if err != nil {
 		return err
@@ -84,12 +103,6 @@ func Run(ctx context.Context, options Options) error {
 		return certCache.Certificate()
 	}
 
-	basicSecretStorage := map[string]secrets.SecretStorage{
-		"env":       secrets.NewEnvSecretProviderFromConfig(secrets.GenericConfig{}),
-		"file":      secrets.NewFileSecretProviderFromConfig(secrets.FileConfig{}),
-		"plaintext": secrets.NewPlainSecretProviderFromConfig(secrets.GenericConfig{}),
-	}
-
 	u, err := urlx.Parse(options.Server.URL)
 	if err != nil {
 		return fmt.Errorf("invalid server url: %w", err)
@@ -111,6 +124,7 @@ func Run(ctx context.Context, options Options) error {
 
 	destination := &api.Destination{
 		Name:     options.Name,
+		Kind:     destinationKindKubernetes,
 		UniqueID: chksm,
 	}
 
@@ -120,24 +134,11 @@ func Run(ctx context.Context, options Options) error {
 		return errors.New("unexpected type for http.DefaultTransport")
 	}
 
-	accessKey, err := secrets.GetSecret(options.Server.AccessKey, basicSecretStorage)
+	client, err := newAPIClient(u.String(), options.Server, chksm)
 	if err != nil {
 		return err
 	}
 
-	client := &api.Client{
-		Name:      "connector",
-		Version:   internal.Version,
-		URL:       u.String(),
-		AccessKey: accessKey,
-		HTTP: http.Client{
-			Transport: httpTransportFromOptions(options.Server),
-		},
-		Headers: http.Header{
-			"Infra-Destination": {chksm},
-		},
-	}
-
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
@@ -206,6 +207,38 @@ func Run(ctx context.Context, options Options) error {
 	return tlsServer.ListenAndServeTLS("", "")
 }
 
+// basicSecretStorage returns the secret storage used to read secrets from the
+// connector options.
+func basicSecretStorage() map[string]secrets.SecretStorage {
+	return map[string]secrets.SecretStorage{
+		"env":       secrets.NewEnvSecretProviderFromConfig(secrets.GenericConfig{}),
+		"file":      secrets.NewFileSecretProviderFromConfig(secrets.FileConfig{}),
+		"plaintext": secrets.NewPlainSecretProviderFromConfig(secrets.GenericConfig{}),
+	}
+}
+
+// newAPIClient returns a client for the infra server. Requests from the client
+// identify the destination by its unique ID.
+func newAPIClient(serverURL string, opts ServerOptions, uniqueID string) (*api.Client, error) {
+	accessKey, err := secrets.GetSecret(opts.AccessKey, basicSecretStorage())
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Client{
+		Name:      "connector",
+		Version:   internal.Version,
+		URL:       serverURL,
+		AccessKey: accessKey,
+		HTTP: http.Client{
+			Transport: httpTransportFromOptions(opts),
+		},
+		Headers: http.Header{
+			"Infra-Destination": {uniqueID},
+		},
+	}, nil
+}
+
 func httpTransportFromOptions(opts ServerOptions) *http.Transport {
 	roots, err := x509.SystemCertPool()
 	if err != nil {
@@ -302,24 +335,19 @@ func syncWithServer(k8s *kubernetes.Kubernetes, client *api.Client, destination
 			}
 		}
 
-		grants, err := client.ListGrants(api.ListGrantsRequest{Resource: destination.Name})
+		grants, err := client.ListGrants(api.ListGrantsRequest{Destination: destination.Name})
 		if err != nil {
 			logging.Errorf("error listing grants: %v", err)
 			return
 		}
 
-		// TODO(https://github.com/infrahq/infra/issues/2422): support wildcard resource searches
-		for _, n := range namespaces {
-			g, err := client.ListGrants(api.ListGrantsRequest{Resource: fmt.Sprintf("%s.%s", destination.Name, n)})
-			if err != nil {
-				logging.Errorf("error listing grants: %v", err)
-				return
-			}
-
-			grants.Items = append(grants.Items, g.Items...)
+		roles, err := customRoles(client)
+		if err != nil {
+			logging.Errorf("error listing roles: %v", err)
+			return
 		}
 
-		err = updateRoles(client, k8s, grants.Items)
+		err = updateRoles(client, k8s, destination.Name, namespaces, roles, grants.Items)
 		if err != nil {
 			logging.Errorf("error updating grants: %v", err)
 			return
@@ -327,8 +355,13 @@ func syncWithServer(k8s *kubernetes.Kubernetes, client *api.Client, destination
 	}
 }
 
-// UpdateRoles converts infra grants to role-bindings in the current cluster
-func updateRoles(c *api.Client, k *kubernetes.Kubernetes, grants []api.Grant) error {
+// UpdateRoles converts infra grants to role-bindings in the current cluster. A
+// grant with a wildcard namespace creates a role-binding in each of the
+// namespaces that match it. A grant of a custom role creates a binding for each
+// of the privileges in the role. A grant with conditions is bound to a group for
+// the grant, which proxyMiddleware includes in requests that satisfy the
+// conditions.
+func updateRoles(c *api.Client, k *kubernetes.Kubernetes, destination string, namespaces []string, roles map[string][]string, grants []api.Grant) error {
 	logging.Debugf("syncing local grants from infra configuration")
 
 	crSubjects := make(map[string][]rbacv1.Subject)                           // cluster-role: subject
@@ -337,11 +370,15 @@ func updateRoles(c *api.Client, k *kubernetes.Kubernetes, grants []api.Grant) er
 	for _, g := range grants {
 		var name, kind string
 
-		if g.Privilege == "connect" {
+		privileges := grantPrivileges(g.Privilege, roles)
+		if len(privileges) == 0 {
 			continue
 		}
 
 		switch {
+		case g.Conditions != nil:
+			name = conditionalGrantGroup(g.ID.String())
+			kind = rbacv1.GroupKind
 		case g.Group != 0:
 			group, err := c.GetGroup(g.Group)
 			if err != nil {
@@ -366,21 +403,28 @@ func updateRoles(c *api.Client, k *kubernetes.Kubernetes, grants []api.Grant) er
 			Name:     name,
 		}
 
-		parts := strings.Split(g.Resource, ".")
+		parts := strings.Split(g.Resource, resource.Separator)
 
-		var crn kubernetes.ClusterRoleNamespace
+		if !resource.MatchSegment(parts[0], destination) {
+			logging.Debugf("grant resource %s does not match destination %s", g.Resource, destination)
+			continue
+		}
 
 		switch len(parts) {
 		// <cluster>
 		case 1:
-			crn.ClusterRole = g.Privilege
-			crSubjects[g.Privilege] = append(crSubjects[g.Privilege], subj)
+			for _, privilege := range privileges {
+				crSubjects[privilege] = append(crSubjects[privilege], subj)
+			}
 
 		// <cluster>.<namespace>
 		case 2:
-			crn.ClusterRole = g.Privilege
-			crn.Namespace = parts[1]
-			crnSubjects[crn] = append(crnSubjects[crn], subj)
+			for _, namespace := range grantNamespaces(parts[1], namespaces) {
+				for _, privilege := range privileges {
+					crn := kubernetes.ClusterRoleNamespace{ClusterRole: privilege, Namespace: namespace}
+					crnSubjects[crn] = append(crnSubjects[crn], subj)
+				}
+			}
 
 		default:
 			logging.Warnf("invalid grant resource: %s", g.Resource)
@@ -399,6 +443,162 @@ func updateRoles(c *api.Client, k *kubernetes.Kubernetes, grants []api.Grant) er
 	return nil
 }
 
+// customRoles returns the permissions of each custom role, by role name. A
+// server which does not support custom roles has none, so that grants are still
+// synced when the connector is newer than the server.
+func customRoles(client *api.Client) (map[string][]string, error) {
+	roles, err := client.ListRoles(api.ListRolesRequest{})
+	switch {
+	case api.ErrorStatusCode(err) == http.StatusNotFound:
+		return map[string][]string{}, nil
+	case err != nil:
+		return nil, err
+	}
+	return rolePermissions(roles.Items), nil
+}
+
+// rolePermissions returns the permissions of each custom role, by role name.
+func rolePermissions(roles []api.Role) map[string][]string {
+	permissions := make(map[string][]string, len(roles))
+	for _, role := range roles {
+		permissions[role.Name] = role.Permissions
+	}
+	return permissions
+}
+
+// grantPrivileges returns the cluster roles for the privilege of a grant. The
+// privilege is either a cluster role, or the name of a custom role which
+// includes the cluster roles. The connect privilege does not need a cluster role.
+func grantPrivileges(privilege string, roles map[string][]string) []string {
+	privileges := []string{privilege}
+	if permissions, ok := roles[privilege]; ok {
+		privileges = permissions
+	}
+
+	result := make([]string, 0, len(privileges))
+	for _, p := range privileges {
+		if p != "connect" {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+// subjectRoles are the roles granted to users and groups, by the name of the
+// user or group.
+type subjectRoles struct {
+	users  map[string][]string
+	groups map[string][]string
+}
+
+// roles returns the sorted roles granted to a user, or to any of their groups.
+func (g subjectRoles) roles(user string, groups []string) []string {
+	granted := make(map[string]bool)
+	for _, role := range g.users[user] {
+		granted[role] = true
+	}
+
+	for _, group := range groups {
+		for _, role := range g.groups[group] {
+			granted[role] = true
+		}
+	}
+
+	roles := make([]string, 0, len(granted))
+	for role := range granted {
+		roles = append(roles, role)
+	}
+
+	sort.Strings(roles)
+	return roles
+}
+
+// grantedRoles returns the roles granted to each user and group by grants on
+// the destination. A grant must be on the destination itself, not one of its
+// resources. The privileges function returns the roles for the privilege of a
+// grant, grants without roles are ignored. The roles of a grant with conditions
+// are granted to a group for the grant, see requestGroups.
+func grantedRoles(c *api.Client, destination string, grants []api.Grant, privileges func(privilege string) []string) (subjectRoles, error) {
+	result := subjectRoles{users: map[string][]string{}, groups: map[string][]string{}}
+
+	for _, g := range grants {
+		if strings.Contains(g.Resource, resource.Separator) || !resource.MatchSegment(g.Resource, destination) {
+			logging.Debugf("grant resource %s does not match destination %s", g.Resource, destination)
+			continue
+		}
+
+		roles := privileges(g.Privilege)
+		if len(roles) == 0 {
+			continue
+		}
+
+		switch {
+		case g.Conditions != nil:
+			group := conditionalGrantGroup(g.ID.String())
+			result.groups[group] = append(result.groups[group], roles...)
+		case g.Group != 0:
+			group, err := c.GetGroup(g.Group)
+			if err != nil {
+				return result, err
+			}
+
+			result.groups[group.Name] = append(result.groups[group.Name], roles...)
+		case g.User != 0:
+			user, err := c.GetUser(g.User)
+			if err != nil {
+				return result, err
+			}
+
+			result.users[user.Name] = append(result.users[user.Name], roles...)
+		}
+	}
+
+	return result, nil
+}
+
+// conditionalGrantGroup returns the name of the group that the roles of a grant
+// with conditions are granted to.
+func conditionalGrantGroup(grantID string) string {
+	return claims.GrantGroupPrefix + grantID
+}
+
+// requestGroups returns the groups of the user, and the groups of the grants
+// with conditions which a request from ip at time t satisfies. A group of the
+// user with the name of a grant group is ignored, so that it can not satisfy
+// the conditions of a grant.
+func requestGroups(claim claims.Custom, ip net.IP, t time.Time) []string {
+	groups := make([]string, 0, len(claim.Groups))
+	for _, g := range claim.Groups {
+		if !strings.HasPrefix(g, claims.GrantGroupPrefix) {
+			groups = append(groups, g)
+		}
+	}
+
+	for _, g := range claim.Grants {
+		if g.Conditions.Allows(ip, t) {
+			groups = append(groups, conditionalGrantGroup(g.ID))
+		}
+	}
+	return groups
+}
+
+// grantNamespaces returns the namespaces that a grant applies to. A namespace
+// without a wildcard is returned as is, even if it does not exist yet.
+func grantNamespaces(pattern string, namespaces []string) []string {
+	if !resource.HasWildcard(pattern) {
+		return []string{pattern}
+	}
+
+	var matches []string
+	for _, namespace := range namespaces {
+		if resource.MatchSegment(pattern, namespace) {
+			matches = append(matches, namespace)
+		}
+	}
+
+	return matches
+}
+
 // createOrUpdateDestination creates a destination in the infra server if it does not exist and updates it if it does
 func createOrUpdateDestination(client *api.Client, local *api.Destination) error {
 	if local.ID != 0 {
@@ -417,6 +617,7 @@ func createOrUpdateDestination(client *api.Client, local *api.Destination) error
 
 	request := &api.CreateDestinationRequest{
 		Name:       local.Name,
+		Kind:       local.Kind,
 		UniqueID:   local.UniqueID,
 		Version:    internal.FullVersion(),
 		Connection: local.Connection,
@@ -440,6 +641,7 @@ func updateDestination(client *api.Client, local *api.Destination) error {
 	request := api.UpdateDestinationRequest{
 		ID:         local.ID,
 		Name:       local.Name,
+		Kind:       local.Kind,
 		UniqueID:   local.UniqueID,
 		Version:    internal.FullVersion(),
 		Connection: local.Connection,
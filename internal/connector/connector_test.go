@@ -10,16 +10,21 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"gopkg.in/square/go-jose.v2"
 	"gopkg.in/square/go-jose.v2/jwt"
 	"gotest.tools/v3/assert"
 
+	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/claims"
 	"github.com/infrahq/infra/internal/server"
 )
@@ -103,6 +108,25 @@ func TestAuthenticator_Authenticate(t *testing.T) {
 			fakeClient:  fakeClient{key: *pub},
 			expectedErr: "no username in JWT claim",
 		},
+		{
+			name: "ID token of an OIDC client",
+			setup: func(t *testing.T, req *http.Request) {
+				// signed by the same key, with the name and groups of the user
+				j := generateJWTForAudience(t, priv, "client-id-of-a-wiki", "test@example.com", time.Now().Add(time.Hour))
+				req.Header.Set("Authorization", "Bearer "+j)
+			},
+			fakeClient:  fakeClient{key: *pub},
+			expectedErr: "invalid audience",
+		},
+		{
+			name: "no audience",
+			setup: func(t *testing.T, req *http.Request) {
+				j := generateJWTForAudience(t, priv, "", "test@example.com", time.Now().Add(time.Hour))
+				req.Header.Set("Authorization", "Bearer "+j)
+			},
+			fakeClient:  fakeClient{key: *pub},
+			expectedErr: "invalid audience",
+		},
 		{
 			name: "valid JWT",
 			setup: func(t *testing.T, req *http.Request) {
@@ -143,12 +167,18 @@ func generateJWK(t *testing.T) (pub *jose.JSONWebKey, priv *jose.JSONWebKey) {
 }
 
 func generateJWT(t *testing.T, priv *jose.JSONWebKey, email string, expiry time.Time) string {
+	t.Helper()
+	return generateJWTForAudience(t, priv, claims.DestinationAudience, email, expiry)
+}
+
+func generateJWTForAudience(t *testing.T, priv *jose.JSONWebKey, audience string, email string, expiry time.Time) string {
 	t.Helper()
 	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.EdDSA, Key: priv}, (&jose.SignerOptions{}).WithType("JWT"))
 	assert.NilError(t, err)
 
 	cl := jwt.Claims{
 		Issuer:   "InfraHQ",
+		Audience: jwt.Audience{audience},
 		Expiry:   jwt.NewNumericDate(expiry),
 		IssuedAt: jwt.NewNumericDate(time.Now()),
 	}
@@ -226,3 +256,147 @@ func TestCertCache_Certificate(t *testing.T) {
 		assert.Equal(t, parsedCert.DNSNames[0], "test-host")
 	})
 }
+
+func TestGrantNamespaces(t *testing.T) {
+	namespaces := []string{"default", "kube-system", "kube-public", "team-a"}
+
+	t.Run("exact namespace", func(t *testing.T) {
+		assert.DeepEqual(t, grantNamespaces("team-b", namespaces), []string{"team-b"})
+	})
+
+	t.Run("wildcard namespace", func(t *testing.T) {
+		assert.DeepEqual(t, grantNamespaces("kube-*", namespaces), []string{"kube-system", "kube-public"})
+	})
+
+	t.Run("all namespaces", func(t *testing.T) {
+		assert.DeepEqual(t, grantNamespaces("*", namespaces), namespaces)
+	})
+
+	t.Run("no match", func(t *testing.T) {
+		assert.Assert(t, len(grantNamespaces("prod-*", namespaces)) == 0)
+	})
+}
+
+func TestGrantPrivileges(t *testing.T) {
+	roles := map[string][]string{
+		"operator": {"view", "edit", "connect"},
+	}
+
+	assert.DeepEqual(t, grantPrivileges("view", roles), []string{"view"})
+	assert.DeepEqual(t, grantPrivileges("operator", roles), []string{"view", "edit"})
+	assert.Assert(t, len(grantPrivileges("connect", roles)) == 0)
+}
+
+func TestSubjectRoles_Roles(t *testing.T) {
+	grants := subjectRoles{
+		users:  map[string][]string{"alice@example.com": {"write", "read"}},
+		groups: map[string][]string{"analysts": {"read"}, "admins": {"admin"}},
+	}
+
+	assert.DeepEqual(t, grants.roles("alice@example.com", []string{"analysts"}), []string{"read", "write"})
+	assert.DeepEqual(t, grants.roles("bob@example.com", []string{"analysts", "admins"}), []string{"admin", "read"})
+	assert.DeepEqual(t, grants.roles("bob@example.com", nil), []string{})
+}
+
+func TestRequestGroups(t *testing.T) {
+	claim := claims.Custom{
+		Name:   "alice@example.com",
+		Groups: []string{"developers", claims.GrantGroupPrefix + "forged"},
+		Grants: []claims.ConditionalGrant{
+			{ID: "vpn", Conditions: claims.Conditions{CIDRs: []string{"10.0.0.0/8"}}},
+			{ID: "workday", Conditions: claims.Conditions{
+				Windows: []claims.TimeWindow{{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00", Timezone: "UTC"}},
+			}},
+		},
+	}
+
+	// Monday at noon
+	workday := time.Date(2022, 10, 3, 12, 0, 0, 0, time.UTC)
+	vpn := net.ParseIP("10.1.2.3")
+
+	assert.DeepEqual(t, requestGroups(claim, vpn, workday),
+		[]string{"developers", "infra:grant:vpn", "infra:grant:workday"})
+	assert.DeepEqual(t, requestGroups(claim, net.ParseIP("192.168.1.2"), workday),
+		[]string{"developers", "infra:grant:workday"})
+	assert.DeepEqual(t, requestGroups(claim, vpn, workday.Add(6*time.Hour)),
+		[]string{"developers", "infra:grant:vpn"})
+	assert.DeepEqual(t, requestGroups(claim, nil, workday.AddDate(0, 0, 5)),
+		[]string{"developers"})
+}
+
+func TestProxyMiddleware(t *testing.T) {
+	var upstreamReq *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamReq = r
+	}))
+	t.Cleanup(upstream.Close)
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	assert.NilError(t, err)
+
+	pub, priv := generateJWK(t)
+	authn := newAuthenticator("https://127.0.0.1:12345", Options{})
+	authn.client = fakeClient{key: *pub}
+
+	router := gin.New()
+	router.Use(proxyMiddleware(httputil.NewSingleHostReverseProxy(upstreamURL), authn, "the-bearer-token"))
+
+	// the reverse proxy requires a response writer with CloseNotify, so the
+	// router is served by a test server instead of a response recorder
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/pods", nil)
+	assert.NilError(t, err)
+	req.Header.Set("Authorization", "Bearer "+generateJWT(t, priv, "test@example.com", time.Now().Add(time.Hour)))
+	req.Header.Add("Impersonate-Group", "system:masters")
+	req.Header.Set("Impersonate-User", "admin")
+	req.Header.Set("Impersonate-Uid", "1234")
+	req.Header.Set("Impersonate-Extra-Scopes", "everything")
+
+	resp, err := http.DefaultClient.Do(req)
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	assert.Assert(t, upstreamReq != nil)
+	assert.Equal(t, upstreamReq.Header.Get("Authorization"), "Bearer the-bearer-token")
+	assert.DeepEqual(t, upstreamReq.Header.Values("Impersonate-User"), []string{"test@example.com"})
+	assert.DeepEqual(t, upstreamReq.Header.Values("Impersonate-Group"), []string{"developers"})
+	assert.DeepEqual(t, upstreamReq.Header.Values("Impersonate-Uid"), []string(nil))
+	assert.DeepEqual(t, upstreamReq.Header.Values("Impersonate-Extra-Scopes"), []string(nil))
+}
+
+func TestCustomRoles(t *testing.T) {
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, r.URL.Path, "/api/roles")
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			_, _ = w.Write([]byte(`{"items":[{"name":"deployer","permissions":["edit","view"]}],"count":1}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client := &api.Client{URL: srv.URL}
+
+	t.Run("roles", func(t *testing.T) {
+		status = http.StatusOK
+		roles, err := customRoles(client)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, roles, map[string][]string{"deployer": {"edit", "view"}})
+	})
+
+	t.Run("server without custom roles", func(t *testing.T) {
+		status = http.StatusNotFound
+		roles, err := customRoles(client)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, roles, map[string][]string{})
+	})
+
+	t.Run("error", func(t *testing.T) {
+		status = http.StatusInternalServerError
+		_, err := customRoles(client)
+		assert.ErrorContains(t, err, "500")
+	})
+}
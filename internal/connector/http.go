@@ -0,0 +1,412 @@
+package connector
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goware/urlx"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/ginutil"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/repeat"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	// sessionCookieName is the cookie which holds the token of a browser
+	// session with the connector.
+	sessionCookieName = "infra_session"
+	// loginCookieName is the cookie which holds the state of a login, until
+	// the infra server redirects back to the connector.
+	loginCookieName = "infra_login"
+	// callbackPath is where the infra server redirects to after a login.
+	callbackPath = "/.infra/callback"
+	// logoutPath ends the browser session with the connector.
+	logoutPath = "/.infra/logout"
+)
+
+// Headers set on requests to the upstream web application. The same headers
+// are removed from requests made by browsers.
+const (
+	headerUser   = "X-Infra-User"
+	headerGroups = "X-Infra-Groups"
+	headerRoles  = "X-Infra-Roles"
+)
+
+// HTTPOptions configure a connector which protects a web application. Users
+// log in to the connector with their browser, and requests are forwarded to
+// the application with the identity of the user in the X-Infra-User,
+// X-Infra-Groups, and X-Infra-Roles headers.
+type HTTPOptions struct {
+	// Upstream is the URL of the web application.
+	Upstream string
+	// Addr is the address that browsers use to reach the connector.
+	Addr string
+	// Roles are the roles of the web application which can be granted.
+	Roles []string
+	// TLSCertFile and TLSKeyFile are the certificate that browsers are shown.
+	// Defaults to a certificate signed by the CA of the connector.
+	TLSCertFile string
+	TLSKeyFile  string
+}
+
+// runHTTP registers a web application as an http destination, and proxies
+// requests from users who have a grant on the destination.
+func runHTTP(ctx context.Context, options Options) error {
+	if options.HTTP.Upstream == "" || options.HTTP.Addr == "" {
+		return errors.New("http-upstream and http-addr are required for an http destination")
+	}
+
+	upstream, err := urlx.Parse(options.HTTP.Upstream)
+	if err != nil {
+		return fmt.Errorf("invalid upstream url: %w", err)
+	}
+
+	host, _, err := net.SplitHostPort(withDefaultPort(options.HTTP.Addr))
+	if err != nil {
+		return fmt.Errorf("invalid http address %q: %w", options.HTTP.Addr, err)
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if options.HTTP.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(options.HTTP.TLSCertFile, options.HTTP.TLSKeyFile)
+		if err != nil {
+			return fmt.Errorf("load tls certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	} else {
+		caCertPEM, err := os.ReadFile(options.CACert)
+		if err != nil {
+			return err
+		}
+
+		caKeyPEM, err := os.ReadFile(options.CAKey)
+		if err != nil {
+			return err
+		}
+
+		certCache := NewCertCache(caCertPEM, caKeyPEM)
+		if _, err := certCache.AddHost(host); err != nil {
+			return fmt.Errorf("generate certificate: %w", err)
+		}
+
+		tlsConfig.GetCertificate = func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return certCache.Certificate()
+		}
+	}
+
+	if options.Name == "" {
+		// a dot separates the destination from its resources in a grant
+		options.Name = strings.SplitN(host, ".", 2)[0]
+	}
+
+	chksm := sha256.Sum256([]byte(options.HTTP.Addr))
+	uniqueID := hex.EncodeToString(chksm[:])
+
+	u, err := urlx.Parse(options.Server.URL)
+	if err != nil {
+		return fmt.Errorf("invalid server url: %w", err)
+	}
+
+	u.Scheme = "https"
+
+	client, err := newAPIClient(u.String(), options.Server, uniqueID)
+	if err != nil {
+		return err
+	}
+
+	roles := append([]string{}, options.HTTP.Roles...)
+	sort.Strings(roles)
+
+	h := &httpConnector{
+		serverURL: u.String(),
+		options:   options,
+		destination: &api.Destination{
+			Name:     options.Name,
+			Kind:     destinationKindHTTP,
+			UniqueID: uniqueID,
+			Roles:    roles,
+			Connection: api.DestinationConnection{
+				URL: options.HTTP.Addr,
+			},
+		},
+		authn: newAuthenticator(u.String(), options),
+		proxy: httputil.NewSingleHostReverseProxy(upstream),
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	repeat.Start(ctx, 5*time.Second, h.syncWithServer(client))
+
+	ginutil.SetMode()
+
+	tlsServer := &http.Server{
+		Addr:      ":443",
+		Handler:   h.routes(),
+		TLSConfig: tlsConfig,
+		ErrorLog:  log.New(logging.NewFilteredHTTPLogger(), "", 0),
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = tlsServer.Close()
+	}()
+
+	logging.Infof("starting infra connector (%s) - https:%s upstream:%s", internal.FullVersion(), tlsServer.Addr, upstream)
+
+	if err := tlsServer.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+type httpConnector struct {
+	serverURL   string
+	options     Options
+	destination *api.Destination
+	authn       *authenticator
+	proxy       *httputil.ReverseProxy
+
+	mu            sync.Mutex
+	destinationID uid.ID
+	grants        subjectRoles
+}
+
+func (h *httpConnector) routes() *gin.Engine {
+	router := gin.New()
+	router.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.GET(callbackPath, h.callback)
+	router.GET(logoutPath, h.logout)
+	router.NoRoute(h.proxyRequest)
+	return router
+}
+
+func (h *httpConnector) syncWithServer(client *api.Client) func(context.Context) {
+	return func(context.Context) {
+		if h.destination.ID == 0 {
+			if err := createOrUpdateDestination(client, h.destination); err != nil {
+				logging.Errorf("initializing destination: %v", err)
+				return
+			}
+		}
+
+		grants, err := client.ListGrants(api.ListGrantsRequest{Destination: h.destination.Name})
+		if err != nil {
+			logging.Errorf("error listing grants: %v", err)
+			return
+		}
+
+		roles, err := customRoles(client)
+		if err != nil {
+			logging.Errorf("error listing roles: %v", err)
+			return
+		}
+
+		granted, err := grantedRoles(client, h.destination.Name, grants.Items, webRoles(roles))
+		if err != nil {
+			logging.Errorf("error updating grants: %v", err)
+			return
+		}
+
+		h.mu.Lock()
+		h.destinationID = h.destination.ID
+		h.grants = granted
+		h.mu.Unlock()
+	}
+}
+
+// webRoles returns the roles of the web application for the privilege of a
+// grant. Unlike other destinations any privilege, including connect, allows
+// access to the application.
+func webRoles(roles map[string][]string) func(string) []string {
+	return func(privilege string) []string {
+		if permissions, ok := roles[privilege]; ok {
+			return permissions
+		}
+		return []string{privilege}
+	}
+}
+
+// proxyRequest forwards a request to the upstream web application, when the
+// user of the browser session has a grant on the destination. Browsers
+// without a session are sent to the infra server to log in.
+func (h *httpConnector) proxyRequest(c *gin.Context) {
+	// never trust identity headers from the browser
+	for _, header := range []string{headerUser, headerGroups, headerRoles} {
+		c.Request.Header.Del(header)
+	}
+
+	token, err := c.Cookie(sessionCookieName)
+	if err != nil {
+		h.login(c)
+		return
+	}
+
+	claim, err := h.authn.authenticateToken(token)
+	if err != nil {
+		logging.L.Info().Err(err).Msgf("failed to authenticate request")
+		h.login(c)
+		return
+	}
+
+	h.mu.Lock()
+	roles := h.grants.roles(claim.Name, requestGroups(claim, net.ParseIP(c.RemoteIP()), time.Now()))
+	h.mu.Unlock()
+
+	if len(roles) == 0 {
+		c.String(http.StatusForbidden, "%s has no grants for %s", claim.Name, h.destination.Name)
+		c.Abort()
+		return
+	}
+
+	removeCookie(c.Request, sessionCookieName)
+
+	c.Request.Header.Set(headerUser, claim.Name)
+	if len(claim.Groups) > 0 {
+		c.Request.Header.Set(headerGroups, strings.Join(claim.Groups, ","))
+	}
+
+	var appRoles []string
+	for _, role := range roles {
+		if role != "connect" {
+			appRoles = append(appRoles, role)
+		}
+	}
+	if len(appRoles) > 0 {
+		c.Request.Header.Set(headerRoles, strings.Join(appRoles, ","))
+	}
+
+	h.proxy.ServeHTTP(c.Writer, c.Request)
+}
+
+// login redirects the browser to the infra server to log in. Requests which
+// are not from a browser navigating to a page are rejected instead.
+func (h *httpConnector) login(c *gin.Context) {
+	h.mu.Lock()
+	destinationID := h.destinationID
+	h.mu.Unlock()
+
+	if c.Request.Method != http.MethodGet || destinationID == 0 {
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	state, err := generate.CryptoRandom(24, generate.CharsetAlphaNumeric)
+	if err != nil {
+		logging.Errorf("generate login state: %v", err)
+		c.AbortWithStatus(http.StatusInternalServerError)
+		return
+	}
+
+	loginState := url.Values{"state": {state}, "next": {c.Request.URL.RequestURI()}}
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginCookieName, loginState.Encode(), int((10 * time.Minute).Seconds()), "/", "", true, true)
+
+	query := url.Values{
+		"redirect_uri": {"https://" + h.options.HTTP.Addr + callbackPath},
+		"state":        {state},
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("%s/api/destinations/%s/authorize?%s", h.serverURL, destinationID, query.Encode()))
+	c.Abort()
+}
+
+// callback exchanges the login code from the infra server for a token, and
+// starts a browser session with the token.
+func (h *httpConnector) callback(c *gin.Context) {
+	cookie, err := c.Cookie(loginCookieName)
+	if err != nil {
+		c.String(http.StatusBadRequest, "login expired, try again")
+		return
+	}
+
+	loginState, err := url.ParseQuery(cookie)
+	if err != nil || loginState.Get("state") == "" || loginState.Get("state") != c.Query("state") {
+		c.String(http.StatusBadRequest, "invalid login state, try again")
+		return
+	}
+
+	client := &api.Client{
+		Name:      "connector",
+		Version:   internal.Version,
+		URL:       h.serverURL,
+		AccessKey: c.Query("code"),
+		HTTP: http.Client{
+			Transport: httpTransportFromOptions(h.options.Server),
+		},
+	}
+
+	token, err := client.CreateToken()
+	if err != nil {
+		logging.L.Info().Err(err).Msgf("failed to exchange login code")
+		c.String(http.StatusUnauthorized, "login failed, try again")
+		return
+	}
+
+	if _, err := h.authn.authenticateToken(token.Token); err != nil {
+		logging.L.Info().Err(err).Msgf("invalid token for login")
+		c.String(http.StatusUnauthorized, "login failed, try again")
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(loginCookieName, "", -1, "/", "", true, true)
+	c.SetCookie(sessionCookieName, token.Token, int(time.Until(token.Expires.Time()).Seconds()), "/", "", true, true)
+
+	c.Redirect(http.StatusFound, localPath(loginState.Get("next")))
+}
+
+// logout ends the browser session with the connector.
+func (h *httpConnector) logout(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(sessionCookieName, "", -1, "/", "", true, true)
+	c.String(http.StatusOK, "logged out of %s", h.destination.Name)
+}
+
+// localPath returns the path if it is on the connector, otherwise it returns
+// the root path.
+func localPath(path string) string {
+	if !strings.HasPrefix(path, "/") || strings.HasPrefix(path, "//") || strings.HasPrefix(path, "/\\") {
+		return "/"
+	}
+	return path
+}
+
+// removeCookie removes a cookie from a request, keeping any other cookies.
+func removeCookie(req *http.Request, name string) {
+	cookies := req.Cookies()
+	req.Header.Del("Cookie")
+	for _, cookie := range cookies {
+		if cookie.Name != name {
+			req.AddCookie(cookie)
+		}
+	}
+}
+
+func withDefaultPort(addr string) string {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return net.JoinHostPort(addr, "443")
+	}
+	return addr
+}
@@ -0,0 +1,178 @@
+package connector
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestHTTPConnector(t *testing.T) {
+	pub, priv := generateJWK(t)
+
+	var upstreamReq *http.Request
+	upstream := httptest.NewServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		upstreamReq = req
+		resp.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(upstream.Close)
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	assert.NilError(t, err)
+
+	token := generateJWT(t, priv, "test@example.com", time.Now().Add(time.Hour))
+
+	infra := httptest.NewTLSServer(http.HandlerFunc(func(resp http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/api/tokens" || req.Header.Get("Authorization") != "Bearer the-code" {
+			resp.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		resp.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(resp).Encode(api.CreateTokenResponse{Token: token, Expires: api.Time(time.Now().Add(time.Hour))})
+	}))
+	t.Cleanup(infra.Close)
+
+	options := Options{
+		Server: ServerOptions{SkipTLSVerify: true},
+		HTTP:   HTTPOptions{Addr: "grafana.example.com"},
+	}
+
+	authn := newAuthenticator(infra.URL, options)
+	authn.client = fakeClient{key: *pub}
+
+	h := &httpConnector{
+		serverURL:     infra.URL,
+		options:       options,
+		destination:   &api.Destination{Name: "grafana"},
+		authn:         authn,
+		proxy:         httputil.NewSingleHostReverseProxy(upstreamURL),
+		destinationID: uid.ID(123),
+	}
+
+	// the reverse proxy requires a response writer with CloseNotify, so the
+	// routes are served by a test server instead of a response recorder
+	srv := httptest.NewServer(h.routes())
+	t.Cleanup(srv.Close)
+
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	request := func(t *testing.T, method, target string, cookies ...*http.Cookie) *http.Response {
+		t.Helper()
+		upstreamReq = nil
+
+		req, err := http.NewRequest(method, srv.URL+target, nil)
+		assert.NilError(t, err)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+
+		resp, err := client.Do(req)
+		assert.NilError(t, err)
+		t.Cleanup(func() {
+			_ = resp.Body.Close()
+		})
+		return resp
+	}
+
+	session := &http.Cookie{Name: sessionCookieName, Value: token}
+
+	t.Run("redirect to login", func(t *testing.T) {
+		resp := request(t, http.MethodGet, "/dashboards?id=1")
+		assert.Equal(t, resp.StatusCode, http.StatusFound)
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, location.Path, "/api/destinations/"+uid.ID(123).String()+"/authorize")
+		assert.Equal(t, location.Query().Get("redirect_uri"), "https://grafana.example.com/.infra/callback")
+		assert.Assert(t, location.Query().Get("state") != "")
+		assert.Assert(t, upstreamReq == nil)
+
+		t.Run("callback", func(t *testing.T) {
+			var login *http.Cookie
+			for _, cookie := range resp.Cookies() {
+				if cookie.Name == loginCookieName {
+					login = cookie
+				}
+			}
+			assert.Assert(t, login != nil)
+
+			resp := request(t, http.MethodGet, callbackPath+"?code=the-code&state=wrong", login)
+			assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+
+			resp = request(t, http.MethodGet, callbackPath+"?code=the-code&state="+location.Query().Get("state"), login)
+			assert.Equal(t, resp.StatusCode, http.StatusFound)
+			assert.Equal(t, resp.Header.Get("Location"), "/dashboards?id=1")
+
+			var session *http.Cookie
+			for _, cookie := range resp.Cookies() {
+				if cookie.Name == sessionCookieName {
+					session = cookie
+				}
+			}
+			assert.Assert(t, session != nil)
+			assert.Equal(t, session.Value, token)
+			assert.Assert(t, session.HttpOnly && session.Secure)
+		})
+	})
+
+	t.Run("no grants", func(t *testing.T) {
+		resp := request(t, http.MethodGet, "/", session)
+		assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+		assert.Assert(t, upstreamReq == nil)
+	})
+
+	t.Run("proxy with identity headers", func(t *testing.T) {
+		h.grants = subjectRoles{
+			users:  map[string][]string{"test@example.com": {"connect"}},
+			groups: map[string][]string{"developers": {"Editor"}},
+		}
+
+		upstreamReq = nil
+		req, err := http.NewRequest(http.MethodGet, srv.URL+"/api/search", nil)
+		assert.NilError(t, err)
+		req.AddCookie(session)
+		req.AddCookie(&http.Cookie{Name: "grafana_session", Value: "abc"})
+		req.Header.Set(headerUser, "admin@example.com")
+
+		resp, err := client.Do(req)
+		assert.NilError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		assert.Assert(t, upstreamReq != nil)
+		assert.Equal(t, upstreamReq.URL.Path, "/api/search")
+		assert.Equal(t, upstreamReq.Header.Get(headerUser), "test@example.com")
+		assert.Equal(t, upstreamReq.Header.Get(headerGroups), "developers")
+		assert.Equal(t, upstreamReq.Header.Get(headerRoles), "Editor")
+
+		_, err = upstreamReq.Cookie(sessionCookieName)
+		assert.ErrorIs(t, err, http.ErrNoCookie)
+		_, err = upstreamReq.Cookie("grafana_session")
+		assert.NilError(t, err)
+	})
+
+	t.Run("not a browser navigation", func(t *testing.T) {
+		resp := request(t, http.MethodPost, "/api/dashboards")
+		assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+}
+
+func TestLocalPath(t *testing.T) {
+	assert.Equal(t, localPath("/dashboards?id=1"), "/dashboards?id=1")
+	assert.Equal(t, localPath("//evil.example.com"), "/")
+	assert.Equal(t, localPath("https://evil.example.com"), "/")
+	assert.Equal(t, localPath(""), "/")
+}
@@ -0,0 +1,488 @@
+package connector
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/goware/urlx"
+	"github.com/infrahq/secrets"
+	"github.com/jackc/pgx/v4"
+	_ "github.com/jackc/pgx/v4/stdlib" // registers the pgx driver for database/sql
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/ginutil"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/repeat"
+)
+
+// postgresCredentialsLifetime is how long the login role of a database
+// credential is valid. The role is dropped once it expires.
+const postgresCredentialsLifetime = time.Hour
+
+// loginRolePrefix is the prefix of the login roles created by the connector.
+// Roles with the prefix are dropped when they expire, or when the grants
+// they were created for are removed.
+const loginRolePrefix = "infra_"
+
+// loginRoleCharset are the characters of the random part of a login role name.
+// Unquoted identifiers are lower case, so the name can be used without quotes.
+const loginRoleCharset = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// PostgresOptions configure a connector which issues short-lived credentials
+// for a Postgres database.
+type PostgresOptions struct {
+	// ConnectionString is used by the connector to manage login roles. The
+	// database user must have the CREATEROLE attribute.
+	ConnectionString string
+	// Addr is the address that clients use to connect to the database.
+	Addr string
+	// Database is the name of the database that clients connect to.
+	Database string
+	// ConnectorAddr is the address that clients use to request credentials
+	// from the connector. Defaults to the hostname of the host, on port 443.
+	ConnectorAddr string
+}
+
+// runPostgres registers a database as a postgres destination. The roles of
+// the destination are the group roles of the database. When a user requests
+// credentials the connector creates a login role which is a member of the
+// group roles that the user has been granted.
+func runPostgres(ctx context.Context, options Options) error {
+	if options.Postgres.Addr == "" || options.Postgres.Database == "" {
+		return errors.New("postgres-addr and postgres-database are required for a postgres destination")
+	}
+
+	connectionString, err := secrets.GetSecret(options.Postgres.ConnectionString, basicSecretStorage())
+	if err != nil {
+		return err
+	}
+
+	db, err := sql.Open("pgx", connectionString)
+	if err != nil {
+		return fmt.Errorf("open database: %w", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return fmt.Errorf("connect to database: %w", err)
+	}
+
+	caCertPEM, err := os.ReadFile(options.CACert)
+	if err != nil {
+		return err
+	}
+
+	caKeyPEM, err := os.ReadFile(options.CAKey)
+	if err != nil {
+		return err
+	}
+
+	addr := options.Postgres.ConnectorAddr
+	if addr == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return fmt.Errorf("hostname: %w", err)
+		}
+		addr = net.JoinHostPort(hostname, "443")
+	}
+
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid connector address %q: %w", addr, err)
+	}
+
+	certCache := NewCertCache(caCertPEM, caKeyPEM)
+	if _, err := certCache.AddHost(host); err != nil {
+		return fmt.Errorf("generate certificate: %w", err)
+	}
+
+	if options.Name == "" {
+		options.Name = options.Postgres.Database
+	}
+
+	chksm := sha256.Sum256([]byte(options.Postgres.Addr + "/" + options.Postgres.Database))
+	uniqueID := hex.EncodeToString(chksm[:])
+
+	u, err := urlx.Parse(options.Server.URL)
+	if err != nil {
+		return fmt.Errorf("invalid server url: %w", err)
+	}
+
+	u.Scheme = "https"
+
+	client, err := newAPIClient(u.String(), options.Server, uniqueID)
+	if err != nil {
+		return err
+	}
+
+	p := &postgresConnector{
+		db:      db,
+		options: options.Postgres,
+		destination: &api.Destination{
+			Name:     options.Name,
+			Kind:     destinationKindPostgres,
+			UniqueID: uniqueID,
+			Connection: api.DestinationConnection{
+				URL: addr,
+				CA:  api.PEM(caCertPEM),
+			},
+		},
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	repeat.Start(ctx, 5*time.Second, p.syncWithServer(client))
+
+	ginutil.SetMode()
+	router := gin.New()
+	router.GET("/healthz", func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	router.POST("/postgres/credentials", p.createCredentials(newAuthenticator(u.String(), options)))
+
+	tlsServer := &http.Server{
+		Addr:    ":443",
+		Handler: router,
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+				return certCache.Certificate()
+			},
+		},
+		ErrorLog: log.New(logging.NewFilteredHTTPLogger(), "", 0),
+	}
+
+	go func() {
+		<-ctx.Done()
+		_ = tlsServer.Close()
+	}()
+
+	logging.Infof("starting infra connector (%s) - https:%s postgres:%s", internal.FullVersion(), tlsServer.Addr, options.Postgres.Addr)
+
+	if err := tlsServer.ListenAndServeTLS("", ""); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+type postgresConnector struct {
+	db          *sql.DB
+	options     PostgresOptions
+	destination *api.Destination
+
+	mu     sync.Mutex
+	grants subjectRoles
+}
+
+func (p *postgresConnector) syncWithServer(client *api.Client) func(context.Context) {
+	return func(ctx context.Context) {
+		groupRoles, err := listGroupRoles(ctx, p.db)
+		if err != nil {
+			logging.Errorf("could not get database roles: %v", err)
+			return
+		}
+
+		if p.destination.ID == 0 || !slicesEqual(p.destination.Roles, groupRoles) {
+			p.destination.Roles = groupRoles
+			if err := createOrUpdateDestination(client, p.destination); err != nil {
+				logging.Errorf("initializing destination: %v", err)
+				return
+			}
+		}
+
+		grants, err := client.ListGrants(api.ListGrantsRequest{Destination: p.destination.Name})
+		if err != nil {
+			logging.Errorf("error listing grants: %v", err)
+			return
+		}
+
+		roles, err := customRoles(client)
+		if err != nil {
+			logging.Errorf("error listing roles: %v", err)
+			return
+		}
+
+		granted, err := databaseGrants(client, p.destination.Name, groupRoles, roles, grants.Items)
+		if err != nil {
+			logging.Errorf("error updating grants: %v", err)
+			return
+		}
+
+		p.mu.Lock()
+		p.grants = granted
+		p.mu.Unlock()
+
+		if err := dropRevokedLoginRoles(ctx, p.db, granted, time.Now()); err != nil {
+			logging.Errorf("error dropping revoked login roles: %v", err)
+			return
+		}
+	}
+}
+
+// createCredentials creates a login role for the authenticated user, which is
+// a member of the database roles granted to the user or their groups.
+func (p *postgresConnector) createCredentials(authn *authenticator) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claim, err := authn.Authenticate(c.Request)
+		if err != nil {
+			logging.L.Info().Err(err).Msgf("failed to authenticate request")
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+
+		p.mu.Lock()
+		roles := p.grants.roles(claim.Name, requestGroups(claim, net.ParseIP(c.RemoteIP()), time.Now()))
+		p.mu.Unlock()
+
+		if len(roles) == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, api.Error{
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("%s has no grants for %s", claim.Name, p.destination.Name),
+			})
+			return
+		}
+
+		role := loginRole{
+			User:    claim.Name,
+			Groups:  claim.Groups,
+			Roles:   roles,
+			Expires: time.Now().Add(postgresCredentialsLifetime).Truncate(time.Second),
+		}
+
+		password, err := createLoginRole(c.Request.Context(), p.db, &role)
+		if err != nil {
+			logging.Errorf("create login role: %v", err)
+			c.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+
+		logging.Infof("created login role %s for %s with roles %s", role.Name, role.User, strings.Join(role.Roles, ", "))
+
+		host, port, err := net.SplitHostPort(p.options.Addr)
+		if err != nil {
+			host, port = p.options.Addr, "5432"
+		}
+
+		c.JSON(http.StatusCreated, api.DatabaseCredentials{
+			Username: role.Name,
+			Password: password,
+			Host:     host,
+			Port:     port,
+			Database: p.options.Database,
+			Roles:    role.Roles,
+			Expires:  api.Time(role.Expires),
+		})
+	}
+}
+
+// databaseGrants returns the database roles granted to each user and group.
+// The privilege of a grant is a group role of the database, or a custom role
+// which includes group roles. Privileges which are not group roles of the
+// database are ignored.
+func databaseGrants(c *api.Client, destination string, groupRoles []string, roles map[string][]string, grants []api.Grant) (subjectRoles, error) {
+	exists := make(map[string]bool, len(groupRoles))
+	for _, role := range groupRoles {
+		exists[role] = true
+	}
+
+	return grantedRoles(c, destination, grants, func(privilege string) []string {
+		var privileges []string
+		for _, p := range grantPrivileges(privilege, roles) {
+			if exists[p] {
+				privileges = append(privileges, p)
+			}
+		}
+		return privileges
+	})
+}
+
+// loginRole is a login role created by the connector. The user, groups, and
+// roles are stored as the comment of the role, so that the role can be dropped
+// when the grants are removed.
+type loginRole struct {
+	Name    string    `json:"-"`
+	User    string    `json:"user"`
+	Groups  []string  `json:"groups,omitempty"`
+	Roles   []string  `json:"roles"`
+	Expires time.Time `json:"-"`
+}
+
+// revoked returns true if the login role has expired, or if any of its roles
+// are no longer granted to the user or their groups.
+func (r loginRole) revoked(grants subjectRoles, now time.Time) bool {
+	if !now.Before(r.Expires) {
+		return true
+	}
+
+	granted := make(map[string]bool)
+	for _, role := range grants.roles(r.User, r.Groups) {
+		granted[role] = true
+	}
+
+	for _, role := range r.Roles {
+		if !granted[role] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// listGroupRoles returns the sorted names of the roles in the database which
+// can not log in. Roles reserved by Postgres are excluded.
+func listGroupRoles(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT rolname FROM pg_roles WHERE NOT rolcanlogin AND rolname NOT LIKE 'pg\_%' ORDER BY rolname`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []string
+	for rows.Next() {
+		var role string
+		if err := rows.Scan(&role); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// createLoginRole creates a login role which is a member of the roles, and
+// returns its password. The name of the role is set to a random name.
+func createLoginRole(ctx context.Context, db *sql.DB, role *loginRole) (string, error) {
+	name, err := generate.CryptoRandom(12, loginRoleCharset)
+	if err != nil {
+		return "", err
+	}
+
+	password, err := generate.CryptoRandom(32, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return "", err
+	}
+
+	role.Name = loginRolePrefix + name
+
+	comment, err := json.Marshal(role)
+	if err != nil {
+		return "", err
+	}
+
+	groupRoles := make([]string, 0, len(role.Roles))
+	for _, r := range role.Roles {
+		groupRoles = append(groupRoles, pgx.Identifier{r}.Sanitize())
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback() // nolint:errcheck // no-op after commit
+
+	// DDL statements do not accept parameters. The name and password are
+	// alphanumeric, and the roles are quoted identifiers.
+	stmt := fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD '%s' VALID UNTIL '%s' IN ROLE %s",
+		pgx.Identifier{role.Name}.Sanitize(), password, role.Expires.UTC().Format(time.RFC3339), strings.Join(groupRoles, ", "))
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return "", fmt.Errorf("create role: %w", err)
+	}
+
+	stmt = fmt.Sprintf("COMMENT ON ROLE %s IS %s", pgx.Identifier{role.Name}.Sanitize(), quoteLiteral(string(comment)))
+	if _, err := tx.ExecContext(ctx, stmt); err != nil {
+		return "", fmt.Errorf("comment on role: %w", err)
+	}
+
+	return password, tx.Commit()
+}
+
+// listLoginRoles returns the login roles created by the connector.
+func listLoginRoles(ctx context.Context, db *sql.DB) ([]loginRole, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT rolname, rolvaliduntil, COALESCE(shobj_description(oid, 'pg_authid'), '')
+		FROM pg_roles WHERE rolcanlogin AND rolname LIKE $1
+		ORDER BY rolname`, strings.ReplaceAll(loginRolePrefix, "_", `\_`)+"%")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []loginRole
+	for rows.Next() {
+		var (
+			name       string
+			validUntil sql.NullTime
+			comment    string
+		)
+
+		if err := rows.Scan(&name, &validUntil, &comment); err != nil {
+			return nil, err
+		}
+
+		var role loginRole
+		if err := json.Unmarshal([]byte(comment), &role); err != nil {
+			logging.Warnf("ignoring login role %s with an invalid comment: %v", name, err)
+			continue
+		}
+
+		role.Name = name
+		role.Expires = validUntil.Time
+		roles = append(roles, role)
+	}
+
+	return roles, rows.Err()
+}
+
+// dropRevokedLoginRoles drops the login roles which have expired, or which are
+// a member of a role that is no longer granted. Sessions of the login role are
+// terminated before the role is dropped.
+func dropRevokedLoginRoles(ctx context.Context, db *sql.DB, grants subjectRoles, now time.Time) error {
+	roles, err := listLoginRoles(ctx, db)
+	if err != nil {
+		return fmt.Errorf("list login roles: %w", err)
+	}
+
+	for _, role := range roles {
+		if !role.revoked(grants, now) {
+			continue
+		}
+
+		logging.Infof("dropping login role %s of %s", role.Name, role.User)
+
+		if _, err := db.ExecContext(ctx, `SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE usename = $1`, role.Name); err != nil {
+			return fmt.Errorf("terminate sessions of %s: %w", role.Name, err)
+		}
+
+		if _, err := db.ExecContext(ctx, "DROP OWNED BY "+pgx.Identifier{role.Name}.Sanitize()); err != nil {
+			return fmt.Errorf("drop objects owned by %s: %w", role.Name, err)
+		}
+
+		if _, err := db.ExecContext(ctx, "DROP ROLE "+pgx.Identifier{role.Name}.Sanitize()); err != nil {
+			return fmt.Errorf("drop role %s: %w", role.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// quoteLiteral quotes a string as a SQL string literal.
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,120 @@
+package connector
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestLoginRole_Revoked(t *testing.T) {
+	now := time.Now()
+	grants := subjectRoles{
+		users:  map[string][]string{"alice@example.com": {"read"}},
+		groups: map[string][]string{"analysts": {"write"}},
+	}
+
+	role := loginRole{User: "alice@example.com", Groups: []string{"analysts"}, Roles: []string{"read", "write"}, Expires: now.Add(time.Hour)}
+	assert.Assert(t, !role.revoked(grants, now))
+
+	// expired
+	assert.Assert(t, role.revoked(grants, now.Add(time.Hour)))
+
+	// the grant of the group was removed
+	delete(grants.groups, "analysts")
+	assert.Assert(t, role.revoked(grants, now))
+}
+
+func TestQuoteLiteral(t *testing.T) {
+	assert.Equal(t, quoteLiteral(`{"user":"o'brien@example.com"}`), `'{"user":"o''brien@example.com"}'`)
+}
+
+// Set POSTGRESQL_CONNECTION to a postgresql connection string to run tests
+// against a local postgresql database. The user must have the CREATEROLE
+// attribute.
+func TestLoginRoles(t *testing.T) {
+	pgConn, ok := os.LookupEnv("POSTGRESQL_CONNECTION")
+	if !ok {
+		t.Skip("set POSTGRESQL_CONNECTION to test login roles")
+	}
+
+	ctx := context.Background()
+
+	db, err := sql.Open("pgx", pgConn)
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_ = db.Close()
+	})
+
+	_, err = db.ExecContext(ctx, `CREATE ROLE connector_test_read`)
+	assert.NilError(t, err)
+	t.Cleanup(func() {
+		_, err := db.ExecContext(ctx, `DROP ROLE connector_test_read`)
+		assert.NilError(t, err)
+	})
+
+	groupRoles, err := listGroupRoles(ctx, db)
+	assert.NilError(t, err)
+	assert.Assert(t, contains(groupRoles, "connector_test_read"), groupRoles)
+
+	role := loginRole{
+		User:    "o'brien@example.com",
+		Roles:   []string{"connector_test_read"},
+		Expires: time.Now().Add(time.Hour).Truncate(time.Second),
+	}
+
+	password, err := createLoginRole(ctx, db, &role)
+	assert.NilError(t, err)
+	assert.Assert(t, password != "")
+
+	roles, err := listLoginRoles(ctx, db)
+	assert.NilError(t, err)
+
+	var created *loginRole
+	for i := range roles {
+		if roles[i].Name == role.Name {
+			created = &roles[i]
+		}
+	}
+	assert.Assert(t, created != nil, "login role %s not found", role.Name)
+	assert.Equal(t, created.User, role.User)
+	assert.DeepEqual(t, created.Roles, role.Roles)
+	assert.Assert(t, created.Expires.Equal(role.Expires))
+
+	grants := subjectRoles{users: map[string][]string{role.User: {"connector_test_read"}}}
+	err = dropRevokedLoginRoles(ctx, db, grants, time.Now())
+	assert.NilError(t, err)
+
+	roles, err = listLoginRoles(ctx, db)
+	assert.NilError(t, err)
+	assert.Assert(t, containsLoginRole(roles, role.Name))
+
+	// the grant was removed
+	err = dropRevokedLoginRoles(ctx, db, subjectRoles{}, time.Now())
+	assert.NilError(t, err)
+
+	roles, err = listLoginRoles(ctx, db)
+	assert.NilError(t, err)
+	assert.Assert(t, !containsLoginRole(roles, role.Name))
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
+
+func containsLoginRole(roles []loginRole, name string) bool {
+	for _, role := range roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
@@ -4,9 +4,12 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
+	"net"
 	"net/http"
 	"net/http/httputil"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 
@@ -27,8 +30,18 @@ func proxyMiddleware(
 			return
 		}
 
+		// the client must not choose who the request impersonates, so remove
+		// any impersonation headers it sent before adding our own
+		for key := range c.Request.Header {
+			if strings.HasPrefix(http.CanonicalHeaderKey(key), "Impersonate-") {
+				c.Request.Header.Del(key)
+			}
+		}
+
+		// the groups of grants with conditions are only included when the
+		// request satisfies the conditions
 		c.Request.Header.Set("Impersonate-User", claim.Name)
-		for _, g := range claim.Groups {
+		for _, g := range requestGroups(claim, net.ParseIP(c.RemoteIP()), time.Now()) {
 			c.Request.Header.Add("Impersonate-Group", g)
 		}
 
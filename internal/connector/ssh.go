@@ -0,0 +1,219 @@
+package connector
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goware/urlx"
+	"golang.org/x/crypto/ssh"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/repeat"
+)
+
+const (
+	destinationKindKubernetes = "kubernetes"
+	destinationKindSSH        = "ssh"
+	destinationKindPostgres   = "postgres"
+	destinationKindHTTP       = "http"
+)
+
+// SSHOptions configure a connector which brokers SSH access to the host it
+// runs on. The SSH server of the host must trust the certificate authority
+// written to TrustedUserCAKeysFile, using the TrustedUserCAKeys option in
+// sshd_config.
+type SSHOptions struct {
+	// Addr is the address that clients use to connect to the SSH server.
+	// Defaults to the hostname of the host, on port 22.
+	Addr string
+	// HostKeyFile is the public host key of the SSH server. Clients use it to
+	// verify the host.
+	HostKeyFile string
+	// TrustedUserCAKeysFile is where the public key of the certificate
+	// authority which signs SSH user certificates is written.
+	TrustedUserCAKeysFile string
+	// PasswdFile lists the users of the host.
+	PasswdFile string
+}
+
+// runSSH registers the host as an ssh destination. Users connect to the SSH
+// server of the host directly, with a certificate issued by the infra server.
+// The principals of the certificate are derived from the grants of the user on
+// the host, so the connector only has to keep the host registered, and keep
+// the certificate authority trusted by the SSH server.
+func runSSH(ctx context.Context, options Options) error {
+	hostKey, err := readHostKey(options.SSH.HostKeyFile)
+	if err != nil {
+		return err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("hostname: %w", err)
+	}
+
+	if options.Name == "" {
+		// a dot separates the destination from its resources in a grant
+		options.Name = strings.SplitN(hostname, ".", 2)[0]
+	}
+
+	addr := options.SSH.Addr
+	if addr == "" {
+		addr = net.JoinHostPort(hostname, "22")
+	}
+
+	chksm := sha256.Sum256(hostKey)
+	uniqueID := hex.EncodeToString(chksm[:])
+
+	u, err := urlx.Parse(options.Server.URL)
+	if err != nil {
+		return fmt.Errorf("invalid server url: %w", err)
+	}
+
+	u.Scheme = "https"
+
+	client, err := newAPIClient(u.String(), options.Server, uniqueID)
+	if err != nil {
+		return err
+	}
+
+	destination := &api.Destination{
+		Name:     options.Name,
+		Kind:     destinationKindSSH,
+		UniqueID: uniqueID,
+		Connection: api.DestinationConnection{
+			URL: addr,
+			CA:  api.PEM(hostKey),
+		},
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	repeat.Start(ctx, 5*time.Second, syncSSHWithServer(client, destination, options.SSH))
+
+	logging.Infof("starting infra connector (%s) - ssh:%s", internal.FullVersion(), addr)
+
+	<-ctx.Done()
+	return nil
+}
+
+func syncSSHWithServer(client *api.Client, destination *api.Destination, options SSHOptions) func(context.Context) {
+	return func(context.Context) {
+		users, err := loginUsers(options.PasswdFile)
+		if err != nil {
+			logging.Errorf("could not get users of the host: %v", err)
+			return
+		}
+
+		ca, err := client.GetSSHCertificateAuthority()
+		if err != nil {
+			logging.Errorf("error getting ssh certificate authority: %v", err)
+			return
+		}
+
+		if err := writeTrustedUserCAKeys(options.TrustedUserCAKeysFile, ca.PublicKey); err != nil {
+			logging.Errorf("error updating trusted user ca keys: %v", err)
+			return
+		}
+
+		if destination.ID != 0 && slicesEqual(destination.Roles, users) {
+			return
+		}
+
+		destination.Roles = users
+		if err := createOrUpdateDestination(client, destination); err != nil {
+			logging.Errorf("initializing destination: %v", err)
+			return
+		}
+	}
+}
+
+// readHostKey reads a public key in the authorized_keys format, and returns
+// it without the comment.
+func readHostKey(filename string) ([]byte, error) {
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("read host key: %w", err)
+	}
+
+	key, _, _, _, err := ssh.ParseAuthorizedKey(content)
+	if err != nil {
+		return nil, fmt.Errorf("parse host key %s: %w", filename, err)
+	}
+
+	return bytes.TrimSpace(ssh.MarshalAuthorizedKey(key)), nil
+}
+
+// writeTrustedUserCAKeys writes the public key of the certificate authority to
+// the file read by the SSH server, when it has changed.
+func writeTrustedUserCAKeys(filename string, publicKey string) error {
+	content := []byte(publicKey + "\n")
+
+	existing, err := os.ReadFile(filename)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	case bytes.Equal(existing, content):
+		return nil
+	}
+
+	logging.Infof("updating trusted user ca keys in %s", filename)
+
+	// nolint:gosec // the file is read by sshd, and only contains a public key
+	return os.WriteFile(filename, content, 0o644)
+}
+
+// loginUsers returns the sorted names of the users in a passwd file which are
+// able to log in. Users with a login shell of nologin or false are excluded.
+func loginUsers(filename string) ([]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var users []string
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// name:password:uid:gid:gecos:home:shell
+		fields := strings.Split(line, ":")
+		if len(fields) != 7 {
+			continue
+		}
+
+		shell := fields[6]
+		if strings.HasSuffix(shell, "/nologin") || strings.HasSuffix(shell, "/false") {
+			continue
+		}
+
+		users = append(users, fields[0])
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read %s: %w", filename, err)
+	}
+
+	sort.Strings(users)
+	return users, nil
+}
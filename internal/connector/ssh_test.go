@@ -0,0 +1,43 @@
+package connector
+
+import (
+	"os"
+	"testing"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/fs"
+)
+
+func TestLoginUsers(t *testing.T) {
+	passwd := `# local users
+root:x:0:0:root:/root:/bin/bash
+daemon:x:1:1:daemon:/usr/sbin:/usr/sbin/nologin
+sync:x:4:65534:sync:/bin:/bin/sync
+ubuntu:x:1000:1000:Ubuntu:/home/ubuntu:
+nobody:x:65534:65534:nobody:/nonexistent:/bin/false
+`
+	dir := fs.NewDir(t, t.Name(), fs.WithFile("passwd", passwd))
+
+	users, err := loginUsers(dir.Join("passwd"))
+	assert.NilError(t, err)
+	assert.DeepEqual(t, users, []string{"root", "sync", "ubuntu"})
+}
+
+func TestWriteTrustedUserCAKeys(t *testing.T) {
+	dir := fs.NewDir(t, t.Name())
+	filename := dir.Join("infra_user_ca.pub")
+
+	err := writeTrustedUserCAKeys(filename, "ssh-ed25519 AAAAfirst")
+	assert.NilError(t, err)
+
+	content, err := os.ReadFile(filename)
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "ssh-ed25519 AAAAfirst\n")
+
+	err = writeTrustedUserCAKeys(filename, "ssh-ed25519 AAAAsecond")
+	assert.NilError(t, err)
+
+	content, err = os.ReadFile(filename)
+	assert.NilError(t, err)
+	assert.Equal(t, string(content), "ssh-ed25519 AAAAsecond\n")
+}
@@ -15,4 +15,6 @@ var (
 	ErrNotFound       = fmt.Errorf("record not found")
 	ErrBadRequest     = fmt.Errorf("bad request")
 	ErrNotImplemented = fmt.Errorf("not implemented")
+	// ErrTooManyRequests means the client is being rate limited, for example after too many failed logins
+	ErrTooManyRequests = fmt.Errorf("too many requests")
 )
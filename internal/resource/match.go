@@ -0,0 +1,87 @@
+// Package resource implements matching of grant resources. A grant resource is
+// a dot separated name, like "cluster" or "cluster.namespace". Each segment of
+// a grant resource may contain the wildcard "*", which matches any sequence of
+// characters within that segment. A wildcard never matches a ".".
+//
+// The infra resource is reserved, and is only matched by a pattern which names
+// it exactly, so that a wildcard grant never gives access to Infra itself.
+package resource
+
+import "strings"
+
+// Wildcard is the character which matches any sequence of characters within a
+// segment of a resource.
+const Wildcard = "*"
+
+// Separator is the character which separates the segments of a resource.
+const Separator = "."
+
+// Infra is the reserved resource of the Infra API.
+const Infra = "infra"
+
+// HasWildcard returns true if pattern contains a wildcard.
+func HasWildcard(pattern string) bool {
+	return strings.Contains(pattern, Wildcard)
+}
+
+// Match returns true if the resource name is matched by pattern. The pattern and
+// the name must have the same number of segments, and each segment of name must
+// match the corresponding segment of pattern. Matching is case sensitive.
+func Match(pattern, name string) bool {
+	if IsInfra(name) {
+		return pattern == name
+	}
+
+	patternSegments := strings.Split(pattern, Separator)
+	nameSegments := strings.Split(name, Separator)
+
+	if len(patternSegments) != len(nameSegments) {
+		return false
+	}
+
+	for i := range patternSegments {
+		if !MatchSegment(patternSegments[i], nameSegments[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// IsInfra returns true if name is the infra resource, or one of its children.
+func IsInfra(name string) bool {
+	return name == Infra || strings.HasPrefix(name, Infra+Separator)
+}
+
+// MatchSegment returns true if a single segment of a resource name is matched
+// by a single segment of a pattern.
+func MatchSegment(pattern, segment string) bool {
+	if !HasWildcard(pattern) {
+		return pattern == segment
+	}
+
+	parts := strings.Split(pattern, Wildcard)
+
+	// the first part must be a prefix, and the last part must be a suffix
+	if !strings.HasPrefix(segment, parts[0]) {
+		return false
+	}
+	segment = segment[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	if len(segment) < len(last) || !strings.HasSuffix(segment, last) {
+		return false
+	}
+	segment = segment[:len(segment)-len(last)]
+
+	// the parts in between may match anywhere, in order
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(segment, part)
+		if i < 0 {
+			return false
+		}
+		segment = segment[i+len(part):]
+	}
+
+	return true
+}
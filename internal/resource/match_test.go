@@ -0,0 +1,50 @@
+package resource
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMatch(t *testing.T) {
+	type testCase struct {
+		pattern  string
+		name     string
+		expected bool
+	}
+
+	testCases := []testCase{
+		{pattern: "prod", name: "prod", expected: true},
+		{pattern: "prod", name: "prod-1", expected: false},
+		{pattern: "prod", name: "Prod", expected: false},
+		{pattern: "prod-*", name: "prod-1", expected: true},
+		{pattern: "prod-*", name: "prod-", expected: true},
+		{pattern: "prod-*", name: "staging-1", expected: false},
+		{pattern: "prod-*", name: "prod-1.default", expected: false},
+		{pattern: "*", name: "anything", expected: true},
+		{pattern: "*", name: "cluster.namespace", expected: false},
+		{pattern: "*-east-*", name: "prod-east-1", expected: true},
+		{pattern: "*-east-*", name: "prod-west-1", expected: false},
+		{pattern: "a*a", name: "a", expected: false},
+		{pattern: "a*a", name: "aa", expected: true},
+		{pattern: "a*b*c", name: "abc", expected: true},
+		{pattern: "a*b*c", name: "acb", expected: false},
+		{pattern: "*.kube-system", name: "prod.kube-system", expected: true},
+		{pattern: "*.kube-system", name: "prod.default", expected: false},
+		{pattern: "*.kube-system", name: "prod", expected: false},
+		{pattern: "cluster.*", name: "cluster.default", expected: true},
+		{pattern: "cluster.*", name: "cluster", expected: false},
+		{pattern: "cluster.*", name: "other.default", expected: false},
+		{pattern: "prod-*.*", name: "prod-1.default", expected: true},
+		{pattern: "infra", name: "infra", expected: true},
+		{pattern: "*", name: "infra", expected: false},
+		{pattern: "infr*", name: "infra", expected: false},
+		{pattern: "infra.*", name: "infra.groups", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.pattern+" "+tc.name, func(t *testing.T) {
+			assert.Equal(t, Match(tc.pattern, tc.name), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_AccessRequests(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	requester := &models.Identity{Name: "requester@example.com"}
+	approver := &models.Identity{Name: "approver@example.com"}
+	createIdentities(t, srv.db, requester, approver)
+
+	approvers := &models.Group{Name: "approvers"}
+	createGroups(t, srv.db, approvers)
+	err := data.AddUsersToGroup(srv.db, approvers.ID, []uid.ID{approver.ID})
+	assert.NilError(t, err)
+
+	err = data.CreateGrant(srv.db, &models.Grant{
+		Subject:   approvers.PolyID(),
+		Privilege: models.InfraApproverRole,
+		Resource:  access.ResourceInfraAPI,
+	})
+	assert.NilError(t, err)
+
+	keyFor := func(t *testing.T, identity *models.Identity) string {
+		t.Helper()
+		key, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+			IssuedFor:  identity.ID,
+			ProviderID: data.InfraProvider(srv.db).ID,
+			ExpiresAt:  time.Now().Add(10 * time.Minute),
+		})
+		assert.NilError(t, err)
+		return key
+	}
+	requesterKey := keyFor(t, requester)
+	approverKey := keyFor(t, approver)
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(method, path, jsonBody(t, body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+key)
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	createRequest := func(t *testing.T, resource string) api.AccessRequest {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/access-requests", requesterKey, api.CreateAccessRequestRequest{
+			Privilege: "admin",
+			Resource:  resource,
+			Reason:    "incident 1234",
+			Duration:  api.Duration(4 * time.Hour),
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var request api.AccessRequest
+		err := json.Unmarshal(resp.Body.Bytes(), &request)
+		assert.NilError(t, err)
+		return request
+	}
+
+	t.Run("missing required fields", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/access-requests", requesterKey, api.CreateAccessRequestRequest{})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		var respBody api.Error
+		err := json.Unmarshal(resp.Body.Bytes(), &respBody)
+		assert.NilError(t, err)
+
+		expected := []api.FieldError{
+			{FieldName: "duration", Errors: []string{"is required"}},
+			{FieldName: "privilege", Errors: []string{"is required"}},
+			{FieldName: "reason", Errors: []string{"is required"}},
+			{FieldName: "resource", Errors: []string{"is required"}},
+		}
+		assert.DeepEqual(t, respBody.FieldErrors, expected)
+	})
+
+	t.Run("approve", func(t *testing.T) {
+		request := createRequest(t, "production")
+		assert.Equal(t, request.Status, models.AccessRequestStatusPending)
+		assert.Equal(t, request.RequestedBy, requester.ID)
+
+		resp := call(t, http.MethodPost, "/api/access-requests/"+request.ID.String()+"/approve", requesterKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = call(t, http.MethodPost, "/api/access-requests/"+request.ID.String()+"/approve", approverKey, nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var approved api.AccessRequest
+		err := json.Unmarshal(resp.Body.Bytes(), &approved)
+		assert.NilError(t, err)
+		assert.Equal(t, approved.Status, models.AccessRequestStatusApproved)
+		assert.Equal(t, approved.DecidedBy, approver.ID)
+		assert.Equal(t, approved.RequestedByName, requester.Name)
+
+		grant, err := data.GetGrant(srv.db, data.ByID(approved.GrantID))
+		assert.NilError(t, err)
+		assert.Equal(t, grant.Subject, requester.PolyID())
+		assert.Equal(t, grant.Privilege, "admin")
+		assert.Equal(t, grant.Resource, "production")
+		assert.Equal(t, grant.CreatedBy, approver.ID)
+		assert.Assert(t, time.Until(grant.ExpiresAt) > 3*time.Hour+59*time.Minute)
+
+		resp = call(t, http.MethodPost, "/api/access-requests/"+request.ID.String()+"/deny", approverKey, nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("deny", func(t *testing.T) {
+		request := createRequest(t, "staging")
+
+		resp := call(t, http.MethodPost, "/api/access-requests/"+request.ID.String()+"/deny", approverKey, nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var denied api.AccessRequest
+		err := json.Unmarshal(resp.Body.Bytes(), &denied)
+		assert.NilError(t, err)
+		assert.Equal(t, denied.Status, models.AccessRequestStatusDenied)
+		assert.Equal(t, denied.GrantID, uid.ID(0))
+
+		grants, err := data.ListGrants(srv.db, &models.Pagination{}, data.BySubject(requester.PolyID()), data.ByResource("staging"))
+		assert.NilError(t, err)
+		assert.Equal(t, len(grants), 0)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/access-requests", requesterKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/access-requests?user="+requester.ID.String(), requesterKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var requests api.ListResponse[api.AccessRequest]
+		err := json.Unmarshal(resp.Body.Bytes(), &requests)
+		assert.NilError(t, err)
+		assert.Equal(t, requests.Count, 2)
+
+		resp = call(t, http.MethodGet, "/api/access-requests?status=denied", approverKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		err = json.Unmarshal(resp.Body.Bytes(), &requests)
+		assert.NilError(t, err)
+		assert.Equal(t, requests.Count, 1)
+		assert.Equal(t, requests.Items[0].Resource, "staging")
+	})
+
+	t.Run("approvers can not grant access to infra", func(t *testing.T) {
+		request := createRequest(t, access.ResourceInfraAPI)
+
+		resp := call(t, http.MethodPost, "/api/access-requests/"+request.ID.String()+"/approve", approverKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = call(t, http.MethodPost, "/api/access-requests/"+request.ID.String()+"/approve", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+}
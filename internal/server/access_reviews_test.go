@@ -0,0 +1,305 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_AccessReviews(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	reviewer := &models.Identity{Name: "reviewer@example.com"}
+	member := &models.Identity{Name: "member@example.com"}
+	other := &models.Identity{Name: "other@example.com"}
+	createIdentities(t, srv.db, reviewer, member, other)
+
+	developers := &models.Group{Name: "developers"}
+	createGroups(t, srv.db, developers)
+	err := data.AddUsersToGroup(srv.db, developers.ID, []uid.ID{member.ID})
+	assert.NilError(t, err)
+
+	createGrant := func(t *testing.T, subject uid.PolymorphicID, privilege, resource string) *models.Grant {
+		t.Helper()
+		grant := &models.Grant{Subject: subject, Privilege: privilege, Resource: resource}
+		assert.NilError(t, data.CreateGrant(srv.db, grant))
+		return grant
+	}
+
+	keyFor := func(t *testing.T, identity *models.Identity) string {
+		t.Helper()
+		key, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+			IssuedFor:  identity.ID,
+			ProviderID: data.InfraProvider(srv.db).ID,
+			ExpiresAt:  time.Now().Add(10 * time.Minute),
+		})
+		assert.NilError(t, err)
+		return key
+	}
+	reviewerKey := keyFor(t, reviewer)
+	otherKey := keyFor(t, other)
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(method, path, jsonBody(t, body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+key)
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	createReview := func(t *testing.T, req api.CreateAccessReviewRequest) api.AccessReview {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/access-reviews", adminAccessKey(srv), req)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var review api.AccessReview
+		err := json.Unmarshal(resp.Body.Bytes(), &review)
+		assert.NilError(t, err)
+		return review
+	}
+
+	listItems := func(t *testing.T, reviewID uid.ID, key string) []api.AccessReviewItem {
+		t.Helper()
+		resp := call(t, http.MethodGet, "/api/access-reviews/"+reviewID.String()+"/items", key, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var items api.ListResponse[api.AccessReviewItem]
+		err := json.Unmarshal(resp.Body.Bytes(), &items)
+		assert.NilError(t, err)
+		sort.Slice(items.Items, func(i, j int) bool {
+			return items.Items[i].SubjectName < items.Items[j].SubjectName
+		})
+		return items.Items
+	}
+
+	decide := func(t *testing.T, reviewID, itemID uid.ID, key, decision string) *httptest.ResponseRecorder {
+		t.Helper()
+		path := "/api/access-reviews/" + reviewID.String() + "/items/" + itemID.String()
+		return call(t, http.MethodPut, path, key, api.DecideAccessReviewItemRequest{Decision: decision})
+	}
+
+	memberView := createGrant(t, member.PolyID(), "view", "production")
+	groupAdmin := createGrant(t, developers.PolyID(), "admin", "production.kube-system")
+	reviewerView := createGrant(t, reviewer.PolyID(), "view", "production")
+	memberStaging := createGrant(t, member.PolyID(), "connect", "staging")
+	createGrant(t, other.PolyID(), "connect", "staging")
+
+	deadline := api.Time(time.Now().Add(24 * time.Hour).Truncate(time.Second))
+
+	t.Run("missing required fields", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/access-reviews", adminAccessKey(srv), api.CreateAccessReviewRequest{})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		var respBody api.Error
+		err := json.Unmarshal(resp.Body.Bytes(), &respBody)
+		assert.NilError(t, err)
+
+		expected := []api.FieldError{
+			{Errors: []string{"one of (destination, group) is required"}},
+			{FieldName: "deadline", Errors: []string{"is required"}},
+			{FieldName: "name", Errors: []string{"is required"}},
+		}
+		assert.DeepEqual(t, respBody.FieldErrors, expected)
+	})
+
+	t.Run("deadline in the past", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/access-reviews", adminAccessKey(srv), api.CreateAccessReviewRequest{
+			Name:        "late",
+			Destination: "production",
+			Deadline:    api.Time(time.Now().Add(-time.Hour)),
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("only admins create", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/access-reviews", reviewerKey, api.CreateAccessReviewRequest{
+			Name:        "production",
+			Destination: "production",
+			Deadline:    deadline,
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("destination", func(t *testing.T) {
+		review := createReview(t, api.CreateAccessReviewRequest{
+			Name:        "production",
+			Destination: "production",
+			Reviewers:   []uid.ID{reviewer.ID},
+			Deadline:    deadline,
+		})
+		assert.Equal(t, review.Status, models.AccessReviewStatusOpen)
+		assert.DeepEqual(t, review.Reviewers, []uid.ID{reviewer.ID})
+		assert.Assert(t, review.Deadline.Equal(deadline))
+
+		items := listItems(t, review.ID, reviewerKey)
+		assert.Equal(t, len(items), 3)
+		assert.Equal(t, items[0].SubjectName, developers.Name)
+		assert.Equal(t, items[0].Group, developers.ID)
+		assert.Equal(t, items[0].Grant, groupAdmin.ID)
+		assert.Equal(t, items[1].SubjectName, member.Name)
+		assert.Equal(t, items[1].User, member.ID)
+		assert.Equal(t, items[1].Grant, memberView.ID)
+		assert.Equal(t, items[2].SubjectName, reviewer.Name)
+		assert.Equal(t, items[2].Grant, reviewerView.ID)
+		for _, item := range items {
+			assert.Equal(t, item.Decision, models.AccessReviewDecisionPending)
+		}
+
+		// other users can not see the review
+		resp := call(t, http.MethodGet, "/api/access-reviews/"+review.ID.String(), otherKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+		resp = decide(t, review.ID, items[1].ID, otherKey, "keep")
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = decide(t, review.ID, items[1].ID, reviewerKey, "keep")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var kept api.AccessReviewItem
+		err := json.Unmarshal(resp.Body.Bytes(), &kept)
+		assert.NilError(t, err)
+		assert.Equal(t, kept.Decision, models.AccessReviewDecisionKeep)
+		assert.Equal(t, kept.DecidedBy, reviewer.ID)
+
+		_, err = data.GetGrant(srv.db, data.ByID(memberView.ID))
+		assert.NilError(t, err)
+
+		resp = decide(t, review.ID, items[0].ID, reviewerKey, "revoke")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		_, err = data.GetGrant(srv.db, data.ByID(groupAdmin.ID))
+		assert.ErrorContains(t, err, "record not found")
+
+		t.Run("revoked grants can not be kept", func(t *testing.T) {
+			resp := decide(t, review.ID, items[0].ID, reviewerKey, "keep")
+			assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		})
+
+		t.Run("reviewers can not review their own grants", func(t *testing.T) {
+			resp := decide(t, review.ID, items[2].ID, reviewerKey, "keep")
+			assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+			resp = decide(t, review.ID, items[2].ID, adminAccessKey(srv), "keep")
+			assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		})
+
+		t.Run("invalid decision", func(t *testing.T) {
+			resp := decide(t, review.ID, items[1].ID, reviewerKey, "pending")
+			assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		})
+
+		t.Run("item of another review", func(t *testing.T) {
+			resp := decide(t, review.ID, review.ID, reviewerKey, "keep")
+			assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+		})
+	})
+
+	t.Run("group", func(t *testing.T) {
+		review := createReview(t, api.CreateAccessReviewRequest{
+			Name:     "developers",
+			Group:    developers.ID,
+			Deadline: deadline,
+		})
+		assert.Equal(t, review.Group, developers.ID)
+
+		items := listItems(t, review.ID, adminAccessKey(srv))
+		var grantIDs []uid.ID
+		for _, item := range items {
+			grantIDs = append(grantIDs, item.Grant)
+		}
+		// the grant to the group was revoked by the destination review
+		assert.DeepEqual(t, grantIDs, []uid.ID{memberView.ID, memberStaging.ID})
+
+		resp := call(t, http.MethodGet, "/api/access-reviews/"+review.ID.String()+"/items", reviewerKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("list", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/access-reviews", reviewerKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var reviews api.ListResponse[api.AccessReview]
+		err := json.Unmarshal(resp.Body.Bytes(), &reviews)
+		assert.NilError(t, err)
+		assert.Equal(t, reviews.Count, 1)
+		assert.Equal(t, reviews.Items[0].Name, "production")
+
+		resp = call(t, http.MethodGet, "/api/access-reviews", otherKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		err = json.Unmarshal(resp.Body.Bytes(), &reviews)
+		assert.NilError(t, err)
+		assert.Equal(t, reviews.Count, 0)
+
+		resp = call(t, http.MethodGet, "/api/access-reviews?status=open", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		err = json.Unmarshal(resp.Body.Bytes(), &reviews)
+		assert.NilError(t, err)
+		assert.Equal(t, reviews.Count, 2)
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		otherStaging := createGrant(t, other.PolyID(), "view", "staging.default")
+		review := createReview(t, api.CreateAccessReviewRequest{
+			Name:        "staging",
+			Destination: "staging",
+			Deadline:    deadline,
+			AutoRevoke:  true,
+		})
+
+		items := listItems(t, review.ID, adminAccessKey(srv))
+		assert.Equal(t, len(items), 3)
+		assert.Equal(t, items[0].SubjectName, member.Name)
+		resp := decide(t, review.ID, items[0].ID, adminAccessKey(srv), "keep")
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		// not yet at the deadline
+		assert.NilError(t, data.CloseExpiredAccessReviews(srv.db))
+		resp = call(t, http.MethodGet, "/api/access-reviews/"+review.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		var updated api.AccessReview
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &updated))
+		assert.Equal(t, updated.Status, models.AccessReviewStatusOpen)
+
+		past := time.Now().Add(-time.Minute).UTC()
+		err := srv.db.Model(&models.AccessReview{}).Where("id = ?", review.ID).Update("deadline", past).Error
+		assert.NilError(t, err)
+
+		assert.NilError(t, data.CloseExpiredAccessReviews(srv.db))
+
+		resp = call(t, http.MethodGet, "/api/access-reviews/"+review.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &updated))
+		assert.Equal(t, updated.Status, models.AccessReviewStatusClosed)
+		assert.Assert(t, !time.Time(updated.Closed).IsZero())
+
+		items = listItems(t, review.ID, adminAccessKey(srv))
+		assert.Equal(t, items[0].Decision, models.AccessReviewDecisionKeep)
+		for _, item := range items[1:] {
+			assert.Equal(t, item.Decision, models.AccessReviewDecisionRevoke)
+			assert.Equal(t, item.DecidedBy, uid.ID(0))
+		}
+
+		_, err = data.GetGrant(srv.db, data.ByID(memberStaging.ID))
+		assert.NilError(t, err)
+		_, err = data.GetGrant(srv.db, data.ByID(otherStaging.ID))
+		assert.ErrorContains(t, err, "record not found")
+
+		resp = decide(t, review.ID, items[0].ID, adminAccessKey(srv), "revoke")
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+}
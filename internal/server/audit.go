@@ -0,0 +1,262 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+type snapshotFunc func(db *gorm.DB, id uid.ID) (any, error)
+
+// auditSnapshots maps a resource type, the first path segment after /api/, to
+// a function that loads the API representation of that resource. The API
+// representation is used so that secrets are never written to the audit log.
+var auditSnapshots = map[string]snapshotFunc{
+	"users": func(db *gorm.DB, id uid.ID) (any, error) {
+		identity, err := data.GetIdentity(db, data.ByID(id))
+		if err != nil {
+			return nil, err
+		}
+		return identity.ToAPI(), nil
+	},
+	"groups": func(db *gorm.DB, id uid.ID) (any, error) {
+		group, err := data.GetGroup(db, data.ByID(id))
+		if err != nil {
+			return nil, err
+		}
+		return group.ToAPI(), nil
+	},
+	"grants": func(db *gorm.DB, id uid.ID) (any, error) {
+		grant, err := data.GetGrant(db, data.ByID(id))
+		if err != nil {
+			return nil, err
+		}
+		return grant.ToAPI(), nil
+	},
+	"roles": func(db *gorm.DB, id uid.ID) (any, error) {
+		role, err := data.GetRole(db, data.ByID(id))
+		if err != nil {
+			return nil, err
+		}
+		return role.ToAPI(), nil
+	},
+	"providers": func(db *gorm.DB, id uid.ID) (any, error) {
+		provider, err := data.GetProvider(db, data.ByID(id))
+		if err != nil {
+			return nil, err
+		}
+		return provider.ToAPI(), nil
+	},
+	"destinations": func(db *gorm.DB, id uid.ID) (any, error) {
+		destination, err := data.GetDestination(db, data.ByID(id))
+		if err != nil {
+			return nil, err
+		}
+		return destination.ToAPI(), nil
+	},
+	"access-requests": func(db *gorm.DB, id uid.ID) (any, error) {
+		request, err := data.GetAccessRequest(db, data.ByID(id))
+		if err != nil {
+			return nil, err
+		}
+		return request.ToAPI(), nil
+	},
+	"access-keys": func(db *gorm.DB, id uid.ID) (any, error) {
+		key, err := data.GetAccessKey(db, data.ByID(id))
+		if err != nil {
+			return nil, err
+		}
+		return key.ToAPI(), nil
+	},
+}
+
+// auditRequest calls handle and records an audit event for the request when
+// it modifies state. Every route which can modify state is handled through it,
+// the API routes by add, and the SCIM routes by scimHandler.
+func auditRequest(c *gin.Context, method, routePath string, handle func() (any, error)) {
+	var (
+		resp any
+		err  error
+	)
+
+	audit := startAuditEvent(c, method, routePath)
+	defer func() {
+		audit.finish(c, resp, err)
+	}()
+
+	resp, err = handle()
+}
+
+// auditRecorder collects the details of a single mutating request so that an
+// audit event can be written once the handler returns.
+type auditRecorder struct {
+	event models.AuditEvent
+}
+
+// startAuditEvent begins recording an audit event for a request. It returns
+// nil for requests which do not modify any state.
+func startAuditEvent(c *gin.Context, method, routePath string) *auditRecorder {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+	default:
+		return nil
+	}
+
+	rec := &auditRecorder{
+		event: models.AuditEvent{
+			Method:       method,
+			Route:        routePath,
+			ResourceType: auditResourceType(routePath),
+		},
+	}
+
+	if param := c.Param("id"); param != "" {
+		if id, err := uid.Parse([]byte(param)); err == nil {
+			rec.event.ResourceID = id
+			rec.event.Before = rec.snapshot(c)
+		}
+	}
+
+	return rec
+}
+
+// finish writes the audit event using the same transaction as the request,
+// so that the event is committed along with any changes made by the handler.
+func (r *auditRecorder) finish(c *gin.Context, resp any, err error) {
+	if r == nil {
+		return
+	}
+
+	db, ok := auditDB(c)
+	if !ok {
+		return
+	}
+
+	r.event.StatusCode = c.Writer.Status()
+
+	if identity := access.AuthenticatedIdentity(c); identity != nil {
+		r.event.ActorID = identity.ID
+		r.event.ActorName = identity.Name
+	}
+
+	if val, ok := c.Get("key"); ok {
+		if key, ok := val.(*models.AccessKey); ok {
+			r.event.AccessKeyID = key.ID
+		}
+	}
+
+	if err == nil {
+		if r.event.ResourceID == 0 {
+			r.event.ResourceID = idFromResponse(resp)
+		}
+		r.event.After = r.snapshot(c)
+	}
+
+	if err := data.CreateAuditEvent(db, &r.event); err != nil {
+		logging.Errorf("failed to write audit event for %s %s: %v", r.event.Method, r.event.Route, err)
+	}
+}
+
+// snapshot returns the JSON encoded API representation of the resource
+// targeted by the request, or an empty string if there is none.
+func (r *auditRecorder) snapshot(c *gin.Context) string {
+	fn, ok := auditSnapshots[r.event.ResourceType]
+	if !ok || r.event.ResourceID == 0 {
+		return ""
+	}
+
+	db, ok := auditDB(c)
+	if !ok {
+		return ""
+	}
+
+	resource, err := fn(db, r.event.ResourceID)
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return ""
+	case err != nil:
+		logging.Debugf("audit snapshot of %s %s: %v", r.event.ResourceType, r.event.ResourceID, err)
+		return ""
+	}
+
+	raw, err := json.Marshal(resource)
+	if err != nil {
+		logging.Debugf("audit snapshot of %s %s: %v", r.event.ResourceType, r.event.ResourceID, err)
+		return ""
+	}
+
+	return string(raw)
+}
+
+func auditDB(c *gin.Context) (*gorm.DB, bool) {
+	val, ok := c.Get("db")
+	if !ok {
+		return nil, false
+	}
+	db, ok := val.(*gorm.DB)
+	return db, ok
+}
+
+// auditResourceType returns the resource type for a route, which is the first
+// path segment after /api/. For example, /api/grants/:id returns grants.
+// SCIM routes use the same resource types, /scim/v2/Users/:id returns users.
+func auditResourceType(routePath string) string {
+	if strings.HasPrefix(routePath, "/scim/v2/") {
+		resource, _, _ := strings.Cut(strings.TrimPrefix(routePath, "/scim/v2/"), "/")
+		return strings.ToLower(resource)
+	}
+
+	resource := strings.TrimPrefix(routePath, "/api/")
+	if resource == routePath {
+		return ""
+	}
+
+	resource, _, _ = strings.Cut(resource, "/")
+	return resource
+}
+
+var reflectTypeUID = reflect.TypeOf(uid.ID(0))
+
+// idFromResponse returns the value of the ID field of a response struct, or 0
+// if the response does not have one. SCIM responses have the ID as a string.
+func idFromResponse(resp any) uid.ID {
+	v := reflect.Indirect(reflect.ValueOf(resp))
+	if v.Kind() != reflect.Struct {
+		return 0
+	}
+
+	field, ok := v.Type().FieldByName("ID")
+	if !ok {
+		return 0
+	}
+
+	f, err := v.FieldByIndexErr(field.Index)
+	if err != nil {
+		return 0
+	}
+
+	switch field.Type {
+	case reflectTypeUID:
+		id, _ := f.Interface().(uid.ID)
+		return id
+	case reflectTypeString:
+		id, err := uid.Parse([]byte(f.String()))
+		if err != nil {
+			return 0
+		}
+		return id
+	}
+	return 0
+}
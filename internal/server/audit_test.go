@@ -0,0 +1,145 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_AuditEvents(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	user := &models.Identity{Name: "someone@example.com"}
+	err := data.CreateIdentity(srv.db, user)
+	assert.NilError(t, err)
+
+	admin, err := data.GetIdentity(srv.db, data.ByName("admin@example.com"))
+	assert.NilError(t, err)
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var buf bytes.Buffer
+		if body != nil {
+			err := json.NewEncoder(&buf).Encode(body)
+			assert.NilError(t, err)
+		}
+
+		req, err := http.NewRequest(method, path, &buf)
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+key)
+		req.Header.Add("Infra-Version", "0.12.3")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	resp := call(t, http.MethodPost, "/api/grants", adminAccessKey(srv), api.CreateGrantRequest{
+		User:      user.ID,
+		Privilege: "view",
+		Resource:  "example",
+	})
+	assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+	var grant api.Grant
+	err = json.Unmarshal(resp.Body.Bytes(), &grant)
+	assert.NilError(t, err)
+
+	resp = call(t, http.MethodDelete, "/api/grants/"+grant.ID.String(), adminAccessKey(srv), nil)
+	assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+	t.Run("mutating requests are recorded", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/audit-events?resourceType=grants", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var events api.ListResponse[api.AuditEvent]
+		err := json.Unmarshal(resp.Body.Bytes(), &events)
+		assert.NilError(t, err)
+		assert.Equal(t, events.Count, 2)
+
+		created := events.Items[0]
+		assert.Equal(t, created.ActorID, admin.ID)
+		assert.Equal(t, created.ActorName, "admin@example.com")
+		assert.Assert(t, created.AccessKeyID != 0)
+		assert.Equal(t, created.Method, http.MethodPost)
+		assert.Equal(t, created.Route, "/api/grants")
+		assert.Equal(t, created.ResourceID, grant.ID)
+		assert.Equal(t, created.StatusCode, http.StatusCreated)
+		assert.Equal(t, created.Before, "")
+
+		var after api.Grant
+		err = json.Unmarshal([]byte(created.After), &after)
+		assert.NilError(t, err)
+		assert.Equal(t, after.Resource, "example")
+
+		deleted := events.Items[1]
+		assert.Equal(t, deleted.Method, http.MethodDelete)
+		assert.Equal(t, deleted.Route, "/api/grants/:id")
+		assert.Equal(t, deleted.ResourceID, grant.ID)
+		assert.Equal(t, deleted.StatusCode, http.StatusNoContent)
+		assert.Equal(t, deleted.Before, created.After)
+		assert.Equal(t, deleted.After, "")
+	})
+
+	userKey, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+		IssuedFor:  user.ID,
+		ProviderID: data.InfraProvider(srv.db).ID,
+		ExpiresAt:  time.Now().Add(10 * time.Minute),
+	})
+	assert.NilError(t, err)
+
+	t.Run("failed requests are recorded", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/grants", userKey, api.CreateGrantRequest{
+			User:      user.ID,
+			Privilege: "admin",
+			Resource:  "example",
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/audit-events?actor="+user.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var events api.ListResponse[api.AuditEvent]
+		err := json.Unmarshal(resp.Body.Bytes(), &events)
+		assert.NilError(t, err)
+		assert.Equal(t, events.Count, 1)
+		assert.Equal(t, events.Items[0].StatusCode, http.StatusForbidden)
+		assert.Equal(t, events.Items[0].ResourceID, uid.ID(0))
+		assert.Equal(t, events.Items[0].After, "")
+	})
+
+	t.Run("filter by actor and time", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/audit-events?actor="+admin.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var events api.ListResponse[api.AuditEvent]
+		err := json.Unmarshal(resp.Body.Bytes(), &events)
+		assert.NilError(t, err)
+		assert.Equal(t, events.Count, 2)
+
+		since := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+		resp = call(t, http.MethodGet, "/api/audit-events?since="+since, adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		err = json.Unmarshal(resp.Body.Bytes(), &events)
+		assert.NilError(t, err)
+		assert.Equal(t, events.Count, 0)
+	})
+
+	t.Run("requires admin", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/audit-events", userKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+}
@@ -19,9 +19,18 @@ type LoginMethod interface {
 
 type AuthScope struct {
 	PasswordResetOnly bool
+	// MFARequired issues a short lived challenge instead of an access key,
+	// which is exchanged with a code for the access key.
+	MFARequired bool
+	// MFAEnrollmentOnly scopes the access key to enrolling in multi-factor
+	// authentication.
+	MFAEnrollmentOnly bool
+	// AccessKeyScopes are the scopes of an access key which was exchanged for
+	// a new key. The new key keeps the scopes.
+	AccessKeyScopes []string
 }
 
-func Login(ctx context.Context, db *gorm.DB, loginMethod LoginMethod, keyExpiresAt time.Time, keyExtension time.Duration) (*models.AccessKey, string, error) {
+func Login(ctx context.Context, db *gorm.DB, loginMethod LoginMethod, client models.ClientMetadata, keyExpiresAt time.Time, keyExtension time.Duration) (*models.AccessKey, string, error) {
 	// challenge the user to authenticate
 	identity, provider, scope, err := loginMethod.Authenticate(ctx, db)
 	if err != nil {
@@ -37,12 +46,26 @@ func Login(ctx context.Context, db *gorm.DB, loginMethod LoginMethod, keyExpires
 		ExpiresAt:         keyExpiresAt,
 		ExtensionDeadline: time.Now().UTC().Add(keyExtension),
 		Extension:         keyExtension,
+		LoginMethod:       loginMethod.Name(),
+		Client:            client,
+		LastUsedAt:        time.Now().UTC(),
 	}
 
-	if scope.PasswordResetOnly {
+	switch {
+	case scope.MFARequired:
+		accessKey.Scopes = models.CommaSeparatedStrings{models.ScopeMFAChallenge}
+		accessKey.ExpiresAt = time.Now().UTC().Add(mfaChallengeLifetime)
+		// the challenge is not extended by using it
+		accessKey.ExtensionDeadline = time.Time{}
+		accessKey.Extension = 0
+	case scope.PasswordResetOnly:
 		accessKey.Scopes = append(accessKey.Scopes, models.ScopePasswordReset)
+	case scope.MFAEnrollmentOnly:
+		accessKey.Scopes = append(accessKey.Scopes, models.ScopeMFAEnrollment)
 	}
 
+	accessKey.Scopes = append(accessKey.Scopes, scope.AccessKeyScopes...)
+
 	bearer, err := data.CreateAccessKey(db, accessKey)
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create access key after login: %w", err)
@@ -53,7 +53,7 @@ func TestLogin(t *testing.T) {
 
 	t.Run("failed login does not create access key", func(t *testing.T) {
 		authn := NewPasswordCredentialAuthentication(username, "invalid password")
-		_, bearer, err := Login(ctx, db, authn, time.Now().Add(1*time.Minute), time.Minute)
+		_, bearer, err := Login(ctx, db, authn, models.ClientMetadata{}, time.Now().Add(1*time.Minute), time.Minute)
 
 		assert.ErrorContains(t, err, "failed to login")
 		assert.Equal(t, bearer, "")
@@ -63,7 +63,7 @@ func TestLogin(t *testing.T) {
 		authn := NewPasswordCredentialAuthentication("gohan@example.com", password)
 		exp := time.Now().Add(1 * time.Minute)
 		ext := 1 * time.Minute
-		key, bearer, err := Login(ctx, db, authn, exp, ext)
+		key, bearer, err := Login(ctx, db, authn, models.ClientMetadata{}, exp, ext)
 
 		assert.NilError(t, err)
 		assert.Assert(t, bearer != "")
@@ -0,0 +1,117 @@
+package authn
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/pki"
+	"github.com/infrahq/infra/uid"
+)
+
+// certificateAuthn exchanges a client certificate, which was signed by the
+// certificate authority of the server, for an access key
+type certificateAuthn struct {
+	ConnectionState *tls.ConnectionState
+	Certificates    pki.CertificateProvider
+}
+
+func NewCertificateAuthentication(state *tls.ConnectionState, certificates pki.CertificateProvider) LoginMethod {
+	return &certificateAuthn{
+		ConnectionState: state,
+		Certificates:    certificates,
+	}
+}
+
+func (a *certificateAuthn) Authenticate(_ context.Context, db *gorm.DB) (*models.Identity, *models.Provider, AuthScope, error) {
+	identity, err := ClientCertificateIdentity(db, a.Certificates, a.ConnectionState)
+	if err != nil {
+		return nil, nil, AuthScope{}, err
+	}
+
+	return identity, data.InfraProvider(db), AuthScope{}, nil
+}
+
+func (a *certificateAuthn) Name() string {
+	return "certificate"
+}
+
+func (a *certificateAuthn) RequiresUpdate(db *gorm.DB) (bool, error) {
+	return false, nil // not applicable to client certificates
+}
+
+// ClientCertificateIdentity returns the identity of the client certificate of
+// the connection. The certificate must be signed by one of the active CAs of
+// the certificate provider, identify a user by ID, and the session it was
+// created with must not have been revoked or expired.
+func ClientCertificateIdentity(db *gorm.DB, certificates pki.CertificateProvider, state *tls.ConnectionState) (*models.Identity, error) {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return nil, errors.New("no client certificate in request")
+	}
+
+	roots := x509.NewCertPool()
+	for _, ca := range certificates.ActiveCAs() {
+		ca := ca
+		roots.AddCert(&ca)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	cert := state.PeerCertificates[0]
+	_, err := cert.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("verify client certificate: %w", err)
+	}
+
+	rawID := strings.TrimPrefix(cert.Subject.CommonName, "User ")
+	if rawID == cert.Subject.CommonName {
+		return nil, fmt.Errorf("client certificate %q is not a user certificate", cert.Subject.CommonName)
+	}
+
+	id, err := uid.Parse([]byte(rawID))
+	if err != nil {
+		return nil, fmt.Errorf("client certificate user id: %w", err)
+	}
+
+	identity, err := data.GetIdentity(db, data.ByID(id))
+	if err != nil {
+		return nil, fmt.Errorf("client certificate identity: %w", err)
+	}
+
+	sessionID, err := uid.Parse([]byte(cert.Subject.SerialNumber))
+	if err != nil {
+		return nil, fmt.Errorf("client certificate session id: %w", err)
+	}
+
+	session, err := data.GetAccessKey(db, data.ByID(sessionID))
+	if err != nil {
+		return nil, fmt.Errorf("client certificate session: %w", err)
+	}
+
+	now := time.Now().UTC()
+	switch {
+	case session.IssuedFor != identity.ID:
+		return nil, fmt.Errorf("client certificate session is for another user")
+	case now.After(session.ExpiresAt):
+		return nil, fmt.Errorf("client certificate session: %w", data.ErrAccessKeyExpired)
+	case !session.ExtensionDeadline.IsZero() && now.After(session.ExtensionDeadline):
+		return nil, fmt.Errorf("client certificate session: %w", data.ErrAccessKeyDeadlineExceeded)
+	}
+
+	return identity, nil
+}
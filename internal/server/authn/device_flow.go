@@ -0,0 +1,145 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+const (
+	// DeviceFlowPollInterval is the minimum time a device waits between
+	// attempts to login with its device code
+	DeviceFlowPollInterval = 5 * time.Second
+	// deviceFlowLifetime is how long the user has to approve the login
+	deviceFlowLifetime = 10 * time.Minute
+
+	deviceCodeLength = 32
+	userCodeLength   = 8
+	// userCodeCharset has no vowels, so that user codes never spell words,
+	// and no digits, which are easily mistaken for letters
+	userCodeCharset = "BCDFGHJKLMNPQRSTVWXZ"
+)
+
+var (
+	// ErrDeviceAuthorizationPending is returned by the device flow login
+	// method until the user approves the login
+	ErrDeviceAuthorizationPending = errors.New("device authorization pending")
+	// ErrDeviceSlowDown is returned by the device flow login method when the
+	// device polls more often than the poll interval. The device should
+	// increase its interval by DeviceFlowPollInterval (RFC 8628 §3.5).
+	ErrDeviceSlowDown = errors.New("slow_down: the device code was used before the poll interval elapsed")
+)
+
+// StartDeviceFlow creates a device flow login. The device code of the returned
+// request is only stored as a checksum, so it can not be retrieved later.
+func StartDeviceFlow(db *gorm.DB) (*models.DeviceFlowAuthRequest, error) {
+	deviceCode, err := generate.CryptoRandom(deviceCodeLength, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return nil, err
+	}
+
+	userCode, err := generate.CryptoRandom(userCodeLength, userCodeCharset)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.DeviceFlowAuthRequest{
+		DeviceCode: deviceCode,
+		UserCode:   userCode,
+		ExpiresAt:  time.Now().UTC().Add(deviceFlowLifetime),
+	}
+
+	if err := data.CreateDeviceFlowAuthRequest(db, request); err != nil {
+		return nil, fmt.Errorf("create device flow: %w", err)
+	}
+
+	return request, nil
+}
+
+// FormatUserCode splits a user code in two halves, to make it easier to read
+func FormatUserCode(code string) string {
+	return code[:len(code)/2] + "-" + code[len(code)/2:]
+}
+
+// NormalizeUserCode removes the formatting from a user code entered by a user
+func NormalizeUserCode(code string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToUpper(code))
+}
+
+// deviceFlowAuthn logs in a device with the device code of a device flow,
+// once a user has approved it
+type deviceFlowAuthn struct {
+	DeviceCode string
+}
+
+func NewDeviceFlowAuthentication(deviceCode string) LoginMethod {
+	return &deviceFlowAuthn{
+		DeviceCode: deviceCode,
+	}
+}
+
+func (a *deviceFlowAuthn) Authenticate(_ context.Context, db *gorm.DB) (*models.Identity, *models.Provider, AuthScope, error) {
+	request, err := data.GetDeviceFlowAuthRequest(db, data.ByDeviceCode(a.DeviceCode))
+	if err != nil {
+		return nil, nil, AuthScope{}, fmt.Errorf("invalid device code: %w", err)
+	}
+
+	if time.Now().After(request.ExpiresAt) {
+		if err := data.DeleteDeviceFlowAuthRequest(db, request.ID); err != nil {
+			return nil, nil, AuthScope{}, fmt.Errorf("delete device flow: %w", err)
+		}
+		return nil, nil, AuthScope{}, fmt.Errorf("device code expired")
+	}
+
+	now := time.Now()
+	polledTooSoon := now.Sub(request.LastPolledAt) < DeviceFlowPollInterval
+
+	if request.ApprovedBy == 0 || polledTooSoon {
+		request.LastPolledAt = now
+		if err := data.SaveDeviceFlowAuthRequest(db, request); err != nil {
+			return nil, nil, AuthScope{}, fmt.Errorf("update device flow: %w", err)
+		}
+		if polledTooSoon {
+			return nil, nil, AuthScope{}, ErrDeviceSlowDown
+		}
+		return nil, nil, AuthScope{}, ErrDeviceAuthorizationPending
+	}
+
+	// the device code can only be used to login once
+	if err := data.DeleteDeviceFlowAuthRequest(db, request.ID); err != nil {
+		return nil, nil, AuthScope{}, fmt.Errorf("delete device flow: %w", err)
+	}
+
+	identity, err := data.GetIdentity(db, data.ByID(request.ApprovedBy))
+	if err != nil {
+		return nil, nil, AuthScope{}, fmt.Errorf("user is not valid: %w", err)
+	}
+
+	provider, err := data.GetProvider(db, data.ByID(request.ProviderID))
+	if err != nil {
+		return nil, nil, AuthScope{}, fmt.Errorf("provider is not valid: %w", err)
+	}
+
+	return identity, provider, AuthScope{AccessKeyScopes: request.Scopes}, nil
+}
+
+func (a *deviceFlowAuthn) Name() string {
+	return "device"
+}
+
+func (a *deviceFlowAuthn) RequiresUpdate(db *gorm.DB) (bool, error) {
+	return false, nil // not applicable to device flow
+}
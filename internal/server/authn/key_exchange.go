@@ -31,6 +31,18 @@ func (a *keyExchangeAuthn) Authenticate(_ context.Context, db *gorm.DB) (*models
 		return nil, nil, AuthScope{}, fmt.Errorf("invalid access key in exchange: %w", err)
 	}
 
+	if validatedRequestKey.Scopes.Includes(models.ScopeSCIM) {
+		return nil, nil, AuthScope{}, fmt.Errorf("%w: SCIM access keys can not be exchanged", internal.ErrBadRequest)
+	}
+
+	if validatedRequestKey.Scopes.Includes(models.ScopeDestinationLogin) {
+		return nil, nil, AuthScope{}, fmt.Errorf("%w: destination login codes can not be exchanged", internal.ErrBadRequest)
+	}
+
+	if validatedRequestKey.Scopes.Includes(models.ScopeMFAChallenge) || validatedRequestKey.Scopes.Includes(models.ScopeMFAEnrollment) {
+		return nil, nil, AuthScope{}, fmt.Errorf("%w: the login must be completed with multi-factor authentication", internal.ErrBadRequest)
+	}
+
 	if a.RequestedExpiry.After(validatedRequestKey.ExpiresAt) {
 		return nil, nil, AuthScope{}, fmt.Errorf("%w: cannot exchange an access key for another access key with a longer lifetime", internal.ErrBadRequest)
 	}
@@ -40,7 +52,9 @@ func (a *keyExchangeAuthn) Authenticate(_ context.Context, db *gorm.DB) (*models
 		return nil, nil, AuthScope{}, fmt.Errorf("user is not valid: %w", err) // the user was probably deleted
 	}
 
-	return identity, data.InfraProvider(db), AuthScope{}, nil
+	// the new key is limited to the same operations, resources, and networks
+	scopes := AuthScope{AccessKeyScopes: validatedRequestKey.LimitingScopes()}
+	return identity, data.InfraProvider(db), scopes, nil
 }
 
 func (a *keyExchangeAuthn) Name() string {
@@ -0,0 +1,100 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/uid"
+)
+
+type ldapAuthn struct {
+	ProviderID uid.ID
+	Username   string
+	Password   string
+	LDAPClient providers.LDAPClient
+}
+
+func NewLDAPAuthentication(providerID uid.ID, username, password string, ldapClient providers.LDAPClient) LoginMethod {
+	return &ldapAuthn{
+		ProviderID: providerID,
+		Username:   username,
+		Password:   password,
+		LDAPClient: ldapClient,
+	}
+}
+
+func (a *ldapAuthn) Authenticate(ctx context.Context, db *gorm.DB) (*models.Identity, *models.Provider, AuthScope, error) {
+	provider, err := data.GetProvider(db, data.ByID(a.ProviderID))
+	if err != nil {
+		return nil, nil, AuthScope{}, err
+	}
+
+	if provider.Kind != models.ProviderKindLDAP {
+		return nil, nil, AuthScope{}, fmt.Errorf("provider %s is not an ldap provider", provider.Name)
+	}
+
+	ldapUser, err := a.LDAPClient.Authenticate(ctx, a.Username, a.Password)
+	if err != nil {
+		if errors.Is(err, providers.ErrUnauthorized) {
+			return nil, nil, AuthScope{}, fmt.Errorf("ldap: %w", err)
+		}
+
+		// the directory could not be searched, the user should be shown this
+		return nil, nil, AuthScope{}, fmt.Errorf("%w: %s", internal.ErrBadGateway, err)
+	}
+
+	identity, err := loginProviderUser(db, provider, ldapUser.Name, ldapUser.Groups)
+	if err != nil {
+		return nil, nil, AuthScope{}, err
+	}
+
+	return identity, provider, AuthScope{}, nil
+}
+
+// loginProviderUser gets or creates the user who logged in with a provider
+// which does not issue tokens, such as an LDAP or SAML provider. The groups
+// from the provider replace the groups from the previous login.
+func loginProviderUser(db *gorm.DB, provider *models.Provider, name string, groups []string) (*models.Identity, error) {
+	// the identity of the connector is used by destinations, not users
+	if name == models.InternalInfraConnectorIdentityName {
+		return nil, fmt.Errorf("%w: %q is a reserved name", internal.ErrUnauthorized, name)
+	}
+
+	identity, err := data.GetIdentity(db.Preload("Groups"), data.ByName(name))
+	if err != nil {
+		if !errors.Is(err, internal.ErrNotFound) {
+			return nil, fmt.Errorf("get user: %w", err)
+		}
+
+		identity = &models.Identity{Name: name}
+
+		if err := data.CreateIdentity(db, identity); err != nil {
+			return nil, fmt.Errorf("create user: %w", err)
+		}
+	}
+
+	if _, err := data.CreateProviderUser(db, provider, identity); err != nil {
+		return nil, fmt.Errorf("add user for provider login: %w", err)
+	}
+
+	if err := data.AssignIdentityToGroups(db, identity, provider, groups); err != nil {
+		return nil, fmt.Errorf("assign identity to groups: %w", err)
+	}
+
+	return identity, nil
+}
+
+func (a *ldapAuthn) Name() string {
+	return "ldap"
+}
+
+func (a *ldapAuthn) RequiresUpdate(db *gorm.DB) (bool, error) {
+	return false, nil // not applicable to ldap
+}
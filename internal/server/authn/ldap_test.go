@@ -0,0 +1,101 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/internal/testing/ldaptest"
+)
+
+func TestLDAPAuthenticate(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+
+	directory := ldaptest.NewServer(t,
+		ldaptest.Entry{DN: "cn=infra,dc=example,dc=com", Password: "service-password"},
+		ldaptest.Entry{
+			DN:         "uid=alice,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{"uid": {"alice"}, "mail": {"alice@example.com"}},
+			Password:   "alice-password",
+		},
+		ldaptest.Entry{
+			DN: "cn=developers,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"cn":     {"developers"},
+				"member": {"uid=alice,ou=people,dc=example,dc=com"},
+			},
+		},
+	)
+
+	provider := models.Provider{
+		Name:           "ad",
+		Kind:           models.ProviderKindLDAP,
+		URL:            directory.URL,
+		LDAPBindDN:     "cn=infra,dc=example,dc=com",
+		LDAPSearchBase: "dc=example,dc=com",
+	}
+	err := data.CreateProvider(db, &provider)
+	assert.NilError(t, err)
+
+	client := providers.NewLDAPClient(provider, "service-password")
+
+	t.Run("successful authentication", func(t *testing.T) {
+		// the user was removed from a group in the directory since the last login
+		existing := &models.Identity{Name: "alice@example.com"}
+		assert.NilError(t, data.CreateIdentity(db, existing))
+		_, err := data.CreateProviderUser(db, &provider, existing)
+		assert.NilError(t, err)
+		assert.NilError(t, data.AssignIdentityToGroups(db, existing, &provider, []string{"admins"}))
+
+		ldapAuthn := NewLDAPAuthentication(provider.ID, "alice", "alice-password", client)
+		identity, loginProvider, _, err := ldapAuthn.Authenticate(ctx, db)
+		assert.NilError(t, err)
+
+		assert.Equal(t, identity.ID, existing.ID)
+		assert.Equal(t, loginProvider.ID, provider.ID)
+
+		groups, err := data.ListGroups(db, &models.Pagination{}, data.ByGroupMember(identity.ID))
+		assert.NilError(t, err)
+		assert.Equal(t, len(groups), 1)
+		assert.Equal(t, groups[0].Name, "developers")
+	})
+
+	t.Run("invalid password", func(t *testing.T) {
+		ldapAuthn := NewLDAPAuthentication(provider.ID, "alice", "wrong", client)
+		_, _, _, err := ldapAuthn.Authenticate(ctx, db)
+		assert.ErrorIs(t, err, providers.ErrUnauthorized)
+	})
+
+	t.Run("directory unavailable", func(t *testing.T) {
+		unavailable := provider
+		unavailable.URL = "ldap://127.0.0.1:1"
+
+		ldapAuthn := NewLDAPAuthentication(provider.ID, "alice", "alice-password", providers.NewLDAPClient(unavailable, "service-password"))
+		_, _, _, err := ldapAuthn.Authenticate(ctx, db)
+		assert.Assert(t, errors.Is(err, internal.ErrBadGateway), err)
+	})
+
+	t.Run("reserved name", func(t *testing.T) {
+		_, err := loginProviderUser(db, &provider, models.InternalInfraConnectorIdentityName, nil)
+		assert.ErrorIs(t, err, internal.ErrUnauthorized)
+
+		_, err = data.GetIdentity(db, data.ByName(models.InternalInfraConnectorIdentityName))
+		assert.ErrorIs(t, err, internal.ErrNotFound)
+	})
+
+	t.Run("not an ldap provider", func(t *testing.T) {
+		oidcProvider := models.Provider{Name: "okta", Kind: models.ProviderKindOkta}
+		assert.NilError(t, data.CreateProvider(db, &oidcProvider))
+
+		ldapAuthn := NewLDAPAuthentication(oidcProvider.ID, "alice", "alice-password", client)
+		_, _, _, err := ldapAuthn.Authenticate(ctx, db)
+		assert.ErrorContains(t, err, "not an ldap provider")
+	})
+}
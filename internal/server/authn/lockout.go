@@ -0,0 +1,99 @@
+package authn
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// LoginLockout limits the failed logins of a username, or of a client IP.
+// After MaxFailures consecutive failures logins are rejected for Duration,
+// which doubles with every further failure up to MaxDuration. A MaxFailures
+// of zero disables the lockout.
+type LoginLockout struct {
+	Kind        string
+	MaxFailures int
+	Duration    time.Duration
+	MaxDuration time.Duration
+}
+
+// Check returns an error if logins for the key are locked.
+func (l LoginLockout) Check(db *gorm.DB, key string, now time.Time) error {
+	if l.MaxFailures <= 0 {
+		return nil
+	}
+
+	failure, err := data.GetLoginFailure(db, data.ByLoginFailureKey(l.Kind, key))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		return nil
+	case err != nil:
+		return fmt.Errorf("get login failures: %w", err)
+	}
+
+	if now.Before(failure.LockedUntil) {
+		return fmt.Errorf("%w: too many failed logins, try again in %v", internal.ErrTooManyRequests, failure.LockedUntil.Sub(now).Round(time.Second))
+	}
+
+	return nil
+}
+
+// Fail records a failed login for the key, and locks logins for the key once
+// there have been too many.
+func (l LoginLockout) Fail(db *gorm.DB, key string, now time.Time) error {
+	if l.MaxFailures <= 0 {
+		return nil
+	}
+
+	failure, err := data.GetLoginFailure(db, data.ByLoginFailureKey(l.Kind, key))
+	switch {
+	case errors.Is(err, internal.ErrNotFound):
+		failure = &models.LoginFailure{Kind: l.Kind, Key: key}
+	case err != nil:
+		return fmt.Errorf("get login failures: %w", err)
+	}
+
+	// failures are forgotten once the longest lockout has passed without any
+	// more of them, so that occasional typos never add up to a lockout
+	if now.Sub(failure.LastFailureAt) > l.MaxDuration {
+		failure.Failures = 0
+	}
+
+	failure.Failures++
+	failure.LastFailureAt = now
+
+	if failure.Failures >= l.MaxFailures {
+		failure.LockedUntil = now.Add(l.lockDuration(failure.Failures))
+	}
+
+	if failure.ID == 0 {
+		return data.CreateLoginFailure(db, failure)
+	}
+
+	return data.SaveLoginFailure(db, failure)
+}
+
+// lockDuration returns how long logins are locked after the failures
+func (l LoginLockout) lockDuration(failures int) time.Duration {
+	duration := l.Duration
+	for i := l.MaxFailures; i < failures && duration < l.MaxDuration; i++ {
+		duration *= 2
+	}
+
+	if duration > l.MaxDuration {
+		return l.MaxDuration
+	}
+
+	return duration
+}
+
+// Reset forgets the failed logins of the key, after a successful login.
+func (l LoginLockout) Reset(db *gorm.DB, key string) error {
+	return data.DeleteLoginFailures(db, data.ByLoginFailureKey(l.Kind, key))
+}
@@ -0,0 +1,103 @@
+package authn
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestLoginLockout(t *testing.T) {
+	db := setupDB(t)
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	lockout := LoginLockout{
+		Kind:        models.LoginFailureKindUser,
+		MaxFailures: 3,
+		Duration:    time.Minute,
+		MaxDuration: 5 * time.Minute,
+	}
+
+	assertLocked := func(t *testing.T, key string, at time.Time, locked bool) {
+		t.Helper()
+		err := lockout.Check(db, key, at)
+		if locked {
+			assert.Assert(t, errors.Is(err, internal.ErrTooManyRequests), "expected locked, got %v", err)
+		} else {
+			assert.NilError(t, err)
+		}
+	}
+
+	t.Run("locked after max failures", func(t *testing.T) {
+		key := "alice"
+		for i := 0; i < 2; i++ {
+			assert.NilError(t, lockout.Fail(db, key, now))
+			assertLocked(t, key, now, false)
+		}
+
+		assert.NilError(t, lockout.Fail(db, key, now))
+		assertLocked(t, key, now, true)
+		assertLocked(t, key, now.Add(59*time.Second), true)
+		assertLocked(t, key, now.Add(time.Minute), false)
+
+		// other keys and kinds are not locked
+		assertLocked(t, "bob", now, false)
+		ipLockout := lockout
+		ipLockout.Kind = models.LoginFailureKindIP
+		assert.NilError(t, ipLockout.Check(db, key, now))
+	})
+
+	t.Run("lock duration doubles up to the max", func(t *testing.T) {
+		key := "carol"
+		for i := 0; i < 3; i++ {
+			assert.NilError(t, lockout.Fail(db, key, now))
+		}
+		assertLocked(t, key, now.Add(time.Minute), false)
+
+		expected := []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute, 5 * time.Minute}
+		for _, duration := range expected {
+			now := now.Add(time.Minute)
+			assert.NilError(t, lockout.Fail(db, key, now))
+			assertLocked(t, key, now.Add(duration-time.Second), true)
+			assertLocked(t, key, now.Add(duration), false)
+		}
+	})
+
+	t.Run("failures are forgotten after max duration", func(t *testing.T) {
+		key := "dave"
+		for i := 0; i < 2; i++ {
+			assert.NilError(t, lockout.Fail(db, key, now))
+		}
+
+		later := now.Add(lockout.MaxDuration + time.Second)
+		assert.NilError(t, lockout.Fail(db, key, later))
+		assertLocked(t, key, later, false)
+	})
+
+	t.Run("reset", func(t *testing.T) {
+		key := "erin"
+		for i := 0; i < 3; i++ {
+			assert.NilError(t, lockout.Fail(db, key, now))
+		}
+		assertLocked(t, key, now, true)
+
+		assert.NilError(t, lockout.Reset(db, key))
+		assertLocked(t, key, now, false)
+
+		// failures can be recorded again after a reset
+		assert.NilError(t, lockout.Fail(db, key, now))
+		assertLocked(t, key, now, false)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		disabled := LoginLockout{Kind: models.LoginFailureKindUser}
+		for i := 0; i < 10; i++ {
+			assert.NilError(t, disabled.Fail(db, "frank", now))
+		}
+		assert.NilError(t, disabled.Check(db, "frank", now))
+	})
+}
@@ -0,0 +1,137 @@
+package authn
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// mfaChallengeLifetime is how long a user has to enter a code after their
+// password was verified.
+const mfaChallengeLifetime = 5 * time.Minute
+
+// mfaAuthn completes a password login, by exchanging the challenge issued for
+// the password and a TOTP or recovery code for an access key
+type mfaAuthn struct {
+	Token string
+	Code  string
+
+	identityID uid.ID
+}
+
+func NewMFAAuthentication(token, code string) LoginMethod {
+	return &mfaAuthn{
+		Token: token,
+		Code:  code,
+	}
+}
+
+func (a *mfaAuthn) Authenticate(_ context.Context, db *gorm.DB) (*models.Identity, *models.Provider, AuthScope, error) {
+	scope := AuthScope{}
+
+	challenge, err := data.ValidateAccessKey(db, a.Token)
+	if err != nil {
+		return nil, nil, scope, fmt.Errorf("invalid mfa token: %w", err)
+	}
+
+	if !challenge.Scopes.Includes(models.ScopeMFAChallenge) {
+		return nil, nil, scope, fmt.Errorf("access key is not an mfa token")
+	}
+
+	// the challenge can only be used once, whether the code is correct or not,
+	// so that guessing a code requires the password for every attempt
+	if err := data.DeleteAccessKey(db, challenge.ID); err != nil {
+		return nil, nil, scope, fmt.Errorf("delete mfa token: %w", err)
+	}
+
+	identity, err := data.GetIdentity(db, data.ByID(challenge.IssuedFor))
+	if err != nil {
+		return nil, nil, scope, fmt.Errorf("user is not valid: %w", err)
+	}
+
+	credential, err := data.GetCredential(db, data.ByIdentityID(identity.ID))
+	if err != nil {
+		return nil, nil, scope, fmt.Errorf("get credential: %w", err)
+	}
+
+	if !credential.MFAEnabled {
+		return nil, nil, scope, fmt.Errorf("mfa is not enabled")
+	}
+
+	if !UseMFACode(credential, a.Code, time.Now()) {
+		return nil, nil, scope, fmt.Errorf("could not verify mfa code")
+	}
+
+	if err := data.SaveCredential(db, credential); err != nil {
+		return nil, nil, scope, fmt.Errorf("save credential: %w", err)
+	}
+
+	a.identityID = identity.ID
+	scope.PasswordResetOnly = credential.OneTimePassword
+
+	return identity, data.InfraProvider(db), scope, nil
+}
+
+func (a *mfaAuthn) Name() string {
+	return "mfa"
+}
+
+func (a *mfaAuthn) RequiresUpdate(db *gorm.DB) (bool, error) {
+	credential, err := data.GetCredential(db, data.ByIdentityID(a.identityID))
+	if err != nil {
+		return false, fmt.Errorf("could not get credential for user: %w", err)
+	}
+
+	return credential.OneTimePassword, nil
+}
+
+// UseMFACode returns true if the code is a TOTP code from the secret of the
+// credential, or one of its recovery codes. The counter of the TOTP code is
+// recorded, or the recovery code is removed, so the code can't be used again.
+// The caller must save the credential when the code is accepted.
+func UseMFACode(credential *models.Credential, code string, now time.Time) bool {
+	if counter, ok := ValidateTOTP(string(credential.MFASecret), code, now, credential.MFALastCounter); ok {
+		credential.MFALastCounter = counter
+		return true
+	}
+
+	return useRecoveryCode(credential, code)
+}
+
+// useRecoveryCode removes the code from the recovery codes of the credential,
+// and returns false if it is not one of them
+func useRecoveryCode(credential *models.Credential, code string) bool {
+	hash := hashRecoveryCode(code)
+	for i, h := range credential.MFARecoveryCodes {
+		if subtle.ConstantTimeCompare([]byte(h), []byte(hash)) == 1 {
+			credential.MFARecoveryCodes = append(credential.MFARecoveryCodes[:i:i], credential.MFARecoveryCodes[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// requiresMFA returns true if the identity is a member of a group which
+// requires multi-factor authentication
+func requiresMFA(db *gorm.DB, identityID uid.ID) (bool, error) {
+	groups, err := data.ListGroups(db, &models.Pagination{}, data.ByGroupMember(identityID))
+	if err != nil {
+		return false, fmt.Errorf("list groups: %w", err)
+	}
+
+	for _, group := range groups {
+		if group.RequireMFA {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -44,9 +44,19 @@ func (a *passwordCredentialAuthn) Authenticate(_ context.Context, db *gorm.DB) (
 		return nil, nil, scope, fmt.Errorf("could not verify password: %w", err)
 	}
 
-	if userCredential.OneTimePassword {
+	switch {
+	case userCredential.MFAEnabled:
+		// the login is completed by exchanging the challenge and a code, which
+		// also checks for a one time password
+		scope.MFARequired = true
+	case userCredential.OneTimePassword:
 		// scope the login down to Password Reset Only
 		scope.PasswordResetOnly = true
+	default:
+		scope.MFAEnrollmentOnly, err = requiresMFA(db, identity.ID)
+		if err != nil {
+			return nil, nil, scope, err
+		}
 	}
 
 	// authentication was a success
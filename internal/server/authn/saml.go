@@ -0,0 +1,213 @@
+package authn
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/crewjam/saml"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	// samlLoginLifetime is how long the user has to login at the identity
+	// provider
+	samlLoginLifetime = 10 * time.Minute
+	// samlCodeLifetime is how long the client has to exchange the login code
+	// for an access key
+	samlCodeLifetime = time.Minute
+
+	samlRelayStateLength = 32
+	samlCodeLength       = 32
+)
+
+// StartSAMLLogin creates a login with a SAML provider. The returned URL sends
+// the user to the identity provider with an authentication request. Once the
+// identity provider responds, the user is sent to the redirect URL with a
+// login code and the state.
+func StartSAMLLogin(db *gorm.DB, sp *saml.ServiceProvider, providerID uid.ID, redirectURL, state string) (*url.URL, error) {
+	relayState, err := generate.CryptoRandom(samlRelayStateLength, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return nil, err
+	}
+
+	authnRequest, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return nil, fmt.Errorf("saml authentication request: %w", err)
+	}
+
+	request := &models.SAMLAuthnRequest{
+		ProviderID:  providerID,
+		RequestID:   authnRequest.ID,
+		RelayState:  relayState,
+		RedirectURL: redirectURL,
+		State:       state,
+		ExpiresAt:   time.Now().UTC().Add(samlLoginLifetime),
+	}
+
+	if err := data.CreateSAMLAuthnRequest(db, request); err != nil {
+		return nil, fmt.Errorf("create saml login: %w", err)
+	}
+
+	return authnRequest.Redirect(relayState, sp)
+}
+
+// CompleteSAMLLogin validates the response of the identity provider to the
+// login identified by the relay state. It returns the redirect URL of the
+// login with a code, which the client exchanges for an access key. When the
+// assertion is not valid, the redirect URL has an error instead of a code.
+func CompleteSAMLLogin(db *gorm.DB, sp *saml.ServiceProvider, provider *models.Provider, relayState, samlResponse string) (*url.URL, error) {
+	request, err := data.GetSAMLAuthnRequest(db, data.ByRelayState(relayState))
+	if err != nil {
+		return nil, fmt.Errorf("saml login: %w", err)
+	}
+
+	if request.ProviderID != provider.ID {
+		return nil, fmt.Errorf("%w: saml login is for a different provider", internal.ErrBadRequest)
+	}
+
+	if len(request.CodeChecksum) > 0 {
+		return nil, fmt.Errorf("%w: saml login has already been completed", internal.ErrBadRequest)
+	}
+
+	if time.Now().After(request.ExpiresAt) {
+		if err := data.DeleteSAMLAuthnRequest(db, request.ID); err != nil {
+			return nil, fmt.Errorf("delete saml login: %w", err)
+		}
+		return nil, fmt.Errorf("%w: saml login expired", internal.ErrBadRequest)
+	}
+
+	user, err := samlUserFromResponse(sp, provider, request.RequestID, samlResponse)
+	if err != nil {
+		logging.Debugf("invalid saml response from provider %s: %v", provider.Name, err)
+
+		if err := data.DeleteSAMLAuthnRequest(db, request.ID); err != nil {
+			return nil, fmt.Errorf("delete saml login: %w", err)
+		}
+
+		return samlRedirectURL(request, url.Values{
+			"error":             {"access_denied"},
+			"error_description": {"the response from the identity provider is not valid"},
+		})
+	}
+
+	code, err := generate.CryptoRandom(samlCodeLength, generate.CharsetAlphaNumeric)
+	if err != nil {
+		return nil, err
+	}
+
+	request.Code = code
+	request.Name = user.Name
+	request.Groups = user.Groups
+	request.ExpiresAt = time.Now().UTC().Add(samlCodeLifetime)
+
+	if err := data.SaveSAMLAuthnRequest(db, request); err != nil {
+		return nil, fmt.Errorf("save saml login: %w", err)
+	}
+
+	return samlRedirectURL(request, url.Values{"code": {code}})
+}
+
+func samlUserFromResponse(sp *saml.ServiceProvider, provider *models.Provider, requestID, samlResponse string) (*providers.SAMLUser, error) {
+	raw, err := base64.StdEncoding.DecodeString(samlResponse)
+	if err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+
+	assertion, err := sp.ParseXMLResponse(raw, []string{requestID})
+	if err != nil {
+		// the error message is always the same, the reason is private
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			return nil, invalid.PrivateErr
+		}
+		return nil, err
+	}
+
+	return providers.SAMLUserFromAssertion(assertion, provider.SAMLGroupsAttribute)
+}
+
+func samlRedirectURL(request *models.SAMLAuthnRequest, params url.Values) (*url.URL, error) {
+	u, err := url.Parse(request.RedirectURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redirect url: %w", err)
+	}
+
+	query := u.Query()
+	for k, v := range params {
+		query[k] = v
+	}
+	query.Set("state", request.State)
+	u.RawQuery = query.Encode()
+
+	return u, nil
+}
+
+// samlAuthn logs in with the login code from a SAML login, once the
+// identity provider has responded with a valid assertion
+type samlAuthn struct {
+	ProviderID uid.ID
+	Code       string
+}
+
+func NewSAMLAuthentication(providerID uid.ID, code string) LoginMethod {
+	return &samlAuthn{
+		ProviderID: providerID,
+		Code:       code,
+	}
+}
+
+func (a *samlAuthn) Authenticate(_ context.Context, db *gorm.DB) (*models.Identity, *models.Provider, AuthScope, error) {
+	request, err := data.GetSAMLAuthnRequest(db, data.BySAMLCode(a.Code))
+	if err != nil {
+		return nil, nil, AuthScope{}, fmt.Errorf("invalid saml login code: %w", err)
+	}
+
+	// the code can only be used to login once
+	if err := data.DeleteSAMLAuthnRequest(db, request.ID); err != nil {
+		return nil, nil, AuthScope{}, fmt.Errorf("delete saml login: %w", err)
+	}
+
+	if time.Now().After(request.ExpiresAt) {
+		return nil, nil, AuthScope{}, fmt.Errorf("saml login code expired")
+	}
+
+	if request.ProviderID != a.ProviderID {
+		return nil, nil, AuthScope{}, fmt.Errorf("saml login code is for a different provider")
+	}
+
+	provider, err := data.GetProvider(db, data.ByID(request.ProviderID))
+	if err != nil {
+		return nil, nil, AuthScope{}, fmt.Errorf("provider is not valid: %w", err)
+	}
+
+	if provider.Kind != models.ProviderKindSAML {
+		return nil, nil, AuthScope{}, fmt.Errorf("provider %s is not a saml provider", provider.Name)
+	}
+
+	identity, err := loginProviderUser(db, provider, request.Name, request.Groups)
+	if err != nil {
+		return nil, nil, AuthScope{}, err
+	}
+
+	return identity, provider, AuthScope{}, nil
+}
+
+func (a *samlAuthn) Name() string {
+	return "saml"
+}
+
+func (a *samlAuthn) RequiresUpdate(db *gorm.DB) (bool, error) {
+	return false, nil // not applicable to saml
+}
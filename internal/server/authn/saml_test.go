@@ -0,0 +1,72 @@
+package authn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestSAMLAuthenticate(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+
+	provider := models.Provider{Name: "adfs", Kind: models.ProviderKindSAML}
+	assert.NilError(t, data.CreateProvider(db, &provider))
+
+	// createLogin creates a SAML login which the identity provider has
+	// responded to
+	createLogin := func(t *testing.T, code string, expiresAt time.Time) {
+		t.Helper()
+		request := &models.SAMLAuthnRequest{
+			ProviderID: provider.ID,
+			RequestID:  "id-" + code,
+			RelayState: "relay-" + code,
+			Code:       code,
+			Name:       "alice@example.com",
+			Groups:     models.CommaSeparatedStrings{"developers"},
+			ExpiresAt:  expiresAt,
+		}
+		assert.NilError(t, data.CreateSAMLAuthnRequest(db, request))
+	}
+
+	t.Run("successful authentication", func(t *testing.T) {
+		createLogin(t, "valid-code", time.Now().Add(time.Minute))
+
+		samlAuthn := NewSAMLAuthentication(provider.ID, "valid-code")
+		identity, loginProvider, _, err := samlAuthn.Authenticate(ctx, db)
+		assert.NilError(t, err)
+
+		assert.Equal(t, identity.Name, "alice@example.com")
+		assert.Equal(t, loginProvider.ID, provider.ID)
+
+		groups, err := data.ListGroups(db, &models.Pagination{}, data.ByGroupMember(identity.ID))
+		assert.NilError(t, err)
+		assert.Equal(t, len(groups), 1)
+		assert.Equal(t, groups[0].Name, "developers")
+
+		// the code can only be used once
+		_, _, _, err = samlAuthn.Authenticate(ctx, db)
+		assert.ErrorContains(t, err, "invalid saml login code")
+	})
+
+	t.Run("expired code", func(t *testing.T) {
+		createLogin(t, "expired-code", time.Now().Add(-time.Second))
+
+		samlAuthn := NewSAMLAuthentication(provider.ID, "expired-code")
+		_, _, _, err := samlAuthn.Authenticate(ctx, db)
+		assert.ErrorContains(t, err, "saml login code expired")
+	})
+
+	t.Run("code for a different provider", func(t *testing.T) {
+		createLogin(t, "other-code", time.Now().Add(time.Minute))
+
+		samlAuthn := NewSAMLAuthentication(provider.ID+1, "other-code")
+		_, _, _, err := samlAuthn.Authenticate(ctx, db)
+		assert.ErrorContains(t, err, "different provider")
+	})
+}
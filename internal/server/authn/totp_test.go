@@ -0,0 +1,90 @@
+package authn
+
+import (
+	"encoding/base32"
+	"net/url"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// the SHA-1 test vectors of RFC 6238, truncated to 6 digits
+func TestTOTPCode(t *testing.T) {
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+
+	for unix, expected := range vectors {
+		code, err := TOTPCode(secret, time.Unix(unix, 0))
+		assert.NilError(t, err)
+		assert.Equal(t, code, expected, "time %d", unix)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := NewTOTPSecret()
+	assert.NilError(t, err)
+
+	now := time.Now()
+	code, err := TOTPCode(secret, now)
+	assert.NilError(t, err)
+
+	valid := func(code string, t time.Time, lastCounter int64) bool {
+		_, ok := ValidateTOTP(secret, code, t, lastCounter)
+		return ok
+	}
+
+	counter, ok := ValidateTOTP(secret, code, now, 0)
+	assert.Assert(t, ok)
+	assert.Equal(t, counter, now.Unix()/int64(totpPeriod.Seconds()))
+	// clock drift of the device
+	assert.Assert(t, valid(code, now.Add(totpPeriod), 0))
+	assert.Assert(t, !valid(code, now.Add(3*totpPeriod), 0))
+
+	// a code can not be used again once it was accepted
+	assert.Assert(t, !valid(code, now, counter))
+	assert.Assert(t, !valid(code, now.Add(totpPeriod), counter))
+	assert.Assert(t, valid(code, now, counter-1))
+
+	assert.Assert(t, !valid("", now, 0))
+	_, ok = ValidateTOTP("not base32!", code, now, 0)
+	assert.Assert(t, !ok)
+}
+
+func TestTOTPProvisioningURI(t *testing.T) {
+	uri := TOTPProvisioningURI("Infra", "alice@example.com", "JBSWY3DPEHPK3PXP")
+
+	u, err := url.Parse(uri)
+	assert.NilError(t, err)
+	assert.Equal(t, u.Scheme, "otpauth")
+	assert.Equal(t, u.Host, "totp")
+	assert.Equal(t, u.Path, "/Infra:alice@example.com")
+	assert.Equal(t, u.Query().Get("secret"), "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, u.Query().Get("issuer"), "Infra")
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	codes, hashes, err := NewRecoveryCodes()
+	assert.NilError(t, err)
+	assert.Equal(t, len(codes), recoveryCodeCount)
+
+	credential := &models.Credential{MFARecoveryCodes: hashes}
+
+	assert.Assert(t, useRecoveryCode(credential, codes[3]))
+	assert.Equal(t, len(credential.MFARecoveryCodes), recoveryCodeCount-1)
+
+	// each code can only be used once
+	assert.Assert(t, !useRecoveryCode(credential, codes[3]))
+	assert.Assert(t, useRecoveryCode(credential, " "+codes[0]+" "))
+	assert.Assert(t, !useRecoveryCode(credential, "not-a-code"))
+}
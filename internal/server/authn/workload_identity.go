@@ -0,0 +1,122 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+)
+
+// WorkloadIdentityRule maps the claims of a workload token to an identity.
+type WorkloadIdentityRule struct {
+	// Claims must all match the claims of the token. The values are patterns,
+	// with the syntax of path.Match, so '*' does not match a '/'.
+	Claims map[string]string
+	// Identity is the name of the identity which the workload logs in as.
+	Identity string
+}
+
+// WorkloadIdentityIssuer is a trusted issuer of workload tokens, and the rules
+// which map its tokens to identities. The first matching rule is used.
+type WorkloadIdentityIssuer struct {
+	*providers.WorkloadIssuer
+	Rules []WorkloadIdentityRule
+}
+
+// workloadIdentityAuthn exchanges a token, signed by a trusted issuer of
+// workload identities, for an access key
+type workloadIdentityAuthn struct {
+	Token   string
+	Issuers []WorkloadIdentityIssuer
+}
+
+func NewWorkloadIdentityAuthentication(token string, issuers []WorkloadIdentityIssuer) LoginMethod {
+	return &workloadIdentityAuthn{
+		Token:   token,
+		Issuers: issuers,
+	}
+}
+
+func (a *workloadIdentityAuthn) Authenticate(ctx context.Context, db *gorm.DB) (*models.Identity, *models.Provider, AuthScope, error) {
+	iss, err := providers.WorkloadTokenIssuer(a.Token)
+	if err != nil {
+		return nil, nil, AuthScope{}, err
+	}
+
+	var issuer *WorkloadIdentityIssuer
+	for i := range a.Issuers {
+		if a.Issuers[i].Issuer == iss {
+			issuer = &a.Issuers[i]
+			break
+		}
+	}
+
+	if issuer == nil {
+		return nil, nil, AuthScope{}, fmt.Errorf("token issuer %q is not trusted", iss)
+	}
+
+	claims, err := issuer.Verify(ctx, a.Token)
+	if err != nil {
+		if errors.Is(err, providers.ErrUnauthorized) {
+			return nil, nil, AuthScope{}, err
+		}
+
+		// the keys of the issuer could not be found, the user should be shown this
+		return nil, nil, AuthScope{}, fmt.Errorf("%w: %s", internal.ErrBadGateway, err)
+	}
+
+	rule, ok := matchWorkloadIdentityRule(issuer.Rules, claims)
+	if !ok {
+		return nil, nil, AuthScope{}, fmt.Errorf("no workload identity rule of issuer %q matches the token", iss)
+	}
+
+	identity, err := data.GetIdentity(db, data.ByName(rule.Identity))
+	if err != nil {
+		return nil, nil, AuthScope{}, fmt.Errorf("workload identity %q: %w", rule.Identity, err)
+	}
+
+	return identity, data.InfraProvider(db), AuthScope{}, nil
+}
+
+func matchWorkloadIdentityRule(rules []WorkloadIdentityRule, claims map[string]interface{}) (WorkloadIdentityRule, bool) {
+	for _, rule := range rules {
+		if matchWorkloadClaims(rule.Claims, claims) {
+			return rule, true
+		}
+	}
+
+	return WorkloadIdentityRule{}, false
+}
+
+func matchWorkloadClaims(patterns map[string]string, claims map[string]interface{}) bool {
+	for name, pattern := range patterns {
+		value, ok := claims[name]
+		if !ok {
+			return false
+		}
+
+		// claims may be booleans or numbers, such as the ref_protected claim
+		// of GitHub Actions
+		matched, err := path.Match(pattern, fmt.Sprint(value))
+		if err != nil || !matched {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (a *workloadIdentityAuthn) Name() string {
+	return "workload"
+}
+
+func (a *workloadIdentityAuthn) RequiresUpdate(db *gorm.DB) (bool, error) {
+	return false, nil // not applicable to workload identities
+}
@@ -0,0 +1,118 @@
+package authn
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/internal/testing/jwkstest"
+)
+
+func TestWorkloadIdentityAuthenticate(t *testing.T) {
+	db := setupDB(t)
+	ctx := context.Background()
+
+	github := jwkstest.NewIssuer(t)
+
+	deploy := &models.Identity{Name: "deploy@ci.example.com"}
+	assert.NilError(t, data.CreateIdentity(db, deploy))
+	build := &models.Identity{Name: "build@ci.example.com"}
+	assert.NilError(t, data.CreateIdentity(db, build))
+
+	issuers := []WorkloadIdentityIssuer{
+		{
+			WorkloadIssuer: providers.NewWorkloadIssuer(github.URL, github.JWKSURL, "https://infra.example.com"),
+			Rules: []WorkloadIdentityRule{
+				{
+					Claims:   map[string]string{"repository": "infrahq/infra", "ref": "refs/heads/main", "ref_protected": "true"},
+					Identity: "deploy@ci.example.com",
+				},
+				{
+					Claims:   map[string]string{"repository": "infrahq/*"},
+					Identity: "build@ci.example.com",
+				},
+				{
+					Claims:   map[string]string{"repository": "other/*"},
+					Identity: "missing@ci.example.com",
+				},
+			},
+		},
+	}
+
+	claims := func(repository, ref string) map[string]interface{} {
+		return map[string]interface{}{
+			"aud":           "https://infra.example.com",
+			"sub":           "repo:" + repository + ":ref:" + ref,
+			"repository":    repository,
+			"ref":           ref,
+			"ref_protected": ref == "refs/heads/main",
+		}
+	}
+
+	authenticate := func(t *testing.T, token string) (*models.Identity, *models.Provider, error) {
+		t.Helper()
+		identity, provider, _, err := NewWorkloadIdentityAuthentication(token, issuers).Authenticate(ctx, db)
+		return identity, provider, err
+	}
+
+	t.Run("first matching rule", func(t *testing.T) {
+		identity, provider, err := authenticate(t, github.Token(t, claims("infrahq/infra", "refs/heads/main")))
+		assert.NilError(t, err)
+		assert.Equal(t, identity.ID, deploy.ID)
+		assert.Equal(t, provider.Name, models.InternalInfraProviderName)
+
+		identity, _, err = authenticate(t, github.Token(t, claims("infrahq/infra", "refs/heads/feature")))
+		assert.NilError(t, err)
+		assert.Equal(t, identity.ID, build.ID)
+	})
+
+	t.Run("pattern does not match a path separator", func(t *testing.T) {
+		_, _, err := authenticate(t, github.Token(t, claims("infrahq/infra/fork", "refs/heads/main")))
+		assert.ErrorContains(t, err, "no workload identity rule")
+	})
+
+	t.Run("identity does not exist", func(t *testing.T) {
+		_, _, err := authenticate(t, github.Token(t, claims("other/repo", "refs/heads/main")))
+		assert.Assert(t, errors.Is(err, internal.ErrNotFound), err)
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		c := claims("infrahq/infra", "refs/heads/main")
+		c["aud"] = "https://other.example.com"
+		_, _, err := authenticate(t, github.Token(t, c))
+		assert.Assert(t, errors.Is(err, providers.ErrUnauthorized), err)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		c := claims("infrahq/infra", "refs/heads/main")
+		c["exp"] = time.Now().Add(-time.Minute).Unix()
+		_, _, err := authenticate(t, github.Token(t, c))
+		assert.Assert(t, errors.Is(err, providers.ErrUnauthorized), err)
+	})
+
+	t.Run("untrusted issuer", func(t *testing.T) {
+		other := jwkstest.NewIssuer(t)
+		_, _, err := authenticate(t, other.Token(t, claims("infrahq/infra", "refs/heads/main")))
+		assert.ErrorContains(t, err, "is not trusted")
+	})
+
+	t.Run("signed by another key", func(t *testing.T) {
+		other := jwkstest.NewIssuer(t)
+		c := claims("infrahq/infra", "refs/heads/main")
+		c["iss"] = github.URL
+		_, _, err := authenticate(t, other.Token(t, c))
+		assert.Assert(t, errors.Is(err, providers.ErrUnauthorized), err)
+	})
+
+	t.Run("not a token", func(t *testing.T) {
+		_, _, err := authenticate(t, "not-a-token")
+		assert.Assert(t, errors.Is(err, providers.ErrUnauthorized), err)
+	})
+}
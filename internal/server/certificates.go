@@ -0,0 +1,71 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/pki"
+)
+
+// loadCertificates loads the CAs which sign client certificates. The first CA
+// is created the first time the server starts.
+func (s *Server) loadCertificates() error {
+	certificates, err := pki.NewNativeCertificateProvider(s.db, pki.NativeCertificateProviderConfig{})
+	if err != nil {
+		return err
+	}
+
+	if len(certificates.ActiveCAs()) == 0 {
+		logging.Infof("creating client certificate authority")
+		if err := certificates.CreateCA(); err != nil {
+			return fmt.Errorf("create certificate authority: %w", err)
+		}
+	}
+
+	s.certificates = certificates
+	return nil
+}
+
+// rotateCertificateAuthority rotates the CA once the newest CA has reached
+// half of its lifetime. The previous CA stays active until it expires, so that
+// the certificates which it signed can still be used.
+func (s *Server) rotateCertificateAuthority(context.Context) {
+	cas := s.certificates.ActiveCAs()
+	if len(cas) == 0 {
+		return
+	}
+
+	newest := cas[len(cas)-1]
+	if time.Now().Before(newest.NotBefore.Add(newest.NotAfter.Sub(newest.NotBefore) / 2)) {
+		return
+	}
+
+	logging.Infof("rotating client certificate authority")
+	if err := s.certificates.RotateCA(); err != nil {
+		logging.Errorf("failed to rotate client certificate authority: %v", err)
+	}
+}
+
+// withClientCertificates verifies client certificates, when the client sends
+// one, with the active CAs. The CAs are read for each connection so that a
+// rotation is used without restarting the server.
+func withClientCertificates(cfg *tls.Config, certificates pki.CertificateProvider) *tls.Config {
+	cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	cfg.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		pool := x509.NewCertPool()
+		for _, ca := range certificates.ActiveCAs() {
+			ca := ca
+			pool.AddCert(&ca)
+		}
+
+		clientCfg := cfg.Clone()
+		clientCfg.GetConfigForClient = nil
+		clientCfg.ClientCAs = pool
+		return clientCfg, nil
+	}
+	return cfg
+}
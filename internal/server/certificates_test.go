@@ -0,0 +1,207 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/pki"
+)
+
+func TestAPI_Certificates(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	admin, err := data.GetIdentity(srv.db, data.ByName("admin@example.com"))
+	assert.NilError(t, err)
+
+	call := func(t *testing.T, method, path, key string, cert *x509.Certificate, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		if key != "" {
+			req.Header.Add("Authorization", "Bearer "+key)
+		}
+		if cert != nil {
+			req.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}
+		}
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	csr := func(t *testing.T, commonName string) string {
+		t.Helper()
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		assert.NilError(t, err)
+
+		raw, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+			Subject: pkix.Name{CommonName: commonName},
+		}, key)
+		assert.NilError(t, err)
+
+		return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE REQUEST", Bytes: raw}))
+	}
+
+	createCertificateWithKey := func(t *testing.T, key string) *x509.Certificate {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/certificates", key, nil, api.CreateCertificateRequest{CSR: csr(t, "anything")})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var created api.Certificate
+		err := json.Unmarshal(resp.Body.Bytes(), &created)
+		assert.NilError(t, err)
+
+		block, _ := pem.Decode([]byte(created.Certificate))
+		assert.Assert(t, block != nil)
+		cert, err := x509.ParseCertificate(block.Bytes)
+		assert.NilError(t, err)
+		return cert
+	}
+
+	createCertificate := func(t *testing.T) *x509.Certificate {
+		t.Helper()
+		return createCertificateWithKey(t, adminAccessKey(srv))
+	}
+
+	createKey := func(t *testing.T, scopes ...string) (string, *models.AccessKey) {
+		t.Helper()
+		key := &models.AccessKey{
+			IssuedFor:  admin.ID,
+			ProviderID: data.InfraProvider(srv.db).ID,
+			ExpiresAt:  time.Now().Add(time.Hour),
+			Scopes:     scopes,
+		}
+		secret, err := data.CreateAccessKey(srv.db, key)
+		assert.NilError(t, err)
+		return secret, key
+	}
+
+	t.Run("list certificate authorities", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/certificate-authorities", "", nil, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var cas api.ListResponse[api.CertificateAuthority]
+		err := json.Unmarshal(resp.Body.Bytes(), &cas)
+		assert.NilError(t, err)
+		assert.Equal(t, cas.Count, 2)
+
+		active := srv.certificates.ActiveCAs()
+		block, _ := pem.Decode([]byte(cas.Items[1].Certificate))
+		assert.Assert(t, block != nil)
+		assert.DeepEqual(t, block.Bytes, active[1].Raw)
+	})
+
+	t.Run("create certificate", func(t *testing.T) {
+		cert := createCertificate(t)
+		assert.Equal(t, cert.Subject.CommonName, "User "+admin.ID.String())
+		assert.DeepEqual(t, cert.EmailAddresses, []string{"admin@example.com"})
+		assert.Assert(t, cert.NotAfter.Before(time.Now().Add(25*time.Hour)))
+	})
+
+	t.Run("create certificate with invalid csr", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/certificates", adminAccessKey(srv), nil, api.CreateCertificateRequest{CSR: "not a csr"})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("authenticate with certificate", func(t *testing.T) {
+		cert := createCertificate(t)
+
+		resp := call(t, http.MethodGet, "/api/users/"+admin.ID.String(), "", cert, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		// a certificate can not renew itself
+		resp = call(t, http.MethodPost, "/api/certificates", "", cert, api.CreateCertificateRequest{CSR: csr(t, "anything")})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("login with certificate", func(t *testing.T) {
+		cert := createCertificate(t)
+
+		resp := call(t, http.MethodPost, "/api/login", "", cert, api.LoginRequest{ClientCertificate: true})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var loginResp api.LoginResponse
+		err := json.Unmarshal(resp.Body.Bytes(), &loginResp)
+		assert.NilError(t, err)
+		assert.Equal(t, loginResp.UserID, admin.ID)
+		assert.Assert(t, loginResp.AccessKey != "")
+
+		resp = call(t, http.MethodPost, "/api/login", "", nil, api.LoginRequest{ClientCertificate: true})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("create certificate with a scoped access key", func(t *testing.T) {
+		key, _ := createKey(t, "certificates:write")
+
+		resp := call(t, http.MethodPost, "/api/certificates", key, nil, api.CreateCertificateRequest{CSR: csr(t, "anything")})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("certificate of a revoked session", func(t *testing.T) {
+		key, session := createKey(t)
+		cert := createCertificateWithKey(t, key)
+		assert.Equal(t, cert.Subject.SerialNumber, session.ID.String())
+
+		resp := call(t, http.MethodGet, "/api/users/"+admin.ID.String(), "", cert, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		assert.NilError(t, data.DeleteAccessKey(srv.db, session.ID))
+
+		resp = call(t, http.MethodGet, "/api/users/"+admin.ID.String(), "", cert, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		resp = call(t, http.MethodPost, "/api/login", "", cert, api.LoginRequest{ClientCertificate: true})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("certificate of an expired session", func(t *testing.T) {
+		key, session := createKey(t)
+		cert := createCertificateWithKey(t, key)
+
+		session.ExpiresAt = time.Now().Add(-time.Minute)
+		assert.NilError(t, data.SaveAccessKey(srv.db, session))
+
+		resp := call(t, http.MethodGet, "/api/users/"+admin.ID.String(), "", cert, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("certificate signed by another CA", func(t *testing.T) {
+		keyPair, err := pki.MakeUserCert("User "+admin.ID.String(), time.Hour)
+		assert.NilError(t, err)
+
+		resp := call(t, http.MethodGet, "/api/users/"+admin.ID.String(), "", keyPair.Cert, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		resp = call(t, http.MethodPost, "/api/login", "", keyPair.Cert, api.LoginRequest{ClientCertificate: true})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("certificate of a rotated CA", func(t *testing.T) {
+		cert := createCertificate(t)
+
+		// the previous CA is still active after one rotation
+		assert.NilError(t, srv.certificates.RotateCA())
+		resp := call(t, http.MethodGet, "/api/users/"+admin.ID.String(), "", cert, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		assert.NilError(t, srv.certificates.RotateCA())
+		resp = call(t, http.MethodGet, "/api/users/"+admin.ID.String(), "", cert, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+}
@@ -739,7 +739,7 @@ func (Server) loadGrant(db *gorm.DB, input Grant) (*models.Grant, error) {
 		input.Role = models.BasePermissionConnect
 	}
 
-	grant, err := data.GetGrant(db, data.BySubject(id), data.ByResource(input.Resource), data.ByPrivilege(input.Role))
+	grant, err := data.GetGrant(db, data.BySubject(id), data.ByExactResource(input.Resource), data.ByPrivilege(input.Role))
 	if err != nil {
 		if !errors.Is(err, internal.ErrNotFound) {
 			return nil, err
@@ -9,6 +9,7 @@ import (
 
 	"github.com/ssoroka/slice"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
 	"github.com/infrahq/infra/internal/generate"
 	"github.com/infrahq/infra/internal/server/models"
@@ -141,3 +142,23 @@ func ValidateAccessKey(db *gorm.DB, authnKey string) (*models.AccessKey, error)
 
 	return t, nil
 }
+
+// BySession selects the access keys which were issued at login, excluding the
+// challenges of logins which require multi-factor authentication.
+func BySession() SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("login_method <> ''").Where("scopes IS NULL OR scopes NOT LIKE ?", "%"+models.ScopeMFAChallenge+"%")
+	}
+}
+
+// ListSessions returns the sessions of the identity which have not expired,
+// oldest first. The default sort of access keys is by their random name.
+func ListSessions(db *gorm.DB, identityID uid.ID) ([]models.AccessKey, error) {
+	return list[models.AccessKey](db, &models.Pagination{}, ByIssuedFor(identityID), BySession(), ByNotExpiredOrExtended(), byCreatedAt())
+}
+
+func byCreatedAt() SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderByColumn{Column: clause.Column{Name: "created_at"}, Reorder: true}).Order("id")
+	}
+}
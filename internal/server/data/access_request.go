@@ -0,0 +1,44 @@
+package data
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateAccessRequest(db *gorm.DB, request *models.AccessRequest) error {
+	return add(db, request)
+}
+
+func GetAccessRequest(db *gorm.DB, selectors ...SelectorFunc) (*models.AccessRequest, error) {
+	return get[models.AccessRequest](db, selectors...)
+}
+
+func ListAccessRequests(db *gorm.DB, p *models.Pagination, selectors ...SelectorFunc) ([]models.AccessRequest, error) {
+	return list[models.AccessRequest](db, p, selectors...)
+}
+
+func SaveAccessRequest(db *gorm.DB, request *models.AccessRequest) error {
+	return save(db, request)
+}
+
+func ByOptionalRequestedBy(id uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if id == 0 {
+			return db
+		}
+
+		return db.Where("requested_by = ?", id)
+	}
+}
+
+func ByOptionalStatus(status string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if status == "" {
+			return db
+		}
+
+		return db.Where("status = ?", status)
+	}
+}
@@ -0,0 +1,157 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// CreateAccessReview creates the campaign, and an item for each of the grants
+// under review.
+func CreateAccessReview(db *gorm.DB, review *models.AccessReview, items []models.AccessReviewItem) error {
+	// the reviewers already exist, only the memberships are created
+	if err := add(db.Omit("Reviewers.*"), review); err != nil {
+		return err
+	}
+
+	for i := range items {
+		items[i].AccessReviewID = review.ID
+		if err := add(db, &items[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func GetAccessReview(db *gorm.DB, selectors ...SelectorFunc) (*models.AccessReview, error) {
+	return get[models.AccessReview](db.Preload("Reviewers"), selectors...)
+}
+
+func ListAccessReviews(db *gorm.DB, p *models.Pagination, selectors ...SelectorFunc) ([]models.AccessReview, error) {
+	return list[models.AccessReview](db.Preload("Reviewers"), p, selectors...)
+}
+
+func SaveAccessReview(db *gorm.DB, review *models.AccessReview) error {
+	return save(db.Omit(clause.Associations), review)
+}
+
+func GetAccessReviewItem(db *gorm.DB, selectors ...SelectorFunc) (*models.AccessReviewItem, error) {
+	return get[models.AccessReviewItem](db, selectors...)
+}
+
+func ListAccessReviewItems(db *gorm.DB, p *models.Pagination, selectors ...SelectorFunc) ([]models.AccessReviewItem, error) {
+	return list[models.AccessReviewItem](db, p, selectors...)
+}
+
+func SaveAccessReviewItem(db *gorm.DB, item *models.AccessReviewItem) error {
+	return save(db, item)
+}
+
+// ByReviewer selects the access reviews which the identity may review.
+func ByReviewer(id uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		reviews := db.Session(&gorm.Session{NewDB: true}).
+			Table("access_reviews_reviewers").
+			Select("access_review_id").
+			Where("identity_id = ?", id)
+		return db.Where("id IN (?)", reviews)
+	}
+}
+
+func ByAccessReview(id uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("access_review_id = ?", id)
+	}
+}
+
+func ByOptionalDecision(decision string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if decision == "" {
+			return db
+		}
+
+		return db.Where("decision = ?", decision)
+	}
+}
+
+// ByDeadlinePassed selects the access reviews whose deadline is before t.
+func ByDeadlinePassed(t time.Time) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("deadline <= ?", t)
+	}
+}
+
+// GrantsToGroupAndMembers selects the grants to the group, and the grants to
+// the users who are members of the group. Unlike GrantsInheritedBySubject, the
+// grants to other groups of the members are not included.
+func GrantsToGroupAndMembers(groupID uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		members := db.Session(&gorm.Session{NewDB: true}).
+			Table("identities_groups").
+			Select("identity_id").
+			Where("group_id = ?", groupID)
+		return db.Where("(subject = ? OR (subject LIKE 'i:%' AND subject_id IN (?)))",
+			uid.NewGroupPolymorphicID(groupID), members)
+	}
+}
+
+// CloseExpiredAccessReviews closes the open access reviews whose deadline has
+// passed. When a campaign has AutoRevoke set, the grants of the items which
+// are still pending are revoked.
+func CloseExpiredAccessReviews(db *gorm.DB) error {
+	now := time.Now().UTC()
+	reviews, err := list[models.AccessReview](db, &models.Pagination{},
+		ByOptionalStatus(models.AccessReviewStatusOpen),
+		ByDeadlinePassed(now))
+	if err != nil {
+		return err
+	}
+
+	for i := range reviews {
+		review := &reviews[i]
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if review.AutoRevoke {
+				if err := revokePendingAccessReviewItems(tx, review); err != nil {
+					return err
+				}
+			}
+
+			review.Status = models.AccessReviewStatusClosed
+			review.ClosedAt = now
+			return SaveAccessReview(tx, review)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func revokePendingAccessReviewItems(db *gorm.DB, review *models.AccessReview) error {
+	items, err := ListAccessReviewItems(db, &models.Pagination{},
+		ByAccessReview(review.ID),
+		ByOptionalDecision(models.AccessReviewDecisionPending))
+	if err != nil {
+		return err
+	}
+
+	for i := range items {
+		if err := DeleteGrants(db, ByID(items[i].GrantID)); err != nil {
+			return err
+		}
+
+		items[i].Decision = models.AccessReviewDecisionRevoke
+		items[i].DecidedAt = review.Deadline
+		if err := SaveAccessReviewItem(db, &items[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateAuditEvent(db *gorm.DB, event *models.AuditEvent) error {
+	return add(db, event)
+}
+
+func ListAuditEvents(db *gorm.DB, p *models.Pagination, selectors ...SelectorFunc) ([]models.AuditEvent, error) {
+	return list[models.AuditEvent](db, p, selectors...)
+}
+
+func ByOptionalActorID(id uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if id == 0 {
+			return db
+		}
+
+		return db.Where("actor_id = ?", id)
+	}
+}
+
+func ByOptionalResourceType(resourceType string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if resourceType == "" {
+			return db
+		}
+
+		return db.Where("resource_type = ?", resourceType)
+	}
+}
+
+// ByOptionalCreatedBetween selects records created after since and before until.
+// A zero value for either time leaves that side of the range open.
+func ByOptionalCreatedBetween(since, until time.Time) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if !since.IsZero() {
+			db = db.Where("created_at >= ?", since)
+		}
+
+		if !until.IsZero() {
+			db = db.Where("created_at <= ?", until)
+		}
+
+		return db
+	}
+}
@@ -0,0 +1,49 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestListAuditEvents(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *gorm.DB) {
+		actor := uid.New()
+		events := []models.AuditEvent{
+			{ActorID: actor, Method: "POST", Route: "/api/grants", ResourceType: "grants", StatusCode: 201},
+			{ActorID: actor, Method: "DELETE", Route: "/api/users/:id", ResourceType: "users", StatusCode: 204},
+			{ActorID: uid.New(), Method: "POST", Route: "/api/grants", ResourceType: "grants", StatusCode: 403},
+		}
+		for i := range events {
+			assert.NilError(t, CreateAuditEvent(db, &events[i]))
+		}
+
+		actual, err := ListAuditEvents(db, &models.Pagination{}, ByOptionalActorID(actor))
+		assert.NilError(t, err)
+		assert.Equal(t, len(actual), 2)
+
+		actual, err = ListAuditEvents(db, &models.Pagination{}, ByOptionalResourceType("grants"))
+		assert.NilError(t, err)
+		assert.Equal(t, len(actual), 2)
+
+		actual, err = ListAuditEvents(db, &models.Pagination{},
+			ByOptionalActorID(actor),
+			ByOptionalResourceType("grants"))
+		assert.NilError(t, err)
+		assert.Equal(t, len(actual), 1)
+		assert.Equal(t, actual[0].StatusCode, 201)
+
+		actual, err = ListAuditEvents(db, &models.Pagination{}, ByOptionalCreatedBetween(time.Now().Add(time.Hour), time.Time{}))
+		assert.NilError(t, err)
+		assert.Equal(t, len(actual), 0)
+
+		actual, err = ListAuditEvents(db, &models.Pagination{}, ByOptionalCreatedBetween(time.Now().Add(-time.Hour), time.Now().Add(time.Hour)))
+		assert.NilError(t, err)
+		assert.Equal(t, len(actual), 3)
+	})
+}
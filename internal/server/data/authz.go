@@ -0,0 +1,19 @@
+package data
+
+import "sync/atomic"
+
+// authzGeneration is incremented whenever grants, roles, or group memberships
+// change, so that cached authorization decisions can be discarded.
+var authzGeneration uint64
+
+// AuthzGeneration returns the current generation of the data used to make
+// authorization decisions. A decision made at one generation is stale once
+// the generation changes.
+func AuthzGeneration() uint64 {
+	return atomic.LoadUint64(&authzGeneration)
+}
+
+// InvalidateAuthz marks all authorization decisions made before now as stale.
+func InvalidateAuthz() {
+	atomic.AddUint64(&authzGeneration, 1)
+}
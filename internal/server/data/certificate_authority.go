@@ -0,0 +1,21 @@
+package data
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func AddCertificateAuthority(db *gorm.DB, cert *models.CertificateAuthority) error {
+	return add(db, cert)
+}
+
+// ListCertificateAuthorities returns the certificate authorities which have not
+// expired, the newest is last.
+func ListCertificateAuthorities(db *gorm.DB) ([]models.CertificateAuthority, error) {
+	return list[models.CertificateAuthority](db, &models.Pagination{}, func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at > ?", time.Now().UTC())
+	})
+}
@@ -0,0 +1,43 @@
+package data
+
+import (
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateDeviceFlowAuthRequest(db *gorm.DB, request *models.DeviceFlowAuthRequest) error {
+	if request.DeviceCode == "" {
+		return fmt.Errorf("device code is required")
+	}
+
+	request.DeviceCodeChecksum = secretChecksum(request.DeviceCode)
+	return add(db, request)
+}
+
+func GetDeviceFlowAuthRequest(db *gorm.DB, selectors ...SelectorFunc) (*models.DeviceFlowAuthRequest, error) {
+	return get[models.DeviceFlowAuthRequest](db, selectors...)
+}
+
+func SaveDeviceFlowAuthRequest(db *gorm.DB, request *models.DeviceFlowAuthRequest) error {
+	return save(db, request)
+}
+
+func DeleteDeviceFlowAuthRequest(db *gorm.DB, id uid.ID) error {
+	return delete[models.DeviceFlowAuthRequest](db, id)
+}
+
+func ByUserCode(code string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("user_code = ?", code)
+	}
+}
+
+func ByDeviceCode(code string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("device_code_checksum = ?", secretChecksum(code))
+	}
+}
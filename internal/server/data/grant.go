@@ -1,14 +1,21 @@
 package data
 
 import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
 	"gorm.io/gorm"
 
 	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/resource"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
 )
 
 func CreateGrant(db *gorm.DB, grant *models.Grant) error {
+	InvalidateAuthz()
 	return add(db, grant)
 }
 
@@ -16,7 +23,18 @@ func GetGrant(db *gorm.DB, selectors ...SelectorFunc) (*models.Grant, error) {
 	return get[models.Grant](db, selectors...)
 }
 
+// ListGrants returns the grants that are currently active. Grants that have
+// expired, or that are not yet in effect, are excluded.
 func ListGrants(db *gorm.DB, p *models.Pagination, selectors ...SelectorFunc) ([]models.Grant, error) {
+	selectors = append([]SelectorFunc{ByActiveGrants()}, selectors...)
+	return list[models.Grant](db, p, selectors...)
+}
+
+// ListGrantsIncludingPending returns the grants that have not expired,
+// including grants that are not in effect yet, so that scheduled grants can be
+// seen and deleted before they start.
+func ListGrantsIncludingPending(db *gorm.DB, p *models.Pagination, selectors ...SelectorFunc) ([]models.Grant, error) {
+	selectors = append([]SelectorFunc{ByNotExpiredGrants()}, selectors...)
 	return list[models.Grant](db, p, selectors...)
 }
 
@@ -31,9 +49,47 @@ func DeleteGrants(db *gorm.DB, selectors ...SelectorFunc) error {
 		ids = append(ids, g.ID)
 	}
 
+	InvalidateAuthz()
 	return deleteAll[models.Grant](db, ByIDs(ids))
 }
 
+// DeleteExpiredGrants removes all grants which have expired.
+func DeleteExpiredGrants(db *gorm.DB) error {
+	return DeleteGrants(db, ByExpiredGrants())
+}
+
+// ByActiveGrants selects grants which have not expired, and have reached their
+// not before time.
+func ByActiveGrants() SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		now := time.Now().UTC()
+		return db.Where("(expires_at > ? OR expires_at = ? OR expires_at is null) AND (not_before <= ? OR not_before = ? OR not_before is null)",
+			now, time.Time{}, now, time.Time{})
+	}
+}
+
+// ByNotExpiredGrants selects grants which have not expired, including grants
+// which have not reached their not before time.
+func ByNotExpiredGrants() SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at > ? OR expires_at = ? OR expires_at is null", time.Now().UTC(), time.Time{})
+	}
+}
+
+// ByConditionalGrants selects grants which have conditions.
+func ByConditionalGrants() SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("conditions IS NOT NULL AND conditions != ''")
+	}
+}
+
+// ByExpiredGrants selects grants which have an expiry time in the past.
+func ByExpiredGrants() SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("expires_at <= ? AND expires_at != ?", time.Now().UTC(), time.Time{})
+	}
+}
+
 func ByOptionalPrivilege(s string) SelectorFunc {
 	return func(db *gorm.DB) *gorm.DB {
 		if s == "" {
@@ -53,45 +109,164 @@ func GrantsInheritedBySubject(subjectID uid.PolymorphicID) SelectorFunc {
 				logging.Errorf("invalid subject id %q", subjectID)
 				return db.Where("1 = 0")
 			}
-			var groupIDs []uid.ID
-			err = db.Session(&gorm.Session{NewDB: true}).Raw("select distinct group_id from identities_groups where identity_id = ?", userID).Pluck("group_id", &groupIDs).Error
+
+			// grants to the groups of the user, including the groups which
+			// contain those groups, are found by the subject_id of the grant,
+			// so that one query finds all the grants
+			return db.Where("(subject = ? OR (subject LIKE 'g:%' AND subject_id IN (?)))", subjectID, groupsOfIdentity(userID))
+		case subjectID.IsGroup():
+			groupID, err := subjectID.ID()
 			if err != nil {
-				logging.Errorf("GrantsInheritedByUser: %s", err)
-				_ = db.AddError(err)
+				logging.Errorf("invalid subject id %q", subjectID)
 				return db.Where("1 = 0")
 			}
 
-			subjects := []string{subjectID.String()}
-			for _, groupID := range groupIDs {
-				subjects = append(subjects, uid.NewGroupPolymorphicID(groupID).String())
-			}
-			return db.Where("subject in (?)", subjects)
-		case subjectID.IsGroup():
-			return BySubject(subjectID)(db)
+			// members of the group are also members of the groups which
+			// contain it
+			return db.Where("(subject = ? OR (subject LIKE 'g:%' AND subject_id IN (?)))", subjectID, groupsOfGroup(groupID))
 		default:
 			panic("unhandled subject type")
 		}
 	}
 }
 
+// ListGrantConditions returns the conditions of the active grants of any of the
+// privileges on the resource to the subject. When the subject is a user, grants
+// to the groups of the user are included. The subject has no grant when the
+// result is empty, and a grant without conditions has zero conditions. Unlike
+// ListGrants, the grants are resolved with a single query.
+func ListGrantConditions(db *gorm.DB, subject uid.PolymorphicID, privileges []string, resource string) ([]models.GrantConditions, error) {
+	bySubject := GrantsInheritedBySubject(subject)
+	if _, err := subject.ID(); err != nil {
+		// only grants to exactly this subject can match a subject which is
+		// not an ID, because it can not be a member of a group
+		bySubject = BySubject(subject)
+	}
+
+	selectors := []SelectorFunc{
+		ByActiveGrants(),
+		bySubject,
+		ByPrivileges(privileges),
+		ByResource(resource),
+	}
+
+	db = db.Model(&models.Grant{})
+	for _, selector := range selectors {
+		db = selector(db)
+	}
+
+	// grants created before conditions were added have null conditions
+	var values []sql.NullString
+	if err := db.Distinct("conditions").Pluck("conditions", &values).Error; err != nil {
+		return nil, err
+	}
+
+	conditions := make([]models.GrantConditions, len(values))
+	for i, value := range values {
+		if err := conditions[i].Scan(value.String); err != nil {
+			return nil, err
+		}
+	}
+
+	return conditions, nil
+}
+
 func ByPrivilege(s string) SelectorFunc {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Where("privilege = ?", s)
 	}
 }
 
+// ByPrivileges selects grants with any of the privileges.
+func ByPrivileges(privileges []string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("privilege IN (?)", privileges)
+	}
+}
+
+// ByOptionalResource selects grants which apply to the resource, when the
+// resource is not empty. See ByResource.
 func ByOptionalResource(s string) SelectorFunc {
 	return func(db *gorm.DB) *gorm.DB {
 		if s == "" {
 			return db
 		}
 
-		return db.Where("resource = ?", s)
+		return ByResource(s)(db)
 	}
 }
 
+// ByResource selects grants which apply to the resource. A grant applies to the
+// resource when the resource of the grant is the same, or is a pattern which
+// matches it. Patterns are evaluated the same way as resource.Match.
 func ByResource(s string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if resource.IsInfra(s) {
+			return db.Where("resource = ?", s)
+		}
+
+		return db.Where(
+			"resource = ? OR (resource LIKE '%*%' AND "+resourceSegmentsQuery("resource")+" = ? AND "+resourceMatchQuery(db, "resource")+")",
+			s, strings.Count(s, resource.Separator), s)
+	}
+}
+
+// ByExactResource selects grants with exactly this resource. Unlike ByResource,
+// a grant with a pattern only matches the identical pattern.
+func ByExactResource(s string) SelectorFunc {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Where("resource = ?", s)
 	}
 }
+
+// ByOptionalDestination selects grants which apply to the destination, or to
+// any of the namespaces of the destination, when the destination is not empty.
+func ByOptionalDestination(name string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if name == "" {
+			return db
+		}
+
+		if resource.IsInfra(name) {
+			return db.Where("resource = ?", name)
+		}
+
+		destination := resourceFirstSegmentQuery(db, "resource")
+		return db.Where(
+			"("+resourceSegmentsQuery("resource")+" = 0 AND "+resourceMatchQuery(db, "resource")+") OR "+
+				"("+resourceSegmentsQuery("resource")+" = 1 AND "+resourceMatchQuery(db, destination)+")",
+			name, name)
+	}
+}
+
+// resourceSegmentsQuery returns an SQL expression for the number of separators
+// in the resource column.
+func resourceSegmentsQuery(column string) string {
+	return fmt.Sprintf("(length(%[1]s) - length(replace(%[1]s, '.', '')))", column)
+}
+
+// resourceFirstSegmentQuery returns an SQL expression for the first segment of
+// the resource column, which is the destination name.
+func resourceFirstSegmentQuery(db *gorm.DB, column string) string {
+	if db.Dialector.Name() == "sqlite" {
+		return fmt.Sprintf("substr(%[1]s, 1, instr(%[1]s, '.') - 1)", column)
+	}
+
+	return fmt.Sprintf("split_part(%s, '.', 1)", column)
+}
+
+// resourceMatchQuery returns an SQL condition which is true when a resource,
+// given as a query parameter, is matched by the pattern in expr. The caller
+// must check the number of segments, because a wildcard in SQL also matches
+// the separator.
+func resourceMatchQuery(db *gorm.DB, expr string) string {
+	if db.Dialector.Name() == "sqlite" {
+		// GLOB is case sensitive, unlike LIKE in sqlite
+		return "? GLOB " + expr
+	}
+
+	// escape the characters which have a special meaning to LIKE, before
+	// replacing the wildcard
+	pattern := fmt.Sprintf(`replace(replace(replace(replace(%s, '\', '\\'), '%%', '\%%'), '_', '\_'), '*', '%%')`, expr)
+	return "? LIKE " + pattern + ` ESCAPE '\'`
+}
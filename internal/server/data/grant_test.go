@@ -1,13 +1,18 @@
 package data
 
 import (
+	"errors"
+	"sort"
 	"testing"
+	"time"
 
 	"gorm.io/gorm"
 	"gotest.tools/v3/assert"
 	is "gotest.tools/v3/assert/cmp"
 
+	"github.com/infrahq/infra/internal/claims"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 func TestDuplicateGrant(t *testing.T) {
@@ -47,3 +52,181 @@ func TestDuplicateGrant(t *testing.T) {
 		assert.NilError(t, err)
 	})
 }
+
+func TestListGrants_ExcludesInactive(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *gorm.DB) {
+		grants := []models.Grant{
+			{Subject: "i:1234567", Privilege: "view", Resource: "always"},
+			{Subject: "i:1234567", Privilege: "view", Resource: "active", ExpiresAt: time.Now().Add(time.Hour)},
+			{Subject: "i:1234567", Privilege: "view", Resource: "expired", ExpiresAt: time.Now().Add(-time.Minute)},
+			{Subject: "i:1234567", Privilege: "view", Resource: "future", NotBefore: time.Now().Add(time.Hour)},
+		}
+		for i := range grants {
+			assert.NilError(t, CreateGrant(db, &grants[i]))
+		}
+
+		actual, err := ListGrants(db, &models.Pagination{}, BySubject("i:1234567"))
+		assert.NilError(t, err)
+
+		var resources []string
+		for _, g := range actual {
+			resources = append(resources, g.Resource)
+		}
+		assert.DeepEqual(t, resources, []string{"always", "active"})
+
+		err = DeleteExpiredGrants(db)
+		assert.NilError(t, err)
+
+		_, err = GetGrant(db, ByID(grants[2].ID))
+		assert.ErrorContains(t, err, "record not found")
+
+		for _, i := range []int{0, 1, 3} {
+			_, err = GetGrant(db, ByID(grants[i].ID))
+			assert.NilError(t, err)
+		}
+
+		actual, err = ListGrantsIncludingPending(db, &models.Pagination{}, BySubject("i:1234567"))
+		assert.NilError(t, err)
+
+		resources = nil
+		for _, g := range actual {
+			resources = append(resources, g.Resource)
+		}
+		assert.DeepEqual(t, resources, []string{"always", "active", "future"})
+	})
+}
+
+func TestCreateGrant_ScheduledNextToCurrent(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *gorm.DB) {
+		current := &models.Grant{Subject: "i:1234567", Privilege: "view", Resource: "production"}
+		assert.NilError(t, CreateGrant(db, current))
+
+		start := time.Now().Add(24 * time.Hour)
+		scheduled := &models.Grant{Subject: "i:1234567", Privilege: "view", Resource: "production", NotBefore: start}
+		assert.NilError(t, CreateGrant(db, scheduled))
+
+		duplicate := &models.Grant{Subject: "i:1234567", Privilege: "view", Resource: "production", NotBefore: start}
+		var ucErr UniqueConstraintError
+		assert.Assert(t, errors.As(CreateGrant(db, duplicate), &ucErr))
+	})
+}
+
+func TestListGrants_ByResource(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *gorm.DB) {
+		for _, resource := range []string{"prod", "prod-*", "*.kube-system", "prod.*", "staging.*", "*", "infra"} {
+			err := CreateGrant(db, &models.Grant{Subject: "i:1234567", Privilege: "view", Resource: resource})
+			assert.NilError(t, err)
+		}
+
+		resources := func(t *testing.T, selector SelectorFunc) []string {
+			t.Helper()
+			actual, err := ListGrants(db, &models.Pagination{}, selector)
+			assert.NilError(t, err)
+
+			var resources []string
+			for _, g := range actual {
+				resources = append(resources, g.Resource)
+			}
+			return resources
+		}
+
+		t.Run("by resource", func(t *testing.T) {
+			assert.DeepEqual(t, resources(t, ByResource("prod")), []string{"prod", "*"})
+			assert.DeepEqual(t, resources(t, ByResource("prod-1")), []string{"prod-*", "*"})
+			assert.DeepEqual(t, resources(t, ByResource("prod.kube-system")), []string{"*.kube-system", "prod.*"})
+			assert.DeepEqual(t, resources(t, ByResource("infra")), []string{"infra"})
+		})
+
+		t.Run("by exact resource", func(t *testing.T) {
+			assert.DeepEqual(t, resources(t, ByExactResource("prod-*")), []string{"prod-*"})
+			assert.Assert(t, len(resources(t, ByExactResource("prod-1"))) == 0)
+		})
+
+		t.Run("by destination", func(t *testing.T) {
+			assert.DeepEqual(t, resources(t, ByOptionalDestination("prod")),
+				[]string{"prod", "*.kube-system", "prod.*", "*"})
+			assert.DeepEqual(t, resources(t, ByOptionalDestination("staging")),
+				[]string{"*.kube-system", "staging.*", "*"})
+			assert.DeepEqual(t, resources(t, ByOptionalDestination("infra")), []string{"infra"})
+		})
+	})
+}
+
+func TestListGrantConditions(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *gorm.DB) {
+		user := &models.Identity{Name: "hasgrant@example.com"}
+		assert.NilError(t, CreateIdentity(db, user))
+		other := &models.Identity{Name: "other@example.com"}
+		assert.NilError(t, CreateIdentity(db, other))
+
+		group := &models.Group{Name: "operators"}
+		assert.NilError(t, CreateGroup(db, group))
+		assert.NilError(t, AddUsersToGroup(db, group.ID, []uid.ID{user.ID}))
+
+		vpnOnly := models.GrantConditions{Conditions: claims.Conditions{CIDRs: []string{"10.0.0.0/8"}}}
+
+		grants := []models.Grant{
+			{Subject: user.PolyID(), Privilege: "view", Resource: "staging"},
+			{Subject: group.PolyID(), Privilege: "edit", Resource: "prod-*"},
+			{Subject: group.PolyID(), Privilege: "admin", Resource: "prod-1", ExpiresAt: time.Now().Add(-time.Minute)},
+			{Subject: user.PolyID(), Privilege: "admin", Resource: "dev", Conditions: vpnOnly},
+			{Subject: group.PolyID(), Privilege: "admin", Resource: "dev"},
+		}
+		for i := range grants {
+			assert.NilError(t, CreateGrant(db, &grants[i]))
+			assert.Equal(t, grants[i].SubjectID != 0, true)
+		}
+
+		type testCase struct {
+			subject    uid.PolymorphicID
+			privileges []string
+			resource   string
+			expected   bool
+		}
+
+		testCases := map[string]testCase{
+			"direct grant": {
+				subject: user.PolyID(), privileges: []string{"view"}, resource: "staging", expected: true,
+			},
+			"grant to group of user": {
+				subject: user.PolyID(), privileges: []string{"view", "edit"}, resource: "prod-1", expected: true,
+			},
+			"grant to group": {
+				subject: group.PolyID(), privileges: []string{"edit"}, resource: "prod-2", expected: true,
+			},
+			"group does not inherit grants of members": {
+				subject: group.PolyID(), privileges: []string{"view"}, resource: "staging", expected: false,
+			},
+			"user not in group": {
+				subject: other.PolyID(), privileges: []string{"edit"}, resource: "prod-1", expected: false,
+			},
+			"expired grant": {
+				subject: user.PolyID(), privileges: []string{"admin"}, resource: "prod-1", expected: false,
+			},
+			"other privilege": {
+				subject: user.PolyID(), privileges: []string{"admin"}, resource: "staging", expected: false,
+			},
+		}
+
+		for name, tc := range testCases {
+			t.Run(name, func(t *testing.T) {
+				actual, err := ListGrantConditions(db, tc.subject, tc.privileges, tc.resource)
+				assert.NilError(t, err)
+				assert.Equal(t, len(actual) > 0, tc.expected)
+			})
+		}
+
+		t.Run("conditions", func(t *testing.T) {
+			actual, err := ListGrantConditions(db, user.PolyID(), []string{"admin"}, "dev")
+			assert.NilError(t, err)
+			sort.Slice(actual, func(i, j int) bool {
+				return len(actual[i].CIDRs) < len(actual[j].CIDRs)
+			})
+			assert.DeepEqual(t, actual, []models.GrantConditions{{}, vpnOnly})
+
+			actual, err = ListGrantConditions(db, group.PolyID(), []string{"admin"}, "dev")
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, []models.GrantConditions{{}})
+		})
+	})
+}
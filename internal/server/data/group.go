@@ -1,8 +1,12 @@
 package data
 
 import (
+	"fmt"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 
+	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
 )
@@ -19,12 +23,103 @@ func ListGroups(db *gorm.DB, p *models.Pagination, selectors ...SelectorFunc) ([
 	return list[models.Group](db, p, selectors...)
 }
 
+func SaveGroup(db *gorm.DB, group *models.Group) error {
+	return save(db, group)
+}
+
+// ByGroupMember selects the groups which the identity is a member of, either
+// directly or through a group which is a member of another group.
 func ByGroupMember(id uid.ID) SelectorFunc {
 	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("groups.id IN (?)", groupsOfIdentity(id))
+	}
+}
+
+// ByOptionalMemberOfGroup selects the groups which are direct members of the
+// group.
+func ByOptionalMemberOfGroup(groupID uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if groupID == 0 {
+			return db
+		}
 		return db.
-			Joins("JOIN identities_groups ON groups.id = identities_groups.group_id").
-			Where("identities_groups.identity_id = ?", id)
+			Joins("JOIN groups_groups ON groups.id = groups_groups.member_group_id").
+			Where("groups_groups.group_id = ?", groupID)
+	}
+}
+
+// memberOfQuery finds the groups which contain the groups selected by the
+// query it is formatted with, and the groups which contain those, and so on.
+// UNION discards rows which were already found, so a cycle of groups ends the
+// recursion.
+const memberOfQuery = `WITH RECURSIVE member_of(id) AS (
+	%s
+	UNION
+	SELECT groups_groups.group_id FROM groups_groups JOIN member_of ON groups_groups.member_group_id = member_of.id
+) SELECT id FROM member_of`
+
+// groupsOfIdentity returns a query for the IDs of the groups which the
+// identity is a member of, directly or transitively.
+func groupsOfIdentity(identityID uid.ID) clause.Expr {
+	return gorm.Expr(fmt.Sprintf(memberOfQuery, "SELECT group_id FROM identities_groups WHERE identity_id = ?"), identityID)
+}
+
+// groupsOfGroup returns a query for the IDs of the groups which the group is a
+// member of, directly or transitively. The group itself is not included.
+func groupsOfGroup(groupID uid.ID) clause.Expr {
+	return gorm.Expr(fmt.Sprintf(memberOfQuery, "SELECT group_id FROM groups_groups WHERE member_group_id = ?"), groupID)
+}
+
+// GroupMembershipPaths returns the shortest chain of group memberships from the
+// subject to each group it is a member of, directly or through other groups,
+// by the ID of the group. A chain starts with a group which the subject is a
+// direct member of, and ends with the group itself.
+func GroupMembershipPaths(db *gorm.DB, subject uid.PolymorphicID) (map[uid.ID][]uid.ID, error) {
+	subjectID, err := subject.ID()
+	if err != nil {
+		return nil, err
+	}
+
+	var direct []uid.ID
+	if subject.IsIdentity() {
+		err = db.Table("identities_groups").Where("identity_id = ?", subjectID).Pluck("group_id", &direct).Error
+	} else {
+		err = db.Table("groups_groups").Where("member_group_id = ?", subjectID).Pluck("group_id", &direct).Error
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	paths := map[uid.ID][]uid.ID{}
+	queue := make([]uid.ID, 0, len(direct))
+	for _, id := range direct {
+		if _, ok := paths[id]; !ok {
+			paths[id] = []uid.ID{id}
+			queue = append(queue, id)
+		}
+	}
+
+	// breadth first, so that the first chain found to a group is the shortest.
+	// A cycle of groups ends at a group which was already found.
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+
+		var parents []uid.ID
+		if err := db.Table("groups_groups").Where("member_group_id = ?", current).Pluck("group_id", &parents).Error; err != nil {
+			return nil, err
+		}
+
+		for _, id := range parents {
+			if _, ok := paths[id]; ok || (subject.IsGroup() && id == subjectID) {
+				continue
+			}
+			paths[id] = append(append([]uid.ID{}, paths[current]...), id)
+			queue = append(queue, id)
+		}
 	}
+
+	return paths, nil
 }
 
 func DeleteGroups(db *gorm.DB, selectors ...SelectorFunc) error {
@@ -55,12 +150,19 @@ func DeleteGroups(db *gorm.DB, selectors ...SelectorFunc) error {
 		if err != nil {
 			return err
 		}
+
+		InvalidateAuthz()
+		err = db.Exec("DELETE FROM groups_groups WHERE group_id = ? OR member_group_id = ?", g.ID, g.ID).Error
+		if err != nil {
+			return err
+		}
 	}
 
 	return deleteAll[models.Group](db, ByIDs(ids))
 }
 
 func AddUsersToGroup(db *gorm.DB, groupID uid.ID, idsToAdd []uid.ID) error {
+	InvalidateAuthz()
 	for _, id := range idsToAdd {
 		// This is effectively an "INSERT OR IGNORE" or "INSERT ... ON CONFLICT ... DO NOTHING" statement which
 		// works across both sqlite and postgres
@@ -73,6 +175,7 @@ func AddUsersToGroup(db *gorm.DB, groupID uid.ID, idsToAdd []uid.ID) error {
 }
 
 func RemoveUsersFromGroup(db *gorm.DB, groupID uid.ID, idsToRemove []uid.ID) error {
+	InvalidateAuthz()
 	for _, id := range idsToRemove {
 		err := db.Exec("DELETE FROM identities_groups WHERE identity_id = ? AND group_id = ?", id, groupID).Error
 		if err != nil {
@@ -81,3 +184,55 @@ func RemoveUsersFromGroup(db *gorm.DB, groupID uid.ID, idsToRemove []uid.ID) err
 	}
 	return nil
 }
+
+// AddGroupsToGroup makes the groups members of the group. A group can not be a
+// member of itself, directly or through other groups, so adding a group which
+// the group is already a member of returns an error.
+func AddGroupsToGroup(db *gorm.DB, groupID uid.ID, idsToAdd []uid.ID) error {
+	InvalidateAuthz()
+	return db.Transaction(func(tx *gorm.DB) error {
+		// Two transactions which each add one side of a cycle would both pass
+		// the check below, because neither sees the row inserted by the other.
+		// Locking the table serializes them, so that the second one sees the
+		// first one's row once it commits. Writes to sqlite are already
+		// serialized.
+		if tx.Dialector.Name() != "sqlite" {
+			if err := tx.Exec("LOCK TABLE groups_groups IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+				return err
+			}
+		}
+
+		for _, id := range idsToAdd {
+			if id == groupID {
+				return fmt.Errorf("%w: a group can not be a member of itself", internal.ErrBadRequest)
+			}
+
+			var cycle []uid.ID
+			err := tx.Model(&models.Group{}).Where("id = ? AND id IN (?)", id, groupsOfGroup(groupID)).Pluck("id", &cycle).Error
+			if err != nil {
+				return err
+			}
+			if len(cycle) > 0 {
+				return fmt.Errorf("%w: group %v already contains group %v, adding it would create a cycle", internal.ErrBadRequest, id, groupID)
+			}
+
+			// see AddUsersToGroup
+			err = tx.Exec("INSERT INTO groups_groups (group_id, member_group_id) SELECT ?, ? WHERE NOT EXISTS (SELECT 1 FROM groups_groups WHERE group_id = ? AND member_group_id = ?)", groupID, id, groupID, id).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func RemoveGroupsFromGroup(db *gorm.DB, groupID uid.ID, idsToRemove []uid.ID) error {
+	InvalidateAuthz()
+	for _, id := range idsToRemove {
+		err := db.Exec("DELETE FROM groups_groups WHERE member_group_id = ? AND group_id = ?", id, groupID).Error
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
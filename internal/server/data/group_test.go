@@ -7,6 +7,7 @@ import (
 	"gorm.io/gorm"
 	"gotest.tools/v3/assert"
 
+	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
 )
@@ -218,3 +219,93 @@ func TestAddUsersToGroup(t *testing.T) {
 		})
 	})
 }
+
+func TestAddGroupsToGroup(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *gorm.DB) {
+		var (
+			eng       = models.Group{Name: "eng"}
+			sre       = models.Group{Name: "sre"}
+			sreOncall = models.Group{Name: "sre-oncall"}
+			product   = models.Group{Name: "product"}
+		)
+		createGroups(t, db, &eng, &sre, &sreOncall, &product)
+
+		user := models.Identity{Name: "oncall@example.com", Groups: []models.Group{sreOncall}}
+		createIdentities(t, db, &user)
+
+		assert.NilError(t, AddGroupsToGroup(db, sre.ID, []uid.ID{sreOncall.ID}))
+		assert.NilError(t, AddGroupsToGroup(db, eng.ID, []uid.ID{sre.ID}))
+		// adding a group twice is a no-op
+		assert.NilError(t, AddGroupsToGroup(db, eng.ID, []uid.ID{sre.ID}))
+
+		t.Run("transitive membership", func(t *testing.T) {
+			actual, err := ListGroups(db, &models.Pagination{}, ByGroupMember(user.ID))
+			assert.NilError(t, err)
+			expected := []models.Group{{Name: "eng"}, {Name: "sre"}, {Name: "sre-oncall"}}
+			assert.DeepEqual(t, actual, expected, cmpGroupShallow)
+		})
+
+		t.Run("member groups", func(t *testing.T) {
+			actual, err := ListGroups(db, &models.Pagination{}, ByOptionalMemberOfGroup(eng.ID))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, []models.Group{{Name: "sre"}}, cmpGroupShallow)
+		})
+
+		t.Run("cycles are rejected", func(t *testing.T) {
+			err := AddGroupsToGroup(db, sreOncall.ID, []uid.ID{eng.ID})
+			assert.ErrorIs(t, err, internal.ErrBadRequest)
+
+			err = AddGroupsToGroup(db, sre.ID, []uid.ID{sre.ID})
+			assert.ErrorIs(t, err, internal.ErrBadRequest)
+
+			// a group which is not a parent is allowed
+			assert.NilError(t, AddGroupsToGroup(db, sreOncall.ID, []uid.ID{product.ID}))
+		})
+
+		t.Run("membership paths", func(t *testing.T) {
+			paths, err := GroupMembershipPaths(db, user.PolyID())
+			assert.NilError(t, err)
+			expected := map[uid.ID][]uid.ID{
+				sreOncall.ID: {sreOncall.ID},
+				sre.ID:       {sreOncall.ID, sre.ID},
+				eng.ID:       {sreOncall.ID, sre.ID, eng.ID},
+			}
+			assert.DeepEqual(t, paths, expected)
+		})
+
+		t.Run("inherited grants", func(t *testing.T) {
+			grant := models.Grant{Subject: eng.PolyID(), Privilege: "view", Resource: "prod"}
+			assert.NilError(t, CreateGrant(db, &grant))
+
+			grants, err := ListGrants(db, &models.Pagination{}, GrantsInheritedBySubject(user.PolyID()))
+			assert.NilError(t, err)
+			assert.Equal(t, len(grants), 1)
+			assert.Equal(t, grants[0].ID, grant.ID)
+
+			grants, err = ListGrants(db, &models.Pagination{}, GrantsInheritedBySubject(sreOncall.PolyID()))
+			assert.NilError(t, err)
+			assert.Equal(t, len(grants), 1)
+
+			// product was added to sre-oncall above
+			grants, err = ListGrants(db, &models.Pagination{}, GrantsInheritedBySubject(product.PolyID()))
+			assert.NilError(t, err)
+			assert.Equal(t, len(grants), 1)
+		})
+
+		t.Run("remove group", func(t *testing.T) {
+			assert.NilError(t, RemoveGroupsFromGroup(db, sre.ID, []uid.ID{sreOncall.ID}))
+
+			actual, err := ListGroups(db, &models.Pagination{}, ByGroupMember(user.ID))
+			assert.NilError(t, err)
+			assert.DeepEqual(t, actual, []models.Group{{Name: "sre-oncall"}}, cmpGroupShallow)
+		})
+
+		t.Run("delete group", func(t *testing.T) {
+			assert.NilError(t, DeleteGroups(db, ByID(sreOncall.ID)))
+
+			actual, err := ListGroups(db, &models.Pagination{}, ByOptionalMemberOfGroup(sreOncall.ID))
+			assert.NilError(t, err)
+			assert.Equal(t, len(actual), 0)
+		})
+	})
+}
@@ -20,6 +20,9 @@ func AssignIdentityToGroups(db *gorm.DB, user *models.Identity, provider *models
 	oldGroups := pu.Groups
 	groupsToBeRemoved := slice.Subtract(oldGroups, newGroups)
 	groupsToBeAdded := slice.Subtract(newGroups, oldGroups)
+	if len(groupsToBeRemoved) > 0 || len(groupsToBeAdded) > 0 {
+		InvalidateAuthz()
+	}
 
 	pu.Groups = newGroups
 	pu.LastUpdate = time.Now().UTC()
@@ -0,0 +1,30 @@
+package data
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func CreateLoginFailure(db *gorm.DB, failure *models.LoginFailure) error {
+	return add(db, failure)
+}
+
+func SaveLoginFailure(db *gorm.DB, failure *models.LoginFailure) error {
+	return save(db, failure)
+}
+
+func GetLoginFailure(db *gorm.DB, selectors ...SelectorFunc) (*models.LoginFailure, error) {
+	return get[models.LoginFailure](db, selectors...)
+}
+
+func DeleteLoginFailures(db *gorm.DB, selectors ...SelectorFunc) error {
+	return deleteAll[models.LoginFailure](db, selectors...)
+}
+
+// ByLoginFailureKey selects the failed logins of a username or a client IP.
+func ByLoginFailureKey(kind, key string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("kind = ? AND key = ?", kind, key)
+	}
+}
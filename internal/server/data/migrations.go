@@ -249,7 +249,7 @@ func migrate(db *gorm.DB) error {
 			Migrate: func(tx *gorm.DB) error {
 				logging.Infof("running migration 202203301647")
 				if tx.Migrator().HasTable("machines") {
-					grants, err := ListGrants(db, &models.Pagination{})
+					grants, err := list[models.Grant](db, &models.Pagination{})
 					if err != nil {
 						return err
 					}
@@ -476,6 +476,8 @@ func migrate(db *gorm.DB) error {
 		addAuthURLAndScopeToProviders(),
 		setDestinationLastSeenAt(),
 		deleteDuplicateGrants(),
+		addGrantSubjectID(),
+		addNotBeforeToGrantIndex(),
 		// next one here
 	})
 
@@ -513,6 +515,17 @@ func initializeSchema(db *gorm.DB) error {
 		&models.EncryptionKey{},
 		&models.Credential{},
 		&models.ProviderUser{},
+		&models.AuditEvent{},
+		&models.AccessRequest{},
+		&models.Role{},
+		&models.LoginFailure{},
+		&models.DeviceFlowAuthRequest{},
+		&models.SAMLAuthnRequest{},
+		&models.CertificateAuthority{},
+		&models.OIDCClient{},
+		&models.OIDCAuthorizationCode{},
+		&models.AccessReview{},
+		&models.AccessReviewItem{},
 	}
 
 	for _, table := range tables {
@@ -616,6 +629,65 @@ func deleteDuplicateGrants() *gormigrate.Migration {
 	}}
 }
 
+// addGrantSubjectID adds the subject_id column to grants, and sets it from the
+// subject of existing grants.
+func addGrantSubjectID() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202210181200",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.Grant{}, "subject_id") {
+				if err := tx.Migrator().AddColumn(&models.Grant{}, "subject_id"); err != nil {
+					return err
+				}
+			}
+
+			var grants []models.Grant
+			if err := tx.Unscoped().Select("id", "subject").Find(&grants).Error; err != nil {
+				return err
+			}
+
+			for _, grant := range grants {
+				subjectID, err := grant.Subject.ID()
+				if err != nil {
+					logging.Warnf("grant %v has an invalid subject %q", grant.ID, grant.Subject)
+					continue
+				}
+
+				if err := tx.Exec("UPDATE grants SET subject_id = ? WHERE id = ?", subjectID, grant.ID).Error; err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+	}
+}
+
+// addNotBeforeToGrantIndex drops the unique index of grants, so that it is
+// created again with the not_before column. Grants from before not_before was
+// added have no value, which would not be unique in the index.
+func addNotBeforeToGrantIndex() *gormigrate.Migration {
+	return &gormigrate.Migration{
+		ID: "202210181300",
+		Migrate: func(tx *gorm.DB) error {
+			if !tx.Migrator().HasColumn(&models.Grant{}, "not_before") {
+				if err := tx.Migrator().AddColumn(&models.Grant{}, "not_before"); err != nil {
+					return err
+				}
+			}
+
+			if err := tx.Exec("UPDATE grants SET not_before = ? WHERE not_before IS NULL", time.Time{}).Error; err != nil {
+				return err
+			}
+
+			if tx.Migrator().HasIndex(&models.Grant{}, "idx_grant_srp") {
+				return tx.Migrator().DropIndex(&models.Grant{}, "idx_grant_srp")
+			}
+			return nil
+		},
+	}
+}
+
 // setDestinationLastSeenAt creates the `last_seen_at` column if it does not exist and sets it to
 // the destination's `updated_at` value. No effect if the `last_seen_at` exists
 func setDestinationLastSeenAt() *gormigrate.Migration {
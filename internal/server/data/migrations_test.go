@@ -1,10 +1,12 @@
 package data
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	gocmp "github.com/google/go-cmp/cmp"
 	"github.com/rs/zerolog"
@@ -15,6 +17,7 @@ import (
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/internal/testing/patch"
+	"github.com/infrahq/infra/uid"
 )
 
 func TestMigration_SettingsPopulatePasswordDefaults(t *testing.T) {
@@ -248,3 +251,63 @@ func TestMigration_SetDestinationLastSeenAt(t *testing.T) {
 		})
 	}
 }
+
+func TestMigration_AddGrantSubjectID(t *testing.T) {
+	for _, driver := range dbDrivers(t) {
+		t.Run(driver.Name(), func(t *testing.T) {
+			db, err := newRawDB(driver)
+			assert.NilError(t, err)
+
+			patch.ModelsSymmetricKey(t)
+			logging.PatchLogger(t, zerolog.NewTestWriter(t))
+
+			loadSQL(t, db, "202207120000-"+driver.Name())
+
+			user := uid.New()
+			group := uid.New()
+			err = db.Exec("INSERT INTO grants (id, subject, privilege, resource) VALUES (?, ?, 'view', 'infra'), (?, ?, 'edit', 'production')",
+				uid.New(), uid.NewIdentityPolymorphicID(user), uid.New(), uid.NewGroupPolymorphicID(group)).Error
+			assert.NilError(t, err)
+
+			db, err = NewDB(driver, nil)
+			assert.NilError(t, err)
+
+			var grants []models.Grant
+			err = db.Order("privilege").Find(&grants).Error
+			assert.NilError(t, err)
+
+			assert.Equal(t, len(grants), 2)
+			assert.Equal(t, grants[0].SubjectID, group)
+			assert.Equal(t, grants[1].SubjectID, user)
+		})
+	}
+}
+
+func TestMigration_AddNotBeforeToGrantIndex(t *testing.T) {
+	for _, driver := range dbDrivers(t) {
+		t.Run(driver.Name(), func(t *testing.T) {
+			db, err := newRawDB(driver)
+			assert.NilError(t, err)
+
+			patch.ModelsSymmetricKey(t)
+			logging.PatchLogger(t, zerolog.NewTestWriter(t))
+
+			loadSQL(t, db, "202207120000-"+driver.Name())
+
+			user := uid.NewIdentityPolymorphicID(uid.New())
+			err = db.Exec("INSERT INTO grants (id, subject, privilege, resource) VALUES (?, ?, 'view', 'production')", uid.New(), user).Error
+			assert.NilError(t, err)
+
+			db, err = NewDB(driver, nil)
+			assert.NilError(t, err)
+
+			// the same grant can be scheduled to start later
+			err = CreateGrant(db, &models.Grant{Subject: user, Privilege: "view", Resource: "production", NotBefore: time.Now().Add(time.Hour)})
+			assert.NilError(t, err)
+
+			var ucErr UniqueConstraintError
+			err = CreateGrant(db, &models.Grant{Subject: user, Privilege: "view", Resource: "production"})
+			assert.Assert(t, errors.As(err, &ucErr), "expected a unique constraint error, got %v", err)
+		})
+	}
+}
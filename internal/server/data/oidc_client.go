@@ -0,0 +1,99 @@
+package data
+
+import (
+	"crypto/subtle"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/generate"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+// CreateOIDCClient creates the client with a new client ID, and a new secret
+// unless the client is public. The key which signs the tokens of the client
+// is generated if this is the first client to use its algorithm.
+func CreateOIDCClient(db *gorm.DB, client *models.OIDCClient, public bool) error {
+	if client.SigningAlgorithm == "" {
+		client.SigningAlgorithm = "RS256"
+	}
+
+	keyAlgorithm, ok := keyAlgorithmFromSignatureAlgorithm(client.SigningAlgorithm)
+	if !ok {
+		return fmt.Errorf("unsupported signing algorithm %q", client.SigningAlgorithm)
+	}
+
+	if err := initializeSigningKey(db, keyAlgorithm); err != nil {
+		return fmt.Errorf("signing key: %w", err)
+	}
+
+	var err error
+	if client.ClientID, err = generate.CryptoRandom(24, generate.CharsetAlphaNumeric); err != nil {
+		return err
+	}
+
+	if !public {
+		if client.ClientSecret, err = generate.CryptoRandom(40, generate.CharsetAlphaNumeric); err != nil {
+			return err
+		}
+		client.ClientSecretChecksum = secretChecksum(client.ClientSecret)
+	}
+
+	return add(db, client)
+}
+
+func GetOIDCClient(db *gorm.DB, selectors ...SelectorFunc) (*models.OIDCClient, error) {
+	return get[models.OIDCClient](db, selectors...)
+}
+
+func ListOIDCClients(db *gorm.DB, p *models.Pagination, selectors ...SelectorFunc) ([]models.OIDCClient, error) {
+	return list[models.OIDCClient](db, p, selectors...)
+}
+
+func DeleteOIDCClient(db *gorm.DB, id uid.ID) error {
+	byClient := func(db *gorm.DB) *gorm.DB {
+		return db.Where("client_id = ?", id)
+	}
+	if err := deleteAll[models.OIDCAuthorizationCode](db, byClient); err != nil {
+		return err
+	}
+
+	return delete[models.OIDCClient](db, id)
+}
+
+// ValidateOIDCClientSecret returns true if the secret is the secret of the
+// client.
+func ValidateOIDCClientSecret(client *models.OIDCClient, secret string) bool {
+	return subtle.ConstantTimeCompare(client.ClientSecretChecksum, secretChecksum(secret)) == 1
+}
+
+func ByClientID(clientID string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("client_id = ?", clientID)
+	}
+}
+
+func CreateOIDCAuthorizationCode(db *gorm.DB, code *models.OIDCAuthorizationCode) error {
+	var err error
+	if code.Code, err = generate.CryptoRandom(32, generate.CharsetAlphaNumeric); err != nil {
+		return err
+	}
+
+	code.CodeChecksum = secretChecksum(code.Code)
+	return add(db, code)
+}
+
+func GetOIDCAuthorizationCode(db *gorm.DB, selectors ...SelectorFunc) (*models.OIDCAuthorizationCode, error) {
+	return get[models.OIDCAuthorizationCode](db, selectors...)
+}
+
+func DeleteOIDCAuthorizationCode(db *gorm.DB, id uid.ID) error {
+	return delete[models.OIDCAuthorizationCode](db, id)
+}
+
+func ByOIDCAuthorizationCode(code string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("code_checksum = ?", secretChecksum(code))
+	}
+}
@@ -0,0 +1,67 @@
+package data
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateRole(db *gorm.DB, role *models.Role) error {
+	InvalidateAuthz()
+	return add(db, role)
+}
+
+func GetRole(db *gorm.DB, selectors ...SelectorFunc) (*models.Role, error) {
+	return get[models.Role](db, selectors...)
+}
+
+func ListRoles(db *gorm.DB, p *models.Pagination, selectors ...SelectorFunc) ([]models.Role, error) {
+	return list[models.Role](db, p, selectors...)
+}
+
+func SaveRole(db *gorm.DB, role *models.Role) error {
+	InvalidateAuthz()
+	return save(db, role)
+}
+
+// DeleteRoles removes the roles, and any grants of the roles.
+func DeleteRoles(db *gorm.DB, selectors ...SelectorFunc) error {
+	toDelete, err := ListRoles(db, &models.Pagination{}, selectors...)
+	if err != nil {
+		return err
+	}
+
+	ids := make([]uid.ID, 0)
+	for _, r := range toDelete {
+		ids = append(ids, r.ID)
+
+		if err := DeleteGrants(db, ByPrivilege(r.Name)); err != nil {
+			return err
+		}
+	}
+
+	InvalidateAuthz()
+	return deleteAll[models.Role](db, ByIDs(ids))
+}
+
+// RolesIncluding returns the names of the roles which include any of the
+// permissions.
+func RolesIncluding(db *gorm.DB, permissions ...string) ([]string, error) {
+	roles, err := ListRoles(db, &models.Pagination{})
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, role := range roles {
+		for _, permission := range permissions {
+			if role.Includes(permission) {
+				names = append(names, role.Name)
+				break
+			}
+		}
+	}
+
+	return names, nil
+}
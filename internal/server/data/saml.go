@@ -0,0 +1,44 @@
+package data
+
+import (
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func CreateSAMLAuthnRequest(db *gorm.DB, request *models.SAMLAuthnRequest) error {
+	if request.Code != "" {
+		request.CodeChecksum = secretChecksum(request.Code)
+	}
+	return add(db, request)
+}
+
+func GetSAMLAuthnRequest(db *gorm.DB, selectors ...SelectorFunc) (*models.SAMLAuthnRequest, error) {
+	return get[models.SAMLAuthnRequest](db, selectors...)
+}
+
+// SaveSAMLAuthnRequest saves the request, along with the checksum of its code
+// when the code is set.
+func SaveSAMLAuthnRequest(db *gorm.DB, request *models.SAMLAuthnRequest) error {
+	if request.Code != "" {
+		request.CodeChecksum = secretChecksum(request.Code)
+	}
+	return save(db, request)
+}
+
+func DeleteSAMLAuthnRequest(db *gorm.DB, id uid.ID) error {
+	return delete[models.SAMLAuthnRequest](db, id)
+}
+
+func ByRelayState(relayState string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("relay_state = ?", relayState)
+	}
+}
+
+func BySAMLCode(code string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("code_checksum = ?", secretChecksum(code))
+	}
+}
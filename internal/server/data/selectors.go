@@ -66,6 +66,16 @@ func ByOptionalUniqueID(nodeID string) SelectorFunc {
 	}
 }
 
+func ByOptionalKind(kind string) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		if kind == "" {
+			return db
+		}
+
+		return db.Where("kind = ?", kind)
+	}
+}
+
 func ByProviderID(id uid.ID) SelectorFunc {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Where("provider_id = ?", id)
@@ -202,3 +212,16 @@ func ByOptionalIdentityGroupID(groupID uid.ID) SelectorFunc {
 			Where("identities_groups.group_id = ?", groupID)
 	}
 }
+
+// ByProviderUser selects identities which have a user in the provider.
+func ByProviderUser(providerID uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("id IN (SELECT identity_id FROM provider_users WHERE provider_id = ? AND deleted_at IS NULL)", providerID)
+	}
+}
+
+func ByCreatedByProvider(providerID uid.ID) SelectorFunc {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("created_by_provider = ?", providerID)
+	}
+}
@@ -2,9 +2,13 @@ package data
 
 import (
 	"crypto"
+	"crypto/ecdsa"
 	"crypto/ed25519"
+	"crypto/elliptic"
 	"crypto/rand"
+	"crypto/rsa"
 	"encoding/base64"
+	"fmt"
 
 	"gopkg.in/square/go-jose.v2"
 	"gorm.io/gorm"
@@ -15,46 +19,165 @@ import (
 func InitializeSettings(db *gorm.DB) (*models.Settings, error) {
 	settings, err := GetSettings(db)
 	if settings != nil {
-		return settings, err
+		return settings, initializeSSHCertificateAuthority(db, settings)
 	}
 
-	pubkey, seckey, err := ed25519.GenerateKey(rand.Reader)
+	secs, pubs, err := generateJWK(string(jose.ED25519))
 	if err != nil {
 		return nil, err
 	}
 
-	sec := jose.JSONWebKey{Key: seckey, KeyID: "", Algorithm: string(jose.ED25519), Use: "sig"}
+	settings = &models.Settings{
+		PrivateJWK: secs,
+		PublicJWK:  pubs,
+	}
+
+	if err := generateSSHCertificateAuthority(settings); err != nil {
+		return nil, err
+	}
+
+	// Attrs() assigns the field iff the record is not found
+	if err := db.FirstOrCreate(&settings).Error; err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// initializeSSHCertificateAuthority generates the keys of the SSH certificate
+// authority for settings which were created without them.
+func initializeSSHCertificateAuthority(db *gorm.DB, settings *models.Settings) error {
+	if len(settings.SSHCAPrivateKey) > 0 {
+		return nil
+	}
+
+	if err := generateSSHCertificateAuthority(settings); err != nil {
+		return err
+	}
+
+	return SaveSettings(db, settings)
+}
+
+func generateSSHCertificateAuthority(settings *models.Settings) error {
+	pubkey, seckey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	settings.SSHCAPrivateKey = models.EncryptedAtRestBytes(seckey)
+	settings.SSHCAPublicKey = pubkey
+	return nil
+}
+
+// generateJWK returns a new private and public JSON web key for signing
+// tokens. The algorithm is ED25519, RS256, or ES256.
+func generateJWK(algorithm string) (private []byte, public []byte, err error) {
+	var seckey, pubkey any
+	switch algorithm {
+	case string(jose.ED25519):
+		pubkey, seckey, err = ed25519.GenerateKey(rand.Reader)
+	case string(jose.RS256):
+		var key *rsa.PrivateKey
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		if key != nil {
+			seckey, pubkey = key, key.Public()
+		}
+	case string(jose.ES256):
+		var key *ecdsa.PrivateKey
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		if key != nil {
+			seckey, pubkey = key, key.Public()
+		}
+	default:
+		return nil, nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sec := jose.JSONWebKey{Key: seckey, KeyID: "", Algorithm: algorithm, Use: "sig"}
 
 	thumb, err := sec.Thumbprint(crypto.SHA256)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	sec.KeyID = base64.URLEncoding.EncodeToString(thumb)
 
-	pub := jose.JSONWebKey{Key: pubkey, KeyID: sec.KeyID, Algorithm: string(jose.ED25519), Use: "sig"}
+	pub := jose.JSONWebKey{Key: pubkey, KeyID: sec.KeyID, Algorithm: algorithm, Use: "sig"}
+
+	if private, err = sec.MarshalJSON(); err != nil {
+		return nil, nil, err
+	}
+
+	if public, err = pub.MarshalJSON(); err != nil {
+		return nil, nil, err
+	}
 
-	secs, err := sec.MarshalJSON()
+	return private, public, nil
+}
+
+// settingsJWK returns the private and public keys of the settings for the key
+// algorithm.
+func settingsJWK(settings *models.Settings, algorithm string) (*models.EncryptedAtRestBytes, *[]byte, error) {
+	switch algorithm {
+	case string(jose.ED25519):
+		return &settings.PrivateJWK, &settings.PublicJWK, nil
+	case string(jose.RS256):
+		return &settings.RS256PrivateJWK, &settings.RS256PublicJWK, nil
+	case string(jose.ES256):
+		return &settings.ES256PrivateJWK, &settings.ES256PublicJWK, nil
+	}
+	return nil, nil, fmt.Errorf("unsupported algorithm %q", algorithm)
+}
+
+// initializeSigningKey generates the signing key for the key algorithm, if
+// the settings do not have one yet.
+func initializeSigningKey(db *gorm.DB, algorithm string) error {
+	settings, err := GetSettings(db)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	pubs, err := pub.MarshalJSON()
+	private, public, err := settingsJWK(settings, algorithm)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	settings = &models.Settings{
-		PrivateJWK: secs,
-		PublicJWK:  pubs,
+	if len(*private) > 0 {
+		return nil
 	}
 
-	// Attrs() assigns the field iff the record is not found
-	if err := db.FirstOrCreate(&settings).Error; err != nil {
+	if *private, *public, err = generateJWK(algorithm); err != nil {
+		return err
+	}
+
+	return SaveSettings(db, settings)
+}
+
+// PublicJWKs returns the public keys which sign tokens. The ED25519 key, which
+// signs the tokens of destinations, is first because connectors use the first
+// key.
+func PublicJWKs(db *gorm.DB) ([]jose.JSONWebKey, error) {
+	settings, err := GetSettings(db)
+	if err != nil {
 		return nil, err
 	}
 
-	return settings, nil
+	var keys []jose.JSONWebKey
+	for _, raw := range [][]byte{settings.PublicJWK, settings.RS256PublicJWK, settings.ES256PublicJWK} {
+		if len(raw) == 0 {
+			continue
+		}
+
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(raw); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+
+	return keys, nil
 }
 
 func GetSettings(db *gorm.DB) (*models.Settings, error) {
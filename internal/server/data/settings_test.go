@@ -23,6 +23,8 @@ func TestInitializeSettings(t *testing.T) {
 			assert.Assert(t, settings.ID != 0)
 			assert.Assert(t, len(settings.PrivateJWK) != 0)
 			assert.Assert(t, len(settings.PublicJWK) != 0)
+			assert.Assert(t, len(settings.SSHCAPrivateKey) != 0)
+			assert.Assert(t, len(settings.SSHCAPublicKey) != 0)
 		})
 
 		runStep(t, "next call returns existing settings", func(t *testing.T) {
@@ -30,6 +32,23 @@ func TestInitializeSettings(t *testing.T) {
 			assert.NilError(t, err)
 			assert.DeepEqual(t, settings, nextSettings, cmpModel)
 		})
+
+		runStep(t, "existing settings without an ssh ca are updated", func(t *testing.T) {
+			settings.SSHCAPrivateKey = nil
+			settings.SSHCAPublicKey = nil
+			err := SaveSettings(db, settings)
+			assert.NilError(t, err)
+
+			nextSettings, err := InitializeSettings(db)
+			assert.NilError(t, err)
+			assert.Equal(t, nextSettings.ID, settings.ID)
+			assert.Assert(t, len(nextSettings.SSHCAPrivateKey) != 0)
+			assert.Assert(t, len(nextSettings.SSHCAPublicKey) != 0)
+
+			stored, err := GetSettings(db)
+			assert.NilError(t, err)
+			assert.DeepEqual(t, stored.SSHCAPublicKey, nextSettings.SSHCAPublicKey)
+		})
 	})
 }
 
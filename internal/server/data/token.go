@@ -2,6 +2,7 @@ package data
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"gopkg.in/square/go-jose.v2"
@@ -16,16 +17,40 @@ import (
 
 var signatureAlgorithmFromKeyAlgorithm = map[string]string{
 	"ED25519": "EdDSA", // elliptic curve 25519
+	"RS256":   "RS256", // RSA with SHA-256
+	"ES256":   "ES256", // elliptic curve P-256 with SHA-256
 }
 
-func createJWT(db *gorm.DB, identity *models.Identity, groups []string, expires time.Time) (string, error) {
+// keyAlgorithmFromSignatureAlgorithm returns the algorithm of the key which
+// signs tokens with the signature algorithm.
+func keyAlgorithmFromSignatureAlgorithm(signatureAlgorithm string) (string, bool) {
+	for key, sig := range signatureAlgorithmFromKeyAlgorithm {
+		if sig == signatureAlgorithm {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// signJWT signs the claims with the key of the signature algorithm.
+func signJWT(db *gorm.DB, signatureAlgorithm string, tokenType string, claims ...interface{}) (string, error) {
+	keyAlgorithm, ok := keyAlgorithmFromSignatureAlgorithm(signatureAlgorithm)
+	if !ok {
+		return "", fmt.Errorf("unsupported algorithm")
+	}
+
 	settings, err := GetSettings(db)
 	if err != nil {
 		return "", err
 	}
 
+	private, _, err := settingsJWK(settings, keyAlgorithm)
+	if err != nil {
+		return "", err
+	}
+
 	var sec jose.JSONWebKey
-	if err := sec.UnmarshalJSON(settings.PrivateJWK); err != nil {
+	if err := sec.UnmarshalJSON(*private); err != nil {
 		return "", err
 	}
 
@@ -36,14 +61,24 @@ func createJWT(db *gorm.DB, identity *models.Identity, groups []string, expires
 
 	options := &jose.SignerOptions{}
 
-	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.SignatureAlgorithm(algo), Key: sec}, options.WithType("JWT"))
+	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.SignatureAlgorithm(algo), Key: sec}, options.WithType(jose.ContentType(tokenType)))
 	if err != nil {
 		return "", err
 	}
 
+	builder := jwt.Signed(signer)
+	for _, c := range claims {
+		builder = builder.Claims(c)
+	}
+
+	return builder.CompactSerialize()
+}
+
+func createJWT(db *gorm.DB, identity *models.Identity, groups []string, grants []claims.ConditionalGrant, expires time.Time) (string, error) {
 	now := time.Now().UTC()
 
 	claim := jwt.Claims{
+		Audience:  jwt.Audience{claims.DestinationAudience},
 		NotBefore: jwt.NewNumericDate(now.Add(time.Minute * -5)), // adjust for clock drift
 		Expiry:    jwt.NewNumericDate(expires),
 		IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
@@ -53,14 +88,10 @@ func createJWT(db *gorm.DB, identity *models.Identity, groups []string, expires
 		Name:   identity.Name,
 		Groups: groups,
 		Nonce:  generate.MathRandom(10, generate.CharsetAlphaNumeric),
+		Grants: grants,
 	}
 
-	raw, err := jwt.Signed(signer).Claims(claim).Claims(custom).CompactSerialize()
-	if err != nil {
-		return "", err
-	}
-
-	return raw, nil
+	return signJWT(db, "EdDSA", "JWT", claim, custom)
 }
 
 func CreateIdentityToken(db *gorm.DB, identityID uid.ID) (token *models.Token, err error) {
@@ -79,12 +110,149 @@ func CreateIdentityToken(db *gorm.DB, identityID uid.ID) (token *models.Token, e
 		groups = append(groups, g.Name)
 	}
 
+	// destinations enforce the conditions of grants for each request, so
+	// include the grants which have conditions
+	conditionalGrants, err := ListGrants(db, &models.Pagination{}, GrantsInheritedBySubject(identity.PolyID()), ByConditionalGrants())
+	if err != nil {
+		return nil, err
+	}
+
+	var grants []claims.ConditionalGrant
+	for _, g := range conditionalGrants {
+		grants = append(grants, claims.ConditionalGrant{ID: g.ID.String(), Conditions: g.Conditions.Conditions})
+	}
+
 	expires := time.Now().Add(time.Minute * 5).UTC()
 
-	jwt, err := createJWT(db, identity, groups, expires)
+	jwt, err := createJWT(db, identity, groups, grants, expires)
 	if err != nil {
 		return nil, err
 	}
 
 	return &models.Token{Token: jwt, Expires: expires}, nil
 }
+
+// oidcAccessTokenType is the type of the access tokens issued to OIDC
+// clients, which distinguishes them from ID tokens (RFC 9068).
+const oidcAccessTokenType = "at+jwt"
+
+// CreateOIDCTokens creates the ID token and access token for the user who
+// authorized the client with the code.
+func CreateOIDCTokens(db *gorm.DB, issuer string, client *models.OIDCClient, code *models.OIDCAuthorizationCode, expires time.Time) (idToken string, accessToken string, err error) {
+	user, err := OIDCUserClaims(db, code.IdentityID, code.Scopes)
+	if err != nil {
+		return "", "", err
+	}
+
+	claim := jwt.Claims{
+		Issuer:   issuer,
+		Subject:  user.Subject,
+		Audience: jwt.Audience{client.ClientID},
+		Expiry:   jwt.NewNumericDate(expires),
+		IssuedAt: jwt.NewNumericDate(time.Now().UTC()),
+	}
+
+	idClaims := struct {
+		AuthorizedParty string `json:"azp"`
+		Nonce           string `json:"nonce,omitempty"`
+	}{AuthorizedParty: client.ClientID, Nonce: code.Nonce}
+
+	idToken, err = signJWT(db, client.SigningAlgorithm, "JWT", claim, user, idClaims)
+	if err != nil {
+		return "", "", err
+	}
+
+	claim.ID = generate.MathRandom(16, generate.CharsetAlphaNumeric)
+	accessClaims := struct {
+		ClientID string `json:"client_id"`
+		Scope    string `json:"scope"`
+	}{ClientID: client.ClientID, Scope: strings.Join(code.Scopes, " ")}
+
+	accessToken, err = signJWT(db, client.SigningAlgorithm, oidcAccessTokenType, claim, accessClaims)
+	if err != nil {
+		return "", "", err
+	}
+
+	return idToken, accessToken, nil
+}
+
+// ValidateOIDCAccessToken checks the signature, issuer, and expiry of an
+// access token issued to an OIDC client, and returns the ID of the user and
+// the scopes of the token.
+func ValidateOIDCAccessToken(db *gorm.DB, issuer string, raw string) (uid.ID, []string, error) {
+	tok, err := jwt.ParseSigned(raw)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if len(tok.Headers) != 1 || tok.Headers[0].ExtraHeaders[jose.HeaderType] != oidcAccessTokenType {
+		return 0, nil, fmt.Errorf("invalid token: not an access token")
+	}
+
+	keys, err := PublicJWKs(db)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var key *jose.JSONWebKey
+	for i := range keys {
+		if keys[i].KeyID == tok.Headers[0].KeyID {
+			key = &keys[i]
+		}
+	}
+	if key == nil {
+		return 0, nil, fmt.Errorf("invalid token: unknown key")
+	}
+
+	var allClaims struct {
+		jwt.Claims
+		Scope string `json:"scope"`
+	}
+	if err := tok.Claims(key, &allClaims); err != nil {
+		return 0, nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	if err := allClaims.Claims.Validate(jwt.Expected{Issuer: issuer, Time: time.Now().UTC()}); err != nil {
+		return 0, nil, fmt.Errorf("invalid token: %w", err)
+	}
+
+	identityID, err := uid.Parse([]byte(allClaims.Subject))
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid token subject: %w", err)
+	}
+
+	return identityID, strings.Fields(allClaims.Scope), nil
+}
+
+// OIDCUserClaims returns the claims about the user for the scopes.
+func OIDCUserClaims(db *gorm.DB, identityID uid.ID, scopes []string) (*claims.OIDCUser, error) {
+	identity, err := GetIdentity(db, ByID(identityID))
+	if err != nil {
+		return nil, err
+	}
+
+	user := &claims.OIDCUser{Subject: identity.ID.String()}
+	for _, scope := range scopes {
+		switch scope {
+		case models.OIDCScopeEmail:
+			// the names of users are their email addresses, which were
+			// verified by the identity provider of the user
+			if strings.Contains(identity.Name, "@") {
+				user.Email = identity.Name
+				user.EmailVerified = true
+			}
+		case models.OIDCScopeProfile:
+			user.Name = identity.Name
+		case models.OIDCScopeGroups:
+			groups, err := ListGroups(db, &models.Pagination{}, ByGroupMember(identity.ID))
+			if err != nil {
+				return nil, err
+			}
+			for _, g := range groups {
+				user.Groups = append(user.Groups, g.Name)
+			}
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,59 @@
+package data
+
+import (
+	"testing"
+
+	"gopkg.in/square/go-jose.v2/jwt"
+	"gorm.io/gorm"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/claims"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestCreateIdentityToken(t *testing.T) {
+	runDBTests(t, func(t *testing.T, db *gorm.DB) {
+		_, err := InitializeSettings(db)
+		assert.NilError(t, err)
+
+		user := &models.Identity{Name: "token@example.com"}
+		assert.NilError(t, CreateIdentity(db, user))
+
+		group := &models.Group{Name: "on-call"}
+		assert.NilError(t, CreateGroup(db, group))
+		assert.NilError(t, AddUsersToGroup(db, group.ID, []uid.ID{user.ID}))
+
+		vpnOnly := models.GrantConditions{Conditions: claims.Conditions{CIDRs: []string{"10.0.0.0/8"}}}
+		nights := models.GrantConditions{Conditions: claims.Conditions{
+			Windows: []claims.TimeWindow{{Start: "18:00", End: "08:00", Timezone: "UTC"}},
+		}}
+
+		grants := []models.Grant{
+			{Subject: user.PolyID(), Privilege: "view", Resource: "staging"},
+			{Subject: user.PolyID(), Privilege: "admin", Resource: "staging", Conditions: vpnOnly},
+			{Subject: group.PolyID(), Privilege: "admin", Resource: "prod", Conditions: nights},
+		}
+		for i := range grants {
+			assert.NilError(t, CreateGrant(db, &grants[i]))
+		}
+
+		token, err := CreateIdentityToken(db, user.ID)
+		assert.NilError(t, err)
+
+		tok, err := jwt.ParseSigned(token.Token)
+		assert.NilError(t, err)
+
+		var custom claims.Custom
+		assert.NilError(t, tok.UnsafeClaimsWithoutVerification(&custom))
+
+		assert.Equal(t, custom.Name, "token@example.com")
+		assert.DeepEqual(t, custom.Groups, []string{"on-call"})
+
+		expected := []claims.ConditionalGrant{
+			{ID: grants[1].ID.String(), Conditions: vpnOnly.Conditions},
+			{ID: grants[2].ID.String(), Conditions: nights.Conditions},
+		}
+		assert.DeepEqual(t, custom.Grants, expected)
+	})
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -13,6 +15,8 @@ import (
 	"gotest.tools/v3/assert"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
 )
 
 func TestAPI_CreateDestination(t *testing.T) {
@@ -59,6 +63,7 @@ func TestAPI_CreateDestination(t *testing.T) {
 {
 	"id": "<any-valid-uid>",
 	"name": "final",
+	"kind": "kubernetes",
 	"uniqueID": "unique-id",
 	"version": "",
 	"connection": {
@@ -112,3 +117,125 @@ var cmpAPIDestinationJSON = gocmp.Options{
 	gocmp.FilterPath(pathMapKey(`created`, `updated`), cmpApproximateTime),
 	gocmp.FilterPath(pathMapKey(`id`), cmpAnyValidUID),
 }
+
+func TestAPI_AuthorizeDestination(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	alice := &models.Identity{Name: "alice@example.com"}
+	bob := &models.Identity{Name: "bob@example.com"}
+	createIdentities(t, srv.db, alice, bob)
+
+	// the test database has empty settings, replace them with settings that
+	// have keys to sign tokens
+	err := srv.db.Where("1 = 1").Delete(&models.Settings{}).Error
+	assert.NilError(t, err)
+	_, err = data.InitializeSettings(srv.db)
+	assert.NilError(t, err)
+
+	_, err = data.CreateProviderUser(srv.db, data.InfraProvider(srv.db), alice)
+	assert.NilError(t, err)
+
+	grafana := &models.Destination{Name: "grafana", Kind: models.DestinationKindHTTP, UniqueID: "grafana", ConnectionURL: "grafana.example.com"}
+	err = data.CreateDestination(srv.db, grafana)
+	assert.NilError(t, err)
+
+	err = data.CreateGrant(srv.db, &models.Grant{Subject: alice.PolyID(), Privilege: "connect", Resource: "grafana"})
+	assert.NilError(t, err)
+
+	accessKey := func(t *testing.T, identity *models.Identity) string {
+		key, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+			IssuedFor:  identity.ID,
+			ProviderID: data.InfraProvider(srv.db).ID,
+			ExpiresAt:  time.Now().Add(10 * time.Minute),
+		})
+		assert.NilError(t, err)
+		return key
+	}
+
+	authorize := func(t *testing.T, key, redirectURL string) *httptest.ResponseRecorder {
+		t.Helper()
+		query := url.Values{"redirect_uri": {redirectURL}, "state": {"the-state"}}
+		req, err := http.NewRequest(http.MethodGet, "/api/destinations/"+grafana.ID.String()+"/authorize?"+query.Encode(), nil)
+		assert.NilError(t, err)
+		if key != "" {
+			req.AddCookie(&http.Cookie{Name: CookieAuthorizationName, Value: key})
+		}
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	createToken := func(t *testing.T, key string) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, "/api/tokens", jsonBody(t, api.EmptyRequest{}))
+		assert.NilError(t, err)
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Set("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	callback := "https://grafana.example.com/.infra/callback"
+
+	t.Run("not logged in", func(t *testing.T) {
+		resp := authorize(t, "", callback)
+		assert.Equal(t, resp.Code, http.StatusFound)
+		assert.Assert(t, strings.HasPrefix(resp.Header().Get("Location"), "/login?next="), resp.Header().Get("Location"))
+	})
+
+	t.Run("login code", func(t *testing.T) {
+		resp := authorize(t, accessKey(t, alice), callback)
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+
+		location, err := url.Parse(resp.Header().Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, location.Host, "grafana.example.com")
+		assert.Equal(t, location.Path, "/.infra/callback")
+		assert.Equal(t, location.Query().Get("state"), "the-state")
+
+		code := location.Query().Get("code")
+
+		// the code can not be used for other requests
+		req, err := http.NewRequest(http.MethodGet, "/api/users", nil)
+		assert.NilError(t, err)
+		req.Header.Set("Authorization", "Bearer "+code)
+		req.Header.Set("Infra-Version", "0.13.6")
+		usersResp := httptest.NewRecorder()
+		routes.ServeHTTP(usersResp, req)
+		assert.Equal(t, usersResp.Code, http.StatusUnauthorized, usersResp.Body.String())
+
+		// or exchanged for an access key
+		req, err = http.NewRequest(http.MethodPost, "/api/login", jsonBody(t, api.LoginRequest{AccessKey: code}))
+		assert.NilError(t, err)
+		req.Header.Set("Infra-Version", "0.13.6")
+		loginResp := httptest.NewRecorder()
+		routes.ServeHTTP(loginResp, req)
+		assert.Equal(t, loginResp.Code, http.StatusUnauthorized, loginResp.Body.String())
+
+		tokenResp := createToken(t, code)
+		assert.Equal(t, tokenResp.Code, http.StatusCreated, tokenResp.Body.String())
+
+		var token api.CreateTokenResponse
+		err = json.Unmarshal(tokenResp.Body.Bytes(), &token)
+		assert.NilError(t, err)
+		assert.Assert(t, token.Token != "")
+
+		// the code can only be exchanged once
+		tokenResp = createToken(t, code)
+		assert.Equal(t, tokenResp.Code, http.StatusUnauthorized, tokenResp.Body.String())
+	})
+
+	t.Run("redirect to another host", func(t *testing.T) {
+		resp := authorize(t, accessKey(t, alice), "https://evil.example.com/.infra/callback")
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("no grants", func(t *testing.T) {
+		resp := authorize(t, accessKey(t, bob), callback)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestAPI_DeviceFlow(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	admin, err := data.GetIdentity(srv.db, data.ByName("admin@example.com"))
+	assert.NilError(t, err)
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		if key != "" {
+			req.Header.Add("Authorization", "Bearer "+key)
+		}
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	start := func(t *testing.T) api.StartDeviceFlowResponse {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/device", "", api.EmptyRequest{})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var started api.StartDeviceFlowResponse
+		err := json.Unmarshal(resp.Body.Bytes(), &started)
+		assert.NilError(t, err)
+		return started
+	}
+
+	login := func(t *testing.T, deviceCode string) (*httptest.ResponseRecorder, api.LoginResponse) {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/login", "", api.LoginRequest{DeviceCode: deviceCode})
+
+		var loginResp api.LoginResponse
+		if resp.Code == http.StatusCreated {
+			err := json.Unmarshal(resp.Body.Bytes(), &loginResp)
+			assert.NilError(t, err)
+		}
+		return resp, loginResp
+	}
+
+	// waitForPollInterval moves the last poll of the device back, so that
+	// tests do not have to wait for the poll interval
+	waitForPollInterval := func(t *testing.T, deviceCode string) {
+		t.Helper()
+		request, err := data.GetDeviceFlowAuthRequest(srv.db, data.ByDeviceCode(deviceCode))
+		assert.NilError(t, err)
+		request.LastPolledAt = time.Now().Add(-authn.DeviceFlowPollInterval)
+		err = data.SaveDeviceFlowAuthRequest(srv.db, request)
+		assert.NilError(t, err)
+	}
+
+	t.Run("approved login", func(t *testing.T) {
+		started := start(t)
+		assert.Assert(t, started.DeviceCode != "")
+		assert.Equal(t, len(started.UserCode), 9)
+		assert.Equal(t, started.UserCode[4], byte('-'))
+		assert.Equal(t, started.VerificationURI, "http://example.com/device")
+		assert.Equal(t, started.PollIntervalSeconds, 5)
+		assert.Assert(t, time.Time(started.Expires).After(time.Now()))
+
+		resp, _ := login(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), "device authorization pending"))
+
+		// the user code can be entered without formatting
+		userCode := strings.ToLower(strings.ReplaceAll(started.UserCode, "-", ""))
+		resp = call(t, http.MethodPost, "/api/device/approve", adminAccessKey(srv), api.ApproveDeviceFlowRequest{UserCode: userCode})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		resp = call(t, http.MethodPost, "/api/device/approve", adminAccessKey(srv), api.ApproveDeviceFlowRequest{UserCode: started.UserCode})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		waitForPollInterval(t, started.DeviceCode)
+		resp, loginResp := login(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.Equal(t, loginResp.UserID, admin.ID)
+		assert.Assert(t, loginResp.AccessKey != "")
+
+		resp = call(t, http.MethodGet, "/api/users/"+admin.ID.String(), loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		// the device code can only be used once
+		resp, _ = login(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("polling too often", func(t *testing.T) {
+		started := start(t)
+
+		resp, _ := login(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), "device authorization pending"))
+
+		resp, _ = login(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), "slow_down"))
+
+		// an approved login must also wait for the poll interval
+		resp = call(t, http.MethodPost, "/api/device/approve", adminAccessKey(srv), api.ApproveDeviceFlowRequest{UserCode: started.UserCode})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		resp, _ = login(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), "slow_down"))
+
+		waitForPollInterval(t, started.DeviceCode)
+		resp, _ = login(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+
+	t.Run("approved with a scoped access key", func(t *testing.T) {
+		started := start(t)
+
+		scopes := []string{"device:write", "users:read", "cidr:192.0.2.0/24"}
+		scopedKey, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+			IssuedFor:  admin.ID,
+			ProviderID: data.InfraProvider(srv.db).ID,
+			ExpiresAt:  time.Now().Add(10 * time.Minute),
+			Scopes:     scopes,
+		})
+		assert.NilError(t, err)
+
+		resp := call(t, http.MethodPost, "/api/device/approve", scopedKey, api.ApproveDeviceFlowRequest{UserCode: started.UserCode})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		resp, loginResp := login(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		keyID, _, _ := strings.Cut(loginResp.AccessKey, ".")
+		key, err := data.GetAccessKey(srv.db, data.ByKeyID(keyID))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string(key.Scopes), scopes)
+	})
+
+	t.Run("approve requires authentication", func(t *testing.T) {
+		started := start(t)
+
+		resp := call(t, http.MethodPost, "/api/device/approve", "", api.ApproveDeviceFlowRequest{UserCode: started.UserCode})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("unknown codes", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/device/approve", adminAccessKey(srv), api.ApproveDeviceFlowRequest{UserCode: "BCDF-GHJK"})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		resp, _ = login(t, "not-a-device-code")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		started := start(t)
+
+		request, err := data.GetDeviceFlowAuthRequest(srv.db, data.ByDeviceCode(started.DeviceCode))
+		assert.NilError(t, err)
+		request.ExpiresAt = time.Now().Add(-time.Minute)
+		err = data.SaveDeviceFlowAuthRequest(srv.db, request)
+		assert.NilError(t, err)
+
+		resp := call(t, http.MethodPost, "/api/device/approve", adminAccessKey(srv), api.ApproveDeviceFlowRequest{UserCode: started.UserCode})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		resp, _ = login(t, started.DeviceCode)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+}
@@ -61,6 +61,10 @@ func sendAPIError(c *gin.Context, err error) {
 		resp.Code = http.StatusNotFound
 		resp.Message = err.Error()
 
+	case errors.Is(err, internal.ErrTooManyRequests):
+		resp.Code = http.StatusTooManyRequests
+		resp.Message = err.Error()
+
 	case errors.As(err, &validationError):
 		resp.Code = http.StatusBadRequest
 		resp.Message = err.Error()
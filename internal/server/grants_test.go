@@ -16,6 +16,7 @@ import (
 	"gotest.tools/v3/assert"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/claims"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -340,7 +341,9 @@ func TestAPI_ListGrants(t *testing.T) {
 							"resource": "res1",
 							"user": "%[2]v",
 							"created": "%[3]v",
-							"updated": "%[3]v"
+							"updated": "%[3]v",
+							"notBefore": null,
+							"expiresAt": null
 						}]
 					}`,
 					admin.ID,
@@ -557,6 +560,67 @@ var cmpAPIGrantJSON = gocmp.Options{
 	gocmp.FilterPath(pathMapKey(`id`), cmpAnyValidUID),
 }
 
+func TestAPI_ListGrants_Wildcard(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	userID := uid.ID(3000)
+	for _, resource := range []string{"prod-1", "prod-*", "*.kube-system", "staging.*"} {
+		err := data.CreateGrant(srv.db, &models.Grant{Subject: uid.NewIdentityPolymorphicID(userID), Privilege: "view", Resource: resource})
+		assert.NilError(t, err)
+	}
+
+	listResources := func(t *testing.T, query string) []string {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, "/api/grants?user="+userID.String()+"&"+query, nil)
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Add("Infra-Version", "0.12.3")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var grants api.ListResponse[api.Grant]
+		err = json.NewDecoder(resp.Body).Decode(&grants)
+		assert.NilError(t, err)
+
+		var resources []string
+		for _, g := range grants.Items {
+			resources = append(resources, g.Resource)
+		}
+		return resources
+	}
+
+	t.Run("by resource", func(t *testing.T) {
+		assert.DeepEqual(t, listResources(t, "resource=prod-1"), []string{"prod-1", "prod-*"})
+		assert.DeepEqual(t, listResources(t, "resource=prod-2"), []string{"prod-*"})
+		assert.DeepEqual(t, listResources(t, "resource=staging.kube-system"), []string{"*.kube-system", "staging.*"})
+	})
+
+	t.Run("by destination", func(t *testing.T) {
+		assert.DeepEqual(t, listResources(t, "destination=prod-1"), []string{"prod-1", "prod-*", "*.kube-system"})
+		assert.DeepEqual(t, listResources(t, "destination=staging"), []string{"*.kube-system", "staging.*"})
+	})
+
+	t.Run("create existing grant", func(t *testing.T) {
+		body := jsonBody(t, api.CreateGrantRequest{User: userID, Privilege: "view", Resource: "prod-1"})
+		req, err := http.NewRequest(http.MethodPost, "/api/grants", body)
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Add("Infra-Version", "0.12.3")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var grant api.CreateGrantResponse
+		err = json.NewDecoder(resp.Body).Decode(&grant)
+		assert.NilError(t, err)
+		assert.Equal(t, grant.Resource, "prod-1")
+	})
+}
+
 func TestAPI_CreateGrant(t *testing.T) {
 	srv := setupServer(t, withAdminUser)
 	routes := srv.GenerateRoutes(prometheus.NewRegistry())
@@ -590,6 +654,8 @@ func TestAPI_CreateGrant(t *testing.T) {
 		  "user": "TJ",
 		  "created": "%[2]v",
 		  "updated": "%[2]v",
+		  "notBefore": null,
+		  "expiresAt": null,
 		  "wasCreated": true
 		}`,
 			accessKey.IssuedFor,
@@ -640,6 +706,150 @@ func TestAPI_CreateGrant(t *testing.T) {
 		}
 		assert.DeepEqual(t, respBody.FieldErrors, expected)
 	})
+
+	t.Run("expiry in the past", func(t *testing.T) {
+		body := fmt.Sprintf(`{"user": "TJ", "privilege": "view", "resource": "some-cluster", "expiresAt": %q}`,
+			time.Now().Add(-time.Minute).UTC().Format(time.RFC3339))
+
+		resp := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodPost, "/api/grants", strings.NewReader(body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), "expiresAt must be in the future"))
+	})
+
+	t.Run("expiry before not before", func(t *testing.T) {
+		body := fmt.Sprintf(`{"user": "TJ", "privilege": "view", "resource": "some-cluster", "notBefore": %q, "expiresAt": %q}`,
+			time.Now().Add(2*time.Hour).UTC().Format(time.RFC3339),
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+
+		resp := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodPost, "/api/grants", strings.NewReader(body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), "expiresAt must be after notBefore"))
+	})
+
+	t.Run("with expiry", func(t *testing.T) {
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+		body := fmt.Sprintf(`{"user": "TJ", "privilege": "view", "resource": "some-cluster", "expiresAt": %q}`,
+			expiresAt.Format(time.RFC3339))
+
+		resp := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodPost, "/api/grants", strings.NewReader(body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var grant api.Grant
+		err = json.Unmarshal(resp.Body.Bytes(), &grant)
+		assert.NilError(t, err)
+		assert.Equal(t, grant.ExpiresAt.Time(), expiresAt)
+		assert.Assert(t, grant.NotBefore.Time().IsZero())
+	})
+
+	t.Run("invalid conditions", func(t *testing.T) {
+		body := `{"user": "TJ", "privilege": "view", "resource": "conditional", "conditions": {"cidrs": ["10.0.0.0"]}}`
+
+		resp := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodPost, "/api/grants", strings.NewReader(body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), `invalid cidr \"10.0.0.0\"`), resp.Body.String())
+	})
+
+	t.Run("with conditions", func(t *testing.T) {
+		body := `{"user": "TJ", "privilege": "view", "resource": "conditional", "conditions": {
+			"cidrs": ["10.0.0.0/8"],
+			"windows": [{"days": ["mon", "fri"], "start": "09:00", "end": "17:00", "timezone": "Europe/Paris"}]
+		}}`
+
+		resp := httptest.NewRecorder()
+		req, err := http.NewRequest(http.MethodPost, "/api/grants", strings.NewReader(body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var grant api.Grant
+		err = json.Unmarshal(resp.Body.Bytes(), &grant)
+		assert.NilError(t, err)
+
+		expected := &api.GrantConditions{
+			CIDRs: []string{"10.0.0.0/8"},
+			Windows: []api.TimeWindow{
+				{Days: []string{"mon", "fri"}, Start: "09:00", End: "17:00", Timezone: "Europe/Paris"},
+			},
+		}
+		assert.DeepEqual(t, grant.Conditions, expected)
+	})
+
+	t.Run("scheduled next to a current grant", func(t *testing.T) {
+		call := func(t *testing.T, method, path, body string) *httptest.ResponseRecorder {
+			t.Helper()
+			req, err := http.NewRequest(method, path, strings.NewReader(body))
+			assert.NilError(t, err)
+			req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+			req.Header.Add("Infra-Version", "0.13.6")
+
+			resp := httptest.NewRecorder()
+			routes.ServeHTTP(resp, req)
+			return resp
+		}
+
+		body := fmt.Sprintf(`{"user": "TJ", "privilege": "admin-role", "resource": "some-cluster", "notBefore": %q}`,
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339))
+		resp := call(t, http.MethodPost, "/api/grants", body)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var scheduled api.CreateGrantResponse
+		err := json.Unmarshal(resp.Body.Bytes(), &scheduled)
+		assert.NilError(t, err)
+		assert.Assert(t, scheduled.WasCreated)
+
+		// creating it again returns the scheduled grant
+		resp = call(t, http.MethodPost, "/api/grants", body)
+		var again api.CreateGrantResponse
+		err = json.Unmarshal(resp.Body.Bytes(), &again)
+		assert.NilError(t, err)
+		assert.Assert(t, !again.WasCreated)
+		assert.Equal(t, again.ID, scheduled.ID)
+
+		listGrants := func(t *testing.T, query string) []api.Grant {
+			t.Helper()
+			resp := call(t, http.MethodGet, "/api/grants?resource=some-cluster&privilege=admin-role"+query, "")
+			assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+			var grants api.ListResponse[api.Grant]
+			err := json.Unmarshal(resp.Body.Bytes(), &grants)
+			assert.NilError(t, err)
+			return grants.Items
+		}
+
+		grants := listGrants(t, "")
+		assert.Equal(t, len(grants), 1)
+		assert.Equal(t, grants[0].ID, newGrant.ID)
+
+		grants = listGrants(t, "&showPending=true")
+		assert.Equal(t, len(grants), 2)
+	})
 }
 
 func TestAPI_CreateGrantV0_12_2_Success(t *testing.T) {
@@ -822,3 +1032,274 @@ func TestAPI_DeleteGrant(t *testing.T) {
 		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
 	})
 }
+
+func TestAPI_CheckAuthorization(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	createUser := func(t *testing.T, name string) *models.Identity {
+		t.Helper()
+		user := &models.Identity{Name: name}
+		err := data.CreateIdentity(srv.db, user)
+		assert.NilError(t, err)
+		return user
+	}
+
+	alice := createUser(t, "alice@example.com")
+	bob := createUser(t, "bob@example.com")
+
+	developers := &models.Group{Name: "developers"}
+	err := data.CreateGroup(srv.db, developers)
+	assert.NilError(t, err)
+	err = data.AddUsersToGroup(srv.db, developers.ID, []uid.ID{alice.ID})
+	assert.NilError(t, err)
+
+	// developers are a member of backend, which is a member of engineering
+	backend := &models.Group{Name: "backend"}
+	err = data.CreateGroup(srv.db, backend)
+	assert.NilError(t, err)
+	engineering := &models.Group{Name: "engineering"}
+	err = data.CreateGroup(srv.db, engineering)
+	assert.NilError(t, err)
+	err = data.AddGroupsToGroup(srv.db, backend.ID, []uid.ID{developers.ID})
+	assert.NilError(t, err)
+	err = data.AddGroupsToGroup(srv.db, engineering.ID, []uid.ID{backend.ID})
+	assert.NilError(t, err)
+
+	err = data.CreateRole(srv.db, &models.Role{Name: "operator", Permissions: models.CommaSeparatedStrings{"view", "edit"}})
+	assert.NilError(t, err)
+
+	err = data.CreateGrant(srv.db, &models.Grant{Subject: alice.PolyID(), Privilege: "view", Resource: "production"})
+	assert.NilError(t, err)
+	err = data.CreateGrant(srv.db, &models.Grant{Subject: developers.PolyID(), Privilege: "operator", Resource: "prod*"})
+	assert.NilError(t, err)
+	err = data.CreateGrant(srv.db, &models.Grant{Subject: engineering.PolyID(), Privilege: "view", Resource: "wiki"})
+	assert.NilError(t, err)
+	err = data.CreateGrant(srv.db, &models.Grant{
+		Subject:    bob.PolyID(),
+		Privilege:  "view",
+		Resource:   "staging",
+		Conditions: models.GrantConditions{Conditions: claims.Conditions{CIDRs: []string{"10.0.0.0/8"}}},
+	})
+	assert.NilError(t, err)
+
+	userKey := func(t *testing.T, user *models.Identity) string {
+		t.Helper()
+		key, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+			IssuedFor:  user.ID,
+			ProviderID: data.InfraProvider(srv.db).ID,
+			ExpiresAt:  time.Now().Add(10 * time.Minute),
+		})
+		assert.NilError(t, err)
+		return key
+	}
+
+	type testCase struct {
+		body         api.AuthzCheckRequest
+		key          string
+		remoteAddr   string
+		forwardedFor string
+		expected     func(t *testing.T, resp *httptest.ResponseRecorder)
+	}
+
+	decode := func(t *testing.T, resp *httptest.ResponseRecorder) api.AuthzCheckResponse {
+		t.Helper()
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		var result api.AuthzCheckResponse
+		err := json.NewDecoder(resp.Body).Decode(&result)
+		assert.NilError(t, err)
+		return result
+	}
+
+	run := func(t *testing.T, tc testCase) {
+		req, err := http.NewRequest(http.MethodPost, "/api/authz/check", jsonBody(t, tc.body))
+		assert.NilError(t, err)
+		req.Header.Add("Infra-Version", "0.13.6")
+		if tc.remoteAddr != "" {
+			req.RemoteAddr = tc.remoteAddr
+		}
+		if tc.forwardedFor != "" {
+			req.Header.Add("X-Forwarded-For", tc.forwardedFor)
+		}
+
+		key := tc.key
+		if key == "" {
+			key = adminAccessKey(srv)
+		}
+		req.Header.Add("Authorization", "Bearer "+key)
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		tc.expected(t, resp)
+	}
+
+	testCases := map[string]testCase{
+		"allowed by direct grant and group grant": {
+			body: api.AuthzCheckRequest{User: alice.ID, Privilege: "view", Resource: "production"},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, true)
+				sort.Strings(result.Groups)
+				assert.DeepEqual(t, result.Groups, []string{"backend", "developers", "engineering"})
+
+				sort.Slice(result.Reasons, func(i, j int) bool {
+					return result.Reasons[i].Grant.Resource < result.Reasons[j].Grant.Resource
+				})
+				expected := []api.AuthzReason{
+					{
+						Grant: api.Grant{Group: developers.ID, Privilege: "operator", Resource: "prod*"},
+						Group: "developers",
+						Path:  []string{"developers"},
+						Role:  "operator",
+					},
+					{
+						Grant: api.Grant{User: alice.ID, Privilege: "view", Resource: "production"},
+					},
+				}
+				assert.DeepEqual(t, result.Reasons, expected, cmpAuthzReasonShallow)
+			},
+		},
+		"allowed by group grant with a custom role": {
+			body: api.AuthzCheckRequest{User: alice.ID, Privilege: "edit", Resource: "prod-eu"},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, true)
+				expected := []api.AuthzReason{
+					{
+						Grant: api.Grant{Group: developers.ID, Privilege: "operator", Resource: "prod*"},
+						Group: "developers",
+						Path:  []string{"developers"},
+						Role:  "operator",
+					},
+				}
+				assert.DeepEqual(t, result.Reasons, expected, cmpAuthzReasonShallow)
+			},
+		},
+		"allowed by a grant to a group of a group": {
+			body: api.AuthzCheckRequest{User: alice.ID, Privilege: "view", Resource: "wiki"},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, true)
+				expected := []api.AuthzReason{
+					{
+						Grant: api.Grant{Group: engineering.ID, Privilege: "view", Resource: "wiki"},
+						Group: "engineering",
+						Path:  []string{"developers", "backend", "engineering"},
+					},
+				}
+				assert.DeepEqual(t, result.Reasons, expected, cmpAuthzReasonShallow)
+			},
+		},
+		"group subject of a grant to a group of a group": {
+			body: api.AuthzCheckRequest{Group: developers.ID, Privilege: "view", Resource: "wiki"},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, true)
+				expected := []api.AuthzReason{
+					{
+						Grant: api.Grant{Group: engineering.ID, Privilege: "view", Resource: "wiki"},
+						Group: "engineering",
+						Path:  []string{"backend", "engineering"},
+					},
+				}
+				assert.DeepEqual(t, result.Reasons, expected, cmpAuthzReasonShallow)
+			},
+		},
+		"denied": {
+			body: api.AuthzCheckRequest{User: alice.ID, Privilege: "admin", Resource: "production"},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, false)
+				assert.DeepEqual(t, result.Reasons, []api.AuthzReason{})
+				assert.Equal(t, len(result.Groups), 3)
+			},
+		},
+		"conditional grant satisfied": {
+			body: api.AuthzCheckRequest{User: bob.ID, Privilege: "view", Resource: "staging", ClientIP: "10.1.2.3"},
+			// the conditions are checked against the client IP of the subject,
+			// not the address of the admin who checks
+			remoteAddr: "192.168.1.2:4321",
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, true)
+				assert.Equal(t, len(result.Reasons), 1)
+			},
+		},
+		"conditional grant not satisfied": {
+			body:         api.AuthzCheckRequest{User: bob.ID, Privilege: "view", Resource: "staging", ClientIP: "192.168.1.2"},
+			remoteAddr:   "10.1.2.3:4321",
+			forwardedFor: "10.1.2.3",
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, false)
+				// the grant is returned to explain the conditions
+				assert.Equal(t, len(result.Reasons), 1)
+				assert.DeepEqual(t, result.Reasons[0].Grant.Conditions.CIDRs, []string{"10.0.0.0/8"})
+			},
+		},
+		"conditional grant without a client IP": {
+			body:       api.AuthzCheckRequest{User: bob.ID, Privilege: "view", Resource: "staging"},
+			remoteAddr: "10.1.2.3:4321",
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, false)
+				assert.Equal(t, len(result.Reasons), 1)
+			},
+		},
+		"invalid client IP": {
+			body: api.AuthzCheckRequest{User: bob.ID, Privilege: "view", Resource: "staging", ClientIP: "10.1.2"},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+			},
+		},
+		"group subject": {
+			body: api.AuthzCheckRequest{Group: developers.ID, Privilege: "view", Resource: "production"},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, true)
+				assert.Equal(t, len(result.Reasons), 1)
+				assert.Equal(t, result.Reasons[0].Group, "")
+				assert.Equal(t, result.Reasons[0].Role, "operator")
+			},
+		},
+		"users can check their own access": {
+			body: api.AuthzCheckRequest{User: alice.ID, Privilege: "view", Resource: "production"},
+			key:  userKey(t, alice),
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				result := decode(t, resp)
+				assert.Equal(t, result.Allowed, true)
+			},
+		},
+		"users can not check the access of others": {
+			body: api.AuthzCheckRequest{User: alice.ID, Privilege: "view", Resource: "production"},
+			key:  userKey(t, bob),
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+			},
+		},
+		"unknown user": {
+			body: api.AuthzCheckRequest{User: uid.New(), Privilege: "view", Resource: "production"},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+			},
+		},
+		"missing subject": {
+			body: api.AuthzCheckRequest{Privilege: "view", Resource: "production"},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+			},
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			run(t, tc)
+		})
+	}
+}
+
+var cmpAuthzReasonShallow = gocmp.Comparer(func(x, y api.AuthzReason) bool {
+	return x.Group == y.Group && x.Role == y.Role && gocmp.Equal(x.Path, y.Path) &&
+		x.Grant.User == y.Grant.User && x.Grant.Group == y.Grant.Group &&
+		x.Grant.Privilege == y.Grant.Privilege && x.Grant.Resource == y.Grant.Resource
+})
@@ -14,6 +14,7 @@ import (
 	"gotest.tools/v3/assert"
 
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
 	"github.com/infrahq/infra/uid"
@@ -168,7 +169,8 @@ func TestAPI_ListGroups(t *testing.T) {
 		"id": "%[1]v",
 		"name": "humans",
 		"created": "%[2]v",
-		"updated": "%[2]v"
+		"updated": "%[2]v",
+		"requireMFA": false
 	}
 ]`,
 					humans.ID.String(),
@@ -193,13 +195,15 @@ func TestAPI_ListGroups(t *testing.T) {
 		"id": "%[1]v",
 		"name": "humans",
 		"created": "%[3]v",
-		"updated": "%[3]v"
+		"updated": "%[3]v",
+		"requireMFA": false
 	},
 	{
 		"id": "%[2]v",
 		"name": "second",
 		"created": "%[3]v",
-		"updated": "%[3]v"
+		"updated": "%[3]v",
+		"requireMFA": false
 	}]
 }`,
 					humans.ID,
@@ -225,7 +229,8 @@ func TestAPI_ListGroups(t *testing.T) {
 		"id": "%[1]v",
 		"name": "humans",
 		"created": "%[2]v",
-		"updated": "%[2]v"
+		"updated": "%[2]v",
+		"requireMFA": false
 	}]
 }`,
 					humans.ID.String(),
@@ -526,3 +531,86 @@ func TestAPI_UpdateUsersInGroup(t *testing.T) {
 var cmpModelsIdentityShallow = cmp.Comparer(func(x, y models.Identity) bool {
 	return x.Name == y.Name
 })
+
+func TestAPI_UpdateGroupsInGroup(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	var (
+		admins    = models.Group{Name: "admins"}
+		sre       = models.Group{Name: "sre"}
+		sreOncall = models.Group{Name: "sre-oncall"}
+	)
+	createGroups(t, srv.db, &admins, &sre, &sreOncall)
+
+	oncallKey, oncall := createAccessKey(t, srv.db, "oncall@example.com")
+	assert.NilError(t, data.AddUsersToGroup(srv.db, sreOncall.ID, []uid.ID{oncall.ID}))
+
+	err := data.CreateGrant(srv.db, &models.Grant{
+		Subject:   admins.PolyID(),
+		Privilege: models.InfraAdminRole,
+		Resource:  access.ResourceInfraAPI,
+	})
+	assert.NilError(t, err)
+
+	send := func(t *testing.T, key string, groupID uid.ID, body api.UpdateGroupsInGroupRequest) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPatch, fmt.Sprintf("/api/groups/%s/groups", groupID), jsonBody(t, body))
+		assert.NilError(t, err)
+		req.Header.Set("Authorization", "Bearer "+key)
+		req.Header.Add("Infra-Version", "0.13.0")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("not authorized", func(t *testing.T) {
+		resp := send(t, oncallKey, admins.ID, api.UpdateGroupsInGroupRequest{GroupIDsToAdd: []uid.ID{sre.ID}})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("add groups", func(t *testing.T) {
+		resp := send(t, adminAccessKey(srv), sre.ID, api.UpdateGroupsInGroupRequest{GroupIDsToAdd: []uid.ID{sreOncall.ID}})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = send(t, adminAccessKey(srv), admins.ID, api.UpdateGroupsInGroupRequest{GroupIDsToAdd: []uid.ID{sre.ID}})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		groups, err := data.ListGroups(srv.db, &models.Pagination{}, data.ByGroupMember(oncall.ID))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, groups, []models.Group{admins, sre, sreOncall}, cmpGroupShallow)
+	})
+
+	t.Run("members of nested groups have their grants", func(t *testing.T) {
+		// the grant to admins allows the user to add groups
+		resp := send(t, oncallKey, sre.ID, api.UpdateGroupsInGroupRequest{})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("add group which creates a cycle", func(t *testing.T) {
+		resp := send(t, adminAccessKey(srv), sreOncall.ID, api.UpdateGroupsInGroupRequest{GroupIDsToAdd: []uid.ID{admins.ID}})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("add unknown group", func(t *testing.T) {
+		resp := send(t, adminAccessKey(srv), sre.ID, api.UpdateGroupsInGroupRequest{GroupIDsToAdd: []uid.ID{1337}})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("remove groups", func(t *testing.T) {
+		resp := send(t, adminAccessKey(srv), sre.ID, api.UpdateGroupsInGroupRequest{GroupIDsToRemove: []uid.ID{sreOncall.ID}})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		groups, err := data.ListGroups(srv.db, &models.Pagination{}, data.ByGroupMember(oncall.ID))
+		assert.NilError(t, err)
+		assert.DeepEqual(t, groups, []models.Group{sreOncall}, cmpGroupShallow)
+
+		resp = send(t, oncallKey, sre.ID, api.UpdateGroupsInGroupRequest{})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+}
+
+var cmpGroupShallow = cmp.Comparer(func(x, y models.Group) bool {
+	return x.Name == y.Name
+})
@@ -2,15 +2,22 @@ package server
 
 import (
 	"context"
+	"crypto/x509"
+	"encoding/pem"
 	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
 	"regexp"
 	"strings"
 	"time"
 
 	"github.com/getkin/kin-openapi/openapi3"
 	"github.com/gin-gonic/gin"
-	"github.com/infrahq/secrets"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/ssh"
 
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal"
@@ -28,6 +35,14 @@ type API struct {
 	server     *Server
 	migrations []apiMigration
 	openAPIDoc openapi3.T
+	// routeScopes are the API scopes of the routes, which access keys can be
+	// scoped to
+	routeScopes map[string]bool
+	// resourceRoutes are the routes which check the resources of a request
+	// against the resource scopes of its access key, by method and path
+	resourceRoutes map[string]bool
+
+	loginFailures *prometheus.CounterVec
 }
 
 func (a *API) ListUsers(c *gin.Context, r *api.ListUsersRequest) (*api.ListResponse[api.User], error) {
@@ -126,14 +141,64 @@ func (a *API) DeleteUser(c *gin.Context, r *api.Resource) (*api.EmptyResponse, e
 	return nil, access.DeleteIdentity(c, r.ID)
 }
 
+func (a *API) UnlockUser(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.UnlockUser(c, r.ID)
+}
+
 // TODO: remove after deprecation period
+func (a *API) CreateMFA(c *gin.Context, r *api.CreateMFARequest) (*api.CreateMFAResponse, error) {
+	secret, uri, err := access.CreateMFA(c, r.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CreateMFAResponse{Secret: secret, ProvisioningURI: uri}, nil
+}
+
+func (a *API) VerifyMFA(c *gin.Context, r *api.VerifyMFARequest) (*api.VerifyMFAResponse, error) {
+	codes, err := access.VerifyMFA(c, r.UserID, r.Code)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.VerifyMFAResponse{RecoveryCodes: codes}, nil
+}
+
+func (a *API) DeleteMFA(c *gin.Context, r *api.DeleteMFARequest) (*api.EmptyResponse, error) {
+	return nil, access.DeleteMFA(c, r.UserID, r.Code)
+}
+
+func (a *API) ListSessions(c *gin.Context, r *api.Resource) (*api.ListResponse[api.Session], error) {
+	sessions, err := access.ListSessions(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := access.CurrentSessionID(c)
+	return api.NewListResponse(sessions, api.PaginationResponse{}, func(key models.AccessKey) api.Session {
+		session := key.ToAPISession()
+		session.Current = key.ID == current
+		return *session
+	}), nil
+}
+
+func (a *API) DeleteSession(c *gin.Context, r *api.DeleteSessionRequest) (*api.EmptyResponse, error) {
+	return nil, access.DeleteSession(c, r.UserID, r.SessionID)
+}
+
+// DeleteSessions revokes all the sessions of the user, which logs them out
+// everywhere.
+func (a *API) DeleteSessions(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteSessions(c, r.ID)
+}
+
 func (a *API) deprecatedListUserGroups(c *gin.Context, r *api.Resource) (*api.ListResponse[api.Group], error) {
 	return a.ListGroups(c, &api.ListGroupsRequest{UserID: r.ID})
 }
 
 func (a *API) ListGroups(c *gin.Context, r *api.ListGroupsRequest) (*api.ListResponse[api.Group], error) {
 	p := models.RequestToPagination(r.PaginationRequest)
-	groups, err := access.ListGroups(c, r.Name, r.UserID, &p)
+	groups, err := access.ListGroups(c, r.Name, r.UserID, r.GroupID, &p)
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +221,8 @@ func (a *API) GetGroup(c *gin.Context, r *api.Resource) (*api.Group, error) {
 
 func (a *API) CreateGroup(c *gin.Context, r *api.CreateGroupRequest) (*api.Group, error) {
 	group := &models.Group{
-		Name: r.Name,
+		Name:       r.Name,
+		RequireMFA: r.RequireMFA,
 	}
 
 	authIdent := access.AuthenticatedIdentity(c)
@@ -172,6 +238,21 @@ func (a *API) CreateGroup(c *gin.Context, r *api.CreateGroupRequest) (*api.Group
 	return group.ToAPI(), nil
 }
 
+func (a *API) UpdateGroup(c *gin.Context, r *api.UpdateGroupRequest) (*api.Group, error) {
+	group, err := access.GetGroup(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	group.RequireMFA = r.RequireMFA
+
+	if err := access.SaveGroup(c, group); err != nil {
+		return nil, err
+	}
+
+	return group.ToAPI(), nil
+}
+
 func (a *API) DeleteGroup(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
 	return nil, access.DeleteGroup(c, r.ID)
 }
@@ -180,6 +261,10 @@ func (a *API) UpdateUsersInGroup(c *gin.Context, r *api.UpdateUsersInGroupReques
 	return nil, access.UpdateUsersInGroup(c, r.GroupID, r.UserIDsToAdd, r.UserIDsToRemove)
 }
 
+func (a *API) UpdateGroupsInGroup(c *gin.Context, r *api.UpdateGroupsInGroupRequest) (*api.EmptyResponse, error) {
+	return nil, access.UpdateGroupsInGroup(c, r.GroupID, r.GroupIDsToAdd, r.GroupIDsToRemove)
+}
+
 // caution: this endpoint is unauthenticated, do not return sensitive info
 func (a *API) ListProviders(c *gin.Context, r *api.ListProvidersRequest) (*api.ListResponse[api.Provider], error) {
 	exclude := []models.ProviderKind{models.ProviderKindInfra}
@@ -233,7 +318,7 @@ func (a *API) CreateProvider(c *gin.Context, r *api.CreateProviderRequest) (*api
 	}
 	provider.Kind = kind
 
-	if err := a.setProviderInfoFromServer(c, provider); err != nil {
+	if err := a.setProviderInfo(c, provider, r.URL, r.LDAP, r.SAML); err != nil {
 		return nil, err
 	}
 
@@ -261,7 +346,7 @@ func (a *API) UpdateProvider(c *gin.Context, r *api.UpdateProviderRequest) (*api
 	}
 	provider.Kind = kind
 
-	if err := a.setProviderInfoFromServer(c, provider); err != nil {
+	if err := a.setProviderInfo(c, provider, r.URL, r.LDAP, r.SAML); err != nil {
 		return nil, err
 	}
 
@@ -276,9 +361,73 @@ func (a *API) DeleteProvider(c *gin.Context, r *api.Resource) (*api.EmptyRespons
 	return nil, access.DeleteProvider(c, r.ID)
 }
 
+func (a *API) CreateProviderSCIMKey(c *gin.Context, r *api.CreateProviderSCIMKeyRequest) (*api.CreateAccessKeyResponse, error) {
+	accessKey, raw, err := access.CreateSCIMAccessKey(c, r.ID, time.Duration(r.TTL))
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.CreateAccessKeyResponse{
+		ID:         accessKey.ID,
+		Created:    api.Time(accessKey.CreatedAt),
+		Name:       accessKey.Name,
+		IssuedFor:  accessKey.IssuedFor,
+		ProviderID: accessKey.ProviderID,
+		Expires:    api.Time(accessKey.ExpiresAt),
+		AccessKey:  raw,
+	}, nil
+}
+
+func (a *API) ListOIDCClients(c *gin.Context, r *api.ListOIDCClientsRequest) (*api.ListResponse[api.OIDCClient], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	clients, err := access.ListOIDCClients(c, r.Name, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(clients, models.PaginationToResponse(p), func(client models.OIDCClient) api.OIDCClient {
+		return *client.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) CreateOIDCClient(c *gin.Context, r *api.CreateOIDCClientRequest) (*api.CreateOIDCClientResponse, error) {
+	client := &models.OIDCClient{
+		Name:             r.Name,
+		RedirectURIs:     r.RedirectURIs,
+		SigningAlgorithm: r.SigningAlgorithm,
+	}
+
+	for _, uri := range r.RedirectURIs {
+		if u, err := url.Parse(uri); err != nil || !u.IsAbs() || strings.Contains(uri, ",") {
+			return nil, fmt.Errorf("%w: invalid redirect uri %q", internal.ErrBadRequest, uri)
+		}
+	}
+
+	if err := access.CreateOIDCClient(c, client, r.Public); err != nil {
+		return nil, err
+	}
+
+	return &api.CreateOIDCClientResponse{
+		ID:               client.ID,
+		Created:          api.Time(client.CreatedAt),
+		Name:             client.Name,
+		ClientID:         client.ClientID,
+		ClientSecret:     client.ClientSecret,
+		RedirectURIs:     client.RedirectURIs,
+		SigningAlgorithm: client.SigningAlgorithm,
+		Public:           client.Public(),
+	}, nil
+}
+
+func (a *API) DeleteOIDCClient(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteOIDCClient(c, r.ID)
+}
+
 func (a *API) ListDestinations(c *gin.Context, r *api.ListDestinationsRequest) (*api.ListResponse[api.Destination], error) {
 	p := models.RequestToPagination(r.PaginationRequest)
-	destinations, err := access.ListDestinations(c, r.UniqueID, r.Name, &p)
+	destinations, err := access.ListDestinations(c, r.UniqueID, r.Name, r.Kind, &p)
 	if err != nil {
 		return nil, err
 	}
@@ -302,6 +451,7 @@ func (a *API) GetDestination(c *gin.Context, r *api.Resource) (*api.Destination,
 func (a *API) CreateDestination(c *gin.Context, r *api.CreateDestinationRequest) (*api.Destination, error) {
 	destination := &models.Destination{
 		Name:          r.Name,
+		Kind:          destinationKind(r.Kind),
 		UniqueID:      r.UniqueID,
 		ConnectionURL: r.Connection.URL,
 		ConnectionCA:  string(r.Connection.CA),
@@ -324,6 +474,7 @@ func (a *API) UpdateDestination(c *gin.Context, r *api.UpdateDestinationRequest)
 			ID: r.ID,
 		},
 		Name:          r.Name,
+		Kind:          destinationKind(r.Kind),
 		UniqueID:      r.UniqueID,
 		ConnectionURL: r.Connection.URL,
 		ConnectionCA:  string(r.Connection.CA),
@@ -343,6 +494,115 @@ func (a *API) DeleteDestination(c *gin.Context, r *api.Resource) (*api.EmptyResp
 	return nil, access.DeleteDestination(c, r.ID)
 }
 
+// destinationKind returns the kind of a destination. Connectors which do not
+// send a kind are kubernetes connectors.
+func destinationKind(kind string) string {
+	if kind == "" {
+		return models.DestinationKindKubernetes
+	}
+	return kind
+}
+
+func (a *API) CreateSSHCertificate(c *gin.Context, r *api.CreateSSHCertificateRequest) (*api.SSHCertificate, error) {
+	publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(r.PublicKey))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid public key: %v", internal.ErrBadRequest, err)
+	}
+
+	cert, err := access.CreateSSHCertificate(c, r.ID, publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SSHCertificate{
+		Certificate: string(ssh.MarshalAuthorizedKey(cert)),
+		Principals:  cert.ValidPrincipals,
+		Expires:     api.Time(time.Unix(int64(cert.ValidBefore), 0)),
+	}, nil
+}
+
+func (a *API) GetSSHCertificateAuthority(c *gin.Context, _ *api.EmptyRequest) (*api.SSHCertificateAuthority, error) {
+	publicKey, err := access.GetSSHCertificateAuthority(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.SSHCertificateAuthority{PublicKey: string(publicKey)}, nil
+}
+
+func (a *API) CreateCertificate(c *gin.Context, r *api.CreateCertificateRequest) (*api.Certificate, error) {
+	block, _ := pem.Decode([]byte(r.CSR))
+	if block == nil || block.Type != "CERTIFICATE REQUEST" {
+		return nil, fmt.Errorf("%w: csr must be a PEM encoded certificate request", internal.ErrBadRequest)
+	}
+
+	csr, err := x509.ParseCertificateRequest(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid csr: %v", internal.ErrBadRequest, err)
+	}
+
+	cert, raw, err := access.CreateCertificate(c, a.server.certificates, csr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &api.Certificate{Certificate: string(raw), Expires: api.Time(cert.NotAfter)}, nil
+}
+
+func (a *API) ListCertificateAuthorities(c *gin.Context, _ *api.EmptyRequest) (*api.ListResponse[api.CertificateAuthority], error) {
+	cas := a.server.certificates.ActiveCAs()
+
+	return api.NewListResponse(cas, api.PaginationResponse{}, func(ca x509.Certificate) api.CertificateAuthority {
+		return api.CertificateAuthority{
+			Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw})),
+			Expires:     api.Time(ca.NotAfter),
+		}
+	}), nil
+}
+
+// authorizeDestinationHandler logs in to an http destination. The browser of
+// the user is redirected back to the connector with a login code, which the
+// connector exchanges for a token. Users who are not logged in to Infra are
+// sent to the login page first.
+func (a *API) authorizeDestinationHandler(c *gin.Context) {
+	if err := RequireAccessKey(c); err != nil {
+		// a valid access key which can not be used here is not logged in again
+		var authzErr access.AuthorizationError
+		if errors.As(err, &authzErr) {
+			sendAPIError(c, err)
+			return
+		}
+		c.Redirect(http.StatusFound, "/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+		return
+	}
+
+	id, err := uid.Parse([]byte(c.Param("id")))
+	if err != nil {
+		sendAPIError(c, fmt.Errorf("%w: invalid destination id", internal.ErrBadRequest))
+		return
+	}
+
+	redirectURL := c.Query("redirect_uri")
+	code, err := access.CreateDestinationLogin(c, id, redirectURL)
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	u, err := url.Parse(redirectURL)
+	if err != nil {
+		sendAPIError(c, fmt.Errorf("%w: invalid redirect url", internal.ErrBadRequest))
+		return
+	}
+
+	query := u.Query()
+	query.Set("code", code)
+	query.Set("state", c.Query("state"))
+	u.RawQuery = query.Encode()
+
+	c.Redirect(http.StatusFound, u.String())
+}
+
 func (a *API) CreateToken(c *gin.Context, r *api.EmptyRequest) (*api.CreateTokenResponse, error) {
 	if access.AuthenticatedIdentity(c) != nil {
 		err := a.UpdateIdentityInfoFromProvider(c)
@@ -381,6 +641,14 @@ func (a *API) DeleteAccessKey(c *gin.Context, r *api.Resource) (*api.EmptyRespon
 }
 
 func (a *API) CreateAccessKey(c *gin.Context, r *api.CreateAccessKeyRequest) (*api.CreateAccessKeyResponse, error) {
+	if err := a.validateAccessKeyScopes(r.Scopes); err != nil {
+		return nil, err
+	}
+
+	if err := requireScopesWithinKey(c, r.Scopes); err != nil {
+		return nil, err
+	}
+
 	accessKey := &models.AccessKey{
 		IssuedFor:         r.UserID,
 		Name:              r.Name,
@@ -388,6 +656,7 @@ func (a *API) CreateAccessKey(c *gin.Context, r *api.CreateAccessKeyRequest) (*a
 		ExpiresAt:         time.Now().UTC().Add(time.Duration(r.TTL)),
 		Extension:         time.Duration(r.ExtensionDeadline),
 		ExtensionDeadline: time.Now().UTC().Add(time.Duration(r.ExtensionDeadline)),
+		Scopes:            r.Scopes,
 	}
 
 	raw, err := access.CreateAccessKey(c, accessKey)
@@ -402,6 +671,7 @@ func (a *API) CreateAccessKey(c *gin.Context, r *api.CreateAccessKeyRequest) (*a
 		IssuedFor:         accessKey.IssuedFor,
 		Expires:           api.Time(accessKey.ExpiresAt),
 		ExtensionDeadline: api.Time(accessKey.ExtensionDeadline),
+		Scopes:            accessKey.Scopes,
 		AccessKey:         raw,
 	}, nil
 }
@@ -416,7 +686,7 @@ func (a *API) ListGrants(c *gin.Context, r *api.ListGrantsRequest) (*api.ListRes
 		subject = uid.NewGroupPolymorphicID(r.Group)
 	}
 
-	grants, err := access.ListGrants(c, subject, r.Resource, r.Privilege, r.ShowInherited, &p)
+	grants, err := access.ListGrants(c, subject, r.Resource, r.Destination, r.Privilege, r.ShowInherited, r.ShowPending, &p)
 	if err != nil {
 		return nil, err
 	}
@@ -457,27 +727,50 @@ func (a *API) CreateGrant(c *gin.Context, r *api.CreateGrantRequest) (*api.Creat
 		subject = uid.NewGroupPolymorphicID(r.Group)
 	}
 
+	expiresAt := r.ExpiresAt.Time()
+	if !expiresAt.IsZero() {
+		if expiresAt.Before(time.Now()) {
+			return nil, fmt.Errorf("%w: expiresAt must be in the future", internal.ErrBadRequest)
+		}
+		if !expiresAt.After(r.NotBefore.Time()) {
+			return nil, fmt.Errorf("%w: expiresAt must be after notBefore", internal.ErrBadRequest)
+		}
+	}
+
+	conditions := models.NewGrantConditions(r.Conditions)
+	if err := conditions.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: conditions: %v", internal.ErrBadRequest, err)
+	}
+
 	grant := &models.Grant{
-		Subject:   subject,
-		Resource:  r.Resource,
-		Privilege: r.Privilege,
+		Subject:    subject,
+		Resource:   r.Resource,
+		Privilege:  r.Privilege,
+		NotBefore:  r.NotBefore.Time(),
+		ExpiresAt:  expiresAt,
+		Conditions: conditions,
 	}
 
 	err := access.CreateGrant(c, grant)
 	var ucerr data.UniqueConstraintError
 
 	if errors.As(err, &ucerr) {
-		grants, err := access.ListGrants(c, grant.Subject, grant.Resource, grant.Privilege, false, &models.Pagination{})
+		grants, err := access.ListGrants(c, grant.Subject, grant.Resource, "", grant.Privilege, false, true, &models.Pagination{})
 
 		if err != nil {
 			return nil, err
 		}
 
-		if len(grants) == 0 {
-			return nil, fmt.Errorf("duplicate grant exists, but cannot be found")
+		// the list also includes grants with a pattern which matches the
+		// resource, so look for the grant which caused the conflict
+		for _, existing := range grants {
+			if existing.Resource == grant.Resource && existing.NotBefore.Equal(grant.NotBefore) {
+				return &api.CreateGrantResponse{Grant: existing.ToAPI()}, nil
+			}
 		}
 
-		return &api.CreateGrantResponse{Grant: grants[0].ToAPI()}, nil
+		// the existing grant has expired, but has not been deleted yet
+		return nil, ucerr
 	}
 
 	if err != nil {
@@ -495,7 +788,7 @@ func (a *API) DeleteGrant(c *gin.Context, r *api.Resource) (*api.EmptyResponse,
 	}
 
 	if grant.Resource == access.ResourceInfraAPI && grant.Privilege == models.InfraAdminRole {
-		infraAdminGrants, err := access.ListGrants(c, "", grant.Resource, grant.Privilege, false, &models.Pagination{})
+		infraAdminGrants, err := access.ListGrants(c, "", grant.Resource, "", grant.Privilege, false, false, &models.Pagination{})
 		if err != nil {
 			return nil, err
 		}
@@ -508,6 +801,251 @@ func (a *API) DeleteGrant(c *gin.Context, r *api.Resource) (*api.EmptyResponse,
 	return nil, access.DeleteGrant(c, r.ID)
 }
 
+func (a *API) CheckAuthorization(c *gin.Context, r *api.AuthzCheckRequest) (*api.AuthzCheckResponse, error) {
+	subject := uid.NewIdentityPolymorphicID(r.User)
+	if r.Group != 0 {
+		subject = uid.NewGroupPolymorphicID(r.Group)
+	}
+
+	// the conditions of grants are checked against where the subject
+	// connects from, not where this request comes from
+	origin := access.Origin{Time: r.Time.Time()}
+	if origin.Time.IsZero() {
+		origin.Time = time.Now()
+	}
+	if r.ClientIP != "" {
+		origin.IP = net.ParseIP(r.ClientIP)
+		if origin.IP == nil {
+			return nil, fmt.Errorf("%w: clientIP must be an IP address", internal.ErrBadRequest)
+		}
+	}
+
+	decision, err := access.CheckAuthorization(c, subject, r.Privilege, r.Resource, origin)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &api.AuthzCheckResponse{
+		Allowed: decision.Allowed,
+		Reasons: []api.AuthzReason{},
+		Groups:  []string{},
+	}
+	for _, group := range decision.Groups {
+		result.Groups = append(result.Groups, group.Name)
+	}
+
+	for _, grant := range decision.Grants {
+		reason := api.AuthzReason{Grant: *grant.ToAPI()}
+		if groupID, err := grant.Subject.ID(); err == nil && grant.Subject.IsGroup() {
+			for _, group := range decision.Paths[groupID] {
+				reason.Path = append(reason.Path, group.Name)
+			}
+			if len(reason.Path) > 0 {
+				reason.Group = reason.Path[len(reason.Path)-1]
+			}
+		}
+		if grant.Privilege != r.Privilege {
+			reason.Role = grant.Privilege
+		}
+		result.Reasons = append(result.Reasons, reason)
+	}
+
+	return result, nil
+}
+
+func (a *API) ListRoles(c *gin.Context, r *api.ListRolesRequest) (*api.ListResponse[api.Role], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	roles, err := access.ListRoles(c, r.Name, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(roles, models.PaginationToResponse(p), func(role models.Role) api.Role {
+		return *role.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) GetRole(c *gin.Context, r *api.Resource) (*api.Role, error) {
+	role, err := access.GetRole(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return role.ToAPI(), nil
+}
+
+func (a *API) CreateRole(c *gin.Context, r *api.CreateRoleRequest) (*api.Role, error) {
+	role := &models.Role{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: r.Permissions,
+	}
+
+	if err := access.CreateRole(c, role); err != nil {
+		return nil, err
+	}
+
+	return role.ToAPI(), nil
+}
+
+func (a *API) UpdateRole(c *gin.Context, r *api.UpdateRoleRequest) (*api.Role, error) {
+	role, err := access.GetRole(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	role.Description = r.Description
+	role.Permissions = r.Permissions
+
+	if err := access.SaveRole(c, role); err != nil {
+		return nil, err
+	}
+
+	return role.ToAPI(), nil
+}
+
+func (a *API) DeleteRole(c *gin.Context, r *api.Resource) (*api.EmptyResponse, error) {
+	return nil, access.DeleteRole(c, r.ID)
+}
+
+func (a *API) ListAuditEvents(c *gin.Context, r *api.ListAuditEventsRequest) (*api.ListResponse[api.AuditEvent], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	events, err := access.ListAuditEvents(c, r.Actor, r.ResourceType, r.Since.Time(), r.Until.Time(), &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(events, models.PaginationToResponse(p), func(event models.AuditEvent) api.AuditEvent {
+		return *event.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) ListAccessRequests(c *gin.Context, r *api.ListAccessRequestsRequest) (*api.ListResponse[api.AccessRequest], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	requests, err := access.ListAccessRequests(c, r.User, r.Status, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(requests, models.PaginationToResponse(p), func(request models.AccessRequest) api.AccessRequest {
+		return *request.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) GetAccessRequest(c *gin.Context, r *api.Resource) (*api.AccessRequest, error) {
+	request, err := access.GetAccessRequest(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return request.ToAPI(), nil
+}
+
+func (a *API) CreateAccessRequest(c *gin.Context, r *api.CreateAccessRequestRequest) (*api.AccessRequest, error) {
+	request := &models.AccessRequest{
+		Privilege: r.Privilege,
+		Resource:  r.Resource,
+		Reason:    r.Reason,
+		Duration:  time.Duration(r.Duration),
+	}
+
+	if request.Duration < 0 {
+		return nil, fmt.Errorf("%w: duration must be positive", internal.ErrBadRequest)
+	}
+
+	if err := access.CreateAccessRequest(c, request); err != nil {
+		return nil, err
+	}
+
+	return request.ToAPI(), nil
+}
+
+func (a *API) ApproveAccessRequest(c *gin.Context, r *api.Resource) (*api.AccessRequest, error) {
+	request, err := access.ApproveAccessRequest(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return request.ToAPI(), nil
+}
+
+func (a *API) DenyAccessRequest(c *gin.Context, r *api.Resource) (*api.AccessRequest, error) {
+	request, err := access.DenyAccessRequest(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return request.ToAPI(), nil
+}
+
+func (a *API) ListAccessReviews(c *gin.Context, r *api.ListAccessReviewsRequest) (*api.ListResponse[api.AccessReview], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	reviews, err := access.ListAccessReviews(c, r.Status, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(reviews, models.PaginationToResponse(p), func(review models.AccessReview) api.AccessReview {
+		return *review.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) GetAccessReview(c *gin.Context, r *api.Resource) (*api.AccessReview, error) {
+	review, err := access.GetAccessReview(c, r.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return review.ToAPI(), nil
+}
+
+func (a *API) CreateAccessReview(c *gin.Context, r *api.CreateAccessReviewRequest) (*api.AccessReview, error) {
+	review := &models.AccessReview{
+		Name:        r.Name,
+		Destination: r.Destination,
+		GroupID:     r.Group,
+		Deadline:    time.Time(r.Deadline),
+		AutoRevoke:  r.AutoRevoke,
+	}
+
+	if err := access.CreateAccessReview(c, review, r.Reviewers); err != nil {
+		return nil, err
+	}
+
+	return review.ToAPI(), nil
+}
+
+func (a *API) ListAccessReviewItems(c *gin.Context, r *api.ListAccessReviewItemsRequest) (*api.ListResponse[api.AccessReviewItem], error) {
+	p := models.RequestToPagination(r.PaginationRequest)
+	items, err := access.ListAccessReviewItems(c, r.ID, r.Decision, &p)
+	if err != nil {
+		return nil, err
+	}
+
+	result := api.NewListResponse(items, models.PaginationToResponse(p), func(item models.AccessReviewItem) api.AccessReviewItem {
+		return *item.ToAPI()
+	})
+
+	return result, nil
+}
+
+func (a *API) DecideAccessReviewItem(c *gin.Context, r *api.DecideAccessReviewItemRequest) (*api.AccessReviewItem, error) {
+	item, err := access.DecideAccessReviewItem(c, r.ID, r.ItemID, r.Decision)
+	if err != nil {
+		return nil, err
+	}
+
+	return item.ToAPI(), nil
+}
+
 func (a *API) SignupEnabled(c *gin.Context, _ *api.EmptyRequest) (*api.SignupEnabledResponse, error) {
 	if !a.server.options.EnableSignup {
 		return &api.SignupEnabledResponse{Enabled: false}, nil
@@ -577,11 +1115,42 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 		}
 
 		loginMethod = authn.NewOIDCAuthentication(r.OIDC.ProviderID, r.OIDC.RedirectURL, r.OIDC.Code, providerClient)
+	case r.LDAP != nil:
+		provider, err := access.GetProvider(c, r.LDAP.ProviderID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity provider: %w", err)
+		}
+
+		ldapClient, err := a.server.ldapClient(provider)
+		if err != nil {
+			return nil, fmt.Errorf("ldap provider client: %w", err)
+		}
+
+		loginMethod = authn.NewLDAPAuthentication(provider.ID, r.LDAP.Name, r.LDAP.Password, ldapClient)
+	case r.SAML != nil:
+		loginMethod = authn.NewSAMLAuthentication(r.SAML.ProviderID, r.SAML.Code)
+	case r.MFA != nil:
+		loginMethod = authn.NewMFAAuthentication(r.MFA.Token, r.MFA.Code)
+	case r.DeviceCode != "":
+		loginMethod = authn.NewDeviceFlowAuthentication(r.DeviceCode)
+	case r.ClientCertificate:
+		loginMethod = authn.NewCertificateAuthentication(c.Request.TLS, a.server.certificates)
+	case r.WorkloadToken != "":
+		// workloads get short-lived access keys, they login again when needed
+		expires = time.Now().UTC().Add(a.server.options.WorkloadIdentity.AccessKeyDuration)
+		loginMethod = authn.NewWorkloadIdentityAuthentication(r.WorkloadToken, a.server.workloadIssuers)
 	default:
 		// make sure to always fail by default
 		return nil, fmt.Errorf("%w: missing login credentials", internal.ErrBadRequest)
 	}
 
+	lockouts := a.loginLockouts(c, r)
+	for _, l := range lockouts {
+		if err := access.CheckLoginLockout(c, l.LoginLockout, l.key); err != nil {
+			return nil, err
+		}
+	}
+
 	// do the actual login now that we know the method selected
 	key, bearer, requiresUpdate, err := access.Login(c, loginMethod, expires, a.server.options.SessionExtensionDeadline)
 	if err != nil {
@@ -590,15 +1159,102 @@ func (a *API) Login(c *gin.Context, r *api.LoginRequest) (*api.LoginResponse, er
 			// this means an external request failed, probably to an IDP
 			return nil, err
 		}
+		if errors.Is(err, authn.ErrDeviceAuthorizationPending) || errors.Is(err, authn.ErrDeviceSlowDown) {
+			// the device polls until the user approves, this is not a failed login
+			return nil, fmt.Errorf("%w: %v", internal.ErrBadRequest, err)
+		}
+
+		a.loginFailures.WithLabelValues(loginMethod.Name()).Inc()
+		for _, l := range lockouts {
+			if err := access.LoginFailed(c, l.LoginLockout, l.key); err != nil {
+				logging.Errorf("record failed login: %v", err)
+			}
+		}
+
 		// all other failures from login should result in an unauthorized response
 		return nil, fmt.Errorf("%w: login failed: %v", internal.ErrUnauthorized, err)
 	}
 
+	for _, l := range lockouts {
+		if l.Kind == models.LoginFailureKindUser {
+			if err := access.LoginSucceeded(c, l.LoginLockout, l.key); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if key.Scopes.Includes(models.ScopeMFAChallenge) {
+		// the login is completed by a second request with a code
+		return &api.LoginResponse{UserID: key.IssuedFor, Name: key.IssuedForIdentity.Name, MFAToken: bearer, Expires: api.Time(key.ExpiresAt)}, nil
+	}
+
 	setAuthCookie(c, bearer, expires)
 
 	a.t.Event("login", key.IssuedFor.String(), Properties{"method": loginMethod.Name()})
 
-	return &api.LoginResponse{UserID: key.IssuedFor, Name: key.IssuedForIdentity.Name, AccessKey: bearer, Expires: api.Time(expires), PasswordUpdateRequired: requiresUpdate}, nil
+	return &api.LoginResponse{
+		UserID:                 key.IssuedFor,
+		Name:                   key.IssuedForIdentity.Name,
+		AccessKey:              bearer,
+		Expires:                api.Time(expires),
+		PasswordUpdateRequired: requiresUpdate,
+		MFAEnrollmentRequired:  key.Scopes.Includes(models.ScopeMFAEnrollment),
+	}, nil
+}
+
+// loginLockout is a lockout, and the username or client IP it applies to
+type loginLockout struct {
+	authn.LoginLockout
+	key string
+}
+
+// loginLockouts returns the lockouts which apply to a login request. Every
+// login is limited by client IP, and password logins by username as well. The
+// users of an LDAP provider are locked out by the directory itself.
+func (a *API) loginLockouts(c *gin.Context, r *api.LoginRequest) []loginLockout {
+	opts := a.server.options.LoginLockout
+	lockout := func(kind string, maxFailures int, key string) loginLockout {
+		return loginLockout{
+			LoginLockout: authn.LoginLockout{
+				Kind:        kind,
+				MaxFailures: maxFailures,
+				Duration:    opts.Duration,
+				MaxDuration: opts.MaxDuration,
+			},
+			key: key,
+		}
+	}
+
+	var lockouts []loginLockout
+	if r.PasswordCredentials != nil {
+		lockouts = append(lockouts, lockout(models.LoginFailureKindUser, opts.UserMaxFailures, r.PasswordCredentials.Name))
+	}
+
+	// the address of the peer, as forwarded for headers can be set by the client
+	return append(lockouts, lockout(models.LoginFailureKindIP, opts.IPMaxFailures, c.RemoteIP()))
+}
+
+// caution: this endpoint is unauthenticated, do not return sensitive info
+func (a *API) StartDeviceFlow(c *gin.Context, r *api.EmptyRequest) (*api.StartDeviceFlowResponse, error) {
+	request, err := access.StartDeviceFlow(c)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationURI := a.baseURL(c)
+	verificationURI.Path = path.Join(verificationURI.Path, "/device")
+
+	return &api.StartDeviceFlowResponse{
+		DeviceCode:          request.DeviceCode,
+		UserCode:            authn.FormatUserCode(request.UserCode),
+		VerificationURI:     verificationURI.String(),
+		PollIntervalSeconds: int(authn.DeviceFlowPollInterval.Seconds()),
+		Expires:             api.Time(request.ExpiresAt),
+	}, nil
+}
+
+func (a *API) ApproveDeviceFlow(c *gin.Context, r *api.ApproveDeviceFlowRequest) (*api.EmptyResponse, error) {
+	return nil, access.ApproveDeviceFlow(c, r.UserCode)
 }
 
 func (a *API) Logout(c *gin.Context, r *api.EmptyRequest) (*api.EmptyResponse, error) {
@@ -627,6 +1283,11 @@ func (a *API) UpdateIdentityInfoFromProvider(c *gin.Context) error {
 		return nil
 	}
 
+	// the groups of LDAP and SAML users are updated when they login
+	if provider.Kind == models.ProviderKindLDAP || provider.Kind == models.ProviderKindSAML {
+		return nil
+	}
+
 	oidc, err := a.providerClient(c, provider, redirectURL)
 	if err != nil {
 		return fmt.Errorf("update provider client: %w", err)
@@ -636,18 +1297,95 @@ func (a *API) UpdateIdentityInfoFromProvider(c *gin.Context) error {
 }
 
 func (a *API) providerClient(ctx context.Context, provider *models.Provider, redirectURL string) (providers.OIDCClient, error) {
-	if c := providers.OIDCClientFromContext(ctx); c != nil {
-		// oidc is added to the context during unit tests
-		return c, nil
+	return a.server.providerClient(ctx, provider, redirectURL)
+}
+
+// setProviderInfo checks the provider can be used to login. LDAP and SAML
+// providers are configured by the request, and the info of other providers is
+// provided by their OIDC server.
+func (a *API) setProviderInfo(c *gin.Context, provider *models.Provider, rawURL string, ldap *api.ProviderLDAP, samlConfig *api.ProviderSAML) error {
+	switch provider.Kind {
+	case models.ProviderKindLDAP:
+		return a.setLDAPProviderInfo(c, provider, rawURL, ldap)
+	case models.ProviderKindSAML:
+		return a.setSAMLProviderInfo(c, provider, rawURL, samlConfig)
 	}
 
-	clientSecret, err := secrets.GetSecret(string(provider.ClientSecret), a.server.secrets)
+	return a.setProviderInfoFromServer(c, provider)
+}
+
+// setLDAPProviderInfo sets the directory configuration of an LDAP provider,
+// and checks the directory can be searched
+func (a *API) setLDAPProviderInfo(c *gin.Context, provider *models.Provider, rawURL string, ldap *api.ProviderLDAP) error {
+	// the ldap:// or ldaps:// scheme is required
+	provider.URL = strings.TrimSpace(rawURL)
+	provider.ClientID = ""
+	provider.ClientSecret = ""
+	provider.LDAPBindDN = ldap.BindDN
+	provider.LDAPBindPassword = models.EncryptedAtRest(ldap.BindPassword)
+	provider.LDAPSearchBase = ldap.SearchBase
+	provider.LDAPUserFilter = ldap.UserFilter
+	provider.LDAPGroupFilter = ldap.GroupFilter
+
+	if provider.LDAPUserFilter == "" {
+		provider.LDAPUserFilter = providers.DefaultLDAPUserFilter
+	}
+
+	if provider.LDAPGroupFilter == "" {
+		provider.LDAPGroupFilter = providers.DefaultLDAPGroupFilter
+	}
+
+	client, err := a.server.ldapClient(provider)
 	if err != nil {
-		logging.Debugf("could not get client secret: %s", err)
-		return nil, fmt.Errorf("client secret not found")
+		return fmt.Errorf("%w: %s", internal.ErrBadRequest, err)
 	}
 
-	return providers.NewOIDCClient(*provider, clientSecret, redirectURL), nil
+	if err := client.Validate(c); err != nil {
+		if errors.Is(err, providers.ErrValidation) {
+			return fmt.Errorf("%w: %s", internal.ErrBadRequest, err)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// setSAMLProviderInfo imports the metadata of the identity provider of a SAML
+// provider. The metadata is downloaded from the URL of the provider, unless
+// the request includes it.
+func (a *API) setSAMLProviderInfo(c *gin.Context, provider *models.Provider, rawURL string, config *api.ProviderSAML) error {
+	if config == nil {
+		config = &api.ProviderSAML{}
+	}
+
+	// the URL is the metadata URL, the scheme is required
+	provider.URL = strings.TrimSpace(rawURL)
+	provider.ClientID = ""
+	provider.ClientSecret = ""
+	provider.SAMLGroupsAttribute = config.GroupsAttribute
+	if provider.SAMLGroupsAttribute == "" {
+		provider.SAMLGroupsAttribute = providers.DefaultSAMLGroupsAttribute
+	}
+
+	metadata := []byte(config.Metadata)
+	if len(metadata) == 0 {
+		var err error
+		metadata, err = providers.FetchSAMLMetadata(c, provider.URL)
+		if err != nil {
+			if errors.Is(err, providers.ErrValidation) {
+				return fmt.Errorf("%w: %s", internal.ErrBadRequest, err)
+			}
+			return err
+		}
+	}
+
+	if _, err := providers.ParseSAMLMetadata(metadata); err != nil {
+		return fmt.Errorf("%w: %s", internal.ErrBadRequest, err)
+	}
+
+	provider.SAMLMetadata = string(metadata)
+
+	return nil
 }
 
 // setProviderInfoFromServer checks information provided by an OIDC server
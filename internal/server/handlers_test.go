@@ -382,6 +382,55 @@ func TestAPI_CreateAccessKey(t *testing.T) {
 				assert.DeepEqual(t, respBody.FieldErrors, expected)
 			},
 		},
+		{
+			name: "scopes",
+			setup: func(t *testing.T) api.CreateAccessKeyRequest {
+				return api.CreateAccessKeyRequest{
+					UserID:            userResp.ID,
+					TTL:               api.Duration(time.Minute),
+					ExtensionDeadline: api.Duration(time.Minute),
+					Scopes:            []string{"grants:read", "resource:production", "cidr:10.0.0.0/8"},
+				}
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+				respBody := &api.CreateAccessKeyResponse{}
+				err := json.Unmarshal(resp.Body.Bytes(), respBody)
+				assert.NilError(t, err)
+				assert.DeepEqual(t, respBody.Scopes, []string{"grants:read", "resource:production", "cidr:10.0.0.0/8"})
+			},
+		},
+		{
+			name: "invalid scopes",
+			setup: func(t *testing.T) api.CreateAccessKeyRequest {
+				return api.CreateAccessKeyRequest{
+					UserID:            userResp.ID,
+					TTL:               api.Duration(time.Minute),
+					ExtensionDeadline: api.Duration(time.Minute),
+					Scopes:            []string{"password-reset"},
+				}
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+				assert.Assert(t, strings.Contains(resp.Body.String(), `unknown scope \"password-reset\"`), resp.Body.String())
+			},
+		},
+		{
+			name: "unknown api scope",
+			setup: func(t *testing.T) api.CreateAccessKeyRequest {
+				return api.CreateAccessKeyRequest{
+					UserID:            userResp.ID,
+					TTL:               api.Duration(time.Minute),
+					ExtensionDeadline: api.Duration(time.Minute),
+					Scopes:            []string{"widgets:read"},
+				}
+			},
+			expected: func(t *testing.T, resp *httptest.ResponseRecorder) {
+				assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+				assert.Assert(t, strings.Contains(resp.Body.String(), `unknown scope \"widgets\"`), resp.Body.String())
+			},
+		},
 	}
 
 	for _, tc := range testCases {
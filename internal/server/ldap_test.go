@@ -0,0 +1,143 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/ldaptest"
+)
+
+func TestAPI_LDAPProvider(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	directory := ldaptest.NewServer(t,
+		ldaptest.Entry{DN: "cn=infra,dc=example,dc=com", Password: "service-password"},
+		ldaptest.Entry{
+			DN:         "cn=Alice,cn=Users,dc=example,dc=com",
+			Attributes: map[string][]string{"sAMAccountName": {"alice"}, "mail": {"alice@example.com"}},
+			Password:   "alice-password",
+		},
+		ldaptest.Entry{
+			DN: "cn=Developers,cn=Users,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"cn":     {"Developers"},
+				"member": {"cn=Alice,cn=Users,dc=example,dc=com"},
+			},
+		},
+	)
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		if key != "" {
+			req.Header.Add("Authorization", "Bearer "+key)
+		}
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	createReq := api.CreateProviderRequest{
+		Name: "ad",
+		URL:  directory.URL,
+		Kind: "ldap",
+		LDAP: &api.ProviderLDAP{
+			BindDN:       "cn=infra,dc=example,dc=com",
+			BindPassword: "service-password",
+			SearchBase:   "dc=example,dc=com",
+			UserFilter:   "(sAMAccountName=%s)",
+		},
+	}
+
+	t.Run("create with invalid bind password", func(t *testing.T) {
+		invalid := createReq
+		ldap := *createReq.LDAP
+		ldap.BindPassword = "wrong"
+		invalid.LDAP = &ldap
+
+		resp := call(t, http.MethodPost, "/api/providers", adminAccessKey(srv), invalid)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("create requires ldap configuration", func(t *testing.T) {
+		invalid := createReq
+		invalid.LDAP = nil
+
+		resp := call(t, http.MethodPost, "/api/providers", adminAccessKey(srv), invalid)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		var apiErr api.Error
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &apiErr))
+		assert.DeepEqual(t, apiErr.FieldErrors, []api.FieldError{{FieldName: "ldap", Errors: []string{"is required"}}})
+	})
+
+	resp := call(t, http.MethodPost, "/api/providers", adminAccessKey(srv), createReq)
+	assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+	var provider api.Provider
+	assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &provider))
+	assert.Equal(t, provider.Kind, "ldap")
+	assert.Equal(t, provider.URL, directory.URL)
+
+	stored, err := data.GetProvider(srv.db, data.ByID(provider.ID))
+	assert.NilError(t, err)
+	assert.Equal(t, stored.LDAPGroupFilter, "(member=%s)")
+
+	login := func(t *testing.T, name, password string) (*httptest.ResponseRecorder, api.LoginResponse) {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/login", "", api.LoginRequest{
+			LDAP: &api.LoginRequestLDAP{ProviderID: provider.ID, Name: name, Password: password},
+		})
+
+		var loginResp api.LoginResponse
+		if resp.Code == http.StatusCreated {
+			assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &loginResp))
+		}
+		return resp, loginResp
+	}
+
+	t.Run("login", func(t *testing.T) {
+		resp, loginResp := login(t, "alice", "alice-password")
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.Equal(t, loginResp.Name, "alice@example.com")
+
+		groups, err := data.ListGroups(srv.db, &models.Pagination{}, data.ByGroupMember(loginResp.UserID))
+		assert.NilError(t, err)
+		assert.Equal(t, len(groups), 1)
+		assert.Equal(t, groups[0].Name, "Developers")
+
+		resp = call(t, http.MethodGet, "/api/users/"+loginResp.UserID.String(), loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+
+	t.Run("login with invalid password", func(t *testing.T) {
+		resp, _ := login(t, "alice", "wrong")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("login with unknown user", func(t *testing.T) {
+		resp, _ := login(t, "bob", "alice-password")
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("login with an oidc provider", func(t *testing.T) {
+		oidcProvider := &models.Provider{Name: "okta", Kind: models.ProviderKindOkta}
+		assert.NilError(t, data.CreateProvider(srv.db, oidcProvider))
+
+		resp := call(t, http.MethodPost, "/api/login", "", api.LoginRequest{
+			LDAP: &api.LoginRequestLDAP{ProviderID: oidcProvider.ID, Name: "alice", Password: "alice-password"},
+		})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+}
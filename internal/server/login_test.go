@@ -2,9 +2,11 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/crypto/bcrypt"
@@ -13,6 +15,7 @@ import (
 	"github.com/infrahq/infra/api"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/jwkstest"
 	"github.com/infrahq/infra/uid"
 )
 
@@ -103,7 +106,7 @@ func TestAPI_Login(t *testing.T) {
 				assert.NilError(t, err)
 
 				expected := []api.FieldError{
-					{Errors: []string{"one of (accessKey, passwordCredentials, oidc) is required"}},
+					{Errors: []string{"one of (accessKey, passwordCredentials, oidc, ldap, saml, mfa, deviceCode, workloadToken, clientCertificate) is required"}},
 				}
 				assert.DeepEqual(t, respBody.FieldErrors, expected)
 			},
@@ -201,3 +204,218 @@ func TestAPI_Login(t *testing.T) {
 	}
 
 }
+
+func TestAPI_LoginLockout(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	srv.options.LoginLockout = LoginLockoutOptions{
+		UserMaxFailures: 3,
+		IPMaxFailures:   5,
+		Duration:        time.Minute,
+		MaxDuration:     time.Hour,
+	}
+	registry := prometheus.NewRegistry()
+	routes := srv.GenerateRoutes(registry)
+
+	createUser := func(t *testing.T, name string) *models.Identity {
+		t.Helper()
+		user := &models.Identity{Name: name}
+		err := data.CreateIdentity(srv.db, user)
+		assert.NilError(t, err)
+
+		_, err = data.CreateProviderUser(srv.db, data.InfraProvider(srv.db), user)
+		assert.NilError(t, err)
+
+		hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+		assert.NilError(t, err)
+
+		err = data.CreateCredential(srv.db, &models.Credential{IdentityID: user.ID, PasswordHash: hash})
+		assert.NilError(t, err)
+		return user
+	}
+
+	loginForwardedFor := func(t *testing.T, remoteAddr, forwardedFor, name, password string) *httptest.ResponseRecorder {
+		t.Helper()
+		body := jsonBody(t, api.LoginRequest{
+			PasswordCredentials: &api.LoginRequestPasswordCredentials{Name: name, Password: password},
+		})
+		req := httptest.NewRequest(http.MethodPost, "/api/login", body)
+		req.RemoteAddr = remoteAddr
+		req.Header.Add("Infra-Version", "0.13.6")
+		if forwardedFor != "" {
+			req.Header.Add("X-Forwarded-For", forwardedFor)
+		}
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	login := func(t *testing.T, remoteAddr, name, password string) *httptest.ResponseRecorder {
+		t.Helper()
+		return loginForwardedFor(t, remoteAddr, "", name, password)
+	}
+
+	unlock := func(t *testing.T, user *models.Identity) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodDelete, "/api/users/"+user.ID.String()+"/lockout", nil)
+		req.Header.Add("Authorization", "Bearer "+adminAccessKey(srv))
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+	}
+
+	failures := func(t *testing.T) float64 {
+		t.Helper()
+		families, err := registry.Gather()
+		assert.NilError(t, err)
+		for _, family := range families {
+			if family.GetName() != "infra_login_failures_total" {
+				continue
+			}
+			for _, metric := range family.GetMetric() {
+				for _, label := range metric.GetLabel() {
+					if label.GetName() == "method" && label.GetValue() == "credentials" {
+						return metric.GetCounter().GetValue()
+					}
+				}
+			}
+		}
+		return 0
+	}
+
+	t.Run("username is locked", func(t *testing.T) {
+		user := createUser(t, "alice@example.com")
+
+		for i := 0; i < 3; i++ {
+			resp := login(t, "10.0.0.1:1234", user.Name, "wrong")
+			assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+		}
+		assert.Equal(t, failures(t), float64(3))
+
+		// the correct password from another address is still locked out
+		resp := login(t, "10.0.0.2:1234", user.Name, "hunter2")
+		assert.Equal(t, resp.Code, http.StatusTooManyRequests, resp.Body.String())
+
+		unlock(t, user)
+
+		resp = login(t, "10.0.0.2:1234", user.Name, "hunter2")
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+
+	t.Run("successful login resets the username", func(t *testing.T) {
+		user := createUser(t, "bob@example.com")
+
+		for i := 0; i < 2; i++ {
+			resp := login(t, "10.0.0.3:1234", user.Name, "wrong")
+			assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+		}
+
+		resp := login(t, "10.0.0.3:1234", user.Name, "hunter2")
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		for i := 0; i < 2; i++ {
+			resp := login(t, "10.0.0.4:1234", user.Name, "wrong")
+			assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+		}
+
+		resp = login(t, "10.0.0.4:1234", user.Name, "hunter2")
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+
+	t.Run("client IP is locked", func(t *testing.T) {
+		user := createUser(t, "carol@example.com")
+
+		for i := 0; i < 5; i++ {
+			resp := login(t, "10.0.0.5:1234", fmt.Sprintf("unknown-%d@example.com", i), "wrong")
+			assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+		}
+
+		resp := login(t, "10.0.0.5:1234", user.Name, "hunter2")
+		assert.Equal(t, resp.Code, http.StatusTooManyRequests, resp.Body.String())
+
+		resp = login(t, "10.0.0.6:1234", user.Name, "hunter2")
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+
+	t.Run("forwarded for header is ignored", func(t *testing.T) {
+		user := createUser(t, "dave@example.com")
+
+		// a client can't avoid the lockout by changing the header
+		for i := 0; i < 5; i++ {
+			resp := loginForwardedFor(t, "10.0.0.7:1234", fmt.Sprintf("192.168.0.%d", i), fmt.Sprintf("unknown-%d@example.com", i), "wrong")
+			assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+		}
+
+		resp := loginForwardedFor(t, "10.0.0.7:1234", "192.168.0.100", user.Name, "hunter2")
+		assert.Equal(t, resp.Code, http.StatusTooManyRequests, resp.Body.String())
+
+		// or lock out another address by sending it in the header
+		resp = loginForwardedFor(t, "10.0.0.7:1234", "10.0.0.8", user.Name, "hunter2")
+		assert.Equal(t, resp.Code, http.StatusTooManyRequests, resp.Body.String())
+		resp = login(t, "10.0.0.8:1234", user.Name, "hunter2")
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+}
+
+func TestAPI_LoginWorkloadIdentity(t *testing.T) {
+	issuer := jwkstest.NewIssuer(t)
+
+	srv := setupServer(t, withAdminUser, func(_ *testing.T, opts *Options) {
+		opts.WorkloadIdentity = WorkloadIdentityOptions{
+			AccessKeyDuration: 15 * time.Minute,
+			Issuers: []WorkloadIssuerOptions{{
+				Issuer:   issuer.URL,
+				JWKSURL:  issuer.JWKSURL,
+				Audience: "infra",
+				Rules: []WorkloadIdentityRuleOptions{{
+					Claims:   map[string]string{"kubernetes.io/namespace": "ci", "kubernetes.io/serviceaccount": "deploy"},
+					Identity: "deploy@ci.example.com",
+				}},
+			}},
+		}
+	})
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	user := &models.Identity{Name: "deploy@ci.example.com"}
+	assert.NilError(t, data.CreateIdentity(srv.db, user))
+
+	login := func(t *testing.T, token string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/login", jsonBody(t, api.LoginRequest{WorkloadToken: token}))
+		req.Header.Add("Infra-Version", "0.13.3")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	t.Run("token matches a rule", func(t *testing.T) {
+		resp := login(t, issuer.Token(t, map[string]interface{}{
+			"aud":                          "infra",
+			"kubernetes.io/namespace":      "ci",
+			"kubernetes.io/serviceaccount": "deploy",
+		}))
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var loginResp api.LoginResponse
+		assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &loginResp))
+		assert.Equal(t, loginResp.UserID, user.ID)
+		assert.Assert(t, time.Until(time.Time(loginResp.Expires)) <= 15*time.Minute)
+
+		key, err := data.ValidateAccessKey(srv.db, loginResp.AccessKey)
+		assert.NilError(t, err)
+		assert.Equal(t, key.IssuedFor, user.ID)
+		assert.Assert(t, time.Until(key.ExpiresAt) <= 15*time.Minute)
+	})
+
+	t.Run("token does not match a rule", func(t *testing.T) {
+		resp := login(t, issuer.Token(t, map[string]interface{}{
+			"aud":                          "infra",
+			"kubernetes.io/namespace":      "default",
+			"kubernetes.io/serviceaccount": "deploy",
+		}))
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+}
@@ -138,5 +138,29 @@ func setupMetrics(db *gorm.DB) *prometheus.Registry {
 		return values
 	}))
 
+	registry.MustRegister(metrics.NewCollector(prometheus.Opts{
+		Namespace: "infra",
+		Name:      "provider_user_syncs",
+		Help:      "The number of provider users by the result of their last sync with the provider",
+	}, []string{"provider", "result"}, func() []metrics.Metric {
+		var results []struct {
+			Provider   string
+			SyncStatus string
+			Count      int
+		}
+
+		if err := db.Raw("SELECT providers.name AS provider, provider_users.sync_status, COUNT(*) AS count FROM provider_users JOIN providers ON providers.id = provider_users.provider_id WHERE provider_users.deleted_at IS NULL AND providers.deleted_at IS NULL AND provider_users.sync_status <> '' GROUP BY providers.name, provider_users.sync_status").Scan(&results).Error; err != nil {
+			logging.L.Warn().Err(err).Msg("provider user syncs")
+			return []metrics.Metric{}
+		}
+
+		values := make([]metrics.Metric, 0, len(results))
+		for _, result := range results {
+			values = append(values, metrics.Metric{Count: float64(result.Count), LabelValues: []string{result.Provider, result.SyncStatus}})
+		}
+
+		return values
+	}))
+
 	return registry
 }
@@ -2,6 +2,7 @@ package server
 
 import (
 	"bytes"
+	"fmt"
 	"io/ioutil"
 	"regexp"
 	"testing"
@@ -82,6 +83,27 @@ func TestMetrics(t *testing.T) {
 		golden.Assert(t, string(actual), t.Name())
 	})
 
+	t.Run("infra provider user syncs", func(t *testing.T) {
+		db := setupDB(t)
+
+		provider := &models.Provider{Name: "okta", Kind: "okta"}
+		assert.NilError(t, data.CreateProvider(db, provider))
+
+		for i, status := range []string{models.ProviderUserSyncSuccess, models.ProviderUserSyncSuccess, models.ProviderUserSyncFailed, ""} {
+			identity := &models.Identity{Name: fmt.Sprintf("user%d", i)}
+			assert.NilError(t, data.CreateIdentity(db, identity))
+
+			pu, err := data.CreateProviderUser(db, provider, identity)
+			assert.NilError(t, err)
+
+			pu.SyncStatus = status
+			assert.NilError(t, data.UpdateProviderUser(db, pu))
+		}
+
+		actual := run(db, `infra_provider_user_syncs({.*})? \d+`)
+		golden.Assert(t, string(actual), t.Name())
+	})
+
 	t.Run("infra destinations", func(t *testing.T) {
 		db := setupDB(t)
 
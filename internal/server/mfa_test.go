@@ -0,0 +1,272 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/bcrypt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/authn"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_MFA(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	createUser := func(t *testing.T, name string) *models.Identity {
+		t.Helper()
+		user := &models.Identity{Name: name}
+		err := data.CreateIdentity(srv.db, user)
+		assert.NilError(t, err)
+
+		_, err = data.CreateProviderUser(srv.db, data.InfraProvider(srv.db), user)
+		assert.NilError(t, err)
+
+		hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+		assert.NilError(t, err)
+
+		err = data.CreateCredential(srv.db, &models.Credential{IdentityID: user.ID, PasswordHash: hash})
+		assert.NilError(t, err)
+		return user
+	}
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(method, path, jsonBody(t, body))
+		assert.NilError(t, err)
+		if key != "" {
+			req.Header.Add("Authorization", "Bearer "+key)
+		}
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	login := func(t *testing.T, req api.LoginRequest) (*httptest.ResponseRecorder, api.LoginResponse) {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/login", "", req)
+
+		var loginResp api.LoginResponse
+		if resp.Code == http.StatusCreated {
+			err := json.Unmarshal(resp.Body.Bytes(), &loginResp)
+			assert.NilError(t, err)
+		}
+		return resp, loginResp
+	}
+
+	passwordLogin := func(name string) api.LoginRequest {
+		return api.LoginRequest{PasswordCredentials: &api.LoginRequestPasswordCredentials{Name: name, Password: "hunter2"}}
+	}
+
+	enroll := func(t *testing.T, user *models.Identity, key string) (secret string, recoveryCodes []string) {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/users/"+user.ID.String()+"/mfa", key, api.EmptyRequest{})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var created api.CreateMFAResponse
+		err := json.Unmarshal(resp.Body.Bytes(), &created)
+		assert.NilError(t, err)
+		assert.Assert(t, created.Secret != "")
+		assert.Equal(t, created.ProvisioningURI, authn.TOTPProvisioningURI("Infra", user.Name, created.Secret))
+
+		resp = call(t, http.MethodPost, "/api/users/"+user.ID.String()+"/mfa/verify", key, api.VerifyMFARequest{Code: "000000"})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		code, err := authn.TOTPCode(created.Secret, time.Now())
+		assert.NilError(t, err)
+
+		resp = call(t, http.MethodPost, "/api/users/"+user.ID.String()+"/mfa/verify", key, api.VerifyMFARequest{Code: code})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var verified api.VerifyMFAResponse
+		err = json.Unmarshal(resp.Body.Bytes(), &verified)
+		assert.NilError(t, err)
+		assert.Equal(t, len(verified.RecoveryCodes), 10)
+
+		return created.Secret, verified.RecoveryCodes
+	}
+
+	alice := createUser(t, "alice@example.com")
+
+	_, loginResp := login(t, passwordLogin(alice.Name))
+	assert.Assert(t, loginResp.AccessKey != "")
+	secret, recoveryCodes := enroll(t, alice, loginResp.AccessKey)
+
+	credential, err := data.GetCredential(srv.db, data.ByIdentityID(alice.ID))
+	assert.NilError(t, err)
+	assert.Assert(t, credential.MFAEnabled)
+	for _, code := range recoveryCodes {
+		assert.Assert(t, !credential.MFARecoveryCodes.Includes(code), "recovery codes must be hashed")
+	}
+
+	t.Run("enrolling twice", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/users/"+alice.ID.String()+"/mfa", loginResp.AccessKey, api.EmptyRequest{})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("enrolling another user", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/users/"+alice.ID.String()+"/mfa", adminAccessKey(srv), api.EmptyRequest{})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	// each code can only be used once, so later steps use codes from the
+	// next periods, which are accepted to allow for clock drift
+	nextCode := func(t *testing.T, periods int) string {
+		t.Helper()
+		code, err := authn.TOTPCode(secret, time.Now().Add(time.Duration(periods)*30*time.Second))
+		assert.NilError(t, err)
+		return code
+	}
+
+	t.Run("login with a code", func(t *testing.T) {
+		resp, challenge := login(t, passwordLogin(alice.Name))
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.Equal(t, challenge.AccessKey, "")
+		assert.Assert(t, challenge.MFAToken != "")
+		assert.Equal(t, len(resp.Result().Cookies()), 0)
+
+		// the token is not an access key
+		resp = call(t, http.MethodGet, "/api/users/"+alice.ID.String(), challenge.MFAToken, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		resp, _ = login(t, api.LoginRequest{AccessKey: challenge.MFAToken})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		// the code used to enroll can't be used again
+		code, err := authn.TOTPCode(secret, time.Now())
+		assert.NilError(t, err)
+		resp, _ = login(t, api.LoginRequest{MFA: &api.LoginRequestMFA{Token: challenge.MFAToken, Code: code}})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		_, challenge = login(t, passwordLogin(alice.Name))
+		code = nextCode(t, 1)
+		resp, loggedIn := login(t, api.LoginRequest{MFA: &api.LoginRequestMFA{Token: challenge.MFAToken, Code: code}})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.Assert(t, loggedIn.AccessKey != "")
+		assert.Equal(t, loggedIn.UserID, alice.ID)
+
+		resp = call(t, http.MethodGet, "/api/users/"+alice.ID.String(), loggedIn.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		// the token can only be used once
+		resp, _ = login(t, api.LoginRequest{MFA: &api.LoginRequestMFA{Token: challenge.MFAToken, Code: code}})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		// the code can only be used once
+		_, challenge = login(t, passwordLogin(alice.Name))
+		resp, _ = login(t, api.LoginRequest{MFA: &api.LoginRequestMFA{Token: challenge.MFAToken, Code: code}})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("wrong code", func(t *testing.T) {
+		_, challenge := login(t, passwordLogin(alice.Name))
+
+		resp, _ := login(t, api.LoginRequest{MFA: &api.LoginRequestMFA{Token: challenge.MFAToken, Code: "000000"}})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		// a failed attempt uses up the token
+		code := nextCode(t, 1)
+		resp, _ = login(t, api.LoginRequest{MFA: &api.LoginRequestMFA{Token: challenge.MFAToken, Code: code}})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("login with a recovery code", func(t *testing.T) {
+		_, challenge := login(t, passwordLogin(alice.Name))
+		resp, loggedIn := login(t, api.LoginRequest{MFA: &api.LoginRequestMFA{Token: challenge.MFAToken, Code: recoveryCodes[0]}})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.Assert(t, loggedIn.AccessKey != "")
+
+		// a recovery code can only be used once
+		_, challenge = login(t, passwordLogin(alice.Name))
+		resp, _ = login(t, api.LoginRequest{MFA: &api.LoginRequestMFA{Token: challenge.MFAToken, Code: recoveryCodes[0]}})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("user disables mfa without a code", func(t *testing.T) {
+		_, challenge := login(t, passwordLogin(alice.Name))
+		_, loggedIn := login(t, api.LoginRequest{MFA: &api.LoginRequestMFA{Token: challenge.MFAToken, Code: recoveryCodes[1]}})
+		assert.Assert(t, loggedIn.AccessKey != "")
+
+		resp := call(t, http.MethodDelete, "/api/users/"+alice.ID.String()+"/mfa", loggedIn.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		resp = call(t, http.MethodDelete, "/api/users/"+alice.ID.String()+"/mfa", loggedIn.AccessKey, api.DeleteMFARequest{Code: "000000"})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		credential, err := data.GetCredential(srv.db, data.ByIdentityID(alice.ID))
+		assert.NilError(t, err)
+		assert.Assert(t, credential.MFAEnabled)
+	})
+
+	t.Run("admin disables mfa", func(t *testing.T) {
+		resp := call(t, http.MethodDelete, "/api/users/"+alice.ID.String()+"/mfa", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		_, loginResp := login(t, passwordLogin(alice.Name))
+		assert.Assert(t, loginResp.AccessKey != "")
+		assert.Equal(t, loginResp.MFAToken, "")
+	})
+
+	t.Run("group requires mfa", func(t *testing.T) {
+		bob := createUser(t, "bob@example.com")
+
+		resp := call(t, http.MethodPost, "/api/groups", adminAccessKey(srv), api.CreateGroupRequest{Name: "admins"})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var group api.Group
+		err := json.Unmarshal(resp.Body.Bytes(), &group)
+		assert.NilError(t, err)
+		assert.Assert(t, !group.RequireMFA)
+
+		err = data.AddUsersToGroup(srv.db, group.ID, []uid.ID{bob.ID})
+		assert.NilError(t, err)
+
+		resp = call(t, http.MethodPut, "/api/groups/"+group.ID.String(), adminAccessKey(srv), api.UpdateGroupRequest{RequireMFA: true})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		err = json.Unmarshal(resp.Body.Bytes(), &group)
+		assert.NilError(t, err)
+		assert.Assert(t, group.RequireMFA)
+
+		_, loginResp := login(t, passwordLogin(bob.Name))
+		assert.Assert(t, loginResp.MFAEnrollmentRequired)
+
+		// the access key can only be used to enroll
+		resp = call(t, http.MethodGet, "/api/users/"+bob.ID.String(), loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		enroll(t, bob, loginResp.AccessKey)
+
+		resp = call(t, http.MethodGet, "/api/users/"+bob.ID.String(), loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		_, challenge := login(t, passwordLogin(bob.Name))
+		assert.Assert(t, challenge.MFAToken != "")
+	})
+
+	t.Run("user disables mfa with a code", func(t *testing.T) {
+		carol := createUser(t, "carol@example.com")
+		_, loginResp := login(t, passwordLogin(carol.Name))
+		carolSecret, _ := enroll(t, carol, loginResp.AccessKey)
+
+		code, err := authn.TOTPCode(carolSecret, time.Now().Add(30*time.Second))
+		assert.NilError(t, err)
+
+		resp := call(t, http.MethodDelete, "/api/users/"+carol.ID.String()+"/mfa", loginResp.AccessKey, api.DeleteMFARequest{Code: code})
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		credential, err := data.GetCredential(srv.db, data.ByIdentityID(carol.ID))
+		assert.NilError(t, err)
+		assert.Assert(t, !credential.MFAEnabled)
+	})
+}
@@ -14,8 +14,10 @@ import (
 	"github.com/infrahq/infra/internal"
 	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/authn"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/pki"
 )
 
 // TimeoutMiddleware adds a timeout to the request context within the Gin context.
@@ -38,6 +40,7 @@ func TimeoutMiddleware(timeout time.Duration) gin.HandlerFunc {
 // DatabaseMiddleware injects a `db` object into the Gin context.
 func DatabaseMiddleware(db *gorm.DB) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		generation := data.AuthzGeneration()
 		err := db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
 			c.Set("db", tx)
 			c.Next()
@@ -46,6 +49,31 @@ func DatabaseMiddleware(db *gorm.DB) gin.HandlerFunc {
 		if err != nil {
 			logging.Debugf(err.Error())
 		}
+
+		// authorization decisions cached by other requests while this
+		// transaction was open could not see its changes
+		if data.AuthzGeneration() != generation {
+			data.InvalidateAuthz()
+		}
+	}
+}
+
+// AuthzCacheMiddleware injects the cache of authorization decisions into the
+// Gin context.
+func AuthzCacheMiddleware(cache *access.AuthzCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("authzCache", cache)
+		c.Next()
+	}
+}
+
+// ResourceRoutesMiddleware injects the routes which check the resources of a
+// request against the resource scopes of its access key. RequireAccessKey
+// refuses keys with resource scopes for all other routes.
+func ResourceRoutesMiddleware(routes map[string]bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("resourceRoutes", routes)
+		c.Next()
 	}
 }
 
@@ -53,7 +81,7 @@ func DestinationMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		uniqueID := c.GetHeader("Infra-Destination")
 		if uniqueID != "" {
-			destinations, err := access.ListDestinations(c, uniqueID, "", &models.Pagination{})
+			destinations, err := access.ListDestinations(c, uniqueID, "", "", &models.Pagination{})
 			if err != nil {
 				return
 			}
@@ -81,10 +109,18 @@ func DestinationMiddleware() gin.HandlerFunc {
 	}
 }
 
-// AuthenticationMiddleware validates the incoming token
-func AuthenticationMiddleware() gin.HandlerFunc {
+// AuthenticationMiddleware validates the incoming token, or the client
+// certificate of a request without a token
+func AuthenticationMiddleware(certificates pki.CertificateProvider) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		if err := RequireAccessKey(c); err != nil {
+		var err error
+		if hasClientCertificateOnly(c) {
+			err = RequireClientCertificate(c, certificates)
+		} else {
+			err = RequireAccessKey(c)
+		}
+
+		if err != nil {
 			sendAPIError(c, err)
 			return
 		}
@@ -93,6 +129,63 @@ func AuthenticationMiddleware() gin.HandlerFunc {
 	}
 }
 
+// hasClientCertificateOnly returns true if the request has a client
+// certificate, and no token. A token is used when the request has both.
+func hasClientCertificateOnly(c *gin.Context) bool {
+	if c.Request.TLS == nil || len(c.Request.TLS.PeerCertificates) == 0 {
+		return false
+	}
+
+	if c.Request.Header.Get("Authorization") != "" {
+		return false
+	}
+
+	_, err := c.Cookie(CookieAuthorizationName)
+	return err != nil
+}
+
+// RequireClientCertificate checks the client certificate is signed by one of
+// the active CAs, and identifies a user. Requests authenticated by a client
+// certificate do not have an access key.
+func RequireClientCertificate(c *gin.Context, certificates pki.CertificateProvider) error {
+	val, ok := c.Get("db")
+	if !ok {
+		return errors.New("could not find db in context")
+	}
+
+	db, ok := val.(*gorm.DB)
+	if !ok {
+		return errors.New("unknown db type in context")
+	}
+
+	identity, err := authn.ClientCertificateIdentity(db, certificates, c.Request.TLS)
+	if err != nil {
+		return fmt.Errorf("%w: invalid client certificate: %s", internal.ErrUnauthorized, err)
+	}
+
+	if err := updateLastSeenAt(db, identity); err != nil {
+		return err
+	}
+
+	c.Set("identity", identity)
+
+	return nil
+}
+
+// updateLastSeenAt records that the identity used the API. It is saved at most
+// once a minute, so that most requests do not write to the database.
+func updateLastSeenAt(db *gorm.DB, identity *models.Identity) error {
+	if time.Since(identity.LastSeenAt) < time.Minute {
+		return nil
+	}
+
+	identity.LastSeenAt = time.Now().UTC()
+	if err := data.SaveIdentity(db, identity); err != nil {
+		return fmt.Errorf("identity update fail: %w", err)
+	}
+	return nil
+}
+
 // RequireAccessKey checks the bearer token is present and valid
 func RequireAccessKey(c *gin.Context) error {
 	val, ok := c.Get("db")
@@ -142,16 +235,62 @@ func RequireAccessKey(c *gin.Context) error {
 		}
 	}
 
+	if accessKey.Scopes.Includes(models.ScopeSCIM) && !strings.HasPrefix(c.Request.URL.Path, "/scim/") {
+		return fmt.Errorf("%w: SCIM access keys can only be used for provisioning", internal.ErrUnauthorized)
+	}
+
+	if accessKey.Scopes.Includes(models.ScopeDestinationLogin) {
+		// POST /api/tokens only
+		if c.Request.URL.Path != "/api/tokens" || c.Request.Method != http.MethodPost {
+			return fmt.Errorf("%w: destination login codes can only be exchanged for a token", internal.ErrUnauthorized)
+		}
+	}
+
+	if accessKey.Scopes.Includes(models.ScopeMFAChallenge) {
+		return fmt.Errorf("%w: mfa tokens can only be used to login", internal.ErrUnauthorized)
+	}
+
+	if accessKey.Scopes.Includes(models.ScopeMFAEnrollment) {
+		// POST /api/users/:id/mfa and /api/users/:id/mfa/verify only
+		enroll := "/api/users/" + accessKey.IssuedFor.String() + "/mfa"
+		path := c.Request.URL.Path
+		if (path != enroll && path != enroll+"/verify") || c.Request.Method != http.MethodPost {
+			return fmt.Errorf("%w: multi-factor authentication is required, enroll to continue", internal.ErrUnauthorized)
+		}
+	}
+
 	c.Set("key", accessKey)
 
+	if err := requireNetworkScope(c); err != nil {
+		return err
+	}
+
+	if err := requireOperationScope(c, routeScope(c.FullPath()), c.Request.Method); err != nil {
+		return err
+	}
+
+	if err := requireResourceScopedRoute(c); err != nil {
+		return err
+	}
+
+	// record where the session is used, at most once a minute unless the
+	// client changed
+	client := access.RequestClientMetadata(c)
+	if accessKey.Client != client || time.Since(accessKey.LastUsedAt) > time.Minute {
+		accessKey.Client = client
+		accessKey.LastUsedAt = time.Now().UTC()
+		if err := data.SaveAccessKey(db, accessKey); err != nil {
+			return fmt.Errorf("access key update: %w", err)
+		}
+	}
+
 	identity, err := data.GetIdentity(db, data.ByID(accessKey.IssuedFor))
 	if err != nil {
 		return fmt.Errorf("identity for token: %w", err)
 	}
 
-	identity.LastSeenAt = time.Now().UTC()
-	if err = data.SaveIdentity(db, identity); err != nil {
-		return fmt.Errorf("identity update fail: %w", err)
+	if err := updateLastSeenAt(db, identity); err != nil {
+		return err
 	}
 
 	c.Set("identity", identity)
@@ -265,7 +265,7 @@ func TestDestinationMiddleware(t *testing.T) {
 	router := gin.New()
 	router.Use(
 		DatabaseMiddleware(db),
-		AuthenticationMiddleware(),
+		AuthenticationMiddleware(nil),
 		DestinationMiddleware(),
 	)
 
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"github.com/infrahq/infra/api"
@@ -12,7 +13,33 @@ var (
 	AccessKeySecretLength = 24 // the length of the secret used to validate an access key
 )
 
-const ScopePasswordReset = "password-reset"
+const (
+	ScopePasswordReset = "password-reset"
+	// ScopeSCIM limits an access key to the SCIM provisioning endpoints of the
+	// provider the key was issued for.
+	ScopeSCIM = "scim"
+	// ScopeDestinationLogin limits an access key to being exchanged once for a
+	// token, by the connector of an http destination.
+	ScopeDestinationLogin = "destination-login"
+	// ScopeMFAChallenge limits an access key to being exchanged once, with a
+	// TOTP or recovery code, for an access key which completes the login.
+	ScopeMFAChallenge = "mfa-challenge"
+	// ScopeMFAEnrollment limits an access key to the endpoints used to enroll
+	// in multi-factor authentication. It is issued at login to users who are
+	// required to use MFA and have not enrolled yet.
+	ScopeMFAEnrollment = "mfa-enrollment"
+
+	// ScopeResourcePrefix limits an access key to requests for a resource,
+	// and its namespaces, such as "resource:production".
+	ScopeResourcePrefix = "resource:"
+	// ScopeCIDRPrefix limits an access key to requests from a network, such
+	// as "cidr:10.0.0.0/8".
+	ScopeCIDRPrefix = "cidr:"
+	// ScopeRead and ScopeWrite are the operations of an API scope, such as
+	// "grants:read". Write includes read.
+	ScopeRead  = "read"
+	ScopeWrite = "write"
+)
 
 // AccessKey is a session token presented to the Infra server as proof of authentication
 type AccessKey struct {
@@ -30,6 +57,49 @@ type AccessKey struct {
 	KeyID          string `gorm:"<-;uniqueIndex:idx_access_keys_key_id,where:deleted_at is NULL"`
 	Secret         string `gorm:"-"`
 	SecretChecksum []byte
+
+	// LoginMethod is the name of the login method for keys issued at login,
+	// which are the sessions of the user. It is empty for other keys.
+	LoginMethod string
+	// Client is the client which last used the key
+	Client     ClientMetadata `gorm:"embedded;embeddedPrefix:client_"`
+	LastUsedAt time.Time
+}
+
+// ClientMetadata describes the client of a request.
+type ClientMetadata struct {
+	IP        string
+	UserAgent string
+	// Version is the version of the Infra CLI or api.Client, from the user
+	// agent. It is empty for other clients.
+	Version string
+}
+
+// LimitingScopes returns the scopes which limit the operations, resources,
+// and networks the key can be used for, such as "grants:read". A key issued
+// with this key as proof of authentication must keep these scopes.
+func (ak *AccessKey) LimitingScopes() []string {
+	var scopes []string
+	for _, scope := range ak.Scopes {
+		if strings.Contains(scope, ":") {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes
+}
+
+// ToAPISession returns the key as a session of the user.
+func (ak *AccessKey) ToAPISession() *api.Session {
+	return &api.Session{
+		ID:            ak.ID,
+		Created:       api.Time(ak.CreatedAt),
+		LoginMethod:   ak.LoginMethod,
+		ClientIP:      ak.Client.IP,
+		UserAgent:     ak.Client.UserAgent,
+		ClientVersion: ak.Client.Version,
+		LastUsed:      api.Time(ak.LastUsedAt),
+		Expires:       api.Time(ak.ExpiresAt),
+	}
 }
 
 func (ak *AccessKey) ToAPI() *api.AccessKey {
@@ -47,5 +117,6 @@ func (ak *AccessKey) ToAPI() *api.AccessKey {
 		ProviderID:        ak.ProviderID,
 		Expires:           api.Time(ak.ExpiresAt),
 		ExtensionDeadline: api.Time(ak.ExtensionDeadline),
+		Scopes:            ak.Scopes,
 	}
 }
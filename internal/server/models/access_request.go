@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// InfraApproverRole allows an identity to approve or deny access requests
+// when granted on the infra resource.
+const InfraApproverRole = "approver"
+
+const (
+	AccessRequestStatusPending  = "pending"
+	AccessRequestStatusApproved = "approved"
+	AccessRequestStatusDenied   = "denied"
+)
+
+// AccessRequest is a request from a user for a grant. When the request is
+// approved a grant is created that expires after Duration.
+type AccessRequest struct {
+	Model
+
+	RequestedBy         uid.ID    `validate:"required"` // the ID of the identity that the grant is for
+	RequestedByIdentity *Identity `gorm:"foreignKey:RequestedBy"`
+
+	Privilege string        `validate:"required"`
+	Resource  string        `validate:"required"`
+	Reason    string        `validate:"required"`
+	Duration  time.Duration `validate:"required"` // how long the grant is valid for once approved
+
+	Status    string `validate:"required"`
+	DecidedBy uid.ID // the ID of the identity that approved or denied the request
+	DecidedAt time.Time
+	GrantID   uid.ID // the grant that was created when the request was approved
+}
+
+func (r *AccessRequest) ToAPI() *api.AccessRequest {
+	requestedByName := ""
+	if r.RequestedByIdentity != nil {
+		requestedByName = r.RequestedByIdentity.Name
+	}
+
+	return &api.AccessRequest{
+		ID:              r.ID,
+		Created:         api.Time(r.CreatedAt),
+		RequestedBy:     r.RequestedBy,
+		RequestedByName: requestedByName,
+		Privilege:       r.Privilege,
+		Resource:        r.Resource,
+		Reason:          r.Reason,
+		Duration:        api.Duration(r.Duration),
+		Status:          r.Status,
+		DecidedBy:       r.DecidedBy,
+		Decided:         api.Time(r.DecidedAt),
+		GrantID:         r.GrantID,
+	}
+}
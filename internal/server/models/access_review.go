@@ -0,0 +1,108 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	AccessReviewStatusOpen   = "open"
+	AccessReviewStatusClosed = "closed"
+)
+
+const (
+	AccessReviewDecisionPending = "pending"
+	AccessReviewDecisionKeep    = "keep"
+	AccessReviewDecisionRevoke  = "revoke"
+)
+
+// AccessReview is a campaign to review the grants to a destination, or the
+// grants to a group and its members. An item is created for each grant when
+// the campaign is created, and reviewers decide to keep or revoke each grant
+// before the deadline.
+type AccessReview struct {
+	Model
+
+	Name        string `validate:"required"`
+	Destination string // the destination the grants apply to, empty when the campaign is for a group
+	GroupID     uid.ID // the group the grants are for, zero when the campaign is for a destination
+	CreatedBy   uid.ID
+
+	// Reviewers may decide the items of the campaign, in addition to admins.
+	Reviewers []Identity `gorm:"many2many:access_reviews_reviewers"`
+
+	Deadline time.Time `validate:"required"`
+	// AutoRevoke revokes the grants of items which are still pending at the
+	// deadline.
+	AutoRevoke bool
+
+	Status   string `validate:"required"`
+	ClosedAt time.Time
+}
+
+func (r *AccessReview) ToAPI() *api.AccessReview {
+	reviewers := make([]uid.ID, 0, len(r.Reviewers))
+	for _, reviewer := range r.Reviewers {
+		reviewers = append(reviewers, reviewer.ID)
+	}
+
+	return &api.AccessReview{
+		ID:          r.ID,
+		Created:     api.Time(r.CreatedAt),
+		CreatedBy:   r.CreatedBy,
+		Name:        r.Name,
+		Destination: r.Destination,
+		Group:       r.GroupID,
+		Reviewers:   reviewers,
+		Deadline:    api.Time(r.Deadline),
+		AutoRevoke:  r.AutoRevoke,
+		Status:      r.Status,
+		Closed:      api.Time(r.ClosedAt),
+	}
+}
+
+// AccessReviewItem is the decision to keep or revoke one grant. The grant is
+// copied to the item, so that the results of the campaign can be exported after
+// the grant is revoked.
+type AccessReviewItem struct {
+	Model
+
+	AccessReviewID uid.ID `gorm:"index" validate:"required"`
+
+	GrantID     uid.ID            `validate:"required"`
+	Subject     uid.PolymorphicID `validate:"required"`
+	SubjectName string            // the name of the user or group when the campaign was created
+	Privilege   string            `validate:"required"`
+	Resource    string            `validate:"required"`
+
+	Decision  string `validate:"required"`
+	DecidedBy uid.ID // the ID of the reviewer, zero when the grant was revoked at the deadline
+	DecidedAt time.Time
+}
+
+func (r *AccessReviewItem) ToAPI() *api.AccessReviewItem {
+	item := &api.AccessReviewItem{
+		ID:          r.ID,
+		Review:      r.AccessReviewID,
+		Grant:       r.GrantID,
+		SubjectName: r.SubjectName,
+		Privilege:   r.Privilege,
+		Resource:    r.Resource,
+		Decision:    r.Decision,
+		DecidedBy:   r.DecidedBy,
+		Decided:     api.Time(r.DecidedAt),
+	}
+
+	if id, err := r.Subject.ID(); err == nil {
+		switch {
+		case r.Subject.IsIdentity():
+			item.User = id
+		case r.Subject.IsGroup():
+			item.Group = id
+		}
+	}
+
+	return item
+}
@@ -0,0 +1,44 @@
+package models
+
+import (
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// AuditEvent is a record of a single API request that attempted to modify
+// the state of the server. Audit events are never updated or deleted.
+type AuditEvent struct {
+	Model
+
+	ActorID     uid.ID // the identity that made the request, 0 if unauthenticated
+	ActorName   string
+	AccessKeyID uid.ID // the access key used to authenticate the request
+
+	Method       string `validate:"required"`
+	Route        string `validate:"required"` // the route template, ex: /api/grants/:id
+	ResourceType string
+	ResourceID   uid.ID
+
+	// Before and After are JSON encoded snapshots of the target resource
+	Before string
+	After  string
+
+	StatusCode int
+}
+
+func (e *AuditEvent) ToAPI() *api.AuditEvent {
+	return &api.AuditEvent{
+		ID:           e.ID,
+		Created:      api.Time(e.CreatedAt),
+		ActorID:      e.ActorID,
+		ActorName:    e.ActorName,
+		AccessKeyID:  e.AccessKeyID,
+		Method:       e.Method,
+		Route:        e.Route,
+		ResourceType: e.ResourceType,
+		ResourceID:   e.ResourceID,
+		Before:       e.Before,
+		After:        e.After,
+		StatusCode:   e.StatusCode,
+	}
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// CertificateAuthority is a CA which signs client certificates.
+// The two newest certificates are active, so that certificates signed by the
+// previous CA are trusted until it expires.
+type CertificateAuthority struct {
+	Model
+
+	KeyAlgorithm     string
+	SigningAlgorithm string
+	PublicKey        []byte
+	PrivateKey       EncryptedAtRestBytes
+	// SignedCert is the PEM encoded certificate of the CA.
+	SignedCert []byte
+	ExpiresAt  time.Time
+}
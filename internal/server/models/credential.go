@@ -8,4 +8,17 @@ type Credential struct {
 	IdentityID      uid.ID `gorm:"<-;uniqueIndex:idx_credentials_identity_id,where:deleted_at is NULL"`
 	PasswordHash    []byte `validate:"required"`
 	OneTimePassword bool
+
+	// MFASecret is the TOTP secret of the user. A code generated from the
+	// secret is required to login once MFAEnabled is set, which happens when the
+	// user has verified a code for the first time.
+	MFASecret  EncryptedAtRest `gorm:"default:''"`
+	MFAEnabled bool
+	// MFARecoveryCodes are the SHA-256 hashes of the recovery codes which have
+	// not been used yet. Each can be used once instead of a TOTP code.
+	MFARecoveryCodes CommaSeparatedStrings `gorm:"default:''"`
+	// MFALastCounter is the TOTP counter of the last code which was accepted.
+	// Codes from the same or an earlier period are rejected, so that a code
+	// can't be replayed.
+	MFALastCounter int64
 }
@@ -6,10 +6,22 @@ import (
 	"github.com/infrahq/infra/api"
 )
 
+// Kinds of destinations. A kubernetes destination is a cluster, an ssh
+// destination is a host which accepts SSH certificates signed by Infra, a
+// postgres destination is a database which issues short-lived login roles, and
+// an http destination is a web application behind the connector.
+const (
+	DestinationKindKubernetes = "kubernetes"
+	DestinationKindSSH        = "ssh"
+	DestinationKindPostgres   = "postgres"
+	DestinationKindHTTP       = "http"
+)
+
 type Destination struct {
 	Model
 
 	Name       string `validate:"required"`
+	Kind       string `gorm:"default:kubernetes"`
 	UniqueID   string `gorm:"uniqueIndex:idx_destinations_unique_id,where:deleted_at is NULL"`
 	LastSeenAt time.Time
 
@@ -35,6 +47,7 @@ func (d *Destination) ToAPI() *api.Destination {
 		Created:  api.Time(d.CreatedAt),
 		Updated:  api.Time(d.UpdatedAt),
 		Name:     d.Name,
+		Kind:     d.Kind,
 		UniqueID: d.UniqueID,
 		Connection: api.DestinationConnection{
 			URL: d.ConnectionURL,
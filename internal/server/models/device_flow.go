@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/uid"
+)
+
+// DeviceFlowAuthRequest is a login started by a device which can not open a
+// browser, such as the CLI in an SSH session. The user approves the login by
+// entering the user code in the UI on another device, while the device polls
+// with the device code until the login is approved (RFC 8628).
+type DeviceFlowAuthRequest struct {
+	Model
+
+	// UserCode is shown to the user, who enters it to approve the login
+	UserCode string `gorm:"uniqueIndex:idx_device_flow_auth_requests_user_code,where:deleted_at is NULL"`
+	// DeviceCode is the secret the device polls with, only its checksum is
+	// stored
+	DeviceCode         string `gorm:"-"`
+	DeviceCodeChecksum []byte
+
+	ExpiresAt time.Time
+
+	// ApprovedBy is the user who approved the login, it is zero until then
+	ApprovedBy uid.ID
+	// ProviderID is the provider the approving user logged in with
+	ProviderID uid.ID
+	// Scopes are the limiting scopes of the access key which approved the
+	// login, the access key issued to the device keeps them
+	Scopes CommaSeparatedStrings
+
+	// LastPolledAt is when the device last attempted to login, it is used to
+	// slow down devices which poll faster than the poll interval
+	LastPolledAt time.Time
+}
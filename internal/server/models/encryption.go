@@ -40,7 +40,9 @@ func (s *EncryptedAtRest) Scan(v interface{}) error {
 		return fmt.Errorf("unsupported type: %T", v)
 	}
 
-	if SkipSymmetricKey {
+	// a sealed value is never empty, so an empty column has no secret. This is
+	// the case for columns which were added to existing rows.
+	if SkipSymmetricKey || vStr == "" {
 		*s = EncryptedAtRest(vStr)
 		return nil
 	}
@@ -1,7 +1,15 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
 	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/claims"
 	"github.com/infrahq/infra/uid"
 )
 
@@ -19,35 +27,139 @@ const BasePermissionConnect = "connect"
 // field bloat should be avoided here since this model is going to be used heavily.
 //
 // Subject
-// 		Subject is mostly an Identity, which is a string specifying a user, group, the name of a role, or another grant
+// 		Subject is an Identity, which is a string specifying a user or group
 // 			- an identity:  	i:E97WmsYfvo   		 - a user reference
 // 			- a group: 			g:CCoJ1ornpf   		 - a group reference
-// 			- a role:  			r:role-name   		 - a role definition
-// 			- a permission: p:permissionn-name - a permission definition
 // Privilege
-// 		Privilege is a predicate that describes what sort of access the identity has to the resource
+// 		Privilege is a predicate that describes what sort of access the identity has to the resource.
+// 		It is a builtin role, a permission, or the name of a custom Role which bundles permissions
 // URN
 // 		URN is Universal Resource Notation.
+// NotBefore
+//    time you want the grant to become active at, optional
 // Expiry
-//    time you want the grant to expire at
+//    time you want the grant to expire at, optional
+// Conditions
+//    networks and times of day the grant applies to, optional
 //
 type Grant struct {
 	Model
 
-	Subject   uid.PolymorphicID `validate:"required" gorm:"uniqueIndex:idx_grant_srp,where:deleted_at is NULL"` // usually an identity, but could be a role definition
+	Subject   uid.PolymorphicID `validate:"required" gorm:"uniqueIndex:idx_grant_srp,where:deleted_at is NULL"` // an identity or a group
 	Privilege string            `validate:"required" gorm:"uniqueIndex:idx_grant_srp,where:deleted_at is NULL"` // role or permission
 	Resource  string            `validate:"required" gorm:"uniqueIndex:idx_grant_srp,where:deleted_at is NULL"` // Universal Resource Notation
 	CreatedBy uid.ID
+
+	// SubjectID is the ID of Subject, so that grants to the groups of a user
+	// can be found in the same query as grants to the user.
+	SubjectID uid.ID `gorm:"index"`
+
+	// NotBefore is part of the unique index, so that a grant can be scheduled
+	// to start while the same grant is in effect.
+	NotBefore time.Time `gorm:"uniqueIndex:idx_grant_srp,where:deleted_at is NULL"` // the grant has no effect before this time, zero means immediately
+	ExpiresAt time.Time // the grant has no effect after this time, zero means never
+
+	// Conditions limit the grant to requests from some networks, or at some
+	// times. The zero value has no conditions.
+	Conditions GrantConditions
+}
+
+// GrantConditions are the conditions of a grant, stored as JSON.
+type GrantConditions struct {
+	claims.Conditions
+}
+
+func (c GrantConditions) Value() (driver.Value, error) {
+	if c.IsZero() {
+		return "", nil
+	}
+
+	b, err := json.Marshal(c.Conditions)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (c *GrantConditions) Scan(v interface{}) error {
+	var b []byte
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return fmt.Errorf("expected string type for %v", v)
+	}
+
+	if len(b) == 0 {
+		*c = GrantConditions{}
+		return nil
+	}
+
+	return json.Unmarshal(b, &c.Conditions)
+}
+
+func (c GrantConditions) GormDataType() string {
+	return "text"
+}
+
+func (c GrantConditions) ToAPI() *api.GrantConditions {
+	if c.IsZero() {
+		return nil
+	}
+
+	result := &api.GrantConditions{CIDRs: c.CIDRs}
+	for _, w := range c.Windows {
+		result.Windows = append(result.Windows, api.TimeWindow(w))
+	}
+	return result
+}
+
+// NewGrantConditions returns the conditions of the API request. Conditions are
+// not validated, use Validate.
+func NewGrantConditions(conditions *api.GrantConditions) GrantConditions {
+	if conditions == nil {
+		return GrantConditions{}
+	}
+
+	result := GrantConditions{claims.Conditions{CIDRs: conditions.CIDRs}}
+	for _, w := range conditions.Windows {
+		result.Windows = append(result.Windows, claims.TimeWindow(w))
+	}
+	return result
+}
+
+// BeforeSave sets SubjectID from Subject, so that grants to the groups of a
+// user can be found by joining the group memberships of the user.
+func (r *Grant) BeforeSave(_ *gorm.DB) error {
+	if id, err := r.Subject.ID(); err == nil {
+		r.SubjectID = id
+	}
+	return nil
+}
+
+// IsActive returns true if the grant is in effect at time t.
+func (r *Grant) IsActive(t time.Time) bool {
+	if !r.NotBefore.IsZero() && t.Before(r.NotBefore) {
+		return false
+	}
+	return r.ExpiresAt.IsZero() || t.Before(r.ExpiresAt)
 }
 
 func (r *Grant) ToAPI() *api.Grant {
 	grant := &api.Grant{
-		ID:        r.ID,
-		Created:   api.Time(r.CreatedAt),
-		Updated:   api.Time(r.UpdatedAt),
-		CreatedBy: r.CreatedBy,
-		Privilege: r.Privilege,
-		Resource:  r.Resource,
+		ID:         r.ID,
+		Created:    api.Time(r.CreatedAt),
+		Updated:    api.Time(r.UpdatedAt),
+		CreatedBy:  r.CreatedBy,
+		Privilege:  r.Privilege,
+		Resource:   r.Resource,
+		NotBefore:  api.Time(r.NotBefore),
+		ExpiresAt:  api.Time(r.ExpiresAt),
+		Conditions: r.Conditions.ToAPI(),
 	}
 
 	switch {
@@ -11,16 +11,23 @@ type Group struct {
 	Name              string `gorm:"uniqueIndex:idx_groups_name,where:deleted_at is NULL"`
 	CreatedBy         uid.ID
 	CreatedByProvider uid.ID
+	// RequireMFA requires members of the group, who login with a password, to
+	// enroll in multi-factor authentication.
+	RequireMFA bool
 
 	Identities []Identity `gorm:"many2many:identities_groups"`
+	// MemberGroups are the groups which are members of this group. The members
+	// of a member group are also members of this group.
+	MemberGroups []Group `gorm:"many2many:groups_groups;joinForeignKey:GroupID;joinReferences:MemberGroupID"`
 }
 
 func (g *Group) ToAPI() *api.Group {
 	return &api.Group{
-		ID:      g.ID,
-		Created: api.Time(g.CreatedAt),
-		Updated: api.Time(g.UpdatedAt),
-		Name:    g.Name,
+		ID:         g.ID,
+		Created:    api.Time(g.CreatedAt),
+		Updated:    api.Time(g.UpdatedAt),
+		Name:       g.Name,
+		RequireMFA: g.RequireMFA,
 	}
 }
 
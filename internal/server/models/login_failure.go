@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+const (
+	LoginFailureKindUser = "user"
+	LoginFailureKindIP   = "ip"
+)
+
+// LoginFailure counts the consecutive failed logins for a username or a
+// client IP, which are stored in the database so that the count is shared by
+// all replicas of the server.
+type LoginFailure struct {
+	Model
+
+	// Kind is one of LoginFailureKindUser or LoginFailureKindIP
+	Kind string `gorm:"uniqueIndex:idx_login_failures_kind_key,where:deleted_at is NULL"`
+	// Key is the username, or the client IP
+	Key string `gorm:"uniqueIndex:idx_login_failures_kind_key,where:deleted_at is NULL"`
+
+	Failures      int
+	LastFailureAt time.Time
+	// LockedUntil is when logins are allowed again. It is zero until the
+	// number of failures reaches the limit.
+	LockedUntil time.Time
+}
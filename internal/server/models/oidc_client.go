@@ -0,0 +1,79 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+const (
+	// OIDCScopeOpenID is required in every authorization request
+	OIDCScopeOpenID = "openid"
+	// OIDCScopeEmail adds the email and email_verified claims
+	OIDCScopeEmail = "email"
+	// OIDCScopeProfile adds the name claim
+	OIDCScopeProfile = "profile"
+	// OIDCScopeGroups adds the groups claim, with the names of the groups of
+	// the user
+	OIDCScopeGroups = "groups"
+)
+
+// OIDCClient is an application which trusts Infra as its OpenID Connect
+// identity provider, such as kube-apiserver or an internal web app.
+type OIDCClient struct {
+	Model
+
+	Name     string `gorm:"uniqueIndex:idx_oidc_clients_name,where:deleted_at is NULL" validate:"required"`
+	ClientID string `gorm:"uniqueIndex:idx_oidc_clients_client_id,where:deleted_at is NULL"`
+	// ClientSecret is only set when the client is created, only its checksum
+	// is stored. Public clients, such as CLIs, have no secret and must use
+	// PKCE instead.
+	ClientSecret         string `gorm:"-"`
+	ClientSecretChecksum []byte
+
+	// RedirectURIs are the URIs which the browser of the user may be
+	// redirected to with an authorization code. They must match exactly.
+	RedirectURIs CommaSeparatedStrings
+	// SigningAlgorithm is the algorithm of the tokens issued to the client,
+	// RS256, ES256, or EdDSA.
+	SigningAlgorithm string
+}
+
+// Public returns true if the client has no secret.
+func (c *OIDCClient) Public() bool {
+	return len(c.ClientSecretChecksum) == 0
+}
+
+func (c *OIDCClient) ToAPI() *api.OIDCClient {
+	return &api.OIDCClient{
+		ID:               c.ID,
+		Created:          api.Time(c.CreatedAt),
+		Name:             c.Name,
+		ClientID:         c.ClientID,
+		RedirectURIs:     c.RedirectURIs,
+		SigningAlgorithm: c.SigningAlgorithm,
+		Public:           c.Public(),
+	}
+}
+
+// OIDCAuthorizationCode is issued to a client when a user authorizes it, and
+// is exchanged once by the client for tokens.
+type OIDCAuthorizationCode struct {
+	Model
+
+	// Code is sent to the client, only its checksum is stored
+	Code         string `gorm:"-"`
+	CodeChecksum []byte `gorm:"uniqueIndex:idx_oidc_authorization_codes_code_checksum,where:deleted_at is NULL"`
+
+	ClientID    uid.ID
+	IdentityID  uid.ID
+	RedirectURI string
+	Scopes      CommaSeparatedStrings
+	Nonce       string
+	// CodeChallenge is the S256 PKCE challenge, which the client must answer
+	// with its verifier to exchange the code
+	CodeChallenge string
+
+	ExpiresAt time.Time
+}
@@ -17,6 +17,8 @@ const (
 	ProviderKindOkta   ProviderKind = "okta"
 	ProviderKindAzure  ProviderKind = "azure"
 	ProviderKindGoogle ProviderKind = "google"
+	ProviderKindLDAP   ProviderKind = "ldap"
+	ProviderKindSAML   ProviderKind = "saml"
 )
 
 func (p ProviderKind) String() string {
@@ -30,6 +32,8 @@ var providerKindMap = map[string]ProviderKind{
 	ProviderKindOkta.String():   ProviderKindOkta,
 	ProviderKindAzure.String():  ProviderKindAzure,
 	ProviderKindGoogle.String(): ProviderKindGoogle,
+	ProviderKindLDAP.String():   ProviderKindLDAP,
+	ProviderKindSAML.String():   ProviderKindSAML,
 }
 
 // ParseProviderKind validates that a string is valid kind then returns the ProviderKind
@@ -53,6 +57,24 @@ type Provider struct {
 	AuthURL      string
 	Scopes       CommaSeparatedStrings
 	CreatedBy    uid.ID
+
+	// LDAPBindDN and LDAPBindPassword are the service account used to search
+	// the directory of an LDAP provider for users and their groups.
+	LDAPBindDN       string
+	LDAPBindPassword EncryptedAtRest `gorm:"default:''"`
+	LDAPSearchBase   string
+	// LDAPUserFilter and LDAPGroupFilter are search filters where %s is
+	// replaced by the username, and the DN of the user, respectively.
+	LDAPUserFilter  string
+	LDAPGroupFilter string
+
+	// SAMLMetadata is the metadata of the identity provider of a SAML
+	// provider, which includes its single sign-on URL and the certificates
+	// used to sign assertions.
+	SAMLMetadata string
+	// SAMLGroupsAttribute is the name of the assertion attribute which lists
+	// the groups of the user.
+	SAMLGroupsAttribute string
 }
 
 func (p *Provider) ToAPI() *api.Provider {
@@ -6,6 +6,11 @@ import (
 	"github.com/infrahq/infra/uid"
 )
 
+const (
+	ProviderUserSyncSuccess = "success"
+	ProviderUserSyncFailed  = "failed"
+)
+
 // ProviderUser is a cache of the provider's user and their groups, plus any authentication-specific information for that provider.
 type ProviderUser struct {
 	Model
@@ -22,4 +27,8 @@ type ProviderUser struct {
 	AccessToken  EncryptedAtRest
 	RefreshToken EncryptedAtRest
 	ExpiresAt    time.Time
+
+	// SyncStatus is the result of the last background sync of the user with
+	// the provider. It is empty if the user has not been synced.
+	SyncStatus string
 }
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/uid"
+)
+
+// Infra API permissions which can be included in a role. A role which includes
+// a permission, and is granted on the infra resource, allows the operation
+// without also requiring the admin role.
+const (
+	PermissionUsersRead          = "users:read"
+	PermissionUsersCreate        = "users:create"
+	PermissionGroupsRead         = "groups:read"
+	PermissionGroupsCreate       = "groups:create"
+	PermissionGrantsRead         = "grants:read"
+	PermissionGrantsCreate       = "grants:create"
+	PermissionGrantsDelete       = "grants:delete"
+	PermissionDestinationsDelete = "destinations:delete"
+	PermissionAuditEventsRead    = "audit-events:read"
+	PermissionRolesRead          = "roles:read"
+)
+
+// BuiltinRoles are the roles which are defined by Infra. A custom role can not
+// use the name of a builtin role.
+var BuiltinRoles = []string{InfraAdminRole, InfraViewRole, InfraConnectorRole, InfraApproverRole}
+
+// Role is a named set of permissions. A role is granted like any other
+// privilege, by using its name as the privilege of a grant. On the infra
+// resource the permissions are Infra API permissions or builtin roles, and on
+// a destination they are privileges of the destination, like a cluster role.
+type Role struct {
+	Model
+
+	Name        string `gorm:"uniqueIndex:idx_roles_name,where:deleted_at is NULL"`
+	Description string
+	Permissions CommaSeparatedStrings
+	CreatedBy   uid.ID
+}
+
+func (r *Role) ToAPI() *api.Role {
+	permissions := make([]string, len(r.Permissions))
+	copy(permissions, r.Permissions)
+
+	return &api.Role{
+		ID:          r.ID,
+		Created:     api.Time(r.CreatedAt),
+		Updated:     api.Time(r.UpdatedAt),
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: permissions,
+	}
+}
+
+// Includes returns true if the role includes the permission.
+func (r *Role) Includes(permission string) bool {
+	for _, p := range r.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+
+	"github.com/infrahq/infra/uid"
+)
+
+// SAMLAuthnRequest is a login with a SAML provider. It is created when the
+// user is sent to the identity provider with an authentication request, and
+// completed when the identity provider posts a signed assertion back to the
+// server. The client then exchanges the login code for an access key.
+type SAMLAuthnRequest struct {
+	Model
+
+	ProviderID uid.ID
+	// RequestID is the ID of the authentication request, the assertion must
+	// be in response to it
+	RequestID string
+	// RelayState is returned by the identity provider along with the
+	// assertion, and identifies the login
+	RelayState string `gorm:"uniqueIndex:idx_saml_authn_requests_relay_state,where:deleted_at is NULL"`
+	// RedirectURL and State are where the client is sent with the login code,
+	// and the state the client provided to check the response is for its login
+	RedirectURL string
+	State       string
+
+	ExpiresAt time.Time
+
+	// Code is set once the assertion is validated, only its checksum is stored
+	Code         string `gorm:"-"`
+	CodeChecksum []byte
+	// Name and Groups are the user from the assertion
+	Name   string
+	Groups CommaSeparatedStrings
+}
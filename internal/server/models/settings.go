@@ -6,6 +6,19 @@ type Settings struct {
 	PrivateJWK EncryptedAtRestBytes
 	PublicJWK  []byte
 
+	// The RS256 and ES256 keys sign the tokens of OIDC clients which do not
+	// support EdDSA. They are generated when the first client which uses the
+	// algorithm is created.
+	RS256PrivateJWK EncryptedAtRestBytes
+	RS256PublicJWK  []byte
+	ES256PrivateJWK EncryptedAtRestBytes
+	ES256PublicJWK  []byte
+
+	// SSHCAPrivateKey and SSHCAPublicKey are the ed25519 keys of the
+	// certificate authority which signs SSH user certificates.
+	SSHCAPrivateKey EncryptedAtRestBytes
+	SSHCAPublicKey  []byte
+
 	LowercaseMin int `gorm:"default:0"`
 	UppercaseMin int `gorm:"default:0"`
 	NumberMin    int `gorm:"default:0"`
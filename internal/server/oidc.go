@@ -0,0 +1,224 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ssoroka/slice"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// oidcIssuer returns the issuer of the tokens issued to OIDC clients, which
+// is the URL of the server.
+func (a *API) oidcIssuer(c *gin.Context) string {
+	if issuer := a.server.options.OIDC.Issuer; issuer != "" {
+		return strings.TrimSuffix(issuer, "/")
+	}
+
+	u := a.baseURL(c)
+	return u.String()
+}
+
+type openIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+}
+
+// openIDConfigurationHandler responds with the OIDC discovery document, which
+// clients use to find the endpoints and keys of the issuer.
+func (a *API) openIDConfigurationHandler(c *gin.Context) {
+	issuer := a.oidcIssuer(c)
+
+	c.JSON(http.StatusOK, openIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserInfoEndpoint:                  issuer + "/userinfo",
+		JWKSURI:                           issuer + "/.well-known/jwks.json",
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{"RS256", "ES256", "EdDSA"},
+		ScopesSupported:                   []string{models.OIDCScopeOpenID, models.OIDCScopeEmail, models.OIDCScopeProfile, models.OIDCScopeGroups},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "nonce", "email", "email_verified", "name", "groups"},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+	})
+}
+
+// oauthAuthorizeHandler authorizes an OIDC client for the user. Users who are
+// not logged in are redirected to login first. The browser of the user is
+// redirected back to the client with an authorization code.
+func (a *API) oauthAuthorizeHandler(c *gin.Context) {
+	redirectURI := c.Query("redirect_uri")
+	client, err := access.GetOIDCClientForRedirect(c, c.Query("client_id"), redirectURI)
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	// once the redirect URI is known to be the client's, errors are sent to
+	// the client
+	redirect := func(values url.Values) {
+		query := u.Query()
+		for k, v := range values {
+			query[k] = v
+		}
+		if state := c.Query("state"); state != "" {
+			query.Set("state", state)
+		}
+		u.RawQuery = query.Encode()
+		c.Redirect(http.StatusFound, u.String())
+	}
+
+	redirectError := func(code, description string) {
+		redirect(url.Values{"error": {code}, "error_description": {description}})
+	}
+
+	scopes := strings.Fields(c.Query("scope"))
+	codeChallenge := c.Query("code_challenge")
+
+	switch {
+	case c.Query("response_type") != "code":
+		redirectError("unsupported_response_type", "response_type must be code")
+		return
+	case !slice.Contains(scopes, models.OIDCScopeOpenID):
+		redirectError("invalid_scope", "scope must include openid")
+		return
+	case codeChallenge == "" && client.Public():
+		redirectError("invalid_request", "public clients must send a code_challenge")
+		return
+	case codeChallenge != "" && c.Query("code_challenge_method") != "S256":
+		redirectError("invalid_request", "code_challenge_method must be S256")
+		return
+	}
+
+	var authzErr access.AuthorizationError
+	if err := RequireAccessKey(c); err != nil {
+		if errors.As(err, &authzErr) {
+			redirectError("access_denied", err.Error())
+			return
+		}
+		c.Redirect(http.StatusFound, "/login?next="+url.QueryEscape(c.Request.URL.RequestURI()))
+		return
+	}
+
+	code, err := access.CreateOIDCAuthorizationCode(c, client, redirectURI, scopes, c.Query("nonce"), codeChallenge)
+	if err != nil {
+		if errors.As(err, &authzErr) {
+			redirectError("access_denied", err.Error())
+			return
+		}
+		logging.Errorf("failed to authorize oidc client: %v", err)
+		redirectError("server_error", "failed to authorize the client")
+		return
+	}
+
+	redirect(url.Values{"code": {code}})
+}
+
+type oauthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int    `json:"expires_in"`
+	IDToken     string `json:"id_token"`
+}
+
+type oauthErrorResponse struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// oauthTokenHandler exchanges an authorization code from an OIDC client for
+// an ID token and an access token.
+func (a *API) oauthTokenHandler(c *gin.Context) {
+	c.Header("Cache-Control", "no-store")
+
+	if grantType := c.PostForm("grant_type"); grantType != "authorization_code" {
+		c.JSON(http.StatusBadRequest, oauthErrorResponse{
+			Error:       "unsupported_grant_type",
+			Description: "grant_type must be authorization_code",
+		})
+		return
+	}
+
+	req := access.OIDCTokenRequest{
+		ClientID:     c.PostForm("client_id"),
+		ClientSecret: c.PostForm("client_secret"),
+		Code:         c.PostForm("code"),
+		RedirectURI:  c.PostForm("redirect_uri"),
+		CodeVerifier: c.PostForm("code_verifier"),
+	}
+	if clientID, clientSecret, ok := c.Request.BasicAuth(); ok {
+		req.ClientID, req.ClientSecret = clientID, clientSecret
+	}
+
+	duration := a.server.options.OIDC.TokenDuration
+	idToken, accessToken, err := access.ExchangeOIDCAuthorizationCode(c, a.oidcIssuer(c), req, time.Now().Add(duration).UTC())
+	switch {
+	case errors.Is(err, internal.ErrUnauthorized):
+		c.JSON(http.StatusUnauthorized, oauthErrorResponse{Error: "invalid_client"})
+		return
+	case errors.Is(err, internal.ErrBadRequest):
+		c.JSON(http.StatusBadRequest, oauthErrorResponse{
+			Error:       "invalid_grant",
+			Description: strings.TrimPrefix(err.Error(), internal.ErrBadRequest.Error()+": "),
+		})
+		return
+	case err != nil:
+		logging.Errorf("failed to exchange oidc authorization code: %v", err)
+		c.JSON(http.StatusInternalServerError, oauthErrorResponse{Error: "server_error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, oauthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(duration.Seconds()),
+		IDToken:     idToken,
+	})
+}
+
+// userInfoHandler responds with the claims about the user of an access token
+// issued to an OIDC client.
+func (a *API) userInfoHandler(c *gin.Context) {
+	token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+
+	user, err := access.OIDCUserInfo(c, a.oidcIssuer(c), token)
+	switch {
+	case errors.Is(err, internal.ErrUnauthorized):
+		c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+		c.Status(http.StatusUnauthorized)
+		return
+	case err != nil:
+		sendAPIError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
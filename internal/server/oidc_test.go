@@ -0,0 +1,322 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/oauth2"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestOIDCIdentityProvider(t *testing.T) {
+	srv := setupServer(t, withAdminUser, func(_ *testing.T, opts *Options) {
+		opts.OIDC.TokenDuration = time.Hour
+	})
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+	httpSrv := httptest.NewServer(routes)
+	t.Cleanup(httpSrv.Close)
+
+	ctx := context.Background()
+	httpClient := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// create a user who is a member of a group, and logs in with a password
+	user := &models.Identity{Name: "alice@example.com"}
+	err := data.CreateIdentity(srv.db, user)
+	assert.NilError(t, err)
+	_, err = data.CreateProviderUser(srv.db, data.InfraProvider(srv.db), user)
+	assert.NilError(t, err)
+	hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	assert.NilError(t, err)
+	err = data.CreateCredential(srv.db, &models.Credential{IdentityID: user.ID, PasswordHash: hash})
+	assert.NilError(t, err)
+	group := &models.Group{Name: "developers"}
+	err = data.CreateGroup(srv.db, group)
+	assert.NilError(t, err)
+	err = data.AddUsersToGroup(srv.db, group.ID, []uid.ID{user.ID})
+	assert.NilError(t, err)
+
+	userKey, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+		IssuedFor:  user.ID,
+		ProviderID: data.InfraProvider(srv.db).ID,
+		ExpiresAt:  time.Now().Add(time.Hour).UTC(),
+	})
+	assert.NilError(t, err)
+
+	call := func(t *testing.T, method, path, key string, body interface{}) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(method, httpSrv.URL+path, jsonBody(t, body))
+		assert.NilError(t, err)
+		req.Header.Set("Infra-Version", "0.13.6")
+		if key != "" {
+			req.Header.Set("Authorization", "Bearer "+key)
+		}
+		resp, err := httpClient.Do(req)
+		assert.NilError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	createClient := func(t *testing.T, req api.CreateOIDCClientRequest) api.CreateOIDCClientResponse {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/oidc-clients", adminAccessKey(srv), req)
+		assert.Equal(t, resp.StatusCode, http.StatusCreated)
+
+		var client api.CreateOIDCClientResponse
+		err := json.NewDecoder(resp.Body).Decode(&client)
+		assert.NilError(t, err)
+		return client
+	}
+
+	const verifier = "the-code-verifier-which-is-long-enough-for-pkce"
+	challenge := func() string {
+		sum := sha256.Sum256([]byte(verifier))
+		return base64.RawURLEncoding.EncodeToString(sum[:])
+	}()
+
+	authorize := func(t *testing.T, key string, query url.Values) *url.URL {
+		t.Helper()
+		resp := call(t, http.MethodGet, "/oauth/authorize?"+query.Encode(), key, nil)
+		assert.Equal(t, resp.StatusCode, http.StatusFound)
+
+		location, err := url.Parse(resp.Header.Get("Location"))
+		assert.NilError(t, err)
+		return location
+	}
+
+	exchange := func(t *testing.T, clientID, clientSecret string, form url.Values) *http.Response {
+		t.Helper()
+		form.Set("grant_type", "authorization_code")
+		req, err := http.NewRequest(http.MethodPost, httpSrv.URL+"/oauth/token", strings.NewReader(form.Encode()))
+		assert.NilError(t, err)
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth(clientID, clientSecret)
+
+		resp, err := httpClient.Do(req)
+		assert.NilError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	client := createClient(t, api.CreateOIDCClientRequest{
+		Name:         "wiki",
+		RedirectURIs: []string{"https://wiki.example.com/callback"},
+	})
+	assert.Equal(t, client.SigningAlgorithm, "RS256")
+	assert.Assert(t, client.ClientSecret != "")
+
+	authorizeQuery := func() url.Values {
+		return url.Values{
+			"response_type":         {"code"},
+			"client_id":             {client.ClientID},
+			"redirect_uri":          {"https://wiki.example.com/callback"},
+			"scope":                 {"openid email profile groups"},
+			"state":                 {"the-state"},
+			"nonce":                 {"the-nonce"},
+			"code_challenge":        {challenge},
+			"code_challenge_method": {"S256"},
+		}
+	}
+
+	t.Run("discovery and login", func(t *testing.T) {
+		location := authorize(t, userKey, authorizeQuery())
+		assert.Equal(t, location.Host, "wiki.example.com")
+		assert.Equal(t, location.Query().Get("state"), "the-state")
+		code := location.Query().Get("code")
+		assert.Assert(t, code != "")
+
+		resp := exchange(t, client.ClientID, client.ClientSecret, url.Values{
+			"code":          {code},
+			"redirect_uri":  {"https://wiki.example.com/callback"},
+			"code_verifier": {verifier},
+		})
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		var tokens struct {
+			AccessToken string `json:"access_token"`
+			TokenType   string `json:"token_type"`
+			IDToken     string `json:"id_token"`
+		}
+		err := json.NewDecoder(resp.Body).Decode(&tokens)
+		assert.NilError(t, err)
+		assert.Equal(t, tokens.TokenType, "Bearer")
+
+		provider, err := oidc.NewProvider(ctx, httpSrv.URL)
+		assert.NilError(t, err)
+
+		idToken, err := provider.Verifier(&oidc.Config{ClientID: client.ClientID}).Verify(ctx, tokens.IDToken)
+		assert.NilError(t, err)
+		assert.Equal(t, idToken.Subject, user.ID.String())
+		assert.Equal(t, idToken.Nonce, "the-nonce")
+
+		var claims struct {
+			Email         string   `json:"email"`
+			EmailVerified bool     `json:"email_verified"`
+			Groups        []string `json:"groups"`
+		}
+		err = idToken.Claims(&claims)
+		assert.NilError(t, err)
+		assert.Equal(t, claims.Email, "alice@example.com")
+		assert.Equal(t, claims.EmailVerified, true)
+		assert.DeepEqual(t, claims.Groups, []string{"developers"})
+
+		userInfo, err := provider.UserInfo(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: tokens.AccessToken}))
+		assert.NilError(t, err)
+		assert.Equal(t, userInfo.Subject, user.ID.String())
+		assert.Equal(t, userInfo.Email, "alice@example.com")
+
+		// the ID token is not an access token
+		_, err = provider.UserInfo(ctx, oauth2.StaticTokenSource(&oauth2.Token{AccessToken: tokens.IDToken}))
+		assert.ErrorContains(t, err, "401")
+
+		// codes can only be used once
+		resp = exchange(t, client.ClientID, client.ClientSecret, url.Values{
+			"code":          {code},
+			"redirect_uri":  {"https://wiki.example.com/callback"},
+			"code_verifier": {verifier},
+		})
+		assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+	})
+
+	t.Run("users who are not logged in login first", func(t *testing.T) {
+		location := authorize(t, "", authorizeQuery())
+		assert.Equal(t, location.Path, "/login")
+		assert.Assert(t, strings.HasPrefix(location.Query().Get("next"), "/oauth/authorize?"))
+	})
+
+	t.Run("access keys with scopes can not authorize clients", func(t *testing.T) {
+		for _, scope := range []string{"users:read", "resource:production", "cidr:127.0.0.0/8"} {
+			key, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+				IssuedFor:  user.ID,
+				ProviderID: data.InfraProvider(srv.db).ID,
+				ExpiresAt:  time.Now().Add(time.Hour).UTC(),
+				Scopes:     []string{scope},
+			})
+			assert.NilError(t, err)
+
+			location := authorize(t, key, authorizeQuery())
+			assert.Equal(t, location.Query().Get("error"), "access_denied", scope)
+			assert.Equal(t, location.Query().Get("code"), "", scope)
+		}
+	})
+
+	t.Run("redirect uri must be registered", func(t *testing.T) {
+		query := authorizeQuery()
+		query.Set("redirect_uri", "https://evil.example.com/callback")
+		resp := call(t, http.MethodGet, "/oauth/authorize?"+query.Encode(), userKey, nil)
+		assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+	})
+
+	t.Run("openid scope is required", func(t *testing.T) {
+		query := authorizeQuery()
+		query.Set("scope", "email")
+		location := authorize(t, userKey, query)
+		assert.Equal(t, location.Query().Get("error"), "invalid_scope")
+		assert.Equal(t, location.Query().Get("state"), "the-state")
+	})
+
+	t.Run("invalid client secret", func(t *testing.T) {
+		code := authorize(t, userKey, authorizeQuery()).Query().Get("code")
+		resp := exchange(t, client.ClientID, "not-the-secret", url.Values{
+			"code":          {code},
+			"redirect_uri":  {"https://wiki.example.com/callback"},
+			"code_verifier": {verifier},
+		})
+		assert.Equal(t, resp.StatusCode, http.StatusUnauthorized)
+	})
+
+	t.Run("invalid code verifier", func(t *testing.T) {
+		code := authorize(t, userKey, authorizeQuery()).Query().Get("code")
+		resp := exchange(t, client.ClientID, client.ClientSecret, url.Values{
+			"code":          {code},
+			"redirect_uri":  {"https://wiki.example.com/callback"},
+			"code_verifier": {"not-the-verifier"},
+		})
+		assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+	})
+
+	t.Run("public clients with ES256", func(t *testing.T) {
+		public := createClient(t, api.CreateOIDCClientRequest{
+			Name:             "kubernetes",
+			RedirectURIs:     []string{"http://localhost:8000"},
+			SigningAlgorithm: "ES256",
+			Public:           true,
+		})
+		assert.Equal(t, public.ClientSecret, "")
+
+		query := authorizeQuery()
+		query.Set("client_id", public.ClientID)
+		query.Set("redirect_uri", "http://localhost:8000")
+		query.Del("code_challenge")
+		location := authorize(t, userKey, query)
+		assert.Equal(t, location.Query().Get("error"), "invalid_request")
+
+		query.Set("code_challenge", challenge)
+		code := authorize(t, userKey, query).Query().Get("code")
+
+		form := url.Values{
+			"client_id":     {public.ClientID},
+			"code":          {code},
+			"redirect_uri":  {"http://localhost:8000"},
+			"code_verifier": {verifier},
+			"grant_type":    {"authorization_code"},
+		}
+		resp, err := httpClient.PostForm(httpSrv.URL+"/oauth/token", form)
+		assert.NilError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		var tokens struct {
+			IDToken string `json:"id_token"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&tokens)
+		assert.NilError(t, err)
+
+		provider, err := oidc.NewProvider(ctx, httpSrv.URL)
+		assert.NilError(t, err)
+
+		config := &oidc.Config{ClientID: public.ClientID, SupportedSigningAlgs: []string{oidc.ES256}}
+		_, err = provider.Verifier(config).Verify(ctx, tokens.IDToken)
+		assert.NilError(t, err)
+	})
+
+	t.Run("clients are managed by admins", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/oidc-clients", userKey, nil)
+		assert.Equal(t, resp.StatusCode, http.StatusForbidden)
+
+		resp = call(t, http.MethodGet, "/api/oidc-clients", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+		var clients api.ListResponse[api.OIDCClient]
+		err := json.NewDecoder(resp.Body).Decode(&clients)
+		assert.NilError(t, err)
+		assert.Equal(t, clients.Count, 2)
+		assert.Equal(t, clients.Items[0].Name, "kubernetes")
+		assert.Equal(t, clients.Items[0].Public, true)
+
+		resp = call(t, http.MethodDelete, "/api/oidc-clients/"+client.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.StatusCode, http.StatusNoContent)
+
+		resp = call(t, http.MethodGet, "/oauth/authorize?"+authorizeQuery().Encode(), userKey, nil)
+		assert.Equal(t, resp.StatusCode, http.StatusBadRequest)
+	})
+}
@@ -28,14 +28,18 @@ var funcPartialNameToTagNames = []struct {
 	partial string
 	tag     string
 }{
+	{partial: "AccessRequest", tag: "Access Requests"},
+	{partial: "AuditEvent", tag: "Audit"},
 	{partial: "AccessKey", tag: "Authentication"},
 	{partial: "Login", tag: "Authentication"},
 	{partial: "Logout", tag: "Authentication"},
 	{partial: "Destination", tag: "Destinations"},
 	{partial: "Token", tag: "Destinations"},
+	{partial: "SSHCertificate", tag: "Destinations"},
 	{partial: "Grant", tag: "Grants"},
 	{partial: "Group", tag: "Groups"},
 	{partial: "Provider", tag: "Providers"},
+	{partial: "Role", tag: "Roles"},
 	{partial: "User", tag: "Users"},
 }
 
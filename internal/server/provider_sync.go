@@ -0,0 +1,137 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/infrahq/secrets"
+	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/logging"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+)
+
+// providerSyncInterval is how often users are synced with their identity provider
+const providerSyncInterval = 1 * time.Hour
+
+func (s *Server) providerClient(ctx context.Context, provider *models.Provider, redirectURL string) (providers.OIDCClient, error) {
+	if c := providers.OIDCClientFromContext(ctx); c != nil {
+		// oidc is added to the context during unit tests
+		return c, nil
+	}
+
+	clientSecret, err := secrets.GetSecret(string(provider.ClientSecret), s.secrets)
+	if err != nil {
+		logging.Debugf("could not get client secret: %s", err)
+		return nil, fmt.Errorf("client secret not found")
+	}
+
+	return providers.NewOIDCClient(*provider, clientSecret, redirectURL), nil
+}
+
+func (s *Server) ldapClient(provider *models.Provider) (providers.LDAPClient, error) {
+	bindPassword, err := secrets.GetSecret(string(provider.LDAPBindPassword), s.secrets)
+	if err != nil {
+		logging.Debugf("could not get bind password: %s", err)
+		return nil, fmt.Errorf("bind password not found")
+	}
+
+	return providers.NewLDAPClient(*provider, bindPassword), nil
+}
+
+// syncProviderUsers refreshes the groups of every user from their identity
+// provider, so that changes at the provider take effect without waiting for
+// the user to log in again. LDAP and SAML providers are not synced, the
+// groups of their users are updated each time the user logs in.
+func (s *Server) syncProviderUsers(ctx context.Context) {
+	providerList, err := data.ListProviders(s.db, &models.Pagination{},
+		data.NotProviderKind(models.ProviderKindInfra),
+		data.NotProviderKind(models.ProviderKindLDAP),
+		data.NotProviderKind(models.ProviderKindSAML))
+	if err != nil {
+		logging.Errorf("failed to list providers to sync: %v", err)
+		return
+	}
+
+	for i := range providerList {
+		provider := &providerList[i]
+
+		client, err := s.providerClient(ctx, provider, "")
+		if err != nil {
+			logging.Errorf("failed to sync users of provider %s: %v", provider.Name, err)
+			continue
+		}
+
+		providerUsers, err := data.ListProviderUsers(s.db, &models.Pagination{}, data.ByProviderID(provider.ID))
+		if err != nil {
+			logging.Errorf("failed to list users of provider %s: %v", provider.Name, err)
+			continue
+		}
+
+		for _, providerUser := range providerUsers {
+			if ctx.Err() != nil {
+				return
+			}
+
+			// users which have not logged in with the provider have nothing to refresh
+			if providerUser.RefreshToken == "" {
+				continue
+			}
+
+			if err := s.syncProviderUser(ctx, provider, client, providerUser); err != nil {
+				logging.Errorf("failed to sync user %s with provider %s: %v", providerUser.IdentityID, provider.Name, err)
+			}
+		}
+	}
+}
+
+// syncProviderUser updates the groups of a single user. If the provider
+// rejects the refresh token of the user, the groups from the provider and any
+// sessions started with the provider are revoked.
+func (s *Server) syncProviderUser(ctx context.Context, provider *models.Provider, client providers.OIDCClient, providerUser models.ProviderUser) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		identity, err := data.GetIdentity(tx, data.ByID(providerUser.IdentityID))
+		if err != nil {
+			return fmt.Errorf("get user: %w", err)
+		}
+
+		status := models.ProviderUserSyncSuccess
+
+		syncErr := data.SyncProviderUser(ctx, tx, identity, provider, client)
+		switch {
+		case errors.Is(syncErr, providers.ErrRefreshTokenRejected):
+			logging.Infof("revoking access of user %s, provider %s rejected their refresh token", identity.ID, provider.Name)
+			status = models.ProviderUserSyncFailed
+
+			if err := data.AssignIdentityToGroups(tx, identity, provider, nil); err != nil {
+				return fmt.Errorf("remove provider groups: %w", err)
+			}
+
+			if err := data.DeleteAccessKeys(tx, data.ByIssuedFor(identity.ID), data.ByProviderID(provider.ID)); err != nil {
+				return fmt.Errorf("delete provider access keys: %w", err)
+			}
+		case syncErr != nil:
+			logging.Warnf("failed to sync user %s with provider %s: %v", identity.ID, provider.Name, syncErr)
+			status = models.ProviderUserSyncFailed
+		}
+
+		// the provider user is updated by the sync, get the latest version
+		updated, err := data.GetProviderUser(tx, provider.ID, identity.ID)
+		if err != nil {
+			return err
+		}
+
+		updated.SyncStatus = status
+		if errors.Is(syncErr, providers.ErrRefreshTokenRejected) {
+			// the tokens can not be used again, the user must log in to get new ones
+			updated.AccessToken = ""
+			updated.RefreshToken = ""
+		}
+
+		return data.UpdateProviderUser(tx, updated)
+	})
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+)
+
+// syncOIDCImplementation is a fake oidc identity provider which returns the
+// groups of each user, and rejects the refresh tokens of some users
+type syncOIDCImplementation struct {
+	fakeOIDCImplementation
+	groups   map[string][]string
+	rejected map[string]bool
+}
+
+func (m *syncOIDCImplementation) RefreshAccessToken(_ context.Context, providerUser *models.ProviderUser) (string, *time.Time, error) {
+	if m.rejected[providerUser.Email] {
+		return "", nil, fmt.Errorf("refresh user token: %w", providers.ErrRefreshTokenRejected)
+	}
+	return string(providerUser.AccessToken), &providerUser.ExpiresAt, nil
+}
+
+func (m *syncOIDCImplementation) GetUserInfo(_ context.Context, providerUser *models.ProviderUser) (*providers.UserInfoClaims, error) {
+	return &providers.UserInfoClaims{Email: providerUser.Email, Groups: m.groups[providerUser.Email]}, nil
+}
+
+func TestSyncProviderUsers(t *testing.T) {
+	srv := setupServer(t)
+	db := srv.db
+
+	provider := &models.Provider{Name: "okta", Kind: models.ProviderKindOkta}
+	err := data.CreateProvider(db, provider)
+	assert.NilError(t, err)
+
+	createUser := func(t *testing.T, name string, groups ...string) *models.Identity {
+		t.Helper()
+		identity := &models.Identity{Name: name}
+		createIdentities(t, db, identity)
+
+		pu, err := data.CreateProviderUser(db, provider, identity)
+		assert.NilError(t, err)
+
+		pu.AccessToken = "access"
+		pu.RefreshToken = "refresh"
+		pu.ExpiresAt = time.Now().Add(time.Hour)
+		assert.NilError(t, data.UpdateProviderUser(db, pu))
+
+		assert.NilError(t, data.AssignIdentityToGroups(db, identity, provider, groups))
+
+		_, err = data.CreateAccessKey(db, &models.AccessKey{IssuedFor: identity.ID, ProviderID: provider.ID, ExpiresAt: time.Now().Add(time.Hour)})
+		assert.NilError(t, err)
+		return identity
+	}
+
+	groupNames := func(t *testing.T, identity *models.Identity) []string {
+		t.Helper()
+		groups, err := data.ListGroups(db, &models.Pagination{}, data.ByGroupMember(identity.ID))
+		assert.NilError(t, err)
+
+		var names []string
+		for _, g := range groups {
+			names = append(names, g.Name)
+		}
+		return names
+	}
+
+	alice := createUser(t, "alice@example.com", "developers")
+	bob := createUser(t, "bob@example.com", "operators")
+	// users without a refresh token are not synced
+	carol := &models.Identity{Name: "carol@example.com"}
+	createIdentities(t, db, carol)
+	_, err = data.CreateProviderUser(db, provider, carol)
+	assert.NilError(t, err)
+
+	client := &syncOIDCImplementation{
+		groups:   map[string][]string{"alice@example.com": {"developers", "oncall"}},
+		rejected: map[string]bool{"bob@example.com": true},
+	}
+
+	srv.syncProviderUsers(providers.WithOIDCClient(context.Background(), client))
+
+	t.Run("groups are updated", func(t *testing.T) {
+		assert.DeepEqual(t, groupNames(t, alice), []string{"developers", "oncall"})
+
+		pu, err := data.GetProviderUser(db, provider.ID, alice.ID)
+		assert.NilError(t, err)
+		assert.Equal(t, pu.SyncStatus, models.ProviderUserSyncSuccess)
+
+		keys, err := data.ListAccessKeys(db, &models.Pagination{}, data.ByIssuedFor(alice.ID))
+		assert.NilError(t, err)
+		assert.Equal(t, len(keys), 1)
+	})
+
+	t.Run("access is revoked when the refresh token is rejected", func(t *testing.T) {
+		assert.Equal(t, len(groupNames(t, bob)), 0)
+
+		pu, err := data.GetProviderUser(db, provider.ID, bob.ID)
+		assert.NilError(t, err)
+		assert.Equal(t, pu.SyncStatus, models.ProviderUserSyncFailed)
+		assert.Equal(t, string(pu.RefreshToken), "")
+		assert.Equal(t, len(pu.Groups), 0)
+
+		keys, err := data.ListAccessKeys(db, &models.Pagination{}, data.ByIssuedFor(bob.ID))
+		assert.NilError(t, err)
+		assert.Equal(t, len(keys), 0)
+	})
+
+	t.Run("users without tokens are skipped", func(t *testing.T) {
+		pu, err := data.GetProviderUser(db, provider.ID, carol.ID)
+		assert.NilError(t, err)
+		assert.Equal(t, pu.SyncStatus, "")
+	})
+}
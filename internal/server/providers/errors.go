@@ -10,4 +10,5 @@ var (
 	ErrInvalidProviderClientID     = fmt.Errorf("%w: invalid provider client id", ErrValidation)
 	ErrInvalidProviderClientSecret = fmt.Errorf("%w: invalid provider client secret", ErrValidation)
 	ErrUnauthorized                = fmt.Errorf("unauthorized")
+	ErrRefreshTokenRejected        = fmt.Errorf("refresh token rejected by provider")
 )
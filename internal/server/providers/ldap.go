@@ -0,0 +1,191 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+const (
+	ldapRequestTimeout = time.Second * 10
+
+	DefaultLDAPUserFilter  = "(uid=%s)"
+	DefaultLDAPGroupFilter = "(member=%s)"
+)
+
+var ErrInvalidLDAPFilter = fmt.Errorf("%w: ldap filters must contain %%s exactly once", ErrValidation)
+
+// LDAPUser is a user who was authenticated by an LDAP directory
+type LDAPUser struct {
+	// Name is the email of the user, from the mail attribute of the directory
+	// entry. Users without an email can not login.
+	Name   string
+	Groups []string
+}
+
+type LDAPClient interface {
+	// Validate checks that the directory can be searched with the bind DN
+	Validate(context.Context) error
+	// Authenticate checks the username and password of a user, and looks up
+	// the groups the user is a member of.
+	Authenticate(ctx context.Context, username, password string) (*LDAPUser, error)
+}
+
+type ldapClientImplementation struct {
+	URL          string
+	BindDN       string
+	BindPassword string
+	SearchBase   string
+	UserFilter   string
+	GroupFilter  string
+}
+
+func NewLDAPClient(provider models.Provider, bindPassword string) LDAPClient {
+	client := &ldapClientImplementation{
+		URL:          provider.URL,
+		BindDN:       provider.LDAPBindDN,
+		BindPassword: bindPassword,
+		SearchBase:   provider.LDAPSearchBase,
+		UserFilter:   provider.LDAPUserFilter,
+		GroupFilter:  provider.LDAPGroupFilter,
+	}
+
+	if client.UserFilter == "" {
+		client.UserFilter = DefaultLDAPUserFilter
+	}
+
+	if client.GroupFilter == "" {
+		client.GroupFilter = DefaultLDAPGroupFilter
+	}
+
+	return client
+}
+
+func (l *ldapClientImplementation) Validate(ctx context.Context) error {
+	for _, filter := range []string{l.UserFilter, l.GroupFilter} {
+		if strings.Count(filter, "%s") != 1 {
+			return ErrInvalidLDAPFilter
+		}
+	}
+
+	conn, err := l.dial(ctx)
+	if err != nil {
+		if errors.Is(err, ErrValidation) {
+			return err
+		}
+		return fmt.Errorf("%w: %s", ErrValidation, err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return fmt.Errorf("%w: invalid bind dn or password", ErrValidation)
+		}
+		return fmt.Errorf("bind: %w", err)
+	}
+
+	return nil
+}
+
+func (l *ldapClientImplementation) Authenticate(ctx context.Context, username, password string) (*LDAPUser, error) {
+	// a bind without a password is an unauthenticated bind, which succeeds
+	// for any user
+	if username == "" || password == "" {
+		return nil, ErrUnauthorized
+	}
+
+	conn, err := l.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+		return nil, fmt.Errorf("bind: %w", err)
+	}
+
+	users, err := conn.Search(ldap.NewSearchRequest(
+		l.SearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 0, false,
+		fmt.Sprintf(l.UserFilter, ldap.EscapeFilter(username)),
+		[]string{"mail"}, nil,
+	))
+	if err != nil && !ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, fmt.Errorf("search user: %w", err)
+	}
+
+	// the username must identify exactly one user
+	if users == nil || len(users.Entries) != 1 {
+		return nil, ErrUnauthorized
+	}
+
+	user := users.Entries[0]
+
+	if err := conn.Bind(user.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrUnauthorized
+		}
+		return nil, fmt.Errorf("bind user: %w", err)
+	}
+
+	// the user may not be allowed to search for groups, so search as the bind DN
+	if err := conn.Bind(l.BindDN, l.BindPassword); err != nil {
+		return nil, fmt.Errorf("bind: %w", err)
+	}
+
+	groups, err := conn.Search(ldap.NewSearchRequest(
+		l.SearchBase, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, 0, false,
+		fmt.Sprintf(l.GroupFilter, ldap.EscapeFilter(user.DN)),
+		[]string{"cn"}, nil,
+	))
+	if err != nil {
+		return nil, fmt.Errorf("search groups: %w", err)
+	}
+
+	// users are identified by email across providers, so the username can
+	// not be used instead, it could be the name of any other user
+	email := user.GetAttributeValue("mail")
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		return nil, fmt.Errorf("%w: the directory entry of %q has no valid mail attribute", ErrUnauthorized, username)
+	}
+
+	result := &LDAPUser{Name: email}
+
+	for _, group := range groups.Entries {
+		if name := group.GetAttributeValue("cn"); name != "" {
+			result.Groups = append(result.Groups, name)
+		}
+	}
+
+	return result, nil
+}
+
+// dial connects to the directory. The URL must be an ldap:// or ldaps:// URL.
+func (l *ldapClientImplementation) dial(ctx context.Context) (*ldap.Conn, error) {
+	u, err := url.Parse(l.URL)
+	if err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") {
+		return nil, fmt.Errorf("%w: url must start with ldap:// or ldaps://", ErrInvalidProviderURL)
+	}
+
+	timeout := ldapRequestTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	conn, err := ldap.DialURL(l.URL, ldap.DialWithDialer(&net.Dialer{Timeout: timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("connect to ldap server: %w", err)
+	}
+
+	conn.SetTimeout(timeout)
+
+	return conn, nil
+}
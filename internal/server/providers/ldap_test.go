@@ -0,0 +1,149 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/ldaptest"
+)
+
+func TestLDAPClient(t *testing.T) {
+	srv := ldaptest.NewServer(t,
+		ldaptest.Entry{DN: "cn=infra,ou=services,dc=example,dc=com", Password: "service-password"},
+		ldaptest.Entry{
+			DN:         "uid=alice,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{"uid": {"alice"}, "mail": {"alice@example.com"}},
+			Password:   "alice-password",
+		},
+		ldaptest.Entry{
+			DN:         "uid=bob,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{"uid": {"bob"}, "mail": {"bob@example.com"}},
+			Password:   "bob-password",
+		},
+		ldaptest.Entry{
+			DN:         "uid=bob,ou=contractors,dc=example,dc=com",
+			Attributes: map[string][]string{"uid": {"bob"}},
+			Password:   "other-bob-password",
+		},
+		ldaptest.Entry{
+			DN:         "uid=carol,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{"uid": {"carol"}},
+			Password:   "carol-password",
+		},
+		ldaptest.Entry{
+			DN:         "uid=connector,ou=people,dc=example,dc=com",
+			Attributes: map[string][]string{"uid": {"connector"}, "mail": {"connector"}},
+			Password:   "connector-password",
+		},
+		ldaptest.Entry{
+			DN: "cn=developers,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"cn":     {"developers"},
+				"member": {"uid=alice,ou=people,dc=example,dc=com", "uid=bob,ou=people,dc=example,dc=com"},
+			},
+		},
+		ldaptest.Entry{
+			DN: "cn=admins,ou=groups,dc=example,dc=com",
+			Attributes: map[string][]string{
+				"cn":     {"admins"},
+				"member": {"uid=alice,ou=people,dc=example,dc=com"},
+			},
+		},
+	)
+
+	provider := models.Provider{
+		Kind:           models.ProviderKindLDAP,
+		URL:            srv.URL,
+		LDAPBindDN:     "cn=infra,ou=services,dc=example,dc=com",
+		LDAPSearchBase: "dc=example,dc=com",
+	}
+	client := NewLDAPClient(provider, "service-password")
+	ctx := context.Background()
+
+	t.Run("validate", func(t *testing.T) {
+		assert.NilError(t, client.Validate(ctx))
+
+		err := NewLDAPClient(provider, "wrong").Validate(ctx)
+		assert.ErrorContains(t, err, "invalid bind dn or password")
+		assert.Assert(t, errors.Is(err, ErrValidation))
+
+		invalidURL := provider
+		invalidURL.URL = "example.com"
+		err = NewLDAPClient(invalidURL, "service-password").Validate(ctx)
+		assert.ErrorIs(t, err, ErrInvalidProviderURL)
+
+		invalidFilter := provider
+		invalidFilter.LDAPUserFilter = "(uid=alice)"
+		err = NewLDAPClient(invalidFilter, "service-password").Validate(ctx)
+		assert.ErrorIs(t, err, ErrInvalidLDAPFilter)
+	})
+
+	t.Run("authenticate", func(t *testing.T) {
+		user, err := client.Authenticate(ctx, "alice", "alice-password")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, user, &LDAPUser{Name: "alice@example.com", Groups: []string{"developers", "admins"}})
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "alice", "bob-password")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("empty password", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "alice", "")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "dave", "alice-password")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("username is escaped", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "*", "alice-password")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("ambiguous username", func(t *testing.T) {
+		_, err := client.Authenticate(ctx, "bob", "bob-password")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("search base and user filter", func(t *testing.T) {
+		people := provider
+		people.LDAPSearchBase = "ou=people,dc=example,dc=com"
+		people.LDAPUserFilter = "(&(uid=%s)(!(mail=alice@example.com)))"
+		client := NewLDAPClient(people, "service-password")
+
+		user, err := client.Authenticate(ctx, "bob", "bob-password")
+		assert.NilError(t, err)
+		// groups outside of the search base are not found
+		assert.DeepEqual(t, user, &LDAPUser{Name: "bob@example.com"})
+
+		_, err = client.Authenticate(ctx, "alice", "alice-password")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+	})
+
+	t.Run("no valid email", func(t *testing.T) {
+		// the username must not be used instead of the email
+		_, err := client.Authenticate(ctx, "carol", "carol-password")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		assert.ErrorContains(t, err, "no valid mail attribute")
+
+		_, err = client.Authenticate(ctx, "connector", "connector-password")
+		assert.ErrorIs(t, err, ErrUnauthorized)
+		assert.ErrorContains(t, err, "no valid mail attribute")
+	})
+
+	t.Run("server unavailable", func(t *testing.T) {
+		unavailable := provider
+		unavailable.URL = "ldap://127.0.0.1:1"
+		_, err := NewLDAPClient(unavailable, "service-password").Authenticate(ctx, "alice", "alice-password")
+		assert.ErrorContains(t, err, "connect to ldap server")
+		assert.Assert(t, !errors.Is(err, ErrUnauthorized))
+	})
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"time"
 
@@ -247,6 +248,10 @@ func (o *oidcClientImplementation) RefreshAccessToken(ctx context.Context, provi
 
 	newToken, err := tokenSource.Token() // this refreshes token if needed
 	if err != nil {
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) && retrieveErr.Response != nil && retrieveErr.Response.StatusCode < http.StatusInternalServerError {
+			return "", nil, fmt.Errorf("refresh user token: %w: %s", ErrRefreshTokenRejected, err)
+		}
 		return "", nil, fmt.Errorf("refresh user token: %w", err)
 	}
 
@@ -0,0 +1,171 @@
+package providers
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/crewjam/saml"
+
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+const (
+	samlRequestTimeout = time.Second * 10
+	// samlMetadataMaxSize limits the metadata read from an identity provider
+	samlMetadataMaxSize = 1 << 20
+
+	DefaultSAMLGroupsAttribute = "groups"
+)
+
+var ErrInvalidSAMLMetadata = fmt.Errorf("%w: invalid saml metadata", ErrValidation)
+
+// SAMLUser is a user who was authenticated by a SAML identity provider
+type SAMLUser struct {
+	// Name is the NameID of the assertion, which is the email of the user
+	Name   string
+	Groups []string
+}
+
+// FetchSAMLMetadata downloads the metadata of a SAML identity provider
+func FetchSAMLMetadata(ctx context.Context, metadataURL string) ([]byte, error) {
+	u, err := url.Parse(metadataURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, ErrInvalidProviderURL
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, samlRequestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProviderURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: metadata request returned %s", ErrInvalidProviderURL, resp.Status)
+	}
+
+	return io.ReadAll(io.LimitReader(resp.Body, samlMetadataMaxSize))
+}
+
+// ParseSAMLMetadata parses the metadata of a SAML identity provider. The
+// metadata must include a single sign-on URL with the HTTP-Redirect binding,
+// and a certificate to verify the signature of assertions. The metadata may
+// be an EntitiesDescriptor, in which case the first identity provider is used.
+func ParseSAMLMetadata(metadata []byte) (*saml.EntityDescriptor, error) {
+	var entity saml.EntityDescriptor
+	if err := xml.Unmarshal(metadata, &entity); err != nil {
+		var entities saml.EntitiesDescriptor
+		if err := xml.Unmarshal(metadata, &entities); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidSAMLMetadata, err)
+		}
+
+		found := false
+		for _, e := range entities.EntityDescriptors {
+			if len(e.IDPSSODescriptors) > 0 {
+				entity, found = e, true
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w: no identity provider in metadata", ErrInvalidSAMLMetadata)
+		}
+	}
+
+	sp := saml.ServiceProvider{IDPMetadata: &entity}
+	if sp.GetSSOBindingLocation(saml.HTTPRedirectBinding) == "" {
+		return nil, fmt.Errorf("%w: no single sign-on url with the HTTP-Redirect binding", ErrInvalidSAMLMetadata)
+	}
+
+	if !hasSAMLSigningCertificate(entity) {
+		return nil, fmt.Errorf("%w: no signing certificate", ErrInvalidSAMLMetadata)
+	}
+
+	return &entity, nil
+}
+
+func hasSAMLSigningCertificate(entity saml.EntityDescriptor) bool {
+	for _, idp := range entity.IDPSSODescriptors {
+		for _, key := range idp.KeyDescriptors {
+			if key.Use != "" && key.Use != "signing" {
+				continue
+			}
+			if len(key.KeyInfo.X509Data.X509Certificates) > 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NewSAMLServiceProvider returns the service provider which logs in the users
+// of a SAML provider. The endpoints of the service provider are on the Infra
+// server at baseURL. The entity ID of the service provider is the URL of its
+// metadata.
+func NewSAMLServiceProvider(provider models.Provider, baseURL url.URL) (*saml.ServiceProvider, error) {
+	idpMetadata, err := ParseSAMLMetadata([]byte(provider.SAMLMetadata))
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := func(name string) url.URL {
+		u := baseURL
+		u.Path = path.Join(baseURL.Path, fmt.Sprintf("/api/providers/%s/saml/%s", provider.ID, name))
+		return u
+	}
+
+	metadataURL := endpoint("metadata")
+
+	return &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		MetadataURL:       metadataURL,
+		AcsURL:            endpoint("acs"),
+		IDPMetadata:       idpMetadata,
+		AuthnNameIDFormat: saml.EmailAddressNameIDFormat,
+	}, nil
+}
+
+// SAMLUserFromAssertion returns the user from a validated assertion. The
+// groups of the user are the values of the groups attribute, which is matched
+// by its name or friendly name.
+func SAMLUserFromAssertion(assertion *saml.Assertion, groupsAttribute string) (*SAMLUser, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || strings.TrimSpace(assertion.Subject.NameID.Value) == "" {
+		return nil, fmt.Errorf("assertion has no name id")
+	}
+
+	if groupsAttribute == "" {
+		groupsAttribute = DefaultSAMLGroupsAttribute
+	}
+
+	user := &SAMLUser{Name: strings.TrimSpace(assertion.Subject.NameID.Value)}
+
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			if attr.Name != groupsAttribute && attr.FriendlyName != groupsAttribute {
+				continue
+			}
+
+			for _, value := range attr.Values {
+				if value.Value != "" {
+					user.Groups = append(user.Groups, value.Value)
+				}
+			}
+		}
+	}
+
+	return user, nil
+}
@@ -0,0 +1,105 @@
+package providers
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/crewjam/saml"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/samltest"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestParseSAMLMetadata(t *testing.T) {
+	idp := samltest.NewIdentityProvider(t, samltest.User{NameID: "alice@example.com"})
+
+	t.Run("fetched from the identity provider", func(t *testing.T) {
+		metadata, err := FetchSAMLMetadata(context.Background(), idp.MetadataURL)
+		assert.NilError(t, err)
+
+		entity, err := ParseSAMLMetadata(metadata)
+		assert.NilError(t, err)
+		assert.Equal(t, entity.EntityID, idp.MetadataURL)
+	})
+
+	t.Run("entities descriptor", func(t *testing.T) {
+		metadata := `<EntitiesDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata">` + idp.Metadata(t) + `</EntitiesDescriptor>`
+
+		entity, err := ParseSAMLMetadata([]byte(metadata))
+		assert.NilError(t, err)
+		assert.Equal(t, entity.EntityID, idp.MetadataURL)
+	})
+
+	t.Run("no signing certificate", func(t *testing.T) {
+		metadata := `<EntityDescriptor xmlns="urn:oasis:names:tc:SAML:2.0:metadata" entityID="https://idp.example.com">
+  <IDPSSODescriptor protocolSupportEnumeration="urn:oasis:names:tc:SAML:2.0:protocol">
+    <SingleSignOnService Binding="urn:oasis:names:tc:SAML:2.0:bindings:HTTP-Redirect" Location="https://idp.example.com/sso"/>
+  </IDPSSODescriptor>
+</EntityDescriptor>`
+
+		_, err := ParseSAMLMetadata([]byte(metadata))
+		assert.ErrorContains(t, err, "no signing certificate")
+		assert.Assert(t, errors.Is(err, ErrValidation))
+	})
+
+	t.Run("not metadata", func(t *testing.T) {
+		_, err := ParseSAMLMetadata([]byte(`{"issuer": "https://idp.example.com"}`))
+		assert.Assert(t, errors.Is(err, ErrInvalidSAMLMetadata), err)
+	})
+
+	t.Run("invalid metadata url", func(t *testing.T) {
+		_, err := FetchSAMLMetadata(context.Background(), "idp.example.com/metadata")
+		assert.Assert(t, errors.Is(err, ErrInvalidProviderURL), err)
+	})
+}
+
+func TestNewSAMLServiceProvider(t *testing.T) {
+	idp := samltest.NewIdentityProvider(t, samltest.User{NameID: "alice@example.com"})
+
+	provider := models.Provider{
+		Model:        models.Model{ID: uid.ID(1234)},
+		Kind:         models.ProviderKindSAML,
+		SAMLMetadata: idp.Metadata(t),
+	}
+
+	sp, err := NewSAMLServiceProvider(provider, url.URL{Scheme: "https", Host: "infra.example.com"})
+	assert.NilError(t, err)
+
+	assert.Equal(t, sp.EntityID, "https://infra.example.com/api/providers/"+provider.ID.String()+"/saml/metadata")
+	assert.Equal(t, sp.AcsURL.String(), "https://infra.example.com/api/providers/"+provider.ID.String()+"/saml/acs")
+	assert.Assert(t, strings.HasSuffix(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), "/sso"))
+}
+
+func TestSAMLUserFromAssertion(t *testing.T) {
+	assertion := &saml.Assertion{
+		Subject: &saml.Subject{NameID: &saml.NameID{Value: "alice@example.com"}},
+		AttributeStatements: []saml.AttributeStatement{{
+			Attributes: []saml.Attribute{
+				{Name: "http://schemas.microsoft.com/ws/2008/06/identity/claims/groups", FriendlyName: "memberOf", Values: []saml.AttributeValue{{Value: "developers"}, {Value: "admins"}}},
+				{Name: "groups", Values: []saml.AttributeValue{{Value: "everyone"}}},
+			},
+		}},
+	}
+
+	t.Run("default groups attribute", func(t *testing.T) {
+		user, err := SAMLUserFromAssertion(assertion, "")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, user, &SAMLUser{Name: "alice@example.com", Groups: []string{"everyone"}})
+	})
+
+	t.Run("groups attribute matches the friendly name", func(t *testing.T) {
+		user, err := SAMLUserFromAssertion(assertion, "memberOf")
+		assert.NilError(t, err)
+		assert.DeepEqual(t, user.Groups, []string{"developers", "admins"})
+	})
+
+	t.Run("no name id", func(t *testing.T) {
+		_, err := SAMLUserFromAssertion(&saml.Assertion{}, "")
+		assert.ErrorContains(t, err, "no name id")
+	})
+}
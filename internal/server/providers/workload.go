@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"gopkg.in/square/go-jose.v2/jwt"
+)
+
+// WorkloadIssuer verifies the OIDC tokens of a trusted issuer of workload
+// identities, such as GitHub Actions, GitLab, or a Kubernetes cluster.
+type WorkloadIssuer struct {
+	// Issuer must match the iss claim of the token.
+	Issuer string
+	// Audience must be one of the aud claims of the token.
+	Audience string
+
+	jwksURL string
+
+	mu       sync.Mutex
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewWorkloadIssuer returns a WorkloadIssuer which verifies tokens with the
+// keys at jwksURL. When jwksURL is empty, the keys are found by OIDC discovery
+// the first time a token is verified.
+func NewWorkloadIssuer(issuer, jwksURL, audience string) *WorkloadIssuer {
+	return &WorkloadIssuer{
+		Issuer:   issuer,
+		Audience: audience,
+		jwksURL:  jwksURL,
+	}
+}
+
+// Verify checks the signature, issuer, audience, and expiry of the token, and
+// returns its claims.
+func (w *WorkloadIssuer) Verify(ctx context.Context, rawToken string) (map[string]interface{}, error) {
+	verifier, err := w.tokenVerifier(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrUnauthorized, err)
+	}
+
+	claims := map[string]interface{}{}
+	if err := token.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("token claims: %w", err)
+	}
+
+	return claims, nil
+}
+
+func (w *WorkloadIssuer) tokenVerifier(ctx context.Context) (*oidc.IDTokenVerifier, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.verifier != nil {
+		return w.verifier, nil
+	}
+
+	jwksURL := w.jwksURL
+	if jwksURL == "" {
+		ctx, cancel := context.WithTimeout(ctx, oidcProviderRequestTimeout)
+		defer cancel()
+
+		provider, err := oidc.NewProvider(ctx, w.Issuer)
+		if err != nil {
+			return nil, fmt.Errorf("discover workload issuer %s: %w", w.Issuer, err)
+		}
+
+		var discovery struct {
+			JWKSURL string `json:"jwks_uri"`
+		}
+		if err := provider.Claims(&discovery); err != nil {
+			return nil, fmt.Errorf("discover workload issuer %s: %w", w.Issuer, err)
+		}
+		jwksURL = discovery.JWKSURL
+	}
+
+	// the key set is shared by every login, so it must not use the context of
+	// the request which created it
+	keySet := oidc.NewRemoteKeySet(context.Background(), jwksURL)
+	w.verifier = oidc.NewVerifier(w.Issuer, keySet, &oidc.Config{
+		ClientID:             w.Audience,
+		SupportedSigningAlgs: []string{oidc.RS256, oidc.ES256},
+	})
+
+	return w.verifier, nil
+}
+
+// WorkloadTokenIssuer returns the iss claim of a token without verifying it,
+// so that the token can be verified by its issuer.
+func WorkloadTokenIssuer(rawToken string) (string, error) {
+	token, err := jwt.ParseSigned(rawToken)
+	if err != nil {
+		return "", fmt.Errorf("%w: invalid token: %v", ErrUnauthorized, err)
+	}
+
+	var claims jwt.Claims
+	if err := token.UnsafeClaimsWithoutVerification(&claims); err != nil {
+		return "", fmt.Errorf("%w: invalid token: %v", ErrUnauthorized, err)
+	}
+
+	return claims.Issuer, nil
+}
@@ -226,7 +226,7 @@ func TestAPI_CreateProvider(t *testing.T) {
 				assert.NilError(t, err)
 
 				expected := []api.FieldError{
-					{FieldName: "kind", Errors: []string{"must be one of (oidc, okta, azure, google)"}},
+					{FieldName: "kind", Errors: []string{"must be one of (oidc, okta, azure, google, ldap, saml)"}},
 				}
 				assert.DeepEqual(t, respBody.FieldErrors, expected)
 			},
@@ -345,7 +345,7 @@ func TestAPI_UpdateProvider(t *testing.T) {
 				assert.NilError(t, err)
 
 				expected := []api.FieldError{
-					{FieldName: "kind", Errors: []string{"must be one of (oidc, okta, azure, google)"}},
+					{FieldName: "kind", Errors: []string{"must be one of (oidc, okta, azure, google, ldap, saml)"}},
 				}
 				assert.DeepEqual(t, respBody.FieldErrors, expected)
 			},
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestAPI_Roles(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	manager := &models.Identity{Name: "manager@example.com"}
+	user := &models.Identity{Name: "user@example.com"}
+	createIdentities(t, srv.db, manager, user)
+
+	managerKey, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+		IssuedFor:  manager.ID,
+		ProviderID: data.InfraProvider(srv.db).ID,
+		ExpiresAt:  time.Now().Add(10 * time.Minute),
+	})
+	assert.NilError(t, err)
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(method, path, jsonBody(t, body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+key)
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	var role api.Role
+
+	t.Run("create role", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/roles", adminAccessKey(srv), api.CreateRoleRequest{
+			Name:        "grant-manager",
+			Description: "Manages grants",
+			Permissions: []string{models.PermissionGrantsRead, models.PermissionGrantsCreate},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		err := json.Unmarshal(resp.Body.Bytes(), &role)
+		assert.NilError(t, err)
+		assert.Equal(t, role.Name, "grant-manager")
+		assert.DeepEqual(t, role.Permissions, []string{"grants:read", "grants:create"})
+	})
+
+	t.Run("create role with builtin name", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/roles", adminAccessKey(srv), api.CreateRoleRequest{
+			Name:        models.InfraAdminRole,
+			Permissions: []string{models.PermissionGrantsRead},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("create role with invalid permission", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/roles", adminAccessKey(srv), api.CreateRoleRequest{
+			Name:        "invalid",
+			Permissions: []string{"grants:read,providers:create"},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("create duplicate role", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/roles", adminAccessKey(srv), api.CreateRoleRequest{
+			Name:        "grant-manager",
+			Permissions: []string{models.PermissionGrantsRead},
+		})
+		assert.Equal(t, resp.Code, http.StatusConflict, resp.Body.String())
+	})
+
+	t.Run("non-admin can not create role", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/roles", managerKey, api.CreateRoleRequest{
+			Name:        "escalate",
+			Permissions: []string{models.InfraAdminRole},
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	err = data.CreateGrant(srv.db, &models.Grant{
+		Subject:   manager.PolyID(),
+		Privilege: "grant-manager",
+		Resource:  access.ResourceInfraAPI,
+	})
+	assert.NilError(t, err)
+
+	t.Run("role allows creating grants", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/grants", managerKey, api.CreateGrantRequest{
+			User:      user.ID,
+			Privilege: "view",
+			Resource:  "production",
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+	})
+
+	t.Run("role does not allow creating infra grants", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/grants", managerKey, api.CreateGrantRequest{
+			User:      manager.ID,
+			Privilege: models.InfraAdminRole,
+			Resource:  access.ResourceInfraAPI,
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("role does not allow managing providers", func(t *testing.T) {
+		provider := &models.Provider{Name: "okta", Kind: models.ProviderKindOkta}
+		err := data.CreateProvider(srv.db, provider)
+		assert.NilError(t, err)
+
+		resp := call(t, http.MethodDelete, "/api/providers/"+provider.ID.String(), managerKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("update role", func(t *testing.T) {
+		resp := call(t, http.MethodPut, "/api/roles/"+role.ID.String(), adminAccessKey(srv), api.UpdateRoleRequest{
+			Permissions: []string{models.PermissionGrantsRead},
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var updated api.Role
+		err := json.Unmarshal(resp.Body.Bytes(), &updated)
+		assert.NilError(t, err)
+		assert.Equal(t, updated.Name, "grant-manager")
+		assert.DeepEqual(t, updated.Permissions, []string{"grants:read"})
+
+		resp = call(t, http.MethodPost, "/api/grants", managerKey, api.CreateGrantRequest{
+			User:      user.ID,
+			Privilege: "view",
+			Resource:  "staging",
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("list roles", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/roles?name=grant-manager", managerKey, nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/roles?name=grant-manager", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var roles api.ListResponse[api.Role]
+		err := json.Unmarshal(resp.Body.Bytes(), &roles)
+		assert.NilError(t, err)
+		assert.Equal(t, roles.Count, 1)
+		assert.Equal(t, roles.Items[0].ID, role.ID)
+	})
+
+	t.Run("delete role", func(t *testing.T) {
+		resp := call(t, http.MethodDelete, "/api/roles/"+role.ID.String(), adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		_, err := data.GetRole(srv.db, data.ByID(role.ID))
+		assert.ErrorContains(t, err, "record not found")
+
+		grants, err := data.ListGrants(srv.db, &models.Pagination{}, data.ByPrivilege("grant-manager"))
+		assert.NilError(t, err)
+		assert.Equal(t, len(grants), 0)
+	})
+}
@@ -38,7 +38,14 @@ type Routes struct {
 // with all the middleware that will apply to the route when the
 // Router.{GET,POST,etc} method is called.
 func (s *Server) GenerateRoutes(promRegistry prometheus.Registerer) Routes {
-	a := &API{t: s.tel, server: s}
+	a := &API{t: s.tel, server: s, routeScopes: map[string]bool{}, resourceRoutes: map[string]bool{}}
+	a.loginFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "infra",
+		Name:      "login_failures_total",
+		Help:      "The total number of failed logins, by login method",
+	}, []string{"method"})
+	promRegistry.MustRegister(a.loginFailures)
+
 	router := gin.New()
 	router.NoRoute(a.notFoundHandler)
 
@@ -58,11 +65,27 @@ func (s *Server) GenerateRoutes(promRegistry prometheus.Registerer) Routes {
 	apiGroup := router.Group("/",
 		metrics.Middleware(promRegistry),
 		DatabaseMiddleware(a.server.db), // must be after TimeoutMiddleware to time out db queries.
+		AuthzCacheMiddleware(a.server.authzCache),
+		ResourceRoutesMiddleware(a.resourceRoutes),
 	)
 	apiGroup.GET("/.well-known/jwks.json", a.wellKnownJWKsHandler)
+	// redirects the browser, so this route is not part of the API document
+	apiGroup.GET("/api/destinations/:id/authorize", a.authorizeDestinationHandler)
+	// the SAML endpoints are used by browsers and identity providers, so these
+	// routes are not part of the API document either
+	apiGroup.GET("/api/providers/:id/saml/metadata", a.samlMetadataHandler)
+	apiGroup.GET("/api/providers/:id/saml/login", a.samlLoginHandler)
+	apiGroup.POST("/api/providers/:id/saml/acs", a.samlACSHandler)
+	// the OIDC identity provider endpoints are used by OIDC clients, and
+	// follow the OAuth conventions instead of the API
+	apiGroup.GET("/.well-known/openid-configuration", a.openIDConfigurationHandler)
+	apiGroup.GET("/oauth/authorize", a.oauthAuthorizeHandler)
+	apiGroup.POST("/oauth/token", a.oauthTokenHandler)
+	apiGroup.GET("/userinfo", a.userInfoHandler)
+	apiGroup.POST("/userinfo", a.userInfoHandler)
 
 	authn := apiGroup.Group("/",
-		AuthenticationMiddleware(),
+		AuthenticationMiddleware(a.server.certificates),
 		DestinationMiddleware(),
 	)
 
@@ -71,6 +94,13 @@ func (s *Server) GenerateRoutes(promRegistry prometheus.Registerer) Routes {
 	get(a, authn, "/api/users/:id", a.GetUser)
 	put(a, authn, "/api/users/:id", a.UpdateUser)
 	del(a, authn, "/api/users/:id", a.DeleteUser)
+	post(a, authn, "/api/users/:id/mfa", a.CreateMFA)
+	post(a, authn, "/api/users/:id/mfa/verify", a.VerifyMFA)
+	del(a, authn, "/api/users/:id/mfa", a.DeleteMFA)
+	del(a, authn, "/api/users/:id/lockout", a.UnlockUser)
+	get(a, authn, "/api/users/:id/sessions", a.ListSessions)
+	del(a, authn, "/api/users/:id/sessions", a.DeleteSessions)
+	del(a, authn, "/api/users/:id/sessions/:session", a.DeleteSession)
 
 	get(a, authn, "/api/access-keys", a.ListAccessKeys)
 	post(a, authn, "/api/access-keys", a.CreateAccessKey)
@@ -79,40 +109,92 @@ func (s *Server) GenerateRoutes(promRegistry prometheus.Registerer) Routes {
 	get(a, authn, "/api/groups", a.ListGroups)
 	post(a, authn, "/api/groups", a.CreateGroup)
 	get(a, authn, "/api/groups/:id", a.GetGroup)
+	put(a, authn, "/api/groups/:id", a.UpdateGroup)
 	del(a, authn, "/api/groups/:id", a.DeleteGroup)
 	patch(a, authn, "/api/groups/:id/users", a.UpdateUsersInGroup)
+	patch(a, authn, "/api/groups/:id/groups", a.UpdateGroupsInGroup)
 
 	get(a, authn, "/api/grants", a.ListGrants)
 	get(a, authn, "/api/grants/:id", a.GetGrant)
 	post(a, authn, "/api/grants", a.CreateGrant)
 	del(a, authn, "/api/grants/:id", a.DeleteGrant)
+	post(a, authn, "/api/authz/check", a.CheckAuthorization)
+
+	get(a, authn, "/api/roles", a.ListRoles)
+	get(a, authn, "/api/roles/:id", a.GetRole)
+	post(a, authn, "/api/roles", a.CreateRole)
+	put(a, authn, "/api/roles/:id", a.UpdateRole)
+	del(a, authn, "/api/roles/:id", a.DeleteRole)
 
 	post(a, authn, "/api/providers", a.CreateProvider)
 	put(a, authn, "/api/providers/:id", a.UpdateProvider)
 	del(a, authn, "/api/providers/:id", a.DeleteProvider)
+	post(a, authn, "/api/providers/:id/scim-keys", a.CreateProviderSCIMKey)
+
+	get(a, authn, "/api/oidc-clients", a.ListOIDCClients)
+	post(a, authn, "/api/oidc-clients", a.CreateOIDCClient)
+	del(a, authn, "/api/oidc-clients/:id", a.DeleteOIDCClient)
 
 	get(a, authn, "/api/destinations", a.ListDestinations)
 	get(a, authn, "/api/destinations/:id", a.GetDestination)
 	post(a, authn, "/api/destinations", a.CreateDestination)
 	put(a, authn, "/api/destinations/:id", a.UpdateDestination)
 	del(a, authn, "/api/destinations/:id", a.DeleteDestination)
+	post(a, authn, "/api/destinations/:id/ssh-certificates", a.CreateSSHCertificate)
+	get(a, authn, "/api/ssh-ca", a.GetSSHCertificateAuthority)
+
+	post(a, authn, "/api/certificates", a.CreateCertificate)
+
+	get(a, authn, "/api/audit-events", a.ListAuditEvents)
+
+	get(a, authn, "/api/access-requests", a.ListAccessRequests)
+	get(a, authn, "/api/access-requests/:id", a.GetAccessRequest)
+	post(a, authn, "/api/access-requests", a.CreateAccessRequest)
+	post(a, authn, "/api/access-requests/:id/approve", a.ApproveAccessRequest)
+	post(a, authn, "/api/access-requests/:id/deny", a.DenyAccessRequest)
+
+	get(a, authn, "/api/access-reviews", a.ListAccessReviews)
+	get(a, authn, "/api/access-reviews/:id", a.GetAccessReview)
+	post(a, authn, "/api/access-reviews", a.CreateAccessReview)
+	get(a, authn, "/api/access-reviews/:id/items", a.ListAccessReviewItems)
+	put(a, authn, "/api/access-reviews/:id/items/:item", a.DecideAccessReviewItem)
+
+	post(a, authn, "/api/device/approve", a.ApproveDeviceFlow)
 
 	post(a, authn, "/api/tokens", a.CreateToken)
 	post(a, authn, "/api/logout", a.Logout)
 
 	authn.GET("/api/debug/pprof/*profile", pprofHandler)
 
+	// SCIM provisioning uses its own request and response formats, so these
+	// routes are not part of the API document
+	scim := apiGroup.Group("/scim/v2", scimAuthenticationMiddleware())
+	scim.GET("/Users", scimHandler(a.scimListUsers))
+	scim.POST("/Users", scimHandler(a.scimCreateUser))
+	scim.GET("/Users/:id", scimHandler(a.scimGetUser))
+	scim.PUT("/Users/:id", scimHandler(a.scimReplaceUser))
+	scim.PATCH("/Users/:id", scimHandler(a.scimPatchUser))
+	scim.DELETE("/Users/:id", scimHandler(a.scimDeleteUser))
+	scim.GET("/Groups", scimHandler(a.scimListGroups))
+	scim.POST("/Groups", scimHandler(a.scimCreateGroup))
+	scim.GET("/Groups/:id", scimHandler(a.scimGetGroup))
+	scim.PUT("/Groups/:id", scimHandler(a.scimReplaceGroup))
+	scim.PATCH("/Groups/:id", scimHandler(a.scimPatchGroup))
+	scim.DELETE("/Groups/:id", scimHandler(a.scimDeleteGroup))
+
 	// these endpoints do not require authentication
 	noAuthn := apiGroup.Group("/")
 	get(a, noAuthn, "/api/signup", a.SignupEnabled)
 	post(a, noAuthn, "/api/signup", a.Signup)
 
 	post(a, noAuthn, "/api/login", a.Login)
+	post(a, noAuthn, "/api/device", a.StartDeviceFlow)
 
 	get(a, noAuthn, "/api/providers", a.ListProviders)
 	get(a, noAuthn, "/api/providers/:id", a.GetProvider)
 
 	get(a, noAuthn, "/api/version", a.Version)
+	get(a, noAuthn, "/api/certificate-authorities", a.ListCertificateAuthorities)
 
 	// Deprecated in 0.12
 	// TODO: remove after a couple versions
@@ -170,33 +252,51 @@ func add[Req, Res any](a *API, r *gin.RouterGroup, route route[Req, Res]) {
 		a.register(openAPIRouteDefinition(route))
 	}
 
+	// the operation scope is checked by RequireAccessKey, the resource scope
+	// is checked once the request is bound
+	if scope := routeScope(route.path); scope != "" {
+		a.routeScopes[scope] = true
+	}
+	if a.resourceRoutes == nil {
+		a.resourceRoutes = map[string]bool{}
+	}
+	a.resourceRoutes[resourceRouteKey(route.method, route.path)] = true
+
 	wrappedHandler := func(c *gin.Context) {
-		req := new(Req)
-		if err := bind(c, req); err != nil {
-			sendAPIError(c, err)
-			return
-		}
+		auditRequest(c, route.method, route.path, func() (any, error) {
+			req := new(Req)
+			if err := bind(c, req); err != nil {
+				sendAPIError(c, err)
+				return nil, err
+			}
 
-		trimWhitespace(req)
+			if err := requireResourceScope(c, req); err != nil {
+				sendAPIError(c, err)
+				return nil, err
+			}
 
-		resp, err := route.handler(c, req)
-		if err != nil {
-			sendAPIError(c, err)
-			return
-		}
+			trimWhitespace(req)
 
-		if !route.omitFromTelemetry {
-			a.t.RouteEvent(c, route.path, Properties{"method": strings.ToLower(route.method)})
-		}
+			resp, err := route.handler(c, req)
+			if err != nil {
+				sendAPIError(c, err)
+				return nil, err
+			}
 
-		statusCode := defaultResponseCodeForMethod(route.method)
-		if c, ok := any(resp).(statusCoder); ok {
-			if code := c.StatusCode(); code != 0 {
-				statusCode = code
+			if !route.omitFromTelemetry {
+				a.t.RouteEvent(c, route.path, Properties{"method": strings.ToLower(route.method)})
+			}
+
+			statusCode := defaultResponseCodeForMethod(route.method)
+			if c, ok := any(resp).(statusCoder); ok {
+				if code := c.StatusCode(); code != 0 {
+					statusCode = code
+				}
 			}
-		}
 
-		c.JSON(statusCode, resp)
+			c.JSON(statusCode, resp)
+			return resp, nil
+		})
 	}
 
 	bindRoute(a, r, route.method, route.path, wrappedHandler)
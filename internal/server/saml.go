@@ -0,0 +1,122 @@
+package server
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
+	"github.com/infrahq/infra/uid"
+)
+
+// samlMetadataHandler responds with the metadata of the service provider of a
+// SAML provider, which is used to configure Infra at the identity provider.
+func (a *API) samlMetadataHandler(c *gin.Context) {
+	_, sp, err := a.samlServiceProvider(c)
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	metadata, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+// samlLoginHandler starts a login with a SAML provider. The browser of the
+// user is redirected to the identity provider with an authentication request.
+func (a *API) samlLoginHandler(c *gin.Context) {
+	provider, sp, err := a.samlServiceProvider(c)
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	u, err := access.StartSAMLLogin(c, provider, sp, c.Query("redirectURL"), c.Query("state"))
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, u.String())
+}
+
+// samlACSHandler is the assertion consumer service of a SAML provider. The
+// identity provider posts the response to the authentication request, and
+// the browser of the user is redirected back to the client with a login code.
+func (a *API) samlACSHandler(c *gin.Context) {
+	provider, sp, err := a.samlServiceProvider(c)
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	u, err := access.CompleteSAMLLogin(c, provider, sp, c.PostForm("RelayState"), c.PostForm("SAMLResponse"))
+	if err != nil {
+		sendAPIError(c, err)
+		return
+	}
+
+	c.Redirect(http.StatusFound, u.String())
+}
+
+// samlServiceProvider returns the provider from the request path, and its
+// service provider. The endpoints of the service provider are on the base URL
+// of the server.
+func (a *API) samlServiceProvider(c *gin.Context) (*models.Provider, *saml.ServiceProvider, error) {
+	id, err := uid.Parse([]byte(c.Param("id")))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: invalid provider id", internal.ErrBadRequest)
+	}
+
+	provider, err := access.GetProvider(c, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if provider.Kind != models.ProviderKindSAML {
+		return nil, nil, fmt.Errorf("%w: provider %s is not a saml provider", internal.ErrBadRequest, provider.Name)
+	}
+
+	sp, err := providers.NewSAMLServiceProvider(*provider, a.baseURL(c))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return provider, sp, nil
+}
+
+// baseURL returns the URL of the Infra server. It is the configured BaseURL,
+// or the URL requested by the client when BaseURL is not set.
+func (a *API) baseURL(c *gin.Context) url.URL {
+	if base := a.server.options.BaseURL; base != "" {
+		// BaseURL is validated when the server is created
+		if u, err := url.Parse(strings.TrimSuffix(base, "/")); err == nil {
+			return url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}
+		}
+	}
+
+	return requestBaseURL(c)
+}
+
+// requestBaseURL returns the URL of the Infra server, as it was requested by
+// the client
+func requestBaseURL(c *gin.Context) url.URL {
+	scheme := "https"
+	if c.Request.TLS == nil {
+		scheme = "http"
+	}
+	return url.URL{Scheme: scheme, Host: c.Request.Host}
+}
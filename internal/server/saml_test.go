@@ -0,0 +1,233 @@
+package server
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/testing/samltest"
+)
+
+func TestAPI_SAMLProvider(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	idp := samltest.NewIdentityProvider(t, samltest.User{
+		NameID: "alice@example.com",
+		Groups: []string{"Developers"},
+	})
+
+	serve := func(t *testing.T, req *http.Request, key string) *httptest.ResponseRecorder {
+		t.Helper()
+		if key != "" {
+			req.Header.Add("Authorization", "Bearer "+key)
+		}
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		return serve(t, httptest.NewRequest(method, path, jsonBody(t, body)), key)
+	}
+
+	t.Run("create with invalid metadata", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/providers", adminAccessKey(srv), api.CreateProviderRequest{
+			Name: "invalid",
+			URL:  "https://idp.example.com/metadata",
+			Kind: "saml",
+			SAML: &api.ProviderSAML{Metadata: "<EntityDescriptor/>"},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("create with metadata in the request", func(t *testing.T) {
+		metadata := idp.Metadata(t)
+		resp := call(t, http.MethodPost, "/api/providers", adminAccessKey(srv), api.CreateProviderRequest{
+			Name: "offline",
+			URL:  "https://idp.example.com/metadata",
+			Kind: "saml",
+			SAML: &api.ProviderSAML{Metadata: metadata, GroupsAttribute: "memberOf"},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		stored, err := data.GetProvider(srv.db, data.ByName("offline"))
+		assert.NilError(t, err)
+		assert.Equal(t, stored.SAMLMetadata, metadata)
+		assert.Equal(t, stored.SAMLGroupsAttribute, "memberOf")
+	})
+
+	resp := call(t, http.MethodPost, "/api/providers", adminAccessKey(srv), api.CreateProviderRequest{
+		Name: "adfs",
+		URL:  idp.MetadataURL,
+		Kind: "saml",
+	})
+	assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+	var provider api.Provider
+	assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &provider))
+	assert.Equal(t, provider.Kind, "saml")
+
+	stored, err := data.GetProvider(srv.db, data.ByID(provider.ID))
+	assert.NilError(t, err)
+	assert.Equal(t, stored.SAMLGroupsAttribute, "groups")
+
+	samlPath := "/api/providers/" + provider.ID.String() + "/saml/"
+	acsURL := "http://example.com" + samlPath + "acs"
+
+	t.Run("service provider metadata", func(t *testing.T) {
+		resp := call(t, http.MethodGet, samlPath+"metadata", "", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), `entityID="http://example.com`+samlPath+`metadata"`), resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), `Location="`+acsURL+`"`), resp.Body.String())
+	})
+
+	// startLogin sends the user to the identity provider, and returns the
+	// form the browser posts back to the server
+	startLogin := func(t *testing.T, redirectURL string) url.Values {
+		t.Helper()
+		query := url.Values{"redirectURL": {redirectURL}, "state": {"the-state"}}
+		resp := call(t, http.MethodGet, samlPath+"login?"+query.Encode(), "", nil)
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+
+		location := resp.Header().Get("Location")
+		assert.Assert(t, strings.HasPrefix(location, strings.TrimSuffix(idp.MetadataURL, "/metadata")+"/sso?"), location)
+
+		formURL, form := idp.Respond(t, location)
+		assert.Equal(t, formURL, acsURL)
+		return form
+	}
+
+	postForm := func(t *testing.T, form url.Values) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, acsURL, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return serve(t, req, "")
+	}
+
+	login := func(t *testing.T, code string) (*httptest.ResponseRecorder, api.LoginResponse) {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/login", "", api.LoginRequest{
+			SAML: &api.LoginRequestSAML{ProviderID: provider.ID, Code: code},
+		})
+
+		var loginResp api.LoginResponse
+		if resp.Code == http.StatusCreated {
+			assert.NilError(t, json.Unmarshal(resp.Body.Bytes(), &loginResp))
+		}
+		return resp, loginResp
+	}
+
+	t.Run("login", func(t *testing.T) {
+		form := startLogin(t, "http://localhost:8301")
+
+		resp := postForm(t, form)
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+
+		callback, err := url.Parse(resp.Header().Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, callback.Host, "localhost:8301")
+		assert.Equal(t, callback.Query().Get("state"), "the-state")
+		code := callback.Query().Get("code")
+		assert.Assert(t, code != "")
+
+		resp, loginResp := login(t, code)
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.Equal(t, loginResp.Name, "alice@example.com")
+
+		groups, err := data.ListGroups(srv.db, &models.Pagination{}, data.ByGroupMember(loginResp.UserID))
+		assert.NilError(t, err)
+		assert.Equal(t, len(groups), 1)
+		assert.Equal(t, groups[0].Name, "Developers")
+
+		resp = call(t, http.MethodGet, "/api/users/"+loginResp.UserID.String(), loginResp.AccessKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		t.Run("code can only be used once", func(t *testing.T) {
+			resp, _ := login(t, code)
+			assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+		})
+
+		t.Run("response can only be used once", func(t *testing.T) {
+			resp := postForm(t, form)
+			assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		})
+	})
+
+	t.Run("login from the UI", func(t *testing.T) {
+		form := startLogin(t, "http://example.com/login/callback")
+
+		resp := postForm(t, form)
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+		assert.Assert(t, strings.HasPrefix(resp.Header().Get("Location"), "http://example.com/login/callback?"))
+	})
+
+	t.Run("redirect url must be a client", func(t *testing.T) {
+		query := url.Values{"redirectURL": {"https://attacker.example.com/"}, "state": {"the-state"}}
+		resp := call(t, http.MethodGet, samlPath+"login?"+query.Encode(), "", nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("assertion was modified", func(t *testing.T) {
+		form := startLogin(t, "http://localhost:8301")
+
+		raw, err := base64.StdEncoding.DecodeString(form.Get("SAMLResponse"))
+		assert.NilError(t, err)
+		modified := strings.ReplaceAll(string(raw), "alice@example.com", "admin@example.com")
+		form.Set("SAMLResponse", base64.StdEncoding.EncodeToString([]byte(modified)))
+
+		resp := postForm(t, form)
+		assert.Equal(t, resp.Code, http.StatusFound, resp.Body.String())
+
+		callback, err := url.Parse(resp.Header().Get("Location"))
+		assert.NilError(t, err)
+		assert.Equal(t, callback.Query().Get("error"), "access_denied")
+		assert.Equal(t, callback.Query().Get("code"), "")
+	})
+
+	t.Run("unknown relay state", func(t *testing.T) {
+		form := startLogin(t, "http://localhost:8301")
+		form.Set("RelayState", "unknown")
+
+		resp := postForm(t, form)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("login with an oidc provider", func(t *testing.T) {
+		oidcProvider := &models.Provider{Name: "okta", Kind: models.ProviderKindOkta}
+		assert.NilError(t, data.CreateProvider(srv.db, oidcProvider))
+
+		resp := call(t, http.MethodGet, "/api/providers/"+oidcProvider.ID.String()+"/saml/login?redirectURL=http://localhost:8301", "", nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+		body, err := io.ReadAll(resp.Body)
+		assert.NilError(t, err)
+		assert.Assert(t, strings.Contains(string(body), "not a saml provider"), string(body))
+	})
+
+	t.Run("base url behind a proxy which terminates tls", func(t *testing.T) {
+		srv.options.BaseURL = "https://infra.example.com/"
+		t.Cleanup(func() {
+			srv.options.BaseURL = ""
+		})
+
+		// the request from the proxy is plain http
+		resp := call(t, http.MethodGet, samlPath+"metadata", "", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), `entityID="https://infra.example.com`+samlPath+`metadata"`), resp.Body.String())
+		assert.Assert(t, strings.Contains(resp.Body.String(), `Location="https://infra.example.com`+samlPath+`acs"`), resp.Body.String())
+	})
+}
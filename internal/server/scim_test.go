@@ -0,0 +1,296 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_SCIM(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	provider := &models.Provider{Name: "okta", Kind: models.ProviderKindOkta}
+	err := data.CreateProvider(srv.db, provider)
+	assert.NilError(t, err)
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(method, path, jsonBody(t, body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+key)
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	decode := func(t *testing.T, resp *httptest.ResponseRecorder, into any) {
+		t.Helper()
+		err := json.Unmarshal(resp.Body.Bytes(), into)
+		assert.NilError(t, err, resp.Body.String())
+	}
+
+	resp := call(t, http.MethodPost, "/api/providers/"+provider.ID.String()+"/scim-keys", adminAccessKey(srv), api.CreateProviderSCIMKeyRequest{
+		TTL: api.Duration(time.Hour),
+	})
+	assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+	var created api.CreateAccessKeyResponse
+	decode(t, resp, &created)
+	scimKey := created.AccessKey
+
+	createUser := func(t *testing.T, name string) scimUser {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/scim/v2/Users", scimKey, scimUser{
+			Schemas:  []string{scimUserSchema},
+			UserName: name,
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		assert.Equal(t, resp.Header().Get("Content-Type"), scimContentType)
+
+		var user scimUser
+		decode(t, resp, &user)
+		return user
+	}
+
+	t.Run("infra provider keys can not be created", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/providers/"+data.InfraProvider(srv.db).ID.String()+"/scim-keys", adminAccessKey(srv), api.CreateProviderSCIMKeyRequest{
+			TTL: api.Duration(time.Hour),
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("requires scim key", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/scim/v2/Users", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		var scimErr scimError
+		decode(t, resp, &scimErr)
+		assert.Equal(t, scimErr.Status, "403")
+	})
+
+	t.Run("scim key can not be used for the api", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/users", scimKey, nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		resp = call(t, http.MethodPost, "/api/login", "", api.LoginRequest{AccessKey: scimKey})
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+	})
+
+	t.Run("provision users", func(t *testing.T) {
+		user := createUser(t, "alice@example.com")
+		assert.Equal(t, user.UserName, "alice@example.com")
+		assert.Equal(t, *user.Active, true)
+
+		identity, err := data.GetIdentity(srv.db, data.ByName("alice@example.com"))
+		assert.NilError(t, err)
+		assert.Equal(t, user.ID, identity.ID.String())
+
+		_, err = data.GetProviderUser(srv.db, provider.ID, identity.ID)
+		assert.NilError(t, err)
+
+		resp := call(t, http.MethodPost, "/scim/v2/Users", scimKey, scimUser{UserName: "alice@example.com"})
+		assert.Equal(t, resp.Code, http.StatusConflict, resp.Body.String())
+
+		resp = call(t, http.MethodGet, `/scim/v2/Users?filter=userName+eq+"alice@example.com"`, scimKey, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list scimListResponse
+		decode(t, resp, &list)
+		assert.Equal(t, list.TotalResults, 1)
+
+		resp = call(t, http.MethodGet, `/scim/v2/Users?filter=emails+co+"example"`, scimKey, nil)
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("users from other providers are not visible", func(t *testing.T) {
+		other := &models.Identity{Name: "other@example.com"}
+		createIdentities(t, srv.db, other)
+
+		resp := call(t, http.MethodGet, "/scim/v2/Users/"+other.ID.String(), scimKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+
+	t.Run("group membership", func(t *testing.T) {
+		bob := createUser(t, "bob@example.com")
+		carol := createUser(t, "carol@example.com")
+
+		resp := call(t, http.MethodPost, "/scim/v2/Groups", scimKey, scimGroup{
+			Schemas:     []string{scimGroupSchema},
+			DisplayName: "engineering",
+			Members:     []scimMember{{Value: bob.ID}},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var group scimGroup
+		decode(t, resp, &group)
+		assert.DeepEqual(t, group.Members, []scimMember{{Value: bob.ID, Display: "bob@example.com"}})
+
+		bobID, err := uid.Parse([]byte(bob.ID))
+		assert.NilError(t, err)
+		pu, err := data.GetProviderUser(srv.db, provider.ID, bobID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string(pu.Groups), []string{"engineering"})
+
+		resp = call(t, http.MethodPatch, "/scim/v2/Groups/"+group.ID, scimKey, scimPatchRequest{
+			Operations: []scimPatchOperation{
+				{Op: "add", Path: "members", Value: json.RawMessage(`[{"value":"` + carol.ID + `"}]`)},
+				{Op: "remove", Path: `members[value eq "` + bob.ID + `"]`},
+			},
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		decode(t, resp, &group)
+		assert.DeepEqual(t, group.Members, []scimMember{{Value: carol.ID, Display: "carol@example.com"}})
+
+		pu, err = data.GetProviderUser(srv.db, provider.ID, bobID)
+		assert.NilError(t, err)
+		assert.Equal(t, len(pu.Groups), 0)
+
+		groups, err := data.ListGroups(srv.db, &models.Pagination{}, data.ByGroupMember(bobID))
+		assert.NilError(t, err)
+		assert.Equal(t, len(groups), 0)
+
+		other := &models.Identity{Name: "outsider@example.com"}
+		createIdentities(t, srv.db, other)
+
+		resp = call(t, http.MethodPatch, "/scim/v2/Groups/"+group.ID, scimKey, scimPatchRequest{
+			Operations: []scimPatchOperation{
+				{Op: "add", Path: "members", Value: json.RawMessage(`[{"value":"` + other.ID.String() + `"}]`)},
+			},
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+
+		resp = call(t, http.MethodPatch, "/scim/v2/Groups/"+group.ID, scimKey, scimPatchRequest{
+			Operations: []scimPatchOperation{
+				{Op: "replace", Value: json.RawMessage(`{"displayName":"platform"}`)},
+			},
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		decode(t, resp, &group)
+		assert.Equal(t, group.DisplayName, "platform")
+
+		carolID, err := uid.Parse([]byte(carol.ID))
+		assert.NilError(t, err)
+		pu, err = data.GetProviderUser(srv.db, provider.ID, carolID)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, []string(pu.Groups), []string{"platform"})
+
+		resp = call(t, http.MethodDelete, "/scim/v2/Groups/"+group.ID, scimKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		_, err = data.GetGroup(srv.db, data.ByName("platform"))
+		assert.ErrorContains(t, err, "record not found")
+	})
+
+	t.Run("deprovision", func(t *testing.T) {
+		dave := createUser(t, "dave@example.com")
+		daveID, err := uid.Parse([]byte(dave.ID))
+		assert.NilError(t, err)
+
+		resp := call(t, http.MethodPost, "/scim/v2/Groups", scimKey, scimGroup{
+			DisplayName: "oncall",
+			Members:     []scimMember{{Value: dave.ID}},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		err = data.CreateGrant(srv.db, &models.Grant{Subject: uid.NewIdentityPolymorphicID(daveID), Privilege: "view", Resource: "production"})
+		assert.NilError(t, err)
+
+		_, err = data.CreateAccessKey(srv.db, &models.AccessKey{IssuedFor: daveID, ProviderID: provider.ID, ExpiresAt: time.Now().Add(time.Hour)})
+		assert.NilError(t, err)
+
+		resp = call(t, http.MethodPatch, "/scim/v2/Users/"+dave.ID, scimKey, scimPatchRequest{
+			Operations: []scimPatchOperation{
+				{Op: "replace", Value: json.RawMessage(`{"active":false}`)},
+			},
+		})
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var user scimUser
+		decode(t, resp, &user)
+		assert.Equal(t, *user.Active, false)
+
+		_, err = data.GetIdentity(srv.db, data.ByID(daveID))
+		assert.ErrorContains(t, err, "record not found")
+
+		keys, err := data.ListAccessKeys(srv.db, &models.Pagination{}, data.ByIssuedFor(daveID))
+		assert.NilError(t, err)
+		assert.Equal(t, len(keys), 0)
+
+		grants, err := data.ListGrants(srv.db, &models.Pagination{}, data.BySubject(uid.NewIdentityPolymorphicID(daveID)))
+		assert.NilError(t, err)
+		assert.Equal(t, len(grants), 0)
+
+		oncall, err := data.GetGroup(srv.db, data.ByName("oncall"))
+		assert.NilError(t, err)
+		members, err := data.ListIdentities(srv.db, &models.Pagination{}, data.ByOptionalIdentityGroupID(oncall.ID))
+		assert.NilError(t, err)
+		assert.Equal(t, len(members), 0)
+
+		resp = call(t, http.MethodGet, "/scim/v2/Users/"+dave.ID, scimKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+
+	t.Run("deprovision keeps users of other providers", func(t *testing.T) {
+		erin := &models.Identity{Name: "erin@example.com"}
+		createIdentities(t, srv.db, erin)
+		_, err := data.CreateProviderUser(srv.db, data.InfraProvider(srv.db), erin)
+		assert.NilError(t, err)
+
+		user := createUser(t, "erin@example.com")
+		assert.Equal(t, user.ID, erin.ID.String())
+
+		resp := call(t, http.MethodDelete, "/scim/v2/Users/"+user.ID, scimKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		_, err = data.GetIdentity(srv.db, data.ByID(erin.ID))
+		assert.NilError(t, err)
+
+		_, err = data.GetProviderUser(srv.db, provider.ID, erin.ID)
+		assert.ErrorContains(t, err, "record not found")
+	})
+	t.Run("changes are recorded in the audit log", func(t *testing.T) {
+		user := createUser(t, "frank@example.com")
+		resp := call(t, http.MethodDelete, "/scim/v2/Users/"+user.ID, scimKey, nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		id, err := uid.Parse([]byte(user.ID))
+		assert.NilError(t, err)
+
+		all, err := data.ListAuditEvents(srv.db, &models.Pagination{}, data.ByOptionalResourceType("users"))
+		assert.NilError(t, err)
+		var events []models.AuditEvent
+		for _, event := range all {
+			if event.ResourceID == id {
+				events = append(events, event)
+			}
+		}
+		assert.Equal(t, len(events), 2)
+
+		assert.Equal(t, events[0].Method, http.MethodPost)
+		assert.Equal(t, events[0].Route, "/scim/v2/Users")
+		assert.Equal(t, events[0].ResourceType, "users")
+		assert.Equal(t, events[0].StatusCode, http.StatusCreated)
+		assert.Assert(t, strings.Contains(events[0].After, "frank@example.com"), events[0].After)
+
+		assert.Equal(t, events[1].Method, http.MethodDelete)
+		assert.Equal(t, events[1].Route, "/scim/v2/Users/:id")
+		assert.Equal(t, events[1].StatusCode, http.StatusNoContent)
+		assert.Equal(t, events[1].Before, events[0].After)
+	})
+}
@@ -0,0 +1,294 @@
+package server
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+// routeScope returns the name of the API scope of a route, which is the first
+// segment of its path, such as "grants" for /api/grants/:id. Routes outside
+// of /api have no scope.
+func routeScope(path string) string {
+	if !strings.HasPrefix(path, "/api/") {
+		return ""
+	}
+
+	scope, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/"), "/")
+	return scope
+}
+
+// accessKeyScopes are the restrictions set by the scopes of an access key.
+// The scopes used by the server for special purpose keys, such as
+// password-reset, are checked by RequireAccessKey instead.
+type accessKeyScopes struct {
+	// operations maps an API scope to read or write
+	operations map[string]string
+	resources  []string
+	networks   []*net.IPNet
+}
+
+func parseAccessKeyScopes(scopes []string) (accessKeyScopes, error) {
+	result := accessKeyScopes{operations: map[string]string{}}
+	for _, scope := range scopes {
+		switch {
+		case strings.HasPrefix(scope, models.ScopeResourcePrefix):
+			resource := strings.TrimPrefix(scope, models.ScopeResourcePrefix)
+			if resource == "" {
+				return result, fmt.Errorf("scope %q is missing a resource", scope)
+			}
+			result.resources = append(result.resources, resource)
+
+		case strings.HasPrefix(scope, models.ScopeCIDRPrefix):
+			_, network, err := net.ParseCIDR(strings.TrimPrefix(scope, models.ScopeCIDRPrefix))
+			if err != nil {
+				return result, fmt.Errorf("scope %q: %w", scope, err)
+			}
+			result.networks = append(result.networks, network)
+
+		case strings.Contains(scope, ":"):
+			name, operation, _ := strings.Cut(scope, ":")
+			if operation != models.ScopeRead && operation != models.ScopeWrite {
+				return result, fmt.Errorf("scope %q must end with :%s or :%s", scope, models.ScopeRead, models.ScopeWrite)
+			}
+			if result.operations[name] != models.ScopeWrite {
+				result.operations[name] = operation
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// validateAccessKeyScopes checks the scopes of a new access key. API scopes
+// must be the scope of at least one route.
+func (a *API) validateAccessKeyScopes(scopes []string) error {
+	for _, scope := range scopes {
+		if !strings.Contains(scope, ":") {
+			return fmt.Errorf("%w: unknown scope %q", internal.ErrBadRequest, scope)
+		}
+	}
+
+	parsed, err := parseAccessKeyScopes(scopes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", internal.ErrBadRequest, err)
+	}
+
+	for name := range parsed.operations {
+		if !a.routeScopes[name] {
+			known := make([]string, 0, len(a.routeScopes))
+			for scope := range a.routeScopes {
+				known = append(known, scope)
+			}
+			sort.Strings(known)
+			return fmt.Errorf("%w: unknown scope %q, expected one of: %s", internal.ErrBadRequest, name, strings.Join(known, ", "))
+		}
+	}
+
+	return nil
+}
+
+// requireScopesWithinKey checks the scopes of a new access key are within the
+// scopes of the access key of the request, so that a scoped key can not be used
+// to create a key with more access than it has. Only a key without scopes can
+// create a key without scopes.
+func requireScopesWithinKey(c *gin.Context, scopes []string) error {
+	current, err := requestAccessKeyScopes(c)
+	if err != nil {
+		return err
+	}
+
+	requested, err := parseAccessKeyScopes(scopes)
+	if err != nil {
+		return fmt.Errorf("%w: %v", internal.ErrBadRequest, err)
+	}
+
+	if len(current.operations) > 0 {
+		if len(requested.operations) == 0 {
+			return access.AuthorizationError{Resource: "access keys without API scopes with this access key", Operation: "create"}
+		}
+		for name, operation := range requested.operations {
+			switch current.operations[name] {
+			case models.ScopeWrite:
+			case operation:
+			default:
+				return access.AuthorizationError{Resource: "access keys with scope " + name + ":" + operation + " with this access key", Operation: "create"}
+			}
+		}
+	}
+
+	if len(current.resources) > 0 {
+		if len(requested.resources) == 0 {
+			return access.AuthorizationError{Resource: "access keys without resource scopes with this access key", Operation: "create"}
+		}
+		for _, resource := range requested.resources {
+			if !resourceInScopes(resource, current.resources) {
+				return access.AuthorizationError{Resource: "access keys for " + resource + " with this access key", Operation: "create"}
+			}
+		}
+	}
+
+	if len(current.networks) > 0 {
+		if len(requested.networks) == 0 {
+			return access.AuthorizationError{Resource: "access keys without network scopes with this access key", Operation: "create"}
+		}
+		for _, network := range requested.networks {
+			if !networkInScopes(network, current.networks) {
+				return access.AuthorizationError{Resource: "access keys for " + network.String() + " with this access key", Operation: "create"}
+			}
+		}
+	}
+
+	return nil
+}
+
+// networkInScopes returns true if the network is within one of the scoped
+// networks.
+func networkInScopes(network *net.IPNet, scoped []*net.IPNet) bool {
+	ones, _ := network.Mask.Size()
+	for _, s := range scoped {
+		scopedOnes, _ := s.Mask.Size()
+		if s.Contains(network.IP) && scopedOnes <= ones {
+			return true
+		}
+	}
+	return false
+}
+
+// requestAccessKeyScopes returns the scopes of the access key of the request.
+// Requests authenticated with a client certificate have no scopes.
+func requestAccessKeyScopes(c *gin.Context) (accessKeyScopes, error) {
+	val, _ := c.Get("key")
+	key, ok := val.(*models.AccessKey)
+	if !ok {
+		return accessKeyScopes{}, nil
+	}
+
+	return parseAccessKeyScopes(key.Scopes)
+}
+
+// requireNetworkScope checks the client IP is in one of the networks of the
+// access key, if the key is scoped to networks. The client IP is the address of
+// the peer, because the X-Forwarded-For header is set by the client.
+func requireNetworkScope(c *gin.Context) error {
+	scopes, err := requestAccessKeyScopes(c)
+	if err != nil {
+		return err
+	}
+
+	if len(scopes.networks) == 0 {
+		return nil
+	}
+
+	ip := net.ParseIP(c.RemoteIP())
+	for _, network := range scopes.networks {
+		if ip != nil && network.Contains(ip) {
+			return nil
+		}
+	}
+
+	return access.AuthorizationError{Resource: "this access key from " + c.RemoteIP(), Operation: "use"}
+}
+
+// requireOperationScope checks the access key of the request is scoped for the
+// operation, if the key is scoped to API operations. GET requests read, all
+// other methods write. Routes without an API scope can not be used by these
+// keys.
+func requireOperationScope(c *gin.Context, scope, method string) error {
+	scopes, err := requestAccessKeyScopes(c)
+	if err != nil {
+		return err
+	}
+
+	if len(scopes.operations) == 0 {
+		return nil
+	}
+
+	operation := models.ScopeWrite
+	if method == http.MethodGet {
+		operation = models.ScopeRead
+	}
+
+	switch scopes.operations[scope] {
+	case models.ScopeWrite:
+		return nil
+	case operation:
+		return nil
+	}
+
+	return access.AuthorizationError{Resource: scope + " with this access key", Operation: operation}
+}
+
+// requireResourceScope checks the resources of the request are within the
+// resources of the access key, if the key is scoped to resources. Requests
+// which are not for specific resources can not be made with these keys.
+func requireResourceScope(c *gin.Context, req any) error {
+	scopes, err := requestAccessKeyScopes(c)
+	if err != nil {
+		return err
+	}
+
+	if len(scopes.resources) == 0 {
+		return nil
+	}
+
+	r, ok := req.(api.ResourceRequest)
+	if !ok || len(r.ScopeResources()) == 0 {
+		return access.AuthorizationError{Resource: "requests without a resource with this access key", Operation: "make"}
+	}
+
+	for _, resource := range r.ScopeResources() {
+		if !resourceInScopes(resource, scopes.resources) {
+			return access.AuthorizationError{Resource: resource + " with this access key", Operation: "access"}
+		}
+	}
+
+	return nil
+}
+
+// requireResourceScopedRoute checks the route of the request checks the
+// resources of the request, if the access key is scoped to resources. Routes
+// added with add check resources with requireResourceScope, after the request
+// is bound.
+func requireResourceScopedRoute(c *gin.Context) error {
+	scopes, err := requestAccessKeyScopes(c)
+	if err != nil {
+		return err
+	}
+
+	if len(scopes.resources) == 0 {
+		return nil
+	}
+
+	val, _ := c.Get("resourceRoutes")
+	routes, _ := val.(map[string]bool)
+	if !routes[resourceRouteKey(c.Request.Method, c.FullPath())] {
+		return access.AuthorizationError{Resource: "requests without a resource with this access key", Operation: "make"}
+	}
+
+	return nil
+}
+
+func resourceRouteKey(method, path string) string {
+	return method + " " + path
+}
+
+// resourceInScopes returns true if the resource is one of the scoped
+// resources, or one of their namespaces.
+func resourceInScopes(resource string, scoped []string) bool {
+	for _, s := range scoped {
+		if resource == s || strings.HasPrefix(resource, s+".") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestRouteScope(t *testing.T) {
+	assert.Equal(t, routeScope("/api/grants"), "grants")
+	assert.Equal(t, routeScope("/api/grants/:id"), "grants")
+	assert.Equal(t, routeScope("/api/users/:id/mfa/verify"), "users")
+	assert.Equal(t, routeScope("/v1/users"), "")
+	assert.Equal(t, routeScope("/scim/v2/Users"), "")
+}
+
+func TestAPI_AccessKeyScopes(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	admin, err := data.GetIdentity(srv.db, data.ByName("admin@example.com"))
+	assert.NilError(t, err)
+
+	createKey := func(t *testing.T, scopes ...string) string {
+		t.Helper()
+		key := &models.AccessKey{
+			IssuedFor:  admin.ID,
+			ProviderID: data.InfraProvider(srv.db).ID,
+			ExpiresAt:  time.Now().Add(time.Hour).UTC(),
+			Scopes:     scopes,
+		}
+		secret, err := data.CreateAccessKey(srv.db, key)
+		assert.NilError(t, err)
+		return secret
+	}
+
+	call := func(t *testing.T, method, path, key, remoteAddr string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		req.Header.Add("Authorization", "Bearer "+key)
+		req.Header.Add("Infra-Version", "0.13.6")
+		if remoteAddr != "" {
+			req.RemoteAddr = remoteAddr
+		}
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	grant := func(resource string) api.CreateGrantRequest {
+		return api.CreateGrantRequest{User: admin.ID, Privilege: "view", Resource: resource}
+	}
+
+	t.Run("operations", func(t *testing.T) {
+		key := createKey(t, "grants:read", "destinations:write")
+
+		resp := call(t, http.MethodGet, "/api/grants", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = call(t, http.MethodPost, "/api/grants", key, "", grant("production"))
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/users", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		// write includes read
+		resp = call(t, http.MethodGet, "/api/destinations", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/debug/pprof/heap", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("resources", func(t *testing.T) {
+		key := createKey(t, "resource:production")
+
+		resp := call(t, http.MethodGet, "/api/grants?resource=production", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = call(t, http.MethodPost, "/api/grants", key, "", grant("production.default"))
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		resp = call(t, http.MethodPost, "/api/grants", key, "", grant("production-2"))
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/grants?destination=staging", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		// requests which are not for a resource
+		resp = call(t, http.MethodGet, "/api/grants", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/users", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("routes which are not part of the API", func(t *testing.T) {
+		for _, scope := range []string{"users:read", "resource:production"} {
+			key := createKey(t, scope)
+
+			resp := call(t, http.MethodGet, "/api/debug/pprof/heap", key, "", nil)
+			assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+			resp = call(t, http.MethodGet, "/api/destinations/"+admin.ID.String()+"/authorize", key, "", nil)
+			assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+		}
+	})
+
+	t.Run("networks", func(t *testing.T) {
+		key := createKey(t, "cidr:10.0.0.0/8", "cidr:192.0.2.0/24")
+
+		resp := call(t, http.MethodGet, "/api/users", key, "192.0.2.10:4321", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/users", key, "198.51.100.10:4321", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("networks ignore the forwarded for header", func(t *testing.T) {
+		key := createKey(t, "cidr:192.0.2.0/24")
+
+		req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+		req.Header.Add("Authorization", "Bearer "+key)
+		req.Header.Add("Infra-Version", "0.13.6")
+		req.Header.Add("X-Forwarded-For", "192.0.2.10")
+		req.RemoteAddr = "198.51.100.10:4321"
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("create keys within the scopes of the key", func(t *testing.T) {
+		newKey := func(scopes ...string) api.CreateAccessKeyRequest {
+			return api.CreateAccessKeyRequest{
+				UserID:            admin.ID,
+				TTL:               api.Duration(time.Hour),
+				ExtensionDeadline: api.Duration(time.Hour),
+				Scopes:            scopes,
+			}
+		}
+		key := createKey(t, "access-keys:write", "grants:read", "cidr:10.0.0.0/8")
+		remoteAddr := "10.1.2.3:4321"
+
+		type testCase struct {
+			name     string
+			scopes   []string
+			expected int
+		}
+		testCases := []testCase{
+			{
+				name:     "same scopes",
+				scopes:   []string{"access-keys:write", "grants:read", "cidr:10.0.0.0/8"},
+				expected: http.StatusCreated,
+			},
+			{
+				name:     "narrower scopes",
+				scopes:   []string{"grants:read", "resource:production", "cidr:10.1.0.0/16"},
+				expected: http.StatusCreated,
+			},
+			{
+				name:     "no scopes",
+				expected: http.StatusForbidden,
+			},
+			{
+				name:     "write instead of read",
+				scopes:   []string{"grants:write", "cidr:10.0.0.0/8"},
+				expected: http.StatusForbidden,
+			},
+			{
+				name:     "another API scope",
+				scopes:   []string{"users:read", "cidr:10.0.0.0/8"},
+				expected: http.StatusForbidden,
+			},
+			{
+				name:     "without a network",
+				scopes:   []string{"grants:read"},
+				expected: http.StatusForbidden,
+			},
+			{
+				name:     "wider network",
+				scopes:   []string{"grants:read", "cidr:0.0.0.0/0"},
+				expected: http.StatusForbidden,
+			},
+		}
+
+		for _, tc := range testCases {
+			t.Run(tc.name, func(t *testing.T) {
+				resp := call(t, http.MethodPost, "/api/access-keys", key, remoteAddr, newKey(tc.scopes...))
+				assert.Equal(t, resp.Code, tc.expected, resp.Body.String())
+			})
+		}
+
+		t.Run("key without scopes", func(t *testing.T) {
+			resp := call(t, http.MethodPost, "/api/access-keys", adminAccessKey(srv), "", newKey())
+			assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+		})
+	})
+
+	t.Run("exchanged keys keep the scopes", func(t *testing.T) {
+		key := createKey(t, "grants:read")
+
+		resp := call(t, http.MethodPost, "/api/login", "", "", api.LoginRequest{AccessKey: key})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var loginResp api.LoginResponse
+		err := json.Unmarshal(resp.Body.Bytes(), &loginResp)
+		assert.NilError(t, err)
+
+		resp = call(t, http.MethodGet, "/api/grants", loginResp.AccessKey, "", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/users", loginResp.AccessKey, "", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("destination logins can not keep the scopes", func(t *testing.T) {
+		key := createKey(t, "cidr:192.0.2.0/24")
+
+		resp := call(t, http.MethodGet, "/api/destinations/"+admin.ID.String()+"/authorize?redirect_uri=https://app.example.com/", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("keys without scopes", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/users", adminAccessKey(srv), "", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+	})
+}
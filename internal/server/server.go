@@ -21,13 +21,17 @@ import (
 	"gorm.io/gorm"
 
 	"github.com/infrahq/infra/internal"
+	"github.com/infrahq/infra/internal/access"
 	"github.com/infrahq/infra/internal/cmd/types"
 	"github.com/infrahq/infra/internal/ginutil"
 	"github.com/infrahq/infra/internal/logging"
 	"github.com/infrahq/infra/internal/repeat"
+	"github.com/infrahq/infra/internal/server/authn"
 	"github.com/infrahq/infra/internal/server/data"
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/internal/server/providers"
 	"github.com/infrahq/infra/metrics"
+	"github.com/infrahq/infra/pki"
 )
 
 type Options struct {
@@ -37,6 +41,11 @@ type Options struct {
 	EnableSignup             bool
 	SessionDuration          time.Duration
 	SessionExtensionDeadline time.Duration
+	// BaseURL is the URL which clients use to reach the server, such as
+	// https://infra.example.com. It is used to build the URLs given to identity
+	// providers and clients. When it is not set the URL of the request is used,
+	// which has the wrong scheme behind a proxy which terminates TLS.
+	BaseURL string `validate:"omitempty,url"`
 
 	DBFile                  string
 	DBEncryptionKey         string
@@ -53,9 +62,64 @@ type Options struct {
 
 	Config
 
-	Addr ListenerOptions
-	UI   UIOptions
-	TLS  TLSOptions
+	Addr             ListenerOptions
+	UI               UIOptions
+	TLS              TLSOptions
+	LoginLockout     LoginLockoutOptions
+	WorkloadIdentity WorkloadIdentityOptions
+	OIDC             OIDCOptions
+}
+
+// LoginLockoutOptions limit the failed logins of a username, and of a client
+// IP. Once there have been too many consecutive failures, logins are locked
+// for Duration, which doubles with every further failure up to MaxDuration.
+type LoginLockoutOptions struct {
+	// UserMaxFailures is the number of failed logins for a username before it
+	// is locked. Zero disables the limit.
+	UserMaxFailures int
+	// IPMaxFailures is the number of failed logins from a client IP before it
+	// is locked. Zero disables the limit.
+	IPMaxFailures int
+	Duration      time.Duration
+	MaxDuration   time.Duration
+}
+
+// WorkloadIdentityOptions configure the trusted issuers of OIDC tokens which
+// workloads, such as CI jobs, exchange for access keys.
+type WorkloadIdentityOptions struct {
+	// AccessKeyDuration is the lifetime of the access keys issued to
+	// workloads.
+	AccessKeyDuration time.Duration
+	Issuers           []WorkloadIssuerOptions `validate:"dive"`
+}
+
+type WorkloadIssuerOptions struct {
+	// Issuer must match the iss claim of the tokens.
+	Issuer string `validate:"required"`
+	// JWKSURL is the URL of the keys which sign the tokens. When it is not set
+	// the keys are found by OIDC discovery from the issuer.
+	JWKSURL string
+	// Audience must be one of the aud claims of the tokens.
+	Audience string                        `validate:"required"`
+	Rules    []WorkloadIdentityRuleOptions `validate:"dive"`
+}
+
+// WorkloadIdentityRuleOptions map the claims of a token to the name of an
+// identity. Every claim must match, the values are patterns like 'org/*'.
+type WorkloadIdentityRuleOptions struct {
+	Claims   map[string]string `validate:"min=1"`
+	Identity string            `validate:"required"`
+}
+
+// OIDCOptions configure Infra as an OpenID Connect identity provider for the
+// registered OIDC clients.
+type OIDCOptions struct {
+	// Issuer is the URL of the server in the tokens it issues, which clients
+	// such as kube-apiserver must be configured with. When it is not set the
+	// URL of the request is used.
+	Issuer string
+	// TokenDuration is the lifetime of the ID and access tokens.
+	TokenDuration time.Duration
 }
 
 type ListenerOptions struct {
@@ -87,13 +151,16 @@ type TLSOptions struct {
 }
 
 type Server struct {
-	options  Options
-	db       *gorm.DB
-	tel      *Telemetry
-	secrets  map[string]secrets.SecretStorage
-	keys     map[string]secrets.SymmetricKeyProvider
-	Addrs    Addrs
-	routines []routine
+	options         Options
+	workloadIssuers []authn.WorkloadIdentityIssuer
+	certificates    pki.CertificateProvider
+	db              *gorm.DB
+	tel             *Telemetry
+	secrets         map[string]secrets.SecretStorage
+	keys            map[string]secrets.SymmetricKeyProvider
+	Addrs           Addrs
+	routines        []routine
+	authzCache      *access.AuthzCache
 }
 
 type Addrs struct {
@@ -106,12 +173,33 @@ type Addrs struct {
 func newServer(options Options) *Server {
 	options.UI.FS = uiFS
 	return &Server{
-		options: options,
-		secrets: map[string]secrets.SecretStorage{},
-		keys:    map[string]secrets.SymmetricKeyProvider{},
+		options:         options,
+		workloadIssuers: workloadIssuers(options.WorkloadIdentity),
+		secrets:         map[string]secrets.SecretStorage{},
+		keys:            map[string]secrets.SymmetricKeyProvider{},
+		authzCache:      access.NewAuthzCache(access.AuthzCacheTTL),
 	}
 }
 
+// workloadIssuers creates the issuers once, so that their keys are cached
+// between logins.
+func workloadIssuers(opts WorkloadIdentityOptions) []authn.WorkloadIdentityIssuer {
+	issuers := make([]authn.WorkloadIdentityIssuer, 0, len(opts.Issuers))
+	for _, i := range opts.Issuers {
+		issuer := authn.WorkloadIdentityIssuer{
+			WorkloadIssuer: providers.NewWorkloadIssuer(i.Issuer, i.JWKSURL, i.Audience),
+		}
+		for _, rule := range i.Rules {
+			issuer.Rules = append(issuer.Rules, authn.WorkloadIdentityRule{
+				Claims:   rule.Claims,
+				Identity: rule.Identity,
+			})
+		}
+		issuers = append(issuers, issuer)
+	}
+	return issuers
+}
+
 // New creates a Server, and initializes it. The returned Server is ready to run.
 func New(options Options) (*Server, error) {
 	server := newServer(options)
@@ -143,6 +231,10 @@ func New(options Options) (*Server, error) {
 		return nil, fmt.Errorf("settings: %w", err)
 	}
 
+	if err := server.loadCertificates(); err != nil {
+		return nil, fmt.Errorf("certificates: %w", err)
+	}
+
 	if options.EnableTelemetry {
 		server.tel = NewTelemetry(server.db, settings.ID)
 	}
@@ -164,6 +256,22 @@ func (s *Server) Run(ctx context.Context) error {
 		})
 	}
 
+	repeat.Start(ctx, 1*time.Minute, func(context.Context) {
+		if err := data.DeleteExpiredGrants(s.db); err != nil {
+			logging.Errorf("failed to remove expired grants: %v", err)
+		}
+	})
+
+	repeat.Start(ctx, 1*time.Minute, func(context.Context) {
+		if err := data.CloseExpiredAccessReviews(s.db); err != nil {
+			logging.Errorf("failed to close expired access reviews: %v", err)
+		}
+	})
+
+	repeat.Start(ctx, providerSyncInterval, s.syncProviderUsers)
+
+	repeat.Start(ctx, 1*time.Hour, s.rotateCertificateAuthority)
+
 	group, _ := errgroup.WithContext(ctx)
 	for i := range s.routines {
 		group.Go(s.routines[i].run)
@@ -249,7 +357,7 @@ func (s *Server) listen() error {
 
 	tlsServer := &http.Server{
 		Addr:      s.options.Addr.HTTPS,
-		TLSConfig: tlsConfig,
+		TLSConfig: withClientCertificates(tlsConfig, s.certificates),
 		Handler:   router,
 		ErrorLog:  httpErrorLog,
 	}
@@ -46,6 +46,9 @@ func setupServer(t *testing.T, ops ...func(*testing.T, *Options)) *Server {
 	err = s.loadConfig(s.options.Config)
 	assert.NilError(t, err)
 
+	err = s.loadCertificates()
+	assert.NilError(t, err)
+
 	data.InvalidateCache()
 	t.Cleanup(data.InvalidateCache)
 
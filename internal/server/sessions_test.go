@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/bcrypt"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+)
+
+func TestAPI_Sessions(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	createUser := func(t *testing.T, name string) *models.Identity {
+		t.Helper()
+		user := &models.Identity{Name: name}
+		err := data.CreateIdentity(srv.db, user)
+		assert.NilError(t, err)
+
+		_, err = data.CreateProviderUser(srv.db, data.InfraProvider(srv.db), user)
+		assert.NilError(t, err)
+
+		hash, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+		assert.NilError(t, err)
+
+		err = data.CreateCredential(srv.db, &models.Credential{IdentityID: user.ID, PasswordHash: hash})
+		assert.NilError(t, err)
+		return user
+	}
+
+	call := func(t *testing.T, method, path, key, userAgent string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, jsonBody(t, body))
+		if key != "" {
+			req.Header.Add("Authorization", "Bearer "+key)
+		}
+		req.Header.Add("Infra-Version", "0.13.6")
+		req.Header.Set("User-Agent", userAgent)
+		req.RemoteAddr = "192.0.2.10:4321"
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	login := func(t *testing.T, name, userAgent string) string {
+		t.Helper()
+		resp := call(t, http.MethodPost, "/api/login", "", userAgent, api.LoginRequest{
+			PasswordCredentials: &api.LoginRequestPasswordCredentials{Name: name, Password: "hunter2"},
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var loginResp api.LoginResponse
+		err := json.Unmarshal(resp.Body.Bytes(), &loginResp)
+		assert.NilError(t, err)
+		return loginResp.AccessKey
+	}
+
+	listSessions := func(t *testing.T, user *models.Identity, key, userAgent string) []api.Session {
+		t.Helper()
+		resp := call(t, http.MethodGet, "/api/users/"+user.ID.String()+"/sessions", key, userAgent, nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var sessions api.ListResponse[api.Session]
+		err := json.Unmarshal(resp.Body.Bytes(), &sessions)
+		assert.NilError(t, err)
+		return sessions.Items
+	}
+
+	t.Run("list sessions", func(t *testing.T) {
+		user := createUser(t, "list@example.com")
+		userAgent := "Infra/0.13.6 (infra 0.16.0; linux/amd64)"
+		key := login(t, user.Name, userAgent)
+		login(t, user.Name, "Mozilla/5.0")
+
+		// access keys which were not issued at login are not sessions
+		_, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+			IssuedFor:  user.ID,
+			ProviderID: data.InfraProvider(srv.db).ID,
+			ExpiresAt:  time.Now().Add(time.Hour).UTC(),
+		})
+		assert.NilError(t, err)
+
+		sessions := listSessions(t, user, key, userAgent)
+		assert.Equal(t, len(sessions), 2)
+		assert.Equal(t, sessions[0].LoginMethod, "credentials")
+		assert.Equal(t, sessions[0].ClientIP, "192.0.2.10")
+		assert.Equal(t, sessions[0].ClientVersion, "0.16.0")
+		assert.Equal(t, sessions[0].Current, true)
+
+		assert.Equal(t, sessions[1].UserAgent, "Mozilla/5.0")
+		assert.Equal(t, sessions[1].ClientVersion, "")
+		assert.Equal(t, sessions[1].Current, false)
+	})
+
+	t.Run("using a session records the client", func(t *testing.T) {
+		user := createUser(t, "use@example.com")
+		key := login(t, user.Name, "Mozilla/5.0")
+
+		resp := call(t, http.MethodGet, "/api/users/"+user.ID.String(), key, "Infra/0.13.6 (infra 0.17.0; linux/amd64)", nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		sessions, err := data.ListSessions(srv.db, user.ID)
+		assert.NilError(t, err)
+		assert.Equal(t, len(sessions), 1)
+		assert.Equal(t, sessions[0].Client.UserAgent, "Infra/0.13.6 (infra 0.17.0; linux/amd64)")
+		assert.Equal(t, sessions[0].Client.Version, "0.17.0")
+		assert.Assert(t, !sessions[0].LastUsedAt.IsZero())
+	})
+
+	t.Run("revoke a session", func(t *testing.T) {
+		user := createUser(t, "revoke@example.com")
+		key := login(t, user.Name, "")
+		other := login(t, user.Name, "")
+
+		sessions := listSessions(t, user, key, "")
+		assert.Equal(t, len(sessions), 2)
+		otherSession := sessions[0]
+		if otherSession.Current {
+			otherSession = sessions[1]
+		}
+
+		path := "/api/users/" + user.ID.String() + "/sessions/" + otherSession.ID.String()
+		resp := call(t, http.MethodDelete, path, key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		resp = call(t, http.MethodGet, "/api/users/"+user.ID.String(), other, "", nil)
+		assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+
+		resp = call(t, http.MethodDelete, path, key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusNotFound, resp.Body.String())
+	})
+
+	t.Run("log out everywhere", func(t *testing.T) {
+		user := createUser(t, "everywhere@example.com")
+		key := login(t, user.Name, "")
+		other := login(t, user.Name, "")
+
+		resp := call(t, http.MethodDelete, "/api/users/"+user.ID.String()+"/sessions", key, "", nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		for _, k := range []string{key, other} {
+			resp = call(t, http.MethodGet, "/api/users/"+user.ID.String(), k, "", nil)
+			assert.Equal(t, resp.Code, http.StatusUnauthorized, resp.Body.String())
+		}
+	})
+
+	t.Run("other users", func(t *testing.T) {
+		user := createUser(t, "owner@example.com")
+		login(t, user.Name, "")
+		other := createUser(t, "other@example.com")
+		otherKey := login(t, other.Name, "")
+
+		resp := call(t, http.MethodGet, "/api/users/"+user.ID.String()+"/sessions", otherKey, "", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = call(t, http.MethodDelete, "/api/users/"+user.ID.String()+"/sessions", otherKey, "", nil)
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		// admins can revoke the sessions of other users
+		resp = call(t, http.MethodDelete, "/api/users/"+user.ID.String()+"/sessions", adminAccessKey(srv), "", nil)
+		assert.Equal(t, resp.Code, http.StatusNoContent, resp.Body.String())
+
+		sessions := listSessions(t, user, adminAccessKey(srv), "")
+		assert.Equal(t, len(sessions), 0)
+	})
+}
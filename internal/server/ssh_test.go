@@ -0,0 +1,187 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/v3/assert"
+
+	"github.com/infrahq/infra/api"
+	"github.com/infrahq/infra/internal/claims"
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/pki"
+	"github.com/infrahq/infra/uid"
+)
+
+func TestAPI_CreateSSHCertificate(t *testing.T) {
+	srv := setupServer(t, withAdminUser)
+	routes := srv.GenerateRoutes(prometheus.NewRegistry())
+
+	_, err := data.InitializeSettings(srv.db)
+	assert.NilError(t, err)
+
+	alice := &models.Identity{Name: "alice@example.com"}
+	bob := &models.Identity{Name: "bob@example.com"}
+	carol := &models.Identity{Name: "carol@example.com"}
+	createIdentities(t, srv.db, alice, bob, carol)
+
+	oncall := &models.Group{Name: "oncall"}
+	createGroups(t, srv.db, oncall)
+	err = data.AddUsersToGroup(srv.db, oncall.ID, []uid.ID{alice.ID})
+	assert.NilError(t, err)
+
+	web := &models.Destination{Name: "web-01", Kind: models.DestinationKindSSH, UniqueID: "web-01", Roles: []string{"root", "ubuntu"}}
+	err = data.CreateDestination(srv.db, web)
+	assert.NilError(t, err)
+
+	cluster := &models.Destination{Name: "cluster", UniqueID: "cluster"}
+	err = data.CreateDestination(srv.db, cluster)
+	assert.NilError(t, err)
+
+	grants := []*models.Grant{
+		{Subject: alice.PolyID(), Privilege: "ubuntu", Resource: "web-01"},
+		{Subject: oncall.PolyID(), Privilege: "root", Resource: "web-*"},
+		// not a user of the host
+		{Subject: alice.PolyID(), Privilege: "postgres", Resource: "web-01"},
+		{Subject: bob.PolyID(), Privilege: "view", Resource: "cluster"},
+		{Subject: carol.PolyID(), Privilege: "ubuntu", Resource: "web-01", Conditions: models.GrantConditions{
+			Conditions: claims.Conditions{CIDRs: []string{"10.0.0.0/8"}},
+		}},
+	}
+	for _, g := range grants {
+		assert.NilError(t, data.CreateGrant(srv.db, g))
+	}
+
+	accessKey := func(t *testing.T, identity *models.Identity) string {
+		key, err := data.CreateAccessKey(srv.db, &models.AccessKey{
+			IssuedFor:  identity.ID,
+			ProviderID: data.InfraProvider(srv.db).ID,
+			ExpiresAt:  time.Now().Add(10 * time.Minute),
+		})
+		assert.NilError(t, err)
+		return key
+	}
+
+	call := func(t *testing.T, method, path, key string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		req, err := http.NewRequest(method, path, jsonBody(t, body))
+		assert.NilError(t, err)
+		req.Header.Add("Authorization", "Bearer "+key)
+		req.Header.Add("Infra-Version", "0.13.6")
+
+		resp := httptest.NewRecorder()
+		routes.ServeHTTP(resp, req)
+		return resp
+	}
+
+	userPub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+	publicKey, err := pki.MarshalSSHPublicKey(userPub)
+	assert.NilError(t, err)
+
+	t.Run("principals from grants", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/destinations/"+web.ID.String()+"/ssh-certificates", accessKey(t, alice), api.CreateSSHCertificateRequest{
+			PublicKey: string(publicKey),
+		})
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var created api.SSHCertificate
+		err := json.Unmarshal(resp.Body.Bytes(), &created)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, created.Principals, []string{"root", "ubuntu"})
+
+		resp = call(t, http.MethodGet, "/api/ssh-ca", accessKey(t, bob), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var ca api.SSHCertificateAuthority
+		err = json.Unmarshal(resp.Body.Bytes(), &ca)
+		assert.NilError(t, err)
+
+		caKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(ca.PublicKey))
+		assert.NilError(t, err)
+
+		certKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(created.Certificate))
+		assert.NilError(t, err)
+		cert, ok := certKey.(*ssh.Certificate)
+		assert.Assert(t, ok)
+
+		assert.Equal(t, cert.KeyId, "alice@example.com")
+		assert.DeepEqual(t, cert.SignatureKey.Marshal(), caKey.Marshal())
+		assert.Assert(t, time.Unix(int64(cert.ValidBefore), 0).Before(time.Now().Add(time.Hour)))
+	})
+
+	t.Run("no grants on the host", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/destinations/"+web.ID.String()+"/ssh-certificates", accessKey(t, bob), api.CreateSSHCertificateRequest{
+			PublicKey: string(publicKey),
+		})
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+	})
+
+	t.Run("grant with conditions", func(t *testing.T) {
+		createCert := func(t *testing.T, remoteAddr string) *httptest.ResponseRecorder {
+			t.Helper()
+			req, err := http.NewRequest(http.MethodPost, "/api/destinations/"+web.ID.String()+"/ssh-certificates",
+				jsonBody(t, api.CreateSSHCertificateRequest{PublicKey: string(publicKey)}))
+			assert.NilError(t, err)
+			req.RemoteAddr = remoteAddr
+			req.Header.Add("Authorization", "Bearer "+accessKey(t, carol))
+			req.Header.Add("Infra-Version", "0.13.6")
+
+			resp := httptest.NewRecorder()
+			routes.ServeHTTP(resp, req)
+			return resp
+		}
+
+		resp := createCert(t, "192.168.1.2:4321")
+		assert.Equal(t, resp.Code, http.StatusForbidden, resp.Body.String())
+
+		resp = createCert(t, "10.1.2.3:4321")
+		assert.Equal(t, resp.Code, http.StatusCreated, resp.Body.String())
+
+		var created api.SSHCertificate
+		err := json.Unmarshal(resp.Body.Bytes(), &created)
+		assert.NilError(t, err)
+		assert.DeepEqual(t, created.Principals, []string{"ubuntu"})
+	})
+
+	t.Run("not an ssh destination", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/destinations/"+cluster.ID.String()+"/ssh-certificates", accessKey(t, bob), api.CreateSSHCertificateRequest{
+			PublicKey: string(publicKey),
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("invalid public key", func(t *testing.T) {
+		resp := call(t, http.MethodPost, "/api/destinations/"+web.ID.String()+"/ssh-certificates", accessKey(t, alice), api.CreateSSHCertificateRequest{
+			PublicKey: "not a key",
+		})
+		assert.Equal(t, resp.Code, http.StatusBadRequest, resp.Body.String())
+	})
+
+	t.Run("list destinations by kind", func(t *testing.T) {
+		resp := call(t, http.MethodGet, "/api/destinations?kind=ssh", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		var list api.ListResponse[api.Destination]
+		err := json.Unmarshal(resp.Body.Bytes(), &list)
+		assert.NilError(t, err)
+		assert.Equal(t, list.Count, 1)
+		assert.Equal(t, list.Items[0].Name, "web-01")
+
+		resp = call(t, http.MethodGet, "/api/destinations?kind=kubernetes", adminAccessKey(srv), nil)
+		assert.Equal(t, resp.Code, http.StatusOK, resp.Body.String())
+
+		err = json.Unmarshal(resp.Body.Bytes(), &list)
+		assert.NilError(t, err)
+		assert.Equal(t, list.Count, 1)
+		assert.Equal(t, list.Items[0].Name, "cluster")
+	})
+}
@@ -0,0 +1,76 @@
+// Package jwkstest provides an issuer of signed JWTs for tests, which serves
+// its signing key at a JWKS URL, like the OIDC token issuers of CI systems.
+package jwkstest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gopkg.in/square/go-jose.v2"
+	"gopkg.in/square/go-jose.v2/jwt"
+	"gotest.tools/v3/assert"
+)
+
+// Issuer signs tokens with a key which is served from JWKSURL.
+type Issuer struct {
+	// URL is the iss claim of the tokens from the issuer.
+	URL string
+	// JWKSURL is the URL of the public key of the issuer.
+	JWKSURL string
+
+	signer jose.Signer
+}
+
+// NewIssuer starts an issuer with a new signing key. The server is stopped
+// when the test ends.
+func NewIssuer(t *testing.T) *Issuer {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+
+	jwk := jose.JSONWebKey{Key: key, KeyID: "jwkstest", Algorithm: string(jose.RS256), Use: "sig"}
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jwk},
+		(&jose.SignerOptions{}).WithType("JWT"))
+	assert.NilError(t, err)
+
+	keySet := jose.JSONWebKeySet{Keys: []jose.JSONWebKey{jwk.Public()}}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	mux.HandleFunc("/.well-known/jwks", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(keySet)
+	})
+
+	return &Issuer{
+		URL:     srv.URL,
+		JWKSURL: srv.URL + "/.well-known/jwks",
+		signer:  signer,
+	}
+}
+
+// Token returns a token signed by the issuer, with the claims. The iss and
+// exp claims are set, unless they are in claims.
+func (i *Issuer) Token(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	all := map[string]interface{}{
+		"iss": i.URL,
+		"iat": time.Now().Unix(),
+		"exp": time.Now().Add(5 * time.Minute).Unix(),
+	}
+	for k, v := range claims {
+		all[k] = v
+	}
+
+	token, err := jwt.Signed(i.signer).Claims(all).CompactSerialize()
+	assert.NilError(t, err)
+	return token
+}
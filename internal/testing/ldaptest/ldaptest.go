@@ -0,0 +1,329 @@
+// Package ldaptest provides an in-process LDAP server for tests. It supports
+// simple binds, and subtree searches with and, or, not, equality and presence
+// filters, which is enough to test a client against a directory.
+package ldaptest
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+	"gotest.tools/v3/assert"
+)
+
+// Entry is an entry in the directory.
+type Entry struct {
+	DN         string
+	Attributes map[string][]string
+	// Password allows a simple bind as the entry when it is set.
+	Password string
+}
+
+// Server is an LDAP server which listens on localhost.
+type Server struct {
+	// URL is the ldap:// URL of the server.
+	URL string
+
+	listener net.Listener
+	entries  []Entry
+	wg       sync.WaitGroup
+
+	mu    sync.Mutex
+	conns map[net.Conn]struct{}
+}
+
+// Application tags of the LDAP protocol operations, from RFC 4511.
+const (
+	appBindRequest      ber.Tag = 0
+	appBindResponse     ber.Tag = 1
+	appUnbindRequest    ber.Tag = 2
+	appSearchRequest    ber.Tag = 3
+	appSearchResultItem ber.Tag = 4
+	appSearchResultDone ber.Tag = 5
+)
+
+// Result codes of the LDAP protocol, from RFC 4511.
+const (
+	resultSuccess                 = 0
+	resultSizeLimitExceeded       = 4
+	resultInappropriateAuth       = 48
+	resultInvalidCredentials      = 49
+	resultInsufficientAccessRight = 50
+	resultUnwillingToPerform      = 53
+)
+
+// Filter tags of the LDAP protocol, from RFC 4511.
+const (
+	filterAnd      ber.Tag = 0
+	filterOr       ber.Tag = 1
+	filterNot      ber.Tag = 2
+	filterEquality ber.Tag = 3
+	filterPresent  ber.Tag = 7
+)
+
+// NewServer starts a server with the entries. The server is stopped when the
+// test ends.
+func NewServer(t *testing.T, entries ...Entry) *Server {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+
+	s := &Server{
+		URL:      "ldap://" + listener.Addr().String(),
+		listener: listener,
+		entries:  entries,
+		conns:    make(map[net.Conn]struct{}),
+	}
+
+	s.wg.Add(1)
+	go s.serve()
+
+	t.Cleanup(func() {
+		_ = s.listener.Close()
+
+		// connections which the client left open are closed as well
+		s.mu.Lock()
+		for conn := range s.conns {
+			_ = conn.Close()
+		}
+		s.mu.Unlock()
+
+		s.wg.Wait()
+	})
+
+	return s
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(conn)
+
+			s.mu.Lock()
+			delete(s.conns, conn)
+			s.mu.Unlock()
+			_ = conn.Close()
+		}()
+	}
+}
+
+// handle responds to the requests on a connection until it is closed.
+func (s *Server) handle(conn net.Conn) {
+	// bound is the DN of the last successful bind, empty when anonymous
+	var bound string
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil {
+			return
+		}
+
+		if len(packet.Children) < 2 {
+			return
+		}
+
+		messageID, ok := packet.Children[0].Value.(int64)
+		if !ok {
+			return
+		}
+
+		op := packet.Children[1]
+		if op.ClassType != ber.ClassApplication {
+			return
+		}
+
+		var responses []*ber.Packet
+		switch op.Tag {
+		case appBindRequest:
+			var code int
+			bound, code = s.bind(op)
+			responses = append(responses, result(appBindResponse, code))
+		case appSearchRequest:
+			if bound == "" {
+				responses = append(responses, result(appSearchResultDone, resultInsufficientAccessRight))
+				break
+			}
+			responses = s.search(op)
+		case appUnbindRequest:
+			return
+		default:
+			responses = append(responses, result(op.Tag+1, resultUnwillingToPerform))
+		}
+
+		for _, response := range responses {
+			message := ber.NewSequence("LDAP Response")
+			message.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "Message ID"))
+			message.AppendChild(response)
+
+			if _, err := conn.Write(message.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind returns the bound DN and the result code of a simple bind request. A
+// bind with an empty password is an unauthenticated bind, which succeeds for
+// any DN but does not allow a search.
+func (s *Server) bind(op *ber.Packet) (string, int) {
+	if len(op.Children) < 3 {
+		return "", resultUnwillingToPerform
+	}
+
+	dn, _ := op.Children[1].Value.(string)
+	auth := op.Children[2]
+	if auth.ClassType != ber.ClassContext || auth.Tag != 0 {
+		return "", resultInappropriateAuth
+	}
+
+	password := auth.Data.String()
+	if password == "" {
+		return "", resultSuccess
+	}
+
+	for _, entry := range s.entries {
+		if strings.EqualFold(entry.DN, dn) && entry.Password != "" && entry.Password == password {
+			return entry.DN, resultSuccess
+		}
+	}
+
+	return "", resultInvalidCredentials
+}
+
+// search returns the responses to a search of the subtree of the base DN.
+func (s *Server) search(op *ber.Packet) []*ber.Packet {
+	if len(op.Children) < 8 {
+		return []*ber.Packet{result(appSearchResultDone, resultUnwillingToPerform)}
+	}
+
+	base, _ := op.Children[0].Value.(string)
+	sizeLimit, _ := op.Children[3].Value.(int64)
+	filter := op.Children[6]
+
+	var attributes []string
+	for _, attribute := range op.Children[7].Children {
+		if name, ok := attribute.Value.(string); ok {
+			attributes = append(attributes, name)
+		}
+	}
+
+	var responses []*ber.Packet
+	for _, entry := range s.entries {
+		if !inSubtree(entry.DN, base) || !matches(entry, filter) {
+			continue
+		}
+
+		if sizeLimit > 0 && int64(len(responses)) == sizeLimit {
+			return append(responses, result(appSearchResultDone, resultSizeLimitExceeded))
+		}
+
+		responses = append(responses, searchResultEntry(entry, attributes))
+	}
+
+	return append(responses, result(appSearchResultDone, resultSuccess))
+}
+
+func inSubtree(dn, base string) bool {
+	dn, base = strings.ToLower(dn), strings.ToLower(base)
+	return base == "" || dn == base || strings.HasSuffix(dn, ","+base)
+}
+
+func matches(entry Entry, filter *ber.Packet) bool {
+	if filter.ClassType != ber.ClassContext {
+		return false
+	}
+
+	switch filter.Tag {
+	case filterAnd:
+		for _, child := range filter.Children {
+			if !matches(entry, child) {
+				return false
+			}
+		}
+		return true
+	case filterOr:
+		for _, child := range filter.Children {
+			if matches(entry, child) {
+				return true
+			}
+		}
+		return false
+	case filterNot:
+		return len(filter.Children) == 1 && !matches(entry, filter.Children[0])
+	case filterEquality:
+		if len(filter.Children) != 2 {
+			return false
+		}
+		name, _ := filter.Children[0].Value.(string)
+		value, _ := filter.Children[1].Value.(string)
+		for _, v := range attributeValues(entry, name) {
+			if strings.EqualFold(v, value) {
+				return true
+			}
+		}
+		return false
+	case filterPresent:
+		return len(attributeValues(entry, filter.Data.String())) > 0
+	default:
+		return false
+	}
+}
+
+// attributeValues returns the values of an attribute. Attribute names are
+// case insensitive.
+func attributeValues(entry Entry, name string) []string {
+	for k, v := range entry.Attributes {
+		if strings.EqualFold(k, name) {
+			return v
+		}
+	}
+	return nil
+}
+
+func searchResultEntry(entry Entry, attributes []string) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, appSearchResultItem, nil, "Search Result Entry")
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, entry.DN, "Object Name"))
+
+	list := ber.NewSequence("Attributes")
+	for _, name := range attributes {
+		values := attributeValues(entry, name)
+		if len(values) == 0 {
+			continue
+		}
+
+		attribute := ber.NewSequence("Attribute")
+		attribute.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "Type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "Values")
+		for _, value := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, value, "Value"))
+		}
+		attribute.AppendChild(set)
+		list.AppendChild(attribute)
+	}
+	response.AppendChild(list)
+
+	return response
+}
+
+func result(tag ber.Tag, code int) *ber.Packet {
+	response := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	response.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "Result Code"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Matched DN"))
+	response.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "Diagnostic Message"))
+	return response
+}
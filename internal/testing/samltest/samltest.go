@@ -0,0 +1,154 @@
+// Package samltest provides a SAML identity provider for tests. The identity
+// provider logs in its user without prompting, and signs the assertions in its
+// responses, which is enough to test a service provider.
+package samltest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/xml"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	dsig "github.com/russellhaering/goxmldsig"
+	"gotest.tools/v3/assert"
+)
+
+// User is the user who is logged in by the identity provider.
+type User struct {
+	// NameID is the email of the user.
+	NameID string
+	// Groups are sent in the groups attribute of the assertion.
+	Groups []string
+}
+
+// IdentityProvider is a SAML identity provider which serves its metadata on
+// localhost.
+type IdentityProvider struct {
+	// MetadataURL is the URL of the metadata of the identity provider.
+	MetadataURL string
+	// User is logged in by every authentication request.
+	User User
+
+	idp *saml.IdentityProvider
+}
+
+// NewIdentityProvider starts an identity provider with a new signing key. The
+// server is stopped when the test ends.
+func NewIdentityProvider(t *testing.T, user User) *IdentityProvider {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NilError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "samltest"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	raw, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NilError(t, err)
+	cert, err := x509.ParseCertificate(raw)
+	assert.NilError(t, err)
+
+	p := &IdentityProvider{User: user}
+
+	mux := http.NewServeMux()
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	metadataURL, err := url.Parse(srv.URL + "/metadata")
+	assert.NilError(t, err)
+	ssoURL, err := url.Parse(srv.URL + "/sso")
+	assert.NilError(t, err)
+
+	p.MetadataURL = metadataURL.String()
+	p.idp = &saml.IdentityProvider{
+		Key:             key,
+		Certificate:     cert,
+		MetadataURL:     *metadataURL,
+		SSOURL:          *ssoURL,
+		SignatureMethod: dsig.RSASHA256SignatureMethod,
+	}
+
+	mux.HandleFunc("/metadata", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/samlmetadata+xml")
+		_ = xml.NewEncoder(w).Encode(p.idp.Metadata())
+	})
+
+	return p
+}
+
+// Metadata returns the XML metadata of the identity provider.
+func (p *IdentityProvider) Metadata(t *testing.T) string {
+	t.Helper()
+	metadata, err := xml.Marshal(p.idp.Metadata())
+	assert.NilError(t, err)
+	return string(metadata)
+}
+
+// Respond handles the authentication request in the redirect URL from a
+// service provider, as if the user had logged in. It returns the URL of the
+// assertion consumer service, and the form which the browser posts to it.
+func (p *IdentityProvider) Respond(t *testing.T, redirectURL string) (string, url.Values) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, redirectURL, nil)
+
+	authnRequest, err := saml.NewIdpAuthnRequest(p.idp, req)
+	assert.NilError(t, err)
+
+	// every service provider is trusted, and its assertion consumer service
+	// is the one in the request
+	var request saml.AuthnRequest
+	assert.NilError(t, xml.Unmarshal(authnRequest.RequestBuffer, &request))
+	p.idp.ServiceProviderProvider = serviceProvider{acsURL: request.AssertionConsumerServiceURL}
+
+	assert.NilError(t, authnRequest.Validate())
+
+	session := &saml.Session{
+		ID:           "session",
+		NameID:       p.User.NameID,
+		NameIDFormat: string(saml.EmailAddressNameIDFormat),
+	}
+	if len(p.User.Groups) > 0 {
+		groups := saml.Attribute{Name: "groups"}
+		for _, group := range p.User.Groups {
+			groups.Values = append(groups.Values, saml.AttributeValue{Type: "xs:string", Value: group})
+		}
+		session.CustomAttributes = append(session.CustomAttributes, groups)
+	}
+
+	assert.NilError(t, saml.DefaultAssertionMaker{}.MakeAssertion(authnRequest, session))
+
+	form, err := authnRequest.PostBinding()
+	assert.NilError(t, err)
+
+	return form.URL, url.Values{
+		"SAMLResponse": {form.SAMLResponse},
+		"RelayState":   {form.RelayState},
+	}
+}
+
+type serviceProvider struct {
+	acsURL string
+}
+
+func (s serviceProvider) GetServiceProvider(_ *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	return &saml.EntityDescriptor{
+		EntityID: serviceProviderID,
+		SPSSODescriptors: []saml.SPSSODescriptor{{
+			AssertionConsumerServices: []saml.IndexedEndpoint{{
+				Binding:  saml.HTTPPostBinding,
+				Location: s.acsURL,
+			}},
+		}},
+	}, nil
+}
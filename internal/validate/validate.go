@@ -39,6 +39,9 @@ func validateStruct(v reflect.Value) Error {
 	if v.Kind() == reflect.Struct {
 		for i := 0; i < v.NumField(); i++ {
 			f := v.Field(i)
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
 			if v.Type().Field(i).Anonymous {
 				// validate the embedded struct
 				for k, v := range validateStruct(f) {
@@ -3,6 +3,7 @@ package validate
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"gotest.tools/v3/assert"
 )
@@ -182,6 +183,18 @@ func TestValidate_Nested(t *testing.T) {
 		}
 		assert.DeepEqual(t, fieldError, expected)
 	})
+
+	t.Run("struct fields with unexported fields", func(t *testing.T) {
+		n := struct {
+			ExampleRequest
+			Since time.Time
+		}{
+			ExampleRequest: ExampleRequest{ID: "ok", First: "1"},
+			Since:          time.Now(),
+		}
+		err := Validate(n)
+		assert.NilError(t, err)
+	})
 }
 
 type MutualExample struct {
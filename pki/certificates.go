@@ -9,10 +9,10 @@ import (
 	"fmt"
 	"math/big"
 	"math/rand"
-	"strings"
 	"time"
 
 	"github.com/infrahq/infra/internal/server/models"
+	"github.com/infrahq/infra/uid"
 )
 
 // the pki package defines an interface and implementations of public key encryption, specifically around certificates.
@@ -81,25 +81,22 @@ func MakeUserCert(commonName string, lifetime time.Duration) (*KeyPair, error) {
 	return keyPair, nil
 }
 
-func SignUserCert(cp CertificateProvider, cert *x509.Certificate, user *models.Identity) (*x509.Certificate, []byte, error) {
-	if len(cert.Raw) == 0 {
-		panic("cert.Raw is missing")
-	}
-
-	rawCert := cert.Raw
-
-	if !strings.HasPrefix(cert.Subject.CommonName, "User ") {
-		return nil, nil, fmt.Errorf("invalid certificate common name for user certificate")
+// SignUserCert signs a certificate for the user from a certificate signing
+// request. The subject of the request is replaced, so that the certificate
+// identifies the user by ID, and by name in the email addresses. The serial
+// number of the subject is the ID of the session which requested the
+// certificate, the certificate is only valid while the session is.
+func SignUserCert(cp CertificateProvider, csr *x509.CertificateRequest, user *models.Identity, sessionID uid.ID) (*x509.Certificate, []byte, error) {
+	if err := csr.CheckSignature(); err != nil {
+		return nil, nil, fmt.Errorf("invalid certificate request signature: %w", err)
 	}
 
 	pem1, err := cp.SignCertificate(x509.CertificateRequest{
-		Raw:                rawCert,
-		PublicKeyAlgorithm: cert.PublicKeyAlgorithm,
-		PublicKey:          cert.PublicKey,
-		Subject:            cert.Subject,
+		Raw:                csr.Raw,
+		PublicKeyAlgorithm: csr.PublicKeyAlgorithm,
+		PublicKey:          csr.PublicKey,
+		Subject:            pkix.Name{CommonName: "User " + user.ID.String(), SerialNumber: sessionID.String()},
 		EmailAddresses:     []string{user.Name},
-		Extensions:         cert.Extensions,
-		ExtraExtensions:    cert.ExtraExtensions,
 		SignatureAlgorithm: x509.PureEd25519,
 	})
 	if err != nil {
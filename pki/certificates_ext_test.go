@@ -1,8 +1,10 @@
 package pki_test
 
 import (
+	"crypto/rand"
 	"crypto/tls"
 	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -22,7 +24,6 @@ import (
 )
 
 func TestCertificateSigningWorks(t *testing.T) {
-	t.Skip("persistence not implemented")
 	db := setupDB(t)
 
 	cp, err := pki.NewNativeCertificateProvider(db, pki.NativeCertificateProviderConfig{
@@ -44,10 +45,20 @@ func TestCertificateSigningWorks(t *testing.T) {
 	keyPair, err := pki.MakeUserCert("User "+user.ID.String(), 24*time.Hour)
 	assert.NilError(t, err)
 
-	// happens on the server, needs to be a request for this.
-	signedCert, signedRaw, err := pki.SignUserCert(cp, keyPair.Cert, user)
+	rawCSR, err := x509.CreateCertificateRequest(rand.Reader, &x509.CertificateRequest{
+		Subject: pkix.Name{CommonName: "User " + user.ID.String()},
+	}, keyPair.PrivateKey)
 	assert.NilError(t, err)
 
+	csr, err := x509.ParseCertificateRequest(rawCSR)
+	assert.NilError(t, err)
+
+	// happens on the server
+	signedCert, signedRaw, err := pki.SignUserCert(cp, csr, user, uid.New())
+	assert.NilError(t, err)
+	assert.Equal(t, signedCert.Subject.CommonName, "User "+user.ID.String())
+	assert.DeepEqual(t, signedCert.EmailAddresses, []string{user.Name})
+
 	keyPair.SignedCert = signedCert
 	keyPair.SignedCertPEM = signedRaw
 
@@ -121,6 +132,7 @@ func setupDB(t *testing.T) *gorm.DB {
 
 	err = data.CreateProvider(db, &models.Provider{
 		Name:      models.InternalInfraProviderName,
+		Kind:      models.ProviderKindInfra,
 		CreatedBy: models.CreatedBySystem,
 	})
 	assert.NilError(t, err)
@@ -43,7 +43,6 @@ func eachProvider(t *testing.T, eachFunc func(t *testing.T, p CertificateProvide
 }
 
 func TestCertificatesImplementations(t *testing.T) {
-	t.Skip("persistence not implemented")
 	eachProvider(t, func(t *testing.T, p CertificateProvider) {
 		err := p.CreateCA()
 		assert.NilError(t, err)
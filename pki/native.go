@@ -1,6 +1,7 @@
 package pki
 
 import (
+	"bytes"
 	"crypto/ed25519"
 	"crypto/rand"
 	"crypto/tls"
@@ -12,9 +13,13 @@ import (
 	"math/big"
 	"net"
 	"strings"
+	"sync"
 	"time"
 
 	"gorm.io/gorm"
+
+	"github.com/infrahq/infra/internal/server/data"
+	"github.com/infrahq/infra/internal/server/models"
 )
 
 const (
@@ -40,6 +45,9 @@ type NativeCertificateProvider struct {
 
 	db *gorm.DB
 
+	// mu guards the keypairs, which are replaced by a rotation while
+	// certificates are being signed
+	mu              sync.RWMutex
 	activeKeypair   KeyPair
 	previousKeypair KeyPair
 }
@@ -110,6 +118,9 @@ func NewNativeCertificateProvider(db *gorm.DB, cfg NativeCertificateProviderConf
 }
 
 func (n *NativeCertificateProvider) Preload(rootCACertificate, publicKey []byte) (err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
 	if n.activeKeypair.SignedCert != nil {
 		return fmt.Errorf("cannot preload a certificate when another one is already loaded.")
 	}
@@ -167,12 +178,15 @@ func (n *NativeCertificateProvider) Preload(rootCACertificate, publicKey []byte)
 		SignedCert:       cert,
 	}
 
-	return n.RotateCA()
+	return n.rotateCA()
 }
 
 // CreateCA creates a new root CA and immediately does a half-rotation.
 // the new active key after rotation is the one that should be used.
 func (n *NativeCertificateProvider) CreateCA() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
 	pub, prv, err := ed25519.GenerateKey(randReader)
 	if err != nil {
 		return fmt.Errorf("generating keys: %w", err)
@@ -198,11 +212,14 @@ func (n *NativeCertificateProvider) CreateCA() error {
 	n.activeKeypair.KeyAlgorithm = x509.Ed25519.String()
 	n.activeKeypair.SigningAlgorithm = x509.PureEd25519.String()
 
-	return n.RotateCA()
+	return n.rotateCA()
 }
 
 // ActiveCAs returns the currently in-use CAs, the newest cert is always the last in the list
 func (n *NativeCertificateProvider) ActiveCAs() []x509.Certificate {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
 	result := []x509.Certificate{}
 
 	if n.previousKeypair.SignedCert != nil && certActive(n.previousKeypair.SignedCert) {
@@ -230,6 +247,9 @@ func certActive(cert *x509.Certificate) bool {
 
 // TODO: SignCertificate should be renamed to SignUserCertificate?
 func (n *NativeCertificateProvider) SignCertificate(csr x509.CertificateRequest) (pemBytes []byte, err error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
 	switch {
 	case csr.Subject.CommonName == rootCAName:
 		return nil, fmt.Errorf("cannot sign cert pretending to be the root CA")
@@ -249,12 +269,17 @@ func (n *NativeCertificateProvider) SignCertificate(csr x509.CertificateRequest)
 		return nil, fmt.Errorf("%q is not an acceptable public key algorithm, expecting one of: %v", csr.PublicKeyAlgorithm, allowedPublicKeyAlgorithms)
 	}
 
+	serial, err := rand.Int(randReader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("creating random serial: %w", err)
+	}
+
 	certTemplate := &x509.Certificate{
 		Signature:          csr.Signature,
 		SignatureAlgorithm: csr.SignatureAlgorithm,
 		PublicKeyAlgorithm: csr.PublicKeyAlgorithm,
 		PublicKey:          csr.PublicKey,
-		SerialNumber:       big.NewInt(2),
+		SerialNumber:       serial,
 		Issuer:             n.activeKeypair.SignedCert.Subject,
 		Subject:            csr.Subject,
 		EmailAddresses:     csr.EmailAddresses,
@@ -287,6 +312,13 @@ func (n *NativeCertificateProvider) SignCertificate(csr x509.CertificateRequest)
 
 // RotateCA does a half-rotation. the current cert becomes the previous cert, and there are always two active certificates
 func (n *NativeCertificateProvider) RotateCA() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.rotateCA()
+}
+
+func (n *NativeCertificateProvider) rotateCA() error {
 	n.previousKeypair = n.activeKeypair
 	n.activeKeypair = KeyPair{}
 
@@ -380,18 +412,88 @@ func createCertSignedBy(signer, signee KeyPair, lifetime time.Duration) (*x509.C
 	return cert, rawCert, nil
 }
 
+// loadFromDB loads the two newest certificates which have not expired. Before
+// the first CA is created there are none.
 func (n *NativeCertificateProvider) loadFromDB() error {
-	// TODO:
-	return fmt.Errorf("persistence not implemented")
+	certs, err := data.ListCertificateAuthorities(n.db)
+	if err != nil {
+		return fmt.Errorf("list certificate authorities: %w", err)
+	}
+
+	if len(certs) > 0 {
+		if n.activeKeypair, err = keyPairFromCertificateAuthority(certs[len(certs)-1]); err != nil {
+			return err
+		}
+	}
+
+	if len(certs) > 1 {
+		if n.previousKeypair, err = keyPairFromCertificateAuthority(certs[len(certs)-2]); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// saveToDB stores new certs to the database. Used when rotating keys.
+// saveToDB stores new certs to the database. Used when rotating keys. The
+// previous keypair is stored too, unless it was already the newest stored.
 func (n *NativeCertificateProvider) saveToDB() error {
-	// TODO:
-	return fmt.Errorf("persistence not implemented")
+	certs, err := data.ListCertificateAuthorities(n.db)
+	if err != nil {
+		return fmt.Errorf("list certificate authorities: %w", err)
+	}
+
+	if n.previousKeypair.SignedCert != nil {
+		if len(certs) == 0 || !bytes.Equal(certs[len(certs)-1].SignedCert, n.previousKeypair.SignedCertPEM) {
+			if err := data.AddCertificateAuthority(n.db, certificateAuthorityFromKeyPair(n.previousKeypair)); err != nil {
+				return fmt.Errorf("save previous certificate authority: %w", err)
+			}
+		}
+	}
+
+	if err := data.AddCertificateAuthority(n.db, certificateAuthorityFromKeyPair(n.activeKeypair)); err != nil {
+		return fmt.Errorf("save certificate authority: %w", err)
+	}
+
+	return nil
+}
+
+func certificateAuthorityFromKeyPair(keyPair KeyPair) *models.CertificateAuthority {
+	return &models.CertificateAuthority{
+		KeyAlgorithm:     keyPair.KeyAlgorithm,
+		SigningAlgorithm: keyPair.SigningAlgorithm,
+		PublicKey:        keyPair.PublicKey,
+		PrivateKey:       models.EncryptedAtRestBytes(keyPair.PrivateKey),
+		SignedCert:       keyPair.SignedCertPEM,
+		ExpiresAt:        keyPair.SignedCert.NotAfter,
+	}
+}
+
+func keyPairFromCertificateAuthority(ca models.CertificateAuthority) (KeyPair, error) {
+	p, _ := pem.Decode(ca.SignedCert)
+	if p == nil {
+		return KeyPair{}, fmt.Errorf("decoding certificate authority %s", ca.ID)
+	}
+
+	cert, err := x509.ParseCertificate(p.Bytes)
+	if err != nil {
+		return KeyPair{}, fmt.Errorf("parsing certificate authority %s: %w", ca.ID, err)
+	}
+
+	return KeyPair{
+		KeyAlgorithm:     ca.KeyAlgorithm,
+		SigningAlgorithm: ca.SigningAlgorithm,
+		PublicKey:        ca.PublicKey,
+		PrivateKey:       ed25519.PrivateKey(ca.PrivateKey),
+		SignedCertPEM:    ca.SignedCert,
+		SignedCert:       cert,
+	}, nil
 }
 
 func (n *NativeCertificateProvider) TLSCertificates() ([]tls.Certificate, error) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
 	result := []tls.Certificate{}
 
 	keyPairs := []KeyPair{
@@ -26,7 +26,6 @@ func setupDB(t *testing.T) *gorm.DB {
 }
 
 func TestCertificateStorage(t *testing.T) {
-	t.Skip("persistence not implemented")
 	cfg := NativeCertificateProviderConfig{
 		FullKeyRotationDurationInDays: 2,
 	}
@@ -61,7 +60,6 @@ var cmpX509Certificate = cmp.Comparer(func(x, y x509.Certificate) bool {
 })
 
 func TestTLSCertificates(t *testing.T) {
-	t.Skip("persistence not implemented")
 	cfg := NativeCertificateProviderConfig{
 		FullKeyRotationDurationInDays: 2,
 	}
@@ -0,0 +1,136 @@
+package pki
+
+import (
+	"crypto/ed25519"
+	"encoding/binary"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// sshClockSkew is subtracted from the start of the validity period of SSH
+// certificates, so that they are accepted by hosts with a clock that is behind.
+const sshClockSkew = 1 * time.Minute
+
+// SSHUserCertificateOptions are the properties of an SSH user certificate.
+type SSHUserCertificateOptions struct {
+	// KeyID identifies the certificate in the logs of the SSH server.
+	KeyID string
+	// Principals are the users that the certificate allows logging in as.
+	Principals []string
+	// Lifetime is how long the certificate is valid.
+	Lifetime time.Duration
+}
+
+// SignSSHUserCertificate creates an SSH user certificate for the public key,
+// signed by the certificate authority key.
+func SignSSHUserCertificate(caKey ed25519.PrivateKey, publicKey ssh.PublicKey, opts SSHUserCertificateOptions) (*ssh.Certificate, error) {
+	signer, err := ssh.NewSignerFromKey(caKey)
+	if err != nil {
+		return nil, fmt.Errorf("ssh signer: %w", err)
+	}
+
+	serial := make([]byte, 8)
+	if _, err := randReader.Read(serial); err != nil {
+		return nil, fmt.Errorf("ssh certificate serial: %w", err)
+	}
+
+	now := time.Now()
+	cert := &ssh.Certificate{
+		Key:             publicKey,
+		Serial:          binary.BigEndian.Uint64(serial),
+		CertType:        ssh.UserCert,
+		KeyId:           opts.KeyID,
+		ValidPrincipals: opts.Principals,
+		ValidAfter:      uint64(now.Add(-sshClockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(opts.Lifetime).Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: map[string]string{
+				"permit-agent-forwarding": "",
+				"permit-port-forwarding":  "",
+				"permit-pty":              "",
+				"permit-user-rc":          "",
+			},
+		},
+	}
+
+	if err := cert.SignCert(randReader, signer); err != nil {
+		return nil, fmt.Errorf("sign ssh certificate: %w", err)
+	}
+
+	return cert, nil
+}
+
+// MarshalSSHPublicKey returns the public key in the authorized_keys format
+// used by OpenSSH, without a trailing newline.
+func MarshalSSHPublicKey(key ed25519.PublicKey) ([]byte, error) {
+	pub, err := ssh.NewPublicKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("ssh public key: %w", err)
+	}
+
+	authorizedKey := ssh.MarshalAuthorizedKey(pub)
+	return authorizedKey[:len(authorizedKey)-1], nil
+}
+
+// MarshalSSHPrivateKey returns the private key in the PEM encoded format used
+// by OpenSSH. The key is not encrypted.
+func MarshalSSHPrivateKey(key ed25519.PrivateKey) ([]byte, error) {
+	// an ed25519 private key is the seed followed by the public key
+	publicKey := ed25519.PublicKey(key[ed25519.SeedSize:])
+
+	pub, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("ssh public key: %w", err)
+	}
+
+	check := make([]byte, 4)
+	if _, err := randReader.Read(check); err != nil {
+		return nil, fmt.Errorf("ssh private key check: %w", err)
+	}
+
+	privateKey := struct {
+		Check1  uint32
+		Check2  uint32
+		Keytype string
+		Pub     []byte
+		Priv    []byte
+		Comment string
+		Pad     []byte `ssh:"rest"`
+	}{
+		Check1:  binary.BigEndian.Uint32(check),
+		Check2:  binary.BigEndian.Uint32(check),
+		Keytype: ssh.KeyAlgoED25519,
+		Pub:     publicKey,
+		Priv:    key,
+	}
+
+	// the private key section is padded to the cipher block size, which is 8
+	// when the key is not encrypted
+	unpadded := len(ssh.Marshal(privateKey))
+	for i := 0; (unpadded+i)%8 != 0; i++ {
+		privateKey.Pad = append(privateKey.Pad, byte(i+1))
+	}
+
+	envelope := struct {
+		CipherName   string
+		KdfName      string
+		KdfOpts      string
+		NumKeys      uint32
+		PubKey       []byte
+		PrivKeyBlock []byte
+	}{
+		CipherName:   "none",
+		KdfName:      "none",
+		NumKeys:      1,
+		PubKey:       pub.Marshal(),
+		PrivKeyBlock: ssh.Marshal(privateKey),
+	}
+
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  "OPENSSH PRIVATE KEY",
+		Bytes: append([]byte("openssh-key-v1\x00"), ssh.Marshal(envelope)...),
+	}), nil
+}
@@ -0,0 +1,70 @@
+package pki
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+	"gotest.tools/v3/assert"
+)
+
+func TestSignSSHUserCertificate(t *testing.T) {
+	_, caKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+
+	userPub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+
+	sshUserPub, err := ssh.NewPublicKey(userPub)
+	assert.NilError(t, err)
+
+	cert, err := SignSSHUserCertificate(caKey, sshUserPub, SSHUserCertificateOptions{
+		KeyID:      "alice@example.com",
+		Principals: []string{"ubuntu"},
+		Lifetime:   10 * time.Minute,
+	})
+	assert.NilError(t, err)
+
+	caPub, err := MarshalSSHPublicKey(caKey.Public().(ed25519.PublicKey)) // nolint:forcetypeassert
+	assert.NilError(t, err)
+
+	trusted, _, _, _, err := ssh.ParseAuthorizedKey(caPub)
+	assert.NilError(t, err)
+
+	checker := &ssh.CertChecker{
+		IsUserAuthority: func(auth ssh.PublicKey) bool {
+			return string(auth.Marshal()) == string(trusted.Marshal())
+		},
+	}
+
+	_, err = checker.Authenticate(connMetadata{user: "ubuntu"}, cert)
+	assert.NilError(t, err)
+
+	_, err = checker.Authenticate(connMetadata{user: "root"}, cert)
+	assert.ErrorContains(t, err, `principal "root" not in the set of valid principals`)
+}
+
+func TestMarshalSSHPrivateKey(t *testing.T) {
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	assert.NilError(t, err)
+
+	keyPEM, err := MarshalSSHPrivateKey(key)
+	assert.NilError(t, err)
+
+	parsed, err := ssh.ParseRawPrivateKey(keyPEM)
+	assert.NilError(t, err)
+	parsedKey, ok := parsed.(*ed25519.PrivateKey)
+	assert.Assert(t, ok, "unexpected key type %T", parsed)
+	assert.Assert(t, key.Equal(*parsedKey))
+}
+
+type connMetadata struct {
+	ssh.ConnMetadata
+	user string
+}
+
+func (c connMetadata) User() string {
+	return c.user
+}
@@ -10,7 +10,7 @@ export default function AuthRequired({ children }) {
   }
 
   if (!auth?.id) {
-    router.replace('/login')
+    router.replace(`/login?next=${encodeURIComponent(router.asPath)}`)
     return null
   }
 
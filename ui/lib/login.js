@@ -0,0 +1,16 @@
+// redirectAfterLogin returns to the page that required a login, such as the
+// login of a destination. Only paths on this server are allowed.
+export function redirectAfterLogin(router, next) {
+  if (!next || !next.startsWith('/') || next.startsWith('//')) {
+    router.replace('/')
+    return
+  }
+
+  // api routes are not pages of the ui
+  if (next.startsWith('/api/')) {
+    window.location.replace(next)
+    return
+  }
+
+  router.replace(next)
+}
@@ -2,24 +2,33 @@ import { useEffect } from 'react'
 import { useRouter } from 'next/router'
 import { useSWRConfig } from 'swr'
 
+import { redirectAfterLogin } from '../../lib/login'
+
 export default function Callback() {
   const { mutate } = useSWRConfig()
   const router = useRouter()
 
-  async function login({ providerID, code, redirectURL }) {
+  async function login({ providerID, providerKind, code, redirectURL }) {
     await fetch('/api/login', {
       method: 'POST',
-      body: JSON.stringify({
-        oidc: {
-          providerID,
-          code,
-          redirectURL,
-        },
-      }),
+      body: JSON.stringify(
+        providerKind === 'saml'
+          ? { saml: { providerID, code } }
+          : {
+              oidc: {
+                providerID,
+                code,
+                redirectURL,
+              },
+            }
+      ),
     })
 
     await mutate('/api/users/self')
-    router.replace('/')
+
+    const next = window.localStorage.getItem('next')
+    window.localStorage.removeItem('next')
+    redirectAfterLogin(router, next)
   }
 
   useEffect(() => {
@@ -27,6 +36,7 @@ export default function Callback() {
     const params = Object.fromEntries(urlSearchParams.entries())
 
     const providerID = window.localStorage.getItem('providerID')
+    const providerKind = window.localStorage.getItem('providerKind')
     const redirectURL = window.localStorage.getItem('redirectURL')
 
     if (!params.code || !providerID || !redirectURL) {
@@ -37,10 +47,12 @@ export default function Callback() {
     if (params.state === window.localStorage.getItem('state')) {
       login({
         providerID,
+        providerKind,
         code: params.code,
         redirectURL,
       })
       window.localStorage.removeItem('providerID')
+      window.localStorage.removeItem('providerKind')
       window.localStorage.removeItem('state')
       window.localStorage.removeItem('redirectURL')
     }
@@ -3,11 +3,16 @@ import { useState } from 'react'
 import useSWR, { useSWRConfig } from 'swr'
 
 import { providers as providersList } from '../../lib/providers'
+import { redirectAfterLogin } from '../../lib/login'
 
 import LoginLayout from '../../components/layouts/login'
 
-function oidcLogin({ id, clientID, authURL, scopes }) {
+function oidcLogin({ id, clientID, authURL, scopes }, next) {
   window.localStorage.setItem('providerID', id)
+  window.localStorage.removeItem('providerKind')
+  if (next) {
+    window.localStorage.setItem('next', next)
+  }
 
   const state = [...Array(10)]
     .map(() => (~~(Math.random() * 36)).toString(36))
@@ -22,7 +27,27 @@ function oidcLogin({ id, clientID, authURL, scopes }) {
   )}&state=${state}`
 }
 
-function Providers({ providers }) {
+function samlLogin({ id }, next) {
+  window.localStorage.setItem('providerID', id)
+  window.localStorage.setItem('providerKind', 'saml')
+  if (next) {
+    window.localStorage.setItem('next', next)
+  }
+
+  const state = [...Array(10)]
+    .map(() => (~~(Math.random() * 36)).toString(36))
+    .join('')
+  window.localStorage.setItem('state', state)
+
+  const redirectURL = window.location.origin + '/login/callback'
+  window.localStorage.setItem('redirectURL', redirectURL)
+
+  document.location.href = `/api/providers/${id}/saml/login?redirectURL=${encodeURIComponent(
+    redirectURL
+  )}&state=${state}`
+}
+
+function Providers({ providers, next, onLDAPLogin }) {
   return (
     <>
       <div className='mt-2 w-full max-w-sm'>
@@ -30,7 +55,13 @@ function Providers({ providers }) {
           p =>
             p.kind && (
               <button
-                onClick={() => oidcLogin(p)}
+                onClick={() =>
+                  p.kind === 'ldap'
+                    ? onLDAPLogin(p)
+                    : p.kind === 'saml'
+                    ? samlLogin(p, next)
+                    : oidcLogin(p, next)
+                }
                 key={p.id}
                 title={`${p.name} — ${p.url}`}
                 className='my-2 flex w-full items-center rounded-md border border-gray-700 px-4 py-3 hover:border-gray-600'
@@ -75,6 +106,9 @@ export default function Login() {
 
   const [name, setName] = useState('')
   const [password, setPassword] = useState('')
+  const [ldapProvider, setLDAPProvider] = useState(null)
+  const [mfaToken, setMFAToken] = useState('')
+  const [code, setCode] = useState('')
   const [error, setError] = useState('')
 
   async function onSubmit(e) {
@@ -83,20 +117,35 @@ export default function Login() {
     try {
       const res = await fetch('/api/login', {
         method: 'post',
-        body: JSON.stringify({
-          passwordCredentials: {
-            name,
-            password,
-          },
-        }),
+        body: JSON.stringify(
+          mfaToken
+            ? { mfa: { token: mfaToken, code } }
+            : ldapProvider
+            ? { ldap: { providerID: ldapProvider.id, name, password } }
+            : { passwordCredentials: { name, password } }
+        ),
       })
 
       if (!res.ok) {
+        // the token can only be used once, start over with the password
+        setMFAToken('')
         throw await res.json()
       }
 
       const data = await res.json()
 
+      if (data.mfaToken) {
+        setMFAToken(data.mfaToken)
+        return false
+      }
+
+      if (data.mfaEnrollmentRequired) {
+        setError(
+          'Multi-factor authentication is required, run infra login to enroll'
+        )
+        return false
+      }
+
       if (data.passwordUpdateRequired) {
         router.replace({
           pathname: '/login/finish',
@@ -107,7 +156,7 @@ export default function Login() {
       }
 
       await mutate('/api/users/self')
-      router.replace('/')
+      redirectAfterLogin(router, router.query.next)
     } catch (e) {
       console.error(e)
       setError('Invalid credentials')
@@ -123,56 +172,105 @@ export default function Login() {
         Welcome back. Login with your credentials{' '}
         {providers?.length > 0 && 'or via your identity provider.'}
       </h2>
-      {providers?.length > 0 && <Providers providers={providers || []} />}
+      {providers?.length > 0 && (
+        <Providers
+          providers={providers || []}
+          next={router.query.next}
+          onLDAPLogin={p => {
+            setLDAPProvider(p)
+            setError('')
+          }}
+        />
+      )}
       <form
         onSubmit={onSubmit}
         className='relative flex w-full max-w-sm flex-col'
       >
-        <div className='my-2 w-full'>
-          <label htmlFor='name' className='text-3xs uppercase text-gray-500'>
-            Username or Email
-          </label>
-          <input
-            required
-            autoFocus
-            name='name'
-            placeholder='enter your username or email'
-            onChange={e => {
-              setName(e.target.value)
-              setError('')
-            }}
-            className={`w-full border-b border-gray-800 bg-transparent px-px py-2 text-2xs placeholder:italic focus:border-b focus:border-gray-200 focus:outline-none ${
-              error ? 'border-pink-500/60' : ''
-            }`}
-          />
-        </div>
-        <div className='my-2 w-full'>
-          <label
-            htmlFor='password'
-            className='text-3xs uppercase text-gray-500'
-          >
-            Password
-          </label>
-          <input
-            required
-            name='password'
-            type='password'
-            placeholder='enter your password'
-            onChange={e => {
-              setPassword(e.target.value)
-              setError('')
-            }}
-            className={`w-full border-b border-gray-800 bg-transparent px-px py-2 text-2xs placeholder:italic focus:border-b focus:outline-none focus:ring-gray-200 ${
-              error ? 'border-pink-500/60' : ''
-            }`}
-          />
-        </div>
-        <button
-          disabled={!name || !password}
-          className='mt-6 mb-2 rounded-lg border border-violet-300 px-4 py-3 text-2xs text-violet-100 hover:border-violet-100 disabled:pointer-events-none disabled:opacity-30'
-        >
-          Login
-        </button>
+        {mfaToken ? (
+          <>
+            <div className='my-2 w-full'>
+              <label
+                htmlFor='code'
+                className='text-3xs uppercase text-gray-500'
+              >
+                Code
+              </label>
+              <input
+                required
+                autoFocus
+                name='code'
+                autoComplete='one-time-code'
+                placeholder='enter the code from your authenticator app'
+                onChange={e => {
+                  setCode(e.target.value)
+                  setError('')
+                }}
+                className={`w-full border-b border-gray-800 bg-transparent px-px py-2 text-2xs placeholder:italic focus:border-b focus:border-gray-200 focus:outline-none ${
+                  error ? 'border-pink-500/60' : ''
+                }`}
+              />
+            </div>
+            <button
+              disabled={!code}
+              className='mt-6 mb-2 rounded-lg border border-violet-300 px-4 py-3 text-2xs text-violet-100 hover:border-violet-100 disabled:pointer-events-none disabled:opacity-30'
+            >
+              Verify
+            </button>
+          </>
+        ) : (
+          <>
+            <div className='my-2 w-full'>
+              <label
+                htmlFor='name'
+                className='text-3xs uppercase text-gray-500'
+              >
+                {ldapProvider
+                  ? `Username for ${ldapProvider.name}`
+                  : 'Username or Email'}
+              </label>
+              <input
+                required
+                autoFocus
+                name='name'
+                placeholder='enter your username or email'
+                onChange={e => {
+                  setName(e.target.value)
+                  setError('')
+                }}
+                className={`w-full border-b border-gray-800 bg-transparent px-px py-2 text-2xs placeholder:italic focus:border-b focus:border-gray-200 focus:outline-none ${
+                  error ? 'border-pink-500/60' : ''
+                }`}
+              />
+            </div>
+            <div className='my-2 w-full'>
+              <label
+                htmlFor='password'
+                className='text-3xs uppercase text-gray-500'
+              >
+                Password
+              </label>
+              <input
+                required
+                name='password'
+                type='password'
+                placeholder='enter your password'
+                onChange={e => {
+                  setPassword(e.target.value)
+                  setError('')
+                }}
+                className={`w-full border-b border-gray-800 bg-transparent px-px py-2 text-2xs placeholder:italic focus:border-b focus:outline-none focus:ring-gray-200 ${
+                  error ? 'border-pink-500/60' : ''
+                }`}
+              />
+            </div>
+            <button
+              disabled={!name || !password}
+              className='mt-6 mb-2 rounded-lg border border-violet-300 px-4 py-3 text-2xs text-violet-100 hover:border-violet-100 disabled:pointer-events-none disabled:opacity-30'
+            >
+              Login
+            </button>
+          </>
+        )}
         {error && (
           <p className='absolute -bottom-3.5 mx-auto w-full text-center text-2xs text-pink-400'>
             {error}